@@ -10,6 +10,7 @@
 	"github.com/rancher/rancher/pkg/api/steve/health"
 	"github.com/rancher/rancher/pkg/api/steve/projects"
 	"github.com/rancher/rancher/pkg/api/steve/proxy"
+	"github.com/rancher/rancher/pkg/auth/tokens"
 	"github.com/rancher/rancher/pkg/capr/configserver"
 	"github.com/rancher/rancher/pkg/capr/installer"
 	exttokenstore "github.com/rancher/rancher/pkg/ext/stores/tokens"
@@ -75,7 +76,7 @@ func AdditionalAPIs(ctx context.Context, config *wrangler.Context, steve *steve.
 			config.Mgmt.User().Cache(), config.Mgmt.UserAttribute().Cache(),
 			config.Core.Secret().Cache(), config.Core.Secret(),
 			config.Mgmt.OIDCClient().Cache(), config.Mgmt.OIDCClient(),
-			config.Core.Namespace())
+			config.Core.Namespace(), tokens.NewManager(config))
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,79 @@
+package machine
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta2"
+	"github.com/rancher/rancher/pkg/plan"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// historyHandler serves the "history" link of a machine, which returns the plans replaced on the
+// machine, newest first, as recorded by plan.Store in the plan history of its machine-plan secret.
+type historyHandler struct {
+	secrets  corecontrollers.SecretClient
+	machines capicontrollers.MachineClient
+}
+
+// historyEntry is a plan.PlanHistoryEntry with its outputs decoded.
+type historyEntry struct {
+	plan.PlanHistoryEntry
+	AppliedOutput         map[string]string                         `json:"appliedOutput,omitempty"`
+	AppliedPeriodicOutput map[string]plan.PeriodicInstructionOutput `json:"appliedPeriodicOutput,omitempty"`
+}
+
+func (h *historyHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	entries, err := h.history(apiRequest)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(rw).Encode(entries)
+}
+
+func (h *historyHandler) history(apiRequest *types.APIRequest) ([]historyEntry, error) {
+	machine, err := h.machines.Get(apiRequest.Namespace, apiRequest.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	secret, err := h.secrets.Get(machine.Namespace, capr.PlanSecretFromBootstrapName(machine.Spec.Bootstrap.ConfigRef.Name), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	history, err := plan.NewStore(h.secrets).History(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]historyEntry, 0, len(history))
+	for _, e := range history {
+		entry := historyEntry{PlanHistoryEntry: e}
+		output, err := e.Output()
+		if err != nil {
+			return nil, err
+		}
+		for name, out := range output {
+			if entry.AppliedOutput == nil {
+				entry.AppliedOutput = map[string]string{}
+			}
+			entry.AppliedOutput[name] = string(out)
+		}
+		if entry.AppliedPeriodicOutput, err = e.PeriodicOutput(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
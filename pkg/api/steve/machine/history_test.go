@@ -0,0 +1,120 @@
+package machine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func gzipJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// serveHistory runs the history handler for machine fleet-default/custom-1 and returns the response
+// and the error passed to the error handler, if any.
+func serveHistory(test *outputTest, accessErr error) (*httptest.ResponseRecorder, error) {
+	handler := &historyHandler{secrets: test.secrets, machines: test.machines}
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/cluster.x-k8s.io.machines/fleet-default/custom-1?link=history", nil)
+	var handlerErr error
+	apiRequest := &types.APIRequest{
+		Namespace:     "fleet-default",
+		Name:          "custom-1",
+		Request:       req,
+		Response:      rw,
+		AccessControl: &fakeAccessControl{err: accessErr},
+		ErrorHandler: func(_ *types.APIRequest, err error) {
+			handlerErr = err
+		},
+	}
+	handler.ServeHTTP(rw, types.StoreAPIContext(apiRequest).Request)
+	return rw, handlerErr
+}
+
+func TestHistoryHandler(t *testing.T) {
+	t.Run("returns the decoded history", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		secret := newPlanSecret(t)
+		entries := []plan.PlanHistoryEntry{
+			{
+				Checksum:      "second",
+				Applied:       true,
+				ReplacedAt:    "2026-10-18T10:00:00Z",
+				AppliedOutput: gzipJSON(t, map[string][]byte{"restart": []byte("restarted")}),
+			},
+			{Checksum: "first", State: plan.PlanStateFailed, ReplacedAt: "2026-10-18T09:00:00Z"},
+		}
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).Return(secret, nil)
+		test.secrets.EXPECT().Get("fleet-default", plan.HistorySecretName(secret), gomock.Any()).Return(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: plan.HistorySecretName(secret)},
+			Data:       map[string][]byte{plan.PlanHistoryKey: gzipJSON(t, entries)},
+		}, nil)
+
+		rw, err := serveHistory(test, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &got))
+		require.Len(t, got, 2)
+		assert.Equal(t, "second", got[0]["checksum"])
+		assert.Equal(t, map[string]any{"restart": "restarted"}, got[0]["appliedOutput"])
+		assert.Equal(t, "first", got[1]["checksum"])
+		assert.Equal(t, string(plan.PlanStateFailed), got[1]["state"])
+		assert.NotContains(t, got[1], "appliedOutput")
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		secret := newPlanSecret(t)
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).Return(secret, nil)
+		test.secrets.EXPECT().Get("fleet-default", plan.HistorySecretName(secret), gomock.Any()).
+			Return(nil, apierrors.NewNotFound(corev1.Resource("secrets"), plan.HistorySecretName(secret)))
+
+		rw, err := serveHistory(test, nil)
+		require.NoError(t, err)
+		assert.JSONEq(t, "[]", rw.Body.String())
+	})
+
+	t.Run("plan secret not found", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).
+			Return(nil, apierrors.NewNotFound(corev1.Resource("secrets"), "custom-1-machine-plan"))
+
+		_, err := serveHistory(test, nil)
+		assert.True(t, apierrors.IsNotFound(err), "expected a not found error, got %v", err)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		test := newOutputTest(t)
+		forbidden := apierror.NewAPIError(validation.PermissionDenied, "can not update machine")
+
+		_, err := serveHistory(test, forbidden)
+		assert.Equal(t, forbidden, err)
+	})
+}
@@ -17,6 +17,14 @@ func Register(server *steve.Server, clients *wrangler.Context) {
 			machines: clients.CAPI.Machine(),
 			secrets:  clients.Core.Secret(),
 		}
+		outputHandler := &outputHandler{
+			machines: clients.CAPI.Machine(),
+			secrets:  clients.Core.Secret(),
+		}
+		historyHandler := &historyHandler{
+			machines: clients.CAPI.Machine(),
+			secrets:  clients.Core.Secret(),
+		}
 
 		server.SchemaFactory.AddTemplate(schema2.Template{
 			Group: "cluster.x-k8s.io",
@@ -29,11 +37,15 @@ func Register(server *steve.Server, clients *wrangler.Context) {
 					schema.LinkHandlers["shell"] = sshHandler
 				}
 				schema.LinkHandlers["sshkeys"] = sshHandler
+				schema.LinkHandlers["output"] = outputHandler
+				schema.LinkHandlers["history"] = historyHandler
 				schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
 					if err := request.AccessControl.CanUpdate(request, types.APIObject{}, request.Schema); err != nil ||
 						resource.APIObject.Data().String("spec", "infrastructureRef", "apiGroup") != capr.RKEMachineAPIGroup {
 						delete(resource.Links, "shell")
 						delete(resource.Links, "sshkeys")
+						delete(resource.Links, "output")
+						delete(resource.Links, "history")
 					}
 				}
 			},
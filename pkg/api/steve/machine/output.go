@@ -0,0 +1,88 @@
+package machine
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta2"
+	"github.com/rancher/rancher/pkg/plan"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// outputHandler serves the "output" link of a machine, which streams the live output of the
+// instructions of the plan assigned to the machine as newline-delimited JSON plan.OutputChunks.
+// The optional "instruction" query parameter limits the stream to a single instruction, and "since"
+// skips the chunks up to and including the given sequence, so a client can resume a stream. If the
+// stream fails after it started, a final streamError line carries the error, and the client can
+// resume from the last chunk it received.
+type outputHandler struct {
+	secrets  corecontrollers.SecretClient
+	machines capicontrollers.MachineClient
+}
+
+// streamError is the last line of a stream that failed after the response headers were written.
+type streamError struct {
+	Error string `json:"error"`
+}
+
+func (h *outputHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	if err := h.stream(apiRequest); err != nil {
+		apiRequest.WriteError(err)
+	}
+}
+
+func (h *outputHandler) stream(apiRequest *types.APIRequest) error {
+	var since int64
+	if raw := apiRequest.Request.URL.Query().Get("since"); raw != "" {
+		var err error
+		if since, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return apierror.NewAPIError(validation.InvalidFormat, "since must be an integer")
+		}
+	}
+
+	machine, err := h.machines.Get(apiRequest.Namespace, apiRequest.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	stream, err := plan.WatchLiveOutput(apiRequest.Context(), h.secrets, machine.Namespace,
+		capr.PlanSecretFromBootstrapName(machine.Spec.Bootstrap.ConfigRef.Name), apiRequest.Request.URL.Query().Get("instruction"), since)
+	if err != nil {
+		return err
+	}
+
+	rw := apiRequest.Response
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher, _ := rw.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	encoder := json.NewEncoder(rw)
+	for chunk := range stream.C {
+		if err := encoder.Encode(chunk); err != nil {
+			// The client went away; the request context is canceled, which stops the watch.
+			return nil
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := stream.Err(); err != nil {
+		_ = encoder.Encode(streamError{Error: err.Error()})
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package machine
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+)
+
+type fakeAccessControl struct {
+	types.AccessControl
+	err error
+}
+
+func (f *fakeAccessControl) CanUpdate(_ *types.APIRequest, _ types.APIObject, _ *types.APISchema) error {
+	return f.err
+}
+
+// newPlanSecret returns the plan secret of the machine, with live output of the given chunks.
+func newPlanSecret(t *testing.T, data ...string) *corev1.Secret {
+	t.Helper()
+	rawPlan := []byte("{}")
+	out := plan.LiveOutput{Checksum: plan.PlanHash(rawPlan)}
+	for _, d := range data {
+		out.Append("restore", plan.StdoutStream, []byte(d), time.Now())
+	}
+	raw, err := json.Marshal(out)
+	require.NoError(t, err)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "custom-1-machine-plan", ResourceVersion: "1"},
+		Data:       map[string][]byte{"plan": rawPlan, plan.LiveOutputKey: raw},
+	}
+}
+
+type outputTest struct {
+	handler  *outputHandler
+	secrets  *fake.MockClientInterface[*corev1.Secret, *corev1.SecretList]
+	machines *fake.MockClientInterface[*capi.Machine, *capi.MachineList]
+}
+
+func newOutputTest(t *testing.T) *outputTest {
+	ctrl := gomock.NewController(t)
+	test := &outputTest{
+		secrets:  fake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl),
+		machines: fake.NewMockClientInterface[*capi.Machine, *capi.MachineList](ctrl),
+	}
+	test.handler = &outputHandler{secrets: test.secrets, machines: test.machines}
+	return test
+}
+
+func (o *outputTest) expectMachine() {
+	o.machines.EXPECT().Get("fleet-default", "custom-1", gomock.Any()).Return(&capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "custom-1"},
+		Spec: capi.MachineSpec{
+			Bootstrap: capi.Bootstrap{ConfigRef: capi.ContractVersionedObjectReference{Name: "custom-1"}},
+		},
+	}, nil)
+}
+
+// serve runs the handler for the output of machine fleet-default/custom-1 and returns the response
+// and the error passed to the error handler, if any.
+func (o *outputTest) serve(query string, accessErr error) (*httptest.ResponseRecorder, error) {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/cluster.x-k8s.io.machines/fleet-default/custom-1?link=output"+query, nil)
+	var handlerErr error
+	apiRequest := &types.APIRequest{
+		Namespace:     "fleet-default",
+		Name:          "custom-1",
+		Request:       req,
+		Response:      rw,
+		AccessControl: &fakeAccessControl{err: accessErr},
+		ErrorHandler: func(_ *types.APIRequest, err error) {
+			handlerErr = err
+		},
+	}
+	o.handler.ServeHTTP(rw, types.StoreAPIContext(apiRequest).Request)
+	return rw, handlerErr
+}
+
+// lines decodes each NDJSON line of the response.
+func lines(t *testing.T, rw *httptest.ResponseRecorder) []map[string]any {
+	t.Helper()
+	var result []map[string]any
+	scanner := bufio.NewScanner(rw.Body)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		result = append(result, line)
+	}
+	return result
+}
+
+func TestOutputHandler(t *testing.T) {
+	t.Run("streams chunks after since", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		secret := newPlanSecret(t, "a", "b", "c")
+		watcher := watch.NewRaceFreeFake()
+		watcher.Delete(secret)
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).Return(secret, nil)
+		test.secrets.EXPECT().Watch("fleet-default", gomock.Any()).Return(watcher, nil)
+
+		rw, err := test.serve("&since=1", nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.Equal(t, "application/x-ndjson", rw.Header().Get("Content-Type"))
+		got := lines(t, rw)
+		require.Len(t, got, 2)
+		assert.EqualValues(t, 2, got[0]["seq"])
+		assert.EqualValues(t, 3, got[1]["seq"])
+	})
+
+	t.Run("forwards watch errors", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		secret := newPlanSecret(t, "a")
+		watcher := watch.NewRaceFreeFake()
+		forbidden := apierrors.NewForbidden(corev1.Resource("secrets"), secret.Name, nil)
+		watcher.Error(&forbidden.ErrStatus)
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).Return(secret, nil)
+		test.secrets.EXPECT().Watch("fleet-default", gomock.Any()).Return(watcher, nil)
+
+		rw, err := test.serve("", nil)
+		require.NoError(t, err)
+		got := lines(t, rw)
+		require.Len(t, got, 2)
+		assert.EqualValues(t, 1, got[0]["seq"])
+		assert.Contains(t, got[1]["error"], "forbidden")
+	})
+
+	t.Run("plan secret not found", func(t *testing.T) {
+		test := newOutputTest(t)
+		test.expectMachine()
+		test.secrets.EXPECT().Get("fleet-default", "custom-1-machine-plan", gomock.Any()).
+			Return(nil, apierrors.NewNotFound(corev1.Resource("secrets"), "custom-1-machine-plan"))
+
+		_, err := test.serve("", nil)
+		assert.True(t, apierrors.IsNotFound(err), "expected a not found error, got %v", err)
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		test := newOutputTest(t)
+
+		_, err := test.serve("&since=abc", nil)
+		var apiErr *apierror.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, validation.InvalidFormat, apiErr.Code)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		test := newOutputTest(t)
+		forbidden := apierror.NewAPIError(validation.PermissionDenied, "can not update machine")
+
+		_, err := test.serve("", forbidden)
+		assert.Equal(t, forbidden, err)
+	})
+}
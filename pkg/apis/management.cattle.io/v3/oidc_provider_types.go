@@ -75,4 +75,20 @@ type OIDCClientSpec struct {
 	//
 	// +optional
 	Scopes []string `json:"scopes"`
+
+	// GrantTypes is the list of OAuth 2.0 grant types allowed for this client.
+	//
+	// If not configured, the client can use the authorization_code and
+	// refresh_token grants. The client_credentials and
+	// urn:ietf:params:oauth:grant-type:device_code grants must be enabled
+	// explicitly.
+	//
+	// +optional
+	// +kubebuilder:validation:items:Enum=authorization_code;refresh_token;client_credentials;urn:ietf:params:oauth:grant-type:device_code
+	GrantTypes []string `json:"grantTypes,omitempty"`
+
+	// PostLogoutRedirectURIs defines the URIs the end_session endpoint is
+	// allowed to redirect to once the user is logged out.
+	// +optional
+	PostLogoutRedirectURIs []string `json:"postLogoutRedirectURIs,omitempty"`
 }
@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CertificateRotationArgs contains parameters for rotating cluster certificates.
+type CertificateRotationArgs struct {
+	// Services is the list of services whose certificates will be rotated.
+	// If empty, the certificates for all services are rotated.
+	// +kubebuilder:validation:items:Enum=admin;api-server;auth-proxy;cloud-controller;controller-manager;etcd;k3s-controller;k3s-server;kube-proxy;kubelet;rke2-controller;rke2-server;scheduler
+	// +optional
+	Services []string `json:"services,omitempty"`
+}
+
+// CertificateRotationSpec defines the desired state of CertificateRotation.
+type CertificateRotationSpec struct {
+	// OperationSpec contains the shared operation inputs, including the required ClusterRef.
+	OperationSpec `json:",inline"`
+
+	// Args contains parameters for rotating cluster certificates.
+	// +optional
+	Args CertificateRotationArgs `json:"args,omitempty"`
+}
+
+// CertificateRotationStep is the step of the CertificateRotation operation.
+type CertificateRotationStep string
+
+const (
+	// CertificateRotationStepPreflight indicates the step is to verify that the nodes
+	// targeted by the rotation are present and have a certificate directory.
+	CertificateRotationStepPreflight CertificateRotationStep = "Preflight"
+
+	// CertificateRotationStepRotate indicates the step is to rotate the certificates
+	// of the selected services on each etcd and control plane node, one node at a time.
+	CertificateRotationStepRotate CertificateRotationStep = "Rotate"
+
+	// CertificateRotationStepRestart indicates the step is to restart the distro agent
+	// service on worker nodes so that they pick up the rotated certificates.
+	CertificateRotationStepRestart CertificateRotationStep = "Restart"
+)
+
+// CertificateRotationStatus defines the observed state of CertificateRotation.
+type CertificateRotationStatus struct {
+	// OperationStatus is the shared status common to all operations.
+	OperationStatus `json:",inline"`
+
+	// Step is the current step of the operation.
+	// Step is typically only valid during the InProgress phase.
+	// +kubebuilder:validation:Enum=Preflight;Rotate;Restart
+	// +optional
+	Step CertificateRotationStep `json:"step,omitempty"`
+}
+
+func (s *CertificateRotationStatus) SetPhase(phase OperationPhase) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
+}
+
+func (s *CertificateRotationStatus) SetStep(step CertificateRotationStep) {
+	if s.Step == step {
+		return
+	}
+	s.Step = step
+	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=certificaterotations,scope=Namespaced,categories=operations
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels={"auth.cattle.io/cluster-indexed=true"}
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterRef.name"
+// +kubebuilder:printcolumn:name="Paused",type=string,JSONPath=".spec.paused"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Step",type=string,JSONPath=".status.step"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// CertificateRotation is the mechanism for initiating a certificate rotation
+// operation for provisioned or imported RKE2/K3s clusters.
+type CertificateRotation struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of the CertificateRotation.
+	// +required
+	Spec CertificateRotationSpec `json:"spec,omitempty"`
+
+	// Status is the observed state of the CertificateRotation.
+	// +optional
+	Status CertificateRotationStatus `json:"status,omitempty"`
+}
@@ -26,8 +26,70 @@ type OperationSpec struct {
 	// A value == 0 expires immediately.
 	// +optional
 	TTL int64 `json:"ttl,omitempty"`
+
+	// DryRun indicates whether the operation only previews the plans it would assign.
+	// A dry run performs the preflight checks of the operation, then renders the plan every
+	// subsequent step would assign to each machine-plan secret without writing them. The rendered
+	// plans are recorded in the ConfigMap named by status.planPreview, which is owned by the
+	// operation; set a TTL to keep the operation around long enough to review them.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Priority orders operations waiting for the beacon of the same cluster.
+	// Once the beacon is released, it is acquired by the eligible waiting operation with the highest
+	// priority; operations of equal priority acquire it in the order they were created.
+	// The default value is `0`.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// NotBefore is the earliest time the operation may start.
+	// Until then, the operation stays Pending and lets other operations of the cluster run first.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// MaintenanceWindow restricts when the operation may start.
+	// The operation stays Pending until the window opens; once started, it runs to completion even if
+	// the window closes.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// StepTimeouts bounds how long the operation may spend in each of its steps, keyed by step name,
+	// e.g. `Restart: 45m`. Steps that are not listed use the default timeout of the operation type
+	// for the step, if any; a zero duration disables the timeout of a step. The time is measured
+	// from when the operation entered the step, including any time spent paused or waiting for a
+	// delegate. An operation exceeding the timeout of its current step is marked as Failed.
+	// A step that times out is not reverted: the cluster is left as the step left it.
+	// +optional
+	StepTimeouts map[string]metav1.Duration `json:"stepTimeouts,omitempty"`
 }
 
+// MaintenanceWindow is a recurring period of time during which operations may start.
+type MaintenanceWindow struct {
+	// Start is the time of day the window opens, formatted as HH:MM in the window's time zone.
+	// +required
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// Duration is how long the window stays open, e.g. `4h`.
+	// +required
+	Duration metav1.Duration `json:"duration"`
+
+	// Days are the days of the week the window opens on.
+	// The window opens every day when empty.
+	// +optional
+	// +listType=set
+	Days []Weekday `json:"days,omitempty"`
+
+	// TimeZone is the IANA name of the time zone Start is expressed in, e.g. `Europe/Berlin`.
+	// The default value is `UTC`.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Weekday is a day of the week.
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
 // OperationPhase represents the current phase of the operation.
 type OperationPhase string
 
@@ -46,12 +108,23 @@ type OperationSpec struct {
 
 	// OperationPhaseCanceled indicates the operation was canceled by the user or system.
 	OperationPhaseCanceled OperationPhase = "Canceled"
+
+	// OperationPhaseDryRunCompleted indicates the operation was a dry run and has rendered the plans
+	// it would assign. Nothing was changed on the cluster.
+	OperationPhaseDryRunCompleted OperationPhase = "DryRunCompleted"
 )
 
+// isTerminal returns true when the operation has finished in the given phase.
+func isTerminal(phase OperationPhase) bool {
+	return phase == OperationPhaseSucceeded || phase == OperationPhaseFailed || phase == OperationPhaseCanceled ||
+		phase == OperationPhaseDryRunCompleted
+}
+
 // OperationStatus defines the observed state of an operation.
 type OperationStatus struct {
 	// Conditions represent the latest available observations of an operation's current state.
 	// Known condition types are Pending, InProgress, Succeeded, Failed, Canceled, and Paused .
+	// PlanChanged records the last plan the operation replaced on a node and what changed in it.
 	// Operations may have additional conditions of their own.
 	// Operations may also provide additional information in the form of messages.
 	// +optional
@@ -71,7 +144,8 @@ type OperationStatus struct {
 	// A Succeeded operation is one that completed successfully.
 	// A Failed operation is one that failed to complete successfully.
 	// A Canceled operation is one that was canceled by the user or system.
-	// +kubebuilder:validation:Enum=Pending;InProgress;Succeeded;Failed;Canceled
+	// A DryRunCompleted operation is a dry run that rendered the plans it would assign.
+	// +kubebuilder:validation:Enum=Pending;InProgress;Succeeded;Failed;Canceled;DryRunCompleted
 	// +optional
 	Phase OperationPhase `json:"phase,omitempty"`
 
@@ -79,4 +153,62 @@ type OperationStatus struct {
 	// +optional
 	// +kubebuilder:validation:Minimum=1
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// PlanPreview is the name of the ConfigMap in the namespace of the operation holding the plans
+	// rendered by a dry run. It is only set once the operation is DryRunCompleted.
+	// +optional
+	PlanPreview string `json:"planPreview,omitempty"`
+
+	// QueuePosition is the 1-based position of the operation among the operations waiting for the
+	// beacon of the cluster. It is only set while the operation is Pending.
+	// +optional
+	QueuePosition int32 `json:"queuePosition,omitempty"`
+
+	// Leader is the name of the machine elected to lead the operation, for operations that run
+	// their first steps on a single node before rolling out to the rest of the cluster.
+	// +optional
+	Leader string `json:"leader,omitempty"`
+
+	// StepHistory records when the operation entered and left each of its steps, oldest first.
+	// Only the most recent transitions are kept.
+	// +optional
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=32
+	StepHistory []StepTiming `json:"stepHistory,omitempty"`
+}
+
+// maxStepHistory is the number of step transitions kept in OperationStatus.StepHistory.
+const maxStepHistory = 32
+
+// StepTiming records when an operation entered and left one of its steps.
+type StepTiming struct {
+	// Name is the name of the step.
+	Name string `json:"name"`
+
+	// Started is when the operation entered the step.
+	Started metav1.Time `json:"started"`
+
+	// Finished is when the operation left the step. It is unset while the step is running.
+	// +optional
+	Finished *metav1.Time `json:"finished,omitempty"`
+}
+
+// RecordStep closes the timing of the running step, if any, and opens one for step at now. An
+// empty step only closes the running step.
+func (s *OperationStatus) RecordStep(step string, now metav1.Time) {
+	s.FinishStep(now)
+	if step == "" {
+		return
+	}
+	s.StepHistory = append(s.StepHistory, StepTiming{Name: step, Started: now})
+	if len(s.StepHistory) > maxStepHistory {
+		s.StepHistory = s.StepHistory[len(s.StepHistory)-maxStepHistory:]
+	}
+}
+
+// FinishStep closes the timing of the running step at now, if any.
+func (s *OperationStatus) FinishStep(now metav1.Time) {
+	if n := len(s.StepHistory); n > 0 && s.StepHistory[n-1].Finished == nil {
+		s.StepHistory[n-1].Finished = &now
+	}
 }
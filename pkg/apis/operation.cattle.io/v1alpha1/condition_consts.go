@@ -25,12 +25,20 @@
 
 	// PausedCondition represents the condition state for a task or process that has been paused.
 	PausedCondition = condition.Cond("Paused")
+
+	// PlanChangedCondition records the last plan an operation replaced on a node, with a summary of
+	// what changed in its message.
+	PlanChangedCondition = condition.Cond("PlanChanged")
 )
 
 const (
 	// ClusterNotFoundReason surfaces when an operation fails because the cluster is not found.
 	ClusterNotFoundReason = "ClusterNotFound"
 
+	// ClusterRefForbiddenReason surfaces when an operation fails because its clusterRef points at a
+	// cluster outside of the operation's namespace.
+	ClusterRefForbiddenReason = "ClusterRefForbidden"
+
 	// BeaconLostReason surfaces when an operation fails because the beacon is lost.
 	BeaconLostReason = "BeaconLost"
 
@@ -53,6 +61,9 @@
 
 	PlanFailedReason = "PlanFailed"
 
+	// PlanReplacedReason surfaces when an operation replaced the plan previously assigned to a node.
+	PlanReplacedReason = "PlanReplaced"
+
 	// FinishedReason surfaces when an operation has reached a terminal state (success/failure).
 	FinishedReason = "Finished"
 
@@ -80,6 +91,57 @@
 	WaitingForEncryptionKeyRotationReason = "WaitingForEncryptionKeyRotation"
 
 	PreflightCheckFailedReason = "PreflightCheckFailed"
+
+	// DryRunCompletedReason surfaces when a dry run has rendered the plans of an operation.
+	DryRunCompletedReason = "DryRunCompleted"
+
+	// TemplateNotFoundReason surfaces when a CustomOperation fails because the referenced
+	// OperationTemplate does not exist.
+	TemplateNotFoundReason = "TemplateNotFound"
+
+	// TemplateChangedReason surfaces when a CustomOperation fails because the referenced
+	// OperationTemplate was modified while the operation was running.
+	TemplateChangedReason = "TemplateChanged"
+
+	// WaitingInQueueReason surfaces when an operation is queued behind other operations of the
+	// cluster for the beacon.
+	WaitingInQueueReason = "WaitingInQueue"
+
+	// WaitingForScheduleReason surfaces when an operation is waiting for its notBefore time or
+	// maintenance window.
+	WaitingForScheduleReason = "WaitingForSchedule"
+
+	// InvalidScheduleReason surfaces when an operation is canceled because its maintenance window
+	// is invalid.
+	InvalidScheduleReason = "InvalidSchedule"
+
+	// StepTimedOutReason surfaces when an operation is marked as failed because it spent longer
+	// than the timeout of its current step in it.
+	StepTimedOutReason = "StepTimedOut"
+
+	// RollingBackReason surfaces when a failed operation is restoring the state the cluster was in
+	// before it started.
+	RollingBackReason = "RollingBack"
+
+	// RolledBackReason surfaces when an operation is marked as failed after its changes were rolled
+	// back.
+	RolledBackReason = "RolledBack"
+
+	// InvalidOperationSetReason surfaces when an OperationSet fails because its cluster selector or
+	// template is invalid.
+	InvalidOperationSetReason = "InvalidOperationSet"
+
+	// NoClustersSelectedReason surfaces when an OperationSet fails because its cluster selector
+	// matches no supported cluster.
+	NoClustersSelectedReason = "NoClustersSelected"
+
+	// FailureBudgetExceededReason surfaces when an OperationSet fails because more of its
+	// operations failed or were canceled than its failure budget allows.
+	FailureBudgetExceededReason = "FailureBudgetExceeded"
+
+	// OperationExistsReason surfaces when an OperationSet fails because an operation it would
+	// create already exists and is not owned by the set.
+	OperationExistsReason = "OperationExists"
 )
 
 func WaitingForDelegateMessage(beacon *planv1alpha1.Beacon) string {
@@ -0,0 +1,94 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CustomOperationArgs contains parameters for running an OperationTemplate against a cluster.
+type CustomOperationArgs struct {
+	// Template is the name of the OperationTemplate to run.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Template string `json:"template"`
+}
+
+// CustomOperationSpec defines the desired state of CustomOperation.
+type CustomOperationSpec struct {
+	// OperationSpec contains the shared operation inputs, including the required ClusterRef.
+	OperationSpec `json:",inline"`
+
+	// Args contains parameters for running an OperationTemplate against a cluster.
+	// +required
+	Args CustomOperationArgs `json:"args"`
+}
+
+// CustomOperationStep is the step of the CustomOperation operation. It is the name of the
+// current step of the referenced OperationTemplate.
+type CustomOperationStep string
+
+// CustomOperationStatus defines the observed state of CustomOperation.
+type CustomOperationStatus struct {
+	// OperationStatus is the shared status common to all operations.
+	OperationStatus `json:",inline"`
+
+	// Step is the current step of the operation.
+	// Step is typically only valid during the InProgress phase.
+	// +optional
+	Step CustomOperationStep `json:"step,omitempty"`
+
+	// TemplateGeneration is the generation of the OperationTemplate observed when the operation
+	// started. The operation fails if the template is modified while it is running.
+	// +optional
+	TemplateGeneration int64 `json:"templateGeneration,omitempty"`
+}
+
+func (s *CustomOperationStatus) SetPhase(phase OperationPhase) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
+}
+
+func (s *CustomOperationStatus) SetStep(step CustomOperationStep) {
+	if s.Step == step {
+		return
+	}
+	s.Step = step
+	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=customoperations,scope=Namespaced,categories=operations
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels={"auth.cattle.io/cluster-indexed=true"}
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterRef.name"
+// +kubebuilder:printcolumn:name="Template",type=string,JSONPath=".spec.args.template"
+// +kubebuilder:printcolumn:name="Paused",type=string,JSONPath=".spec.paused"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Step",type=string,JSONPath=".status.step"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// CustomOperation is the mechanism for running the steps declared by an OperationTemplate
+// against a provisioned or imported RKE2/K3s cluster.
+type CustomOperation struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of the CustomOperation.
+	// +required
+	Spec CustomOperationSpec `json:"spec,omitempty"`
+
+	// Status is the observed state of the CustomOperation.
+	// +optional
+	Status CustomOperationStatus `json:"status,omitempty"`
+}
@@ -8,12 +8,29 @@
 type EncryptionKeyRotationSpec struct {
 	// OperationSpec contains the shared operation inputs, including the required ClusterRef.
 	OperationSpec `json:",inline"`
+
+	// Rollback enables the compensating rollback of a failed rotation.
+	// When enabled, the encryption config file of every etcd and control-plane node is backed up in the
+	// Preflight step, and a failure of the Rotate step before re-encryption started restores the backup
+	// and restarts the servers in restart order. This covers failures before the rotate-keys command
+	// was handed to the leader, and a rotate-keys command that the leader rejected while its
+	// secrets-encrypt status still shows no re-encryption stage and matching hashes. The rollback
+	// checks that every secret still decrypts before the operation is marked as Failed. Failures after
+	// re-encryption started, including every failure of the Restart step, are not rolled back, as the
+	// backed up config lacks the new key; the failure message then says so.
+	// The default value is `false`.
+	// +optional
+	Rollback bool `json:"rollback,omitempty"`
 }
 
 // EncryptionKeyRotationStep is the step of the EncryptionKeyRotation operation.
 type EncryptionKeyRotationStep string
 
 const (
+	// EncryptionKeyRotationStepPreflight indicates the step is to back up the encryption config file
+	// of every etcd and control-plane node. It only runs when rollback is enabled.
+	EncryptionKeyRotationStepPreflight EncryptionKeyRotationStep = "Preflight"
+
 	// EncryptionKeyRotationStepRotate indicates the step is to rotate the encryption keys
 	// by running the secrets-encrypt rotate-keys command on the elected control-plane leader.
 	EncryptionKeyRotationStepRotate EncryptionKeyRotationStep = "Rotate"
@@ -21,6 +38,11 @@ type EncryptionKeyRotationSpec struct {
 	// EncryptionKeyRotationStepRestart indicates the step is to restart the distro server
 	// service on all control plane nodes after key rotation.
 	EncryptionKeyRotationStepRestart EncryptionKeyRotationStep = "Restart"
+
+	// EncryptionKeyRotationStepRollback indicates the step is to restore the encryption config file
+	// backed up in the Preflight step and restart the distro server service on all control plane
+	// nodes after the Rotate step failed before re-encryption started.
+	EncryptionKeyRotationStepRollback EncryptionKeyRotationStep = "Rollback"
 )
 
 // EncryptionKeyRotationStatus defines the observed state of EncryptionKeyRotation.
@@ -30,9 +52,20 @@ type EncryptionKeyRotationStatus struct {
 
 	// Step is the current step of the operation.
 	// Step is typically only valid during the InProgress phase.
-	// +kubebuilder:validation:Enum=Rotate;Restart
+	// +kubebuilder:validation:Enum=Preflight;Rotate;Restart;Rollback
 	// +optional
 	Step EncryptionKeyRotationStep `json:"step,omitempty"`
+
+	// RollbackCause is the failure of the Rotate step that triggered the rollback.
+	// It is only set once a rollback has started.
+	// +optional
+	RollbackCause string `json:"rollbackCause,omitempty"`
+
+	// ReencryptionStarted is set once the rotate-keys command was handed to the leader, after which
+	// secrets may be encrypted with the new key and a failure is no longer rolled back. It is cleared
+	// again when the leader rejected the command before re-encryption started.
+	// +optional
+	ReencryptionStarted bool `json:"reencryptionStarted,omitempty"`
 }
 
 func (s *EncryptionKeyRotationStatus) SetPhase(phase OperationPhase) {
@@ -41,6 +74,9 @@ func (s *EncryptionKeyRotationStatus) SetPhase(phase OperationPhase) {
 	}
 	s.Phase = phase
 	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
 }
 
 func (s *EncryptionKeyRotationStatus) SetStep(step EncryptionKeyRotationStep) {
@@ -49,6 +85,7 @@ func (s *EncryptionKeyRotationStatus) SetStep(step EncryptionKeyRotationStep) {
 	}
 	s.Step = step
 	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
 }
 
 // +genclient
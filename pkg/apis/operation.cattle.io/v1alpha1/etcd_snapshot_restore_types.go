@@ -68,6 +68,9 @@ func (s *ETCDSnapshotRestoreStatus) SetPhase(phase OperationPhase) {
 	}
 	s.Phase = phase
 	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
 }
 
 func (s *ETCDSnapshotRestoreStatus) SetStep(step ETCDSnapshotRestoreStep) {
@@ -76,6 +79,7 @@ func (s *ETCDSnapshotRestoreStatus) SetStep(step ETCDSnapshotRestoreStep) {
 	}
 	s.Step = step
 	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
 }
 
 // +genclient
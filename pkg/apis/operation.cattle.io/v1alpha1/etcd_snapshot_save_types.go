@@ -57,6 +57,9 @@ func (s *ETCDSnapshotSaveStatus) SetPhase(phase OperationPhase) {
 	}
 	s.Phase = phase
 	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
 }
 
 func (s *ETCDSnapshotSaveStatus) SetStep(step ETCDSnapshotSaveStep) {
@@ -65,6 +68,7 @@ func (s *ETCDSnapshotSaveStatus) SetStep(step ETCDSnapshotSaveStep) {
 	}
 	s.Step = step
 	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
 }
 
 // +genclient
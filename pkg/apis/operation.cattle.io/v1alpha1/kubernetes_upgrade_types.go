@@ -0,0 +1,177 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KubernetesUpgradeDrainOptions contains the kubectl drain parameters used before a node is upgraded.
+type KubernetesUpgradeDrainOptions struct {
+	// Force specifies whether to drain the node even if there are pods not
+	// managed by a ReplicationController, Job, or DaemonSet.
+	// +optional
+	Force bool `json:"force,omitempty"`
+
+	// IgnoreDaemonSets specifies whether to ignore DaemonSet-managed pods.
+	// Defaults to true when unset.
+	// +nullable
+	// +optional
+	IgnoreDaemonSets *bool `json:"ignoreDaemonSets,omitempty"`
+
+	// DeleteEmptyDirData instructs the drain to proceed even if there are
+	// pods using emptyDir.
+	// +optional
+	DeleteEmptyDirData bool `json:"deleteEmptyDirData,omitempty"`
+
+	// DisableEviction forces drain to use delete rather than evict.
+	// +optional
+	DisableEviction bool `json:"disableEviction,omitempty"`
+
+	// GracePeriod is the period of time in seconds given to each pod to
+	// terminate gracefully. If zero or unset, the value specified in the pod is used.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	GracePeriod int `json:"gracePeriod,omitempty"`
+
+	// Timeout is the number of seconds to wait for the drain to complete before giving up.
+	// If zero or unset, the drain waits indefinitely.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// KubernetesUpgradeRoleStrategy controls how the nodes of a single role are rolled.
+type KubernetesUpgradeRoleStrategy struct {
+	// MaxUnavailable is the number of nodes of this role that may be upgraded at the same time.
+	// It can be an absolute number (e.g. "2") or a percentage of the nodes of this role (e.g. "20%"),
+	// which is rounded up. Defaults to "1".
+	// +kubebuilder:validation:Pattern=`^([1-9][0-9]*|[1-9][0-9]?%|100%)$`
+	// +optional
+	MaxUnavailable string `json:"maxUnavailable,omitempty"`
+
+	// Drain contains the drain options used before the node is upgraded.
+	// Nodes are not drained when unset.
+	// +optional
+	Drain *KubernetesUpgradeDrainOptions `json:"drain,omitempty"`
+}
+
+// KubernetesUpgradeArgs contains parameters for upgrading the Kubernetes version of a cluster.
+type KubernetesUpgradeArgs struct {
+	// KubernetesVersion is the RKE2/K3s version to upgrade the cluster to, e.g. v1.33.1+rke2r1.
+	// It must match the distribution currently running on the cluster.
+	// For provisioned clusters, the version is recorded on the cluster object once every node has been
+	// upgraded, before the cluster is unpaused. A failed upgrade leaves the version unchanged, so the
+	// provisioning controllers converge any upgraded node back on the previous version.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	KubernetesVersion string `json:"kubernetesVersion"`
+
+	// InstallerImage overrides the system-agent installer image used to install the new version.
+	// Defaults to the system-agent-installer-image setting suffixed with the distribution and version.
+	// +optional
+	InstallerImage string `json:"installerImage,omitempty"`
+
+	// Etcd is the upgrade strategy for etcd nodes, including etcd nodes that also hold the
+	// control-plane role.
+	// +optional
+	Etcd KubernetesUpgradeRoleStrategy `json:"etcd,omitempty"`
+
+	// ControlPlane is the upgrade strategy for control-plane nodes that do not hold the etcd role.
+	// +optional
+	ControlPlane KubernetesUpgradeRoleStrategy `json:"controlPlane,omitempty"`
+
+	// Worker is the upgrade strategy for worker-only nodes.
+	// +optional
+	Worker KubernetesUpgradeRoleStrategy `json:"worker,omitempty"`
+}
+
+// KubernetesUpgradeSpec defines the desired state of KubernetesUpgrade.
+type KubernetesUpgradeSpec struct {
+	// OperationSpec contains the shared operation inputs, including the required ClusterRef.
+	OperationSpec `json:",inline"`
+
+	// Args contains parameters for upgrading the Kubernetes version of a cluster.
+	// +required
+	Args KubernetesUpgradeArgs `json:"args"`
+}
+
+// KubernetesUpgradeStep is the step of the KubernetesUpgrade operation.
+type KubernetesUpgradeStep string
+
+const (
+	// KubernetesUpgradeStepPreflight indicates the step is to verify that the requested version
+	// matches the distribution of the cluster and that every node can be drained if requested.
+	KubernetesUpgradeStepPreflight KubernetesUpgradeStep = "Preflight"
+
+	// KubernetesUpgradeStepEtcd indicates the step is to upgrade the etcd nodes.
+	KubernetesUpgradeStepEtcd KubernetesUpgradeStep = "Etcd"
+
+	// KubernetesUpgradeStepControlPlane indicates the step is to upgrade the control-plane nodes
+	// that do not hold the etcd role.
+	KubernetesUpgradeStepControlPlane KubernetesUpgradeStep = "ControlPlane"
+
+	// KubernetesUpgradeStepWorker indicates the step is to upgrade the worker-only nodes.
+	KubernetesUpgradeStepWorker KubernetesUpgradeStep = "Worker"
+)
+
+// KubernetesUpgradeStatus defines the observed state of KubernetesUpgrade.
+type KubernetesUpgradeStatus struct {
+	// OperationStatus is the shared status common to all operations.
+	OperationStatus `json:",inline"`
+
+	// Step is the current step of the operation.
+	// Step is typically only valid during the InProgress phase.
+	// +kubebuilder:validation:Enum=Preflight;Etcd;ControlPlane;Worker
+	// +optional
+	Step KubernetesUpgradeStep `json:"step,omitempty"`
+}
+
+func (s *KubernetesUpgradeStatus) SetPhase(phase OperationPhase) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+	s.LastUpdated = metav1.Now()
+	if isTerminal(phase) {
+		s.FinishStep(s.LastUpdated)
+	}
+}
+
+func (s *KubernetesUpgradeStatus) SetStep(step KubernetesUpgradeStep) {
+	if s.Step == step {
+		return
+	}
+	s.Step = step
+	s.LastUpdated = metav1.Now()
+	s.RecordStep(string(step), s.LastUpdated)
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=kubernetesupgrades,scope=Namespaced,categories=operations
+// +kubebuilder:subresource:status
+// +kubebuilder:metadata:labels={"auth.cattle.io/cluster-indexed=true"}
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterRef.name"
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=".spec.args.kubernetesVersion"
+// +kubebuilder:printcolumn:name="Paused",type=string,JSONPath=".spec.paused"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Step",type=string,JSONPath=".status.step"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// KubernetesUpgrade is the mechanism for initiating a rolling Kubernetes version upgrade
+// for provisioned or imported RKE2/K3s clusters.
+type KubernetesUpgrade struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of the KubernetesUpgrade.
+	// +required
+	Spec KubernetesUpgradeSpec `json:"spec,omitempty"`
+
+	// Status is the observed state of the KubernetesUpgrade.
+	// +optional
+	Status KubernetesUpgradeStatus `json:"status,omitempty"`
+}
@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// OperationRecordClusterLabel is set on every OperationRecord to the name of the cluster the
+	// recorded operation ran against, so the records of a cluster can be listed with a label
+	// selector.
+	OperationRecordClusterLabel = "operation.cattle.io/cluster"
+
+	// OperationRecordKindLabel is set on every OperationRecord to the kind of the recorded
+	// operation.
+	OperationRecordKindLabel = "operation.cattle.io/kind"
+
+	// CreatedByAnnotation is set on every operation, on creation, to the name of the user the
+	// Kubernetes API server authenticated the create request as. It is set by an admission policy
+	// that overrides any value supplied by the client, so it can be trusted for attribution.
+	CreatedByAnnotation = "operation.cattle.io/created-by"
+)
+
+// OperationRecordSpec captures an operation as it was once it reached a terminal phase and its
+// phase hooks have finished.
+type OperationRecordSpec struct {
+	// OperationRef references the recorded operation. The operation itself may have been deleted
+	// since.
+	// +required
+	OperationRef corev1.ObjectReference `json:"operationRef"`
+
+	// ClusterRef is a reference to the Cluster the operation ran against.
+	// +optional
+	ClusterRef *corev1.ObjectReference `json:"clusterRef,omitempty"`
+
+	// CreatedBy is the name of the user that created the operation, as authenticated by the
+	// Kubernetes API server. For operations created through the Rancher API, this is the ID of the
+	// Rancher user.
+	// +optional
+	CreatedBy string `json:"createdBy,omitempty"`
+
+	// Phase is the terminal phase the operation finished in.
+	// +kubebuilder:validation:Enum=Succeeded;Failed;Canceled;DryRunCompleted
+	// +required
+	Phase OperationPhase `json:"phase"`
+
+	// Message explains why the operation failed or was canceled.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Started is when the operation was created.
+	// +required
+	Started metav1.Time `json:"started"`
+
+	// Completed is when the operation reached its terminal phase.
+	// +required
+	Completed metav1.Time `json:"completed"`
+
+	// Leader is the name of the machine elected to lead the operation, if it elected one.
+	// +optional
+	Leader string `json:"leader,omitempty"`
+
+	// Steps records when the operation entered and left each of its steps, oldest first.
+	// +optional
+	// +listType=atomic
+	Steps []StepTiming `json:"steps,omitempty"`
+
+	// OperationSpec is the spec of the operation.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	OperationSpec runtime.RawExtension `json:"operationSpec,omitempty"`
+
+	// OperationStatus is the final status of the operation.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	OperationStatus runtime.RawExtension `json:"operationStatus,omitempty"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=operationrecords,scope=Namespaced,categories=operations
+// +kubebuilder:metadata:labels={"auth.cattle.io/cluster-indexed=true"}
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=".spec.clusterRef.name"
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=".spec.operationRef.kind"
+// +kubebuilder:printcolumn:name="Operation",type=string,JSONPath=".spec.operationRef.name"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".spec.phase"
+// +kubebuilder:printcolumn:name="Created By",type=string,JSONPath=".spec.createdBy"
+// +kubebuilder:printcolumn:name="Completed",type=date,JSONPath=".spec.completed"
+
+// OperationRecord is the history entry written once an operation reaches a terminal phase. Records
+// are never updated and are not owned by the operation they record, so the history of a cluster
+// outlives operations deleted once their TTL expires. The records of a cluster are selected by the
+// OperationRecordClusterLabel label.
+type OperationRecord struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the recorded operation.
+	// +required
+	Spec OperationRecordSpec `json:"spec"`
+}
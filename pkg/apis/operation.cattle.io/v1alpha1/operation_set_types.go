@@ -0,0 +1,176 @@
+package v1alpha1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// OperationSetLabel is set on every operation created by an OperationSet to the name of the set.
+const OperationSetLabel = "operation.cattle.io/operation-set"
+
+// OperationSetTemplate is the operation an OperationSet creates for each selected cluster.
+type OperationSetTemplate struct {
+	// Kind is the kind of the operation to create.
+	// +kubebuilder:validation:Enum=CertificateRotation;EncryptionKeyRotation;ETCDSnapshotSave;ETCDSnapshotRestore;KubernetesUpgrade;CustomOperation
+	// +required
+	Kind string `json:"kind"`
+
+	// Labels are added to the labels of every created operation.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Spec is the spec of every created operation. Its clusterRef is set to the selected cluster.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +required
+	Spec runtime.RawExtension `json:"spec"`
+}
+
+// OperationSetSpec defines the desired state of OperationSet.
+type OperationSetSpec struct {
+	// ClusterSelector selects the management clusters to run the operation against. The clusters
+	// are selected once, when the set starts; clusters labeled afterwards are not added to it.
+	// +required
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+
+	// Template is the operation created for each selected cluster.
+	// +required
+	Template OperationSetTemplate `json:"template"`
+
+	// MaxConcurrent is the number of operations of the set that may run at the same time.
+	// The default value is `1`.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrent int32 `json:"maxConcurrent,omitempty"`
+
+	// FailureBudget is the number of operations of the set that may fail or be canceled before the
+	// set stops creating operations for the remaining clusters. Operations already running are left
+	// to finish.
+	// The default value is `0`.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailureBudget int32 `json:"failureBudget,omitempty"`
+
+	// Paused indicates whether the set is paused.
+	// When paused, the set creates no further operations; operations already running are left to
+	// finish.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// OperationSetCluster is the operation of an OperationSet for a single cluster.
+type OperationSetCluster struct {
+	// Name is the name of the management cluster.
+	// +required
+	Name string `json:"name"`
+
+	// Operation is the name of the operation created for the cluster. It is empty until the
+	// operation is created.
+	// +optional
+	Operation string `json:"operation,omitempty"`
+
+	// Phase is the last observed phase of the operation.
+	// +optional
+	Phase OperationPhase `json:"phase,omitempty"`
+}
+
+// OperationSetStatus defines the observed state of OperationSet.
+type OperationSetStatus struct {
+	// Conditions represent the latest available observations of the set's current state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:MaxItems=32
+	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+
+	// LastUpdated identifies when the phase of the set last transitioned.
+	// +optional
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty,omitzero"`
+
+	// Phase is the aggregate phase of the operations of the set.
+	// A Pending set has not selected its clusters yet.
+	// An InProgress set has operations left to create or running.
+	// A Succeeded set ran an operation against every selected cluster within its failure budget.
+	// A Failed set exceeded its failure budget, or selected no clusters.
+	// A DryRunCompleted set ran a dry run against every selected cluster within its failure budget.
+	// +kubebuilder:validation:Enum=Pending;InProgress;Succeeded;Failed;DryRunCompleted
+	// +optional
+	Phase OperationPhase `json:"phase,omitempty"`
+
+	// ObservedGeneration is the latest generation observed by the controller.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Clusters are the selected clusters and their operations, ordered by cluster name.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Clusters []OperationSetCluster `json:"clusters,omitempty"`
+
+	// Total is the number of selected clusters.
+	// +optional
+	Total int32 `json:"total,omitempty"`
+
+	// Running is the number of operations that are Pending or InProgress.
+	// +optional
+	Running int32 `json:"running,omitempty"`
+
+	// Succeeded is the number of operations that succeeded.
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+
+	// Failed is the number of operations that failed.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// Canceled is the number of operations that were canceled.
+	// +optional
+	Canceled int32 `json:"canceled,omitempty"`
+
+	// DryRuns is the number of operations that completed a dry run.
+	// +optional
+	DryRuns int32 `json:"dryRuns,omitempty"`
+}
+
+func (s *OperationSetStatus) SetPhase(phase OperationPhase) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+	s.LastUpdated = metav1.Now()
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=operationsets,scope=Namespaced,categories=operations
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=".spec.template.kind"
+// +kubebuilder:printcolumn:name="Paused",type=string,JSONPath=".spec.paused"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=".status.total"
+// +kubebuilder:printcolumn:name="Succeeded",type=integer,JSONPath=".status.succeeded"
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// OperationSet is the mechanism for running the same operation against every cluster matching a
+// label selector. The set creates one operation per selected cluster in its own namespace, at
+// most spec.maxConcurrent at a time, and rolls their phases up into its status. Operations created
+// by the set carry the OperationSetLabel label and are owned by the set, so deleting the set deletes
+// them; their OperationRecords outlive it.
+type OperationSet struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the desired state of the OperationSet.
+	// +required
+	Spec OperationSetSpec `json:"spec"`
+
+	// Status is the observed state of the OperationSet.
+	// +optional
+	Status OperationSetStatus `json:"status,omitempty"`
+}
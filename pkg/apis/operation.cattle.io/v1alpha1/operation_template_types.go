@@ -0,0 +1,109 @@
+package v1alpha1
+
+import (
+	"github.com/rancher/rancher/pkg/plan"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OperationTemplateRole is a machine role that an OperationTemplateStep can be targeted at.
+// +kubebuilder:validation:Enum=etcd;controlPlane;worker;init
+type OperationTemplateRole string
+
+const (
+	// OperationTemplateRoleEtcd selects the nodes that run etcd.
+	OperationTemplateRoleEtcd OperationTemplateRole = "etcd"
+
+	// OperationTemplateRoleControlPlane selects the nodes that run the control plane.
+	OperationTemplateRoleControlPlane OperationTemplateRole = "controlPlane"
+
+	// OperationTemplateRoleWorker selects the worker nodes.
+	OperationTemplateRoleWorker OperationTemplateRole = "worker"
+
+	// OperationTemplateRoleInit selects the init node of the cluster.
+	OperationTemplateRoleInit OperationTemplateRole = "init"
+)
+
+// OperationTemplateStep is a single step of an OperationTemplate. Every node selected by the step
+// is handed a plan that runs Script exactly once per operation.
+type OperationTemplateStep struct {
+	// Name identifies the step. It is reported as the step of a running CustomOperation and scopes
+	// the idempotency tracking of the script on each node.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	// +required
+	Name string `json:"name"`
+
+	// Selector is the list of roles targeted by the step. A node is selected when it holds any of
+	// the listed roles. Windows nodes are never selected.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	// +required
+	Selector []OperationTemplateRole `json:"selector"`
+
+	// Script is the shell script run on each selected node with /bin/sh.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Script string `json:"script"`
+
+	// Probes are the additional health checks that must pass on each selected node once the
+	// script has run. A "%s" in a probe URL or address is replaced with the loopback address of the
+	// node, and a "%s" in a certificate or key path or exec command with the distro data directory.
+	// +optional
+	Probes map[string]plan.Probe `json:"probes,omitempty"`
+
+	// FailureThreshold is the number of times the script is attempted on a node before the
+	// operation is marked as failed. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold int `json:"failureThreshold,omitempty"`
+
+	// Concurrency is the number of selected nodes that run the step at the same time.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// Supervisor indicates whether the supervisor probe must also pass on server nodes. Steps that
+	// temporarily take down the control plane should leave this unset.
+	// +optional
+	Supervisor bool `json:"supervisor,omitempty"`
+
+	// Timeout is how long a CustomOperation may spend in the step before it is marked as failed,
+	// unless overridden by the stepTimeouts of the operation. The step never times out when unset.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// OperationTemplateSpec defines the steps of an OperationTemplate.
+type OperationTemplateSpec struct {
+	// Steps are run in order by every CustomOperation referencing the template.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	// +required
+	Steps []OperationTemplateStep `json:"steps"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=operationtemplates,scope=Cluster,categories=operations
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+
+// OperationTemplate declares the steps of a day-2 operation that is not built into Rancher.
+// Templates are run against a cluster by creating a CustomOperation that references them. As the
+// steps run arbitrary scripts on the nodes of a cluster, templates are cluster-scoped and should
+// only be writable by administrators.
+type OperationTemplate struct {
+	metav1.TypeMeta `json:",inline"`
+	// metadata is the standard object's metadata.
+	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the steps of the OperationTemplate.
+	// +required
+	Spec OperationTemplateSpec `json:"spec"`
+}
@@ -26,6 +26,40 @@
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// CertificateRotationList is a list of CertificateRotation resources
+type CertificateRotationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []CertificateRotation `json:"items"`
+}
+
+func NewCertificateRotation(namespace, name string, obj CertificateRotation) *CertificateRotation {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("CertificateRotation").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CustomOperationList is a list of CustomOperation resources
+type CustomOperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []CustomOperation `json:"items"`
+}
+
+func NewCustomOperation(namespace, name string, obj CustomOperation) *CustomOperation {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("CustomOperation").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ETCDSnapshotRestoreList is a list of ETCDSnapshotRestore resources
 type ETCDSnapshotRestoreList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -74,3 +108,71 @@ func NewEncryptionKeyRotation(namespace, name string, obj EncryptionKeyRotation)
 	obj.Namespace = namespace
 	return &obj
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KubernetesUpgradeList is a list of KubernetesUpgrade resources
+type KubernetesUpgradeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []KubernetesUpgrade `json:"items"`
+}
+
+func NewKubernetesUpgrade(namespace, name string, obj KubernetesUpgrade) *KubernetesUpgrade {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("KubernetesUpgrade").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OperationRecordList is a list of OperationRecord resources
+type OperationRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []OperationRecord `json:"items"`
+}
+
+func NewOperationRecord(namespace, name string, obj OperationRecord) *OperationRecord {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("OperationRecord").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OperationSetList is a list of OperationSet resources
+type OperationSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []OperationSet `json:"items"`
+}
+
+func NewOperationSet(namespace, name string, obj OperationSet) *OperationSet {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("OperationSet").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// OperationTemplateList is a list of OperationTemplate resources
+type OperationTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []OperationTemplate `json:"items"`
+}
+
+func NewOperationTemplate(namespace, name string, obj OperationTemplate) *OperationTemplate {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("OperationTemplate").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
@@ -28,9 +28,15 @@
 )
 
 var (
+	CertificateRotationResourceName   = "certificaterotations"
+	CustomOperationResourceName       = "customoperations"
 	ETCDSnapshotRestoreResourceName   = "etcdsnapshotrestores"
 	ETCDSnapshotSaveResourceName      = "etcdsnapshotsaves"
 	EncryptionKeyRotationResourceName = "encryptionkeyrotations"
+	KubernetesUpgradeResourceName     = "kubernetesupgrades"
+	OperationRecordResourceName       = "operationrecords"
+	OperationSetResourceName          = "operationsets"
+	OperationTemplateResourceName     = "operationtemplates"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -54,12 +60,24 @@ func Resource(resource string) schema.GroupResource {
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&CertificateRotation{},
+		&CertificateRotationList{},
+		&CustomOperation{},
+		&CustomOperationList{},
 		&ETCDSnapshotRestore{},
 		&ETCDSnapshotRestoreList{},
 		&ETCDSnapshotSave{},
 		&ETCDSnapshotSaveList{},
 		&EncryptionKeyRotation{},
 		&EncryptionKeyRotationList{},
+		&KubernetesUpgrade{},
+		&KubernetesUpgradeList{},
+		&OperationRecord{},
+		&OperationRecordList{},
+		&OperationSet{},
+		&OperationSetList{},
+		&OperationTemplate{},
+		&OperationTemplateList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
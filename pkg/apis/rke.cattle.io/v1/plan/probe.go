@@ -3,4 +3,7 @@
 import planapi "github.com/rancher/rancher/pkg/plan"
 
 type HTTPGetAction = planapi.HTTPGetAction
+type TCPSocketAction = planapi.TCPSocketAction
+type ExecAction = planapi.ExecAction
+type GRPCAction = planapi.GRPCAction
 type Probe = planapi.Probe
@@ -0,0 +1,337 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// bulkIDRefRegexp matches references to resources created earlier in the same bulk request, e.g. "bulkId:qwerty".
+var bulkIDRefRegexp = regexp.MustCompile(`bulkId:([^"/\s]+)`)
+
+// bulkRequest defines a SCIM bulk request.
+type bulkRequest struct {
+	Schemas []string `json:"schemas"`
+	// FailOnErrors is the number of errors after which the remaining operations are not processed.
+	// 0 means that all operations are processed.
+	FailOnErrors int             `json:"failOnErrors"`
+	Operations   []bulkOperation `json:"Operations"`
+}
+
+// bulkOperation defines a single operation in a SCIM bulk request.
+type bulkOperation struct {
+	Method  string          `json:"method"`            // The HTTP method of the operation.
+	BulkID  string          `json:"bulkId,omitempty"`  // A transient identifier of a newly created resource.
+	Version string          `json:"version,omitempty"` // The current version of the resource being changed.
+	Path    string          `json:"path"`              // The resource path relative to the provider's base URL, e.g. "/Users".
+	Data    json.RawMessage `json:"data,omitempty"`    // The resource data as it would appear for a single request.
+}
+
+// bulkResponse defines a SCIM bulk response.
+type bulkResponse struct {
+	Schemas    []string                `json:"schemas"`
+	Operations []bulkOperationResponse `json:"Operations"`
+}
+
+// bulkOperationResponse defines the result of a single operation in a SCIM bulk response.
+type bulkOperationResponse struct {
+	Method   string          `json:"method"`
+	BulkID   string          `json:"bulkId,omitempty"`
+	Version  string          `json:"version,omitempty"`
+	Location string          `json:"location,omitempty"`
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"` // The error response if the operation failed.
+}
+
+// bulkResponseRecorder captures the response of a single bulk operation.
+type bulkResponseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBulkResponseRecorder() *bulkResponseRecorder {
+	return &bulkResponseRecorder{header: http.Header{}}
+}
+
+// Header implements [http.ResponseWriter].
+func (rr *bulkResponseRecorder) Header() http.Header {
+	return rr.header
+}
+
+// Write implements [http.ResponseWriter].
+func (rr *bulkResponseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr.body.Write(b)
+}
+
+// WriteHeader implements [http.ResponseWriter].
+func (rr *bulkResponseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+}
+
+// Bulk processes a SCIM bulk request.
+// Operations are processed sequentially, in the order they are specified, by the same handlers that serve
+// individual requests. Resources created by POST operations can be referenced by subsequent operations
+// using "bulkId:<bulkId>" in their path or data.
+// Returns:
+//   - 200 with the result of each processed operation
+//   - 400 for invalid requests
+//   - 413 if the request exceeds the maximum number of operations or payload size.
+func (s *SCIMServer) Bulk(w http.ResponseWriter, r *http.Request) {
+	logrus.Tracef("scim::Bulk: url %s", r.URL)
+
+	provider := r.PathValue("provider")
+
+	payload := bulkRequest{}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBulkPayloadSize)).Decode(&payload)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, NewError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("The size of the bulk operation exceeds the maxPayloadSize (%d)", maxBulkPayloadSize)))
+			return
+		}
+		logrus.Errorf("scim::Bulk: failed to decode request body: %s", err)
+		writeError(w, NewError(http.StatusBadRequest, "Invalid request body"))
+		return
+	}
+
+	if len(payload.Operations) > maxBulkOperations {
+		writeError(w, NewError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("The number of bulk operations exceeds the maxOperations (%d)", maxBulkOperations)))
+		return
+	}
+	if payload.FailOnErrors < 0 {
+		writeError(w, NewError(http.StatusBadRequest, "failOnErrors must be >= 0", "invalidValue"))
+		return
+	}
+
+	response := bulkResponse{
+		Schemas:    []string{bulkResponseSchemaID},
+		Operations: []bulkOperationResponse{},
+	}
+
+	// bulkIDs maps the bulkId of created resources to their id.
+	bulkIDs := map[string]string{}
+	var errorCount int
+	for _, op := range payload.Operations {
+		result, status := s.processBulkOperation(r, provider, op, bulkIDs)
+		response.Operations = append(response.Operations, result)
+
+		if status >= http.StatusBadRequest {
+			errorCount++
+			if payload.FailOnErrors > 0 && errorCount >= payload.FailOnErrors {
+				logrus.Tracef("scim::Bulk: stopping after %d errors", errorCount)
+				break
+			}
+		}
+	}
+
+	writeResponse(w, response)
+}
+
+// processBulkOperation processes a single bulk operation and returns its result along with its HTTP status code.
+func (s *SCIMServer) processBulkOperation(r *http.Request, provider string, op bulkOperation, bulkIDs map[string]string) (bulkOperationResponse, int) {
+	result := bulkOperationResponse{
+		Method: op.Method,
+		BulkID: op.BulkID,
+	}
+	fail := func(scimErr *Error) (bulkOperationResponse, int) {
+		result.Status = strconv.Itoa(scimErr.Status)
+		if b, err := json.Marshal(scimErr); err == nil {
+			result.Response = b
+		}
+		return result, scimErr.Status
+	}
+
+	method := strings.ToUpper(op.Method)
+	if method == http.MethodPost && op.BulkID == "" {
+		return fail(NewError(http.StatusBadRequest, "bulkId is required for POST operations", "invalidValue"))
+	}
+
+	opPath, scimErr := resolveBulkIDs(op.Path, bulkIDs)
+	if scimErr != nil {
+		return fail(scimErr)
+	}
+	data, scimErr := resolveDataBulkIDs(op.Data, bulkIDs)
+	if scimErr != nil {
+		return fail(scimErr)
+	}
+
+	endpoint, id, _ := strings.Cut(strings.TrimPrefix(opPath, "/"), "/")
+	handler := s.bulkHandler(method, endpoint, id)
+	if handler == nil {
+		return fail(NewError(http.StatusBadRequest, fmt.Sprintf("Unsupported bulk operation: %s %s", op.Method, op.Path), "invalidPath"))
+	}
+
+	req := r.Clone(r.Context())
+	req.Method = method
+	req.URL = &url.URL{Path: URLPrefix + "/" + provider + "/" + strings.TrimPrefix(opPath, "/")}
+	req.RequestURI = req.URL.RequestURI()
+	req.Body = io.NopCloser(strings.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.Header.Del("If-Match")
+	if op.Version != "" {
+		req.Header.Set("If-Match", op.Version)
+	}
+	req.SetPathValue("provider", provider)
+	req.SetPathValue("id", id)
+
+	rec := newBulkResponseRecorder()
+	handler(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	result.Status = strconv.Itoa(rec.status)
+	if rec.status >= http.StatusBadRequest {
+		result.Response = bytes.TrimSpace(rec.body.Bytes())
+		return result, rec.status
+	}
+
+	result.Version = rec.Header().Get("ETag")
+	result.Location = rec.Header().Get("Location")
+	if result.Location == "" && id != "" {
+		result.Location = locationURL(r, provider, endpoint, id)
+	}
+
+	if method == http.MethodPost {
+		created := struct {
+			ID string `json:"id"`
+		}{}
+		if err := json.Unmarshal(rec.body.Bytes(), &created); err != nil || created.ID == "" {
+			logrus.Errorf("scim::Bulk: failed to get the id of the resource created for bulkId %s: %v", op.BulkID, err)
+		} else {
+			bulkIDs[op.BulkID] = created.ID
+		}
+	}
+
+	return result, rec.status
+}
+
+// bulkHandler returns the handler of a bulk operation, or nil if the operation is not supported.
+func (s *SCIMServer) bulkHandler(method, endpoint, id string) http.HandlerFunc {
+	if strings.Contains(id, "/") {
+		return nil
+	}
+
+	switch endpoint {
+	case userEndpoint:
+		switch {
+		case method == http.MethodPost && id == "":
+			return s.CreateUser
+		case method == http.MethodPut && id != "":
+			return s.UpdateUser
+		case method == http.MethodPatch && id != "":
+			return s.PatchUser
+		case method == http.MethodDelete && id != "":
+			return s.DeleteUser
+		}
+	case groupEndpoint:
+		switch {
+		case method == http.MethodPost && id == "":
+			return s.CreateGroup
+		case method == http.MethodPut && id != "":
+			return s.UpdateGroup
+		case method == http.MethodPatch && id != "":
+			return s.PatchGroup
+		case method == http.MethodDelete && id != "":
+			return s.DeleteGroup
+		}
+	}
+
+	return nil
+}
+
+// resolveBulkIDs replaces "bulkId:<bulkId>" references in s with the id of the resources created earlier in the same bulk request.
+// Returns a 409 error if a reference can't be resolved.
+func resolveBulkIDs(s string, bulkIDs map[string]string) (string, *Error) {
+	var unresolved []string
+	resolved := replaceBulkIDs(s, bulkIDs, &unresolved)
+	if len(unresolved) > 0 {
+		return "", unresolvedBulkIDsError(unresolved)
+	}
+
+	return resolved, nil
+}
+
+// resolveDataBulkIDs replaces "bulkId:<bulkId>" references in the data of a bulk operation.
+// Only the "value" and "$ref" attributes, e.g. the values of group members, can reference other resources,
+// so other attributes such as displayName are left unchanged.
+// Returns a 409 error if a reference can't be resolved.
+func resolveDataBulkIDs(data json.RawMessage, bulkIDs map[string]string) (string, *Error) {
+	if !bytes.Contains(data, []byte("bulkId:")) {
+		return string(data), nil
+	}
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return string(data), nil // Invalid data is rejected by the handler of the operation.
+	}
+
+	var unresolved []string
+	value = replaceValueBulkIDs(value, false, bulkIDs, &unresolved)
+	if len(unresolved) > 0 {
+		return "", unresolvedBulkIDsError(unresolved)
+	}
+
+	resolved, err := json.Marshal(value)
+	if err != nil {
+		return "", NewInternalError()
+	}
+
+	return string(resolved), nil
+}
+
+// replaceValueBulkIDs replaces "bulkId:<bulkId>" references in the strings of a decoded JSON value
+// that are held by a "value" or "$ref" attribute, as indicated by isRef for value itself.
+func replaceValueBulkIDs(value any, isRef bool, bulkIDs map[string]string, unresolved *[]string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = replaceValueBulkIDs(item, key == "value" || key == "$ref", bulkIDs, unresolved)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = replaceValueBulkIDs(item, isRef, bulkIDs, unresolved)
+		}
+	case string:
+		if isRef {
+			return replaceBulkIDs(v, bulkIDs, unresolved)
+		}
+	}
+
+	return value
+}
+
+// replaceBulkIDs replaces "bulkId:<bulkId>" references in s and appends the ones that can't be resolved to unresolved.
+func replaceBulkIDs(s string, bulkIDs map[string]string, unresolved *[]string) string {
+	return bulkIDRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		bulkID := strings.TrimPrefix(ref, "bulkId:")
+		if id, ok := bulkIDs[bulkID]; ok {
+			return id
+		}
+		*unresolved = append(*unresolved, bulkID)
+		return ref
+	})
+}
+
+func unresolvedBulkIDsError(unresolved []string) *Error {
+	return NewError(http.StatusConflict, fmt.Sprintf("Unresolved bulkId reference: %s", strings.Join(unresolved, ", ")), "invalidValue")
+}
@@ -0,0 +1,397 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/user/mocks"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestBulk(t *testing.T) {
+	provider := "okta"
+
+	doBulk := func(t *testing.T, srv *SCIMServer, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1-scim/"+provider+"/Bulk", bytes.NewBufferString(body))
+		r.SetPathValue("provider", provider)
+		w := httptest.NewRecorder()
+
+		srv.Bulk(w, r)
+		return w
+	}
+	decodeBulk := func(t *testing.T, w *httptest.ResponseRecorder) bulkResponse {
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp bulkResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, []string{bulkResponseSchemaID}, resp.Schemas)
+		return resp
+	}
+	decodeOpError := func(t *testing.T, op bulkOperationResponse) Error {
+		var resp Error
+		err := json.Unmarshal(op.Response, &resp)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Schemas, errorSchemaID)
+		return resp
+	}
+
+	t.Run("creates a user and resolves bulkId references in later operations", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userID := "u-abc123"
+		enabled := true
+		user := &v3.User{
+			ObjectMeta: metav1.ObjectMeta{Name: userID, ResourceVersion: "10"},
+			Enabled:    &enabled,
+		}
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+		userCache.EXPECT().Get(userID).Return(user, nil)
+
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureUser("okta_user://john.doe", "john.doe").Return(user, nil)
+		userMGR.EXPECT().UserAttributeCreateOrUpdate(userID, provider, []v3.Principal{}, gomock.Any()).Return(nil)
+
+		userClient := fake.NewMockNonNamespacedClientInterface[*v3.User, *v3.UserList](ctrl)
+		userClient.EXPECT().Delete(userID, gomock.Any()).Return(nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			users:     userClient,
+			userMGR:   userMGR,
+			getConfig: testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [
+				{
+					"method": "POST",
+					"path": "/Users",
+					"bulkId": "qwerty",
+					"data": {
+						"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+						"userName": "john.doe"
+					}
+				},
+				{
+					"method": "DELETE",
+					"path": "/Users/bulkId:qwerty"
+				}
+			]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 2)
+
+		wantLocation := "/v1-scim/" + provider + "/Users/" + userID
+
+		assert.Equal(t, "POST", resp.Operations[0].Method)
+		assert.Equal(t, "qwerty", resp.Operations[0].BulkID)
+		assert.Equal(t, "201", resp.Operations[0].Status)
+		assert.Contains(t, resp.Operations[0].Location, wantLocation)
+		assert.Empty(t, resp.Operations[0].Response)
+
+		assert.Equal(t, "DELETE", resp.Operations[1].Method)
+		assert.Equal(t, "204", resp.Operations[1].Status)
+		assert.Contains(t, resp.Operations[1].Location, wantLocation)
+		assert.Empty(t, resp.Operations[1].Response)
+	})
+
+	t.Run("returns the version of changed resources", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupID := "grp-abc123"
+		group := &v3.Group{
+			ObjectMeta:  metav1.ObjectMeta{Name: groupID, ResourceVersion: "10"},
+			DisplayName: "Engineering",
+		}
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(group, nil)
+
+		groupClient := fake.NewMockNonNamespacedClientInterface[*v3.Group, *v3.GroupList](ctrl)
+		groupClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(g *v3.Group) (*v3.Group, error) {
+			assert.Equal(t, "ext-123", g.ExternalID)
+			g = g.DeepCopy()
+			g.ResourceVersion = "11"
+			return g, nil
+		})
+
+		// Members are listed for the precondition and for the response.
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil).Times(2)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			groups:      groupClient,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [
+				{
+					"method": "PATCH",
+					"path": "/Groups/grp-abc123",
+					"version": "W/\"10.e3b0c44298fc1c14\"",
+					"data": {
+						"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+						"Operations": [{"op": "replace", "path": "externalId", "value": "ext-123"}]
+					}
+				}
+			]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 1)
+
+		assert.Equal(t, "200", resp.Operations[0].Status)
+		assert.Equal(t, `W/"11.e3b0c44298fc1c14"`, resp.Operations[0].Version)
+		assert.Contains(t, resp.Operations[0].Location, "/v1-scim/"+provider+"/Groups/"+groupID)
+	})
+
+	t.Run("version mismatch fails the operation", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupID := "grp-abc123"
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(&v3.Group{
+			ObjectMeta:  metav1.ObjectMeta{Name: groupID, ResourceVersion: "10"},
+			DisplayName: "Engineering",
+		}, nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [{"method": "DELETE", "path": "/Groups/grp-abc123", "version": "W/\"9\""}]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 1)
+
+		assert.Equal(t, "412", resp.Operations[0].Status)
+		assert.Equal(t, http.StatusPreconditionFailed, decodeOpError(t, resp.Operations[0]).Status)
+	})
+
+	t.Run("processes all operations without failOnErrors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get("u-missing1").Return(nil, apierrors.NewNotFound(v3.Resource("user"), "u-missing1"))
+		userCache.EXPECT().Get("u-missing2").Return(nil, apierrors.NewNotFound(v3.Resource("user"), "u-missing2"))
+
+		srv := &SCIMServer{
+			userCache: userCache,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [
+				{"method": "DELETE", "path": "/Users/u-missing1"},
+				{"method": "DELETE", "path": "/Users/u-missing2"}
+			]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 2)
+
+		for _, op := range resp.Operations {
+			assert.Equal(t, "404", op.Status)
+			assert.Empty(t, op.Location)
+			assert.Equal(t, http.StatusNotFound, decodeOpError(t, op).Status)
+		}
+	})
+
+	t.Run("stops processing after failOnErrors errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get("u-missing1").Return(nil, apierrors.NewNotFound(v3.Resource("user"), "u-missing1"))
+
+		srv := &SCIMServer{
+			userCache: userCache,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"failOnErrors": 1,
+			"Operations": [
+				{"method": "DELETE", "path": "/Users/u-missing1"},
+				{"method": "DELETE", "path": "/Users/u-missing2"}
+			]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 1)
+		assert.Equal(t, "404", resp.Operations[0].Status)
+	})
+
+	t.Run("unresolved bulkId reference", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [{"method": "DELETE", "path": "/Users/bulkId:unknown"}]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 1)
+
+		assert.Equal(t, "409", resp.Operations[0].Status)
+		opErr := decodeOpError(t, resp.Operations[0])
+		assert.Equal(t, "invalidValue", opErr.ScimType)
+		assert.Contains(t, opErr.Detail, "unknown")
+	})
+
+	t.Run("POST operation without bulkId", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [{"method": "POST", "path": "/Users", "data": {"userName": "john.doe"}}]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 1)
+
+		assert.Equal(t, "400", resp.Operations[0].Status)
+		assert.Equal(t, "invalidValue", decodeOpError(t, resp.Operations[0]).ScimType)
+	})
+
+	t.Run("unsupported operations", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:BulkRequest"],
+			"Operations": [
+				{"method": "GET", "path": "/Users/u-abc123"},
+				{"method": "POST", "path": "/Users/u-abc123", "bulkId": "qwerty"},
+				{"method": "DELETE", "path": "/Users"},
+				{"method": "PUT", "path": "/Schemas/foo"},
+				{"method": "DELETE", "path": "/Users/u-abc123/foo"}
+			]
+		}`
+		resp := decodeBulk(t, doBulk(t, srv, body))
+		require.Len(t, resp.Operations, 5)
+
+		for _, op := range resp.Operations {
+			assert.Equal(t, "400", op.Status)
+			assert.Equal(t, "invalidPath", decodeOpError(t, op).ScimType)
+		}
+	})
+
+	t.Run("too many operations", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		ops := make([]string, maxBulkOperations+1)
+		for i := range ops {
+			ops[i] = fmt.Sprintf(`{"method": "DELETE", "path": "/Users/u-%d"}`, i)
+		}
+		body := `{"Operations": [` + strings.Join(ops, ",") + `]}`
+
+		w := doBulk(t, srv, body)
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("payload too large", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		body := `{"Operations": [{"method": "POST", "path": "/Users", "bulkId": "qwerty", "data": {"userName": "` +
+			strings.Repeat("a", maxBulkPayloadSize) + `"}}]}`
+
+		w := doBulk(t, srv, body)
+		require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("negative failOnErrors", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		w := doBulk(t, srv, `{"failOnErrors": -1, "Operations": []}`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("invalid request body", func(t *testing.T) {
+		srv := &SCIMServer{}
+
+		w := doBulk(t, srv, `{invalid`)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestResolveBulkIDs(t *testing.T) {
+	bulkIDs := map[string]string{
+		"qwerty": "u-abc123",
+		"ytrewq": "u-def456",
+	}
+
+	t.Run("resolves references", func(t *testing.T) {
+		resolved, err := resolveDataBulkIDs(json.RawMessage(`{"members":[{"value":"bulkId:qwerty"},{"value":"bulkId:ytrewq"}]}`), bulkIDs)
+		require.Nil(t, err)
+		assert.JSONEq(t, `{"members":[{"value":"u-abc123"},{"value":"u-def456"}]}`, resolved)
+	})
+
+	t.Run("resolves path references", func(t *testing.T) {
+		resolved, err := resolveBulkIDs("/Users/bulkId:qwerty", bulkIDs)
+		require.Nil(t, err)
+		assert.Equal(t, "/Users/u-abc123", resolved)
+	})
+
+	t.Run("resolves patch values and references", func(t *testing.T) {
+		resolved, err := resolveDataBulkIDs(json.RawMessage(`{
+			"Operations": [
+				{"op": "add", "path": "members", "value": [{"value": "bulkId:qwerty", "$ref": "/Users/bulkId:ytrewq"}]},
+				{"op": "remove", "path": "members[value eq \"u-old\"]"}
+			]
+		}`), bulkIDs)
+		require.Nil(t, err)
+		assert.JSONEq(t, `{
+			"Operations": [
+				{"op": "add", "path": "members", "value": [{"value": "u-abc123", "$ref": "/Users/u-def456"}]},
+				{"op": "remove", "path": "members[value eq \"u-old\"]"}
+			]
+		}`, resolved)
+	})
+
+	t.Run("leaves other attributes unchanged", func(t *testing.T) {
+		data := `{"displayName":"bulkId:qwerty","externalId":"bulkId:unknown","members":[{"value":"bulkId:qwerty","display":"bulkId:qwerty"}],"count":10}`
+		resolved, err := resolveDataBulkIDs(json.RawMessage(data), bulkIDs)
+		require.Nil(t, err)
+		assert.JSONEq(t, `{"displayName":"bulkId:qwerty","externalId":"bulkId:unknown","members":[{"value":"u-abc123","display":"bulkId:qwerty"}],"count":10}`, resolved)
+	})
+
+	t.Run("data without references is unchanged", func(t *testing.T) {
+		data := `{"displayName": "Engineering"}`
+		resolved, err := resolveDataBulkIDs(json.RawMessage(data), bulkIDs)
+		require.Nil(t, err)
+		assert.Equal(t, data, resolved)
+	})
+
+	t.Run("unresolved references", func(t *testing.T) {
+		_, err := resolveDataBulkIDs(json.RawMessage(`{"members":[{"value":"bulkId:qwerty"},{"value":"bulkId:unknown"}]}`), bulkIDs)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusConflict, err.Status)
+		assert.Equal(t, "Unresolved bulkId reference: unknown", err.Detail)
+	})
+
+	t.Run("unresolved path references", func(t *testing.T) {
+		_, err := resolveBulkIDs("/Users/bulkId:unknown", bulkIDs)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusConflict, err.Status)
+	})
+}
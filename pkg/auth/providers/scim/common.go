@@ -26,6 +26,10 @@
 	defaultPageSize = 100
 	// maxPageSize is the maximum allowed page size to prevent excessive memory usage.
 	maxPageSize = 1000
+	// maxBulkOperations is the maximum number of operations allowed in a bulk request.
+	maxBulkOperations = 1000
+	// maxBulkPayloadSize is the maximum size of a bulk request body in bytes.
+	maxBulkPayloadSize = 1048576
 )
 
 // patchOp defines a single operation in a SCIM PATCH request.
@@ -45,6 +45,14 @@ type ServiceProviderConfig struct {
 	SupportFiltering bool
 	// SupportPatch indicates whether or not the SCIM implementation supports patch requests.
 	SupportPatch bool
+	// SupportBulk indicates whether or not the SCIM implementation supports bulk requests.
+	SupportBulk bool
+	// MaxOperations is the maximum number of operations in a bulk request.
+	MaxOperations int
+	// MaxPayloadSize is the maximum payload size of a bulk request in bytes.
+	MaxPayloadSize int
+	// SupportETag indicates whether or not the SCIM implementation supports entity tags (ETags).
+	SupportETag bool
 }
 
 // getRaw returns the raw representation of the ServiceProviderConfig.
@@ -56,9 +64,9 @@ func (c ServiceProviderConfig) getRaw() map[string]any {
 			"supported": c.SupportPatch,
 		},
 		"bulk": map[string]any{
-			"supported":      false,
-			"maxOperations":  1000,
-			"maxPayloadSize": 1048576,
+			"supported":      c.SupportBulk,
+			"maxOperations":  c.MaxOperations,
+			"maxPayloadSize": c.MaxPayloadSize,
 		},
 		"filter": map[string]any{
 			"supported":  c.SupportFiltering,
@@ -71,7 +79,7 @@ func (c ServiceProviderConfig) getRaw() map[string]any {
 			"supported": false,
 		},
 		"etag": map[string]bool{
-			"supported": false,
+			"supported": c.SupportETag,
 		},
 		"authenticationSchemes": c.getRawAuthenticationSchemes(),
 	}
@@ -110,6 +118,10 @@ func (s *SCIMServer) GetServiceProviderConfig(w http.ResponseWriter, r *http.Req
 		MaxResults:       maxPageSize,
 		SupportFiltering: true,
 		SupportPatch:     true,
+		SupportBulk:      true,
+		MaxOperations:    maxBulkOperations,
+		MaxPayloadSize:   maxBulkPayloadSize,
+		SupportETag:      true,
 	}
 
 	writeResponse(w, config.getRaw())
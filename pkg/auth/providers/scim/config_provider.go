@@ -3,10 +3,12 @@
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	wcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -34,6 +36,7 @@
 //	groupIdAttribute:           "displayName" | "externalId"    (default: "displayName")
 //	rateLimitRequestsPerSecond: integer                         (default: 0 = disabled)
 //	rateLimitBurst:             integer                         (default: 10)
+//	userLabelMappings:          "attribute=label,..."           (default: none)
 type providerConfig struct {
 	// Enabled controls whether SCIM provisioning is active for this provider.
 	// The SCIM feature flag must also be enabled; this flag alone is not sufficient.
@@ -65,6 +68,17 @@ type providerConfig struct {
 	// RateLimitBurst is how many requests this provider can make in a quick burst
 	// before the steady-state rate kicks in.
 	RateLimitBurst int
+
+	// UserLabelMappings maps SCIM user attributes to the labels they're copied to on Rancher users,
+	// so that e.g. RBAC automation can select users by department.
+	// Keys are the userLabelAttributes keys of the SCIM attributes.
+	//
+	// Configured as a comma-separated list of "attribute=label" pairs, e.g.
+	// "department=example.com/department,employeeNumber=example.com/employee-number".
+	// Supported attributes: "userName", "externalId", and the Enterprise User "department",
+	// "employeeNumber" and "manager", optionally prefixed with their schema URN.
+	// Labels prefixed with a cattle.io, kubernetes.io or k8s.io domain are reserved.
+	UserLabelMappings map[string]string
 }
 
 func (c *providerConfig) userID(user scimUser) string {
@@ -105,6 +119,83 @@ func defaultProviderConfig() providerConfig {
 	GroupIDExternalID:  true,
 }
 
+// userLabelAttributes maps the SCIM user attributes that can be mapped to labels
+// to the keys of their values returned by userAttributeValues.
+var userLabelAttributes = map[string]string{
+	"username":       "username",
+	"externalid":     "externalid",
+	"department":     departmentKey,
+	"employeenumber": employeeNumberKey,
+	"manager":        managerKey,
+	"manager.value":  managerKey,
+}
+
+// reservedLabelDomains are the domains, along with their subdomains, of the label prefixes
+// that can't be mapped from SCIM attributes, as their labels are managed by Rancher and Kubernetes.
+var reservedLabelDomains = []string{"cattle.io", "kubernetes.io", "k8s.io"}
+
+// reservedLabelPrefix returns the prefix of the label if it's reserved, or an empty string otherwise.
+func reservedLabelPrefix(label string) string {
+	prefix, _, ok := strings.Cut(label, "/")
+	if !ok {
+		return ""
+	}
+	for _, domain := range reservedLabelDomains {
+		if prefix == domain || strings.HasSuffix(prefix, "."+domain) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// parseUserLabelMappings parses a comma-separated list of "attribute=label" pairs.
+func parseUserLabelMappings(value string) (map[string]string, error) {
+	mappings := map[string]string{}
+	labels := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		attribute, label, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q: expected attribute=label", pair)
+		}
+		attribute, label = strings.TrimSpace(attribute), strings.TrimSpace(label)
+
+		path, _, err := stripSchemaURN(attribute, userResource)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute %q: %w", attribute, err)
+		}
+		extraKey, ok := userLabelAttributes[strings.ToLower(path)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported attribute %q", attribute)
+		}
+		if _, ok := mappings[extraKey]; ok {
+			return nil, fmt.Errorf("duplicate attribute %q", attribute)
+		}
+
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label %q: %s", label, strings.Join(errs, "; "))
+		}
+		if prefix := reservedLabelPrefix(label); prefix != "" {
+			return nil, fmt.Errorf("invalid label %q: prefix %s is reserved", label, prefix)
+		}
+		if labels[label] {
+			return nil, fmt.Errorf("duplicate label %q", label)
+		}
+
+		mappings[extraKey] = label
+		labels[label] = true
+	}
+
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+	return mappings, nil
+}
+
 // getProviderConfig loads the SCIM configuration for a provider from the ConfigMap.
 // Returns default config if no ConfigMap exists.
 func getProviderConfig(configMapCache wcorev1.ConfigMapCache, provider string) providerConfig {
@@ -171,6 +262,15 @@ func getProviderConfig(configMapCache wcorev1.ConfigMapCache, provider string) p
 		}
 	}
 
+	if v := cm.Data["userLabelMappings"]; v != "" {
+		mappings, err := parseUserLabelMappings(v)
+		if err != nil {
+			logrus.Errorf("scim::getProviderConfig: invalid userLabelMappings %q in configmap %s, using default: %s", v, name, err)
+		} else {
+			cfg.UserLabelMappings = mappings
+		}
+	}
+
 	return cfg
 }
 
@@ -260,6 +260,40 @@ func TestGetProviderConfig(t *testing.T) {
 			},
 			wantConfig: providerConfig{UserIDAttribute: UserIDUserName, GroupIDAttribute: GroupIDDisplayName, RateLimitRequestsPerSecond: 50, RateLimitBurst: 100},
 		},
+		{
+			name: "user label mappings",
+			setup: func(cache *fake.MockCacheInterface[*corev1.ConfigMap]) {
+				cache.EXPECT().Get(tokenSecretNamespace, "scim-config-azuread").
+					Return(&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "scim-config-azuread"},
+						Data: map[string]string{
+							"userLabelMappings": "department=example.com/department, employeeNumber=example.com/employee-number",
+						},
+					}, nil)
+			},
+			wantConfig: providerConfig{
+				UserIDAttribute:  UserIDUserName,
+				GroupIDAttribute: GroupIDDisplayName,
+				RateLimitBurst:   defaultRateLimitBurst,
+				UserLabelMappings: map[string]string{
+					departmentKey:     "example.com/department",
+					employeeNumberKey: "example.com/employee-number",
+				},
+			},
+		},
+		{
+			name: "invalid user label mappings fall back to defaults",
+			setup: func(cache *fake.MockCacheInterface[*corev1.ConfigMap]) {
+				cache.EXPECT().Get(tokenSecretNamespace, "scim-config-azuread").
+					Return(&corev1.ConfigMap{
+						ObjectMeta: metav1.ObjectMeta{Name: "scim-config-azuread"},
+						Data: map[string]string{
+							"userLabelMappings": "department=example.com/department,title=example.com/title",
+						},
+					}, nil)
+			},
+			wantConfig: defaultProviderConfig(),
+		},
 		{
 			name: "invalid rate limit values fall back to defaults",
 			setup: func(cache *fake.MockCacheInterface[*corev1.ConfigMap]) {
@@ -300,3 +334,111 @@ func TestGroupPrincipalName(t *testing.T) {
 	assert.Equal(t, "okta_group://Engineering", groupPrincipalName("okta", "Engineering"))
 	assert.Equal(t, "azuread_group://obj-456", groupPrincipalName("azuread", "obj-456"))
 }
+
+func TestParseUserLabelMappings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		value       string
+		want        map[string]string
+		errContains string
+	}{
+		{
+			name:  "single mapping",
+			value: "department=example.com/department",
+			want:  map[string]string{departmentKey: "example.com/department"},
+		},
+		{
+			name:  "multiple mappings with whitespace and empty entries",
+			value: " userName = username ,externalId=example.com/external-id,,",
+			want: map[string]string{
+				"username":   "username",
+				"externalid": "example.com/external-id",
+			},
+		},
+		{
+			name:  "attributes are case insensitive",
+			value: "EmployeeNumber=example.com/employee-number",
+			want:  map[string]string{employeeNumberKey: "example.com/employee-number"},
+		},
+		{
+			name:  "attributes with schema URN prefix",
+			value: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value=example.com/manager",
+			want:  map[string]string{managerKey: "example.com/manager"},
+		},
+		{
+			name:  "only empty entries",
+			value: ",,",
+		},
+		{
+			name:        "missing separator",
+			value:       "department",
+			errContains: "expected attribute=label",
+		},
+		{
+			name:        "unsupported attribute",
+			value:       "title=example.com/title",
+			errContains: "unsupported attribute",
+		},
+		{
+			name:        "unrecognized schema URN",
+			value:       "urn:bogus:department=example.com/department",
+			errContains: "invalid attribute",
+		},
+		{
+			name:        "invalid label",
+			value:       "department=example.com/invalid label",
+			errContains: "invalid label",
+		},
+		{
+			name:        "reserved cattle.io prefix",
+			value:       "department=cattle.io/department",
+			errContains: "prefix cattle.io is reserved",
+		},
+		{
+			name:        "reserved cattle.io subdomain prefix",
+			value:       "department=management.cattle.io/department",
+			errContains: "prefix management.cattle.io is reserved",
+		},
+		{
+			name:        "reserved kubernetes.io prefix",
+			value:       "manager=node-role.kubernetes.io/manager",
+			errContains: "prefix node-role.kubernetes.io is reserved",
+		},
+		{
+			name:        "reserved k8s.io prefix",
+			value:       "userName=k8s.io/username",
+			errContains: "prefix k8s.io is reserved",
+		},
+		{
+			name:  "prefix only ending with a reserved domain",
+			value: "department=notcattle.io/department",
+			want:  map[string]string{departmentKey: "notcattle.io/department"},
+		},
+		{
+			name:        "duplicate attribute",
+			value:       "manager=example.com/manager,manager.value=example.com/manager-id",
+			errContains: "duplicate attribute",
+		},
+		{
+			name:        "duplicate label",
+			value:       "department=example.com/label,employeeNumber=example.com/label",
+			errContains: "duplicate label",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseUserLabelMappings(tt.value)
+			if tt.errContains != "" {
+				assert.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -40,10 +40,10 @@ func TestGetServiceProviderConfig(t *testing.T) {
 		require.True(t, ok, "patch should be present and be a map")
 		assert.Equal(t, true, patch["supported"])
 
-		// Verify bulk operations (not supported)
+		// Verify bulk operations
 		bulk, ok := response["bulk"].(map[string]any)
 		require.True(t, ok, "bulk should be present and be a map")
-		assert.Equal(t, false, bulk["supported"])
+		assert.Equal(t, true, bulk["supported"])
 		assert.Equal(t, float64(1000), bulk["maxOperations"]) // JSON numbers are float64
 		assert.Equal(t, float64(1048576), bulk["maxPayloadSize"])
 
@@ -63,10 +63,10 @@ func TestGetServiceProviderConfig(t *testing.T) {
 		require.True(t, ok, "sort should be present and be a map")
 		assert.Equal(t, false, sort["supported"])
 
-		// Verify etag (not supported)
+		// Verify etag support
 		etag, ok := response["etag"].(map[string]any)
 		require.True(t, ok, "etag should be present and be a map")
-		assert.Equal(t, false, etag["supported"])
+		assert.Equal(t, true, etag["supported"])
 
 		// Verify authentication schemes
 		authSchemes, ok := response["authenticationSchemes"].([]any)
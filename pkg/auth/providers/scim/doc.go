@@ -5,6 +5,9 @@
 //
 //   - User resources: list, get, create, update, delete
 //   - Group resources: list, get, create, update (PATCH), delete
+//   - Bulk operations on users and groups following RFC 7644 3.7
+//   - Weak ETags and If-Match preconditions following RFC 7644 3.14
+//   - The Enterprise User extension following RFC 7643 4.3, with optional mapping of user attributes to labels
 //   - Bearer token authentication via Kubernetes secrets
 //   - Pagination following RFC 7644 3.4.2.4
 //
@@ -0,0 +1,247 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Keys of the Enterprise User attributes, as used in user label mappings.
+const (
+	departmentKey     = "department"
+	employeeNumberKey = "employeenumber"
+	managerKey        = "manager"
+)
+
+// enterpriseUserAnnotationPrefix is the prefix of the UserAttribute annotations storing the Enterprise User attributes.
+// They aren't stored in the extras of the provider, which are replaced when the user logs in or is refreshed.
+const enterpriseUserAnnotationPrefix = "scim.authn.management.cattle.io/"
+
+var enterpriseUserKeys = []string{departmentKey, employeeNumberKey, managerKey}
+
+// scimEnterpriseUser represents the SCIM Enterprise User extension (RFC 7643 4.3).
+type scimEnterpriseUser struct {
+	EmployeeNumber string       `json:"employeeNumber"` // A string identifier assigned by the organization.
+	Department     string       `json:"department"`     // The name of a department.
+	Manager        *scimManager `json:"manager"`        // The user's manager.
+}
+
+// scimManager represents the manager of a SCIM Enterprise User.
+type scimManager struct {
+	Value string `json:"value"` // The id of the SCIM User representing the manager.
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+// Azure sends the manager id as a plain string instead of a complex attribute.
+func (m *scimManager) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		m.Value = value
+		return nil
+	}
+
+	var t struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return fmt.Errorf("invalid manager value: %s", data)
+	}
+	m.Value = t.Value
+
+	return nil
+}
+
+// managerID returns the id of the user's manager, if any.
+func (e *scimEnterpriseUser) managerID() string {
+	if e.Manager == nil {
+		return ""
+	}
+	return e.Manager.Value
+}
+
+// managerFromValue extracts the manager id from a PATCH operation value,
+// accepting both a plain string and a complex value with a "value" sub-attribute.
+func managerFromValue(v any) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	var m scimManager
+	if err := m.UnmarshalJSON(data); err != nil {
+		return "", err
+	}
+
+	return m.Value, nil
+}
+
+// enterpriseUserAnnotation returns the UserAttribute annotation storing an Enterprise User attribute of a provider,
+// e.g. "scim.authn.management.cattle.io/okta.department".
+func enterpriseUserAnnotation(provider, key string) string {
+	return enterpriseUserAnnotationPrefix + provider + "." + key
+}
+
+// setEnterpriseAttribute sets an Enterprise User attribute of a provider, removing it if value is empty.
+// Returns true if the UserAttribute was changed.
+func setEnterpriseAttribute(attr *v3.UserAttribute, provider, key, value string) bool {
+	annotation := enterpriseUserAnnotation(provider, key)
+	current, ok := attr.Annotations[annotation]
+	if value == "" {
+		if !ok {
+			return false
+		}
+		delete(attr.Annotations, annotation)
+		return true
+	}
+
+	if ok && current == value {
+		return false
+	}
+	if attr.Annotations == nil {
+		attr.Annotations = map[string]string{}
+	}
+	attr.Annotations[annotation] = value
+	return true
+}
+
+// applyEnterpriseUser stores the Enterprise User attributes of a provider in the UserAttribute.
+// Returns true if the UserAttribute was changed.
+func applyEnterpriseUser(attr *v3.UserAttribute, provider string, e *scimEnterpriseUser) bool {
+	var changed bool
+	if setEnterpriseAttribute(attr, provider, departmentKey, e.Department) {
+		changed = true
+	}
+	if setEnterpriseAttribute(attr, provider, employeeNumberKey, e.EmployeeNumber) {
+		changed = true
+	}
+	if setEnterpriseAttribute(attr, provider, managerKey, e.managerID()) {
+		changed = true
+	}
+	return changed
+}
+
+// enterpriseAttributes returns the Enterprise User attributes of a provider stored in the UserAttribute.
+func enterpriseAttributes(attr *v3.UserAttribute, provider string) map[string][]string {
+	attributes := map[string][]string{}
+	for _, key := range enterpriseUserKeys {
+		if value := attr.Annotations[enterpriseUserAnnotation(provider, key)]; value != "" {
+			attributes[key] = []string{value}
+		}
+	}
+	return attributes
+}
+
+// userAttributeValues returns the attributes of a user that can be mapped to labels,
+// which are the extras of the provider along with the Enterprise User attributes.
+func userAttributeValues(attr *v3.UserAttribute, provider string) map[string][]string {
+	values := maps.Clone(attr.ExtraByProvider[provider])
+	if values == nil {
+		values = map[string][]string{}
+	}
+	maps.Copy(values, enterpriseAttributes(attr, provider))
+	return values
+}
+
+// setEnterpriseUser adds the Enterprise User extension to a SCIM User resource
+// if any of its attributes is set.
+func setEnterpriseUser(resource map[string]any, attributes map[string][]string) {
+	extension := map[string]any{}
+	if department := first(attributes[departmentKey]); department != "" {
+		extension["department"] = department
+	}
+	if employeeNumber := first(attributes[employeeNumberKey]); employeeNumber != "" {
+		extension["employeeNumber"] = employeeNumber
+	}
+	if manager := first(attributes[managerKey]); manager != "" {
+		extension["manager"] = map[string]any{"value": manager}
+	}
+	if len(extension) == 0 {
+		return
+	}
+
+	resource[enterpriseUserSchemaID] = extension
+	if schemas, ok := resource["schemas"].([]string); ok {
+		resource["schemas"] = append(schemas, enterpriseUserSchemaID)
+	}
+}
+
+// managedLabelsAnnotation lists, comma separated, the user labels set from user label mappings, so
+// the labels of mappings removed from the provider config can be removed too.
+const managedLabelsAnnotation = "scim.authn.management.cattle.io/managed-labels"
+
+// applyUserLabels sets the user labels mapped from SCIM attributes in the provider config.
+// The label is removed if the attribute has no value or its value is not a valid label value, and so
+// are the labels previously set from mappings that no longer exist.
+// Returns true if the labels of the user, or its managedLabelsAnnotation, were changed.
+func applyUserLabels(user *v3.User, values map[string][]string, cfg providerConfig) bool {
+	var changed bool
+	mapped := slices.Collect(maps.Values(cfg.UserLabelMappings))
+	for _, label := range managedLabels(user) {
+		if _, ok := user.Labels[label]; ok && !slices.Contains(mapped, label) {
+			delete(user.Labels, label)
+			changed = true
+		}
+	}
+
+	var managed []string
+	for key, label := range cfg.UserLabelMappings {
+		value := first(values[key])
+		if value != "" {
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				logrus.Warnf("scim::applyUserLabels: value %q of %s for user %s is not a valid label value: %s", value, key, user.Name, strings.Join(errs, "; "))
+				value = ""
+			}
+		}
+
+		if value == "" {
+			if _, ok := user.Labels[label]; ok {
+				delete(user.Labels, label)
+				changed = true
+			}
+			continue
+		}
+
+		managed = append(managed, label)
+		if user.Labels[label] != value {
+			if user.Labels == nil {
+				user.Labels = map[string]string{}
+			}
+			user.Labels[label] = value
+			changed = true
+		}
+	}
+
+	slices.Sort(managed)
+	if value := strings.Join(managed, ","); value != user.Annotations[managedLabelsAnnotation] {
+		if value == "" {
+			delete(user.Annotations, managedLabelsAnnotation)
+		} else {
+			if user.Annotations == nil {
+				user.Annotations = map[string]string{}
+			}
+			user.Annotations[managedLabelsAnnotation] = value
+		}
+		changed = true
+	}
+
+	return changed
+}
+
+// managedLabels returns the user labels listed in the managedLabelsAnnotation of the user.
+func managedLabels(user *v3.User) []string {
+	value := user.Annotations[managedLabelsAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
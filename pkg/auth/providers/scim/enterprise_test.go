@@ -0,0 +1,237 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestScimManagerUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		desc    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{desc: "complex value", data: `{"value":"u-manager"}`, want: "u-manager"},
+		{desc: "plain string", data: `"u-manager"`, want: "u-manager"},
+		{desc: "empty object", data: `{}`},
+		{desc: "invalid value", data: `42`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var m scimManager
+			err := json.Unmarshal([]byte(tt.data), &m)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Value)
+		})
+	}
+}
+
+func TestManagerFromValue(t *testing.T) {
+	manager, err := managerFromValue("u-manager")
+	require.NoError(t, err)
+	assert.Equal(t, "u-manager", manager)
+
+	manager, err = managerFromValue(map[string]any{"value": "u-manager"})
+	require.NoError(t, err)
+	assert.Equal(t, "u-manager", manager)
+
+	manager, err = managerFromValue(nil)
+	require.NoError(t, err)
+	assert.Empty(t, manager)
+
+	_, err = managerFromValue(true)
+	assert.Error(t, err)
+}
+
+func TestSetEnterpriseAttribute(t *testing.T) {
+	attr := &v3.UserAttribute{}
+	annotation := "scim.authn.management.cattle.io/okta.department"
+
+	assert.True(t, setEnterpriseAttribute(attr, "okta", departmentKey, "Engineering"))
+	assert.Equal(t, "Engineering", attr.Annotations[annotation])
+
+	assert.False(t, setEnterpriseAttribute(attr, "okta", departmentKey, "Engineering"))
+
+	assert.True(t, setEnterpriseAttribute(attr, "okta", departmentKey, ""))
+	assert.NotContains(t, attr.Annotations, annotation)
+
+	assert.False(t, setEnterpriseAttribute(attr, "okta", departmentKey, ""))
+}
+
+func TestApplyEnterpriseUser(t *testing.T) {
+	attr := &v3.UserAttribute{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				enterpriseUserAnnotation("okta", employeeNumberKey): "1234",
+				enterpriseUserAnnotation("azuread", departmentKey):  "Sales",
+			},
+		},
+		ExtraByProvider: map[string]map[string][]string{
+			"okta": {"username": {"john.doe"}},
+		},
+	}
+
+	changed := applyEnterpriseUser(attr, "okta", &scimEnterpriseUser{
+		Department: "Engineering",
+		Manager:    &scimManager{Value: "u-manager"},
+	})
+	assert.True(t, changed)
+	assert.Equal(t, map[string]string{
+		enterpriseUserAnnotation("okta", departmentKey):    "Engineering",
+		enterpriseUserAnnotation("okta", managerKey):       "u-manager",
+		enterpriseUserAnnotation("azuread", departmentKey): "Sales",
+	}, attr.Annotations)
+	assert.Equal(t, map[string][]string{"username": {"john.doe"}}, attr.ExtraByProvider["okta"])
+	assert.Equal(t, map[string][]string{
+		departmentKey: {"Engineering"},
+		managerKey:    {"u-manager"},
+	}, enterpriseAttributes(attr, "okta"))
+
+	changed = applyEnterpriseUser(attr, "okta", &scimEnterpriseUser{
+		Department: "Engineering",
+		Manager:    &scimManager{Value: "u-manager"},
+	})
+	assert.False(t, changed)
+}
+
+func TestSetEnterpriseUser(t *testing.T) {
+	t.Run("adds the extension and its schema", func(t *testing.T) {
+		resource := map[string]any{"schemas": []string{userSchemaID}}
+
+		setEnterpriseUser(resource, map[string][]string{
+			departmentKey:     {"Engineering"},
+			employeeNumberKey: {"1234"},
+			managerKey:        {"u-manager"},
+		})
+
+		assert.Equal(t, []string{userSchemaID, enterpriseUserSchemaID}, resource["schemas"])
+		assert.Equal(t, map[string]any{
+			"department":     "Engineering",
+			"employeeNumber": "1234",
+			"manager":        map[string]any{"value": "u-manager"},
+		}, resource[enterpriseUserSchemaID])
+	})
+
+	t.Run("no-op without Enterprise User attributes", func(t *testing.T) {
+		resource := map[string]any{"schemas": []string{userSchemaID}}
+
+		setEnterpriseUser(resource, map[string][]string{"username": {"john.doe"}})
+
+		assert.Equal(t, []string{userSchemaID}, resource["schemas"])
+		assert.NotContains(t, resource, enterpriseUserSchemaID)
+	})
+}
+
+func TestApplyUserLabels(t *testing.T) {
+	cfg := providerConfig{
+		UserLabelMappings: map[string]string{
+			departmentKey: "example.com/department",
+			managerKey:    "example.com/manager",
+		},
+	}
+
+	t.Run("sets the mapped labels", func(t *testing.T) {
+		user := &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-abc123"}}
+
+		changed := applyUserLabels(user, map[string][]string{
+			departmentKey: {"Engineering"},
+			managerKey:    {"u-manager"},
+		}, cfg)
+		assert.True(t, changed)
+		assert.Equal(t, map[string]string{
+			"example.com/department": "Engineering",
+			"example.com/manager":    "u-manager",
+		}, user.Labels)
+
+		changed = applyUserLabels(user, map[string][]string{
+			departmentKey: {"Engineering"},
+			managerKey:    {"u-manager"},
+		}, cfg)
+		assert.False(t, changed)
+	})
+
+	t.Run("removes labels of missing or invalid values", func(t *testing.T) {
+		user := &v3.User{ObjectMeta: metav1.ObjectMeta{
+			Name: "u-abc123",
+			Labels: map[string]string{
+				"example.com/department": "Engineering",
+				"example.com/manager":    "u-manager",
+				"other":                  "value",
+			},
+		}}
+
+		changed := applyUserLabels(user, map[string][]string{
+			departmentKey: {"Research & Development"},
+		}, cfg)
+		assert.True(t, changed)
+		assert.Equal(t, map[string]string{"other": "value"}, user.Labels)
+	})
+
+	t.Run("removes labels of removed mappings", func(t *testing.T) {
+		user := &v3.User{ObjectMeta: metav1.ObjectMeta{
+			Name: "u-abc123",
+			Labels: map[string]string{
+				"example.com/department": "Engineering",
+				"example.com/title":      "Engineer",
+				"other":                  "value",
+			},
+			Annotations: map[string]string{managedLabelsAnnotation: "example.com/department,example.com/title"},
+		}}
+
+		changed := applyUserLabels(user, map[string][]string{
+			departmentKey: {"Engineering"},
+			managerKey:    {"u-manager"},
+		}, cfg)
+		assert.True(t, changed)
+		assert.Equal(t, map[string]string{
+			"example.com/department": "Engineering",
+			"example.com/manager":    "u-manager",
+			"other":                  "value",
+		}, user.Labels)
+		assert.Equal(t, "example.com/department,example.com/manager", user.Annotations[managedLabelsAnnotation])
+
+		changed = applyUserLabels(user, map[string][]string{departmentKey: {"Engineering"}}, defaultProviderConfig())
+		assert.True(t, changed)
+		assert.Equal(t, map[string]string{"other": "value"}, user.Labels)
+		assert.NotContains(t, user.Annotations, managedLabelsAnnotation)
+	})
+
+	t.Run("no-op without mappings", func(t *testing.T) {
+		user := &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-abc123"}}
+
+		changed := applyUserLabels(user, map[string][]string{departmentKey: {"Engineering"}}, defaultProviderConfig())
+		assert.False(t, changed)
+		assert.Nil(t, user.Labels)
+	})
+}
+
+func TestUserAttributeValues(t *testing.T) {
+	attr := &v3.UserAttribute{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				enterpriseUserAnnotation("okta", departmentKey): "Engineering",
+			},
+		},
+		ExtraByProvider: map[string]map[string][]string{
+			"okta": {"username": {"john.doe"}},
+		},
+	}
+
+	assert.Equal(t, map[string][]string{
+		"username":    {"john.doe"},
+		departmentKey: {"Engineering"},
+	}, userAttributeValues(attr, "okta"))
+	assert.Equal(t, map[string][]string{"username": {"john.doe"}}, attr.ExtraByProvider["okta"])
+	assert.Empty(t, userAttributeValues(attr, "azuread"))
+}
@@ -0,0 +1,96 @@
+package scim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+)
+
+// weakETag returns a weak entity tag derived from the resourceVersions of the objects
+// backing a SCIM resource. Returns an empty string if none of them has a resourceVersion.
+func weakETag(resourceVersions ...string) string {
+	if strings.Join(resourceVersions, "") == "" {
+		return ""
+	}
+	return `W/"` + strings.Join(resourceVersions, ".") + `"`
+}
+
+// userETag returns the entity tag of a SCIM User.
+// The user's SCIM attributes are stored on the UserAttribute, so its resourceVersion
+// is part of the tag along with the one of the User.
+func userETag(user *v3.User, attr *v3.UserAttribute) string {
+	var attrVersion string
+	if attr != nil {
+		attrVersion = attr.ResourceVersion
+	}
+	return weakETag(user.ResourceVersion, attrVersion)
+}
+
+// groupETag returns the entity tag of a SCIM Group.
+// Group members are stored on the UserAttributes of the members rather than on the Group,
+// so a digest of the members is part of the tag along with the resourceVersion of the Group.
+func groupETag(group *v3.Group, members []scimMember) string {
+	if group.ResourceVersion == "" {
+		return ""
+	}
+	return weakETag(group.ResourceVersion, membersDigest(members))
+}
+
+// membersDigest returns a short digest of the set of group members, independent of their order.
+func membersDigest(members []scimMember) string {
+	values := make([]string, 0, len(members))
+	for _, member := range members {
+		values = append(values, member.Value)
+	}
+	slices.Sort(values)
+	values = slices.Compact(values)
+
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// setVersion sets the version in the metadata of a SCIM resource.
+func setVersion(resource map[string]any, etag string) {
+	if etag == "" {
+		return
+	}
+	if meta, ok := resource["meta"].(map[string]any); ok {
+		meta["version"] = etag
+	}
+}
+
+// setETag sets the version in the metadata of a SCIM resource and the ETag response header.
+func setETag(w http.ResponseWriter, resource map[string]any, etag string) {
+	if etag == "" {
+		return
+	}
+	setVersion(resource, etag)
+	w.Header().Set("ETag", etag)
+}
+
+// checkPrecondition evaluates the If-Match request header against the current entity tag of a resource.
+// Entity tags are compared regardless of the weak indicator, as described in RFC 7644 3.14.
+// Returns a 412 error if none of the tags matches.
+func checkPrecondition(r *http.Request, etag string) *Error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+
+	current := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		if current != "" && strings.TrimPrefix(tag, "W/") == current {
+			return nil
+		}
+	}
+
+	return NewError(http.StatusPreconditionFailed, "Resource has changed on the server")
+}
@@ -0,0 +1,111 @@
+package scim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWeakETag(t *testing.T) {
+	tests := []struct {
+		desc     string
+		versions []string
+		want     string
+	}{
+		{desc: "single version", versions: []string{"123"}, want: `W/"123"`},
+		{desc: "multiple versions", versions: []string{"123", "456"}, want: `W/"123.456"`},
+		{desc: "partially empty versions", versions: []string{"123", ""}, want: `W/"123."`},
+		{desc: "empty versions", versions: []string{"", ""}, want: ""},
+		{desc: "no versions", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, weakETag(tt.versions...))
+		})
+	}
+}
+
+func TestUserETag(t *testing.T) {
+	user := &v3.User{ObjectMeta: metav1.ObjectMeta{Name: "u-abc123", ResourceVersion: "10"}}
+
+	assert.Equal(t, `W/"10.20"`, userETag(user, &v3.UserAttribute{
+		ObjectMeta: metav1.ObjectMeta{Name: "u-abc123", ResourceVersion: "20"},
+	}))
+	assert.Equal(t, `W/"10."`, userETag(user, nil))
+}
+
+func TestGroupETag(t *testing.T) {
+	group := &v3.Group{ObjectMeta: metav1.ObjectMeta{Name: "grp-abc123", ResourceVersion: "10"}}
+	memberA := scimMember{Value: "u-a", Display: "a", Type: userResource}
+	memberB := scimMember{Value: "u-b", Display: "b", Type: userResource}
+
+	assert.Equal(t, `W/"10.e3b0c44298fc1c14"`, groupETag(group, nil))
+	assert.Equal(t, `W/"10.f2a25fa23a90c98c"`, groupETag(group, []scimMember{memberA}))
+	assert.Equal(t, `W/"10.86732bd2e4f5023a"`, groupETag(group, []scimMember{memberA, memberB}))
+	// The order of members and duplicates don't change the tag.
+	assert.Equal(t, `W/"10.86732bd2e4f5023a"`, groupETag(group, []scimMember{memberB, memberA, memberB}))
+	assert.Empty(t, groupETag(&v3.Group{}, []scimMember{memberA}))
+}
+
+func TestSetETag(t *testing.T) {
+	t.Run("sets meta.version and the ETag header", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		resource := map[string]any{"meta": map[string]any{}}
+
+		setETag(w, resource, `W/"10"`)
+
+		assert.Equal(t, `W/"10"`, w.Header().Get("ETag"))
+		assert.Equal(t, `W/"10"`, resource["meta"].(map[string]any)["version"])
+	})
+
+	t.Run("no-op without a version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		resource := map[string]any{"meta": map[string]any{}}
+
+		setETag(w, resource, "")
+
+		assert.Empty(t, w.Header().Get("ETag"))
+		assert.NotContains(t, resource["meta"], "version")
+	})
+}
+
+func TestCheckPrecondition(t *testing.T) {
+	tests := []struct {
+		desc    string
+		ifMatch string
+		etag    string
+		wantErr bool
+	}{
+		{desc: "no If-Match header", etag: `W/"10"`},
+		{desc: "matching weak tag", ifMatch: `W/"10"`, etag: `W/"10"`},
+		{desc: "matching strong tag", ifMatch: `"10"`, etag: `W/"10"`},
+		{desc: "matching one of multiple tags", ifMatch: `W/"9", W/"10"`, etag: `W/"10"`},
+		{desc: "wildcard", ifMatch: "*", etag: `W/"10"`},
+		{desc: "mismatching tag", ifMatch: `W/"9"`, etag: `W/"10"`, wantErr: true},
+		{desc: "resource without a version", ifMatch: `W/"10"`, wantErr: true},
+		{desc: "empty tag doesn't match a resource without a version", ifMatch: `""`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/v1-scim/okta/Users/u-abc123", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			err := checkPrecondition(r, tt.etag)
+			if tt.wantErr {
+				require.NotNil(t, err)
+				assert.Equal(t, http.StatusPreconditionFailed, err.Status)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
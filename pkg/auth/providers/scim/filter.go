@@ -250,10 +250,11 @@ func tokenize(filter string) ([]string, error) {
 }
 
 // knownResourceSchemaURNs maps schema URN prefixes to their resource type.
-// Only resource schemas are valid as attribute path prefixes per RFC 7644 section 3.10.
+// Only resource schemas and their extensions are valid as attribute path prefixes per RFC 7644 section 3.10.
 var knownResourceSchemaURNs = map[string]string{
-	userSchemaID:  userResource,
-	groupSchemaID: groupResource,
+	userSchemaID:           userResource,
+	groupSchemaID:          groupResource,
+	enterpriseUserSchemaID: userResource,
 }
 
 // stripSchemaURN removes a recognized schema URN prefix from an attribute path
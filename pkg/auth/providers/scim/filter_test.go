@@ -328,6 +328,20 @@ func TestStripSchemaURN(t *testing.T) {
 			wantAttrPath:     "displayName",
 			wantResourceType: "Group",
 		},
+		{
+			name:                 "enterprise user URN prefix",
+			path:                 "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department",
+			expectedResourceType: "User",
+			wantAttrPath:         "department",
+			wantResourceType:     "User",
+		},
+		{
+			name:                 "enterprise user URN mismatched resource type",
+			path:                 "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department",
+			expectedResourceType: "Group",
+			wantErr:              true,
+			errContains:          "does not match",
+		},
 		{
 			name:                 "user URN matching resource type",
 			path:                 "urn:ietf:params:scim:schemas:core:2.0:User:active",
@@ -95,14 +95,12 @@ func (s *SCIMServer) ListGroups(w http.ResponseWriter, r *http.Request) {
 	// Collect all matching resources (needed to compute totalResults).
 	var allResources []any
 	if len(groups) > 0 {
-		var uniqueGroups map[string][]scimMember
-		if !excludeMembers {
-			uniqueGroups, err = s.getAllRancherGroupMembers(provider)
-			if err != nil {
-				logrus.Errorf("scim::ListGroups: %s", err)
-				writeError(w, NewInternalError())
-				return
-			}
+		// Members are part of the entity tag of a group, so they're needed even if excluded from the response.
+		uniqueGroups, err := s.getAllRancherGroupMembers(provider)
+		if err != nil {
+			logrus.Errorf("scim::ListGroups: %s", err)
+			writeError(w, NewInternalError())
+			return
 		}
 
 		for _, group := range groups {
@@ -137,6 +135,7 @@ func (s *SCIMServer) ListGroups(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				members = []scimMember{}
 			}
+			setVersion(resource, groupETag(group, members))
 			if !excludeMembers {
 				resource["members"] = members
 			}
@@ -239,6 +238,7 @@ func (s *SCIMServer) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	if members == nil {
 		members = []scimMember{}
 	}
+	setETag(w, resource, groupETag(group, members))
 	resource["members"] = members
 
 	w.Header().Set("Location", location)
@@ -282,14 +282,12 @@ func (s *SCIMServer) GetGroup(w http.ResponseWriter, r *http.Request) {
 	}
 	gpn := groupPrincipalName(provider, gid)
 
-	var members []scimMember
-	if !excludeMembers {
-		members, err = s.getRancherGroupMembers(provider, gpn)
-		if err != nil {
-			logrus.Errorf("scim::GetGroups: %s", err)
-			writeError(w, NewInternalError())
-			return
-		}
+	// Members are part of the entity tag of the group, so they're needed even if excluded from the response.
+	members, err := s.getRancherGroupMembers(provider, gpn)
+	if err != nil {
+		logrus.Errorf("scim::GetGroups: %s", err)
+		writeError(w, NewInternalError())
+		return
 	}
 
 	resource := map[string]any{
@@ -306,6 +304,7 @@ func (s *SCIMServer) GetGroup(w http.ResponseWriter, r *http.Request) {
 	if members == nil {
 		members = []scimMember{}
 	}
+	setETag(w, resource, groupETag(group, members))
 	if !excludeMembers {
 		resource["members"] = members
 	}
@@ -316,7 +315,8 @@ func (s *SCIMServer) GetGroup(w http.ResponseWriter, r *http.Request) {
 // UpdateGroup updates a group.
 // Returns:
 //   - 200 on success
-//   - 400 for invalid requests.
+//   - 400 for invalid requests
+//   - 412 if the If-Match header doesn't match the current version of the group.
 func (s *SCIMServer) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	logrus.Tracef("scim::UpdateGroup: url %s", r.URL)
 
@@ -355,6 +355,11 @@ func (s *SCIMServer) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scimErr := s.checkGroupPrecondition(r, provider, group); scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
 	if group.ExternalID != payload.ExternalID && cfg.GroupIDAttribute == GroupIDExternalID {
 		writeError(w, NewError(http.StatusBadRequest, "externalId cannot be changed when it is used as the group principal identifier", "mutability"))
 		return
@@ -406,6 +411,7 @@ func (s *SCIMServer) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	if members == nil {
 		members = []scimMember{}
 	}
+	setETag(w, resource, groupETag(group, members))
 	resource["members"] = members
 
 	w.Header().Set("Location", location)
@@ -419,7 +425,8 @@ func (s *SCIMServer) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 //
 // Returns:
 //   - 200 on success
-//   - 400 for invalid requests.
+//   - 400 for invalid requests
+//   - 412 if the If-Match header doesn't match the current version of the group.
 func (s *SCIMServer) PatchGroup(w http.ResponseWriter, r *http.Request) {
 	logrus.Infof("scim::PatchGroup: url %s", r.URL)
 
@@ -438,6 +445,11 @@ func (s *SCIMServer) PatchGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scimErr := s.checkGroupPrecondition(r, provider, group); scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
 	payload := struct {
 		Operations []patchOp `json:"Operations"`
 		Schemas    []string  `json:"schemas"`
@@ -619,6 +631,7 @@ func (s *SCIMServer) PatchGroup(w http.ResponseWriter, r *http.Request) {
 			"location":     location,
 		},
 	}
+	setETag(w, resource, groupETag(group, members))
 
 	w.Header().Set("Location", location)
 	writeResponse(w, resource)
@@ -628,6 +641,7 @@ func (s *SCIMServer) PatchGroup(w http.ResponseWriter, r *http.Request) {
 // Returns:
 //   - 204 on successful deletion
 //   - 404 if the group is not found
+//   - 412 if the If-Match header doesn't match the current version of the group.
 func (s *SCIMServer) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	logrus.Infof("scim::DeleteGroup: url %s", r.URL)
 
@@ -646,6 +660,11 @@ func (s *SCIMServer) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scimErr := s.checkGroupPrecondition(r, provider, group); scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
 	cfg := s.getConfig(provider)
 	gid := cfg.groupID(group.DisplayName, group.ExternalID)
 	if gid == "" {
@@ -671,6 +690,28 @@ func (s *SCIMServer) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	writeResponse(w, noPayload, http.StatusNoContent)
 }
 
+// checkGroupPrecondition evaluates the If-Match request header against the current entity tag of a group.
+// The members of the group are only retrieved if the header is set.
+func (s *SCIMServer) checkGroupPrecondition(r *http.Request, provider string, group *v3.Group) *Error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	cfg := s.getConfig(provider)
+	gid := cfg.groupID(group.DisplayName, group.ExternalID)
+	if gid == "" {
+		logrus.Errorf("scim::checkGroupPrecondition: group %s has empty %s configured as groupIdAttribute", group.Name, cfg.GroupIDAttribute)
+		return NewInternalError()
+	}
+	members, err := s.getRancherGroupMembers(provider, groupPrincipalName(provider, gid))
+	if err != nil {
+		logrus.Errorf("scim::checkGroupPrecondition: %s", err)
+		return NewInternalError()
+	}
+
+	return checkPrecondition(r, groupETag(group, members))
+}
+
 // getAllRancherGroupMembers retrieves all groups and their members for the specified provider.
 func (s *SCIMServer) getAllRancherGroupMembers(provider string) (map[string][]scimMember, error) {
 	list, err := s.userCache.List(labels.Everything())
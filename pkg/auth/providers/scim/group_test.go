@@ -1794,9 +1794,12 @@ func TestListGroupsWithExternalIdFilter(t *testing.T) {
 
 	groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
 	groupsCache.EXPECT().List(labels.Set{authProviderLabel: provider}.AsSelector()).Return(groups, nil)
+	userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+	userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
 
 	srv := &SCIMServer{
 		groupsCache: groupsCache,
+		userCache:   userCache,
 		getConfig:   testDefaultGetConfig,
 	}
 
@@ -2429,11 +2432,13 @@ func TestGetGroup(t *testing.T) {
 
 		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
 		groupsCache.EXPECT().Get(groupID).Return(group, nil)
-
-		// No userCache calls expected when members excluded
+		// Members are still needed for the version of the group.
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
 
 		srv := &SCIMServer{
 			groupsCache: groupsCache,
+			userCache:   userCache,
 			getConfig:   testDefaultGetConfig,
 		}
 
@@ -3429,3 +3434,189 @@ func TestDeleteGroup(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, resp.Status)
 	})
 }
+
+func TestGroupETags(t *testing.T) {
+	provider := "okta"
+	groupID := "grp-abc123"
+
+	newGroup := func() *v3.Group {
+		return &v3.Group{
+			ObjectMeta:  metav1.ObjectMeta{Name: groupID, ResourceVersion: "10"},
+			DisplayName: "Engineering",
+		}
+	}
+	newRequest := func(method, body, ifMatch string) *http.Request {
+		r := httptest.NewRequest(method, "/v1-scim/"+provider+"/Groups/"+groupID, bytes.NewBufferString(body))
+		r.SetPathValue("provider", provider)
+		r.SetPathValue("id", groupID)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return r
+	}
+
+	t.Run("get returns the version of the group", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		w := httptest.NewRecorder()
+		srv.GetGroup(w, newRequest(http.MethodGet, "", ""))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `W/"10.e3b0c44298fc1c14"`, w.Header().Get("ETag"))
+
+		var resp map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		meta, ok := resp["meta"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, `W/"10.e3b0c44298fc1c14"`, meta["version"])
+	})
+
+	t.Run("update with a matching If-Match returns the new version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		groupClient := fake.NewMockNonNamespacedClientInterface[*v3.Group, *v3.GroupList](ctrl)
+		groupClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(g *v3.Group) (*v3.Group, error) {
+			g = g.DeepCopy()
+			g.ResourceVersion = "11"
+			return g, nil
+		})
+		// Members are listed for the precondition and to sync them.
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil).Times(2)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			groups:      groupClient,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"id": "grp-abc123",
+			"displayName": "Engineering",
+			"externalId": "ext-123"
+		}`
+		w := httptest.NewRecorder()
+		srv.UpdateGroup(w, newRequest(http.MethodPut, body, `W/"10.e3b0c44298fc1c14"`))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `W/"11.e3b0c44298fc1c14"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("update fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"id": "grp-abc123",
+			"displayName": "Engineering",
+			"externalId": "ext-123"
+		}`
+		w := httptest.NewRecorder()
+		srv.UpdateGroup(w, newRequest(http.MethodPut, body, `W/"9"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("update fails when the members changed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{
+			{ObjectMeta: metav1.ObjectMeta{Name: "u-user1"}},
+		}, nil)
+		userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+		userAttributeCache.EXPECT().Get("u-user1").Return(&v3.UserAttribute{
+			ObjectMeta: metav1.ObjectMeta{Name: "u-user1"},
+			GroupPrincipals: map[string]v3.Principals{
+				provider: {Items: []v3.Principal{
+					{ObjectMeta: metav1.ObjectMeta{Name: groupPrincipalName(provider, "Engineering")}},
+				}},
+			},
+		}, nil)
+
+		srv := &SCIMServer{
+			groupsCache:        groupsCache,
+			userCache:          userCache,
+			userAttributeCache: userAttributeCache,
+			getConfig:          testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"id": "grp-abc123",
+			"displayName": "Engineering"
+		}`
+		w := httptest.NewRecorder()
+		// The version of the group without members.
+		srv.UpdateGroup(w, newRequest(http.MethodPut, body, `W/"10.e3b0c44298fc1c14"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("patch fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "externalId", "value": "ext-123"}]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchGroup(w, newRequest(http.MethodPatch, body, `W/"9"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("delete fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		groupsCache := fake.NewMockNonNamespacedCacheInterface[*v3.Group](ctrl)
+		groupsCache.EXPECT().Get(groupID).Return(newGroup(), nil)
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+
+		srv := &SCIMServer{
+			groupsCache: groupsCache,
+			userCache:   userCache,
+			getConfig:   testDefaultGetConfig,
+		}
+
+		w := httptest.NewRecorder()
+		srv.DeleteGroup(w, newRequest(http.MethodDelete, "", `W/"9"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+}
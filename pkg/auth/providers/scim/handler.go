@@ -71,5 +71,8 @@ func NewHandler(scaledContext *config.ScaledContext) http.Handler {
 	r.HandleFunc("PATCH "+URLPrefix+"/{provider}/Groups/{id}", middlewares(srv.PatchGroup))
 	r.HandleFunc("DELETE "+URLPrefix+"/{provider}/Groups/{id}", middlewares(srv.DeleteGroup))
 
+	// Bulk endpoint
+	r.HandleFunc("POST "+URLPrefix+"/{provider}/Bulk", middlewares(srv.Bulk))
+
 	return r
 }
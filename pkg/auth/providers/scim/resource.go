@@ -44,6 +44,14 @@ type Meta struct {
 	Version string `json:"version,omitempty"`
 }
 
+// SchemaExtension specifies a schema extension of a resource type.
+type SchemaExtension struct {
+	// Schema is the URI of the schema extension.
+	Schema string `json:"schema"`
+	// Required specifies whether the schema extension is required for the resource type.
+	Required bool `json:"required"`
+}
+
 // ResourceType specifies the metadata about a resource type.
 type ResourceType struct {
 	// ID is the resource type's server unique id. This is often the same value as the "name" attribute.
@@ -60,8 +68,8 @@ type ResourceType struct {
 	// Schemas is a list of the resource type's supported schemas.
 	Schemas []string `json:"schemas"`
 	// SchemaExtensions is a list of the resource type's schema extensions.
-	// SchemaExtensions []SchemaExtension
-	Meta Meta `json:"meta"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             Meta              `json:"meta"`
 }
 
 // userResourceType defines the SCIM User resource type.
@@ -72,6 +80,9 @@ type ResourceType struct {
 	Endpoint:    "/" + userEndpoint,
 	Schema:      userSchemaID,
 	Schemas:     []string{resourceSchemaID},
+	SchemaExtensions: []SchemaExtension{
+		{Schema: enterpriseUserSchemaID},
+	},
 	Meta: Meta{
 		ResourceType: resourceTypeResource,
 	},
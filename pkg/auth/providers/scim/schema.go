@@ -9,12 +9,15 @@
 
 // Well known SCIM Schema URNs.
 const (
-	listSchemaID     = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
-	groupSchemaID    = "urn:ietf:params:scim:schemas:core:2.0:Group"
-	userSchemaID     = "urn:ietf:params:scim:schemas:core:2.0:User"
-	errorSchemaID    = "urn:ietf:params:scim:api:messages:2.0:Error"
-	resourceSchemaID = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
-	schemaSchemaID   = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	listSchemaID           = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	groupSchemaID          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	userSchemaID           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	enterpriseUserSchemaID = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	errorSchemaID          = "urn:ietf:params:scim:api:messages:2.0:Error"
+	resourceSchemaID       = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	schemaSchemaID         = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	bulkRequestSchemaID    = "urn:ietf:params:scim:api:messages:2.0:BulkRequest"
+	bulkResponseSchemaID   = "urn:ietf:params:scim:api:messages:2.0:BulkResponse"
 )
 
 // Schema defines a SCIM schema.
@@ -183,9 +186,58 @@ type SchemaAttribute struct {
 	},
 }
 
+// EnterpriseUserSchema defines the SCIM Enterprise User schema extension (RFC 7643 4.3).
+var EnterpriseUserSchema = Schema{
+	Schemas:     []string{schemaSchemaID},
+	ID:          enterpriseUserSchemaID,
+	Name:        "EnterpriseUser",
+	Description: "Enterprise User",
+	Attributes: []SchemaAttribute{
+		{
+			Name:        "employeeNumber",
+			Type:        "string",
+			MultiValued: false,
+			Required:    false,
+			Mutability:  "readWrite",
+			Returned:    "default",
+		},
+		{
+			Name:        "department",
+			Type:        "string",
+			MultiValued: false,
+			Required:    false,
+			Mutability:  "readWrite",
+			Returned:    "default",
+		},
+		{
+			Name:        "manager",
+			Type:        "complex",
+			MultiValued: false,
+			Required:    false,
+			Mutability:  "readWrite",
+			Returned:    "default",
+			SubAttributes: []SchemaAttribute{
+				{
+					Name:        "value",
+					Type:        "string",
+					MultiValued: false,
+					Required:    false,
+					CaseExact:   true,
+					Mutability:  "readWrite",
+					Returned:    "default",
+				},
+			},
+		},
+	},
+	Meta: Meta{
+		ResourceType: schemaResource,
+	},
+}
+
 var schemaRegistry = map[string]Schema{
-	UserSchema.ID:  UserSchema,
-	GroupSchema.ID: GroupSchema,
+	UserSchema.ID:           UserSchema,
+	GroupSchema.ID:          GroupSchema,
+	EnterpriseUserSchema.ID: EnterpriseUserSchema,
 }
 
 // ListSchemas lists supported SCIM schemas.
@@ -71,7 +71,8 @@ type scimUser struct {
 		Value   string `json:"value"`
 		Primary Bool   `json:"primary"`
 	} `json:"emails"`
-	Meta Meta `json:"meta"` // The resource metadata.
+	EnterpriseUser *scimEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"` // The Enterprise User extension.
+	Meta           Meta                `json:"meta"`                                                                 // The resource metadata.
 }
 
 // ListUsers returns a list of users.
@@ -173,6 +174,8 @@ func (s *SCIMServer) ListUsers(w http.ResponseWriter, r *http.Request) {
 				"location":     locationURL(r, provider, userEndpoint, user.Name),
 			},
 		}
+		setVersion(resource, userETag(user, attr))
+		setEnterpriseUser(resource, enterpriseAttributes(attr, provider))
 
 		primaryEmail := first(attr.ExtraByProvider[provider]["email"])
 		if primaryEmail != "" {
@@ -333,6 +336,38 @@ type reprovisionMatch struct {
 		return
 	}
 
+	values, enterprise := extras, map[string][]string{}
+	if payload.EnterpriseUser != nil {
+		attr, err := s.userAttributes.Get(user.Name, metav1.GetOptions{})
+		if err != nil {
+			logrus.Errorf("scim::CreateUser: failed to get user attributes for %s: %s", user.Name, err)
+			writeError(w, NewInternalError())
+			return
+		}
+		if applyEnterpriseUser(attr, provider, payload.EnterpriseUser) {
+			attr, err = s.userAttributes.Update(attr)
+			if err != nil {
+				logrus.Errorf("scim::CreateUser: failed to update user attributes for %s: %s", user.Name, err)
+				writeError(w, NewInternalError())
+				return
+			}
+		}
+		values, enterprise = userAttributeValues(attr, provider), enterpriseAttributes(attr, provider)
+	}
+
+	if len(cfg.UserLabelMappings) > 0 {
+		labeled := user.DeepCopy()
+		if applyUserLabels(labeled, values, cfg) {
+			updated, err := s.users.Update(labeled)
+			if err != nil {
+				logrus.Errorf("scim::CreateUser: failed to update labels of user %s: %s", user.Name, err)
+				writeError(w, NewInternalError())
+				return
+			}
+			user = updated
+		}
+	}
+
 	location := locationURL(r, provider, userEndpoint, user.Name)
 	response := map[string]any{
 		"schemas":    []string{userSchemaID},
@@ -354,6 +389,7 @@ type reprovisionMatch struct {
 			},
 		}
 	}
+	setEnterpriseUser(response, enterprise)
 
 	w.Header().Set("Location", location)
 	writeResponse(w, response, http.StatusCreated)
@@ -378,6 +414,9 @@ func (s *SCIMServer) reprovisionUser(w http.ResponseWriter, r *http.Request, pro
 	if primaryEmail != "" {
 		attr.ExtraByProvider[provider]["email"] = []string{primaryEmail}
 	}
+	if payload.EnterpriseUser != nil {
+		applyEnterpriseUser(attr, provider, payload.EnterpriseUser)
+	}
 
 	if _, err := s.userAttributes.Update(attr); err != nil {
 		logrus.Errorf("scim::reprovisionUser: failed to update user attributes for %s: %s", user.Name, err)
@@ -394,6 +433,7 @@ func (s *SCIMServer) reprovisionUser(w http.ResponseWriter, r *http.Request, pro
 	if user.DisplayName != displayName {
 		user.DisplayName = displayName
 	}
+	applyUserLabels(user, userAttributeValues(attr, provider), s.getConfig(provider))
 
 	if _, err := s.users.Update(user); err != nil {
 		logrus.Errorf("scim::reprovisionUser: failed to update user %s: %s", user.Name, err)
@@ -422,6 +462,7 @@ func (s *SCIMServer) reprovisionUser(w http.ResponseWriter, r *http.Request, pro
 			},
 		}
 	}
+	setEnterpriseUser(response, enterpriseAttributes(attr, provider))
 
 	w.Header().Set("Location", location)
 	writeResponse(w, response, http.StatusOK)
@@ -474,6 +515,8 @@ func (s *SCIMServer) GetUser(w http.ResponseWriter, r *http.Request) {
 			"location":     locationURL(r, provider, userEndpoint, user.Name),
 		},
 	}
+	setETag(w, response, userETag(user, attr))
+	setEnterpriseUser(response, enterpriseAttributes(attr, provider))
 
 	primaryEmail := first(attr.ExtraByProvider[provider]["email"])
 	if primaryEmail != "" {
@@ -530,7 +573,8 @@ func (s *SCIMServer) checkUserConflict(provider, userName, externalID, excludeUs
 //   - 200 on success
 //   - 400 for invalid requests
 //   - 404 if the user is not found or is a system user
-//   - 409 if attempting to deprovision the default admin user.
+//   - 409 if attempting to deprovision the default admin user
+//   - 412 if the If-Match header doesn't match the current version of the user.
 func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	logrus.Tracef("scim::UpdateUser: url %s", r.URL)
 
@@ -574,6 +618,11 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scimErr := checkPrecondition(r, userETag(user, attr)); scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
 	cfg := s.getConfig(provider)
 
 	var shouldUpdateAttr, shouldUpdateUser bool
@@ -602,6 +651,9 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		attr.ExtraByProvider[provider]["externalid"] = []string{payload.ExternalID}
 		shouldUpdateAttr = true
 	}
+	if payload.EnterpriseUser != nil && applyEnterpriseUser(attr, provider, payload.EnterpriseUser) {
+		shouldUpdateAttr = true
+	}
 
 	if scimErr := s.checkUserConflict(provider, changedUserName, changedExternalID, user.Name); scimErr != nil {
 		writeError(w, scimErr)
@@ -619,6 +671,11 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		user.Enabled = &payloadActive
 		shouldUpdateUser = true
 	}
+	labeled := user.DeepCopy()
+	if applyUserLabels(labeled, userAttributeValues(attr, provider), cfg) {
+		user = labeled
+		shouldUpdateUser = true
+	}
 	if shouldUpdateAttr {
 		if attrNeedsCreate {
 			attr, err = s.userAttributes.Create(attr)
@@ -632,11 +689,13 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if shouldUpdateUser {
-		if _, err = s.users.Update(user); err != nil {
+		updated, err := s.users.Update(user)
+		if err != nil {
 			logrus.Errorf("scim::UpdateUser: failed to update user %s: %s", user.Name, err)
 			writeError(w, NewInternalError())
 			return
 		}
+		user = updated
 	}
 
 	location := locationURL(r, provider, userEndpoint, user.Name)
@@ -652,6 +711,8 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 			"location":     location,
 		},
 	}
+	setETag(w, response, userETag(user, attr))
+	setEnterpriseUser(response, enterpriseAttributes(attr, provider))
 
 	primaryEmail := first(attr.ExtraByProvider[provider]["email"])
 	if primaryEmail != "" {
@@ -671,7 +732,8 @@ func (s *SCIMServer) UpdateUser(w http.ResponseWriter, r *http.Request) {
 // Returns:
 //   - 204 on successful deletion
 //   - 404 if the user is not found or is a system user
-//   - 409 if attempting to delete the default admin user.
+//   - 409 if attempting to delete the default admin user
+//   - 412 if the If-Match header doesn't match the current version of the user.
 func (s *SCIMServer) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	logrus.Tracef("scim::DeleteUser: url %s", r.URL)
 	// provider := r.PathValue("provider")
@@ -694,6 +756,19 @@ func (s *SCIMServer) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Header.Get("If-Match") != "" {
+		attr, err := s.userAttributeCache.Get(user.Name)
+		if err != nil && !apierrors.IsNotFound(err) {
+			logrus.Errorf("scim::DeleteUser: failed to get user attributes for %s: %s", user.Name, err)
+			writeError(w, NewInternalError())
+			return
+		}
+		if scimErr := checkPrecondition(r, userETag(user, attr)); scimErr != nil {
+			writeError(w, scimErr)
+			return
+		}
+	}
+
 	if user.IsDefaultAdmin() {
 		writeError(w, NewError(http.StatusConflict, "Cannot delete default admin user"))
 		return
@@ -709,13 +784,17 @@ func (s *SCIMServer) DeleteUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // PatchUser applies partial modifications to a user.
-// Currently supports the "replace" operation for updating active status, externalId,
-// and primary email address.
+// Currently supports
+//   - the "replace" and "add" operations for updating active status, externalId,
+//     primary email address and the Enterprise User attributes.
+//   - the "remove" operation for the Enterprise User attributes.
+//
 // Returns:
 //   - 200 on success
 //   - 400 for invalid requests
 //   - 404 if the user is not found or is a system user
-//   - 409 if attempting to deprovision the default admin user.
+//   - 409 if attempting to deprovision the default admin user
+//   - 412 if the If-Match header doesn't match the current version of the user.
 func (s *SCIMServer) PatchUser(w http.ResponseWriter, r *http.Request) {
 	logrus.Tracef("scim::PatchUser: url %s", r.URL)
 
@@ -757,6 +836,11 @@ func (s *SCIMServer) PatchUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scimErr := checkPrecondition(r, userETag(user, attr)); scimErr != nil {
+		writeError(w, scimErr)
+		return
+	}
+
 	attr = attr.DeepCopy()
 	user = user.DeepCopy()
 
@@ -786,11 +870,23 @@ func (s *SCIMServer) PatchUser(w http.ResponseWriter, r *http.Request) {
 			if updateUser {
 				shouldUpdateUser = true
 			}
+		case "remove":
+			updateAttr, scimErr := applyPatchRemoveUser(provider, attr, op)
+			if scimErr != nil {
+				writeError(w, scimErr)
+				return
+			}
+			if updateAttr {
+				shouldUpdateAttr = true
+			}
 		default:
 			writeError(w, NewError(http.StatusBadRequest, fmt.Sprintf("Unsupported patch operation: %s", op.Op)))
 			return
 		}
 	}
+	if applyUserLabels(user, userAttributeValues(attr, provider), cfg) {
+		shouldUpdateUser = true
+	}
 
 	var changedUserName, changedExternalID string
 	if newUserName := first(attr.ExtraByProvider[provider]["username"]); newUserName != origUserName {
@@ -817,11 +913,13 @@ func (s *SCIMServer) PatchUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if shouldUpdateUser {
-		if _, err = s.users.Update(user); err != nil {
+		updated, err := s.users.Update(user)
+		if err != nil {
 			logrus.Errorf("scim::PatchUser: failed to update user %s: %s", user.Name, err)
 			writeError(w, NewInternalError())
 			return
 		}
+		user = updated
 	}
 
 	location := locationURL(r, provider, userEndpoint, user.Name)
@@ -837,6 +935,8 @@ func (s *SCIMServer) PatchUser(w http.ResponseWriter, r *http.Request) {
 			"location":     location,
 		},
 	}
+	setETag(w, response, userETag(user, attr))
+	setEnterpriseUser(response, enterpriseAttributes(attr, provider))
 
 	primaryEmail := first(attr.ExtraByProvider[provider]["email"])
 	if primaryEmail != "" {
@@ -866,6 +966,24 @@ func applyPatchUser(provider string, attr *v3.UserAttribute, user *v3.User, op p
 			if name == "" {
 				return false, false, NewError(http.StatusBadRequest, "empty attribute name in bulk operation")
 			}
+			if strings.EqualFold(name, enterpriseUserSchemaID) {
+				// The Enterprise User extension attributes are nested under the schema URN.
+				updateAttr, updateUser, err := applyPatchUser(provider, attr, user, patchOp{
+					Op:    "replace",
+					Path:  enterpriseUserSchemaID,
+					Value: value,
+				}, cfg)
+				if err != nil {
+					return false, false, fmt.Errorf("failed to apply %s operation: %w", op.Op, err)
+				}
+				if updateAttr {
+					shouldUpdateAttr = true
+				}
+				if updateUser {
+					shouldUpdateUser = true
+				}
+				continue
+			}
 			updateAttr, updateUser, err := applyPatchUser(provider, attr, user, patchOp{
 				Op:    "replace",
 				Path:  name,
@@ -884,6 +1002,29 @@ func applyPatchUser(provider string, attr *v3.UserAttribute, user *v3.User, op p
 		return shouldUpdateAttr, shouldUpdateUser, nil
 	}
 
+	if strings.EqualFold(op.Path, enterpriseUserSchemaID) {
+		fields, ok := op.Value.(map[string]any)
+		if !ok {
+			return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid value for %s: %v", enterpriseUserSchemaID, op.Value))
+		}
+
+		var shouldUpdateAttr bool
+		for name, value := range fields {
+			updateAttr, _, err := applyPatchUser(provider, attr, user, patchOp{
+				Op:    op.Op,
+				Path:  enterpriseUserSchemaID + ":" + name,
+				Value: value,
+			}, cfg)
+			if err != nil {
+				return false, false, err
+			}
+			if updateAttr {
+				shouldUpdateAttr = true
+			}
+		}
+		return shouldUpdateAttr, false, nil
+	}
+
 	path, _, err := stripSchemaURN(op.Path, userResource)
 	if err != nil {
 		return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid path %q: %s", op.Path, err))
@@ -951,9 +1092,57 @@ func applyPatchUser(provider string, attr *v3.UserAttribute, user *v3.User, op p
 			attr.ExtraByProvider[provider]["email"] = []string{email}
 			updateAttr = true
 		}
+	case "department":
+		department, ok := op.Value.(string)
+		if !ok {
+			return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid value for department: %v", op.Value))
+		}
+
+		updateAttr = setEnterpriseAttribute(attr, provider, departmentKey, department)
+	case "employeenumber":
+		employeeNumber, ok := op.Value.(string)
+		if !ok {
+			return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid value for employeeNumber: %v", op.Value))
+		}
+
+		updateAttr = setEnterpriseAttribute(attr, provider, employeeNumberKey, employeeNumber)
+	case "manager", "manager.value":
+		manager, err := managerFromValue(op.Value)
+		if err != nil {
+			return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid value for manager: %v", op.Value))
+		}
+
+		updateAttr = setEnterpriseAttribute(attr, provider, managerKey, manager)
 	default:
 		return false, false, NewError(http.StatusBadRequest, fmt.Sprintf("Unsupported patch path: %s", op.Path))
 	}
 
 	return updateAttr, updateUser, nil
 }
+
+// applyPatchRemoveUser applies a SCIM PATCH remove operation to a user.
+// Only the optional Enterprise User attributes can be removed.
+func applyPatchRemoveUser(provider string, attr *v3.UserAttribute, op patchOp) (bool, *Error) {
+	if op.Path == "" {
+		return false, NewError(http.StatusBadRequest, "path is required for remove operation", "noTarget")
+	}
+
+	path, _, err := stripSchemaURN(op.Path, userResource)
+	if err != nil {
+		return false, NewError(http.StatusBadRequest, fmt.Sprintf("Invalid path %q: %s", op.Path, err))
+	}
+
+	var key string
+	switch strings.ToLower(path) {
+	case "department":
+		key = departmentKey
+	case "employeenumber":
+		key = employeeNumberKey
+	case "manager", "manager.value":
+		key = managerKey
+	default:
+		return false, NewError(http.StatusBadRequest, fmt.Sprintf("Unsupported remove path: %s", op.Path))
+	}
+
+	return setEnterpriseAttribute(attr, provider, key, ""), nil
+}
@@ -3941,3 +3941,516 @@ func TestPatchUser(t *testing.T) {
 		require.Equal(t, http.StatusConflict, w.Code)
 	})
 }
+
+func TestUserETags(t *testing.T) {
+	provider := "okta"
+	userID := "u-abc123"
+
+	newUser := func() *v3.User {
+		enabled := true
+		return &v3.User{
+			ObjectMeta: metav1.ObjectMeta{Name: userID, ResourceVersion: "10"},
+			Enabled:    &enabled,
+		}
+	}
+	newAttr := func() *v3.UserAttribute {
+		return &v3.UserAttribute{
+			ObjectMeta: metav1.ObjectMeta{Name: userID, ResourceVersion: "20"},
+			ExtraByProvider: map[string]map[string][]string{
+				provider: {
+					"username":    {"john.doe"},
+					"externalid":  {"ext-12345"},
+					"principalid": {provider + "_user://john.doe"},
+				},
+			},
+		}
+	}
+	newRequest := func(method, body, ifMatch string) *http.Request {
+		r := httptest.NewRequest(method, "/v1-scim/"+provider+"/Users/"+userID, bytes.NewBufferString(body))
+		r.SetPathValue("provider", provider)
+		r.SetPathValue("id", userID)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return r
+	}
+	assertVersion := func(t *testing.T, w *httptest.ResponseRecorder, want string) {
+		assert.Equal(t, want, w.Header().Get("ETag"))
+
+		var resp map[string]any
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		meta, ok := resp["meta"].(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, want, meta["version"])
+	}
+
+	t.Run("get returns the version of the user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			userMGR:   userMGR,
+		}
+
+		w := httptest.NewRecorder()
+		srv.GetUser(w, newRequest(http.MethodGet, "", ""))
+		require.Equal(t, http.StatusOK, w.Code)
+		assertVersion(t, w, `W/"10.20"`)
+	})
+
+	t.Run("list returns the version of the users", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{newUser()}, nil)
+		userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+		userAttributeCache.EXPECT().Get(userID).Return(newAttr(), nil)
+
+		srv := &SCIMServer{
+			userCache:          userCache,
+			userAttributeCache: userAttributeCache,
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/v1-scim/"+provider+"/Users", nil)
+		r.SetPathValue("provider", provider)
+		w := httptest.NewRecorder()
+		srv.ListUsers(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp listResponse
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.Len(t, resp.Resources, 1)
+		meta := resp.Resources[0].(map[string]any)["meta"].(map[string]any)
+		assert.Equal(t, `W/"10.20"`, meta["version"])
+	})
+
+	t.Run("patch with a matching If-Match returns the new version", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+		userClient := fake.NewMockNonNamespacedClientInterface[*v3.User, *v3.UserList](ctrl)
+		userClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(u *v3.User) (*v3.User, error) {
+			u = u.DeepCopy()
+			u.ResourceVersion = "11"
+			return u, nil
+		})
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			users:     userClient,
+			userMGR:   userMGR,
+			getConfig: testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "active", "value": false}]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchUser(w, newRequest(http.MethodPatch, body, `W/"10.20"`))
+		require.Equal(t, http.StatusOK, w.Code)
+		assertVersion(t, w, `W/"11.20"`)
+	})
+
+	t.Run("patch fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			userMGR:   userMGR,
+			getConfig: testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "active", "value": false}]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchUser(w, newRequest(http.MethodPatch, body, `W/"10.19"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("update fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			userMGR:   userMGR,
+			getConfig: testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "john.doe",
+			"externalId": "ext-12345",
+			"active": false
+		}`
+		w := httptest.NewRecorder()
+		srv.UpdateUser(w, newRequest(http.MethodPut, body, `W/"9.20"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		var resp Error
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Status)
+	})
+
+	t.Run("delete with a matching If-Match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+		userAttributeCache.EXPECT().Get(userID).Return(newAttr(), nil)
+		userClient := fake.NewMockNonNamespacedClientInterface[*v3.User, *v3.UserList](ctrl)
+		userClient.EXPECT().Delete(userID, gomock.Any()).Return(nil)
+
+		srv := &SCIMServer{
+			userCache:          userCache,
+			userAttributeCache: userAttributeCache,
+			users:              userClient,
+		}
+
+		w := httptest.NewRecorder()
+		srv.DeleteUser(w, newRequest(http.MethodDelete, "", `W/"10.20"`))
+		require.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("delete fails when If-Match doesn't match", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+		userAttributeCache.EXPECT().Get(userID).Return(newAttr(), nil)
+
+		srv := &SCIMServer{
+			userCache:          userCache,
+			userAttributeCache: userAttributeCache,
+		}
+
+		w := httptest.NewRecorder()
+		srv.DeleteUser(w, newRequest(http.MethodDelete, "", `W/"10.19"`))
+		require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("delete fails when getting the user attributes fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+		userAttributeCache.EXPECT().Get(userID).Return(nil, fmt.Errorf("some error"))
+
+		srv := &SCIMServer{
+			userCache:          userCache,
+			userAttributeCache: userAttributeCache,
+		}
+
+		w := httptest.NewRecorder()
+		srv.DeleteUser(w, newRequest(http.MethodDelete, "", `W/"10.20"`))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestEnterpriseUser(t *testing.T) {
+	provider := "okta"
+	userID := "u-abc123"
+
+	newUser := func() *v3.User {
+		enabled := true
+		return &v3.User{
+			ObjectMeta: metav1.ObjectMeta{Name: userID},
+			Enabled:    &enabled,
+		}
+	}
+	newAttr := func() *v3.UserAttribute {
+		return &v3.UserAttribute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: userID,
+				Annotations: map[string]string{
+					enterpriseUserAnnotation(provider, departmentKey):     "Engineering",
+					enterpriseUserAnnotation(provider, employeeNumberKey): "1234",
+					enterpriseUserAnnotation(provider, managerKey):        "u-manager",
+				},
+			},
+			ExtraByProvider: map[string]map[string][]string{
+				provider: {
+					"username":    {"john.doe"},
+					"externalid":  {"ext-12345"},
+					"principalid": {provider + "_user://john.doe"},
+				},
+			},
+		}
+	}
+	newRequest := func(method, body string) *http.Request {
+		r := httptest.NewRequest(method, "/v1-scim/"+provider+"/Users/"+userID, bytes.NewBufferString(body))
+		r.SetPathValue("provider", provider)
+		r.SetPathValue("id", userID)
+		return r
+	}
+	labelsConfig := func(string) providerConfig {
+		cfg := defaultProviderConfig()
+		cfg.UserLabelMappings = map[string]string{departmentKey: "example.com/department"}
+		return cfg
+	}
+
+	t.Run("get returns the extension", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			userMGR:   userMGR,
+		}
+
+		w := httptest.NewRecorder()
+		srv.GetUser(w, newRequest(http.MethodGet, ""))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp scimUser
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, []string{userSchemaID, enterpriseUserSchemaID}, resp.Schemas)
+		require.NotNil(t, resp.EnterpriseUser)
+		assert.Equal(t, "Engineering", resp.EnterpriseUser.Department)
+		assert.Equal(t, "1234", resp.EnterpriseUser.EmployeeNumber)
+		assert.Equal(t, "u-manager", resp.EnterpriseUser.managerID())
+	})
+
+	t.Run("patch the extension attributes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+		userAttributeClient := fake.NewMockNonNamespacedClientInterface[*v3.UserAttribute, *v3.UserAttributeList](ctrl)
+		userAttributeClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(attr *v3.UserAttribute) (*v3.UserAttribute, error) {
+			assert.Equal(t, map[string]string{
+				enterpriseUserAnnotation(provider, departmentKey):     "Research",
+				enterpriseUserAnnotation(provider, employeeNumberKey): "5678",
+			}, attr.Annotations)
+			assert.Equal(t, map[string][]string{
+				"username":    {"john.doe"},
+				"externalid":  {"ext-12345"},
+				"principalid": {provider + "_user://john.doe"},
+			}, attr.ExtraByProvider[provider])
+			return attr, nil
+		})
+
+		srv := &SCIMServer{
+			userCache:      userCache,
+			userAttributes: userAttributeClient,
+			userMGR:        userMGR,
+			getConfig:      testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [
+				{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Research"},
+				{"op": "replace", "value": {"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "5678"}}},
+				{"op": "remove", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager"}
+			]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchUser(w, newRequest(http.MethodPatch, body))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp scimUser
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.NotNil(t, resp.EnterpriseUser)
+		assert.Equal(t, "Research", resp.EnterpriseUser.Department)
+		assert.Equal(t, "5678", resp.EnterpriseUser.EmployeeNumber)
+		assert.Nil(t, resp.EnterpriseUser.Manager)
+	})
+
+	t.Run("patch updates the mapped labels", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+		userAttributeClient := fake.NewMockNonNamespacedClientInterface[*v3.UserAttribute, *v3.UserAttributeList](ctrl)
+		userAttributeClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(attr *v3.UserAttribute) (*v3.UserAttribute, error) {
+			return attr, nil
+		})
+		userClient := fake.NewMockNonNamespacedClientInterface[*v3.User, *v3.UserList](ctrl)
+		userClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(u *v3.User) (*v3.User, error) {
+			assert.Equal(t, map[string]string{"example.com/department": "Research"}, u.Labels)
+			return u, nil
+		})
+
+		srv := &SCIMServer{
+			userCache:      userCache,
+			users:          userClient,
+			userAttributes: userAttributeClient,
+			userMGR:        userMGR,
+			getConfig:      labelsConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Research"}]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchUser(w, newRequest(http.MethodPatch, body))
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("patch remove requires a path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+
+		srv := &SCIMServer{
+			userCache: userCache,
+			userMGR:   userMGR,
+			getConfig: testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "remove"}]
+		}`
+		w := httptest.NewRecorder()
+		srv.PatchUser(w, newRequest(http.MethodPatch, body))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var resp Error
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		assert.Equal(t, "noTarget", resp.ScimType)
+	})
+
+	t.Run("update replaces the extension", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().Get(userID).Return(newUser(), nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureAndGetUserAttribute(userID).Return(newAttr(), false, nil)
+		userAttributeClient := fake.NewMockNonNamespacedClientInterface[*v3.UserAttribute, *v3.UserAttributeList](ctrl)
+		userAttributeClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(attr *v3.UserAttribute) (*v3.UserAttribute, error) {
+			assert.Equal(t, "Research", attr.Annotations[enterpriseUserAnnotation(provider, departmentKey)])
+			assert.NotContains(t, attr.Annotations, enterpriseUserAnnotation(provider, employeeNumberKey))
+			assert.Equal(t, "u-other", attr.Annotations[enterpriseUserAnnotation(provider, managerKey)])
+			return attr, nil
+		})
+
+		srv := &SCIMServer{
+			userCache:      userCache,
+			userAttributes: userAttributeClient,
+			userMGR:        userMGR,
+			getConfig:      testDefaultGetConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+			"userName": "john.doe",
+			"externalId": "ext-12345",
+			"active": true,
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+				"department": "Research",
+				"manager": "u-other"
+			}
+		}`
+		w := httptest.NewRecorder()
+		srv.UpdateUser(w, newRequest(http.MethodPut, body))
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("create stores the extension outside of the provider extras", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+		userCache.EXPECT().List(labels.Everything()).Return([]*v3.User{}, nil)
+		userMGR := mocks.NewMockManager(ctrl)
+		userMGR.EXPECT().EnsureUser(provider+"_user://john.doe", "john.doe").Return(newUser(), nil)
+		// The extension isn't part of the extras, which are replaced when the user logs in.
+		extras := map[string][]string{
+			"username":    {"john.doe"},
+			"externalid":  {"ext-12345"},
+			"principalid": {provider + "_user://john.doe"},
+		}
+		userMGR.EXPECT().UserAttributeCreateOrUpdate(userID, provider, []v3.Principal{}, extras).Return(nil)
+		userAttributeClient := fake.NewMockNonNamespacedClientInterface[*v3.UserAttribute, *v3.UserAttributeList](ctrl)
+		userAttributeClient.EXPECT().Get(userID, gomock.Any()).Return(&v3.UserAttribute{
+			ObjectMeta:      metav1.ObjectMeta{Name: userID},
+			ExtraByProvider: map[string]map[string][]string{provider: extras},
+		}, nil)
+		userAttributeClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(attr *v3.UserAttribute) (*v3.UserAttribute, error) {
+			assert.Equal(t, map[string]string{
+				enterpriseUserAnnotation(provider, departmentKey): "Research",
+				enterpriseUserAnnotation(provider, managerKey):    "u-manager",
+			}, attr.Annotations)
+			assert.Equal(t, extras, attr.ExtraByProvider[provider])
+			return attr, nil
+		})
+		userClient := fake.NewMockNonNamespacedClientInterface[*v3.User, *v3.UserList](ctrl)
+		userClient.EXPECT().Update(gomock.Any()).DoAndReturn(func(u *v3.User) (*v3.User, error) {
+			assert.Equal(t, map[string]string{"example.com/department": "Research"}, u.Labels)
+			return u, nil
+		})
+
+		srv := &SCIMServer{
+			userCache:      userCache,
+			users:          userClient,
+			userAttributes: userAttributeClient,
+			userMGR:        userMGR,
+			getConfig:      labelsConfig,
+		}
+
+		body := `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+			"userName": "john.doe",
+			"externalId": "ext-12345",
+			"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+				"department": "Research",
+				"manager": {"value": "u-manager"}
+			}
+		}`
+		r := httptest.NewRequest(http.MethodPost, "/v1-scim/"+provider+"/Users", bytes.NewBufferString(body))
+		r.SetPathValue("provider", provider)
+		w := httptest.NewRecorder()
+		srv.CreateUser(w, r)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var resp scimUser
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		require.NoError(t, err)
+		require.NotNil(t, resp.EnterpriseUser)
+		assert.Equal(t, "Research", resp.EnterpriseUser.Department)
+		assert.Equal(t, "u-manager", resp.EnterpriseUser.managerID())
+	})
+}
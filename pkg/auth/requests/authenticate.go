@@ -2,7 +2,7 @@
 
 import (
 	"context"
-	"crypto/rsa"
+	"crypto"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -24,6 +24,7 @@
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/oidc/provider"
+	"github.com/rancher/rancher/pkg/oidc/provider/session"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/steve/pkg/auth"
@@ -47,7 +48,11 @@ type AuthTokenGetter interface {
 }
 
 type publicKeyGetter interface {
-	GetPublicKey(kid string) (*rsa.PublicKey, error)
+	GetPublicKey(kid string) (crypto.PublicKey, error)
+}
+
+type tokenRevoker interface {
+	IsTokenRevoked(token string) (bool, error)
 }
 
 // Authenticator authenticates a request.
@@ -92,6 +97,7 @@ type tokenAuthenticator struct {
 	now                 func() time.Time // Make it easier to test.
 	extTokenStore       *exttokenstore.SystemStore
 	keyGetter           publicKeyGetter
+	revocations         tokenRevoker
 	oidcClientCache     mgmtcontrollers.OIDCClientCache
 }
 
@@ -143,6 +149,7 @@ func NewAuthenticator(ctx context.Context, clusterRouter ClusterRouter, mgmtCtx
 
 	if features.OIDCProvider.Enabled() {
 		authenticator.keyGetter = provider.NewOIDCKeyClient(mgmtCtx.Wrangler.Core.Secret().Cache())
+		authenticator.revocations = session.NewRevokedTokens(mgmtCtx.Wrangler.Core.Secret().Cache())
 		authenticator.oidcClientCache = mgmtCtx.Wrangler.Mgmt.OIDCClient().Cache()
 	}
 
@@ -344,7 +351,7 @@ func (a *tokenAuthenticator) parseTokenFromJWT(s string) (*authorizationTokenCla
 
 		return nil, errors.New("missing kid in access token")
 	},
-		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name}),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
 		jwt.WithIssuer(settings.ServerURL.Get()+"/oidc"),
 	)
 
@@ -380,6 +387,21 @@ func (a *tokenAuthenticator) TokenFromRequest(req *http.Request) (accessor.Token
 			return nil, ErrMustAuthenticate
 		}
 
+		revoked, err := a.revocations.IsTokenRevoked(tokenAuthValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if access token is revoked: %v: %w", err, ErrMustAuthenticate)
+		}
+		if revoked {
+			logrus.Debugf("TokenFromRequest JWT for %s has been revoked", req.URL)
+			return nil, ErrMustAuthenticate
+		}
+
+		if claims.Token == "" {
+			// Access tokens of the client_credentials grant identify an OIDC client, not a Rancher user.
+			logrus.Debugf("TokenFromRequest JWT for %s is not associated with a Rancher token", req.URL)
+			return nil, ErrMustAuthenticate
+		}
+
 		isExtToken = claims.Kind == "ext"
 		if isExtToken {
 			// Detected ext token in OIDC session. Note we do not
@@ -12,6 +12,7 @@
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	ext "github.com/rancher/rancher/pkg/apis/ext.cattle.io/v1"
 	apiv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
@@ -1315,7 +1316,7 @@ func TestAuthenticateWithAccessTokenAndOIDCEnabled(t *testing.T) {
 			},
 		}
 
-		accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "unknown", now)
+		accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "unknown", jwt.SigningMethodRS256, now)
 		signedToken, err := accessToken.SignedString(privateKey)
 		require.NoError(t, err)
 		req := httptest.NewRequest(http.MethodGet, "/v1/namespaces", nil)
@@ -1391,13 +1392,15 @@ func TestAuthenticateWithAccessTokenAndOIDCEnabled(t *testing.T) {
 
 		oidcClientCache := fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl)
 		oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", testOIDCClient.Status.ClientID).Return([]*v3.OIDCClient{testOIDCClient}, nil)
-		accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "kid", now)
+		accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "kid", jwt.SigningMethodRS256, now)
 		privateKey := testGeneratePrivateKey(t)
 		signedToken, err := accessToken.SignedString(privateKey)
 		require.NoError(t, err)
 
 		signingKeyGetter := mocks.NewMocksigningKeyGetter(ctrl)
 		signingKeyGetter.EXPECT().GetPublicKey("kid").Return(&privateKey.PublicKey, nil)
+		revocations := mocks.NewMocktokenRevoker(ctrl)
+		revocations.EXPECT().IsTokenRevoked(signedToken).Return(false, nil)
 
 		userAttribute := &v3.UserAttribute{
 			ObjectMeta: metav1.ObjectMeta{
@@ -1456,6 +1459,7 @@ func TestAuthenticateWithAccessTokenAndOIDCEnabled(t *testing.T) {
 				return now
 			},
 			keyGetter:       signingKeyGetter,
+			revocations:     revocations,
 			oidcClientCache: oidcClientCache,
 		}
 
@@ -1478,6 +1482,69 @@ func TestAuthenticateWithAccessTokenAndOIDCEnabled(t *testing.T) {
 		require.Len(t, resp.Extras[common.ExtraRequestHost], 1)
 		require.Equal(t, req.Host, resp.Extras[common.ExtraRequestHost][0])
 	})
+
+	t.Run("with revoked access token", func(t *testing.T) {
+		token := &v3.Token{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "token-55rl6",
+				CreationTimestamp: metav1.NewTime(now),
+			},
+			Token:        "jnb9tksmnctvgbn92ngbkptblcjwg4pmfp98wqj29wk5kv85ktg59s",
+			AuthProvider: "fake",
+			TTLMillis:    57600000,
+			UserID:       userID,
+		}
+
+		ctrl := gomock.NewController(t)
+		tokenIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		tokenIndexer.AddIndexers(cache.Indexers{tokenKeyIndex: tokenKeyIndexer})
+		tokenIndexer.Add(token)
+
+		testOIDCClient := &apiv3.OIDCClient{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-client-id",
+			},
+			Spec: apiv3.OIDCClientSpec{
+				TokenExpirationSeconds: 600,
+			},
+			Status: apiv3.OIDCClientStatus{
+				ClientID: "this-is-a-test-client-id",
+			},
+		}
+		accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "kid", jwt.SigningMethodRS256, now)
+		privateKey := testGeneratePrivateKey(t)
+		signedToken, err := accessToken.SignedString(privateKey)
+		require.NoError(t, err)
+
+		signingKeyGetter := mocks.NewMocksigningKeyGetter(ctrl)
+		signingKeyGetter.EXPECT().GetPublicKey("kid").Return(&privateKey.PublicKey, nil)
+		// the token was revoked with the revocation endpoint of the OIDC provider.
+		revocations := mocks.NewMocktokenRevoker(ctrl)
+		revocations.EXPECT().IsTokenRevoked(signedToken).Return(true, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/namespaces", nil)
+		req.Header.Set("Authorization", "Bearer "+signedToken)
+
+		userRefresher := &fakeUserRefresher{}
+		authenticator := tokenAuthenticator{
+			ctx:          t.Context(),
+			tokenIndexer: tokenIndexer,
+			refreshUser:  userRefresher.refreshUser,
+			now: func() time.Time {
+				return now
+			},
+			keyGetter:       signingKeyGetter,
+			revocations:     revocations,
+			oidcClientCache: fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl),
+		}
+
+		resp, err := authenticator.Authenticate(req)
+		require.Nil(t, resp)
+		var apiErr *httperror.APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnauthorized, apiErr.Code.Status)
+		assert.False(t, userRefresher.called)
+	})
 }
 
 func TestAuthenticateWithAccessTokenAndOIDCDisabled(t *testing.T) {
@@ -1553,7 +1620,7 @@ func TestAuthenticateWithAccessTokenAndOIDCDisabled(t *testing.T) {
 		},
 	}
 
-	accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "kid", now)
+	accessToken := provider.CreateAccessToken(testOIDCClient, token, []string{"openid"}, "kid", jwt.SigningMethodRS256, now)
 	privateKey := testGeneratePrivateKey(t)
 	signedToken, err := accessToken.SignedString(privateKey)
 	require.NoError(t, err)
@@ -936,6 +936,9 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, cluster *capi.C
 		} else if r.change {
 			logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - plan for machine %s/%s did not match, appending to outOfSync", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name)
 			outOfSync = append(outOfSync, r.entry.Machine.Name)
+			if diff, err := diffNodePlans(r.entry.Plan.Plan, r.desiredPlan); err == nil && !diff.Empty() {
+				messages[r.entry.Machine.Name] = append(messages[r.entry.Machine.Name], "plan changed: "+diff.String())
+			}
 			// Conditions
 			// 1. If the node is already draining then the plan is out of sync.  There is no harm in updating it if
 			// the node is currently drained.
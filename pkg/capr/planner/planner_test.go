@@ -14,6 +14,7 @@
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
+	planapi "github.com/rancher/rancher/pkg/plan"
 	"github.com/rancher/rancher/pkg/provisioningv2/image"
 	"github.com/rancher/wrangler/v3/pkg/generic/fake"
 	"github.com/stretchr/testify/assert"
@@ -64,6 +65,8 @@ func newMockPlanner(t *testing.T, functions InfoFunctions) *mockPlanner {
 		secrets:      mp.secretClient,
 		secretsCache: mp.secretCache,
 		machineCache: mp.machinesCache,
+		// The plan history is covered by the plan package; the secret client mock does not expect it.
+		history: planapi.NewStore(mp.secretClient).WithHistory(0),
 	}
 	p := Planner{
 		ctx:                           context.TODO(),
@@ -19,7 +19,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:9099/liveness",
 			},
 		},
@@ -28,7 +28,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:2381/health",
 			},
 		},
@@ -37,7 +37,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL:        "https://%s:6443/readyz",
 				CACert:     "%s/server/tls/server-ca.crt",
 				ClientCert: "%s/server/tls/client-kube-apiserver.crt",
@@ -49,7 +49,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "https://%s:%s/healthz",
 			},
 		},
@@ -58,7 +58,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "https://%s:%s/healthz",
 			},
 		},
@@ -67,7 +67,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:10248/healthz",
 			},
 		},
@@ -75,6 +75,7 @@
 	errEmptyCACert  = errors.New("cacert cannot be empty")
 	errEmptyPort    = errors.New("port cannot be empty")
 	errEmptyAddress = errors.New("address cannot be empty")
+	errNotHTTPProbe = errors.New("probe has no httpGet action")
 )
 
 // isCalico returns true if the cni is calico or calico+multus, and returns false otherwise.
@@ -177,6 +178,10 @@ func replaceCACertAndPortForProbes(probe plan.Probe, cacert, host, port string)
 	if host == "" {
 		return plan.Probe{}, errEmptyAddress
 	}
+	if probe.HTTPGetAction == nil {
+		return plan.Probe{}, errNotHTTPProbe
+	}
+	probe = *probe.DeepCopy()
 	probe.HTTPGetAction.CACert = cacert
 	probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, host, port)
 	return probe, nil
@@ -187,9 +192,12 @@ func insertDataDirForProbes(controlPlane *rkev1.RKEControlPlane, probes map[stri
 	result := make(map[string]plan.Probe, len(probes))
 	dataDir := capr.GetDistroDataDir(controlPlane)
 	for k, v := range probes {
-		v.HTTPGetAction.CACert = replaceIfFormatSpecifier(v.HTTPGetAction.CACert, dataDir)
-		v.HTTPGetAction.ClientCert = replaceIfFormatSpecifier(v.HTTPGetAction.ClientCert, dataDir)
-		v.HTTPGetAction.ClientKey = replaceIfFormatSpecifier(v.HTTPGetAction.ClientKey, dataDir)
+		v = *v.DeepCopy()
+		if v.HTTPGetAction != nil {
+			v.HTTPGetAction.CACert = replaceIfFormatSpecifier(v.HTTPGetAction.CACert, dataDir)
+			v.HTTPGetAction.ClientCert = replaceIfFormatSpecifier(v.HTTPGetAction.ClientCert, dataDir)
+			v.HTTPGetAction.ClientKey = replaceIfFormatSpecifier(v.HTTPGetAction.ClientKey, dataDir)
+		}
 		result[k] = v
 	}
 	return result
@@ -199,7 +207,10 @@ func insertDataDirForProbes(controlPlane *rkev1.RKEControlPlane, probes map[stri
 func replaceURLForProbes(probes map[string]plan.Probe, loopbackAddress string) map[string]plan.Probe {
 	result := make(map[string]plan.Probe, len(probes))
 	for k, v := range probes {
-		v.HTTPGetAction.URL = replaceIfFormatSpecifier(v.HTTPGetAction.URL, loopbackAddress)
+		v = *v.DeepCopy()
+		if v.HTTPGetAction != nil {
+			v.HTTPGetAction.URL = replaceIfFormatSpecifier(v.HTTPGetAction.URL, loopbackAddress)
+		}
 		result[k] = v
 	}
 	return result
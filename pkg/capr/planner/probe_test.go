@@ -141,7 +141,7 @@ func TestReplaceCACertAndPortForProbes(t *testing.T) {
 		{
 			name: "URL with specifier",
 			probe: plan.Probe{
-				HTTPGetAction: plan.HTTPGetAction{
+				HTTPGetAction: &plan.HTTPGetAction{
 					CACert: "test",
 					URL:    "https://%s:%s",
 				},
@@ -150,7 +150,7 @@ func TestReplaceCACertAndPortForProbes(t *testing.T) {
 			address: "rancher.com",
 			port:    "1234",
 			expected: plan.Probe{
-				HTTPGetAction: plan.HTTPGetAction{
+				HTTPGetAction: &plan.HTTPGetAction{
 					CACert: "test",
 					URL:    "https://rancher.com:1234",
 				},
@@ -201,7 +201,7 @@ func TestRenderProbes(t *testing.T) {
 			name: "simple probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						CACert:     "cacert",
 						ClientCert: "clientcert",
 						ClientKey:  "clientkey",
@@ -215,7 +215,7 @@ func TestRenderProbes(t *testing.T) {
 			},
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						CACert:     "cacert",
 						ClientCert: "clientcert",
 						ClientKey:  "clientkey",
@@ -227,7 +227,7 @@ func TestRenderProbes(t *testing.T) {
 			name: "replace probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						CACert:     "%s/cacert",
 						ClientCert: "%s/clientcert",
 						ClientKey:  "%s/clientkey",
@@ -241,7 +241,7 @@ func TestRenderProbes(t *testing.T) {
 			},
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						CACert:     "/var/lib/rancher/rke2/cacert",
 						ClientCert: "/var/lib/rancher/rke2/clientcert",
 						ClientKey:  "/var/lib/rancher/rke2/clientkey",
@@ -280,7 +280,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			name: "simple probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://127.0.0.1:1234/test",
 					},
 				},
@@ -288,7 +288,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			loopbackAddress: "",
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://127.0.0.1:1234/test",
 					},
 				},
@@ -298,7 +298,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			name: "replace ipv4 probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://%s:1234/test",
 					},
 				},
@@ -306,7 +306,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			loopbackAddress: "127.0.0.1",
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://127.0.0.1:1234/test",
 					},
 				},
@@ -316,7 +316,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			name: "replace ipv6 probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://%s:1234/test",
 					},
 				},
@@ -324,7 +324,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			loopbackAddress: "[::1]",
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://[::1]:1234/test",
 					},
 				},
@@ -334,7 +334,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			name: "replace dual probe",
 			input: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://%s:1234/test",
 					},
 				},
@@ -342,7 +342,7 @@ func TestReplaceURLForProbes(t *testing.T) {
 			loopbackAddress: "localhost",
 			expected: map[string]plan.Probe{
 				"a": {
-					HTTPGetAction: plan.HTTPGetAction{
+					HTTPGetAction: &plan.HTTPGetAction{
 						URL: "https://localhost:1234/test",
 					},
 				},
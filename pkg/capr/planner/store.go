@@ -22,6 +22,7 @@
 	"github.com/rancher/rancher/pkg/utils"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -41,6 +42,9 @@ type PlanStore struct {
 	secretsCache corecontrollers.SecretCache
 	machineCache capicontrollers.MachineCache
 	equalities   conversion.Equalities
+	// history records the plans replaced by UpdatePlan in the plan history of their machine, like
+	// the operation controllers do.
+	history *planapi.Store
 }
 
 func NewStore(secrets corecontrollers.SecretController, machineCache capicontrollers.MachineCache, equalities conversion.Equalities) *PlanStore {
@@ -49,6 +53,7 @@ func NewStore(secrets corecontrollers.SecretController, machineCache capicontrol
 		secretsCache: secrets.Cache(),
 		machineCache: machineCache,
 		equalities:   equalities,
+		history:      planapi.NewStore(secrets),
 	}
 }
 
@@ -359,6 +364,32 @@ func (p *PlanStore) getPlanSecretFromMachine(machine *capi.Machine) (*corev1.Sec
 	return secret, nil
 }
 
+// diffNodePlans returns the difference between two node plans.
+func diffNodePlans(old, new plan.NodePlan) (planapi.PlanDiff, error) {
+	oldData, err := json.Marshal(old)
+	if err != nil {
+		return planapi.PlanDiff{}, err
+	}
+	newData, err := json.Marshal(new)
+	if err != nil {
+		return planapi.PlanDiff{}, err
+	}
+	return diffRawPlans(oldData, newData)
+}
+
+// diffRawPlans returns the difference between two serialized plans.
+func diffRawPlans(old, new []byte) (planapi.PlanDiff, error) {
+	oldPlan, err := planapi.Parse(old)
+	if err != nil {
+		return planapi.PlanDiff{}, err
+	}
+	newPlan, err := planapi.Parse(new)
+	if err != nil {
+		return planapi.PlanDiff{}, err
+	}
+	return planapi.Diff(oldPlan, newPlan), nil
+}
+
 // UpdatePlan should not be called directly as it will not block further progress if the plan is not in sync
 // maxFailures is the number of attempts the system-agent will make to run the plan (in a failed state). failureThreshold is used to determine when the plan has failed.
 func (p *PlanStore) UpdatePlan(entry *planEntry, newNodePlan plan.NodePlan, joinedTo string, maxFailures, failureThreshold int) error {
@@ -399,6 +430,19 @@ func (p *PlanStore) UpdatePlan(entry *planEntry, newNodePlan plan.NodePlan, join
 	entry.Metadata.Annotations[planapi.PlanLastUpdatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
 	entry.Metadata.Annotations[planapi.PlanProbesPassedAnnotation] = ""
 
+	// Record the plan being replaced and what changed in it, so it can be told why the node got a new
+	// plan. A plan that cannot be parsed is replaced without a diff.
+	if current := secret.Data["plan"]; len(current) > 0 && !bytes.Equal(current, data) {
+		if err := p.history.RecordHistory(secret); err != nil {
+			return err
+		}
+		entry.Metadata.Annotations[planapi.PlanDiffAnnotation] = ""
+		if diff, err := diffRawPlans(current, data); err == nil {
+			logrus.Infof("[planner] replacing plan of machine %s/%s: %s", entry.Machine.Namespace, entry.Machine.Name, diff)
+			entry.Metadata.Annotations[planapi.PlanDiffAnnotation] = diff.String()
+		}
+	}
+
 	capr.CopyPlanMetadataToSecret(secret, entry.Metadata)
 
 	// If the plan is being updated, then delete the probe-statuses so their healthy status will be reported as healthy only when they pass.
@@ -1054,3 +1054,23 @@ func TestSetMachineJoinURL(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffNodePlans(t *testing.T) {
+	old := plan.NodePlan{
+		Files: []plan.File{{Path: "/etc/rancher/rke2/config.yaml", Content: "YQ=="}},
+	}
+	changed := plan.NodePlan{
+		Files: []plan.File{{Path: "/etc/rancher/rke2/config.yaml", Content: "Yg=="}},
+	}
+
+	diff, err := diffNodePlans(old, old)
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	diff, err = diffNodePlans(old, changed)
+	assert.NoError(t, err)
+	assert.Equal(t, "files: ~/etc/rancher/rke2/config.yaml (content)", diff.String())
+
+	_, err = diffRawPlans([]byte("{"), []byte("{}"))
+	assert.Error(t, err)
+}
@@ -10,9 +10,11 @@
 	OIDCClientFieldCreated                       = "created"
 	OIDCClientFieldCreatorID                     = "creatorId"
 	OIDCClientFieldDescription                   = "description"
+	OIDCClientFieldGrantTypes                    = "grantTypes"
 	OIDCClientFieldLabels                        = "labels"
 	OIDCClientFieldName                          = "name"
 	OIDCClientFieldOwnerReferences               = "ownerReferences"
+	OIDCClientFieldPostLogoutRedirectURIs        = "postLogoutRedirectURIs"
 	OIDCClientFieldRedirectURIs                  = "redirectURIs"
 	OIDCClientFieldRefreshTokenExpirationSeconds = "refreshTokenExpirationSeconds"
 	OIDCClientFieldRemoved                       = "removed"
@@ -31,9 +33,11 @@ type OIDCClient struct {
 	Created                       string            `json:"created,omitempty" yaml:"created,omitempty"`
 	CreatorID                     string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
 	Description                   string            `json:"description,omitempty" yaml:"description,omitempty"`
+	GrantTypes                    []string          `json:"grantTypes,omitempty" yaml:"grantTypes,omitempty"`
 	Labels                        map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Name                          string            `json:"name,omitempty" yaml:"name,omitempty"`
 	OwnerReferences               []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	PostLogoutRedirectURIs        []string          `json:"postLogoutRedirectURIs,omitempty" yaml:"postLogoutRedirectURIs,omitempty"`
 	RedirectURIs                  []string          `json:"redirectURIs,omitempty" yaml:"redirectURIs,omitempty"`
 	RefreshTokenExpirationSeconds int64             `json:"refreshTokenExpirationSeconds,omitempty" yaml:"refreshTokenExpirationSeconds,omitempty"`
 	Removed                       string            `json:"removed,omitempty" yaml:"removed,omitempty"`
@@ -3,6 +3,8 @@
 const (
 	OIDCClientSpecType                               = "oidcClientSpec"
 	OIDCClientSpecFieldDescription                   = "description"
+	OIDCClientSpecFieldGrantTypes                    = "grantTypes"
+	OIDCClientSpecFieldPostLogoutRedirectURIs        = "postLogoutRedirectURIs"
 	OIDCClientSpecFieldRedirectURIs                  = "redirectURIs"
 	OIDCClientSpecFieldRefreshTokenExpirationSeconds = "refreshTokenExpirationSeconds"
 	OIDCClientSpecFieldScopes                        = "scopes"
@@ -11,6 +13,8 @@
 
 type OIDCClientSpec struct {
 	Description                   string   `json:"description,omitempty" yaml:"description,omitempty"`
+	GrantTypes                    []string `json:"grantTypes,omitempty" yaml:"grantTypes,omitempty"`
+	PostLogoutRedirectURIs        []string `json:"postLogoutRedirectURIs,omitempty" yaml:"postLogoutRedirectURIs,omitempty"`
 	RedirectURIs                  []string `json:"redirectURIs,omitempty" yaml:"redirectURIs,omitempty"`
 	RefreshTokenExpirationSeconds int64    `json:"refreshTokenExpirationSeconds,omitempty" yaml:"refreshTokenExpirationSeconds,omitempty"`
 	Scopes                        []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
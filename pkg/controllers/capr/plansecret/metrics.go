@@ -0,0 +1,135 @@
+package plansecret
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/metrics"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// planMetrics derives the plan delivery metrics from the changes to machine-plan secrets. It keeps
+// the last state seen per secret, so that each transition is only counted once. The first state seen
+// of a secret is only remembered, so a restart does not count the history of every plan again.
+type planMetrics struct {
+	lock   sync.Mutex
+	states map[string]planMetricsState
+}
+
+type planMetricsState struct {
+	checksum      string
+	applied       bool
+	probesPassed  bool
+	probeFailures map[string]int
+}
+
+// planEvents are the transitions of a machine-plan secret since it was last observed.
+type planEvents struct {
+	assigned      bool
+	applied       time.Duration
+	probesPassed  time.Duration
+	probeFailures map[string]int
+}
+
+func newPlanMetrics() *planMetrics {
+	return &planMetrics{
+		states: map[string]planMetricsState{},
+	}
+}
+
+// observe records the transitions of the machine-plan secret stored under key in the plan delivery
+// metrics. A nil secret forgets the state of a deleted secret.
+func (m *planMetrics) observe(key string, secret *corev1.Secret) {
+	events := m.events(key, secret, time.Now())
+
+	cluster := ""
+	if secret != nil {
+		cluster = secret.Labels[capr.ClusterNameLabel]
+	}
+	if events.assigned {
+		metrics.IncPlanAssignments(cluster)
+	}
+	if events.applied > 0 {
+		metrics.ObservePlanApplied(cluster, events.applied)
+	}
+	if events.probesPassed > 0 {
+		metrics.ObservePlanProbesPassed(cluster, events.probesPassed)
+	}
+	for probe, failures := range events.probeFailures {
+		metrics.AddPlanProbeFailures(cluster, probe, failures)
+	}
+}
+
+// events returns the transitions of the machine-plan secret stored under key since it was last
+// observed, and remembers its current state. The durations are measured from the time the plan was
+// assigned, as recorded in the plan-last-updated annotation, and are zero unless the plan was
+// applied or its probes passed since the secret was last observed.
+func (m *planMetrics) events(key string, secret *corev1.Secret, now time.Time) planEvents {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if secret == nil {
+		delete(m.states, key)
+		return planEvents{}
+	}
+	plan := secret.Data["plan"]
+	if len(plan) == 0 {
+		return planEvents{}
+	}
+
+	current := planMetricsState{
+		checksum:      planapi.PlanHash(plan),
+		probesPassed:  secret.Annotations[planapi.PlanProbesPassedAnnotation] != "",
+		probeFailures: map[string]int{},
+	}
+	current.applied = string(secret.Data["applied-checksum"]) == current.checksum
+	if statuses, _, err := planapi.ParseProbeStatuses(secret.Data["probe-statuses"]); err == nil {
+		for name, status := range *statuses {
+			current.probeFailures[name] = status.FailureCount
+		}
+	}
+
+	previous, seen := m.states[key]
+	m.states[key] = current
+	if !seen {
+		return planEvents{}
+	}
+
+	var events planEvents
+	if previous.checksum != current.checksum {
+		events.assigned = true
+		previous = planMetricsState{checksum: current.checksum}
+	}
+
+	assigned, err := time.Parse(time.RFC3339, secret.Annotations[planapi.PlanLastUpdatedAnnotation])
+	if err == nil {
+		if current.applied && !previous.applied {
+			events.applied = max(now.Sub(assigned), time.Nanosecond)
+		}
+		if current.probesPassed && !previous.probesPassed {
+			passed, err := time.Parse(time.RFC3339, secret.Annotations[planapi.PlanProbesPassedAnnotation])
+			if err != nil {
+				passed = now
+			}
+			events.probesPassed = max(passed.Sub(assigned), time.Nanosecond)
+		}
+	}
+
+	for name, failures := range current.probeFailures {
+		// The failure count of a probe is reset once it succeeds; failures counted since then are new.
+		if last := previous.probeFailures[name]; failures > last {
+			failures -= last
+		} else if failures == last {
+			continue
+		}
+		if failures > 0 {
+			if events.probeFailures == nil {
+				events.probeFailures = map[string]int{}
+			}
+			events.probeFailures[name] = failures
+		}
+	}
+	return events
+}
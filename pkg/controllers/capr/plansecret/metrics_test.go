@@ -0,0 +1,60 @@
+package plansecret
+
+import (
+	"testing"
+	"time"
+
+	planapi "github.com/rancher/rancher/pkg/plan"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newMetricsSecret(plan string, assigned time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "machine-plan",
+			Namespace:   "fleet-default",
+			Labels:      map[string]string{"rke.cattle.io/cluster-name": "test"},
+			Annotations: map[string]string{planapi.PlanLastUpdatedAnnotation: assigned.UTC().Format(time.RFC3339)},
+		},
+		Data: map[string][]byte{"plan": []byte(plan)},
+	}
+}
+
+func TestPlanMetricsEvents(t *testing.T) {
+	t.Parallel()
+
+	m := newPlanMetrics()
+	assigned := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := "fleet-default/machine-plan"
+
+	// The first state seen is only remembered.
+	secret := newMetricsSecret("{}", assigned)
+	assert.Equal(t, planEvents{}, m.events(key, secret, assigned))
+
+	secret = newMetricsSecret(`{"files":[]}`, assigned)
+	assert.Equal(t, planEvents{assigned: true}, m.events(key, secret, assigned))
+
+	secret.Data["applied-checksum"] = []byte(planapi.PlanHash(secret.Data["plan"]))
+	secret.Data["probe-statuses"] = []byte(`{"kubelet":{"failureCount":2}}`)
+	assert.Equal(t, planEvents{
+		applied:       time.Minute,
+		probeFailures: map[string]int{"kubelet": 2},
+	}, m.events(key, secret, assigned.Add(time.Minute)))
+
+	secret.Data["probe-statuses"] = []byte(`{"kubelet":{"healthy":true,"successCount":1}}`)
+	secret.Annotations[planapi.PlanProbesPassedAnnotation] = assigned.Add(2 * time.Minute).Format(time.RFC3339)
+	assert.Equal(t, planEvents{probesPassed: 2 * time.Minute}, m.events(key, secret, assigned.Add(3*time.Minute)))
+
+	// Observing the same state again does not count anything.
+	assert.Equal(t, planEvents{}, m.events(key, secret, assigned.Add(4*time.Minute)))
+
+	// A failure count reset by a successful probe counts the failures since.
+	secret.Data["probe-statuses"] = []byte(`{"kubelet":{"failureCount":1}}`)
+	assert.Equal(t, planEvents{probeFailures: map[string]int{"kubelet": 1}}, m.events(key, secret, assigned.Add(5*time.Minute)))
+
+	// A deleted secret is forgotten.
+	assert.Equal(t, planEvents{}, m.events(key, nil, assigned))
+	assert.Empty(t, m.states)
+}
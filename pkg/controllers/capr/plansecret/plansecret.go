@@ -34,6 +34,7 @@ type handler struct {
 	etcdSnapshotsClient  rkev1controllers.ETCDSnapshotClient
 	etcdSnapshotsCache   rkev1controllers.ETCDSnapshotCache
 	rkeControlPlaneCache rkev1controllers.RKEControlPlaneCache
+	metrics              *planMetrics
 }
 
 func Register(ctx context.Context, clients *wrangler.CAPIContext) {
@@ -46,14 +47,20 @@ func Register(ctx context.Context, clients *wrangler.CAPIContext) {
 		etcdSnapshotsClient:  clients.RKE.ETCDSnapshot(),
 		etcdSnapshotsCache:   clients.RKE.ETCDSnapshot().Cache(),
 		rkeControlPlaneCache: clients.RKE.RKEControlPlane().Cache(),
+		metrics:              newPlanMetrics(),
 	}
 	clients.Core.Secret().OnChange(ctx, "plan-secret", h.OnChange)
 }
 
-func (h *handler) OnChange(_ string, secret *corev1.Secret) (*corev1.Secret, error) {
-	if secret == nil || secret.Type != capr.SecretTypeMachinePlan || len(secret.Data) == 0 {
+func (h *handler) OnChange(key string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if secret == nil {
+		h.metrics.observe(key, nil)
+		return secret, nil
+	}
+	if secret.Type != capr.SecretTypeMachinePlan || len(secret.Data) == 0 {
 		return secret, nil
 	}
+	h.metrics.observe(key, secret)
 	var err error
 
 	logrus.Debugf("[plansecret] reconciling secret %s/%s", secret.Namespace, secret.Name)
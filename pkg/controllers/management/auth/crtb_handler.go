@@ -95,6 +95,9 @@ func crtTokenReaderRoleBindingName(namespace string, subject v1.Subject) string
 	"etcdsnapshotsaves":           "operation.cattle.io",
 	"etcdsnapshotrestores":        "operation.cattle.io",
 	"encryptionkeyrotations":      "operation.cattle.io",
+	"certificaterotations":        "operation.cattle.io",
+	"kubernetesupgrades":          "operation.cattle.io",
+	"operationrecords":            "operation.cattle.io",
 }
 
 type crtbLifecycle struct {
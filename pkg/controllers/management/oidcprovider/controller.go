@@ -58,6 +58,7 @@ func Register(ctx context.Context, wContext *wrangler.Context) {
 		now:             time.Now,
 	}
 	oidcClient.OnChange(ctx, "oidcclient-change", controller.onChange)
+	registerSigningKeyRotation(ctx, wContext)
 }
 
 // onChange sets a new client id in the status field, and creates a k8s with the client secret.
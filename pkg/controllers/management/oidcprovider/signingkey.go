@@ -0,0 +1,184 @@
+package oidcprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	wrangmgmtv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/oidc/provider"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	signingKeySecretNamespace    = "cattle-system"
+	signingKeySecretName         = "oidc-signing-key"
+	rotateSigningKeyAnn          = "cattle.io/oidc-signing-key-rotate"
+	signingKeyCreatedAtPrefixAnn = "cattle.io/oidc-signing-key-created-at_"
+	signingKeyRetiredAtPrefixAnn = "cattle.io/oidc-signing-key-retired-at_"
+)
+
+type signingKeyController struct {
+	secrets         corev1.SecretController
+	oidcClientCache wrangmgmtv3.OIDCClientCache
+	generateKey     func(alg string) ([]byte, []byte, error)
+	now             func() time.Time
+}
+
+func registerSigningKeyRotation(ctx context.Context, wContext *wrangler.Context) {
+	controller := &signingKeyController{
+		secrets:         wContext.Core.Secret(),
+		oidcClientCache: wContext.Mgmt.OIDCClient().Cache(),
+		generateKey:     provider.GenerateSigningKey,
+		now:             time.Now,
+	}
+	wContext.Core.Secret().OnChange(ctx, "oidc-signing-key-rotation", controller.onChange)
+}
+
+// onChange rotates the signing key of the OIDC provider when it's older than the rotation interval, or when the
+// rotate annotation is present. The public key of the previous signing key is kept during the rotation overlap, so
+// the tokens it signed can still be verified, and it's removed afterward.
+func (c *signingKeyController) onChange(key string, secret *v1.Secret) (*v1.Secret, error) {
+	if key != signingKeySecretNamespace+"/"+signingKeySecretName || secret == nil {
+		return secret, nil
+	}
+	activeKid := ""
+	for name := range secret.Data {
+		if kid, ok := strings.CutSuffix(name, ".pem"); ok {
+			activeKid = kid
+			break
+		}
+	}
+	if activeKid == "" {
+		return secret, nil
+	}
+
+	now := c.now()
+	updated := secret.DeepCopy()
+	changed := false
+
+	// keys created before rotation was introduced don't have the created-at annotation. It's set to the current time,
+	// so they're rotated one interval after the upgrade rather than right away.
+	createdAt, ok := parseUnixAnnotation(secret.Annotations[signingKeyCreatedAtPrefixAnn+activeKid])
+	if !ok {
+		createdAt = now
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[signingKeyCreatedAtPrefixAnn+activeKid] = fmt.Sprintf("%d", now.Unix())
+		changed = true
+	}
+	interval := settings.OIDCProviderSigningKeyRotationInterval.GetDuration()
+	_, rotateRequested := secret.Annotations[rotateSigningKeyAnn]
+	if rotateRequested || (interval > 0 && !now.Before(createdAt.Add(interval))) {
+		if err := c.rotate(updated, activeKid, now); err != nil {
+			return secret, err
+		}
+		createdAt = now
+		changed = true
+	}
+
+	var next time.Time
+	if interval > 0 {
+		next = createdAt.Add(interval)
+	}
+	overlap, err := c.rotationOverlap()
+	if err != nil {
+		return secret, err
+	}
+	for ann, value := range updated.Annotations {
+		kid, ok := strings.CutPrefix(ann, signingKeyRetiredAtPrefixAnn)
+		if !ok {
+			continue
+		}
+		retiredAt, ok := parseUnixAnnotation(value)
+		if !ok {
+			logrus.Warnf("[OIDC provider] ignoring invalid retirement time %q of signing key %s", value, kid)
+			continue
+		}
+		expiresAt := retiredAt.Add(overlap)
+		if !now.Before(expiresAt) {
+			logrus.Infof("[OIDC provider] removing previous signing key %s", kid)
+			delete(updated.Data, kid+".pub")
+			delete(updated.Annotations, ann)
+			changed = true
+			continue
+		}
+		if next.IsZero() || expiresAt.Before(next) {
+			next = expiresAt
+		}
+	}
+
+	if changed {
+		secret, err = c.secrets.Update(updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update signing keys: %w", err)
+		}
+	}
+	if !next.IsZero() {
+		c.secrets.EnqueueAfter(signingKeySecretNamespace, signingKeySecretName, next.Sub(now))
+	}
+
+	return secret, nil
+}
+
+// rotationOverlap returns the rotation overlap setting, extended to the longest token lifetime of the OIDC clients, so
+// a previous signing key is kept until all the tokens it signed, including refresh tokens, have expired.
+func (c *signingKeyController) rotationOverlap() (time.Duration, error) {
+	overlap := settings.OIDCProviderSigningKeyRotationOverlap.GetDuration()
+	oidcClients, err := c.oidcClientCache.List(labels.Everything())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list OIDC clients: %w", err)
+	}
+	for _, oidcClient := range oidcClients {
+		lifetime := time.Duration(max(oidcClient.Spec.TokenExpirationSeconds, oidcClient.Spec.RefreshTokenExpirationSeconds)) * time.Second
+		if lifetime > overlap {
+			overlap = lifetime
+		}
+	}
+
+	return overlap, nil
+}
+
+// rotate replaces the active signing key by a new one using the configured algorithm, and marks the previous key as
+// retired.
+func (c *signingKeyController) rotate(secret *v1.Secret, activeKid string, now time.Time) error {
+	alg := provider.SigningAlgorithm()
+	kid := fmt.Sprintf("%s-%d", strings.ToLower(alg), now.Unix())
+	if _, ok := secret.Data[kid+".pub"]; ok {
+		return fmt.Errorf("signing key %s already exists", kid)
+	}
+	privateKeyPEM, publicKeyPEM, err := c.generateKey(alg)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	delete(secret.Data, activeKid+".pem")
+	secret.Data[kid+".pem"] = privateKeyPEM
+	secret.Data[kid+".pub"] = publicKeyPEM
+	delete(secret.Annotations, rotateSigningKeyAnn)
+	delete(secret.Annotations, signingKeyCreatedAtPrefixAnn+activeKid)
+	secret.Annotations[signingKeyRetiredAtPrefixAnn+activeKid] = fmt.Sprintf("%d", now.Unix())
+	secret.Annotations[signingKeyCreatedAtPrefixAnn+kid] = fmt.Sprintf("%d", now.Unix())
+	logrus.Infof("[OIDC provider] rotated signing key %s to %s", activeKid, kid)
+
+	return nil
+}
+
+func parseUnixAnnotation(value string) (time.Time, bool) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
@@ -0,0 +1,257 @@
+package oidcprovider
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSigningKeyOnChange(t *testing.T) {
+	ctlr := gomock.NewController(t)
+	fakeNow := time.Unix(1000000000, 0)
+	const newKid = "rs256-1000000000"
+	interval := 2160 * time.Hour
+	overlap := 720 * time.Hour
+	unix := func(ts time.Time) string {
+		return fmt.Sprintf("%d", ts.Unix())
+	}
+	signingKeySecret := func(annotations map[string]string, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              signingKeySecretName,
+				Namespace:         signingKeySecretNamespace,
+				CreationTimestamp: metav1.NewTime(fakeNow.Add(-time.Hour)),
+				Annotations:       annotations,
+			},
+			Data: data,
+		}
+	}
+	generateKey := func(alg string) ([]byte, []byte, error) {
+		return []byte("new-private-key"), []byte("new-public-key"), nil
+	}
+
+	tests := map[string]struct {
+		secret        *v1.Secret
+		oidcClients   []*v3.OIDCClient
+		alg           string
+		generateKey   func(alg string) ([]byte, []byte, error)
+		expectedData  map[string][]byte
+		expectedAnns  map[string]string
+		expectedAfter time.Duration
+		expectedErr   string
+	}{
+		"signing key is not due for rotation": {
+			secret: signingKeySecret(map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key": unix(fakeNow.Add(-time.Hour)),
+			}, map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			}),
+			expectedAfter: interval - time.Hour,
+		},
+		"legacy signing key without created-at annotation is rotated one interval later": {
+			secret: func() *v1.Secret {
+				secret := signingKeySecret(nil, map[string][]byte{
+					"key.pem": []byte("private-key"),
+					"key.pub": []byte("public-key"),
+				})
+				secret.CreationTimestamp = metav1.NewTime(fakeNow.Add(-2 * interval))
+				return secret
+			}(),
+			expectedData: map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			},
+			expectedAnns: map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key": unix(fakeNow),
+			},
+			expectedAfter: interval,
+		},
+		"signing key is rotated after the rotation interval": {
+			secret: signingKeySecret(map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key": unix(fakeNow.Add(-interval)),
+			}, map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			}),
+			generateKey: generateKey,
+			expectedData: map[string][]byte{
+				"key.pub":       []byte("public-key"),
+				newKid + ".pem": []byte("new-private-key"),
+				newKid + ".pub": []byte("new-public-key"),
+			},
+			expectedAnns: map[string]string{
+				signingKeyRetiredAtPrefixAnn + "key":  unix(fakeNow),
+				signingKeyCreatedAtPrefixAnn + newKid: unix(fakeNow),
+			},
+			expectedAfter: overlap,
+		},
+		"signing key is rotated on demand": {
+			secret: signingKeySecret(map[string]string{
+				rotateSigningKeyAnn:                  "true",
+				signingKeyCreatedAtPrefixAnn + "key": unix(fakeNow.Add(-time.Hour)),
+			}, map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			}),
+			generateKey: generateKey,
+			expectedData: map[string][]byte{
+				"key.pub":       []byte("public-key"),
+				newKid + ".pem": []byte("new-private-key"),
+				newKid + ".pub": []byte("new-public-key"),
+			},
+			expectedAnns: map[string]string{
+				signingKeyRetiredAtPrefixAnn + "key":  unix(fakeNow),
+				signingKeyCreatedAtPrefixAnn + newKid: unix(fakeNow),
+			},
+			expectedAfter: overlap,
+		},
+		"signing key is rotated with the default algorithm when the setting is invalid": {
+			secret: signingKeySecret(map[string]string{
+				rotateSigningKeyAnn:                  "true",
+				signingKeyCreatedAtPrefixAnn + "key": unix(fakeNow.Add(-time.Hour)),
+			}, map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			}),
+			alg: "HS256",
+			generateKey: func(alg string) ([]byte, []byte, error) {
+				if alg != "RS256" {
+					return nil, nil, fmt.Errorf("unexpected algorithm %s", alg)
+				}
+				return generateKey(alg)
+			},
+			expectedData: map[string][]byte{
+				"key.pub":       []byte("public-key"),
+				newKid + ".pem": []byte("new-private-key"),
+				newKid + ".pub": []byte("new-public-key"),
+			},
+			expectedAnns: map[string]string{
+				signingKeyRetiredAtPrefixAnn + "key":  unix(fakeNow),
+				signingKeyCreatedAtPrefixAnn + newKid: unix(fakeNow),
+			},
+			expectedAfter: overlap,
+		},
+		"overlap is extended to the longest refresh token lifetime": {
+			secret: signingKeySecret(map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key2": unix(fakeNow.Add(-overlap)),
+				signingKeyRetiredAtPrefixAnn + "key1": unix(fakeNow.Add(-overlap)),
+			}, map[string][]byte{
+				"key1.pub": []byte("public-key-1"),
+				"key2.pem": []byte("private-key-2"),
+				"key2.pub": []byte("public-key-2"),
+			}),
+			oidcClients: []*v3.OIDCClient{
+				{Spec: v3.OIDCClientSpec{TokenExpirationSeconds: 600, RefreshTokenExpirationSeconds: 3600}},
+				{Spec: v3.OIDCClientSpec{TokenExpirationSeconds: 600, RefreshTokenExpirationSeconds: int64((overlap + 2*time.Hour).Seconds())}},
+			},
+			expectedAfter: 2 * time.Hour,
+		},
+		"previous signing key is removed after the overlap": {
+			secret: signingKeySecret(map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key2": unix(fakeNow.Add(-overlap)),
+				signingKeyRetiredAtPrefixAnn + "key1": unix(fakeNow.Add(-overlap)),
+			}, map[string][]byte{
+				"key1.pub": []byte("public-key-1"),
+				"key2.pem": []byte("private-key-2"),
+				"key2.pub": []byte("public-key-2"),
+			}),
+			expectedData: map[string][]byte{
+				"key2.pem": []byte("private-key-2"),
+				"key2.pub": []byte("public-key-2"),
+			},
+			expectedAnns: map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key2": unix(fakeNow.Add(-overlap)),
+			},
+			expectedAfter: interval - overlap,
+		},
+		"previous signing key is kept during the overlap": {
+			secret: signingKeySecret(map[string]string{
+				signingKeyCreatedAtPrefixAnn + "key2": unix(fakeNow.Add(-time.Hour)),
+				signingKeyRetiredAtPrefixAnn + "key1": unix(fakeNow.Add(-time.Hour)),
+			}, map[string][]byte{
+				"key1.pub": []byte("public-key-1"),
+				"key2.pem": []byte("private-key-2"),
+				"key2.pub": []byte("public-key-2"),
+			}),
+			expectedAfter: overlap - time.Hour,
+		},
+		"error generating the signing key": {
+			secret: signingKeySecret(map[string]string{
+				rotateSigningKeyAnn: "true",
+			}, map[string][]byte{
+				"key.pem": []byte("private-key"),
+				"key.pub": []byte("public-key"),
+			}),
+			generateKey: func(alg string) ([]byte, []byte, error) {
+				return nil, nil, fmt.Errorf("unexpected error")
+			},
+			expectedErr: "failed to generate signing key: unexpected error",
+		},
+		"secret without a signing key is ignored": {
+			secret: signingKeySecret(nil, map[string][]byte{
+				"key.pub": []byte("public-key"),
+			}),
+		},
+		"other secrets are ignored": {
+			secret: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: signingKeySecretNamespace},
+				Data:       map[string][]byte{"key.pem": []byte("private-key")},
+			},
+		},
+		"secrets named like the signing key in other namespaces are ignored": {
+			secret: &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: signingKeySecretName, Namespace: "other"},
+				Data:       map[string][]byte{"key.pem": []byte("private-key")},
+			},
+		},
+	}
+	previousAlg := settings.OIDCProviderSigningAlgorithm.Get()
+	t.Cleanup(func() { _ = settings.OIDCProviderSigningAlgorithm.Set(previousAlg) })
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secrets := fake.NewMockControllerInterface[*v1.Secret, *v1.SecretList](ctlr)
+			if test.expectedData != nil {
+				secrets.EXPECT().Update(gomock.Any()).DoAndReturn(func(secret *v1.Secret) (*v1.Secret, error) {
+					assert.Equal(t, test.expectedData, secret.Data)
+					assert.Equal(t, test.expectedAnns, secret.Annotations)
+					return secret, nil
+				})
+			}
+			if test.expectedAfter != 0 {
+				secrets.EXPECT().EnqueueAfter(signingKeySecretNamespace, signingKeySecretName, test.expectedAfter)
+			}
+			oidcClientCache := fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctlr)
+			oidcClientCache.EXPECT().List(labels.Everything()).Return(test.oidcClients, nil).AnyTimes()
+			alg := test.alg
+			if alg == "" {
+				alg = "RS256"
+			}
+			assert.NoError(t, settings.OIDCProviderSigningAlgorithm.Set(alg))
+			c := &signingKeyController{
+				secrets:         secrets,
+				oidcClientCache: oidcClientCache,
+				generateKey:     test.generateKey,
+				now:             func() time.Time { return fakeNow },
+			}
+
+			_, err := c.onChange(test.secret.Namespace+"/"+test.secret.Name, test.secret)
+
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
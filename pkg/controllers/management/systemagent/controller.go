@@ -114,6 +114,12 @@ type handler struct {
 	etcdSnapshotRestoreCache operationcontrollers.ETCDSnapshotRestoreCache
 	encryptionRotations      operationcontrollers.EncryptionKeyRotationClient
 	encryptionRotationCache  operationcontrollers.EncryptionKeyRotationCache
+	certificateRotations     operationcontrollers.CertificateRotationClient
+	certificateRotationCache operationcontrollers.CertificateRotationCache
+	kubernetesUpgrades       operationcontrollers.KubernetesUpgradeClient
+	kubernetesUpgradeCache   operationcontrollers.KubernetesUpgradeCache
+	customOperations         operationcontrollers.CustomOperationClient
+	customOperationCache     operationcontrollers.CustomOperationCache
 	serviceAccounts          corecontrollers.ServiceAccountClient
 	serviceAccountCache      corecontrollers.ServiceAccountCache
 	secrets                  corecontrollers.SecretClient
@@ -138,6 +144,12 @@ func Register(ctx context.Context, w *wrangler.Context, manager *clustermanager.
 		etcdSnapshotRestoreCache: w.Operation.ETCDSnapshotRestore().Cache(),
 		encryptionRotations:      w.Operation.EncryptionKeyRotation(),
 		encryptionRotationCache:  w.Operation.EncryptionKeyRotation().Cache(),
+		certificateRotations:     w.Operation.CertificateRotation(),
+		certificateRotationCache: w.Operation.CertificateRotation().Cache(),
+		kubernetesUpgrades:       w.Operation.KubernetesUpgrade(),
+		kubernetesUpgradeCache:   w.Operation.KubernetesUpgrade().Cache(),
+		customOperations:         w.Operation.CustomOperation(),
+		customOperationCache:     w.Operation.CustomOperation().Cache(),
 		serviceAccounts:          w.Core.ServiceAccount(),
 		serviceAccountCache:      w.Core.ServiceAccount().Cache(),
 		secrets:                  w.Core.Secret(),
@@ -621,7 +633,31 @@ func (h *handler) hasOperations(clusterName string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return len(rotations) > 0, nil
+	if len(rotations) > 0 {
+		return true, nil
+	}
+
+	certRotations, err := h.certificateRotationCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	if len(certRotations) > 0 {
+		return true, nil
+	}
+
+	upgrades, err := h.kubernetesUpgradeCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	if len(upgrades) > 0 {
+		return true, nil
+	}
+
+	customOps, err := h.customOperationCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	return len(customOps) > 0, nil
 }
 
 // deleteOperations deletes imported operation CRs one-by-one and returns true while any are still present.
@@ -671,6 +707,48 @@ func (h *handler) deleteOperations(clusterName string) (bool, error) {
 		}
 	}
 
+	certRotations, err := h.certificateRotationCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for i := range certRotations {
+		remaining = true
+		if certRotations[i].DeletionTimestamp != nil {
+			continue
+		}
+		if err := h.certificateRotations.Delete(certRotations[i].Namespace, certRotations[i].Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	upgrades, err := h.kubernetesUpgradeCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for i := range upgrades {
+		remaining = true
+		if upgrades[i].DeletionTimestamp != nil {
+			continue
+		}
+		if err := h.kubernetesUpgrades.Delete(upgrades[i].Namespace, upgrades[i].Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	customOps, err := h.customOperationCache.List(clusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for i := range customOps {
+		remaining = true
+		if customOps[i].DeletionTimestamp != nil {
+			continue
+		}
+		if err := h.customOperations.Delete(customOps[i].Namespace, customOps[i].Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
 	return remaining, nil
 }
 
@@ -562,14 +562,38 @@ func TestDeleteOperationsDeletesNamespacedOperationsAndSkipsDeleting(t *testing.
 	saveClient := &fakeETCDSnapshotSaveClient{}
 	restoreClient := &fakeETCDSnapshotRestoreClient{}
 	rotationClient := &fakeEncryptionKeyRotationClient{}
+	certRotationCache := &fakeCertificateRotationCache{
+		items: []*opv1alpha1.CertificateRotation{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-m-test", Name: "op-cert-rotation"}},
+		},
+	}
+	certRotationClient := &fakeCertificateRotationClient{}
+	upgradeCache := &fakeKubernetesUpgradeCache{
+		items: []*opv1alpha1.KubernetesUpgrade{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-m-test", Name: "op-upgrade"}},
+		},
+	}
+	upgradeClient := &fakeKubernetesUpgradeClient{}
+	customOpCache := &fakeCustomOperationCache{
+		items: []*opv1alpha1.CustomOperation{
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "c-m-test", Name: "op-custom"}},
+		},
+	}
+	customOpClient := &fakeCustomOperationClient{}
 
 	h := &handler{
 		etcdSnapshotSaveCache:    saveCache,
 		etcdSnapshotRestoreCache: restoreCache,
 		encryptionRotationCache:  rotationCache,
+		certificateRotationCache: certRotationCache,
+		kubernetesUpgradeCache:   upgradeCache,
+		customOperationCache:     customOpCache,
 		etcdSnapshotSaves:        saveClient,
 		etcdSnapshotRestores:     restoreClient,
 		encryptionRotations:      rotationClient,
+		certificateRotations:     certRotationClient,
+		kubernetesUpgrades:       upgradeClient,
+		customOperations:         customOpClient,
 	}
 
 	remaining, err := h.deleteOperations("c-m-test")
@@ -585,10 +609,19 @@ func TestDeleteOperationsDeletesNamespacedOperationsAndSkipsDeleting(t *testing.
 	if len(rotationClient.deleted) != 1 || rotationClient.deleted[0] != (namespacedName{namespace: "c-m-test", name: "op-rotation"}) {
 		t.Fatalf("expected rotation delete for c-m-test/op-rotation, got %+v", rotationClient.deleted)
 	}
+	if len(certRotationClient.deleted) != 1 || certRotationClient.deleted[0] != (namespacedName{namespace: "c-m-test", name: "op-cert-rotation"}) {
+		t.Fatalf("expected certificate rotation delete for c-m-test/op-cert-rotation, got %+v", certRotationClient.deleted)
+	}
+	if len(upgradeClient.deleted) != 1 || upgradeClient.deleted[0] != (namespacedName{namespace: "c-m-test", name: "op-upgrade"}) {
+		t.Fatalf("expected kubernetes upgrade delete for c-m-test/op-upgrade, got %+v", upgradeClient.deleted)
+	}
+	if len(customOpClient.deleted) != 1 || customOpClient.deleted[0] != (namespacedName{namespace: "c-m-test", name: "op-custom"}) {
+		t.Fatalf("expected custom operation delete for c-m-test/op-custom, got %+v", customOpClient.deleted)
+	}
 	if len(restoreClient.deleted) != 0 {
 		t.Fatalf("expected deleting restore to be skipped, got deletes %+v", restoreClient.deleted)
 	}
-	if saveCache.lastNamespace != "c-m-test" || restoreCache.lastNamespace != "c-m-test" || rotationCache.lastNamespace != "c-m-test" {
+	if saveCache.lastNamespace != "c-m-test" || restoreCache.lastNamespace != "c-m-test" || rotationCache.lastNamespace != "c-m-test" || certRotationCache.lastNamespace != "c-m-test" || upgradeCache.lastNamespace != "c-m-test" || customOpCache.lastNamespace != "c-m-test" {
 		t.Fatalf("expected namespace-scoped list for all operation kinds")
 	}
 }
@@ -600,9 +633,15 @@ func TestDeleteOperationsReturnsFalseWhenNoOperationsExist(t *testing.T) {
 		etcdSnapshotSaveCache:    &fakeETCDSnapshotSaveCache{},
 		etcdSnapshotRestoreCache: &fakeETCDSnapshotRestoreCache{},
 		encryptionRotationCache:  &fakeEncryptionKeyRotationCache{},
+		certificateRotationCache: &fakeCertificateRotationCache{},
+		kubernetesUpgradeCache:   &fakeKubernetesUpgradeCache{},
+		customOperationCache:     &fakeCustomOperationCache{},
 		etcdSnapshotSaves:        &fakeETCDSnapshotSaveClient{},
 		etcdSnapshotRestores:     &fakeETCDSnapshotRestoreClient{},
 		encryptionRotations:      &fakeEncryptionKeyRotationClient{},
+		certificateRotations:     &fakeCertificateRotationClient{},
+		kubernetesUpgrades:       &fakeKubernetesUpgradeClient{},
+		customOperations:         &fakeCustomOperationClient{},
 	}
 
 	remaining, err := h.deleteOperations("c-m-empty")
@@ -624,6 +663,9 @@ func TestDisableNeededReturnsTrueForLeftoverImportedPlanIdentity(t *testing.T) {
 		etcdSnapshotSaveCache:    &fakeETCDSnapshotSaveCache{},
 		etcdSnapshotRestoreCache: &fakeETCDSnapshotRestoreCache{},
 		encryptionRotationCache:  &fakeEncryptionKeyRotationCache{},
+		certificateRotationCache: &fakeCertificateRotationCache{},
+		kubernetesUpgradeCache:   &fakeKubernetesUpgradeCache{},
+		customOperationCache:     &fakeCustomOperationCache{},
 		secretCache: &fakeSecretCache{
 			items: []*corev1.Secret{
 				{
@@ -846,6 +888,48 @@ func (f *fakeEncryptionKeyRotationCache) List(namespace string, selector labels.
 	return f.items, f.err
 }
 
+type fakeCertificateRotationCache struct {
+	operationcontrollers.CertificateRotationCache
+	items           []*opv1alpha1.CertificateRotation
+	err             error
+	lastNamespace   string
+	lastHasSelector bool
+}
+
+func (f *fakeCertificateRotationCache) List(namespace string, selector labels.Selector) ([]*opv1alpha1.CertificateRotation, error) {
+	f.lastNamespace = namespace
+	f.lastHasSelector = selector != nil
+	return f.items, f.err
+}
+
+type fakeKubernetesUpgradeCache struct {
+	operationcontrollers.KubernetesUpgradeCache
+	items           []*opv1alpha1.KubernetesUpgrade
+	err             error
+	lastNamespace   string
+	lastHasSelector bool
+}
+
+func (f *fakeKubernetesUpgradeCache) List(namespace string, selector labels.Selector) ([]*opv1alpha1.KubernetesUpgrade, error) {
+	f.lastNamespace = namespace
+	f.lastHasSelector = selector != nil
+	return f.items, f.err
+}
+
+type fakeCustomOperationCache struct {
+	operationcontrollers.CustomOperationCache
+	items           []*opv1alpha1.CustomOperation
+	err             error
+	lastNamespace   string
+	lastHasSelector bool
+}
+
+func (f *fakeCustomOperationCache) List(namespace string, selector labels.Selector) ([]*opv1alpha1.CustomOperation, error) {
+	f.lastNamespace = namespace
+	f.lastHasSelector = selector != nil
+	return f.items, f.err
+}
+
 type fakeETCDSnapshotSaveClient struct {
 	operationcontrollers.ETCDSnapshotSaveClient
 	deleted []namespacedName
@@ -879,6 +963,39 @@ func (f *fakeEncryptionKeyRotationClient) Delete(namespace, name string, _ *meta
 	return f.err
 }
 
+type fakeCertificateRotationClient struct {
+	operationcontrollers.CertificateRotationClient
+	deleted []namespacedName
+	err     error
+}
+
+func (f *fakeCertificateRotationClient) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespacedName{namespace: namespace, name: name})
+	return f.err
+}
+
+type fakeKubernetesUpgradeClient struct {
+	operationcontrollers.KubernetesUpgradeClient
+	deleted []namespacedName
+	err     error
+}
+
+func (f *fakeKubernetesUpgradeClient) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespacedName{namespace: namespace, name: name})
+	return f.err
+}
+
+type fakeCustomOperationClient struct {
+	operationcontrollers.CustomOperationClient
+	deleted []namespacedName
+	err     error
+}
+
+func (f *fakeCustomOperationClient) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespacedName{namespace: namespace, name: name})
+	return f.err
+}
+
 type fakeBeaconCache struct {
 	plancontrollers.BeaconCache
 	beacon    *planv1alpha1.Beacon
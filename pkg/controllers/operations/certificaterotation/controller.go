@@ -0,0 +1,532 @@
+package certificaterotation
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/capr"
+	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	"github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ControllerOwnerKey is the value used to identify the certificate-rotation handler currently owns the beacon.
+	ControllerOwnerKey = "certificate-rotation"
+
+	// Step hook label prefixes for the certificaterotation operation. They follow the shared label
+	// semantics documented on planv1alpha1's phase-hook label constants, but each prefix only fires
+	// when the operation enters the matching step.
+
+	// PreflightStepHookLabelPrefix gates the Preflight step, before the controller verifies that
+	// the nodes targeted by the rotation are present and carry a certificate directory.
+	PreflightStepHookLabelPrefix = "preflight.step.hook.operation.cattle.io/"
+
+	// RotateStepHookLabelPrefix gates the Rotate step, before reconcileRotate pauses the cluster and
+	// assigns the `<runtime> certificate rotate` plan to the targeted etcd and control-plane nodes.
+	// Fires before PauseCluster so a delegate observes the cluster in its pre-pause state.
+	RotateStepHookLabelPrefix = "rotate.step.hook.operation.cattle.io/"
+
+	// RestartStepHookLabelPrefix gates the Restart step, before reconcileRestart assigns the
+	// `systemctl restart <agent-unit>` plan to the targeted worker nodes.
+	RestartStepHookLabelPrefix = "restart.step.hook.operation.cattle.io/"
+
+	// idempotencyKey is the top-level key used to scope idempotency tracking for this controller.
+	idempotencyKey = "certificate-rotation"
+)
+
+var (
+	// workerServices are the services with certificates on every node running the distro agent.
+	workerServices = []string{"api-server", "auth-proxy", "k3s-server", "kube-proxy", "kubelet", "rke2-server"}
+
+	// controlPlaneServices are the services with certificates on control-plane nodes.
+	controlPlaneServices = append(slices.Clone(workerServices), "admin", "cloud-controller", "controller-manager", "k3s-controller", "rke2-controller", "scheduler")
+
+	// etcdServices are the services with certificates on etcd nodes.
+	etcdServices = []string{"etcd", "k3s-server", "kubelet", "rke2-server"}
+)
+
+type (
+	scope = ops.Scope[*opv1alpha1.CertificateRotation]
+	step  = ops.Step[*opv1alpha1.CertificateRotation, opv1alpha1.CertificateRotationStatus, opv1alpha1.CertificateRotationStep]
+)
+
+// handler holds the step reconcilers of the CertificateRotation controller. The shared state
+// machine (beacon ownership, hooks, pause, cancellation and TTL expiry) is driven by an ops.Engine;
+// all fields are populated at Register time and the handler itself is stateless across reconciles.
+type handler struct {
+	secretCache corecontrollers.SecretCache
+
+	store *plan.Store
+}
+
+// Register wires the CertificateRotation controller into the given wrangler context. It must be
+// called exactly once per process; subsequent calls would clobber the registered status handler.
+func Register(ctx context.Context, clients *wrangler.CAPIContext) {
+	h := &handler{
+		secretCache: clients.Core.Secret().Cache(),
+		store:       plan.NewStore(clients.Core.Secret()),
+	}
+	engine := ops.NewEngine(clients, clients.Operation.CertificateRotation(), h.definition())
+
+	operationcontrollers.RegisterCertificateRotationStatusHandler(ctx, clients.Operation.CertificateRotation(), "", "certificate-rotation-handler", engine.OnChange)
+	ops.WatchBeaconQueue(ctx, clients.Plan.Beacon(), opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation"),
+		clients.Operation.CertificateRotation().Cache(), clients.Operation.CertificateRotation(),
+		func(op *opv1alpha1.CertificateRotation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+	ops.RecordOperations(ctx, clients.Operation.CertificateRotation(), clients.Operation.OperationRecord(), clients.Operation.OperationRecord().Cache(),
+		opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation"),
+		func(op *opv1alpha1.CertificateRotation) *opv1alpha1.OperationSpec {
+			return &op.Spec.OperationSpec
+		},
+		func(op *opv1alpha1.CertificateRotation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+}
+
+// definition returns the engine definition of the CertificateRotation operation: Preflight checks
+// the requested services and the certificate directory of every targeted server, Rotate pauses the
+// cluster and rotates the certificates of the etcd and control-plane nodes one at a time, and
+// Restart restarts the agent of the worker-only nodes one at a time.
+func (h *handler) definition() ops.Definition[*opv1alpha1.CertificateRotation, opv1alpha1.CertificateRotationStatus, opv1alpha1.CertificateRotationStep] {
+	return ops.Definition[*opv1alpha1.CertificateRotation, opv1alpha1.CertificateRotationStatus, opv1alpha1.CertificateRotationStep]{
+		Name:             ControllerOwnerKey,
+		GroupVersionKind: opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation"),
+		Steps: []step{
+			{
+				Name:            opv1alpha1.CertificateRotationStepPreflight,
+				HookLabelPrefix: PreflightStepHookLabelPrefix,
+				Reconcile:       h.reconcilePreflight,
+				Timeout:         15 * time.Minute,
+				Preview:         h.previewPreflight,
+			},
+			{
+				Name:            opv1alpha1.CertificateRotationStepRotate,
+				HookLabelPrefix: RotateStepHookLabelPrefix,
+				PauseCluster:    true,
+				Reconcile:       h.reconcileRotate,
+				Timeout:         time.Hour,
+				Preview:         h.previewRotate,
+			},
+			{
+				Name:            opv1alpha1.CertificateRotationStepRestart,
+				HookLabelPrefix: RestartStepHookLabelPrefix,
+				Reconcile:       h.reconcileRestart,
+				Timeout:         time.Hour,
+				Preview:         h.previewRestart,
+			},
+		},
+		Spec:   func(op *opv1alpha1.CertificateRotation) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		Status: func(op *opv1alpha1.CertificateRotation) *opv1alpha1.CertificateRotationStatus { return &op.Status },
+		OperationStatus: func(status *opv1alpha1.CertificateRotationStatus) *opv1alpha1.OperationStatus {
+			return &status.OperationStatus
+		},
+		Step: func(status *opv1alpha1.CertificateRotationStatus) *opv1alpha1.CertificateRotationStep {
+			return &status.Step
+		},
+	}
+}
+
+// idempotencyValue scopes the idempotent instructions to the operation, so a plan that is
+// re-delivered to the agent never runs the same action twice.
+func idempotencyValue(s *scope) string {
+	return string(s.Op.UID)
+}
+
+// reconcilePreflight verifies that the requested services are valid for the cluster's distro and
+// that every targeted server node has a certificate directory under the distro data-dir. The
+// operation is Canceled if no server node is targeted by the requested services, since there would
+// be nothing to rotate.
+func (h *handler) reconcilePreflight(s *scope, status *opv1alpha1.CertificateRotationStatus) (bool, error) {
+	logrus.Debugf("[certificaterotation] %s/%s: handling preflight", s.Op.Namespace, s.Op.Name)
+
+	if invalid := unsupportedServices(s.Adapter.RuntimeCommand(), s.Op.Spec.Args.Services); len(invalid) > 0 {
+		logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: services %v are not supported by %s", s.Op.Namespace, s.Op.Name, invalid, s.Adapter.RuntimeCommand())
+		ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("services %v are not supported by %s", invalid, s.Adapter.RuntimeCommand()))
+		return false, nil
+	}
+
+	secrets, err := h.collectServers(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
+	}
+
+	concurrency := len(secrets)
+	results := make([]plan.PlanStatus, 0, concurrency)
+
+	for _, secret := range secrets {
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, preflightPlan(s, secret), 1, -1)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+
+		if planStatus.Failure() {
+			logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: preflight check failed for %s/%s",
+				s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("could not find certificate directory for %s/%s", secret.Namespace, secret.Name))
+			return false, nil
+		}
+
+		if planStatus.Waiting() {
+			logrus.Debugf("[certificaterotation] %s/%s: waiting for preflight check for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+
+			concurrency--
+			if concurrency <= 0 {
+				break
+			}
+		}
+	}
+
+	if concurrency < len(secrets) {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// reconcileRotate walks the targeted etcd and control-plane nodes one at a time in
+// plan.DefaultSorter order (init+etcd first, then etcd-only, then mixed, then control-plane-only).
+// Each node stops the server unit, runs `<runtime> certificate rotate` for the requested services,
+// clears the self-signed kube-controller-manager/kube-scheduler serving certificates when those
+// services are rotated, and starts the server unit again. The next node is only handed a plan once
+// the current node has applied and its probes pass. The engine pauses the cluster before the step
+// runs so the planner does not race with the rotation plans.
+//
+// Every instruction is wrapped with the idempotent action script keyed on the operation UID, so a
+// plan that is re-delivered to the agent never rotates the same node twice.
+func (h *handler) reconcileRotate(s *scope, status *opv1alpha1.CertificateRotationStatus) (bool, error) {
+	logrus.Debugf("[certificaterotation] %s/%s: handling certificate rotate", s.Op.Namespace, s.Op.Name)
+
+	secrets, err := h.collectServers(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
+	}
+
+	return h.assignSerially(s, status, secrets, rotatePlan, "certificate rotation")
+}
+
+// reconcileRestart restarts the distro agent unit on worker-only nodes, one at a time, so that the
+// agents reconnect to the servers with the rotated certificates and regenerate their own client
+// certificates. Workers are skipped entirely when none of the requested services live on them.
+// Windows workers are excluded, as the agent unit is not managed by systemd on those nodes.
+func (h *handler) reconcileRestart(s *scope, status *opv1alpha1.CertificateRotationStatus) (bool, error) {
+	logrus.Debugf("[certificaterotation] %s/%s: handling agent restart", s.Op.Namespace, s.Op.Name)
+
+	secrets, err := h.collectWorkers(s).Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
+	}
+
+	return h.assignSerially(s, status, secrets, restartPlan, "agent restart")
+}
+
+// assignSerially assigns the plan built by nodePlan to secrets one at a time, only moving on to the
+// next node once the current one has applied its plan. It fails the operation as soon as a node
+// fails its plan and returns true once every node has applied it.
+func (h *handler) assignSerially(s *scope, status *opv1alpha1.CertificateRotationStatus, secrets []*corev1.Secret, nodePlan func(*scope, *corev1.Secret) (*plan.Plan, error), action string) (bool, error) {
+	results := make([]plan.PlanStatus, 0, len(secrets))
+
+	for _, secret := range secrets {
+		p, err := nodePlan(s, secret)
+		if err != nil {
+			return false, err
+		}
+
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, p, 1, -1)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+
+		if planStatus.Failure() {
+			logrus.Errorf("[certificaterotation] %s/%s: marking operation as failed: failed to apply plan for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("%s failed for %s/%s", action, secret.Namespace, secret.Name))
+			return false, nil
+		}
+
+		if planStatus.Waiting() {
+			logrus.Debugf("[certificaterotation] %s/%s: waiting for %s for %s/%s", s.Op.Namespace, s.Op.Name, action, secret.Namespace, secret.Name)
+			ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// collectServers returns a Collector for the etcd and control-plane machine-plan secrets that hold
+// at least one of the certificates requested for rotation, sorted with plan.DefaultSorter.
+func (h *handler) collectServers(s *scope) *plan.Collector {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithLabels(plan.Or(
+			plan.Label(capr.EtcdRoleLabel, "true"),
+			plan.Label(capr.ControlPlaneRoleLabel, "true"),
+		)).
+		WithFilter(plan.FilterFunc(serverFilter(s.Op.Spec.Args.Services))).
+		WithSorter(plan.DefaultSorter())
+}
+
+// collectWorkers returns a Collector for the worker-only machine-plan secrets restarted by the
+// Restart step, sorted with plan.DefaultSorter.
+func (h *handler) collectWorkers(s *scope) *plan.Collector {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithLabels(plan.Label(capr.WorkerRoleLabel, "true")).
+		WithFilter(plan.FilterFunc(workerFilter(s.Op.Spec.Args.Services))).
+		WithSorter(plan.DefaultSorter())
+}
+
+// previewPreflight renders the plans of the Preflight step for a dry run. A non-empty message means
+// the operation would fail its preflight checks.
+func (h *handler) previewPreflight(s *scope, preview *ops.PlanPreview) (string, error) {
+	if invalid := unsupportedServices(s.Adapter.RuntimeCommand(), s.Op.Spec.Args.Services); len(invalid) > 0 {
+		return fmt.Sprintf("services %v are not supported by %s", invalid, s.Adapter.RuntimeCommand()), nil
+	}
+
+	servers, err := h.collectServers(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+
+	for _, secret := range servers {
+		preview.Add(string(opv1alpha1.CertificateRotationStepPreflight), secret, preflightPlan(s, secret), 1, -1)
+	}
+	return "", nil
+}
+
+// previewRotate renders the plans of the Rotate step for a dry run. A non-empty message means the
+// operation would fail on the current cluster state.
+func (h *handler) previewRotate(s *scope, preview *ops.PlanPreview) (string, error) {
+	servers, err := h.collectServers(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+
+	for _, secret := range servers {
+		nodePlan, err := rotatePlan(s, secret)
+		if err != nil {
+			return "", err
+		}
+		preview.Add(string(opv1alpha1.CertificateRotationStepRotate), secret, nodePlan, 1, -1)
+	}
+	return "", nil
+}
+
+// previewRestart renders the plans of the Restart step for a dry run.
+func (h *handler) previewRestart(s *scope, preview *ops.PlanPreview) (string, error) {
+	workers, err := h.collectWorkers(s).Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+
+	for _, secret := range workers {
+		nodePlan, err := restartPlan(s, secret)
+		if err != nil {
+			return "", err
+		}
+		preview.Add(string(opv1alpha1.CertificateRotationStepRestart), secret, nodePlan, 1, -1)
+	}
+	return "", nil
+}
+
+// preflightPlan builds the plan checking that a single server node has a certificate directory
+// under the distro data-dir.
+func preflightPlan(s *scope, secret *corev1.Secret) *plan.Plan {
+	return &plan.Plan{
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    "preflight",
+					Command: "/bin/sh",
+					Args: []string{
+						"-c",
+						fmt.Sprintf("test -d %s", path.Join(s.Adapter.DistroDataDirectory(secret), "server/tls")),
+					},
+				},
+			},
+		},
+	}
+}
+
+// rotatePlan builds the plan rotating the certificates of a single etcd or control-plane node.
+func rotatePlan(s *scope, secret *corev1.Secret) (*plan.Plan, error) {
+	probes, err := s.Adapter.RenderProbes(secret, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan.Plan{
+		Files:               []plan.File{ops.IdempotentScriptFile(s.Adapter.ProvisioningDataDirectory(secret))},
+		OneTimeInstructions: rotateInstructions(s, secret),
+		Probes:              probes,
+	}, nil
+}
+
+// restartPlan builds the plan restarting the agent unit of a single worker-only node.
+func restartPlan(s *scope, secret *corev1.Secret) (*plan.Plan, error) {
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(secret)
+
+	probes, err := s.Adapter.RenderProbes(secret, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan.Plan{
+		Files: []plan.File{ops.IdempotentScriptFile(provisioningDir)},
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/restart", idempotencyValue(s), "systemctl",
+				[]string{"restart", s.Adapter.AgentUnit()}, nil),
+		},
+		Probes: probes,
+	}, nil
+}
+
+// rotateInstructions builds the one-time instructions for rotating the certificates of a single
+// etcd or control-plane node.
+func rotateInstructions(s *scope, secret *corev1.Secret) []plan.OneTimeInstruction {
+	var (
+		provisioningDir = s.Adapter.ProvisioningDataDirectory(secret)
+		dataDir         = s.Adapter.DistroDataDirectory(secret)
+		value           = idempotencyValue(s)
+		services        = s.Op.Spec.Args.Services
+	)
+
+	args := []string{"certificate", "rotate"}
+	for _, service := range services {
+		args = append(args, "-s", service)
+	}
+
+	instructions := []plan.OneTimeInstruction{
+		ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/stop", value, "systemctl",
+			[]string{"stop", s.Adapter.ServerUnit()}, nil),
+		ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/rotate", value, s.Adapter.RuntimeCommand(), args, nil),
+	}
+
+	// The kube-controller-manager and kube-scheduler serving certificates are self-signed by the
+	// respective services and are only regenerated when missing. On RKE2 the static pod manifests
+	// must be removed too, so the kubelet recreates the pods with the new certificates.
+	if ops.IsControlPlane(secret) {
+		components := []struct {
+			service  string
+			name     string
+			certDir  string
+			certFile string
+		}{
+			{"controller-manager", "kcm", ops.DefaultKubeControllerManagerCertDir, ops.DefaultKubeControllerManagerCert},
+			{"scheduler", "ks", ops.DefaultKubeSchedulerCertDir, ops.DefaultKubeSchedulerCert},
+		}
+		for _, c := range components {
+			if !containsService(services, c.service) {
+				continue
+			}
+			rmArgs := []string{
+				"-f",
+				path.Join(dataDir, c.certDir, c.certFile),
+				path.Join(dataDir, c.certDir, strings.ReplaceAll(c.certFile, ".crt", ".key")),
+			}
+			if s.Adapter.RuntimeCommand() == capr.RuntimeRKE2 {
+				rmArgs = append(rmArgs, path.Join(dataDir, "agent/pod-manifests", strings.TrimSuffix(c.certFile, ".crt")+".yaml"))
+			}
+			instructions = append(instructions, ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/rm-"+c.name, value, "rm", rmArgs, nil))
+		}
+	}
+
+	return append(instructions, ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/start", value, "systemctl",
+		[]string{"start", s.Adapter.ServerUnit()}, nil))
+}
+
+// containsService reports whether service is requested for rotation. An empty services list means
+// every service is rotated.
+func containsService(services []string, service string) bool {
+	return len(services) == 0 || slices.Contains(services, service)
+}
+
+// containsAnyService reports whether any of candidates is requested for rotation.
+func containsAnyService(services []string, candidates []string) bool {
+	if len(services) == 0 {
+		return true
+	}
+	for _, candidate := range candidates {
+		if slices.Contains(services, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverFilter returns a filter matching etcd and control-plane nodes that hold a certificate for
+// at least one of the requested services.
+func serverFilter(services []string) ops.Filter {
+	return func(secret *corev1.Secret) bool {
+		if ops.IsEtcd(secret) && containsAnyService(services, etcdServices) {
+			return true
+		}
+		return ops.IsControlPlane(secret) && containsAnyService(services, controlPlaneServices)
+	}
+}
+
+// workerFilter returns a filter matching the linux worker-only nodes that hold a certificate for at
+// least one of the requested services. Nodes that also carry the etcd or control-plane role are
+// handled by the Rotate step instead.
+func workerFilter(services []string) ops.Filter {
+	return func(secret *corev1.Secret) bool {
+		if ops.IsEtcd(secret) || ops.IsControlPlane(secret) || ops.IsWindows(secret) {
+			return false
+		}
+		return ops.IsWorker(secret) && containsAnyService(services, workerServices)
+	}
+}
+
+// unsupportedServices returns the requested services that belong to the other distro, e.g.
+// rke2-server on a K3s cluster. The distro CLI rejects these outright, so they are caught during
+// preflight rather than after the first server has already been stopped.
+func unsupportedServices(runtime string, services []string) []string {
+	var unsupported []string
+	for _, service := range services {
+		if distro, _, found := strings.Cut(service, "-"); found && (distro == capr.RuntimeRKE2 || distro == capr.RuntimeK3S) && distro != runtime {
+			unsupported = append(unsupported, service)
+		}
+	}
+	return unsupported
+}
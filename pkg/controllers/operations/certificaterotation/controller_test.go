@@ -0,0 +1,456 @@
+package certificaterotation
+
+import (
+	"encoding/json"
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/capr"
+	ops "github.com/rancher/rancher/pkg/operations"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// stubAdapter is a minimal ops.Adapter implementation for tests. PauseCluster records every call
+// so tests can assert the steps never pause the cluster themselves.
+type stubAdapter struct {
+	runtimeCommand    string
+	dataDir           string
+	provisioningDir   string
+	serverUnit        string
+	waitForRegisterOK bool
+	probes            map[string]planapi.Probe
+	pauses            []bool
+}
+
+func (a *stubAdapter) BeaconRef() (string, string)   { return "test-namespace", "test-cluster" }
+func (a *stubAdapter) EtcdSnapshotNamespace() string { return "test-namespace" }
+func (a *stubAdapter) ClusterObject() (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+func (a *stubAdapter) WaitForRegister() (bool, error) { return a.waitForRegisterOK, nil }
+func (a *stubAdapter) PauseCluster(pause bool) error {
+	a.pauses = append(a.pauses, pause)
+	return nil
+}
+func (a *stubAdapter) RuntimeCommand() string                            { return a.runtimeCommand }
+func (a *stubAdapter) DistroDataDirectory(_ *corev1.Secret) string       { return a.dataDir }
+func (a *stubAdapter) ProvisioningDataDirectory(_ *corev1.Secret) string { return a.provisioningDir }
+func (a *stubAdapter) ServerUnit() string                                { return a.serverUnit }
+func (a *stubAdapter) AgentUnit() string                                 { return a.runtimeCommand + "-agent" }
+func (a *stubAdapter) InstallerImage(v string) string                    { return "installer:" + v }
+func (a *stubAdapter) RenderProbes(_ *corev1.Secret, _ bool) (map[string]planapi.Probe, error) {
+	return a.probes, nil
+}
+func (a *stubAdapter) KubectlPath(_ *corev1.Secret) string { return a.dataDir + "/bin/kubectl" }
+func (a *stubAdapter) KubeconfigPath(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/" + a.runtimeCommand + ".yaml"
+}
+func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+func (a *stubAdapter) ConfigFile(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/config.yaml"
+}
+func (a *stubAdapter) ConfigDirectory(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/config.yaml.d"
+}
+func (a *stubAdapter) GetServerURL(_ *corev1.Secret) string      { return "" }
+func (a *stubAdapter) GetSupervisorPort(_ *corev1.Secret) string { return "9345" }
+func (a *stubAdapter) LoopbackAddress(_ *corev1.Secret) string   { return "127.0.0.1" }
+func (a *stubAdapter) ToS3ArgsEnvAndFiles(_ *corev1.Secret) ([]string, []string, []planapi.File) {
+	return nil, nil, nil
+}
+
+func defaultAdapter() *stubAdapter {
+	return &stubAdapter{
+		runtimeCommand:  "rke2",
+		dataDir:         "/var/lib/rancher/rke2",
+		provisioningDir: "/var/lib/rancher/capr",
+		serverUnit:      "rke2-server",
+	}
+}
+
+func newScope(op *opv1alpha1.CertificateRotation, adapter *stubAdapter) *scope {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetName("test")
+	cluster.SetNamespace("fleet-default")
+	cluster.SetAPIVersion("provisioning.cattle.io/v1")
+	cluster.SetKind("Cluster")
+	return &scope{
+		OwnerKey:  planapi.ControllerOwnerKey(op, ControllerOwnerKey),
+		Op:        op,
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
+	}
+}
+
+func newOp(services ...string) *opv1alpha1.CertificateRotation {
+	return &opv1alpha1.CertificateRotation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rotate-1",
+			Namespace: "fleet-default",
+			UID:       "op-uid",
+		},
+		Spec: opv1alpha1.CertificateRotationSpec{
+			Args: opv1alpha1.CertificateRotationArgs{Services: services},
+		},
+	}
+}
+
+// newPlanSecret builds a machine-plan secret for the test cluster carrying the given role labels.
+func newPlanSecret(name string, roles ...string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "fleet-default",
+			UID:         types.UID(name + "-uid"),
+			Annotations: map[string]string{},
+			Labels: map[string]string{
+				capr.ClusterNameLabel: "test",
+			},
+		},
+		Type: planapi.SecretTypeMachinePlan,
+	}
+	for _, role := range roles {
+		secret.Labels[role] = "true"
+	}
+	return secret
+}
+
+func withAppliedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	if out.Data == nil {
+		out.Data = map[string][]byte{}
+	}
+	out.Data["plan"] = data
+	out.Data["appliedPlan"] = data
+	out.Data["probe-statuses"] = []byte(`{"x":{"healthy":true}}`)
+	out.Annotations[planapi.PlanProbesPassedAnnotation] = "applied"
+	return out
+}
+
+func withFailedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	if out.Data == nil {
+		out.Data = map[string][]byte{}
+	}
+	out.Data["plan"] = data
+	out.Data["failed-checksum"] = []byte(planapi.PlanHash(data))
+	out.Data["failure-count"] = []byte("5")
+	out.Data["max-failures"] = []byte("1")
+	out.Data["failure-threshold"] = []byte("1")
+	return out
+}
+
+// newHandler returns a handler collecting the given machine-plan secrets from an indexed secret
+// cache. Every secret updated through its store is recorded in updated so tests can assert which
+// nodes received a plan.
+func newHandler(t *testing.T, ctrl *gomock.Controller, updated *[]string, items ...*corev1.Secret) *handler {
+	t.Helper()
+	m := ctrlfake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+	m.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		if updated != nil {
+			*updated = append(*updated, s.Name)
+		}
+		return s, nil
+	}).AnyTimes()
+	// Replacing a plan records it in a plan history secret, which does not exist yet.
+	m.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}).AnyTimes()
+	m.EXPECT().Create(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		return s, nil
+	}).AnyTimes()
+	return &handler{
+		secretCache: newSecretCache(t, items...),
+		store:       planapi.NewStore(m),
+	}
+}
+
+// newSecretCache returns a secret cache with the plan indexers registered, holding items.
+func newSecretCache(t *testing.T, items ...*corev1.Secret) corecontrollers.SecretCache {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := generic.NewCache[*corev1.Secret](indexer, corev1.Resource("secrets"))
+	planapi.RegisterIndexers(c)
+	for _, item := range items {
+		if err := indexer.Add(item); err != nil {
+			t.Fatalf("adding %s: %v", item.Name, err)
+		}
+	}
+	return c
+}
+
+func expectedRotatePlan(s *scope, secret *corev1.Secret) *planapi.Plan {
+	return &planapi.Plan{
+		Files:               []planapi.File{ops.IdempotentScriptFile(s.Adapter.ProvisioningDataDirectory(secret))},
+		OneTimeInstructions: rotateInstructions(s, secret),
+		Probes:              s.Adapter.(*stubAdapter).probes,
+	}
+}
+
+// instructionCommands flattens the wrapped command + args of each idempotent instruction so tests
+// can assert on what will run on the node without depending on the generated instruction names.
+func instructionCommands(instructions []planapi.OneTimeInstruction) [][]string {
+	out := make([][]string, 0, len(instructions))
+	for _, i := range instructions {
+		// Args: -x <script> <key> <hashedValue> <hashedCommand> <command> <provisioningDir> <args...>
+		out = append(out, append([]string{i.Args[5]}, i.Args[7:]...))
+	}
+	return out
+}
+
+// --- service selection ----------------------------------------------------------------------
+
+func TestServerAndWorkerFilters(t *testing.T) {
+	t.Parallel()
+
+	etcd := newPlanSecret("etcd", capr.EtcdRoleLabel)
+	cp := newPlanSecret("cp", capr.ControlPlaneRoleLabel)
+	worker := newPlanSecret("worker", capr.WorkerRoleLabel)
+	windows := newPlanSecret("windows", capr.WorkerRoleLabel)
+	windows.Labels[capr.CattleOSLabel] = "windows"
+
+	cases := []struct {
+		name     string
+		services []string
+		servers  map[string]bool
+		workers  map[string]bool
+	}{
+		{
+			name:     "all services",
+			services: nil,
+			servers:  map[string]bool{"etcd": true, "cp": true},
+			workers:  map[string]bool{"worker": true},
+		},
+		{
+			name:     "api-server only",
+			services: []string{"api-server"},
+			servers:  map[string]bool{"cp": true},
+			workers:  map[string]bool{"worker": true},
+		},
+		{
+			name:     "etcd only",
+			services: []string{"etcd"},
+			servers:  map[string]bool{"etcd": true},
+			workers:  map[string]bool{},
+		},
+		{
+			name:     "scheduler only",
+			services: []string{"scheduler"},
+			servers:  map[string]bool{"cp": true},
+			workers:  map[string]bool{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			servers, workers := serverFilter(tc.services), workerFilter(tc.services)
+			for _, secret := range []*corev1.Secret{etcd, cp, worker, windows} {
+				assert.Equal(t, tc.servers[secret.Name], servers(secret), "serverFilter mismatch for %s", secret.Name)
+				assert.Equal(t, tc.workers[secret.Name], workers(secret), "workerFilter mismatch for %s", secret.Name)
+			}
+		})
+	}
+}
+
+func TestUnsupportedServices(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, unsupportedServices("rke2", []string{"rke2-server", "api-server", "auth-proxy"}))
+	assert.Equal(t, []string{"k3s-server", "k3s-controller"}, unsupportedServices("rke2", []string{"k3s-server", "kubelet", "k3s-controller"}))
+	assert.Equal(t, []string{"rke2-controller"}, unsupportedServices("k3s", []string{"rke2-controller"}))
+}
+
+func TestRotateInstructions(t *testing.T) {
+	t.Parallel()
+
+	cp := newPlanSecret("cp", capr.ControlPlaneRoleLabel)
+	etcd := newPlanSecret("etcd", capr.EtcdRoleLabel)
+
+	t.Run("api-server only does not touch serving certs", func(t *testing.T) {
+		s := newScope(newOp("api-server"), defaultAdapter())
+		assert.Equal(t, [][]string{
+			{"systemctl", "stop", "rke2-server"},
+			{"rke2", "certificate", "rotate", "-s", "api-server"},
+			{"systemctl", "start", "rke2-server"},
+		}, instructionCommands(rotateInstructions(s, cp)))
+	})
+
+	t.Run("controller-manager removes serving cert and static pod manifest on rke2", func(t *testing.T) {
+		s := newScope(newOp("controller-manager"), defaultAdapter())
+		assert.Equal(t, [][]string{
+			{"systemctl", "stop", "rke2-server"},
+			{"rke2", "certificate", "rotate", "-s", "controller-manager"},
+			{"rm", "-f",
+				"/var/lib/rancher/rke2/server/tls/kube-controller-manager/kube-controller-manager.crt",
+				"/var/lib/rancher/rke2/server/tls/kube-controller-manager/kube-controller-manager.key",
+				"/var/lib/rancher/rke2/agent/pod-manifests/kube-controller-manager.yaml"},
+			{"systemctl", "start", "rke2-server"},
+		}, instructionCommands(rotateInstructions(s, cp)))
+	})
+
+	t.Run("all services on k3s removes both serving certs", func(t *testing.T) {
+		a := defaultAdapter()
+		a.runtimeCommand, a.dataDir, a.serverUnit = "k3s", "/var/lib/rancher/k3s", "k3s"
+		got := instructionCommands(rotateInstructions(newScope(newOp(), a), cp))
+		assert.Len(t, got, 5)
+		assert.Equal(t, []string{"k3s", "certificate", "rotate"}, got[1])
+		assert.Equal(t, []string{"rm", "-f",
+			"/var/lib/rancher/k3s/server/tls/kube-scheduler/kube-scheduler.crt",
+			"/var/lib/rancher/k3s/server/tls/kube-scheduler/kube-scheduler.key"}, got[3])
+	})
+
+	t.Run("etcd node never removes serving certs", func(t *testing.T) {
+		s := newScope(newOp(), defaultAdapter())
+		assert.Len(t, rotateInstructions(s, etcd), 3)
+	})
+}
+
+// --- reconcile steps ------------------------------------------------------------------------
+
+func TestDefinition_PausesOnlyDuringRotate(t *testing.T) {
+	t.Parallel()
+
+	def := (&handler{}).definition()
+
+	names := make([]opv1alpha1.CertificateRotationStep, 0, len(def.Steps))
+	for _, step := range def.Steps {
+		names = append(names, step.Name)
+		assert.Equal(t, step.Name == opv1alpha1.CertificateRotationStepRotate, step.PauseCluster, "step %s", step.Name)
+	}
+	assert.Equal(t, []opv1alpha1.CertificateRotationStep{
+		opv1alpha1.CertificateRotationStepPreflight,
+		opv1alpha1.CertificateRotationStepRotate,
+		opv1alpha1.CertificateRotationStepRestart,
+	}, names)
+}
+
+func TestReconcilePreflight_UnsupportedServiceFails(t *testing.T) {
+	t.Parallel()
+
+	h := &handler{}
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcilePreflight(newScope(newOp("k3s-server"), defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcilePreflight_NoTargetedServersFails(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	h := newHandler(t, ctrl, nil, newPlanSecret("etcd", capr.EtcdRoleLabel))
+
+	// kube-proxy certificates do not live on etcd-only nodes.
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcilePreflight(newScope(newOp("kube-proxy"), defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcileRotate_OneNodeAtATime(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	a := defaultAdapter()
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		newPlanSecret("cp-1", capr.ControlPlaneRoleLabel),
+		newPlanSecret("etcd-1", capr.EtcdRoleLabel, capr.InitNodeLabel),
+	)
+
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcileRotate(newScope(newOp(), a), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Empty(t, a.pauses, "the engine pauses the cluster before the step runs")
+	assert.Equal(t, []string{"etcd-1"}, updated, "only the first node in sorted order may receive a plan")
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+}
+
+func TestReconcileRotate_CompletesWhenApplied(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	op := newOp("api-server")
+	s := newScope(op, defaultAdapter())
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	h := newHandler(t, ctrl, nil, withAppliedPlan(cp, expectedRotatePlan(s, cp)))
+
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcileRotate(s, &status)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, string(status.Phase))
+}
+
+func TestReconcileRotate_PlanFailureMarksFailed(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	s := newScope(newOp(), defaultAdapter())
+	etcd := newPlanSecret("etcd-1", capr.EtcdRoleLabel)
+	h := newHandler(t, ctrl, nil, withFailedPlan(etcd, expectedRotatePlan(s, etcd)))
+
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcileRotate(s, &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcileRestart_SkipsWorkersForServerOnlyServices(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	var updated []string
+	h := newHandler(t, ctrl, &updated, newPlanSecret("worker-1", capr.WorkerRoleLabel))
+
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcileRestart(newScope(newOp("etcd"), defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.Empty(t, updated, "workers hold no etcd certificates and must not be restarted")
+	assert.True(t, done)
+}
+
+func TestReconcileRestart_RestartsAgentOnWorkers(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		newPlanSecret("worker-1", capr.WorkerRoleLabel),
+		newPlanSecret("cp-1", capr.ControlPlaneRoleLabel, capr.WorkerRoleLabel),
+	)
+
+	status := opv1alpha1.CertificateRotationStatus{}
+	done, err := h.reconcileRestart(newScope(newOp("kubelet"), defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []string{"worker-1"}, updated, "server nodes were already restarted during Rotate")
+	assert.Empty(t, string(status.Phase))
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+}
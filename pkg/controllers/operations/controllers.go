@@ -3,14 +3,41 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/controllers/operations/certificaterotation"
+	"github.com/rancher/rancher/pkg/controllers/operations/customoperation"
 	"github.com/rancher/rancher/pkg/controllers/operations/encryptionkeyrotation"
 	"github.com/rancher/rancher/pkg/controllers/operations/etcdsnapshotrestore"
 	"github.com/rancher/rancher/pkg/controllers/operations/etcdsnapshotsave"
+	"github.com/rancher/rancher/pkg/controllers/operations/kubernetesupgrade"
+	"github.com/rancher/rancher/pkg/controllers/operations/operationset"
+	ops "github.com/rancher/rancher/pkg/operations"
 	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
 )
 
 func Register(ctx context.Context, clients *wrangler.CAPIContext) {
+	// Attributes operations to the user creating them for their OperationRecord. Clusters whose API
+	// server does not serve MutatingAdmissionPolicies still run operations; their records simply
+	// carry no creator.
+	if err := ops.ApplyCreatedByPolicy(clients.Apply); err != nil {
+		logrus.Warnf("[operations] failed to apply admission policy %s, operations will not be attributed to their creator: %v", ops.CreatedByPolicyName, err)
+	}
+
+	certificaterotation.Register(ctx, clients)
 	encryptionkeyrotation.Register(ctx, clients)
 	etcdsnapshotsave.Register(ctx, clients)
 	etcdsnapshotrestore.Register(ctx, clients)
+	kubernetesupgrade.Register(ctx, clients)
+	customoperation.Register(ctx, clients)
+	operationset.Register(ctx, clients)
+
+	ops.ObserveOperations(ctx, clients)
+	ops.ObserveBeacons(ctx, clients.Plan.Beacon(), map[string]string{
+		certificaterotation.ControllerOwnerKey:   "CertificateRotation",
+		encryptionkeyrotation.ControllerOwnerKey: "EncryptionKeyRotation",
+		etcdsnapshotsave.ControllerOwnerKey:      "ETCDSnapshotSave",
+		etcdsnapshotrestore.ControllerOwnerKey:   "ETCDSnapshotRestore",
+		kubernetesupgrade.ControllerOwnerKey:     "KubernetesUpgrade",
+		customoperation.ControllerOwnerKey:       "CustomOperation",
+	})
 }
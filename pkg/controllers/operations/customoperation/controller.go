@@ -0,0 +1,306 @@
+package customoperation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	"github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// ControllerOwnerKey is the value used to identify the custom-operation handler currently owns the beacon.
+	ControllerOwnerKey = "custom-operation"
+
+	// StepHookLabelSuffix is appended to the name of a template step to build the step hook label
+	// prefix gating it, e.g. "restart-containerd.step.hook.operation.cattle.io/". It follows the
+	// shared label semantics documented on planv1alpha1's phase-hook label constants.
+	StepHookLabelSuffix = ".step.hook.operation.cattle.io/"
+
+	// idempotencyKey is the top-level key used to scope idempotency tracking for this controller.
+	idempotencyKey = "custom-operation"
+)
+
+type (
+	scope  = ops.Scope[*opv1alpha1.CustomOperation]
+	step   = ops.Step[*opv1alpha1.CustomOperation, opv1alpha1.CustomOperationStatus, opv1alpha1.CustomOperationStep]
+	engine = ops.Engine[*opv1alpha1.CustomOperation, opv1alpha1.CustomOperationStatus, opv1alpha1.CustomOperationStep]
+)
+
+// handler runs CustomOperations. The steps of an operation are only known once its template has
+// been resolved, so a fresh Engine is built from the template on every reconcile.
+type handler struct {
+	customOperations operationcontrollers.CustomOperationController
+	templates        operationcontrollers.OperationTemplateCache
+
+	secretCache corecontrollers.SecretCache
+
+	store *plan.Store
+
+	newEngine func(steps []step) *engine
+}
+
+// Register wires the CustomOperation controller into the given wrangler context. It must be
+// called exactly once per process; subsequent calls would clobber the registered status handler.
+func Register(ctx context.Context, clients *wrangler.CAPIContext) {
+	h := &handler{
+		customOperations: clients.Operation.CustomOperation(),
+		templates:        clients.Operation.OperationTemplate().Cache(),
+		secretCache:      clients.Core.Secret().Cache(),
+		store:            plan.NewStore(clients.Core.Secret()),
+	}
+	h.newEngine = func(steps []step) *engine {
+		return ops.NewEngine(clients, h.customOperations, definition(steps))
+	}
+
+	operationcontrollers.RegisterCustomOperationStatusHandler(ctx, clients.Operation.CustomOperation(), "", "custom-operation-handler", h.OnChange)
+	ops.WatchBeaconQueue(ctx, clients.Plan.Beacon(), opv1alpha1.SchemeGroupVersion.WithKind("CustomOperation"),
+		clients.Operation.CustomOperation().Cache(), clients.Operation.CustomOperation(),
+		func(op *opv1alpha1.CustomOperation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+	ops.RecordOperations(ctx, clients.Operation.CustomOperation(), clients.Operation.OperationRecord(), clients.Operation.OperationRecord().Cache(),
+		opv1alpha1.SchemeGroupVersion.WithKind("CustomOperation"),
+		func(op *opv1alpha1.CustomOperation) *opv1alpha1.OperationSpec {
+			return &op.Spec.OperationSpec
+		},
+		func(op *opv1alpha1.CustomOperation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+}
+
+// definition returns the engine definition of a CustomOperation running the given steps.
+func definition(steps []step) ops.Definition[*opv1alpha1.CustomOperation, opv1alpha1.CustomOperationStatus, opv1alpha1.CustomOperationStep] {
+	return ops.Definition[*opv1alpha1.CustomOperation, opv1alpha1.CustomOperationStatus, opv1alpha1.CustomOperationStep]{
+		Name:             ControllerOwnerKey,
+		GroupVersionKind: opv1alpha1.SchemeGroupVersion.WithKind("CustomOperation"),
+		Steps:            steps,
+		Spec:             func(op *opv1alpha1.CustomOperation) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		Status:           func(op *opv1alpha1.CustomOperation) *opv1alpha1.CustomOperationStatus { return &op.Status },
+		OperationStatus: func(status *opv1alpha1.CustomOperationStatus) *opv1alpha1.OperationStatus {
+			return &status.OperationStatus
+		},
+		Step: func(status *opv1alpha1.CustomOperationStatus) *opv1alpha1.CustomOperationStep { return &status.Step },
+	}
+}
+
+// OnChange is the status handler entrypoint. It resolves the referenced template, then hands the
+// operation to an Engine running the template steps. A missing or modified template fails the
+// operation; the Engine still runs so that the beacon is released and the cluster unpaused.
+func (h *handler) OnChange(op *opv1alpha1.CustomOperation, status opv1alpha1.CustomOperationStatus) (opv1alpha1.CustomOperationStatus, error) {
+	if op == nil || op.DeletionTimestamp != nil {
+		return status, nil
+	}
+
+	template, err := h.templates.Get(op.Spec.Args.Template)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return status, err
+	}
+
+	checkTemplate(op, template, &status)
+
+	var steps []step
+	if template != nil && !ops.IsTerminal(status.Phase) {
+		steps = h.steps(template)
+	}
+
+	return h.newEngine(steps).OnChange(op, status)
+}
+
+// checkTemplate records the generation of the template while the operation is pending, and fails
+// the operation when the template is missing or has changed since the operation started.
+func checkTemplate(op *opv1alpha1.CustomOperation, template *opv1alpha1.OperationTemplate, status *opv1alpha1.CustomOperationStatus) {
+	if ops.IsTerminal(status.Phase) {
+		return
+	}
+
+	if template == nil {
+		logrus.Errorf("[customoperation] %s/%s: operation template %s not found", op.Namespace, op.Name, op.Spec.Args.Template)
+		ops.Fail(&status.OperationStatus, opv1alpha1.TemplateNotFoundReason, fmt.Sprintf("operation template %s not found", op.Spec.Args.Template))
+		return
+	}
+
+	if status.Phase == "" || status.Phase == opv1alpha1.OperationPhasePending {
+		status.TemplateGeneration = template.Generation
+		return
+	}
+
+	if status.TemplateGeneration != template.Generation {
+		logrus.Errorf("[customoperation] %s/%s: operation template %s changed while the operation was running", op.Namespace, op.Name, template.Name)
+		ops.Fail(&status.OperationStatus, opv1alpha1.TemplateChangedReason,
+			fmt.Sprintf("operation template %s changed from generation %d to %d while the operation was running", template.Name, status.TemplateGeneration, template.Generation))
+	}
+}
+
+// steps converts the steps of the template into engine steps. Every step pauses the cluster so
+// the provisioning controllers do not race with the plans handed out by the step.
+func (h *handler) steps(template *opv1alpha1.OperationTemplate) []step {
+	steps := make([]step, 0, len(template.Spec.Steps))
+	for _, templateStep := range template.Spec.Steps {
+		var timeout time.Duration
+		if templateStep.Timeout != nil {
+			timeout = templateStep.Timeout.Duration
+		}
+		steps = append(steps, step{
+			Name:            opv1alpha1.CustomOperationStep(templateStep.Name),
+			HookLabelPrefix: templateStep.Name + StepHookLabelSuffix,
+			PauseCluster:    true,
+			Timeout:         timeout,
+			Reconcile: func(s *scope, status *opv1alpha1.CustomOperationStatus) (bool, error) {
+				return h.reconcileStep(s, status, template.Name, templateStep)
+			},
+			Preview: func(s *scope, preview *ops.PlanPreview) (string, error) {
+				return h.previewStep(s, preview, template.Name, templateStep)
+			},
+		})
+	}
+	return steps
+}
+
+// reconcileStep assigns the plan of the template step to every selected node, handing out at most
+// Concurrency plans that have not yet applied at a time. Returns true once every selected node has
+// applied the plan.
+func (h *handler) reconcileStep(s *scope, status *opv1alpha1.CustomOperationStatus, template string, templateStep opv1alpha1.OperationTemplateStep) (bool, error) {
+	secrets, err := h.collectStep(s, templateStep)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[customoperation] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
+	}
+
+	concurrency := max(templateStep.Concurrency, 1)
+	failureThreshold := max(templateStep.FailureThreshold, 1)
+
+	waiting := false
+	results := make([]plan.PlanStatus, 0, len(secrets))
+
+	for _, secret := range secrets {
+		nodePlan, err := stepPlan(s, template, templateStep, secret)
+		if err != nil {
+			return false, err
+		}
+
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, failureThreshold, failureThreshold)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+
+		if planStatus.Failure() {
+			logrus.Errorf("[customoperation] %s/%s: marking operation as failed: failed to apply plan for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("step %s failed for %s/%s", templateStep.Name, secret.Namespace, secret.Name))
+			return false, nil
+		}
+
+		if planStatus.Waiting() {
+			logrus.Debugf("[customoperation] %s/%s: waiting for step %s on %s/%s", s.Op.Namespace, s.Op.Name, templateStep.Name, secret.Namespace, secret.Name)
+
+			waiting = true
+			concurrency--
+			if concurrency <= 0 {
+				break
+			}
+		}
+	}
+
+	if waiting {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// previewStep renders the plan of the template step for every selected node, in the order
+// reconcileStep would assign them.
+func (h *handler) previewStep(s *scope, preview *ops.PlanPreview, template string, templateStep opv1alpha1.OperationTemplateStep) (string, error) {
+	secrets, err := h.collectStep(s, templateStep)
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+
+	failureThreshold := max(templateStep.FailureThreshold, 1)
+	for _, secret := range secrets {
+		nodePlan, err := stepPlan(s, template, templateStep, secret)
+		if err != nil {
+			return "", err
+		}
+		preview.Add(templateStep.Name, secret, nodePlan, failureThreshold, failureThreshold)
+	}
+	return "", nil
+}
+
+// collectStep collects the machine-plan secrets of the non-Windows nodes selected by the template
+// step, sorted with plan.DefaultSorter.
+func (h *handler) collectStep(s *scope, templateStep opv1alpha1.OperationTemplateStep) ([]*corev1.Secret, error) {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithFilter(plan.FilterFunc(ops.And(selectorFilter(templateStep.Selector), ops.Not(ops.IsWindows)))).
+		WithSorter(plan.DefaultSorter()).
+		Collect()
+}
+
+// selectorFilter returns a filter matching the nodes that hold any of the given roles.
+func selectorFilter(roles []opv1alpha1.OperationTemplateRole) ops.Filter {
+	return func(secret *corev1.Secret) bool {
+		for _, role := range roles {
+			var filter ops.Filter
+			switch role {
+			case opv1alpha1.OperationTemplateRoleEtcd:
+				filter = ops.IsEtcd
+			case opv1alpha1.OperationTemplateRoleControlPlane:
+				filter = ops.IsControlPlane
+			case opv1alpha1.OperationTemplateRoleWorker:
+				filter = ops.IsWorker
+			case opv1alpha1.OperationTemplateRoleInit:
+				filter = ops.IsInitNode
+			default:
+				continue
+			}
+			if filter(secret) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// stepPlan builds the plan that runs the script of the template step exactly once per operation
+// on a single node. The probes of the step are rendered against the node and checked alongside
+// the probes of its role.
+func stepPlan(s *scope, template string, templateStep opv1alpha1.OperationTemplateStep, secret *corev1.Secret) (*plan.Plan, error) {
+	rendered, err := s.Adapter.RenderProbes(secret, templateStep.Supervisor)
+	if err != nil {
+		return nil, err
+	}
+
+	probes := make(map[string]plan.Probe, len(rendered)+len(templateStep.Probes))
+	maps.Copy(probes, rendered)
+	maps.Copy(probes, ops.InsertDataDirForProbes(s.Adapter.DistroDataDirectory(secret),
+		ops.ReplaceURLForProbes(templateStep.Probes, s.Adapter.LoopbackAddress(secret))))
+
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(secret)
+
+	return &plan.Plan{
+		Files: []plan.File{ops.IdempotentScriptFile(provisioningDir)},
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/"+template+"/"+templateStep.Name, s.IdempotencyValue(),
+				"/bin/sh", []string{"-c", templateStep.Script}, nil),
+		},
+		Probes: probes,
+	}, nil
+}
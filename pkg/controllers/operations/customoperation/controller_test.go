@@ -0,0 +1,374 @@
+package customoperation
+
+import (
+	"encoding/json"
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/capr"
+	ops "github.com/rancher/rancher/pkg/operations"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+// stubAdapter is a minimal ops.Adapter implementation for tests.
+type stubAdapter struct {
+	probes map[string]planapi.Probe
+}
+
+func (a *stubAdapter) BeaconRef() (string, string)   { return "fleet-default", "test" }
+func (a *stubAdapter) EtcdSnapshotNamespace() string { return "fleet-default" }
+func (a *stubAdapter) ClusterObject() (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+func (a *stubAdapter) WaitForRegister() (bool, error)              { return true, nil }
+func (a *stubAdapter) PauseCluster(_ bool) error                   { return nil }
+func (a *stubAdapter) RuntimeCommand() string                      { return "rke2" }
+func (a *stubAdapter) ServerUnit() string                          { return "rke2-server" }
+func (a *stubAdapter) AgentUnit() string                           { return "rke2-agent" }
+func (a *stubAdapter) InstallerImage(v string) string              { return "installer:" + v }
+func (a *stubAdapter) DistroDataDirectory(_ *corev1.Secret) string { return "/var/lib/rancher/rke2" }
+func (a *stubAdapter) ProvisioningDataDirectory(_ *corev1.Secret) string {
+	return "/var/lib/rancher/capr"
+}
+func (a *stubAdapter) ConfigFile(_ *corev1.Secret) string { return "/etc/rancher/rke2/config.yaml" }
+func (a *stubAdapter) ConfigDirectory(_ *corev1.Secret) string {
+	return "/etc/rancher/rke2/config.yaml.d"
+}
+func (a *stubAdapter) KubectlPath(_ *corev1.Secret) string {
+	return "/var/lib/rancher/rke2/bin/kubectl"
+}
+func (a *stubAdapter) KubeconfigPath(_ *corev1.Secret) string    { return "/etc/rancher/rke2/rke2.yaml" }
+func (a *stubAdapter) GetServerURL(_ *corev1.Secret) string      { return "" }
+func (a *stubAdapter) GetSupervisorPort(_ *corev1.Secret) string { return "9345" }
+func (a *stubAdapter) LoopbackAddress(_ *corev1.Secret) string   { return "127.0.0.1" }
+func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+func (a *stubAdapter) RenderProbes(_ *corev1.Secret, supervisor bool) (map[string]planapi.Probe, error) {
+	if !supervisor {
+		return a.probes, nil
+	}
+	probes := map[string]planapi.Probe{ops.SupervisorProbeName: {}}
+	for k, v := range a.probes {
+		probes[k] = v
+	}
+	return probes, nil
+}
+func (a *stubAdapter) ToS3ArgsEnvAndFiles(_ *corev1.Secret) ([]string, []string, []planapi.File) {
+	return nil, nil, nil
+}
+
+func newScope(adapter *stubAdapter) *scope {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetName("test")
+	cluster.SetNamespace("fleet-default")
+	op := newOp()
+	return &scope{
+		Op:        op,
+		OwnerKey:  planapi.ControllerOwnerKey(op, ControllerOwnerKey),
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
+	}
+}
+
+func newOp() *opv1alpha1.CustomOperation {
+	return &opv1alpha1.CustomOperation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "custom-1",
+			Namespace: "fleet-default",
+			UID:       "op-uid",
+		},
+		Spec: opv1alpha1.CustomOperationSpec{
+			Args: opv1alpha1.CustomOperationArgs{Template: "registry-mirrors"},
+		},
+	}
+}
+
+func newTemplate() *opv1alpha1.OperationTemplate {
+	return &opv1alpha1.OperationTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "registry-mirrors",
+			Generation: 2,
+		},
+		Spec: opv1alpha1.OperationTemplateSpec{
+			Steps: []opv1alpha1.OperationTemplateStep{
+				{
+					Name:     "servers",
+					Selector: []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleEtcd, opv1alpha1.OperationTemplateRoleControlPlane},
+					Script:   "systemctl restart rke2-server",
+				},
+				{
+					Name:     "workers",
+					Selector: []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleWorker},
+					Script:   "systemctl restart rke2-agent",
+				},
+			},
+		},
+	}
+}
+
+// newPlanSecret builds a machine-plan secret for the test cluster carrying the given role labels.
+func newPlanSecret(name string, roles ...string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "fleet-default",
+			UID:         types.UID(name + "-uid"),
+			Annotations: map[string]string{},
+			Labels: map[string]string{
+				capr.ClusterNameLabel: "test",
+				capr.NodeNameLabel:    name,
+			},
+		},
+		Type: planapi.SecretTypeMachinePlan,
+	}
+	for _, role := range roles {
+		secret.Labels[role] = "true"
+	}
+	return secret
+}
+
+func withAppliedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	out.Data = map[string][]byte{
+		"plan":           data,
+		"appliedPlan":    data,
+		"probe-statuses": []byte(`{"x":{"healthy":true}}`),
+	}
+	out.Annotations[planapi.PlanProbesPassedAnnotation] = "applied"
+	return out
+}
+
+func withFailedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	out.Data = map[string][]byte{
+		"plan":              data,
+		"failed-checksum":   []byte(planapi.PlanHash(data)),
+		"failure-count":     []byte("1"),
+		"max-failures":      []byte("1"),
+		"failure-threshold": []byte("1"),
+	}
+	return out
+}
+
+// newHandler returns a handler collecting the given machine-plan secrets from an indexed secret
+// cache. Every secret updated through its store is recorded in updated so tests can assert which
+// nodes received a plan.
+func newHandler(t *testing.T, ctrl *gomock.Controller, updated *[]string, items ...*corev1.Secret) *handler {
+	t.Helper()
+	m := ctrlfake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+	m.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		if updated != nil {
+			*updated = append(*updated, s.Name)
+		}
+		return s, nil
+	}).AnyTimes()
+	// Replacing a plan records it in a plan history secret, which does not exist yet.
+	m.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}).AnyTimes()
+	m.EXPECT().Create(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		return s, nil
+	}).AnyTimes()
+	return &handler{
+		secretCache: newSecretCache(t, items...),
+		store:       planapi.NewStore(m),
+	}
+}
+
+// newSecretCache returns a secret cache with the plan indexers registered, holding items.
+func newSecretCache(t *testing.T, items ...*corev1.Secret) corecontrollers.SecretCache {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := generic.NewCache[*corev1.Secret](indexer, corev1.Resource("secrets"))
+	planapi.RegisterIndexers(c)
+	for _, item := range items {
+		if err := indexer.Add(item); err != nil {
+			t.Fatalf("adding %s: %v", item.Name, err)
+		}
+	}
+	return c
+}
+
+func expectedStepPlan(t *testing.T, s *scope, templateStep opv1alpha1.OperationTemplateStep, secret *corev1.Secret) *planapi.Plan {
+	t.Helper()
+	p, err := stepPlan(s, "registry-mirrors", templateStep, secret)
+	assert.NoError(t, err)
+	return p
+}
+
+func TestSelectorFilter(t *testing.T) {
+	t.Parallel()
+
+	etcd := newPlanSecret("etcd-1", capr.EtcdRoleLabel, capr.InitNodeLabel)
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	worker := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+
+	tests := []struct {
+		name  string
+		roles []opv1alpha1.OperationTemplateRole
+		want  []bool
+	}{
+		{"etcd", []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleEtcd}, []bool{true, false, false}},
+		{"init", []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleInit}, []bool{true, false, false}},
+		{"servers", []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleEtcd, opv1alpha1.OperationTemplateRoleControlPlane}, []bool{true, true, false}},
+		{"worker", []opv1alpha1.OperationTemplateRole{opv1alpha1.OperationTemplateRoleWorker}, []bool{false, false, true}},
+		{"none", nil, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := selectorFilter(tt.roles)
+			assert.Equal(t, tt.want, []bool{filter(etcd), filter(cp), filter(worker)})
+		})
+	}
+}
+
+func TestCheckTemplate_RecordsGenerationWhilePending(t *testing.T) {
+	t.Parallel()
+
+	status := opv1alpha1.CustomOperationStatus{}
+	checkTemplate(newOp(), newTemplate(), &status)
+
+	assert.Empty(t, string(status.Phase))
+	assert.Equal(t, int64(2), status.TemplateGeneration)
+}
+
+func TestCheckTemplate_MissingTemplateFails(t *testing.T) {
+	t.Parallel()
+
+	status := opv1alpha1.CustomOperationStatus{}
+	checkTemplate(newOp(), nil, &status)
+
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.TemplateNotFoundReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestCheckTemplate_ChangedTemplateFails(t *testing.T) {
+	t.Parallel()
+
+	status := opv1alpha1.CustomOperationStatus{TemplateGeneration: 1}
+	status.Phase = opv1alpha1.OperationPhaseInProgress
+	checkTemplate(newOp(), newTemplate(), &status)
+
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.TemplateChangedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestSteps(t *testing.T) {
+	t.Parallel()
+
+	steps := (&handler{}).steps(newTemplate())
+
+	assert.Len(t, steps, 2)
+	assert.Equal(t, opv1alpha1.CustomOperationStep("servers"), steps[0].Name)
+	assert.Equal(t, "servers.step.hook.operation.cattle.io/", steps[0].HookLabelPrefix)
+	assert.True(t, steps[0].PauseCluster)
+	assert.Equal(t, opv1alpha1.CustomOperationStep("workers"), steps[1].Name)
+}
+
+func TestStepPlan(t *testing.T) {
+	t.Parallel()
+
+	a := &stubAdapter{probes: map[string]planapi.Probe{ops.KubeletProbeName: {}}}
+	templateStep := newTemplate().Spec.Steps[0]
+	templateStep.Supervisor = true
+	templateStep.Probes = map[string]planapi.Probe{
+		"registry": {HTTPGetAction: &planapi.HTTPGetAction{URL: "https://%s:5000/v2/", CACert: "%s/agent/server-ca.crt"}},
+	}
+
+	p, err := stepPlan(newScope(a), "registry-mirrors", templateStep, newPlanSecret("etcd-1", capr.EtcdRoleLabel))
+	assert.NoError(t, err)
+
+	assert.Len(t, p.OneTimeInstructions, 1)
+	args := p.OneTimeInstructions[0].Args
+	assert.Equal(t, []string{"-c", "systemctl restart rke2-server"}, args[len(args)-2:])
+	assert.Contains(t, args, "custom-operation/registry-mirrors/servers")
+	assert.Equal(t, []planapi.File{ops.IdempotentScriptFile("/var/lib/rancher/capr")}, p.Files)
+
+	assert.Contains(t, p.Probes, ops.SupervisorProbeName)
+	assert.Contains(t, p.Probes, ops.KubeletProbeName)
+	assert.Equal(t, "https://127.0.0.1:5000/v2/", p.Probes["registry"].HTTPGetAction.URL)
+	assert.Equal(t, "/var/lib/rancher/rke2/agent/server-ca.crt", p.Probes["registry"].HTTPGetAction.CACert)
+	assert.NotContains(t, a.probes, "registry", "the probes rendered by the adapter must not be modified")
+}
+
+func TestReconcileStep_RespectsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		newPlanSecret("etcd-2", capr.EtcdRoleLabel),
+		newPlanSecret("etcd-1", capr.EtcdRoleLabel, capr.InitNodeLabel),
+		newPlanSecret("cp-1", capr.ControlPlaneRoleLabel),
+		newPlanSecret("worker-1", capr.WorkerRoleLabel),
+	)
+
+	templateStep := newTemplate().Spec.Steps[0]
+	templateStep.Concurrency = 2
+
+	status := opv1alpha1.CustomOperationStatus{Step: "servers"}
+	done, err := h.reconcileStep(newScope(&stubAdapter{}), &status, "registry-mirrors", templateStep)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []string{"etcd-1", "etcd-2"}, updated, "at most concurrency nodes may receive a plan, starting with the init node")
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+}
+
+func TestReconcileStep_DoneWhenApplied(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	s := newScope(&stubAdapter{})
+	templateStep := newTemplate().Spec.Steps[1]
+	worker := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+	windows := newPlanSecret("worker-2", capr.WorkerRoleLabel)
+	windows.Labels[capr.CattleOSLabel] = "windows"
+
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		withAppliedPlan(worker, expectedStepPlan(t, s, templateStep, worker)),
+		windows,
+	)
+
+	status := opv1alpha1.CustomOperationStatus{Step: "workers"}
+	done, err := h.reconcileStep(s, &status, "registry-mirrors", templateStep)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, updated, "windows nodes must not be selected")
+	assert.Empty(t, string(status.Phase))
+}
+
+func TestReconcileStep_PlanFailureMarksFailed(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	s := newScope(&stubAdapter{})
+	templateStep := newTemplate().Spec.Steps[1]
+	worker := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+	h := newHandler(t, ctrl, nil, withFailedPlan(worker, expectedStepPlan(t, s, templateStep, worker)))
+
+	status := opv1alpha1.CustomOperationStatus{Step: "workers"}
+	done, err := h.reconcileStep(s, &status, "registry-mirrors", templateStep)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
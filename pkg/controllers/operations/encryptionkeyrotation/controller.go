@@ -5,6 +5,7 @@
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -14,479 +15,484 @@
 	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
 	ops "github.com/rancher/rancher/pkg/operations"
 	"github.com/rancher/rancher/pkg/plan"
-	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
-	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
-	"github.com/rancher/wrangler/v3/pkg/generic"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-// ControllerOwnerKey is the shared operation-type key for encryption key rotation coordination.
-// Beacon ownership uses a per-operation key derived from the operation UID.
+// ControllerOwnerKey is the shared operation-type key for encryption key rotation coordination. It
+// prefixes the beacon owner key of every rotation, and keys the control-plane leader election.
 const ControllerOwnerKey = "encryption-key-rotation"
-const beaconOwnerRefAnnotation = "rke.cattle.io/operation-owner-ref"
 
 // Step hook label prefixes for the encryptionkeyrotation operation. Each prefix gates a single
 // rotation step and follows the shared label semantics documented on planv1alpha1's phase-hook
 // label constants.
 const (
-	// RotateStepHookLabelPrefix gates the Rotate step, before reconcileRotate pauses the CAPI
-	// cluster and assigns the rotate-keys plan to the elected control-plane leader. Fires before
+	// PreflightStepHookLabelPrefix gates the Preflight step, before reconcilePreflight backs up the
+	// encryption config file of every server node. The backup is only taken when rollback is
+	// enabled.
+	PreflightStepHookLabelPrefix = "preflight.step.hook.operation.cattle.io/"
+
+	// RotateStepHookLabelPrefix gates the Rotate step, before the engine pauses the CAPI cluster and
+	// reconcileRotate assigns the rotate-keys plan to the elected control-plane leader. Fires before
 	// PauseCluster so a delegate observes the cluster in its pre-pause state.
 	RotateStepHookLabelPrefix = "rotate.step.hook.operation.cattle.io/"
 
 	// RestartStepHookLabelPrefix gates the Restart step, before reconcileRestart begins walking
 	// the server pool and issuing the systemctl-restart plan to each node.
 	RestartStepHookLabelPrefix = "restart.step.hook.operation.cattle.io/"
-)
 
-// stepHookPrefixFor returns the step-hook label prefix for the given rotation step, or "" for an
-// unknown / empty step. Used by handleInProgress to decide whether beacon-authorization loss is
-// explained by an active step-scoped delegation vs a genuine loss.
-func stepHookPrefixFor(step opv1alpha1.EncryptionKeyRotationStep) string {
-	switch step {
-	case opv1alpha1.EncryptionKeyRotationStepRotate:
-		return RotateStepHookLabelPrefix
-	case opv1alpha1.EncryptionKeyRotationStepRestart:
-		return RestartStepHookLabelPrefix
-	}
-	return ""
-}
+	// RollbackStepHookLabelPrefix gates the Rollback step, before reconcileRollback begins walking
+	// the server pool and issuing the restore-and-restart plan to each node.
+	RollbackStepHookLabelPrefix = "rollback.step.hook.operation.cattle.io/"
+)
 
-// dynamicResolver is the subset of *dynamic.Controller this handler needs:
-// Get for resolving cluster refs and Enqueue for nudging the backing cluster
-// after terminal beacon transitions.
-type dynamicResolver interface {
-	Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
-	Enqueue(gvk schema.GroupVersionKind, namespace, name string) error
-}
+type (
+	scope = ops.Scope[*opv1alpha1.EncryptionKeyRotation]
+	step  = ops.Step[*opv1alpha1.EncryptionKeyRotation, opv1alpha1.EncryptionKeyRotationStatus, opv1alpha1.EncryptionKeyRotationStep]
+)
 
+// handler holds the step reconcilers of the EncryptionKeyRotation controller. The shared state
+// machine (beacon ownership, hooks, cancellation and TTL expiry) is driven by an ops.Engine.
 type handler struct {
-	encryptionkeyrotations operationcontrollers.EncryptionKeyRotationController
-
-	beacons     plancontrollers.BeaconClient
-	beaconCache plancontrollers.BeaconCache
-
-	secrets corecontrollers.SecretClient
+	secretCache corecontrollers.SecretCache
 
 	store *plan.Store
-
-	dynamic dynamicResolver
-
-	clients *wrangler.CAPIContext
 }
 
 func Register(ctx context.Context, clients *wrangler.CAPIContext) {
 	h := &handler{
-		encryptionkeyrotations: clients.Operation.EncryptionKeyRotation(),
-		beacons:                clients.Plan.Beacon(),
-		beaconCache:            clients.Plan.Beacon().Cache(),
-		secrets:                clients.Core.Secret(),
-		dynamic:                clients.Dynamic,
-		store:                  plan.NewStore(clients.Core.Secret()),
-		clients:                clients,
-	}
-
-	operationcontrollers.RegisterEncryptionKeyRotationStatusHandler(ctx, clients.Operation.EncryptionKeyRotation(), "", "encryption-key-rotation-handler", h.OnChange)
+		secretCache: clients.Core.Secret().Cache(),
+		store:       plan.NewStore(clients.Core.Secret()),
+	}
+	engine := ops.NewEngine(clients, clients.Operation.EncryptionKeyRotation(), h.definition())
+
+	operationcontrollers.RegisterEncryptionKeyRotationStatusHandler(ctx, clients.Operation.EncryptionKeyRotation(), "", "encryption-key-rotation-handler", engine.OnChange)
+	ops.WatchBeaconQueue(ctx, clients.Plan.Beacon(), opv1alpha1.SchemeGroupVersion.WithKind("EncryptionKeyRotation"),
+		clients.Operation.EncryptionKeyRotation().Cache(), clients.Operation.EncryptionKeyRotation(),
+		func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+	ops.RecordOperations(ctx, clients.Operation.EncryptionKeyRotation(), clients.Operation.OperationRecord(), clients.Operation.OperationRecord().Cache(),
+		opv1alpha1.SchemeGroupVersion.WithKind("EncryptionKeyRotation"),
+		func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.OperationSpec {
+			return &op.Spec.OperationSpec
+		},
+		func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
 }
 
-func (h *handler) OnChange(op *opv1alpha1.EncryptionKeyRotation, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	if op == nil {
-		return status, nil
+// definition returns the engine definition of the EncryptionKeyRotation operation: Preflight backs
+// up the encryption config of every server node when rollback is enabled, Rotate pauses the
+// cluster and runs rotate-keys on the elected control-plane leader until re-encryption finishes,
+// and Restart restarts every server node, one at a time. Rollback is a recovery step: fail switches
+// to it after a failure it can roll back, including a timed out step.
+func (h *handler) definition() ops.Definition[*opv1alpha1.EncryptionKeyRotation, opv1alpha1.EncryptionKeyRotationStatus, opv1alpha1.EncryptionKeyRotationStep] {
+	return ops.Definition[*opv1alpha1.EncryptionKeyRotation, opv1alpha1.EncryptionKeyRotationStatus, opv1alpha1.EncryptionKeyRotationStep]{
+		Name:             ControllerOwnerKey,
+		GroupVersionKind: opv1alpha1.SchemeGroupVersion.WithKind("EncryptionKeyRotation"),
+		Steps: []step{
+			{
+				Name:            opv1alpha1.EncryptionKeyRotationStepPreflight,
+				HookLabelPrefix: PreflightStepHookLabelPrefix,
+				Reconcile:       h.reconcilePreflight,
+				Timeout:         15 * time.Minute,
+				Preview:         h.previewPreflight,
+			},
+			{
+				Name:            opv1alpha1.EncryptionKeyRotationStepRotate,
+				HookLabelPrefix: RotateStepHookLabelPrefix,
+				PauseCluster:    true,
+				Reconcile:       h.reconcileRotate,
+				Timeout:         30 * time.Minute,
+				Preview:         previewRotate,
+			},
+			{
+				Name:            opv1alpha1.EncryptionKeyRotationStepRestart,
+				HookLabelPrefix: RestartStepHookLabelPrefix,
+				Reconcile:       h.reconcileRestart,
+				Timeout:         time.Hour,
+				Preview:         h.previewRestart,
+			},
+			{
+				Name:            opv1alpha1.EncryptionKeyRotationStepRollback,
+				HookLabelPrefix: RollbackStepHookLabelPrefix,
+				Reconcile:       h.reconcileRollback,
+				Timeout:         time.Hour,
+				Recovery:        true,
+			},
+		},
+		Spec:   func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		Status: func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.EncryptionKeyRotationStatus { return &op.Status },
+		OperationStatus: func(status *opv1alpha1.EncryptionKeyRotationStatus) *opv1alpha1.OperationStatus {
+			return &status.OperationStatus
+		},
+		Step: func(status *opv1alpha1.EncryptionKeyRotationStatus) *opv1alpha1.EncryptionKeyRotationStep {
+			return &status.Step
+		},
+		StepFailed: fail,
 	}
-	if op.DeletionTimestamp != nil {
-		return status, nil
+}
+
+// reconcilePreflight backs up the encryption config file of every etcd and control-plane node, so
+// that a failed rotation can be rolled back to it. The backups are taken concurrently. Without
+// rollback enabled there is nothing to back up and the step completes at once.
+func (h *handler) reconcilePreflight(s *scope, status *opv1alpha1.EncryptionKeyRotationStatus) (bool, error) {
+	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling preflight", s.Op.Namespace, s.Op.Name)
+
+	if !s.Op.Spec.Rollback {
+		return true, nil
 	}
-	if ops.IsPaused(&op.Spec.OperationSpec) {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: skipping paused operation", op.Namespace, op.Name)
-		return status, nil
+
+	secrets, err := h.collectServers(s)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: no control-plane nodes found at preflight step", s.Op.Namespace, s.Op.Name)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, "no control-plane nodes found; cannot back up encryption config")
+		return false, nil
 	}
 
-	status, err := h.onChange(op, status)
-	if err != nil {
-		return status, err
-	}
-	status = updateStatus(op, status)
-
-	if equality.Semantic.DeepEqual(op.Status, status) {
-		// handle after normal processing to allow for proper phase-related cleanup (freeing beacon)
-		//
-		// See the equivalent guard in etcdsnapshotsave's OnChange for the rationale: while any
-		// lifecycle-hook label is still on the op, TTL garbage collection must be deferred so the
-		// delegate has a chance to observe the terminal phase and pop itself from the beacon.
-		// EKR is particularly exposed to this without the guard because the operation defaults
-		// to TTL=0 (immediate expiry) in some code paths.
-		if ops.IsTerminal(status.Phase) &&
-			ops.IsExpired(&op.Spec.OperationSpec, &status.OperationStatus) &&
-			!planv1alpha1.HasActiveLifecycleHook(op) {
-			err = h.encryptionkeyrotations.Delete(op.Namespace, op.Name, &metav1.DeleteOptions{})
-			if err != nil {
-				return status, err
-			}
-			return status, generic.ErrSkip
+	env := operationEnv(s.Op, status.Step)
+	var waiting []plan.PlanStatus
+	for _, secret := range secrets {
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, backupPlan(s, secret, env), 1, 1)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		if planStatus.Failure() {
+			logrus.Errorf("[encryptionkeyrotation] %s/%s: encryption config backup failed for %s", s.Op.Namespace, s.Op.Name, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("encryption config backup failed for %s", secret.Name))
+			return false, nil
+		}
+		if planStatus.Waiting() {
+			waiting = append(waiting, *planStatus)
 		}
-		h.encryptionkeyrotations.EnqueueAfter(op.Namespace, op.Name, 5*time.Second)
 	}
-	return status, nil
-}
 
-func (h *handler) onChange(op *opv1alpha1.EncryptionKeyRotation, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	if status.Phase == "" {
-		status.Phase = opv1alpha1.OperationPhasePending
-		status.LastUpdated = metav1.Now()
+	if len(waiting) > 0 {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for encryption config backups", s.Op.Namespace, s.Op.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, plan.Message(waiting))
+		return false, nil
 	}
 
-	gvk := schema.FromAPIVersionAndKind(op.Spec.ClusterRef.APIVersion, op.Spec.ClusterRef.Kind)
-	ref, err := h.dynamic.Get(gvk, op.Spec.ClusterRef.Namespace, op.Spec.ClusterRef.Name)
-	if apierrors.IsNotFound(err) {
-		key := fmt.Sprintf("apiVersion=%s, kind=%s", op.Spec.ClusterRef.APIVersion, op.Spec.ClusterRef.Kind)
-		if op.Spec.ClusterRef.Namespace != "" {
-			key += fmt.Sprintf(", namespace=%s", op.Spec.ClusterRef.Namespace)
-		}
-		key += fmt.Sprintf(", name=%s", op.Spec.ClusterRef.Name)
-		logrus.Errorf("[encryptionkeyrotation]: %s/%s failed to find cluster for %s", op.Namespace, op.Name, key)
+	logrus.Infof("[encryptionkeyrotation] %s/%s: backed up encryption config on %d nodes", s.Op.Namespace, s.Op.Name, len(secrets))
+	return true, nil
+}
 
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.ClusterNotFoundReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("cluster %s not found", key))
+// reconcileRotate runs `secrets-encrypt rotate-keys` on the elected leader and
+// stays in Rotate until status reports `reencrypt_finished` on that node.
+func (h *handler) reconcileRotate(s *scope, status *opv1alpha1.EncryptionKeyRotationStatus) (bool, error) {
+	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling secrets-encrypt rotate-keys", s.Op.Namespace, s.Op.Name)
 
-		status.Phase = opv1alpha1.OperationPhaseFailed
-		status.LastUpdated = metav1.Now()
-		return status, nil
-	}
+	leader, err := s.Adapter.FindOrElectLeader(ControllerOwnerKey, ops.IsControlPlane)
 	if err != nil {
-		return status, err
+		return false, err
 	}
 
-	ustrMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ref)
-	if err != nil {
-		return status, err
+	if leader == nil {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: no suitable control-plane leader found yet, will retry", s.Op.Namespace, s.Op.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForSuitableLeaderReason, "waiting for a suitable control-plane leader for encryption key rotation")
+		return false, nil
 	}
+	ops.RecordLeader(&status.OperationStatus, leader)
 
-	ustr := unstructured.Unstructured{Object: ustrMap}
-
-	adapter, err := ops.NewAdapter(h.clients, &ustr)
+	nodePlan, err := rotatePlan(s, leader, operationEnv(s.Op, status.Step), s.Adapter.RuntimeCommand())
 	if err != nil {
-		return status, err
+		return false, err
 	}
 
-	clusterObj, err := adapter.ClusterObject()
+	// Once rotate-keys may run, secrets may be re-encrypted with a key the backed up config lacks,
+	// so a later failure must not restore the backup unless the leader shows that rotate-keys was
+	// rejected before re-encryption started.
+	status.ReencryptionStarted = true
+
+	// Use finite failure threshold so a plan that can't execute
+	// is marked Failed rather than retried forever. The wrapper always exits 0, so a
+	// real apply failure here means the wrapper itself couldn't run.
+	planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(leader, nodePlan, 1, 1)
 	if err != nil {
-		return status, err
+		return false, err
 	}
+	ops.RecordPlanChange(&status.OperationStatus, leader, planStatus.Diff)
 
-	// Resolve the beacon via the adapter, not op.Spec.ClusterRef. See
-	// etcdsnapshotrestore/controller.go for the full rationale.
-	namespace, beaconName := adapter.BeaconRef()
-
-	beacon, err := h.beacons.Get(namespace, beaconName, metav1.GetOptions{})
-	if apierrors.IsNotFound(err) && status.Phase == opv1alpha1.OperationPhasePending {
-		logrus.Warnf("[encryptionkeyrotation]: %s/%s failed to find beacon %s/%s (clusterRef apiVersion=%s kind=%s name=%s)",
-			op.Namespace, op.Name, namespace, beaconName, ustr.GetAPIVersion(), ustr.GetKind(), ustr.GetName())
-
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForBeaconReason)
-		opv1alpha1.PendingCondition.Message(&status, "waiting for beacon creation")
-
-		return status, nil
-	} else if err != nil {
-		return status, err
-	}
-
-	s := &scope{
-		op:         op,
-		beacon:     beacon,
-		namespace:  namespace,
-		clusterObj: clusterObj,
-		adapter:    adapter,
-	}
-
-	switch status.Phase {
-	case opv1alpha1.OperationPhasePending:
-		return h.handlePending(s, status)
-	case opv1alpha1.OperationPhaseInProgress:
-		return h.handleInProgress(s, status)
-	case opv1alpha1.OperationPhaseCanceled:
-		return h.handleCanceled(s, status)
-	case opv1alpha1.OperationPhaseFailed:
-		return h.handleFailed(s, status)
-	case opv1alpha1.OperationPhaseSucceeded:
-		return h.handleSucceeded(s, status)
-	default:
-		// Should be prevented via validation, but just in case
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.UnknownPhaseReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("unknown phase [%s]", op.Status.Phase))
+	if planStatus.Failure() {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: rotate-keys plan failed to execute on leader %s", s.Op.Namespace, s.Op.Name, leader.Name)
+		fail(s, status, opv1alpha1.PlanFailedReason, fmt.Sprintf("encryption key rotation plan failed for leader %s/%s", leader.Namespace, leader.Name))
+		return false, nil
 	}
 
-	return status, nil
-}
-
-type scope struct {
-	op        *opv1alpha1.EncryptionKeyRotation
-	namespace string
-
-	beacon     *planv1alpha1.Beacon
-	clusterObj *unstructured.Unstructured
-	adapter    ops.Adapter
-}
+	if planStatus.Waiting() {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for rotate-keys plan for %s/%s", s.Op.Namespace, s.Op.Name, leader.Namespace, leader.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, plan.Message([]plan.PlanStatus{*planStatus}))
+		return false, nil
+	}
 
-// lifecycleHookDelegate returns (suffix, delegate) for the first label on the operation whose key
-// starts with prefix. Returns ("", "") when no such label is set.
-func (h *handler) lifecycleHookDelegate(s *scope, prefix string) (string, string) {
-	if s.op.Labels == nil {
-		return "", ""
+	// Plan applied and probes passed. Read the one-time output to check the rotate-keys exit code.
+	appliedOutput, err := plan.ReadAppliedOutput(leader)
+	if err != nil {
+		return false, err
 	}
-	for k, v := range s.op.Labels {
-		if strings.HasPrefix(k, prefix) {
-			return strings.TrimPrefix(k, prefix), v
-		}
+	if appliedOutput == nil {
+		// Output not yet in cache; wait for the next reconcile.
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: rotate-keys applied-output not yet available on leader %s", s.Op.Namespace, s.Op.Name, leader.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, "waiting for rotate-keys output")
+		return false, nil
 	}
-	return "", ""
-}
 
-// delegate pushes delegate onto the beacon's delegate chain if it is not already there. Idempotent
-// across the reconciles that may occur while a hook is held.
-func (h *handler) delegate(s *scope, name, delegate string) error {
-	logrus.Tracef("[encryptionkeyrotation] %s/%s: delegating ownership of beacon to %s on behalf of %s", s.op.Namespace, s.op.Name, delegate, name)
+	result, err := readRotateKeysResult(appliedOutput)
+	if errors.Is(err, errRotateKeysOutputNotYet) {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: rotate-keys exit code not yet available on leader %s", s.Op.Namespace, s.Op.Name, leader.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, "waiting for rotate-keys exit code")
+		return false, nil
+	}
+	if err != nil {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: corrupt rotate-keys output on leader %s: %v", s.Op.Namespace, s.Op.Name, leader.Name, err)
+		fail(s, status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt rotate-keys output on leader %s", leader.Name))
+		return false, nil
+	}
 
-	if plan.IsInDelegateChain(s.beacon, delegate) {
-		return nil
+	if result.exitCode != 0 {
+		if rotateKeysCommandTimedOut(result.output) {
+			// CLI timed out but rotation may still be running in the background;
+			// keep watching periodic status before deciding.
+			logrus.Warnf("[encryptionkeyrotation] %s/%s: rotate-keys CLI timed out on leader %s; continuing to observe periodic status", s.Op.Namespace, s.Op.Name, leader.Name)
+		} else {
+			logrus.Errorf("[encryptionkeyrotation] %s/%s: rotate-keys failed on leader %s with exit code %d", s.Op.Namespace, s.Op.Name, leader.Name, result.exitCode)
+			if reencryptionNotStarted(leader) {
+				// rotate-keys was rejected before any secret was re-encrypted, so the backed up
+				// config still decrypts every secret and the failure can be rolled back.
+				status.ReencryptionStarted = false
+			}
+			fail(s, status, opv1alpha1.PlanFailedReason, fmt.Sprintf("secrets-encrypt rotate-keys failed on leader %s (exit code %d); please perform an etcd restore", leader.Name, result.exitCode))
+			return false, nil
+		}
 	}
 
-	beacon, err := plan.PushDelegate(s.beacon, delegate, h.beacons)
+	// Check periodic secrets-encrypt status. Stay in Rotate until reencrypt_finished.
+	waitMsg, err := convergenceWaitMessage(leader, false)
 	if err != nil {
-		return err
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: convergence check failed on leader %s: %v", s.Op.Namespace, s.Op.Name, leader.Name, err)
+		fail(s, status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt encryption key rotation state on leader %s; please perform an etcd restore", leader.Name))
+		return false, nil
 	}
-	s.beacon = beacon
-	return nil
+	if waitMsg != "" {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for convergence on leader %s: %s", s.Op.Namespace, s.Op.Name, leader.Name, waitMsg)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForEncryptionKeyRotationReason, waitMsg)
+		return false, nil
+	}
+
+	logrus.Infof("[encryptionkeyrotation] %s/%s: rotate-keys reencrypt_finished on leader %s", s.Op.Namespace, s.Op.Name, leader.Name)
+	return true, nil
 }
 
-// handleHook is the per-handler entry point for the lifecycle-hook mechanism. Returns (true, nil)
-// while a label with the given prefix exists on the operation, signalling the caller to short
-// circuit. To advance past the hook the operator must clear the label AND pop the delegate (see
-// AdvancePastEncryptionKeyRotationHook in the test helpers for the rationale on ordering).
-func (h *handler) handleHook(s *scope, prefix string) (bool, error) {
-	logrus.Tracef("[encryptionkeyrotation] %s/%s: checking lifecycle hook for prefix %q", s.op.Namespace, s.op.Name, prefix)
+// reconcileRestart walks the server nodes in sorted order, restarting each one
+// and requiring strict hash convergence only on the final control-plane node.
+func (h *handler) reconcileRestart(s *scope, status *opv1alpha1.EncryptionKeyRotationStatus) (bool, error) {
+	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling service restart", s.Op.Namespace, s.Op.Name)
 
-	if name, delegate := h.lifecycleHookDelegate(s, prefix); delegate != "" {
-		err := h.delegate(s, name, delegate)
-		return true, err
+	// collectServers keeps etcd nodes ahead of pure control-plane nodes.
+	secrets, err := h.collectServers(s)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: no control-plane nodes found at restart step", s.Op.Namespace, s.Op.Name)
+		fail(s, status, opv1alpha1.UnknownStepReason, "no control-plane nodes found; cannot verify post-restart encryption status")
+		return false, nil
 	}
-	return false, nil
-}
-
-func (h *handler) handlePending(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	ownerKey := beaconOwnerKey(s.op)
-	if err := h.reclaimStaleBeaconOwnerIfNeeded(s); err != nil {
-		return status, err
+	if !ops.IsControlPlane(secrets[len(secrets)-1]) {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: nodes are not correctly ordered at restart step", s.Op.Namespace, s.Op.Name)
+		fail(s, status, opv1alpha1.UnknownStepReason, "last control plane node not found; cannot verify hash convergence after restart")
+		return false, nil
 	}
 
-	// Pending waits until this op is either the primary owner OR anywhere in the delegate chain.
-	// If we're already in the chain, the primary owner is driving the beacon on our behalf — skip
-	// AcquireBeacon entirely and continue with hook + WaitForRegister. Otherwise attempt to acquire;
-	// a nil return means another controller currently owns it and we must keep waiting.
-	if !plan.IsInDelegateChain(s.beacon, ownerKey) {
-		acquired, err := plan.AcquireBeacon(s.beacon, h.beacons, ownerKey)
+	env := operationEnv(s.Op, status.Step)
+	serverUnit := s.Adapter.ServerUnit()
+	runtime := s.Adapter.RuntimeCommand()
+
+	// pool is ordered with plan.DefaultSorter(), so the final element is the
+	// last control-plane node. For the new k3s rotate-keys flow, strict "All
+	// hashes match" validation only makes sense after every control-plane node
+	// has restarted; before that, k3s may legitimately report
+	// reencrypt_finished while hashes still differ across servers.
+	for i, secret := range secrets {
+		nodePlan, err := restartPlan(s, secret, env, serverUnit, runtime)
 		if err != nil {
-			return status, err
+			return false, err
 		}
-		if acquired == nil {
-			opv1alpha1.PendingCondition.True(&status)
-			opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForBeaconReason)
-			opv1alpha1.PendingCondition.Message(&status, "waiting for beacon acquisition")
-			return status, nil
+		requireHashMatch := i == len(secrets)-1
+		if done, err := h.reconcileRestartNode(s, status, secret, nodePlan, requireHashMatch); err != nil || !done {
+			return false, err
 		}
-		s.beacon = acquired
 	}
 
-	desiredOwnerRef := fmt.Sprintf("%s/%s/%s", s.op.Namespace, s.op.Name, s.op.UID)
-	if s.beacon.Annotations == nil || s.beacon.Annotations[beaconOwnerRefAnnotation] != desiredOwnerRef {
-		beacon := s.beacon.DeepCopy()
-		if beacon.Annotations == nil {
-			beacon.Annotations = map[string]string{}
-		}
-		beacon.Annotations[beaconOwnerRefAnnotation] = desiredOwnerRef
-		updated, err := h.beacons.Update(beacon)
-		if err != nil {
-			return status, err
-		}
-		s.beacon = updated
-	}
+	return true, nil
+}
 
-	// Pending-phase hook fires after beacon acquisition + owner-ref tagging so a delegate can
-	// inspect the recorded ownership before the controller starts driving the rotation.
-	delegated, err := h.handleHook(s, planv1alpha1.PendingPhaseHookLabelPrefix)
+// reconcileRestartNode assigns and tracks the restart plan for one node. For
+// control-plane nodes it also waits for post-restart secrets-encrypt status,
+// and on the final control-plane node it enforces the cluster-wide hash check
+// required by the k3s rotate-keys flow. In the Rollback step the status is
+// only required to parse, since the restored keys need not be at any stage.
+func (h *handler) reconcileRestartNode(
+	s *scope,
+	status *opv1alpha1.EncryptionKeyRotationStatus,
+	secret *corev1.Secret,
+	nodePlan *plan.Plan,
+	requireHashMatch bool,
+) (bool, error) {
+	planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, 5, 5)
 	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.PendingCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
+		return false, err
 	}
+	ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
 
-	logrus.Infof("[encryptionkeyrotation] %s/%s: acquired beacon, waiting for agents to register", s.op.Namespace, s.op.Name)
-
-	if ok, err := s.adapter.WaitForRegister(); err != nil {
-		return status, err
-	} else if !ok {
-		logrus.Infof("[encryptionkeyrotation] %s/%s: waiting for system-agents to connect", s.op.Namespace, s.op.Name)
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForRegistrationReason)
-		opv1alpha1.PendingCondition.Message(&status, "waiting for system-agents to connect")
-		return status, nil
+	if planStatus.Failure() {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: restart plan failed for %s", s.Op.Namespace, s.Op.Name, secret.Name)
+		msg := fmt.Sprintf("restart failed for %s; please perform an etcd restore", secret.Name)
+		if status.Step == opv1alpha1.EncryptionKeyRotationStepRollback {
+			msg = fmt.Sprintf("restart with the restored encryption config failed for %s, or secrets do not decrypt with it; please perform an etcd restore", secret.Name)
+		}
+		fail(s, status, opv1alpha1.PlanFailedReason, msg)
+		return false, nil
 	}
 
-	logrus.Infof("[encryptionkeyrotation] %s/%s: transitioning to rotate", s.op.Namespace, s.op.Name)
-
-	status.Phase = opv1alpha1.OperationPhaseInProgress
-	status.LastUpdated = metav1.Now()
-	status.Step = opv1alpha1.EncryptionKeyRotationStepRotate
-
-	opv1alpha1.InProgressCondition.True(&status)
-	opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.InProgressReason)
-	return status, nil
-}
+	if planStatus.Waiting() {
+		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for restart for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, plan.Message([]plan.PlanStatus{*planStatus}))
+		return false, nil
+	}
 
-func (h *handler) handleInProgress(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	ownerKey := beaconOwnerKey(s.op)
-	stepPrefix := stepHookPrefixFor(s.op.Status.Step)
-
-	// Stage 1 (loose): the op must appear SOMEWHERE in the ownership chain (owner or any
-	// delegate). Being absent entirely means the beacon was reassigned to another controller and
-	// we can't recover. If a step hook is currently active on the op, treat the absence as a
-	// step-scoped delegation and surface WaitingForDelegate instead of failing — the delegate may
-	// have popped us in service of the hook and will restore ownership when the hook clears.
-	if !plan.IsOwningBeaconHolder(s.beacon, ownerKey) && !plan.IsInDelegateChain(s.beacon, ownerKey) {
-		if planv1alpha1.HasStepHookLabel(s.op, stepPrefix) {
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-			opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-			return status, nil
+	if ops.IsControlPlane(secret) {
+		waitMessage := convergenceWaitMessage
+		if status.Step == opv1alpha1.EncryptionKeyRotationStepRollback {
+			waitMessage = rollbackWaitMessage
+		}
+		waitMsg, err := waitMessage(secret, requireHashMatch)
+		if err != nil {
+			logrus.Errorf("[encryptionkeyrotation] %s/%s: convergence check failed on %s: %v", s.Op.Namespace, s.Op.Name, secret.Name, err)
+			fail(s, status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt encryption key rotation state on %s; please perform an etcd restore", secret.Name))
+			return false, nil
 		}
-		status.Phase = opv1alpha1.OperationPhaseFailed
-		status.LastUpdated = metav1.Now()
+		if waitMsg != "" {
+			logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for convergence on %s: %s", s.Op.Namespace, s.Op.Name, secret.Name, waitMsg)
+			ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForEncryptionKeyRotationReason, waitMsg)
+			return false, nil
+		}
+	}
+	return true, nil
+}
 
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.BeaconLostReason)
-		opv1alpha1.FailedCondition.Message(&status, "beacon reassigned, aborting")
+// reconcileRollback walks the server nodes in restart order like reconcileRestart, restoring the
+// encryption config file backed up in the Preflight step before restarting each one. The plan of
+// the last node also lists every secret, so the operation is only marked as Failed with the cause
+// of the rollback once the secrets are known to decrypt with the restored config.
+func (h *handler) reconcileRollback(s *scope, status *opv1alpha1.EncryptionKeyRotationStatus) (bool, error) {
+	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling rollback", s.Op.Namespace, s.Op.Name)
 
-		return status, nil
+	secrets, err := h.collectServers(s)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[encryptionkeyrotation] %s/%s: no control-plane nodes found at rollback step", s.Op.Namespace, s.Op.Name)
+		fail(s, status, opv1alpha1.UnknownStepReason, "no control-plane nodes found")
+		return false, nil
 	}
 
-	var err error
-	s.beacon, err = plan.ToggleBeacon(s.beacon, true, h.beacons)
-	if err != nil {
-		return status, err
-	}
+	env := operationEnv(s.Op, status.Step)
+	serverUnit := s.Adapter.ServerUnit()
+	runtime := s.Adapter.RuntimeCommand()
 
-	// InProgress-phase hook fires on every InProgress reconcile, ahead of step dispatch — useful
-	// for delegates that need to gate ALL step work uniformly without subscribing to each
-	// individual step prefix.
-	delegated, err := h.handleHook(s, planv1alpha1.InProgressPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Stage 2 (strict): after the InProgress-phase hook has been handled, the op must be the
-	// primary owner or the most-recent delegate on the chain to drive step work. If a step hook
-	// is still active on the op, treat the missing-top state as an intentional delegation and
-	// wait; otherwise this is a genuine beacon loss and we fail.
-	if !plan.AuthorizedForBeacon(s.beacon, ownerKey) {
-		if planv1alpha1.HasStepHookLabel(s.op, stepPrefix) {
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-			opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-			return status, nil
+	for i, secret := range secrets {
+		last := i == len(secrets)-1
+		nodePlan, err := rollbackPlan(s, secret, env, serverUnit, runtime, last)
+		if err != nil {
+			return false, err
+		}
+		requireHashMatch := last && ops.IsControlPlane(secret)
+		if done, err := h.reconcileRestartNode(s, status, secret, nodePlan, requireHashMatch); err != nil || !done {
+			return false, err
 		}
-		status.Phase = opv1alpha1.OperationPhaseFailed
-		status.LastUpdated = metav1.Now()
+	}
 
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.BeaconLostReason)
-		opv1alpha1.FailedCondition.Message(&status, "beacon acquired by another controller, aborting")
+	logrus.Infof("[encryptionkeyrotation] %s/%s: rolled back, marking as failed", s.Op.Namespace, s.Op.Name)
+	ops.Fail(&status.OperationStatus, opv1alpha1.RolledBackReason, fmt.Sprintf("encryption key rotation was rolled back after: %s", status.RollbackCause))
+	return false, nil
+}
 
-		return status, nil
+// previewPreflight renders the backup plan of every server node for a dry run. Nothing is rendered
+// without rollback enabled, as the step then takes no backup.
+func (h *handler) previewPreflight(s *scope, preview *ops.PlanPreview) (string, error) {
+	if !s.Op.Spec.Rollback {
+		return "", nil
 	}
 
-	switch s.op.Status.Step {
-	case opv1alpha1.EncryptionKeyRotationStepRotate:
-		return h.reconcileRotate(s, status)
-	case opv1alpha1.EncryptionKeyRotationStepRestart:
-		return h.reconcileRestart(s, status)
+	secrets, err := h.collectServers(s)
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return "no control-plane nodes found; cannot back up encryption config", nil
 	}
 
-	status.Phase = opv1alpha1.OperationPhaseFailed
-	status.LastUpdated = metav1.Now()
-
-	opv1alpha1.FailedCondition.True(&status)
-	opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.UnknownStepReason)
-	opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("current step [%q] is unknown, expected one of: [%q, %q]",
-		status.Step, opv1alpha1.EncryptionKeyRotationStepRotate, opv1alpha1.EncryptionKeyRotationStepRestart))
-
-	return status, nil
+	env := operationEnv(s.Op, opv1alpha1.EncryptionKeyRotationStepPreflight)
+	for _, secret := range secrets {
+		preview.Add(string(opv1alpha1.EncryptionKeyRotationStepPreflight), secret, backupPlan(s, secret, env), 1, 1)
+	}
+	return "", nil
 }
 
-// reconcileRotate runs `secrets-encrypt rotate-keys` on the elected leader and
-// stays in Rotate until status reports `reencrypt_finished` on that node.
-func (h *handler) reconcileRotate(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling secrets-encrypt rotate-keys", s.op.Namespace, s.op.Name)
-
-	// Hook check before PauseCluster so a delegate can inspect or modify the cluster's pre-pause
-	// state. PauseCluster is idempotent so re-entering after the hook clears just no-ops.
-	delegated, err := h.handleHook(s, RotateStepHookLabelPrefix)
+// previewRotate renders the rotate-keys plan of the leader the Rotate step would elect for a dry
+// run, without marking it.
+func previewRotate(s *scope, preview *ops.PlanPreview) (string, error) {
+	leader, err := s.Adapter.PreviewLeader(ControllerOwnerKey, ops.IsControlPlane)
 	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
+		return "", err
+	} else if leader == nil {
+		return "no suitable control-plane leader found for encryption key rotation", nil
 	}
 
-	leader, err := s.adapter.FindOrElectLeader(ControllerOwnerKey, ops.IsControlPlane)
+	rotate, err := rotatePlan(s, leader, operationEnv(s.Op, opv1alpha1.EncryptionKeyRotationStepRotate), s.Adapter.RuntimeCommand())
 	if err != nil {
-		return status, err
+		return "", err
 	}
+	preview.Add(string(opv1alpha1.EncryptionKeyRotationStepRotate), leader, rotate, 1, 1)
+	return "", nil
+}
 
-	if leader == nil {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: no suitable control-plane leader found yet, will retry", s.op.Namespace, s.op.Name)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForSuitableLeaderReason)
-		opv1alpha1.InProgressCondition.Message(&status, "waiting for a suitable control-plane leader for encryption key rotation")
-		return status, nil
+// previewRestart renders the restart plan of every server node in restart order for a dry run.
+func (h *handler) previewRestart(s *scope, preview *ops.PlanPreview) (string, error) {
+	secrets, err := h.collectServers(s)
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return "no control-plane nodes found; cannot verify post-restart encryption status", nil
+	}
+	if !ops.IsControlPlane(secrets[len(secrets)-1]) {
+		return "last control plane node not found; cannot verify hash convergence after restart", nil
 	}
 
-	// Pause the CAPI cluster while rotate-keys is active so unrelated activity
-	// does not race with the encryption-key rotation plan.
-	if err := s.adapter.PauseCluster(true); err != nil {
-		return status, err
+	env := operationEnv(s.Op, opv1alpha1.EncryptionKeyRotationStepRestart)
+	for _, secret := range secrets {
+		restart, err := restartPlan(s, secret, env, s.Adapter.ServerUnit(), s.Adapter.RuntimeCommand())
+		if err != nil {
+			return "", err
+		}
+		preview.Add(string(opv1alpha1.EncryptionKeyRotationStepRestart), secret, restart, 5, 5)
 	}
+	return "", nil
+}
 
-	probes, err := s.adapter.RenderProbes(leader, true)
+// rotatePlan builds the rotate-keys plan for the elected leader.
+func rotatePlan(s *scope, leader *corev1.Secret, env []string, runtime string) (*plan.Plan, error) {
+	probes, err := s.Adapter.RenderProbes(leader, true)
 	if err != nil {
-		return status, err
+		return nil, err
 	}
 
-	env := operationEnv(s.op, status.Step)
-	runtime := s.adapter.RuntimeCommand()
-
 	nodePlan := &plan.Plan{
 		OneTimeInstructions: []plan.OneTimeInstruction{
 			// 1. Run rotate-keys via wrapper that always exits 0; captures real exit code in output.
@@ -536,180 +542,15 @@ func (h *handler) reconcileRotate(s *scope, status opv1alpha1.EncryptionKeyRotat
 		Probes: probes,
 	}
 
-	// Use finite failure threshold so a plan that can't execute
-	// is marked Failed rather than retried forever. The wrapper always exits 0, so a
-	// real apply failure here means the wrapper itself couldn't run.
-	planStatus, err := h.store.AssignPlan(leader, nodePlan, 1, 1)
-	if err != nil {
-		return status, err
-	}
-
-	if planStatus.Failure() {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: rotate-keys plan failed to execute on leader %s", s.op.Namespace, s.op.Name, leader.Name)
-		markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("encryption key rotation plan failed for leader %s/%s", leader.Namespace, leader.Name))
-		return status, nil
-	}
-
-	if planStatus.Waiting() {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for rotate-keys plan for %s/%s", s.op.Namespace, s.op.Name, leader.Namespace, leader.Name)
-
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
-
-		return status, nil
-	}
-
-	// Plan applied and probes passed. Read the one-time output to check the rotate-keys exit code.
-	appliedOutput, err := plan.ReadAppliedOutput(leader)
-	if err != nil {
-		return status, err
-	}
-	if appliedOutput == nil {
-		// Output not yet in cache; wait for the next reconcile.
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: rotate-keys applied-output not yet available on leader %s", s.op.Namespace, s.op.Name, leader.Name)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "waiting for rotate-keys output")
-		return status, nil
-	}
-
-	result, err := readRotateKeysResult(appliedOutput)
-	if errors.Is(err, errRotateKeysOutputNotYet) {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: rotate-keys exit code not yet available on leader %s", s.op.Namespace, s.op.Name, leader.Name)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "waiting for rotate-keys exit code")
-		return status, nil
-	}
-	if err != nil {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: corrupt rotate-keys output on leader %s: %v", s.op.Namespace, s.op.Name, leader.Name, err)
-		markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt rotate-keys output on leader %s", leader.Name))
-		return status, nil
-	}
-
-	if result.exitCode != 0 {
-		if rotateKeysCommandTimedOut(result.output) {
-			// CLI timed out but rotation may still be running in the background;
-			// keep watching periodic status before deciding.
-			logrus.Warnf("[encryptionkeyrotation] %s/%s: rotate-keys CLI timed out on leader %s; continuing to observe periodic status", s.op.Namespace, s.op.Name, leader.Name)
-		} else {
-			logrus.Errorf("[encryptionkeyrotation] %s/%s: rotate-keys failed on leader %s with exit code %d", s.op.Namespace, s.op.Name, leader.Name, result.exitCode)
-			markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("secrets-encrypt rotate-keys failed on leader %s (exit code %d); please perform an etcd restore", leader.Name, result.exitCode))
-			return status, nil
-		}
-	}
-
-	// Check periodic secrets-encrypt status. Stay in Rotate until reencrypt_finished.
-	waitMsg, err := convergenceWaitMessage(leader, false)
-	if err != nil {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: convergence check failed on leader %s: %v", s.op.Namespace, s.op.Name, leader.Name, err)
-		markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt encryption key rotation state on leader %s; please perform an etcd restore", leader.Name))
-		return status, nil
-	}
-	if waitMsg != "" {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for convergence on leader %s: %s", s.op.Namespace, s.op.Name, leader.Name, waitMsg)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForEncryptionKeyRotationReason)
-		opv1alpha1.InProgressCondition.Message(&status, waitMsg)
-		return status, nil
-	}
-
-	logrus.Infof("[encryptionkeyrotation] %s/%s: rotate-keys reencrypt_finished on leader %s, transitioning to restart", s.op.Namespace, s.op.Name, leader.Name)
-	status.Step = opv1alpha1.EncryptionKeyRotationStepRestart
-	return status, nil
-}
-
-// reconcileRestart walks the server nodes in sorted order, restarting each one
-// and requiring strict hash convergence only on the final control-plane node.
-func (h *handler) reconcileRestart(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling service restart", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, RestartStepHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Restart order comes from plan.DefaultSorter(): init+etcd first, then
-	// etcd-only, then mixed etcd/control-plane, then control-plane-only. That
-	// keeps etcd nodes ahead of pure control-plane nodes.
-	secrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
-		WithLabels(
-			plan.Label(capr.ClusterNameLabel, s.clusterObj.GetName()),
-			plan.Or(
-				plan.Label(capr.EtcdRoleLabel, "true"),
-				plan.Label(capr.ControlPlaneRoleLabel, "true"),
-			)).
-		WithSorter(plan.DefaultSorter()).
-		WithValidator(plan.AtLeast(1, "")).
-		Collect()
-
-	if plan.IsTransient(err) {
-		return status, err
-	} else if err != nil {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: no control-plane nodes found at restart step", s.op.Namespace, s.op.Name)
-		markFailed(&status, opv1alpha1.UnknownStepReason, "no control-plane nodes found; cannot verify post-restart encryption status")
-		return status, nil
-	}
-	if !ops.IsControlPlane(secrets[len(secrets)-1]) {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: nodes are not correctly ordered at restart step", s.op.Namespace, s.op.Name)
-		markFailed(&status, opv1alpha1.UnknownStepReason, "last control plane node not found; cannot verify hash convergence after restart")
-		return status, nil
-	}
-
-	env := operationEnv(s.op, status.Step)
-	serverUnit := s.adapter.ServerUnit()
-	runtime := s.adapter.RuntimeCommand()
-
-	// pool is ordered with plan.DefaultSorter(), so the final element is the
-	// last control-plane node. For the new k3s rotate-keys flow, strict "All
-	// hashes match" validation only makes sense after every control-plane node
-	// has restarted; before that, k3s may legitimately report
-	// reencrypt_finished while hashes still differ across servers.
-	for i, secret := range secrets {
-		requireHashMatch := i == len(secrets)-1
-		status, done, err := h.reconcileRestartNode(s, status, secret, env, serverUnit, runtime, requireHashMatch)
-		if err != nil {
-			return status, err
-		}
-		if !done {
-			return status, nil
-		}
-	}
-
-	logrus.Infof("[encryptionkeyrotation] %s/%s: marking as success", s.op.Namespace, s.op.Name)
-
-	status.Phase = opv1alpha1.OperationPhaseSucceeded
-	status.LastUpdated = metav1.Now()
-
-	opv1alpha1.SucceededCondition.True(&status)
-	opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.FinishedReason)
-	opv1alpha1.SucceededCondition.Message(&status, "Operation completed successfully")
-
-	return status, nil
+	return nodePlan, nil
 }
 
-// reconcileRestartNode assigns and tracks the restart plan for one node. For
-// control-plane nodes it also waits for post-restart secrets-encrypt status,
-// and on the final control-plane node it enforces the cluster-wide hash check
-// required by the k3s rotate-keys flow.
-func (h *handler) reconcileRestartNode(
-	s *scope,
-	status opv1alpha1.EncryptionKeyRotationStatus,
-	secret *corev1.Secret,
-	env []string,
-	serverUnit string,
-	runtime string,
-	requireHashMatch bool,
-) (opv1alpha1.EncryptionKeyRotationStatus, bool, error) {
-	probes, err := s.adapter.RenderProbes(secret, true)
+// restartPlan builds the plan restarting the server unit of a single etcd or control-plane node. On
+// control-plane nodes the plan also waits for and periodically captures secrets-encrypt status.
+func restartPlan(s *scope, secret *corev1.Secret, env []string, serverUnit string, runtime string) (*plan.Plan, error) {
+	probes, err := s.Adapter.RenderProbes(secret, true)
 	if err != nil {
-		return status, false, err
+		return nil, err
 	}
 
 	oneTimeInstructions := []plan.OneTimeInstruction{
@@ -768,277 +609,116 @@ func (h *handler) reconcileRestartNode(
 		}
 	}
 
-	planStatus, err := h.store.AssignPlan(secret, nodePlan, 5, 5)
-	if err != nil {
-		return status, false, err
-	}
-
-	if planStatus.Failure() {
-		logrus.Errorf("[encryptionkeyrotation] %s/%s: restart plan failed for %s", s.op.Namespace, s.op.Name, secret.Name)
-		markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("restart failed for %s; please perform an etcd restore", secret.Name))
-		return status, false, nil
-	}
-
-	if planStatus.Waiting() {
-		logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for restart for %s/%s", s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
-		return status, false, nil
-	}
-
-	if ops.IsControlPlane(secret) {
-		waitMsg, err := convergenceWaitMessage(secret, requireHashMatch)
-		if err != nil {
-			logrus.Errorf("[encryptionkeyrotation] %s/%s: convergence check failed on %s: %v", s.op.Namespace, s.op.Name, secret.Name, err)
-			markFailed(&status, opv1alpha1.PlanFailedReason, fmt.Sprintf("corrupt encryption key rotation state on %s; please perform an etcd restore", secret.Name))
-			return status, false, nil
-		}
-		if waitMsg != "" {
-			logrus.Debugf("[encryptionkeyrotation] %s/%s: waiting for convergence on %s: %s", s.op.Namespace, s.op.Name, secret.Name, waitMsg)
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForEncryptionKeyRotationReason)
-			opv1alpha1.InProgressCondition.Message(&status, waitMsg)
-			return status, false, nil
-		}
-	}
-	return status, true, nil
+	return nodePlan, nil
 }
 
-// handleCanceled is the terminal handler for the Canceled phase. Like handleFailed/handleSucceeded
-// it runs its phase hook first so a delegate can observe cancellation, then unpauses the cluster
-// and releases the beacon if we still own it. The cancel-vs-fail distinction is that an external
-// party cancels whereas the operation fails itself — neither implies the other.
-func (h *handler) handleCanceled(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling operation canceled", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, planv1alpha1.CanceledPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.CanceledCondition.True(&status)
-		opv1alpha1.CanceledCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.CanceledCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	if err := s.adapter.PauseCluster(false); err != nil {
-		return status, err
-	}
-
-	// Owner and mid-chain delegates both go through ReleaseBeacon: it clears the beacon fully
-	// for the owner, or removes the delegate slot from the chain otherwise.
-	ownerKey := beaconOwnerKey(s.op)
-	if plan.IsOwningBeaconHolder(s.beacon, ownerKey) || plan.IsInDelegateChain(s.beacon, ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, ownerKey); err != nil {
-			return status, err
-		}
+// backupPlan builds the plan copying the encryption config file of a single etcd or control-plane
+// node to its backup path. A node without an encryption config has its stale backup removed, so a
+// rollback leaves it untouched.
+func backupPlan(s *scope, secret *corev1.Secret, env []string) *plan.Plan {
+	config := path.Join(s.Adapter.DistroDataDirectory(secret), encryptionConfigFile)
+	return &plan.Plan{
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    backupInstructionName,
+					Command: "/bin/sh",
+					Args:    []string{"-c", backupScript(config)},
+					Env:     env,
+				},
+			},
+		},
 	}
-	return status, nil
 }
 
-// handleFailed releases the beacon and unpauses cluster activity after the
-// operation has already been marked terminal.
-func (h *handler) handleFailed(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling operation failed", s.op.Namespace, s.op.Name)
-
-	// Failed-phase hook fires before cleanup so a delegate can inspect failure state (status
-	// conditions, plan-secret applied output, residual rotate-keys process) before the cluster is
-	// unpaused and the beacon released.
-	delegated, err := h.handleHook(s, planv1alpha1.FailedPhaseHookLabelPrefix)
+// rollbackPlan builds the restart plan of a single etcd or control-plane node, preceded by the
+// restore of the encryption config file backed up by backupPlan. When verify is set the plan ends
+// by listing every secret through the apiserver, which fails if any of them does not decrypt.
+func rollbackPlan(s *scope, secret *corev1.Secret, env []string, serverUnit string, runtime string, verify bool) (*plan.Plan, error) {
+	nodePlan, err := restartPlan(s, secret, env, serverUnit, runtime)
 	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	if err := s.adapter.PauseCluster(false); err != nil {
-		return status, err
-	}
-
-	// Owner performs full teardown via ReleaseBeacon; a mid-chain delegate is removed from the
-	// chain by the same call. Non-participants are no-op.
-	ownerKey := beaconOwnerKey(s.op)
-	if plan.IsOwningBeaconHolder(s.beacon, ownerKey) || plan.IsInDelegateChain(s.beacon, ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, ownerKey); err != nil {
-			return status, err
-		}
+		return nil, err
 	}
-	return status, nil
-}
-
-// handleSucceeded clears the active beacon state, releases ownership, unpauses
-// cluster activity, and re-enqueues the backing cluster so downstream
-// controllers observe the final beacon transition.
-func (h *handler) handleSucceeded(s *scope, status opv1alpha1.EncryptionKeyRotationStatus) (opv1alpha1.EncryptionKeyRotationStatus, error) {
-	logrus.Debugf("[encryptionkeyrotation] %s/%s: handling operation succeeded", s.op.Namespace, s.op.Name)
 
-	// Succeeded-phase hook fires before unpausing + releasing — delegates use this to chain
-	// follow-up work (e.g. a verifier that re-runs `secrets-encrypt status` from outside the
-	// operation) before the cluster goes back to accepting new operations.
-	delegated, err := h.handleHook(s, planv1alpha1.SucceededPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.SucceededCondition.True(&status)
-		opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.SucceededCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	if err := s.adapter.PauseCluster(false); err != nil {
-		return status, err
-	}
-
-	// Owner does the full teardown + enqueues the cluster; a mid-chain delegate just removes
-	// itself. Only owner cleanup implies downstream reconciliation, so only owner enqueues.
-	// ReleaseBeacon handles Active=false + Owner="" + Delegates=nil in a single UpdateStatus for
-	// the owner path — no separate ToggleBeacon call is needed.
-	ownerKey := beaconOwnerKey(s.op)
-	owning := plan.IsOwningBeaconHolder(s.beacon, ownerKey)
-	if owning || plan.IsInDelegateChain(s.beacon, ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, ownerKey); err != nil {
-			return status, err
-		}
-	}
-	if owning {
-		// enqueue original object to ensure it is processed by requisite controllers
-		gvk := schema.FromAPIVersionAndKind(s.clusterObj.GetAPIVersion(), s.clusterObj.GetKind())
-		_ = h.dynamic.Enqueue(gvk, s.clusterObj.GetNamespace(), s.clusterObj.GetName())
+	config := path.Join(s.Adapter.DistroDataDirectory(secret), encryptionConfigFile)
+	nodePlan.OneTimeInstructions = append([]plan.OneTimeInstruction{
+		{
+			CommonInstruction: plan.CommonInstruction{
+				Name:    restoreInstructionName,
+				Command: "/bin/sh",
+				Args:    []string{"-c", restoreScript(config)},
+				Env:     env,
+			},
+		},
+	}, nodePlan.OneTimeInstructions...)
+	if verify {
+		nodePlan.OneTimeInstructions = append(nodePlan.OneTimeInstructions, plan.OneTimeInstruction{
+			CommonInstruction: plan.CommonInstruction{
+				Name:    verifySecretsInstructionName,
+				Command: "/bin/sh",
+				Args:    []string{"-c", verifySecretsScript(s.Adapter.KubectlPath(secret), s.Adapter.KubeconfigPath(secret))},
+				Env:     env,
+			},
+		})
 	}
-	return status, nil
-}
-
-// updateStatus updates the conditions of the operation based on the current status.
-// This function also updates the ObservedGeneration.
-// The handler is responsible for updating the condition relevant to the current phase, but this function updates the
-// remaining conditions.
-func updateStatus(op *opv1alpha1.EncryptionKeyRotation, status opv1alpha1.EncryptionKeyRotationStatus) opv1alpha1.EncryptionKeyRotationStatus {
-	logrus.Tracef("[encryptionkeyrotation] %s/%s: updating conditions", op.Namespace, op.Name)
-
-	status.ObservedGeneration = op.Generation
-
-	if status.Phase == opv1alpha1.OperationPhasePending {
-		opv1alpha1.PendingCondition.True(&status)
-	} else if status.Phase == opv1alpha1.OperationPhaseInProgress {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.InProgressReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation now in progress")
-	} else if status.Phase == opv1alpha1.OperationPhaseSucceeded {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation completed successfully")
-		opv1alpha1.InProgressCondition.False(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "Operation completed successfully")
-		opv1alpha1.FailedCondition.False(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.NotFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "Operation completed successfully")
-	} else if status.Phase == opv1alpha1.OperationPhaseFailed {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation failed")
-		opv1alpha1.InProgressCondition.False(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "Operation failed")
-		opv1alpha1.SucceededCondition.False(&status)
-		opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.NotSuccessfulReason)
-		opv1alpha1.SucceededCondition.Message(&status, "Operation failed")
-	}
-
-	return status
+	return nodePlan, nil
 }
 
-// markFailed transitions status to the Failed phase with the given reason and condition message.
-// Callers are responsible for logging before calling.
-func markFailed(status *opv1alpha1.EncryptionKeyRotationStatus, reason, condMsg string) {
-	status.Phase = opv1alpha1.OperationPhaseFailed
-	status.LastUpdated = metav1.Now()
-	opv1alpha1.FailedCondition.True(status)
-	opv1alpha1.FailedCondition.Reason(status, reason)
-	opv1alpha1.FailedCondition.Message(status, condMsg)
+// collectServers returns the etcd and control-plane machine-plan secrets in restart order. The
+// order comes from plan.DefaultSorter(): init+etcd first, then etcd-only, then mixed
+// etcd/control-plane, then control-plane-only.
+func (h *handler) collectServers(s *scope) ([]*corev1.Secret, error) {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithLabels(
+			plan.Label(capr.ClusterNameLabel, s.Cluster.GetName()),
+			plan.Or(
+				plan.Label(capr.EtcdRoleLabel, "true"),
+				plan.Label(capr.ControlPlaneRoleLabel, "true"),
+			)).
+		WithSorter(plan.DefaultSorter()).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
 }
 
-// beaconOwnerKey returns the per-operation beacon owner key used for
-// beacon ownership checks and lifecycle cleanup.
-func beaconOwnerKey(op *opv1alpha1.EncryptionKeyRotation) string {
-	if op == nil {
-		return ControllerOwnerKey
-	}
-	if op.UID != "" {
-		return fmt.Sprintf("%s-%s", ControllerOwnerKey, op.UID)
+// fail handles a failure of the current step. A failure of the Rotate step starts the rollback when
+// it is enabled, the encryption config was backed up and re-encryption has not started; a failure
+// of the rollback itself marks the operation as Failed with both the rollback failure and its
+// cause. A failure of the Restart step is never rolled back, since the Rotate step only completes
+// once re-encryption finished, and its message says so. It is also the StepFailed handler of the
+// definition, so timed out steps roll back too.
+func fail(s *scope, status *opv1alpha1.EncryptionKeyRotationStatus, reason, condMsg string) {
+	switch {
+	case status.Step == opv1alpha1.EncryptionKeyRotationStepRollback:
+		ops.Fail(&status.OperationStatus, reason, fmt.Sprintf("rollback failed: %s; rollback was started after: %s", condMsg, status.RollbackCause))
+	case canRollback(s.Op, status):
+		logrus.Warnf("[encryptionkeyrotation] %s/%s: rolling back after step %s failed: %s", s.Op.Namespace, s.Op.Name, status.Step, condMsg)
+		status.RollbackCause = condMsg
+		status.SetStep(opv1alpha1.EncryptionKeyRotationStepRollback)
+		ops.Wait(&status.OperationStatus, opv1alpha1.RollingBackReason, fmt.Sprintf("rolling back after: %s", condMsg))
+	case s.Op.Spec.Rollback && status.Step == opv1alpha1.EncryptionKeyRotationStepRestart:
+		ops.Fail(&status.OperationStatus, reason, fmt.Sprintf("%s; not rolled back, as secrets were re-encrypted with the new key before the Restart step", condMsg))
+	default:
+		ops.Fail(&status.OperationStatus, reason, condMsg)
 	}
-	return fmt.Sprintf("%s-%s-%s", ControllerOwnerKey, op.Namespace, op.Name)
 }
 
-// reclaimStaleBeaconOwnerIfNeeded clears stale beacon ownership when the
-// recorded owner reference is invalid, missing, deleted, or terminal.
-// Non-matching owners are left untouched so this controller only reclaims its own
-// operation type.
-func (h *handler) reclaimStaleBeaconOwnerIfNeeded(s *scope) error {
-	if s.beacon == nil {
-		return nil
-	}
-
-	currentOwnerKey := s.beacon.Status.Owner
-	newOwnerKey := beaconOwnerKey(s.op)
-	// No owner, or we already own it
-	if currentOwnerKey == "" || currentOwnerKey == newOwnerKey {
-		return nil
-	}
-	// Another controller type owns it
-	if currentOwnerKey != ControllerOwnerKey && !strings.HasPrefix(currentOwnerKey, ControllerOwnerKey+"-") {
-		return nil
-	}
-
-	reclaim := false
-
-	ownerRef := ""
-	if s.beacon.Annotations != nil {
-		ownerRef = s.beacon.Annotations[beaconOwnerRefAnnotation]
-	}
-	parts := strings.SplitN(ownerRef, "/", 3)
-	// Missing or broken owner ref means we cannot trust this owner
-	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
-		reclaim = true
-	} else {
-		currentOp, err := h.encryptionkeyrotations.Get(parts[0], parts[1], metav1.GetOptions{})
-		// Owner object is gone
-		if apierrors.IsNotFound(err) {
-			reclaim = true
-		} else if err != nil {
-			return err
-			// UID changed or owner finished
-		} else if string(currentOp.UID) != parts[2] || ops.IsTerminal(currentOp.Status.Phase) {
-			reclaim = true
-		}
-	}
-	if !reclaim {
-		return nil
-	}
-
-	beacon := s.beacon.DeepCopy()
-	beacon.Status.Owner = ""
-	updated, err := h.beacons.Update(beacon)
-	if err != nil {
-		return err
+// canRollback reports whether a failure of the current step can be rolled back: rollback must be
+// enabled, the step must be Rotate with re-encryption not started, and the Preflight step must have
+// completed. Once re-encryption may have started, secrets may only decrypt with the new key, and
+// restoring the backed up config would lock the cluster out of them.
+func canRollback(op *opv1alpha1.EncryptionKeyRotation, status *opv1alpha1.EncryptionKeyRotationStatus) bool {
+	if !op.Spec.Rollback {
+		return false
 	}
-	s.beacon = updated
-
-	beacon = s.beacon.DeepCopy()
-	if beacon.Annotations != nil {
-		delete(beacon.Annotations, beaconOwnerRefAnnotation)
+	if status.Step != opv1alpha1.EncryptionKeyRotationStepRotate || status.ReencryptionStarted {
+		return false
 	}
-	updated, err = h.beacons.Update(beacon)
-	if err != nil {
-		return err
+	for _, step := range status.StepHistory {
+		if step.Name == string(opv1alpha1.EncryptionKeyRotationStepPreflight) && step.Finished != nil {
+			return true
+		}
 	}
-	s.beacon = updated
-
-	return nil
+	return false
 }
 
 // operationEnv returns env vars that tie plan content to the operation UID and
@@ -1062,9 +742,14 @@ func operationEnv(op *opv1alpha1.EncryptionKeyRotation, step opv1alpha1.Encrypti
 var errStatusTimeout = errors.New("secrets-encrypt status timed out")
 
 const (
+	backupInstructionName        = "backup-encryption-config"
+	restoreInstructionName       = "restore-encryption-config"
+	verifySecretsInstructionName = "verify-secrets-decrypt"
 	rotateKeysInstructionName    = "rotate-keys"
 	statusPeriodicName           = "secrets-encrypt-status"
 	waitForStatusInstructionName = "wait-for-secrets-encrypt-status"
+	stageReencryptRequest        = "reencrypt_request"
+	stageReencryptActive         = "reencrypt_active"
 	stageReencryptFinished       = "reencrypt_finished"
 	hashesMatchMessage           = "All hashes match"
 	exitCodePrefix               = "rancher-rotate-keys-exit-code="
@@ -1076,6 +761,11 @@ func operationEnv(op *opv1alpha1.EncryptionKeyRotation, step opv1alpha1.Encrypti
 
 	// statusTimeoutEndpoint identifies a transient timeout from secrets-encrypt status.
 	statusTimeoutEndpoint = "/encrypt/status"
+
+	// encryptionConfigFile is the path of the encryption config file relative to the distro
+	// data-dir, and encryptionConfigBackupSuffix the suffix of its backup taken in Preflight.
+	encryptionConfigFile         = "server/cred/encryption-config.json"
+	encryptionConfigBackupSuffix = ".rancher-rollback"
 )
 
 // timeoutMarkers are the known CLI timeout signatures from secrets-encrypt calls.
@@ -1114,6 +804,29 @@ func waitForStatusScript(runtime string) string {
 		runtime)
 }
 
+// backupScript returns a shell one-liner that copies the encryption config file to its backup
+// path, or removes a stale backup when the node has no encryption config.
+func backupScript(config string) string {
+	backup := config + encryptionConfigBackupSuffix
+	return fmt.Sprintf(`if [ -f %s ]; then cp -p %s %s; else rm -f %s; fi`, config, config, backup, backup)
+}
+
+// restoreScript returns a shell one-liner that copies the backup taken by backupScript over the
+// encryption config file. Nodes without a backup are left untouched.
+func restoreScript(config string) string {
+	backup := config + encryptionConfigBackupSuffix
+	return fmt.Sprintf(`if [ -f %s ]; then cp -p %s %s; fi`, backup, backup, config)
+}
+
+// verifySecretsScript returns a shell one-liner that lists every secret through the apiserver until
+// it succeeds, up to 10 retries at 10s intervals (100s total), and exits 1 on timeout. Listing
+// fails while any secret does not decrypt with the encryption config of the apiserver.
+func verifySecretsScript(kubectl, kubeconfig string) string {
+	return fmt.Sprintf(
+		`i=0; while [ $i -lt 10 ]; do %s --kubeconfig %s get secrets -A -o name >/dev/null && exit 0; sleep 10; i=$((i+1)); done; exit 1`,
+		kubectl, kubeconfig)
+}
+
 // rotateKeysScript returns the shell wrapper command that captures secrets-encrypt rotate-keys'
 // exit code in stdout and always exits 0, so system-agent never marks the plan failed on a
 // CLI timeout. The controller classifies timeout vs real failure itself.
@@ -1183,10 +896,67 @@ func commandTimedOut(output, endpoint string) bool {
 //   - ("", err)    — hard error: corrupt payload, plan instruction missing, parse failure,
 //     or hash field absent when requireHashMatch is true at reencrypt_finished
 func convergenceWaitMessage(secret *corev1.Secret, requireHashMatch bool) (string, error) {
+	rotStatus, waitMsg, err := periodicStatus(secret)
+	if err != nil || waitMsg != "" {
+		return waitMsg, err
+	}
+
+	if rotStatus.stage != stageReencryptFinished {
+		return fmt.Sprintf("waiting for reencrypt_finished on %s, current stage: %s", secret.Name, rotStatus.stage), nil
+	}
+	if requireHashMatch {
+		if !rotStatus.hashesPresent {
+			// Hash field absent at reencrypt_finished: the runtime output does not satisfy the
+			// convergence contract. Fail rather than waiting indefinitely.
+			return "", fmt.Errorf("secrets-encrypt status on %s reached %s but hash field is absent; runtime output may be incompatible", secret.Name, stageReencryptFinished)
+		}
+		if !rotStatus.hashesMatch {
+			return fmt.Sprintf("waiting for encryption key rotation hashes to converge on %s", secret.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// rollbackWaitMessage checks whether the periodic secrets-encrypt status on secret reports a
+// rotation stage after the encryption config was restored, and, when requireHashMatch is true,
+// that the hashes of all servers match again. The stage itself is not checked, since the restored
+// keys may be at any stage. Return values are as for convergenceWaitMessage.
+func rollbackWaitMessage(secret *corev1.Secret, requireHashMatch bool) (string, error) {
+	rotStatus, waitMsg, err := periodicStatus(secret)
+	if err != nil || waitMsg != "" {
+		return waitMsg, err
+	}
+
+	if requireHashMatch && rotStatus.hashesPresent && !rotStatus.hashesMatch {
+		return fmt.Sprintf("waiting for restored encryption hashes to converge on %s", secret.Name), nil
+	}
+	return "", nil
+}
+
+// reencryptionNotStarted reports whether the periodic secrets-encrypt status on secret shows that
+// re-encryption never started after a rejected rotate-keys: the stage is not one of the
+// re-encryption stages and the encryption config hashes of all servers still match, so no server
+// switched to a new key. Any status that is not available or does not parse reports false.
+func reencryptionNotStarted(secret *corev1.Secret) bool {
+	rotStatus, waitMsg, err := periodicStatus(secret)
+	if err != nil || waitMsg != "" {
+		return false
+	}
+	if rotStatus.stage == stageReencryptRequest || rotStatus.stage == stageReencryptActive {
+		return false
+	}
+	return rotStatus.hashesPresent && rotStatus.hashesMatch
+}
+
+// periodicStatus reads and parses the periodic secrets-encrypt status on secret. A non-empty
+// message means the status is not available yet; an error means it never will be, because the
+// payload is corrupt, the instruction is missing from the assigned plan or the output does not
+// parse.
+func periodicStatus(secret *corev1.Secret) (runtimeStatus, string, error) {
 	periodicOutput, err := plan.ReadAppliedPeriodicOutput(secret)
 	if err != nil {
 		// gzip decode or JSON unmarshal failure — corrupt payload, not a normal wait.
-		return "", fmt.Errorf("corrupt applied-periodic-output on %s: %w", secret.Name, err)
+		return runtimeStatus{}, "", fmt.Errorf("corrupt applied-periodic-output on %s: %w", secret.Name, err)
 	}
 
 	var entry plan.PeriodicInstructionOutput
@@ -1199,41 +969,27 @@ func convergenceWaitMessage(secret *corev1.Secret, requireHashMatch bool) (strin
 		// No output yet, only a wait if the instruction is actually in the assigned plan.
 		// Missing instruction means a malformed/regressed plan so fail immediately.
 		if err := validatePlanHasPeriodicStatus(secret); err != nil {
-			return "", fmt.Errorf("failed reading assigned plan from %s: %w", secret.Name, err)
+			return runtimeStatus{}, "", fmt.Errorf("failed reading assigned plan from %s: %w", secret.Name, err)
 		}
-		return fmt.Sprintf("waiting for secrets-encrypt status on %s", secret.Name), nil
+		return runtimeStatus{}, fmt.Sprintf("waiting for secrets-encrypt status on %s", secret.Name), nil
 	}
 
 	stdout := strings.TrimSpace(string(entry.Stdout))
 	if stdout == "" {
-		return fmt.Sprintf("waiting for secrets-encrypt status output on %s", secret.Name), nil
+		return runtimeStatus{}, fmt.Sprintf("waiting for secrets-encrypt status output on %s", secret.Name), nil
 	}
 
 	rotStatus, err := statusFromOutput(stdout)
 	if err != nil {
 		if errors.Is(err, errStatusTimeout) {
 			// Transient CLI timeout: wait for the next periodic run.
-			return fmt.Sprintf("secrets-encrypt status timed out on %s; waiting for next run", secret.Name), nil
+			return runtimeStatus{}, fmt.Sprintf("secrets-encrypt status timed out on %s; waiting for next run", secret.Name), nil
 		}
 		// Durable parse failure (e.g. missing rotation stage): surface as a hard error
 		// so the caller can fail the operation cleanly rather than looping indefinitely.
-		return "", fmt.Errorf("unable to parse secrets-encrypt status on %s: %w", secret.Name, err)
-	}
-
-	if rotStatus.stage != stageReencryptFinished {
-		return fmt.Sprintf("waiting for reencrypt_finished on %s, current stage: %s", secret.Name, rotStatus.stage), nil
+		return runtimeStatus{}, "", fmt.Errorf("unable to parse secrets-encrypt status on %s: %w", secret.Name, err)
 	}
-	if requireHashMatch {
-		if !rotStatus.hashesPresent {
-			// Hash field absent at reencrypt_finished: the runtime output does not satisfy the
-			// convergence contract. Fail rather than waiting indefinitely.
-			return "", fmt.Errorf("secrets-encrypt status on %s reached %s but hash field is absent; runtime output may be incompatible", secret.Name, stageReencryptFinished)
-		}
-		if !rotStatus.hashesMatch {
-			return fmt.Sprintf("waiting for encryption key rotation hashes to converge on %s", secret.Name), nil
-		}
-	}
-	return "", nil
+	return rotStatus, "", nil
 }
 
 // validatePlanHasPeriodicStatus reports whether the plan currently assigned to secret includes a
@@ -5,21 +5,17 @@
 	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
 	rkeplan "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
-	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
 	ops "github.com/rancher/rancher/pkg/operations"
 	"github.com/rancher/rancher/pkg/plan"
-	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
-	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 )
 
@@ -56,6 +52,14 @@ func (a *stubAdapter) ServerUnit() string {
 	return "rke2-server"
 }
 
+func (a *stubAdapter) AgentUnit() string {
+	return "rke2-agent"
+}
+
+func (a *stubAdapter) InstallerImage(v string) string {
+	return "installer:" + v
+}
+
 func (a *stubAdapter) RenderProbes(_ *corev1.Secret, _ bool) (map[string]rkeplan.Probe, error) {
 	return map[string]rkeplan.Probe{}, nil
 }
@@ -72,6 +76,10 @@ func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret,
 	return nil, nil
 }
 
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+
 func (a *stubAdapter) PauseCluster(paused bool) error {
 	a.pauseCalls = append(a.pauseCalls, paused)
 	return nil
@@ -94,35 +102,6 @@ func (a *stubAdapter) ToS3ArgsEnvAndFiles(_ *corev1.Secret) ([]string, []string,
 	return nil, nil, nil
 }
 
-type enqueueCall struct {
-	gvk       schema.GroupVersionKind
-	namespace string
-	name      string
-}
-
-type fakeDynamic struct {
-	getObj       runtime.Object
-	getErr       error
-	enqueueErr   error
-	enqueueCalls []enqueueCall
-}
-
-func (d *fakeDynamic) Get(_ schema.GroupVersionKind, _, _ string) (runtime.Object, error) {
-	if d.getErr != nil {
-		return nil, d.getErr
-	}
-	return d.getObj, nil
-}
-
-func (d *fakeDynamic) Enqueue(gvk schema.GroupVersionKind, namespace, name string) error {
-	d.enqueueCalls = append(d.enqueueCalls, enqueueCall{
-		gvk:       gvk,
-		namespace: namespace,
-		name:      name,
-	})
-	return d.enqueueErr
-}
-
 func newOp() *opv1alpha1.EncryptionKeyRotation {
 	return &opv1alpha1.EncryptionKeyRotation{
 		ObjectMeta: metav1.ObjectMeta{
@@ -133,66 +112,19 @@ func newOp() *opv1alpha1.EncryptionKeyRotation {
 	}
 }
 
-func newBeacon(owner string, active bool) *planv1alpha1.Beacon {
-	// Beacon ownership lives on Status.Owner; we keep the legacy BeaconOwnerLabel populated so
-	// reclaimStaleBeaconOwnerIfNeeded (which still reads the label) sees a consistent owner.
-	return &planv1alpha1.Beacon{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "fleet-default",
-			Namespace: "fleet-default",
-		},
-		Status: planv1alpha1.BeaconStatus{
-			Active: active,
-			Owner:  owner,
-		},
-	}
-}
-
-func newScope(op *opv1alpha1.EncryptionKeyRotation, beacon *planv1alpha1.Beacon, adapter ops.Adapter) *scope {
+func newScope(op *opv1alpha1.EncryptionKeyRotation, adapter ops.Adapter) *scope {
 	cluster := &unstructured.Unstructured{}
 	cluster.SetAPIVersion("provisioning.cattle.io/v1")
 	cluster.SetKind("Cluster")
 	cluster.SetNamespace("fleet-default")
 	cluster.SetName("test")
 	return &scope{
-		op:         op,
-		beacon:     beacon,
-		namespace:  "fleet-default",
-		clusterObj: cluster,
-		adapter:    adapter,
-	}
-}
-
-type fakeEncryptionKeyRotationController struct {
-	operationcontrollers.EncryptionKeyRotationController
-	getFn func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error)
-}
-
-func (f *fakeEncryptionKeyRotationController) Get(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error) {
-	if f.getFn == nil {
-		return nil, nil
+		Op:        op,
+		OwnerKey:  plan.ControllerOwnerKey(op, ControllerOwnerKey),
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
 	}
-	return f.getFn(namespace, name, opts)
-}
-
-type fakeBeaconClient struct {
-	plancontrollers.BeaconClient
-	updateCalls     int
-	updates         []*planv1alpha1.Beacon
-	statusUpdates   []*planv1alpha1.Beacon
-	updateErr       error
-	updateStatusErr error
-}
-
-func (f *fakeBeaconClient) Update(beacon *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
-	f.updateCalls++
-	f.updates = append(f.updates, beacon.DeepCopy())
-	return beacon, f.updateErr
-}
-
-func (f *fakeBeaconClient) UpdateStatus(beacon *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
-	f.statusUpdates = append(f.statusUpdates, beacon.DeepCopy())
-	return beacon, f.updateStatusErr
 }
 
 func newPeriodicStatusSecret(secretName, stdout string) *corev1.Secret {
@@ -293,6 +225,58 @@ func TestConvergenceWaitMessage(t *testing.T) {
 	}
 }
 
+func TestRollbackWaitMessage(t *testing.T) {
+	tests := []struct {
+		name             string
+		stdout           string
+		requireHashMatch bool
+		wantWait         bool
+		wantErr          bool
+	}{
+		{
+			name:   "returns success at any stage",
+			stdout: "Current Rotation Stage: start\nServer Encryption Hashes: hash mismatch",
+		},
+		{
+			name:             "returns wait while hashes differ on the final node",
+			stdout:           "Current Rotation Stage: start\nServer Encryption Hashes: hash mismatch",
+			requireHashMatch: true,
+			wantWait:         true,
+		},
+		{
+			name:             "returns success with hash match on the final node",
+			stdout:           "Current Rotation Stage: start\nServer Encryption Hashes: All hashes match",
+			requireHashMatch: true,
+		},
+		{
+			name:    "returns error for malformed status output",
+			stdout:  "Server Encryption Hashes: All hashes match",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waitMsg, err := rollbackWaitMessage(newPeriodicStatusSecret("node", tt.stdout), tt.requireHashMatch)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantWait && waitMsg == "" {
+				t.Fatalf("expected wait message, got empty")
+			}
+			if !tt.wantWait && waitMsg != "" {
+				t.Fatalf("expected no wait message, got: %s", waitMsg)
+			}
+		})
+	}
+}
+
 func TestReadRotateKeysResult(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -437,463 +421,221 @@ func TestStatusFromOutput(t *testing.T) {
 	}
 }
 
-func TestUpdateStatusByPhase(t *testing.T) {
+// newBackedUpStatus returns the status of an operation that backed up the encryption config in
+// Preflight and entered step at started.
+func newBackedUpStatus(step opv1alpha1.EncryptionKeyRotationStep, started time.Time) opv1alpha1.EncryptionKeyRotationStatus {
+	status := opv1alpha1.EncryptionKeyRotationStatus{Step: step}
+	status.Phase = opv1alpha1.OperationPhaseInProgress
+	status.RecordStep(string(opv1alpha1.EncryptionKeyRotationStepPreflight), metav1.NewTime(started.Add(-time.Minute)))
+	status.RecordStep(string(step), metav1.NewTime(started))
+	status.LastUpdated = metav1.NewTime(started)
+	return status
+}
+
+func TestReencryptionNotStarted(t *testing.T) {
 	tests := []struct {
-		name  string
-		phase opv1alpha1.OperationPhase
-		check func(t *testing.T, status opv1alpha1.EncryptionKeyRotationStatus)
+		name   string
+		stdout string
+		want   bool
 	}{
 		{
-			name:  "pending sets Pending=true",
-			phase: opv1alpha1.OperationPhasePending,
-			check: func(t *testing.T, s opv1alpha1.EncryptionKeyRotationStatus) {
-				if string(opv1alpha1.PendingCondition.GetStatus(&s)) != "True" {
-					t.Fatalf("expected PendingCondition=True")
-				}
-			},
+			name:   "not started at start stage with matching hashes",
+			stdout: "Current Rotation Stage: start\nServer Encryption Hashes: All hashes match",
+			want:   true,
 		},
 		{
-			name:  "in-progress clears pending with in-progress reason",
-			phase: opv1alpha1.OperationPhaseInProgress,
-			check: func(t *testing.T, s opv1alpha1.EncryptionKeyRotationStatus) {
-				if string(opv1alpha1.PendingCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected PendingCondition=False")
-				}
-				if opv1alpha1.PendingCondition.GetReason(&s) != opv1alpha1.InProgressReason {
-					t.Fatalf("expected PendingCondition reason %q, got %q", opv1alpha1.InProgressReason, opv1alpha1.PendingCondition.GetReason(&s))
-				}
-			},
+			name:   "not started after an earlier rotation with matching hashes",
+			stdout: "Current Rotation Stage: reencrypt_finished\nServer Encryption Hashes: All hashes match",
+			want:   true,
 		},
 		{
-			name:  "succeeded clears pending in-progress and failed",
-			phase: opv1alpha1.OperationPhaseSucceeded,
-			check: func(t *testing.T, s opv1alpha1.EncryptionKeyRotationStatus) {
-				if string(opv1alpha1.PendingCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected PendingCondition=False")
-				}
-				if string(opv1alpha1.InProgressCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected InProgressCondition=False")
-				}
-				if string(opv1alpha1.FailedCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected FailedCondition=False")
-				}
-				if opv1alpha1.FailedCondition.GetReason(&s) != opv1alpha1.NotFailedReason {
-					t.Fatalf("expected FailedCondition reason %q, got %q", opv1alpha1.NotFailedReason, opv1alpha1.FailedCondition.GetReason(&s))
-				}
-			},
+			name:   "started at reencrypt request",
+			stdout: "Current Rotation Stage: reencrypt_request\nServer Encryption Hashes: All hashes match",
 		},
 		{
-			name:  "failed clears pending in-progress and succeeded",
-			phase: opv1alpha1.OperationPhaseFailed,
-			check: func(t *testing.T, s opv1alpha1.EncryptionKeyRotationStatus) {
-				if string(opv1alpha1.PendingCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected PendingCondition=False")
-				}
-				if string(opv1alpha1.InProgressCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected InProgressCondition=False")
-				}
-				if string(opv1alpha1.SucceededCondition.GetStatus(&s)) != "False" {
-					t.Fatalf("expected SucceededCondition=False")
-				}
-				if opv1alpha1.SucceededCondition.GetReason(&s) != opv1alpha1.NotSuccessfulReason {
-					t.Fatalf("expected SucceededCondition reason %q, got %q", opv1alpha1.NotSuccessfulReason, opv1alpha1.SucceededCondition.GetReason(&s))
-				}
-			},
+			name:   "started at reencrypt active",
+			stdout: "Current Rotation Stage: reencrypt_active\nServer Encryption Hashes: All hashes match",
+		},
+		{
+			name:   "started while hashes differ",
+			stdout: "Current Rotation Stage: reencrypt_finished\nServer Encryption Hashes: hash mismatch",
+		},
+		{
+			name:   "unknown without hashes",
+			stdout: "Current Rotation Stage: start",
+		},
+		{
+			name:   "unknown for malformed status output",
+			stdout: "Server Encryption Hashes: All hashes match",
 		},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			op := newOp()
-			op.Generation = 42
-
-			status := updateStatus(op, opv1alpha1.EncryptionKeyRotationStatus{
-				OperationStatus: opv1alpha1.OperationStatus{Phase: tt.phase},
-			})
-			if status.ObservedGeneration != 42 {
-				t.Fatalf("expected ObservedGeneration=42, got %d", status.ObservedGeneration)
+			if got := reencryptionNotStarted(newPeriodicStatusSecret("leader-node", tt.stdout)); got != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, got)
 			}
-			tt.check(t, status)
 		})
 	}
 }
 
-func TestHandleFailed_HoldingBeaconReleasesAndUnpauses(t *testing.T) {
-	op := newOp()
-	adapter := &stubAdapter{waitForRegisterOK: true}
-	beacons := &fakeBeaconClient{}
+func TestFail(t *testing.T) {
+	t.Run("starts rollback after a backed up step failed", func(t *testing.T) {
+		op := newOp()
+		op.Spec.Rollback = true
+		status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRotate, time.Now())
 
-	h := &handler{beacons: beacons}
-	s := newScope(op, newBeacon(beaconOwnerKey(op), true), adapter)
+		fail(newScope(op, nil), &status, opv1alpha1.PlanFailedReason, "restart failed for node-1")
 
-	_, err := h.handleFailed(s, opv1alpha1.EncryptionKeyRotationStatus{})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+		if status.Phase != opv1alpha1.OperationPhaseInProgress {
+			t.Fatalf("expected phase in progress, got %q", status.Phase)
+		}
+		if status.Step != opv1alpha1.EncryptionKeyRotationStepRollback {
+			t.Fatalf("expected step %q, got %q", opv1alpha1.EncryptionKeyRotationStepRollback, status.Step)
+		}
+		if status.RollbackCause != "restart failed for node-1" {
+			t.Fatalf("expected rollback cause to be recorded, got %q", status.RollbackCause)
+		}
+		if opv1alpha1.InProgressCondition.GetReason(&status) != opv1alpha1.RollingBackReason {
+			t.Fatalf("expected in progress reason %q, got %q", opv1alpha1.RollingBackReason, opv1alpha1.InProgressCondition.GetReason(&status))
+		}
+	})
 
-	if len(adapter.pauseCalls) != 1 || adapter.pauseCalls[0] {
-		t.Fatalf("expected PauseCluster(false), got %+v", adapter.pauseCalls)
-	}
-	// ReleaseBeacon writes to UpdateStatus (Status.Owner is the source of truth for beacon
-	// ownership); the legacy main-resource Update is no longer used.
-	if len(beacons.statusUpdates) != 1 {
-		t.Fatalf("expected one beacon status update (ReleaseBeacon), got %d", len(beacons.statusUpdates))
-	}
-	if beacons.statusUpdates[0].Status.Owner != "" {
-		t.Fatalf("expected Status.Owner to be cleared on release, got %q", beacons.statusUpdates[0].Status.Owner)
-	}
-	if len(beacons.updates) != 0 {
-		t.Fatalf("expected no main-resource updates from ReleaseBeacon, got %d", len(beacons.updates))
-	}
-}
+	t.Run("fails once re-encryption started", func(t *testing.T) {
+		op := newOp()
+		op.Spec.Rollback = true
+		status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRotate, time.Now())
+		status.ReencryptionStarted = true
 
-func TestHandleSucceeded_HoldingBeaconTogglesReleasesAndEnqueues(t *testing.T) {
-	op := newOp()
-	adapter := &stubAdapter{waitForRegisterOK: true}
-	beacons := &fakeBeaconClient{}
-	dynamic := &fakeDynamic{}
+		fail(newScope(op, nil), &status, opv1alpha1.PlanFailedReason, "rotate failed")
 
-	h := &handler{
-		beacons: beacons,
-		dynamic: dynamic,
-	}
-	s := newScope(op, newBeacon(beaconOwnerKey(op), true), adapter)
+		if status.Phase != opv1alpha1.OperationPhaseFailed {
+			t.Fatalf("expected phase failed, got %q", status.Phase)
+		}
+	})
 
-	_, err := h.handleSucceeded(s, opv1alpha1.EncryptionKeyRotationStatus{})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+	t.Run("fails in the Restart step", func(t *testing.T) {
+		op := newOp()
+		op.Spec.Rollback = true
+		status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRestart, time.Now())
+		status.ReencryptionStarted = true
+
+		fail(newScope(op, nil), &status, opv1alpha1.PlanFailedReason, "restart failed for node-1")
+
+		if status.Phase != opv1alpha1.OperationPhaseFailed {
+			t.Fatalf("expected phase failed, got %q", status.Phase)
+		}
+		if status.Step == opv1alpha1.EncryptionKeyRotationStepRollback {
+			t.Fatalf("expected no rollback after secrets were re-encrypted")
+		}
+		if msg := opv1alpha1.FailedCondition.GetMessage(&status); !strings.Contains(msg, "not rolled back") {
+			t.Fatalf("expected message to say the failure was not rolled back, got %q", msg)
+		}
+	})
 
-	if len(adapter.pauseCalls) != 1 || adapter.pauseCalls[0] {
-		t.Fatalf("expected PauseCluster(false), got %+v", adapter.pauseCalls)
-	}
-	// ReleaseBeacon on the owner path clears Active + Owner + Delegates in a single
-	// UpdateStatus call — no separate ToggleBeacon is needed.
-	if len(beacons.statusUpdates) != 1 {
-		t.Fatalf("expected one beacon status update (release), got %d", len(beacons.statusUpdates))
-	}
-	if beacons.statusUpdates[0].Status.Active {
-		t.Fatalf("expected beacon to be toggled inactive on release")
-	}
-	if beacons.statusUpdates[0].Status.Owner != "" {
-		t.Fatalf("expected Status.Owner to be cleared on release, got %q", beacons.statusUpdates[0].Status.Owner)
-	}
-	if len(beacons.updates) != 0 {
-		t.Fatalf("expected no main-resource updates from ReleaseBeacon, got %d", len(beacons.updates))
-	}
+	t.Run("fails without rollback enabled", func(t *testing.T) {
+		status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRotate, time.Now())
 
-	if len(dynamic.enqueueCalls) != 1 {
-		t.Fatalf("expected one cluster enqueue, got %d", len(dynamic.enqueueCalls))
-	}
-	expectedGVK := schema.FromAPIVersionAndKind("provisioning.cattle.io/v1", "Cluster")
-	if dynamic.enqueueCalls[0].gvk != expectedGVK || dynamic.enqueueCalls[0].namespace != "fleet-default" || dynamic.enqueueCalls[0].name != "test" {
-		t.Fatalf("unexpected enqueue call: %#v", dynamic.enqueueCalls[0])
-	}
-}
+		fail(newScope(newOp(), nil), &status, opv1alpha1.PlanFailedReason, "rotate failed")
 
-func TestHandleSucceeded_NotHoldingOnlyUnpauses(t *testing.T) {
-	op := newOp()
-	adapter := &stubAdapter{waitForRegisterOK: true}
-	beacons := &fakeBeaconClient{}
-	dynamic := &fakeDynamic{}
+		if status.Phase != opv1alpha1.OperationPhaseFailed {
+			t.Fatalf("expected phase failed, got %q", status.Phase)
+		}
+	})
 
-	h := &handler{
-		beacons: beacons,
-		dynamic: dynamic,
-	}
-	s := newScope(op, newBeacon("other-controller", true), adapter)
+	t.Run("fails without backup", func(t *testing.T) {
+		op := newOp()
+		op.Spec.Rollback = true
+		status := opv1alpha1.EncryptionKeyRotationStatus{Step: opv1alpha1.EncryptionKeyRotationStepRotate}
+		status.RecordStep(string(opv1alpha1.EncryptionKeyRotationStepRotate), metav1.Now())
 
-	_, err := h.handleSucceeded(s, opv1alpha1.EncryptionKeyRotationStatus{})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+		fail(newScope(op, nil), &status, opv1alpha1.PlanFailedReason, "rotate failed")
 
-	if len(adapter.pauseCalls) != 1 || adapter.pauseCalls[0] {
-		t.Fatalf("expected PauseCluster(false), got %+v", adapter.pauseCalls)
-	}
-	if len(beacons.statusUpdates) != 0 {
-		t.Fatalf("expected no beacon status updates, got %d", len(beacons.statusUpdates))
-	}
-	if len(beacons.updates) != 0 {
-		t.Fatalf("expected no beacon release updates, got %d", len(beacons.updates))
-	}
-	if len(dynamic.enqueueCalls) != 0 {
-		t.Fatalf("expected no enqueue calls, got %d", len(dynamic.enqueueCalls))
-	}
+		if status.Phase != opv1alpha1.OperationPhaseFailed {
+			t.Fatalf("expected phase failed, got %q", status.Phase)
+		}
+	})
+
+	t.Run("fails when the rollback fails", func(t *testing.T) {
+		op := newOp()
+		op.Spec.Rollback = true
+		status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRollback, time.Now())
+		status.RollbackCause = "restart failed for node-1"
+
+		fail(newScope(op, nil), &status, opv1alpha1.PlanFailedReason, "restart failed for node-2")
+
+		if status.Phase != opv1alpha1.OperationPhaseFailed {
+			t.Fatalf("expected phase failed, got %q", status.Phase)
+		}
+		msg := opv1alpha1.FailedCondition.GetMessage(&status)
+		if !strings.Contains(msg, "node-1") || !strings.Contains(msg, "node-2") {
+			t.Fatalf("expected message to carry the rollback failure and its cause, got %q", msg)
+		}
+	})
 }
 
-func TestHandleInProgress_BeaconLost(t *testing.T) {
+func TestDefinition_TimedOutStepRollsBack(t *testing.T) {
 	op := newOp()
-	op.Status.Step = opv1alpha1.EncryptionKeyRotationStepRotate
+	op.Spec.Rollback = true
+	status := newBackedUpStatus(opv1alpha1.EncryptionKeyRotationStepRotate, time.Now().Add(-2*time.Hour))
 
-	h := &handler{beacons: &fakeBeaconClient{}}
-	s := newScope(op, newBeacon("other-controller", true), &stubAdapter{waitForRegisterOK: true})
+	(&handler{}).definition().StepFailed(newScope(op, nil), &status, opv1alpha1.StepTimedOutReason, "step Rotate did not complete within 1h0m0s")
 
-	got, err := h.handleInProgress(s, opv1alpha1.EncryptionKeyRotationStatus{})
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if status.Phase != opv1alpha1.OperationPhaseInProgress || status.Step != opv1alpha1.EncryptionKeyRotationStepRollback {
+		t.Fatalf("expected rollback to start, got phase %q step %q", status.Phase, status.Step)
 	}
-	if got.Phase != opv1alpha1.OperationPhaseFailed {
-		t.Fatalf("expected phase failed, got %q", got.Phase)
+	if !strings.Contains(status.RollbackCause, "did not complete within") {
+		t.Fatalf("expected the timeout to be the rollback cause, got %q", status.RollbackCause)
 	}
-	if opv1alpha1.FailedCondition.GetReason(&got) != opv1alpha1.BeaconLostReason {
-		t.Fatalf("expected failed reason %q, got %q", opv1alpha1.BeaconLostReason, opv1alpha1.FailedCondition.GetReason(&got))
+	if opv1alpha1.FailedCondition.IsTrue(&status) {
+		t.Fatalf("expected the failed condition to stay unset while rolling back")
 	}
 }
 
-func TestHandleInProgress_UnknownStep(t *testing.T) {
-	op := newOp()
-	op.Status.Step = "mystery-step"
-	beacons := &fakeBeaconClient{}
-	h := &handler{beacons: beacons}
+func TestDefinition_StepTimeouts(t *testing.T) {
+	for _, step := range (&handler{}).definition().Steps {
+		if step.Timeout <= 0 {
+			t.Fatalf("expected a default timeout for step %q", step.Name)
+		}
+	}
+}
+
+func TestRollbackPlan(t *testing.T) {
+	s := newScope(newOp(), &stubAdapter{})
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	config := "/var/lib/rancher/rke2/server/cred/encryption-config.json"
 
-	s := newScope(op, newBeacon(beaconOwnerKey(op), false), nil)
-	got, err := h.handleInProgress(s, opv1alpha1.EncryptionKeyRotationStatus{Step: "mystery-step"})
+	backup := backupPlan(s, secret, nil)
+	if len(backup.OneTimeInstructions) != 1 || !strings.Contains(backup.OneTimeInstructions[0].Args[1], "cp -p "+config+" "+config+encryptionConfigBackupSuffix) {
+		t.Fatalf("expected backup plan to copy the encryption config, got %+v", backup.OneTimeInstructions)
+	}
+
+	rollback, err := rollbackPlan(s, secret, nil, "rke2-server", "rke2", false)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if got.Phase != opv1alpha1.OperationPhaseFailed {
-		t.Fatalf("expected phase failed, got %q", got.Phase)
+	if len(rollback.OneTimeInstructions) < 2 {
+		t.Fatalf("expected restore and restart instructions, got %+v", rollback.OneTimeInstructions)
 	}
-	if opv1alpha1.FailedCondition.GetReason(&got) != opv1alpha1.UnknownStepReason {
-		t.Fatalf("expected failed reason %q, got %q", opv1alpha1.UnknownStepReason, opv1alpha1.FailedCondition.GetReason(&got))
+	if rollback.OneTimeInstructions[0].Name != restoreInstructionName ||
+		!strings.Contains(rollback.OneTimeInstructions[0].Args[1], "cp -p "+config+encryptionConfigBackupSuffix+" "+config) {
+		t.Fatalf("expected the encryption config to be restored first, got %+v", rollback.OneTimeInstructions[0])
 	}
-	if len(beacons.statusUpdates) != 1 {
-		t.Fatalf("expected one beacon status update before step handling, got %d", len(beacons.statusUpdates))
+	if rollback.OneTimeInstructions[1].Name != "restart" {
+		t.Fatalf("expected the server to be restarted after the restore, got %q", rollback.OneTimeInstructions[1].Name)
 	}
-	if !beacons.statusUpdates[0].Status.Active {
-		t.Fatalf("expected beacon to be toggled active while operation is in progress")
+	for _, instruction := range rollback.OneTimeInstructions {
+		if instruction.Name == verifySecretsInstructionName {
+			t.Fatalf("expected only the last node to verify the secrets")
+		}
 	}
-}
 
-func TestReclaimStaleBeaconOwnerIfNeeded(t *testing.T) {
-	currentOp := &opv1alpha1.EncryptionKeyRotation{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: "fleet-default",
-			Name:      "ekr-current",
-			UID:       types.UID("current-uid"),
-		},
-	}
-
-	tests := []struct {
-		name             string
-		beacon           *planv1alpha1.Beacon
-		getFn            func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error)
-		wantUpdate       bool
-		wantErr          bool
-		wantOwnerCleared bool
-		wantRefCleared   bool
-	}{
-		{
-			name: "no owner label does not reclaim",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Labels:    map[string]string{},
-				},
-			},
-		},
-		{
-			name: "current op owner key does not reclaim",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: beaconOwnerKey(currentOp),
-				},
-			},
-		},
-		{
-			name: "non matching owner does not reclaim",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "etcd-snapshot-save",
-				},
-			},
-		},
-		{
-			name: "malformed owner ref reclaims beacon",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Annotations: map[string]string{
-						beaconOwnerRefAnnotation: "bad-owner-ref",
-					},
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "encryption-key-rotation-old-owner",
-				},
-			},
-			wantUpdate:       true,
-			wantOwnerCleared: true,
-			wantRefCleared:   true,
-		},
-		{
-			name: "missing owner object reclaims beacon",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Annotations: map[string]string{
-						beaconOwnerRefAnnotation: "fleet-default/ekr-old/old-uid",
-					},
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "encryption-key-rotation-old-owner",
-				},
-			},
-			getFn: func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error) {
-				return nil, apierrors.NewNotFound(schema.GroupResource{Group: "operation.cattle.io", Resource: "encryptionkeyrotations"}, name)
-			},
-			wantUpdate:       true,
-			wantOwnerCleared: true,
-			wantRefCleared:   true,
-		},
-		{
-			name: "uid mismatch reclaims beacon",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Annotations: map[string]string{
-						beaconOwnerRefAnnotation: "fleet-default/ekr-old/old-uid",
-					},
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "encryption-key-rotation-old-owner",
-				},
-			},
-			getFn: func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error) {
-				return &opv1alpha1.EncryptionKeyRotation{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: namespace,
-						Name:      name,
-						UID:       "different-uid",
-					},
-					Status: opv1alpha1.EncryptionKeyRotationStatus{
-						OperationStatus: opv1alpha1.OperationStatus{Phase: opv1alpha1.OperationPhaseInProgress},
-					},
-				}, nil
-			},
-			wantUpdate:       true,
-			wantOwnerCleared: true,
-			wantRefCleared:   true,
-		},
-		{
-			name: "terminal owner reclaims beacon",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Annotations: map[string]string{
-						beaconOwnerRefAnnotation: "fleet-default/ekr-old/old-uid",
-					},
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "encryption-key-rotation-old-owner",
-				},
-			},
-			getFn: func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error) {
-				return &opv1alpha1.EncryptionKeyRotation{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: namespace,
-						Name:      name,
-						UID:       "old-uid",
-					},
-					Status: opv1alpha1.EncryptionKeyRotationStatus{
-						OperationStatus: opv1alpha1.OperationStatus{Phase: opv1alpha1.OperationPhaseSucceeded},
-					},
-				}, nil
-			},
-			wantUpdate:       true,
-			wantOwnerCleared: true,
-			wantRefCleared:   true,
-		},
-		{
-			name: "active matching owner does not reclaim",
-			beacon: &planv1alpha1.Beacon{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "fleet-default",
-					Namespace: "fleet-default",
-					Annotations: map[string]string{
-						beaconOwnerRefAnnotation: "fleet-default/ekr-old/old-uid",
-					},
-				},
-				Status: planv1alpha1.BeaconStatus{
-					Owner: "encryption-key-rotation-old-owner",
-				},
-			},
-			getFn: func(namespace, name string, opts metav1.GetOptions) (*opv1alpha1.EncryptionKeyRotation, error) {
-				return &opv1alpha1.EncryptionKeyRotation{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: namespace,
-						Name:      name,
-						UID:       "old-uid",
-					},
-					Status: opv1alpha1.EncryptionKeyRotationStatus{
-						OperationStatus: opv1alpha1.OperationStatus{Phase: opv1alpha1.OperationPhaseInProgress},
-					},
-				}, nil
-			},
-		},
+	last, err := rollbackPlan(s, secret, nil, "rke2-server", "rke2", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			beaconClient := &fakeBeaconClient{}
-			controller := &fakeEncryptionKeyRotationController{getFn: tt.getFn}
-			h := &handler{
-				beacons:                beaconClient,
-				encryptionkeyrotations: controller,
-			}
-			s := &scope{
-				op:     currentOp,
-				beacon: tt.beacon.DeepCopy(),
-			}
-
-			err := h.reclaimStaleBeaconOwnerIfNeeded(s)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Fatalf("expected error, got nil")
-				}
-				return
-			}
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-
-			if tt.wantUpdate && beaconClient.updateCalls == 0 {
-				t.Fatalf("expected beacon update")
-			}
-			if !tt.wantUpdate && beaconClient.updateCalls > 0 {
-				t.Fatalf("did not expect beacon update")
-			}
-			if !tt.wantUpdate {
-				return
-			}
-
-			owner := s.beacon.Status.Owner
-			if tt.wantOwnerCleared && owner != "" {
-				t.Fatalf("expected owner label cleared, got %q", owner)
-			}
-			if s.beacon.Annotations == nil {
-				if tt.wantRefCleared {
-					return
-				}
-				t.Fatalf("expected annotations map present")
-			}
-			if tt.wantRefCleared && s.beacon.Annotations[beaconOwnerRefAnnotation] != "" {
-				t.Fatalf("expected owner ref annotation cleared")
-			}
-		})
+	verify := last.OneTimeInstructions[len(last.OneTimeInstructions)-1]
+	if verify.Name != verifySecretsInstructionName ||
+		!strings.Contains(verify.Args[1], "/var/lib/rancher/rke2/bin/kubectl --kubeconfig /etc/rancher/rke2/rke2.yaml get secrets -A") {
+		t.Fatalf("expected the last node to list every secret after the restart, got %+v", verify)
 	}
 }
@@ -5,29 +5,23 @@
 	"encoding/base64"
 	"fmt"
 	"path"
-	"reflect"
 	"strings"
 	"time"
 
-	"github.com/rancher/lasso/pkg/dynamic"
 	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/capr"
 	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
 	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	ops "github.com/rancher/rancher/pkg/operations"
 	"github.com/rancher/rancher/pkg/plan"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
-	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
-	"github.com/rancher/wrangler/v3/pkg/generic"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
@@ -150,774 +144,749 @@
 `
 )
 
-type handler struct {
-	etcdsnapshotrestores operationcontrollers.ETCDSnapshotRestoreController
+type (
+	scope = ops.Scope[*opv1alpha1.ETCDSnapshotRestore]
+	step  = ops.Step[*opv1alpha1.ETCDSnapshotRestore, opv1alpha1.ETCDSnapshotRestoreStatus, opv1alpha1.ETCDSnapshotRestoreStep]
+)
 
+// handler holds the step reconcilers of the ETCDSnapshotRestore controller. The shared state
+// machine (beacon ownership, hooks, cancellation and TTL expiry) is driven by an ops.Engine; all
+// fields are populated at Register time and the handler itself is stateless across reconciles.
+type handler struct {
 	etcdsnapshots rkecontrollers.ETCDSnapshotController
 
-	beacons     plancontrollers.BeaconClient
-	beaconCache plancontrollers.BeaconCache
-
-	secrets     corecontrollers.SecretClient
 	secretCache corecontrollers.SecretCache
 
 	store *plan.Store
-
-	dynamic *dynamic.Controller
-
-	clients *wrangler.CAPIContext
 }
 
+// Register wires the ETCDSnapshotRestore controller into the given wrangler context. It must be
+// called exactly once per process; subsequent calls would clobber the registered status handler.
 func Register(ctx context.Context, clients *wrangler.CAPIContext) {
 	h := &handler{
-		etcdsnapshotrestores: clients.Operation.ETCDSnapshotRestore(),
-		etcdsnapshots:        clients.RKE.ETCDSnapshot(),
-		beacons:              clients.Plan.Beacon(),
-		beaconCache:          clients.Plan.Beacon().Cache(),
-		secrets:              clients.Core.Secret(),
-		secretCache:          clients.Core.Secret().Cache(),
-		dynamic:              clients.Dynamic,
-		store:                plan.NewStore(clients.Core.Secret()),
-		clients:              clients,
-	}
-
-	operationcontrollers.RegisterETCDSnapshotRestoreStatusHandler(ctx, clients.Operation.ETCDSnapshotRestore(), "", "etcd-snapshot-restore-handler", h.OnChange)
-}
-
-func (h *handler) OnChange(op *opv1alpha1.ETCDSnapshotRestore, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	status, err := h.onChange(op, status)
-	if err != nil {
-		return status, err
-	}
-	status = updateStatus(op, status)
-
-	if reflect.DeepEqual(op.Status, status) {
-		// handle after normal processing to allow for proper phase-related cleanup (freeing beacon)
-		//
-		// See the equivalent guard in etcdsnapshotsave's OnChange for the rationale: while any
-		// lifecycle-hook label is still on the op, TTL garbage collection must be deferred so the
-		// delegate has a chance to observe the terminal phase and pop itself from the beacon.
-		if ops.IsTerminal(status.Phase) &&
-			ops.IsExpired(&op.Spec.OperationSpec, &status.OperationStatus) &&
-			!planv1alpha1.HasActiveLifecycleHook(op) {
-			err = h.etcdsnapshotrestores.Delete(op.Namespace, op.Name, &metav1.DeleteOptions{})
-			if err != nil {
-				return status, err
-			}
-			return status, generic.ErrSkip
-		}
-
-		h.etcdsnapshotrestores.EnqueueAfter(op.Namespace, op.Name, 5*time.Second)
+		etcdsnapshots: clients.RKE.ETCDSnapshot(),
+		secretCache:   clients.Core.Secret().Cache(),
+		store:         plan.NewStore(clients.Core.Secret()),
 	}
-	return status, nil
+	engine := ops.NewEngine(clients, clients.Operation.ETCDSnapshotRestore(), h.definition())
+
+	operationcontrollers.RegisterETCDSnapshotRestoreStatusHandler(ctx, clients.Operation.ETCDSnapshotRestore(), "", "etcd-snapshot-restore-handler", engine.OnChange)
+	ops.WatchBeaconQueue(ctx, clients.Plan.Beacon(), opv1alpha1.SchemeGroupVersion.WithKind("ETCDSnapshotRestore"),
+		clients.Operation.ETCDSnapshotRestore().Cache(), clients.Operation.ETCDSnapshotRestore(),
+		func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+	ops.RecordOperations(ctx, clients.Operation.ETCDSnapshotRestore(), clients.Operation.OperationRecord(), clients.Operation.OperationRecord().Cache(),
+		opv1alpha1.SchemeGroupVersion.WithKind("ETCDSnapshotRestore"),
+		func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.OperationSpec {
+			return &op.Spec.OperationSpec
+		},
+		func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
 }
 
-func (h *handler) onChange(op *opv1alpha1.ETCDSnapshotRestore, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	if op == nil {
-		return status, nil
+// definition returns the engine definition of the ETCDSnapshotRestore operation: Preflight checks
+// that every etcd node has a server token configured, Shutdown stops the distro on every node,
+// Restore resets etcd from the snapshot on the elected leader, PostRestorePodCleanup starts the
+// leader and deletes the system pods to re-create, InitialRestartCluster restarts every node
+// against the leader, PostRestoreNodeCleanup deletes the nodes without a machine, and
+// RestartCluster restarts every node once more without the leader override. The cluster is never
+// paused.
+func (h *handler) definition() ops.Definition[*opv1alpha1.ETCDSnapshotRestore, opv1alpha1.ETCDSnapshotRestoreStatus, opv1alpha1.ETCDSnapshotRestoreStep] {
+	return ops.Definition[*opv1alpha1.ETCDSnapshotRestore, opv1alpha1.ETCDSnapshotRestoreStatus, opv1alpha1.ETCDSnapshotRestoreStep]{
+		Name:             ControllerOwnerKey,
+		GroupVersionKind: opv1alpha1.SchemeGroupVersion.WithKind("ETCDSnapshotRestore"),
+		Steps: []step{
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepPreflight,
+				HookLabelPrefix: PreflightStepHookLabelPrefix,
+				Reconcile:       h.reconcilePreflight,
+				Timeout:         15 * time.Minute,
+				Preview:         h.previewPreflight,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepShutdown,
+				HookLabelPrefix: ShutdownStepHookLabelPrefix,
+				Reconcile:       h.reconcileShutdown,
+				Timeout:         30 * time.Minute,
+				Preview:         h.previewShutdown,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepRestore,
+				HookLabelPrefix: RestoreStepHookLabelPrefix,
+				Reconcile:       h.reconcileRestore,
+				Timeout:         time.Hour,
+				Preview:         h.previewRestore,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup,
+				HookLabelPrefix: PostRestorePodCleanupStepHookLabelPrefix,
+				Reconcile:       h.reconcilePostRestorePodCleanup,
+				Timeout:         30 * time.Minute,
+				Preview:         h.previewPostRestorePodCleanup,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster,
+				HookLabelPrefix: InitialRestartClusterStepHookLabelPrefix,
+				Reconcile:       h.reconcileInitialRestartCluster,
+				Timeout:         time.Hour,
+				Preview:         h.previewInitialRestartCluster,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup,
+				HookLabelPrefix: PostRestoreNodeCleanupStepHookLabelPrefix,
+				Reconcile:       h.reconcilePostRestoreNodeCleanup,
+				Timeout:         30 * time.Minute,
+				Preview:         h.previewPostRestoreNodeCleanup,
+			},
+			{
+				Name:            opv1alpha1.ETCDSnapshotRestoreStepRestartCluster,
+				HookLabelPrefix: RestartClusterStepHookLabelPrefix,
+				Reconcile:       h.reconcileRestartCluster,
+				Timeout:         time.Hour,
+				Preview:         h.previewRestartCluster,
+			},
+		},
+		Spec:   func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		Status: func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.ETCDSnapshotRestoreStatus { return &op.Status },
+		OperationStatus: func(status *opv1alpha1.ETCDSnapshotRestoreStatus) *opv1alpha1.OperationStatus {
+			return &status.OperationStatus
+		},
+		Step: func(status *opv1alpha1.ETCDSnapshotRestoreStatus) *opv1alpha1.ETCDSnapshotRestoreStep {
+			return &status.Step
+		},
 	}
+}
 
-	if op.DeletionTimestamp != nil {
-		return status, nil
-	}
+// reconcilePreflight checks that every etcd node has a server token configured, as the restored
+// cluster could not be joined again without one.
+func (h *handler) reconcilePreflight(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling preflight", s.Op.Namespace, s.Op.Name)
 
-	if ops.IsPaused(&op.Spec.OperationSpec) {
-		logrus.Debugf("[etcdsnapshotrestore] %s/%s: skipping paused operation", op.Namespace, op.Name)
-		return status, nil
+	secrets, err := h.collectEtcd(s)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
 	}
 
-	if status.Phase == "" {
-		status.SetPhase(opv1alpha1.OperationPhasePending)
-	}
+	concurrency := len(secrets)
+	results := make([]plan.PlanStatus, 0, concurrency)
 
-	gvk := schema.FromAPIVersionAndKind(op.Spec.ClusterRef.APIVersion, op.Spec.ClusterRef.Kind)
-	ref, err := h.dynamic.Get(gvk, op.Spec.ClusterRef.Namespace, op.Spec.ClusterRef.Name)
-	if apierrors.IsNotFound(err) {
-		key := fmt.Sprintf("apiVersion=%s, kind=%s", op.Spec.ClusterRef.APIVersion, op.Spec.ClusterRef.Kind)
-		if op.Spec.ClusterRef.Namespace != "" {
-			key += fmt.Sprintf(", namespace=%s", op.Spec.ClusterRef.Namespace)
+	for _, secret := range secrets {
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, preflightPlan(s, secret), 1, -1)
+		if err != nil {
+			return false, err
 		}
-		key += fmt.Sprintf(", name=%s", op.Spec.ClusterRef.Name)
-		logrus.Errorf("[etcdsnapshotrestore]: %s/%s failed to find cluster for %s", op.Namespace, op.Name, key)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.ClusterNotFoundReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("cluster %s not found", key))
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
 
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-		return status, nil
-	}
-	if err != nil {
-		return status, err
-	}
+		results = append(results, *planStatus)
 
-	ustrMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ref)
-	if err != nil {
-		return status, err
-	}
+		if planStatus.Failure() {
+			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: preflight check failed for %s/%s",
+				s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("could not find server token for %s/%s", secret.Namespace, secret.Name))
+			return false, nil
+		}
 
-	ustr := unstructured.Unstructured{Object: ustrMap}
+		if planStatus.Waiting() {
+			logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for preflight check for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
 
-	a, err := ops.NewAdapter(h.clients, &ustr)
-	if err != nil {
-		return status, err
+			concurrency--
+			if concurrency <= 0 {
+				break
+			}
+		}
 	}
 
-	clusterObj, err := a.ClusterObject()
-	if err != nil {
-		return status, err
+	if concurrency < len(secrets) {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+		return false, nil
 	}
 
-	// Resolve the beacon (and every other cluster-scoped artifact — plan secrets, snapshot CRs)
-	// via the adapter, not the op.Spec.ClusterRef. When the UI creates ops against the mgmt v3
-	// Cluster, ClusterRef points there but the real state lives in the underlying provisioner's
-	// namespace (fleet-default for v2prov, CAPI ns for CAPRKE2). BeaconRef() gives the correct
-	// (namespace, name) for each adapter type.
-	namespace, beaconName := a.BeaconRef()
-
-	beacon, err := h.beacons.Get(namespace, beaconName, metav1.GetOptions{})
-	if apierrors.IsNotFound(err) && status.Phase == opv1alpha1.OperationPhasePending {
-		logrus.Warnf("[etcdsnapshotrestore]: %s/%s failed to find beacon %s/%s (clusterRef apiVersion=%s kind=%s name=%s)",
-			op.Namespace, op.Name, namespace, beaconName, ustr.GetAPIVersion(), ustr.GetKind(), ustr.GetName())
+	return true, nil
+}
 
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForBeaconReason)
-		opv1alpha1.PendingCondition.Message(&status, "waiting for beacon creation")
+// reconcileShutdown stops the distro on every non-Windows node at once, tombstones etcd and
+// removes the tls and cred directories of the server nodes.
+func (h *handler) reconcileShutdown(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling shutdown", s.Op.Namespace, s.Op.Name)
 
-		return status, nil
+	secrets, err := h.collectNonWindows(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return false, err
 	} else if err != nil {
-		return status, err
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
 	}
 
-	s := &scope{
-		ownerKey:   plan.ControllerOwnerKey(op, ControllerOwnerKey),
-		op:         op,
-		beacon:     beacon,
-		namespace:  namespace,
-		clusterObj: clusterObj,
-		adapter:    a,
-	}
+	concurrency := len(secrets)
+	results := make([]plan.PlanStatus, 0, concurrency)
 
-	switch status.Phase {
-	case opv1alpha1.OperationPhasePending:
-		return h.handlePending(s, status)
-	case opv1alpha1.OperationPhaseInProgress:
-		return h.handleInProgress(s, status)
-	case opv1alpha1.OperationPhaseCanceled:
-		return h.handleCanceled(s, status)
-	case opv1alpha1.OperationPhaseFailed:
-		return h.handleFailed(s, status)
-	case opv1alpha1.OperationPhaseSucceeded:
-		return h.handleSucceeded(s, status)
-	}
+	for _, secret := range secrets {
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, shutdownPlan(s, secret), 1, -1)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
 
-	status.SetPhase(opv1alpha1.OperationPhaseFailed)
+		results = append(results, *planStatus)
 
-	opv1alpha1.FailedCondition.True(&status)
-	opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.UnknownPhaseReason)
-	opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("unknown phase [%s]", op.Status.Phase))
+		if planStatus.Failure() {
+			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: shutdown failed for %s/%s",
+				s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("shutdown failed for %s/%s", secret.Namespace, secret.Name))
+			return false, nil
+		}
 
-	return status, nil
-}
+		if planStatus.Waiting() {
+			logrus.Infof("[etcdsnapshotrestore] %s/%s: waiting for shutdown for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
 
-type scope struct {
-	ownerKey string
+			concurrency--
+			if concurrency <= 0 {
+				break
+			}
+		}
+	}
 
-	op        *opv1alpha1.ETCDSnapshotRestore
-	namespace string
+	if concurrency < len(secrets) {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+		return false, nil
+	}
 
-	beacon     *planv1alpha1.Beacon
-	clusterObj *unstructured.Unstructured
-	adapter    ops.Adapter
+	return true, nil
 }
 
-// idempotencyValue returns the value the idempotency tracker hashes to determine whether to re-run
-// a given instruction. The UID of the operation never changes for a single CR, so all instructions
-// associated with the same restore CR run exactly once.
-func (s *scope) idempotencyValue() string {
-	return string(s.op.UID)
-}
+// reconcileRestore elects the etcd leader able to restore the snapshot and resets etcd from the
+// snapshot on it. The later steps find the leader again through its election mark.
+func (h *handler) reconcileRestore(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling etcd restore", s.Op.Namespace, s.Op.Name)
 
-// lifecycleHookDelegate returns (suffix, delegate) for the first label on the operation whose key
-// starts with prefix. Returns ("", "") when no such label is set. The suffix is informational —
-// only the delegate value is consulted to drive the beacon push.
-func (h *handler) lifecycleHookDelegate(s *scope, prefix string) (string, string) {
-	if s.op.Labels == nil {
-		return "", ""
+	filter, snapshot, msg, err := h.restoreTarget(s)
+	if err != nil {
+		return false, err
+	} else if msg != "" {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: %s", s.Op.Namespace, s.Op.Name, msg)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, msg)
+		return false, nil
 	}
-	for k, v := range s.op.Labels {
-		if strings.HasPrefix(k, prefix) {
-			return strings.TrimPrefix(k, prefix), v
-		}
+
+	secret, ok := h.leader(s, status, filter, "restore")
+	if !ok {
+		return false, nil
 	}
-	return "", ""
+	ops.RecordLeader(&status.OperationStatus, secret)
+
+	return h.assign(s, status, secret, restorePlan(s, secret, snapshot), "etcd restore")
 }
 
-// delegate pushes delegate onto the beacon's delegate chain if it is not already there. It is a
-// no-op when the delegate is already present, which keeps the call idempotent across the many
-// reconciles that may occur while a hook is held.
-func (h *handler) delegate(s *scope, name, delegate string) error {
-	logrus.Tracef("[etcdsnapshotrestore] %s/%s: delegating ownership of beacon to %s on behalf of %s", s.op.Namespace, s.op.Name, delegate, name)
+// reconcilePostRestorePodCleanup starts the server unit on the etcd leader and, once the API
+// server responds, deletes the well-known system pods that must be re-created after the restore.
+// The pods are deleted from the leader when it is also a control-plane node, and from the first
+// control-plane node otherwise, which is pointed at the leader first.
+func (h *handler) reconcilePostRestorePodCleanup(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling post-restore pod cleanup", s.Op.Namespace, s.Op.Name)
 
-	if plan.IsInDelegateChain(s.beacon, delegate) {
-		return nil
+	etcdSecret, ok := h.leader(s, status, nil, "restore")
+	if !ok {
+		return false, nil
 	}
 
-	beacon, err := plan.PushDelegate(s.beacon, delegate, h.beacons)
-	if err != nil {
-		return err
-	}
-	s.beacon = beacon
-	return nil
-}
-
-// handleHook is the per-handler entry point for the lifecycle-hook mechanism. It returns (true, nil)
-// whenever a label with the given prefix exists on the operation, signalling the caller to short
-// circuit. To advance past the hook the operator must clear the label AND pop the delegate; either
-// alone is insufficient because:
-//
-//   - Clearing the label but leaving the delegate on the chain lets the beacon's authority logic
-//     still report the delegate as the holder, so the owning controller may not regain its
-//     authority on the next reconcile.
-//   - Popping the delegate but leaving the label present causes this function to re-push the
-//     delegate on the very next reconcile (delegate() is no-op only if already in chain).
-func (h *handler) handleHook(s *scope, prefix string) (bool, error) {
-	logrus.Tracef("[etcdsnapshotrestore] %s/%s: checking lifecycle hook for prefix %q", s.op.Namespace, s.op.Name, prefix)
-
-	if name, delegate := h.lifecycleHookDelegate(s, prefix); delegate != "" {
-		err := h.delegate(s, name, delegate)
-		return true, err
-	}
-	return false, nil
-}
+	controlPlaneSecret := etcdSecret
+	if !ops.IsControlPlane(etcdSecret) {
+		secrets, err := h.collectControlPlane(s)
+		if plan.IsTransient(err) {
+			return false, err
+		} else if err != nil {
+			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+			return false, nil
+		} else if len(secrets) == 0 {
+			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: no control-plane nodes found for post-restore pod cleanup", s.Op.Namespace, s.Op.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, "no control-plane nodes found for post-restore pod cleanup")
+			return false, nil
+		}
+		controlPlaneSecret = secrets[0]
 
-// restartClusterHookPrefix picks between the InitialRestart and Restart prefixes based on which
-// step the operation is currently in. reconcileRestartCluster is reused for both restart phases,
-// so we route the hook lookup with the same step-based dispatch the caller uses.
-func restartClusterHookPrefix(step opv1alpha1.ETCDSnapshotRestoreStep) string {
-	if step == opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster {
-		return InitialRestartClusterStepHookLabelPrefix
+		if done, err := h.assign(s, status, etcdSecret, startServicePlan(s, etcdSecret), "post-restore pod cleanup"); err != nil || !done {
+			return false, err
+		}
 	}
-	return RestartClusterStepHookLabelPrefix
-}
 
-// stepHookPrefixFor returns the step-hook label prefix for the given restore step, or "" for an
-// unknown / empty step. Used by handleInProgress to decide whether beacon-authorization loss is
-// explained by an active step-scoped delegation vs a genuine loss.
-func stepHookPrefixFor(step opv1alpha1.ETCDSnapshotRestoreStep) string {
-	switch step {
-	case opv1alpha1.ETCDSnapshotRestoreStepPreflight:
-		return PreflightStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepShutdown:
-		return ShutdownStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepRestore:
-		return RestoreStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup:
-		return PostRestorePodCleanupStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster:
-		return InitialRestartClusterStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup:
-		return PostRestoreNodeCleanupStepHookLabelPrefix
-	case opv1alpha1.ETCDSnapshotRestoreStepRestartCluster:
-		return RestartClusterStepHookLabelPrefix
-	}
-	return ""
+	return h.assign(s, status, controlPlaneSecret, podCleanupPlan(s, etcdSecret, controlPlaneSecret), "post-restore pod cleanup")
 }
 
-// etcdRestoreScriptPath returns the absolute path on the node where the named etcd-restore script lives.
-func etcdRestoreScriptPath(s *scope, secret *corev1.Secret, name string) string {
-	return path.Join(s.adapter.ProvisioningDataDirectory(secret), etcdRestoreBinSubdir, name)
+// reconcileInitialRestartCluster restarts every non-Windows node one at a time, pointing every node
+// other than the etcd leader at the leader's server URL.
+func (h *handler) reconcileInitialRestartCluster(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	return h.restartCluster(s, status, true)
 }
 
-// nonWindowsSecret returns true for any secret whose cattle.io/os label is not "windows". Imported
-// clusters do not set this label at all; treating absent label as non-Windows keeps the shutdown
-// and restart paths from no-oping on them.
-func nonWindowsSecret(secret *corev1.Secret) bool {
-	return secret != nil && secret.Labels[capr.CattleOSLabel] != capr.WindowsMachineOS
+// reconcileRestartCluster restarts every non-Windows node one at a time once more, removing the
+// override pointing the nodes at the etcd leader, so each node returns to its normal configuration.
+func (h *handler) reconcileRestartCluster(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	return h.restartCluster(s, status, false)
 }
 
-func (h *handler) handlePending(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	// Pending waits until this op is either the primary owner OR anywhere in the delegate chain.
-	// If we're already in the chain, the primary owner is driving the beacon on our behalf — skip
-	// AcquireBeacon entirely and continue with hook + WaitForRegister. Otherwise attempt to acquire;
-	// a nil return means another controller currently owns it and we must keep waiting.
-	if !plan.IsInDelegateChain(s.beacon, s.ownerKey) {
-		acquired, err := plan.AcquireBeacon(s.beacon, h.beacons, s.ownerKey)
-		if err != nil {
-			return status, err
-		}
-		if acquired == nil {
-			opv1alpha1.PendingCondition.True(&status)
-			opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForBeaconReason)
-			opv1alpha1.PendingCondition.Message(&status, "waiting for beacon creation")
-			return status, nil
-		}
-		s.beacon = acquired
-	}
-
-	// The Pending-phase hook fires after the beacon has been acquired so external delegates can
-	// inspect the cluster (machine-plan secrets, beacon ownership) before the controller starts
-	// the actual restore workflow.
-	delegated, err := h.handleHook(s, planv1alpha1.PendingPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.PendingCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
+// restartCluster runs the initial or final restart pass. The two passes use distinct idempotency
+// values; otherwise the final pass would skip the restart as already-reconciled.
+func (h *handler) restartCluster(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus, initial bool) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling cluster restart", s.Op.Namespace, s.Op.Name)
 
-		return status, nil
+	secrets, err := h.collectNonWindows(s).Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
 	}
 
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: acquired beacon, waiting for agents to register", s.op.Namespace, s.op.Name)
+	initSecret, ok := h.leader(s, status, ops.IsEtcd, "restart")
+	if !ok {
+		return false, nil
+	}
 
-	if ok, err := s.adapter.WaitForRegister(); err != nil {
-		return status, err
-	} else if !ok {
-		logrus.Infof("[etcdsnapshotrestore] %s/%s: waiting for system-agents to connect", s.op.Namespace, s.op.Name)
+	serverURL := s.Adapter.GetServerURL(initSecret)
+	value := restartIdempotencyValue(s, initial)
+	results := make([]plan.PlanStatus, 0, len(secrets))
 
-		opv1alpha1.PendingCondition.True(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.WaitingForRegistrationReason)
-		opv1alpha1.PendingCondition.Message(&status, "waiting for system-agents to connect")
+	for _, secret := range secrets {
+		nodePlan, err := restartPlan(s, secret, initSecret, serverURL, value, initial)
+		if err != nil {
+			return false, err
+		}
 
-		return status, nil
-	}
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, 1, -1)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
 
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to shutdown", s.op.Namespace, s.op.Name)
+		results = append(results, *planStatus)
 
-	status.SetPhase(opv1alpha1.OperationPhaseInProgress)
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepPreflight)
+		if planStatus.Failure() {
+			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: restart failed for %s/%s",
+				s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("restart failed for %s/%s", secret.Namespace, secret.Name))
+			return false, nil
+		}
 
-	opv1alpha1.InProgressCondition.True(&status)
-	opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.InProgressReason)
+		if planStatus.Waiting() {
+			logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for restart for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+			ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", status.Step, plan.Message(results)))
+			return false, nil
+		}
+	}
 
-	return status, nil
+	return true, nil
 }
 
-func (h *handler) handleInProgress(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	stepPrefix := stepHookPrefixFor(s.op.Status.Step)
-
-	// Stage 1 (loose): the op must appear SOMEWHERE in the ownership chain (owner or any
-	// delegate). Being absent entirely means the beacon was reassigned to another controller and
-	// we can't recover. If a step hook is currently active on the op, treat the absence as a
-	// step-scoped delegation and surface WaitingForDelegate instead of failing — the delegate may
-	// have popped us in service of the hook and will restore ownership when the hook clears.
-	if !plan.IsOwningBeaconHolder(s.beacon, s.ownerKey) && !plan.IsInDelegateChain(s.beacon, s.ownerKey) {
-		if planv1alpha1.HasStepHookLabel(s.op, stepPrefix) {
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-			opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-			return status, nil
-		}
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
+// reconcilePostRestoreNodeCleanup deletes Node objects from the restored cluster that no longer
+// correspond to a machine still in the cluster. A snapshot taken before a node was removed will
+// re-introduce the stale Node on restore; this step prunes those nodes so they don't block readiness.
+//
+// We assemble the keep-list (node names that should survive) from the currently-present machine-plan
+// secrets, which carry the node name as a label. The cleanup script runs on the init node and deletes
+// any Node not in the keep-list. The step is skipped when there is no leader or keep-list.
+func (h *handler) reconcilePostRestoreNodeCleanup(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus) (bool, error) {
+	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling post-restore node cleanup", s.Op.Namespace, s.Op.Name)
 
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.BeaconLostReason)
-		opv1alpha1.FailedCondition.Message(&status, "beacon reassigned, aborting")
+	initSecret, err := s.Adapter.FindOrElectLeader(s.OwnerKey, ops.IsEtcd)
+	if err != nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
+	} else if initSecret == nil {
+		logrus.Warnf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for node cleanup, skipping", s.Op.Namespace, s.Op.Name)
+		return true, nil
+	}
 
-		return status, nil
+	allSecrets, err := h.collectAll(s)
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return false, nil
 	}
 
-	var err error
-	s.beacon, err = plan.ToggleBeacon(s.beacon, true, h.beacons)
-	if err != nil {
-		return status, err
+	nodePlan, skipReason := buildPostRestoreNodeCleanupPlan(s, initSecret, allSecrets)
+	if skipReason != "" {
+		logrus.Warnf("[etcdsnapshotrestore] %s/%s: %s, skipping node cleanup", s.Op.Namespace, s.Op.Name, skipReason)
+		return true, nil
 	}
 
-	// InProgress-phase hook fires on every InProgress reconcile, ahead of step dispatch. This is
-	// the broadest hook in the restore lifecycle — useful for delegates that need to gate ALL
-	// step work uniformly without subscribing to each individual step prefix.
-	delegated, err := h.handleHook(s, planv1alpha1.InProgressPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Stage 2 (strict): after the InProgress-phase hook has been handled, the op must be the
-	// primary owner or the most-recent delegate on the chain to drive step work. If a step hook
-	// is still active on the op, treat the missing-top state as an intentional delegation and
-	// wait; otherwise this is a genuine beacon loss and we fail.
-	if !plan.AuthorizedForBeacon(s.beacon, s.ownerKey) {
-		if planv1alpha1.HasStepHookLabel(s.op, stepPrefix) {
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-			opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-			return status, nil
-		}
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.BeaconLostReason)
-		opv1alpha1.FailedCondition.Message(&status, "Beacon acquired by another controller, aborting")
-
-		return status, nil
-	}
-
-	switch s.op.Status.Step {
-	case opv1alpha1.ETCDSnapshotRestoreStepPreflight:
-		return h.reconcilePreflight(s, status)
-	case opv1alpha1.ETCDSnapshotRestoreStepShutdown:
-		return h.reconcileShutdown(s, status)
-	case opv1alpha1.ETCDSnapshotRestoreStepRestore:
-		return h.reconcileRestore(s, status)
-	case opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup:
-		return h.reconcilePostRestorePodCleanup(s, status)
-	case opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster:
-		return h.reconcileRestartCluster(s, status, opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup)
-	case opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup:
-		return h.reconcilePostRestoreNodeCleanup(s, status)
-	case opv1alpha1.ETCDSnapshotRestoreStepRestartCluster:
-		return h.reconcileRestartCluster(s, status, "")
-	}
-
-	status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-	opv1alpha1.FailedCondition.True(&status)
-	opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.UnknownStepReason)
-	opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("current step [\"%s\"] is unknown, expected one of: [\"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\", \"%s\"]",
-		status.Step,
-		opv1alpha1.ETCDSnapshotRestoreStepPreflight,
-		opv1alpha1.ETCDSnapshotRestoreStepShutdown,
-		opv1alpha1.ETCDSnapshotRestoreStepRestore,
-		opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup,
-		opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster,
-		opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup,
-		opv1alpha1.ETCDSnapshotRestoreStepRestartCluster))
-
-	return status, nil
+	return h.assign(s, status, initSecret, nodePlan, "post-restore node cleanup")
 }
 
-func (h *handler) reconcilePreflight(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling shutdown", s.op.Namespace, s.op.Name)
+// leader finds the etcd leader elected by the Restore step, or elects one among the secrets
+// matching filter. It fails the operation and returns false when there is no eligible leader for
+// the given action.
+func (h *handler) leader(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus, filter ops.Filter, action string) (*corev1.Secret, bool) {
+	secret, err := s.Adapter.FindOrElectLeader(s.OwnerKey, filter)
+	if err != nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+		return nil, false
+	} else if secret == nil {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for %s", s.Op.Namespace, s.Op.Name, action)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, "no eligible etcd leader for "+action)
+		return nil, false
+	}
+	return secret, true
+}
 
-	delegated, err := h.handleHook(s, PreflightStepHookLabelPrefix)
+// assign assigns nodePlan to a single secret. It fails the operation when the plan fails, and
+// returns true once the plan is applied.
+func (h *handler) assign(s *scope, status *opv1alpha1.ETCDSnapshotRestoreStatus, secret *corev1.Secret, nodePlan *plan.Plan, action string) (bool, error) {
+	planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, 1, -1)
 	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
+		return false, err
 	}
+	ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
 
-	secrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
+	if planStatus.Failure() {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: %s failed for %s/%s",
+			s.Op.Namespace, s.Op.Name, action, secret.Namespace, secret.Name)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("%s failed for %s/%s", action, secret.Namespace, secret.Name))
+		return false, nil
+	}
+
+	if planStatus.Waiting() {
+		logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for %s for %s/%s", s.Op.Namespace, s.Op.Name, action, secret.Namespace, secret.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, plan.Message([]plan.PlanStatus{*planStatus}))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// collectEtcd returns the etcd machine-plan secrets, sorted with plan.DefaultSorter. At least one
+// is required.
+func (h *handler) collectEtcd(s *scope) ([]*corev1.Secret, error) {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
 		WithSorter(plan.DefaultSorter()).
 		WithFilter(ops.IsEtcd).
 		WithValidator(plan.AtLeast(1, "")).
 		Collect()
-	if plan.IsTransient(err) {
-		return status, err
-	} else if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as canceled: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
+}
 
-		status.SetPhase(opv1alpha1.OperationPhaseCanceled)
+// collectNonWindows returns a Collector for the non-Windows machine-plan secrets stopped and
+// restarted by the restore, sorted with plan.DefaultSorter.
+func (h *handler) collectNonWindows(s *scope) *plan.Collector {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithSorter(plan.DefaultSorter()).
+		WithFilter(nonWindowsSecret)
+}
 
-		opv1alpha1.CanceledCondition.True(&status)
-		opv1alpha1.CanceledCondition.Reason(&status, opv1alpha1.PreflightCheckFailedReason)
-		opv1alpha1.CanceledCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
-	}
+// collectControlPlane returns the control-plane machine-plan secrets, sorted with
+// plan.DefaultSorter.
+func (h *handler) collectControlPlane(s *scope) ([]*corev1.Secret, error) {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithLabels(plan.Label(capr.ControlPlaneRoleLabel, "true")).
+		WithSorter(plan.DefaultSorter()).
+		Collect()
+}
 
-	concurrency := len(secrets)
-	results := make([]plan.PlanStatus, 0, concurrency)
+// collectAll returns every machine-plan secret of the cluster, sorted with plan.DefaultSorter.
+func (h *handler) collectAll(s *scope) ([]*corev1.Secret, error) {
+	return plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithSorter(plan.DefaultSorter()).
+		Collect()
+}
+
+// previewPreflight renders the plans of the Preflight step for a dry run.
+func (h *handler) previewPreflight(s *scope, preview *ops.PlanPreview) (string, error) {
+	secrets, err := h.collectEtcd(s)
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
 
 	for _, secret := range secrets {
-		nodePlan := &plan.Plan{
-			OneTimeInstructions: []plan.OneTimeInstruction{
-				{
-					CommonInstruction: plan.CommonInstruction{
-						Name:    "preflight",
-						Command: "/bin/sh",
-						Args: []string{
-							"-c",
-
-							fmt.Sprintf(`grep -rE -q '^[[:space:]]*[\x27\x22 ]?token[\x27\x22 ]?[[:space:]]*:[[:space:]]*[\x27\x22 ]*[^[:space:]\x27\x22]+' %s %s/ 2>/dev/null || (exit 1)`,
-								s.adapter.ConfigFile(secret),
-								s.adapter.ConfigDirectory(secret),
-							),
-						},
-					},
-				},
-			},
-		}
+		preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepPreflight), secret, preflightPlan(s, secret), 1, -1)
+	}
+	return "", nil
+}
 
-		planStatus, err := h.store.AssignPlan(secret, nodePlan, 1, -1)
-		if err != nil {
-			return status, err
-		}
+// previewShutdown renders the plans of the Shutdown step for a dry run.
+func (h *handler) previewShutdown(s *scope, preview *ops.PlanPreview) (string, error) {
+	secrets, err := h.collectNonWindows(s).
+		WithValidator(plan.AtLeast(1, "")).
+		Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
 
-		results = append(results, *planStatus)
+	for _, secret := range secrets {
+		preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepShutdown), secret, shutdownPlan(s, secret), 1, -1)
+	}
+	return "", nil
+}
 
-		if planStatus.Failure() {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: preflight check failed for %s/%s",
-				s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
+// previewRestore renders the plan of the Restore step for a dry run, on the etcd leader the step
+// would elect.
+func (h *handler) previewRestore(s *scope, preview *ops.PlanPreview) (string, error) {
+	leader, snapshot, msg, err := h.previewLeader(s)
+	if err != nil || msg != "" {
+		return msg, err
+	}
 
-			status.SetPhase(opv1alpha1.OperationPhaseCanceled)
+	preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepRestore), leader, restorePlan(s, leader, snapshot), 1, -1)
+	return "", nil
+}
 
-			opv1alpha1.CanceledCondition.True(&status)
-			opv1alpha1.CanceledCondition.Reason(&status, opv1alpha1.PreflightCheckFailedReason)
-			opv1alpha1.CanceledCondition.Message(&status, fmt.Sprintf("could not find server token for %s/%s", secret.Namespace, secret.Name))
+// previewPostRestorePodCleanup renders the plans of the PostRestorePodCleanup step for a dry run.
+func (h *handler) previewPostRestorePodCleanup(s *scope, preview *ops.PlanPreview) (string, error) {
+	leader, _, msg, err := h.previewLeader(s)
+	if err != nil || msg != "" {
+		return msg, err
+	}
 
-			return status, nil
+	controlPlaneSecret := leader
+	if !ops.IsControlPlane(leader) {
+		controlPlanes, err := h.collectControlPlane(s)
+		if plan.IsTransient(err) {
+			return "", err
+		} else if err != nil {
+			return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+		} else if len(controlPlanes) == 0 {
+			return "no control-plane nodes found for post-restore pod cleanup", nil
 		}
+		controlPlaneSecret = controlPlanes[0]
 
-		if planStatus.Waiting() {
-			logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for preflight check for %s/%s", s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-			concurrency--
-			if concurrency <= 0 {
-				break
-			}
-		}
+		preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup), leader, startServicePlan(s, leader), 1, -1)
 	}
+	preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup), controlPlaneSecret, podCleanupPlan(s, leader, controlPlaneSecret), 1, -1)
+	return "", nil
+}
 
-	if concurrency < len(secrets) {
-		msg := plan.Message(results)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting in step %s: %s", status.Step, msg))
+// previewInitialRestartCluster renders the plans of the InitialRestartCluster step for a dry run.
+func (h *handler) previewInitialRestartCluster(s *scope, preview *ops.PlanPreview) (string, error) {
+	return h.previewRestart(s, preview, true)
+}
 
-		return status, nil
+// previewPostRestoreNodeCleanup renders the plan of the PostRestoreNodeCleanup step for a dry run.
+// Nothing is rendered when the step would be skipped.
+func (h *handler) previewPostRestoreNodeCleanup(s *scope, preview *ops.PlanPreview) (string, error) {
+	leader, _, msg, err := h.previewLeader(s)
+	if err != nil || msg != "" {
+		return msg, err
 	}
 
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to shutdown", s.op.Namespace, s.op.Name)
+	allSecrets, err := h.collectAll(s)
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
 
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepShutdown)
-	return status, nil
+	if nodePlan, skipReason := buildPostRestoreNodeCleanupPlan(s, leader, allSecrets); skipReason == "" {
+		preview.Add(string(opv1alpha1.ETCDSnapshotRestoreStepPostRestoreNodeCleanup), leader, nodePlan, 1, -1)
+	}
+	return "", nil
 }
 
-func (h *handler) reconcileShutdown(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling shutdown", s.op.Namespace, s.op.Name)
+// previewRestartCluster renders the plans of the RestartCluster step for a dry run.
+func (h *handler) previewRestartCluster(s *scope, preview *ops.PlanPreview) (string, error) {
+	return h.previewRestart(s, preview, false)
+}
 
-	delegated, err := h.handleHook(s, ShutdownStepHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
+// previewRestart renders the plans of the initial or final restart pass for a dry run.
+func (h *handler) previewRestart(s *scope, preview *ops.PlanPreview, initial bool) (string, error) {
+	leader, _, msg, err := h.previewLeader(s)
+	if err != nil || msg != "" {
+		return msg, err
 	}
 
-	secrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
-		WithSorter(plan.DefaultSorter()).
-		WithFilter(nonWindowsSecret).
-		WithValidator(plan.AtLeast(1, "")).
-		Collect()
+	secrets, err := h.collectNonWindows(s).Collect()
 	if plan.IsTransient(err) {
-		return status, err
+		return "", err
 	} else if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
 
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
+	step := opv1alpha1.ETCDSnapshotRestoreStepRestartCluster
+	if initial {
+		step = opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster
+	}
 
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
+	serverURL := s.Adapter.GetServerURL(leader)
+	value := restartIdempotencyValue(s, initial)
+	for _, secret := range secrets {
+		nodePlan, err := restartPlan(s, secret, leader, serverURL, value, initial)
+		if err != nil {
+			return "", err
+		}
+		preview.Add(string(step), secret, nodePlan, 1, -1)
 	}
+	return "", nil
+}
 
-	concurrency := len(secrets)
-	results := make([]plan.PlanStatus, 0, concurrency)
+// previewLeader returns the etcd leader the Restore step would elect, along with the snapshot to
+// restore, without marking it. The later steps find the same leader, as the real restore marks it.
+// A non-empty message means the restore would fail on the current cluster state.
+func (h *handler) previewLeader(s *scope) (*corev1.Secret, *rkev1.ETCDSnapshot, string, error) {
+	filter, snapshot, msg, err := h.restoreTarget(s)
+	if err != nil || msg != "" {
+		return nil, nil, msg, err
+	}
 
-	for _, secret := range secrets {
-		provisioningDir := s.adapter.ProvisioningDataDirectory(secret)
-		// Clear any prior idempotency tracking under the restore key before starting; subsequent
-		// reconciles see the cleanup already applied and skip it.
-		instructions := []plan.OneTimeInstruction{
-			ops.GenerateIdempotencyCleanupInstruction(provisioningDir, idempotencyKey),
+	leader, err := s.Adapter.PreviewLeader(s.OwnerKey, filter)
+	if err != nil {
+		return nil, nil, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	} else if leader == nil {
+		return nil, nil, "no eligible etcd leader for restore", nil
+	}
+	return leader, snapshot, "", nil
+}
+
+// preflightPlan builds the plan checking that a single etcd node has a server token configured.
+func preflightPlan(s *scope, secret *corev1.Secret) *plan.Plan {
+	return &plan.Plan{
+		OneTimeInstructions: []plan.OneTimeInstruction{
 			{
 				CommonInstruction: plan.CommonInstruction{
-					Name:    "shutdown",
+					Name:    "preflight",
 					Command: "/bin/sh",
-					Env: []string{
-						fmt.Sprintf("%s_DATA_DIR=%s", strings.ToUpper(s.adapter.RuntimeCommand()), s.adapter.DistroDataDirectory(secret)),
-					},
 					Args: []string{
 						"-c",
-						fmt.Sprintf("if [ -z $(command -v %[1]s) ] && [ -z $(command -v %[2]s) ]; then echo %[1]s does not appear to be installed; exit 0; else %[2]s; fi",
-							s.adapter.RuntimeCommand(),
-							s.adapter.RuntimeCommand()+"-killall.sh"),
+						fmt.Sprintf(`grep -rE -q '^[[:space:]]*[\x27\x22 ]?token[\x27\x22 ]?[[:space:]]*:[[:space:]]*[\x27\x22 ]*[^[:space:]\x27\x22]+' %s %s/ 2>/dev/null || (exit 1)`,
+							s.Adapter.ConfigFile(secret),
+							s.Adapter.ConfigDirectory(secret),
+						),
 					},
 				},
 			},
-		}
-
-		if secret.Labels[capr.EtcdRoleLabel] == "true" {
-			instructions = append(instructions, plan.OneTimeInstruction{
-				CommonInstruction: plan.CommonInstruction{
-					Name:    "create-etcd-tombstone",
-					Command: "touch",
-					Args:    []string{path.Join(s.adapter.DistroDataDirectory(secret), "server/db/etcd/tombstone")},
-				},
-			})
-		}
+		},
+	}
+}
 
-		if secret.Labels[capr.EtcdRoleLabel] == "true" || secret.Labels[capr.ControlPlaneRoleLabel] == "true" {
-			instructions = append(instructions,
-				plan.OneTimeInstruction{
-					CommonInstruction: plan.CommonInstruction{
-						Name:    "remove-tls-directory",
-						Command: "rm",
-						Args:    []string{"-rf", path.Join(s.adapter.DistroDataDirectory(secret), "server/tls")},
-					},
+// shutdownPlan builds the plan stopping the distro on a single non-Windows node. Etcd nodes also
+// get a tombstone, and server nodes have their tls and cred directories removed.
+func shutdownPlan(s *scope, secret *corev1.Secret) *plan.Plan {
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(secret)
+	// Clear any prior idempotency tracking under the restore key before starting; subsequent
+	// reconciles see the cleanup already applied and skip it.
+	instructions := []plan.OneTimeInstruction{
+		ops.GenerateIdempotencyCleanupInstruction(provisioningDir, idempotencyKey),
+		{
+			CommonInstruction: plan.CommonInstruction{
+				Name:    "shutdown",
+				Command: "/bin/sh",
+				Env: []string{
+					fmt.Sprintf("%s_DATA_DIR=%s", strings.ToUpper(s.Adapter.RuntimeCommand()), s.Adapter.DistroDataDirectory(secret)),
 				},
-				plan.OneTimeInstruction{
-					CommonInstruction: plan.CommonInstruction{
-						Name:    "remove-cred-directory",
-						Command: "rm",
-						Args:    []string{"-rf", path.Join(s.adapter.DistroDataDirectory(secret), "server/cred")},
-					},
+				Args: []string{
+					"-c",
+					fmt.Sprintf("if [ -z $(command -v %[1]s) ] && [ -z $(command -v %[2]s) ]; then echo %[1]s does not appear to be installed; exit 0; else %[2]s; fi",
+						s.Adapter.RuntimeCommand(),
+						s.Adapter.RuntimeCommand()+"-killall.sh"),
 				},
-			)
-		}
-
-		nodePlan := &plan.Plan{
-			Files:               []plan.File{ops.IdempotentScriptFile(provisioningDir)},
-			OneTimeInstructions: instructions,
-		}
-
-		planStatus, err := h.store.AssignPlan(secret, nodePlan, 1, -1)
-		if err != nil {
-			return status, err
-		}
-
-		results = append(results, *planStatus)
-
-		if planStatus.Failure() {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: shutdown failed for %s/%s",
-				s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("shutdown failed for %s/%s", secret.Namespace, secret.Name))
-
-			return status, nil
-		}
+			},
+		},
+	}
 
-		if planStatus.Waiting() {
-			logrus.Infof("[etcdsnapshotrestore] %s/%s: waiting for shutdown for %s/%s", s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
+	if secret.Labels[capr.EtcdRoleLabel] == "true" {
+		instructions = append(instructions, plan.OneTimeInstruction{
+			CommonInstruction: plan.CommonInstruction{
+				Name:    "create-etcd-tombstone",
+				Command: "touch",
+				Args:    []string{path.Join(s.Adapter.DistroDataDirectory(secret), "server/db/etcd/tombstone")},
+			},
+		})
+	}
 
-			concurrency--
-			if concurrency <= 0 {
-				break
-			}
-		}
+	if secret.Labels[capr.EtcdRoleLabel] == "true" || secret.Labels[capr.ControlPlaneRoleLabel] == "true" {
+		instructions = append(instructions,
+			plan.OneTimeInstruction{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    "remove-tls-directory",
+					Command: "rm",
+					Args:    []string{"-rf", path.Join(s.Adapter.DistroDataDirectory(secret), "server/tls")},
+				},
+			},
+			plan.OneTimeInstruction{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    "remove-cred-directory",
+					Command: "rm",
+					Args:    []string{"-rf", path.Join(s.Adapter.DistroDataDirectory(secret), "server/cred")},
+				},
+			},
+		)
 	}
 
-	if concurrency < len(secrets) {
-		msg := plan.Message(results)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting in step %s: %s", status.Step, msg))
-
-		return status, nil
+	nodePlan := &plan.Plan{
+		Files:               []plan.File{ops.IdempotentScriptFile(provisioningDir)},
+		OneTimeInstructions: instructions,
 	}
 
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to restore", s.op.Namespace, s.op.Name)
-
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepRestore)
-	return status, nil
+	return nodePlan
 }
 
-func (h *handler) reconcileRestore(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling etcd restore", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, RestoreStepHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	snapshotName := s.op.Spec.Args.Name
+// restoreTarget resolves the snapshot to restore and the filter selecting the etcd leader
+// candidates that can restore it. A local snapshot can only be restored on the machine that took
+// it, while S3 snapshots and plain snapshot files can be restored on any etcd node. The snapshot is
+// nil when no etcdsnapshot.rke.cattle.io exists for the snapshot name. A non-empty message means
+// the restore cannot proceed.
+func (h *handler) restoreTarget(s *scope) (ops.Filter, *rkev1.ETCDSnapshot, string, error) {
+	snapshotName := s.Op.Spec.Args.Name
 	if snapshotName == "" {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: snapshot name is required for etcd restore", s.op.Namespace, s.op.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "snapshot name is required for etcd restore")
-
-		return status, nil
+		return nil, nil, "snapshot name is required for etcd restore", nil
 	}
 
-	filter := ops.IsEtcd
-	snapshot, err := h.etcdsnapshots.Get(s.adapter.EtcdSnapshotNamespace(), snapshotName, metav1.GetOptions{})
+	snapshot, err := h.etcdsnapshots.Get(s.Adapter.EtcdSnapshotNamespace(), snapshotName, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
-		logrus.Debugf("[etcdsnapshotrestore] %s/%s: could not find associated etcdsnapshot.rke.cattle.io %s/%s, assuming snapshot file", s.op.Namespace, s.op.Name, s.adapter.EtcdSnapshotNamespace(), snapshotName)
-		snapshot = nil
+		logrus.Debugf("[etcdsnapshotrestore] %s/%s: could not find associated etcdsnapshot.rke.cattle.io %s/%s, assuming snapshot file", s.Op.Namespace, s.Op.Name, s.Adapter.EtcdSnapshotNamespace(), snapshotName)
+		return ops.IsEtcd, nil, "", nil
 	} else if err != nil {
-		return status, err
-	} else if snapshot != nil && snapshot.SnapshotFile.S3 == nil {
-		// Prefer the snapshot's stamped MachineLifecycleNameLabel (used by CAPRKE2 where the
-		// owner ref is a mgmt v3 Node but plan secrets are labelled with the CAPI Machine's
-		// name). Fall back to OwnerReferences[0].Name for v2prov/imported paths that predate the
-		// label.
-		machineName := snapshot.Labels[planv1alpha1.MachineLifecycleNameLabel]
-		if machineName == "" && len(snapshot.OwnerReferences) > 0 {
-			machineName = snapshot.OwnerReferences[0].Name
-		}
-		if machineName == "" {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: cannot correlate machine for snapshot %s/%s (no lifecycle label, no owner reference)", s.op.Namespace, s.op.Name, snapshot.Namespace, snapshot.Name)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, "machine correlation is required for local etcd restore")
-
-			return status, nil
-		}
-
-		filter = func(secret *corev1.Secret) bool {
-			if secret == nil || secret.Labels == nil {
-				return false
-			}
-			return secret.Labels[planv1alpha1.MachineLifecycleNameLabel] == machineName
-		}
+		return nil, nil, "", err
+	} else if snapshot.SnapshotFile.S3 != nil {
+		return ops.IsEtcd, snapshot, "", nil
 	}
 
-	secret, err := s.adapter.FindOrElectLeader(s.ownerKey, filter)
-	if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-
-		return status, nil
-	} else if secret == nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for restore", s.op.Namespace, s.op.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "no eligible etcd leader for restore")
-
-		return status, nil
+	// Prefer the snapshot's stamped MachineLifecycleNameLabel (used by CAPRKE2 where the
+	// owner ref is a mgmt v3 Node but plan secrets are labelled with the CAPI Machine's
+	// name). Fall back to OwnerReferences[0].Name for v2prov/imported paths that predate the
+	// label.
+	machineName := snapshot.Labels[planv1alpha1.MachineLifecycleNameLabel]
+	if machineName == "" && len(snapshot.OwnerReferences) > 0 {
+		machineName = snapshot.OwnerReferences[0].Name
 	}
+	if machineName == "" {
+		logrus.Errorf("[etcdsnapshotrestore] %s/%s: cannot correlate machine for snapshot %s/%s (no lifecycle label, no owner reference)", s.Op.Namespace, s.Op.Name, snapshot.Namespace, snapshot.Name)
+		return nil, nil, "machine correlation is required for local etcd restore", nil
+	}
+
+	return func(secret *corev1.Secret) bool {
+		if secret == nil || secret.Labels == nil {
+			return false
+		}
+		return secret.Labels[planv1alpha1.MachineLifecycleNameLabel] == machineName
+	}, snapshot, "", nil
+}
 
-	provisioningDir := s.adapter.ProvisioningDataDirectory(secret)
-	value := s.idempotencyValue()
+// restorePlan builds the `<runtime> server --cluster-reset` plan restoring the snapshot on the
+// elected etcd leader.
+func restorePlan(s *scope, secret *corev1.Secret, snapshot *rkev1.ETCDSnapshot) *plan.Plan {
+	snapshotName := s.Op.Spec.Args.Name
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(secret)
+	value := s.IdempotencyValue()
 
 	args := []string{
 		"server",
 		"--cluster-reset",
-		fmt.Sprintf("--etcd-arg=advertise-client-urls=https://%s:2379", s.adapter.LoopbackAddress(secret)),
+		fmt.Sprintf("--etcd-arg=advertise-client-urls=https://%s:2379", s.Adapter.LoopbackAddress(secret)),
 		"--etcd-disable-snapshots=false",
 	}
 
@@ -926,7 +895,7 @@ func (h *handler) reconcileRestore(s *scope, status opv1alpha1.ETCDSnapshotResto
 	files := []plan.File{
 		{
 			Content: base64.StdEncoding.EncodeToString([]byte("server: \"\"\n")),
-			Path:    path.Join(s.adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
+			Path:    path.Join(s.Adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
 		},
 		ops.IdempotentScriptFile(provisioningDir),
 	}
@@ -937,7 +906,7 @@ func (h *handler) reconcileRestore(s *scope, status opv1alpha1.ETCDSnapshotResto
 		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=db/snapshots/%s", snapshot.SnapshotFile.Name), "--etcd-s3=false")
 	} else {
 		args = append(args, fmt.Sprintf("--cluster-reset-restore-path=%s", snapshot.SnapshotFile.Name))
-		s3Args, s3Env, s3Files := s.adapter.ToS3ArgsEnvAndFiles(secret)
+		s3Args, s3Env, s3Files := s.Adapter.ToS3ArgsEnvAndFiles(secret)
 		args = append(args, s3Args...)
 		env = append(env, s3Env...)
 		files = append(files, s3Files...)
@@ -950,128 +919,45 @@ func (h *handler) reconcileRestore(s *scope, status opv1alpha1.ETCDSnapshotResto
 				CommonInstruction: plan.CommonInstruction{
 					Name:    "remove-etcd-db-dir",
 					Command: "rm",
-					Args:    []string{"-rf", path.Join(s.adapter.DistroDataDirectory(secret), "server/db/etcd")},
+					Args:    []string{"-rf", path.Join(s.Adapter.DistroDataDirectory(secret), "server/db/etcd")},
 				},
 			}),
-			ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/restore", value, s.adapter.RuntimeCommand(), args, env),
+			ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/restore", value, s.Adapter.RuntimeCommand(), args, env),
 		},
 	}
 
-	planStatus, err := h.store.AssignPlan(secret, nodePlan, 1, -1)
-	if err != nil {
-		return status, err
-	}
-
-	if planStatus.Failure() {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: etcd restore failed for %s/%s",
-			s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("etcd restore failed for %s/%s", secret.Namespace, secret.Name))
-
-		return status, nil
-	}
-
-	if planStatus.Waiting() {
-		logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for etcd restore for %s/%s", s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
-
-		return status, nil
-	}
-
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to post-restore pod cleanup", s.op.Namespace, s.op.Name)
-
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepPostRestorePodCleanup)
-	return status, nil
+	return nodePlan
 }
 
-func (h *handler) reconcilePostRestorePodCleanup(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling post-restore pod cleanup", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, PostRestorePodCleanupStepHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	etcdSecret, err := s.adapter.FindOrElectLeader(s.ownerKey, nil)
-	if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-
-		return status, nil
-	} else if etcdSecret == nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for restore", s.op.Namespace, s.op.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "no eligible etcd leader for restore")
-
-		return status, nil
-	}
-
-	var controlPlaneSecret *corev1.Secret
-
-	if ops.IsControlPlane(etcdSecret) {
-		controlPlaneSecret = etcdSecret
-	} else {
-		secrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
-			WithLabels(plan.Label(capr.ControlPlaneRoleLabel, "true")).
-			WithSorter(plan.DefaultSorter()).
-			Collect()
-		if plan.IsTransient(err) {
-			return status, err
-		} else if err != nil {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-
-			return status, nil
-		} else if len(secrets) == 0 {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-
-			return status, nil
-		}
-
-		controlPlaneSecret = secrets[0]
+// startServicePlan builds the plan starting the server unit on the elected etcd leader when it
+// is not also a control-plane node.
+func startServicePlan(s *scope, etcdSecret *corev1.Secret) *plan.Plan {
+	return &plan.Plan{
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			ops.IdempotentInstruction(
+				s.Adapter.ProvisioningDataDirectory(etcdSecret),
+				idempotencyKey+"/post-restore-start-service",
+				s.IdempotencyValue(),
+				"systemctl",
+				[]string{"start", s.Adapter.ServerUnit()},
+				nil),
+		},
 	}
+}
 
-	kubectl := s.adapter.KubectlPath(etcdSecret)
-	kubeconfig := s.adapter.KubeconfigPath(etcdSecret)
+// podCleanupPlan builds the plan starting the server unit on the control-plane node and deleting
+// the well-known system pods once the API server responds. When the elected etcd leader is not
+// that control-plane node, the plan also points the node at the leader's server URL.
+func podCleanupPlan(s *scope, etcdSecret, controlPlaneSecret *corev1.Secret) *plan.Plan {
+	kubectl := s.Adapter.KubectlPath(etcdSecret)
+	kubeconfig := s.Adapter.KubeconfigPath(etcdSecret)
 
 	podSelectors := []string{
 		"kube-system:k8s-app=kube-dns",
 		"kube-system:k8s-app=kube-dns-autoscaler",
 	}
 
-	if s.adapter.RuntimeCommand() == "rke2" {
+	if s.Adapter.RuntimeCommand() == "rke2" {
 		podSelectors = append(podSelectors,
 			"kube-system:app=rke2-metrics-server",
 			"tigera-operator:k8s-app=tigera-operator",
@@ -1085,8 +971,8 @@ func (h *handler) reconcilePostRestorePodCleanup(s *scope, status opv1alpha1.ETC
 		)
 	}
 
-	provisioningDir := s.adapter.ProvisioningDataDirectory(etcdSecret)
-	value := s.idempotencyValue()
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(etcdSecret)
+	value := s.IdempotencyValue()
 	waitScriptPath := etcdRestoreScriptPath(s, etcdSecret, waitForPodListScriptName)
 
 	instructions := []plan.OneTimeInstruction{
@@ -1095,7 +981,7 @@ func (h *handler) reconcilePostRestorePodCleanup(s *scope, status opv1alpha1.ETC
 			idempotencyKey+"/post-restore-start-service",
 			value,
 			"systemctl",
-			[]string{"start", s.adapter.ServerUnit()},
+			[]string{"start", s.Adapter.ServerUnit()},
 			nil),
 		ops.IdempotentInstruction(
 			provisioningDir,
@@ -1144,270 +1030,88 @@ func (h *handler) reconcilePostRestorePodCleanup(s *scope, status opv1alpha1.ETC
 	}
 
 	if etcdSecret.Name != controlPlaneSecret.Name {
-		etcdNodePlan := &plan.Plan{
-			OneTimeInstructions: []plan.OneTimeInstruction{
-				ops.IdempotentInstruction(
-					provisioningDir,
-					idempotencyKey+"/post-restore-start-service",
-					value,
-					"systemctl",
-					[]string{"start", s.adapter.ServerUnit()},
-					nil),
-			},
-		}
-
-		planStatus, err := h.store.AssignPlan(etcdSecret, etcdNodePlan, 1, -1)
-		if err != nil {
-			return status, err
-		}
-
-		if planStatus.Failure() {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: pod cleanup failed for %s/%s",
-				s.op.Namespace, s.op.Name, etcdSecret.Namespace, etcdSecret.Name)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("post-restore pod cleanup failed for %s/%s", etcdSecret.Namespace, etcdSecret.Name))
-
-			return status, nil
-		}
-
-		if planStatus.Waiting() {
-			logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for pod cleanup for %s/%s", s.op.Namespace, s.op.Name, etcdSecret.Namespace, etcdSecret.Name)
-
-			opv1alpha1.InProgressCondition.True(&status)
-			opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-			opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
-
-			return status, nil
-		}
-
 		nodePlan.Files = append(nodePlan.Files, plan.File{
-			Content: base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("server: \"https://%s:%s\"\n", s.adapter.GetServerURL(etcdSecret), s.adapter.GetSupervisorPort(etcdSecret)))),
-			Path:    path.Join(s.adapter.ConfigDirectory(controlPlaneSecret), "zz_etcd-snapshot-restore.yaml"),
+			Content: base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("server: \"https://%s:%s\"\n", s.Adapter.GetServerURL(etcdSecret), s.Adapter.GetSupervisorPort(etcdSecret)))),
+			Path:    path.Join(s.Adapter.ConfigDirectory(controlPlaneSecret), "zz_etcd-snapshot-restore.yaml"),
 		})
 	}
 
-	planStatus, err := h.store.AssignPlan(controlPlaneSecret, nodePlan, 1, -1)
-	if err != nil {
-		return status, err
-	}
-
-	if planStatus.Failure() {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: pod cleanup failed for %s/%s",
-			s.op.Namespace, s.op.Name, etcdSecret.Namespace, etcdSecret.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("post-restore pod cleanup failed for %s/%s", etcdSecret.Namespace, etcdSecret.Name))
-
-		return status, nil
-	}
-
-	if planStatus.Waiting() {
-		logrus.Infof("[etcdsnapshotrestore] %s/%s: waiting for pod cleanup for %s/%s", s.op.Namespace, s.op.Name, controlPlaneSecret.Namespace, controlPlaneSecret.Name)
-
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
+	return nodePlan
+}
 
-		return status, nil
+// restartIdempotencyValue returns the idempotency value of the initial or final restart pass.
+func restartIdempotencyValue(s *scope, initial bool) string {
+	if initial {
+		return s.IdempotencyValue() + "/initial"
 	}
-
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to initial restart", s.op.Namespace, s.op.Name)
-
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepInitialRestartCluster)
-	return status, nil
+	return s.IdempotencyValue() + "/final"
 }
 
-func (h *handler) reconcileRestartCluster(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus, nextStep opv1alpha1.ETCDSnapshotRestoreStep) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling cluster restart", s.op.Namespace, s.op.Name)
+// restartPlan builds the plan restarting the distro on a single node. During the initial restart
+// every node other than the init node is pointed at serverURL; the final restart removes that
+// override again.
+func restartPlan(s *scope, secret, initSecret *corev1.Secret, serverURL, value string, initial bool) (*plan.Plan, error) {
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(secret)
 
-	// The same reconcile function backs both InitialRestartCluster (nextStep set) and the final
-	// RestartCluster (nextStep == ""). Route the hook check to the matching prefix so a delegate
-	// can subscribe to one restart pass without gating the other.
-	delegated, err := h.handleHook(s, restartClusterHookPrefix(s.op.Status.Step))
+	probes, err := s.Adapter.RenderProbes(secret, false)
 	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	secrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
-		WithFilter(nonWindowsSecret).
-		WithSorter(plan.DefaultSorter()).
-		Collect()
-	if plan.IsTransient(err) {
-		return status, err
-	} else if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
+		return nil, err
 	}
 
-	// The two restart phases must use distinct values; otherwise the second phase would skip the
-	// restart as already-reconciled.
-	value := s.idempotencyValue()
-	if nextStep != "" {
-		value = value + "/initial"
-	} else {
-		value = value + "/final"
+	unit := s.Adapter.ServerUnit()
+	if secret.Labels[capr.EtcdRoleLabel] != "true" && secret.Labels[capr.ControlPlaneRoleLabel] != "true" {
+		unit = s.Adapter.AgentUnit()
 	}
 
-	initSecret, err := s.adapter.FindOrElectLeader(s.ownerKey, ops.IsEtcd)
-	if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
-	} else if initSecret == nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for restart", s.op.Namespace, s.op.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "no eligible etcd leader for restart")
-
-		return status, nil
+	nodePlan := &plan.Plan{
+		Files: []plan.File{ops.IdempotentScriptFile(provisioningDir)},
+		OneTimeInstructions: []plan.OneTimeInstruction{
+			ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/restart", value, "systemctl",
+				[]string{"restart", unit}, nil),
+		},
+		Probes: probes,
 	}
 
-	serverURL := s.adapter.GetServerURL(initSecret)
-
-	concurrency := 1
-	results := make([]plan.PlanStatus, 0, concurrency)
-
-	for _, secret := range secrets {
-		provisioningDir := s.adapter.ProvisioningDataDirectory(secret)
-
-		probes, err := s.adapter.RenderProbes(secret, false)
-		if err != nil {
-			return status, err
-		}
-
-		unit := s.adapter.ServerUnit()
-		if secret.Labels[capr.EtcdRoleLabel] != "true" && secret.Labels[capr.ControlPlaneRoleLabel] != "true" {
-			unit = s.adapter.RuntimeCommand() + "-agent"
-		}
-
-		nodePlan := &plan.Plan{
-			Files: []plan.File{ops.IdempotentScriptFile(provisioningDir)},
-			OneTimeInstructions: []plan.OneTimeInstruction{
-				ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/restart", value, "systemctl",
-					[]string{"restart", unit}, nil),
-			},
-			Probes: probes,
-		}
-
-		if secret.UID != initSecret.UID {
-			if nextStep != "" {
-				nodePlan.Files = append(nodePlan.Files, plan.File{
-					Content: base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("server: \"https://%s:%s\"\n", serverURL, s.adapter.GetSupervisorPort(secret)))),
-					Path:    path.Join(s.adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
-				})
-			} else {
-				nodePlan.OneTimeInstructions = append(nodePlan.OneTimeInstructions, plan.OneTimeInstruction{
-					CommonInstruction: plan.CommonInstruction{
-						Name:    "remove-server-arg",
-						Command: "rm",
-						Args: []string{
-							"-rf", path.Join(s.adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
-						},
-					},
-				})
-			}
+	if secret.UID != initSecret.UID {
+		if initial {
+			nodePlan.Files = append(nodePlan.Files, plan.File{
+				Content: base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("server: \"https://%s:%s\"\n", serverURL, s.Adapter.GetSupervisorPort(secret)))),
+				Path:    path.Join(s.Adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
+			})
 		} else {
-			if nextStep == "" {
-				nodePlan.OneTimeInstructions = append(nodePlan.OneTimeInstructions, plan.OneTimeInstruction{
-					CommonInstruction: plan.CommonInstruction{
-						Name:    "remove-server-arg",
-						Command: "rm",
-						Args: []string{
-							"-rf", path.Join(s.adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
-						},
+			nodePlan.OneTimeInstructions = append(nodePlan.OneTimeInstructions, plan.OneTimeInstruction{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    "remove-server-arg",
+					Command: "rm",
+					Args: []string{
+						"-rf", path.Join(s.Adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
 					},
-				})
-			}
-		}
-
-		planStatus, err := h.store.AssignPlan(secret, nodePlan, 1, -1)
-		if err != nil {
-			return status, err
-		}
-
-		results = append(results, *planStatus)
-
-		if planStatus.Failure() {
-			logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: restart failed for %s/%s",
-				s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-			status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-			opv1alpha1.FailedCondition.True(&status)
-			opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-			opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("restart failed for %s/%s", secret.Namespace, secret.Name))
-
-			return status, nil
+				},
+			})
 		}
-
-		if planStatus.Waiting() {
-			logrus.Debugf("[etcdsnapshotrestore] %s/%s: waiting for restart for %s/%s", s.op.Namespace, s.op.Name, secret.Namespace, secret.Name)
-
-			concurrency--
-			if concurrency <= 0 {
-				break
-			}
+	} else {
+		if !initial {
+			nodePlan.OneTimeInstructions = append(nodePlan.OneTimeInstructions, plan.OneTimeInstruction{
+				CommonInstruction: plan.CommonInstruction{
+					Name:    "remove-server-arg",
+					Command: "rm",
+					Args: []string{
+						"-rf", path.Join(s.Adapter.ConfigDirectory(secret), "zz_etcd-snapshot-restore.yaml"),
+					},
+				},
+			})
 		}
 	}
 
-	if concurrency < 1 {
-		msg := plan.Message(results)
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting in step %s: %s", status.Step, msg))
-
-		return status, nil
-	}
-
-	if nextStep != "" {
-		logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to %s", s.op.Namespace, s.op.Name, nextStep)
-		status.SetStep(nextStep)
-		return status, nil
-	}
-
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: marking as success", s.op.Namespace, s.op.Name)
-
-	status.SetPhase(opv1alpha1.OperationPhaseSucceeded)
-
-	opv1alpha1.SucceededCondition.True(&status)
-	opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.FinishedReason)
-	opv1alpha1.SucceededCondition.Message(&status, "Operation completed successfully")
-
-	return status, nil
+	return nodePlan, nil
 }
 
 // buildPostRestoreNodeCleanupPlan assembles the plan that runs the node-cleanup script on the init
 // node. A non-empty skipReason signals that the caller should skip the cleanup phase entirely (the
 // returned plan is nil in that case).
 func buildPostRestoreNodeCleanupPlan(s *scope, initSecret *corev1.Secret, allSecrets []*corev1.Secret) (*plan.Plan, string) {
-	kubectl := s.adapter.KubectlPath(initSecret)
-	kubeconfig := s.adapter.KubeconfigPath(initSecret)
+	kubectl := s.Adapter.KubectlPath(initSecret)
+	kubeconfig := s.Adapter.KubeconfigPath(initSecret)
 	if kubectl == "" || kubeconfig == "" {
 		return nil, "adapter did not provide kubectl/kubeconfig paths"
 	}
@@ -1425,11 +1129,11 @@ func buildPostRestoreNodeCleanupPlan(s *scope, initSecret *corev1.Secret, allSec
 		return nil, "no node names available from machine-plan secrets"
 	}
 
-	provisioningDir := s.adapter.ProvisioningDataDirectory(initSecret)
-	value := s.idempotencyValue()
+	provisioningDir := s.Adapter.ProvisioningDataDirectory(initSecret)
+	value := s.IdempotencyValue()
 
 	cleanupScriptPath := etcdRestoreScriptPath(s, initSecret, nodeCleanupScriptName)
-	nodeNamesPath := etcdRestoreScriptPath(s, initSecret, fmt.Sprintf("node-names-%s", string(s.op.UID)))
+	nodeNamesPath := etcdRestoreScriptPath(s, initSecret, fmt.Sprintf("node-names-%s", string(s.Op.UID)))
 
 	return &plan.Plan{
 		Files: []plan.File{
@@ -1456,224 +1160,14 @@ func buildPostRestoreNodeCleanupPlan(s *scope, initSecret *corev1.Secret, allSec
 	}, ""
 }
 
-// reconcilePostRestoreNodeCleanup deletes Node objects from the restored cluster that no longer
-// correspond to a machine still in the cluster. A snapshot taken before a node was removed will
-// re-introduce the stale Node on restore; this step prunes those nodes so they don't block readiness.
-//
-// We assemble the keep-list (node names that should survive) from the currently-present machine-plan
-// secrets, which carry the node name as a label. The cleanup script runs on the init node and deletes
-// any Node not in the keep-list.
-func (h *handler) reconcilePostRestoreNodeCleanup(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling post-restore node cleanup", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, PostRestoreNodeCleanupStepHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	initSecret, err := s.adapter.FindOrElectLeader(s.ownerKey, ops.IsEtcd)
-	if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
-	}
-
-	if initSecret == nil {
-		logrus.Warnf("[etcdsnapshotrestore] %s/%s: no eligible etcd leader for node cleanup, skipping", s.op.Namespace, s.op.Name)
-		status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepRestartCluster)
-		return status, nil
-	}
-
-	allSecrets, err := plan.NewCollector(h.secrets, s.clusterObj, s.namespace).
-		WithSorter(plan.DefaultSorter()).
-		Collect()
-	if plan.IsTransient(err) {
-		return status, err
-	} else if err != nil {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.op.Namespace, s.op.Name, err)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
-		return status, nil
-	}
-
-	nodePlan, skipReason := buildPostRestoreNodeCleanupPlan(s, initSecret, allSecrets)
-	if skipReason != "" {
-		logrus.Warnf("[etcdsnapshotrestore] %s/%s: %s, skipping node cleanup", s.op.Namespace, s.op.Name, skipReason)
-		status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepRestartCluster)
-		return status, nil
-	}
-
-	planStatus, err := h.store.AssignPlan(initSecret, nodePlan, 1, -1)
-	if err != nil {
-		return status, err
-	}
-
-	if planStatus.Failure() {
-		logrus.Errorf("[etcdsnapshotrestore] %s/%s: marking operation as failed: node cleanup failed for %s/%s",
-			s.op.Namespace, s.op.Name, initSecret.Namespace, initSecret.Name)
-
-		status.SetPhase(opv1alpha1.OperationPhaseFailed)
-
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.PlanFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("post-restore node cleanup failed for %s/%s", initSecret.Namespace, initSecret.Name))
-
-		return status, nil
-	}
-
-	if planStatus.Waiting() {
-		logrus.Infof("[etcdsnapshotrestore] %s/%s: waiting for node cleanup for %s/%s", s.op.Namespace, s.op.Name, initSecret.Namespace, initSecret.Name)
-
-		opv1alpha1.InProgressCondition.True(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.WaitingForPlanAppliedReason)
-		opv1alpha1.InProgressCondition.Message(&status, plan.Message([]plan.PlanStatus{*planStatus}))
-
-		return status, nil
-	}
-
-	logrus.Infof("[etcdsnapshotrestore] %s/%s: transitioning to final restart", s.op.Namespace, s.op.Name)
-
-	status.SetStep(opv1alpha1.ETCDSnapshotRestoreStepRestartCluster)
-	return status, nil
-}
-
-// handleCanceled is called when an external controller cancels the operation. It runs the
-// Canceled-phase hook first so delegates can react to the cancellation, then releases the beacon
-// if this controller still owns it. Mirrors save's handleCanceled — the cancel-vs-fail
-// distinction is that an external party cancels whereas the operation fails itself.
-func (h *handler) handleCanceled(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling operation canceled", s.op.Namespace, s.op.Name)
-
-	delegated, err := h.handleHook(s, planv1alpha1.CanceledPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.CanceledCondition.True(&status)
-		opv1alpha1.CanceledCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.CanceledCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Owner and mid-chain delegates both go through ReleaseBeacon: it clears the beacon fully
-	// for the owner, or removes the delegate slot from the chain otherwise.
-	if plan.IsOwningBeaconHolder(s.beacon, s.ownerKey) || plan.IsInDelegateChain(s.beacon, s.ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, s.ownerKey); err != nil {
-			return status, err
-		}
-	}
-	return status, nil
-}
-
-func (h *handler) handleFailed(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling operation failed", s.op.Namespace, s.op.Name)
-
-	// Failed-phase hook gates beacon release on the failure path. A delegate that wants to inspect
-	// the failure state (op conditions, plan-secret statuses, leftover scripts on nodes) can hold
-	// the beacon here before the next operation acquires it.
-	delegated, err := h.handleHook(s, planv1alpha1.FailedPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.FailedCondition.True(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.FailedCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Owner performs full teardown via ReleaseBeacon; a mid-chain delegate is removed from the
-	// chain by the same call. Non-participants are no-op.
-	if plan.IsOwningBeaconHolder(s.beacon, s.ownerKey) || plan.IsInDelegateChain(s.beacon, s.ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, s.ownerKey); err != nil {
-			return status, err
-		}
-	}
-
-	return status, nil
-}
-
-func (h *handler) handleSucceeded(s *scope, status opv1alpha1.ETCDSnapshotRestoreStatus) (opv1alpha1.ETCDSnapshotRestoreStatus, error) {
-	logrus.Debugf("[etcdsnapshotrestore] %s/%s: handling operation succeeded", s.op.Namespace, s.op.Name)
-
-	// Succeeded-phase hook gates the beacon release that signals "next operation may acquire".
-	// Delegates use this to chain follow-up work (e.g. snapshotbackpopulate post-restore) before
-	// the cluster goes back to accepting new operations.
-	delegated, err := h.handleHook(s, planv1alpha1.SucceededPhaseHookLabelPrefix)
-	if err != nil {
-		return status, err
-	} else if delegated {
-		opv1alpha1.SucceededCondition.True(&status)
-		opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.WaitingForDelegateReason)
-		opv1alpha1.SucceededCondition.Message(&status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.beacon)))
-		return status, nil
-	}
-
-	// Owner does the full teardown + enqueues the cluster; a mid-chain delegate just removes
-	// itself. Only owner cleanup implies downstream reconciliation, so only owner enqueues.
-	owning := plan.IsOwningBeaconHolder(s.beacon, s.ownerKey)
-	if owning || plan.IsInDelegateChain(s.beacon, s.ownerKey) {
-		if err := plan.ReleaseBeacon(s.beacon, h.beacons, s.ownerKey); err != nil {
-			return status, err
-		}
-	}
-	if owning {
-		// enqueue original object to ensure it is processed by requisite controllers
-		gvk := schema.FromAPIVersionAndKind(s.clusterObj.GetAPIVersion(), s.clusterObj.GetKind())
-		_ = h.dynamic.Enqueue(gvk, s.clusterObj.GetNamespace(), s.clusterObj.GetName())
-	}
-
-	return status, nil
+// etcdRestoreScriptPath returns the absolute path on the node where the named etcd-restore script lives.
+func etcdRestoreScriptPath(s *scope, secret *corev1.Secret, name string) string {
+	return path.Join(s.Adapter.ProvisioningDataDirectory(secret), etcdRestoreBinSubdir, name)
 }
 
-// updateStatus updates the conditions of the operation based on the current status.
-// This function also updates the ObservedGeneration.
-// The handler is responsible for updating the condition relevant to the current phase, but this function updates the
-// remaining conditions.
-func updateStatus(op *opv1alpha1.ETCDSnapshotRestore, status opv1alpha1.ETCDSnapshotRestoreStatus) opv1alpha1.ETCDSnapshotRestoreStatus {
-	logrus.Tracef("[etcdsnapshotrestore] %s/%s: updating conditions", op.Namespace, op.Name)
-
-	status.ObservedGeneration = op.Generation
-
-	if status.Phase == opv1alpha1.OperationPhasePending {
-		opv1alpha1.PendingCondition.True(&status)
-	} else if status.Phase == opv1alpha1.OperationPhaseInProgress {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.InProgressReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation now in progress")
-	} else if status.Phase == opv1alpha1.OperationPhaseSucceeded {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation completed successfully")
-		opv1alpha1.InProgressCondition.False(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "Operation completed successfully")
-		opv1alpha1.FailedCondition.False(&status)
-		opv1alpha1.FailedCondition.Reason(&status, opv1alpha1.NotFailedReason)
-		opv1alpha1.FailedCondition.Message(&status, "Operation completed successfully")
-	} else if status.Phase == opv1alpha1.OperationPhaseFailed {
-		opv1alpha1.PendingCondition.False(&status)
-		opv1alpha1.PendingCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.PendingCondition.Message(&status, "Operation failed")
-		opv1alpha1.InProgressCondition.False(&status)
-		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.FinishedReason)
-		opv1alpha1.InProgressCondition.Message(&status, "Operation failed")
-		opv1alpha1.SucceededCondition.False(&status)
-		opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.NotSuccessfulReason)
-		opv1alpha1.SucceededCondition.Message(&status, "Operation failed")
-	}
-
-	return status
+// nonWindowsSecret returns true for any secret whose cattle.io/os label is not "windows". Imported
+// clusters do not set this label at all; treating absent label as non-Windows keeps the shutdown
+// and restart paths from no-oping on them.
+func nonWindowsSecret(secret *corev1.Secret) bool {
+	return secret != nil && secret.Labels[capr.CattleOSLabel] != capr.WindowsMachineOS
 }
@@ -46,6 +46,8 @@ func (a *stubAdapter) RuntimeCommand() string                            { retur
 func (a *stubAdapter) DistroDataDirectory(_ *corev1.Secret) string       { return a.dataDir }
 func (a *stubAdapter) ProvisioningDataDirectory(_ *corev1.Secret) string { return a.provisioningDir }
 func (a *stubAdapter) ServerUnit() string                                { return a.serverUnit }
+func (a *stubAdapter) AgentUnit() string                                 { return a.runtimeCommand + "-agent" }
+func (a *stubAdapter) InstallerImage(v string) string                    { return "installer:" + v }
 func (a *stubAdapter) RenderProbes(_ *corev1.Secret, _ bool) (map[string]rkeplan.Probe, error) {
 	return map[string]rkeplan.Probe{}, nil
 }
@@ -55,6 +57,10 @@ func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret,
 	return nil, nil
 }
 
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+
 // The six methods below complete the ops.Adapter contract for the stub. They are not exercised
 // by the snapshot-restore controller (which only consumes runtime/dataDir/serverUnit/probes/
 // kubectl+kubeconfig paths/plans), so each returns a static, runtime-appropriate value.
@@ -79,16 +85,16 @@ func newTestScope(adapter *stubAdapter, uid types.UID) *scope {
 	cluster.SetKind("Cluster")
 
 	return &scope{
-		op: &opv1alpha1.ETCDSnapshotRestore{
+		Op: &opv1alpha1.ETCDSnapshotRestore{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "restore-1",
 				Namespace: "fleet-default",
 				UID:       uid,
 			},
 		},
-		namespace:  "fleet-default",
-		clusterObj: cluster,
-		adapter:    adapter,
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
 	}
 }
 
@@ -147,9 +153,9 @@ func TestBuildPostRestoreNodeCleanupPlan(t *testing.T) {
 		t.Fatalf("expected 3 files, got %d", len(plan.Files))
 	}
 
-	wantIdempotentPath := ops.IdempotentActionScriptPath(s.adapter.ProvisioningDataDirectory(initSecret))
-	wantCleanupPath := path.Join(s.adapter.ProvisioningDataDirectory(initSecret), etcdRestoreBinSubdir, nodeCleanupScriptName)
-	wantNodeNamesPath := path.Join(s.adapter.ProvisioningDataDirectory(initSecret), etcdRestoreBinSubdir, fmt.Sprintf("node-names-%s", string(s.op.UID)))
+	wantIdempotentPath := ops.IdempotentActionScriptPath(s.Adapter.ProvisioningDataDirectory(initSecret))
+	wantCleanupPath := path.Join(s.Adapter.ProvisioningDataDirectory(initSecret), etcdRestoreBinSubdir, nodeCleanupScriptName)
+	wantNodeNamesPath := path.Join(s.Adapter.ProvisioningDataDirectory(initSecret), etcdRestoreBinSubdir, fmt.Sprintf("node-names-%s", string(s.Op.UID)))
 
 	pathsByPath := map[string]planapi.File{}
 	for _, f := range plan.Files {
@@ -205,10 +211,10 @@ func TestBuildPostRestoreNodeCleanupPlan(t *testing.T) {
 	for _, e := range instr.Env {
 		envSet[e] = true
 	}
-	if !envSet["KUBECTL="+s.adapter.KubectlPath(initSecret)] {
+	if !envSet["KUBECTL="+s.Adapter.KubectlPath(initSecret)] {
 		t.Errorf("KUBECTL env missing or wrong: %v", instr.Env)
 	}
-	if !envSet["KUBECONFIG="+s.adapter.KubeconfigPath(initSecret)] {
+	if !envSet["KUBECONFIG="+s.Adapter.KubeconfigPath(initSecret)] {
 		t.Errorf("KUBECONFIG env missing or wrong: %v", instr.Env)
 	}
 
@@ -260,7 +266,91 @@ func TestIdempotencyValueStable(t *testing.T) {
 	t.Parallel()
 
 	s := newTestScope(defaultAdapter(), "abc-123")
-	if got := s.idempotencyValue(); got != "abc-123" {
+	if got := s.IdempotencyValue(); got != "abc-123" {
 		t.Errorf("idempotencyValue = %q, want %q", got, "abc-123")
 	}
 }
+
+func TestPodCleanupPlanPointsControlPlaneAtEtcdLeader(t *testing.T) {
+	t.Parallel()
+
+	s := newTestScope(defaultAdapter(), "abc-123")
+	etcd := makePlanSecret("etcd-0", "node-etcd", map[string]string{capr.EtcdRoleLabel: "true"})
+	controlPlane := makePlanSecret("cp-0", "node-cp", map[string]string{capr.ControlPlaneRoleLabel: "true"})
+	override := path.Join(s.Adapter.ConfigDirectory(controlPlane), "zz_etcd-snapshot-restore.yaml")
+
+	hasOverride := func(p *planapi.Plan) bool {
+		for _, f := range p.Files {
+			if f.Path == override {
+				return true
+			}
+		}
+		return false
+	}
+
+	if p := podCleanupPlan(s, etcd, controlPlane); !hasOverride(p) {
+		t.Errorf("expected %s in plan when the etcd leader is not the control-plane node", override)
+	}
+
+	both := makePlanSecret("both-0", "node-both", map[string]string{capr.EtcdRoleLabel: "true", capr.ControlPlaneRoleLabel: "true"})
+	if p := podCleanupPlan(s, both, both); hasOverride(p) {
+		t.Errorf("expected no %s in plan when the etcd leader is the control-plane node", override)
+	}
+}
+
+func TestRestartIdempotencyValueDiffersPerPass(t *testing.T) {
+	t.Parallel()
+
+	s := newTestScope(defaultAdapter(), "abc-123")
+	initial := restartIdempotencyValue(s, true)
+	final := restartIdempotencyValue(s, false)
+	if initial == final {
+		t.Errorf("restart passes share idempotency value %q", initial)
+	}
+}
+
+func TestRestartPlanServerOverride(t *testing.T) {
+	t.Parallel()
+
+	s := newTestScope(defaultAdapter(), "abc-123")
+	initSecret := makePlanSecret("etcd-0", "node-etcd", map[string]string{capr.EtcdRoleLabel: "true"})
+	worker := makePlanSecret("worker-0", "node-worker", map[string]string{capr.WorkerRoleLabel: "true"})
+	override := path.Join(s.Adapter.ConfigDirectory(worker), "zz_etcd-snapshot-restore.yaml")
+
+	p, err := restartPlan(s, worker, initSecret, "10.0.0.1", "v", true)
+	if err != nil {
+		t.Fatalf("restartPlan: %v", err)
+	}
+	var content string
+	for _, f := range p.Files {
+		if f.Path == override {
+			content = f.Content
+		}
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(content)
+	if want := "server: \"https://10.0.0.1:9345\"\n"; string(decoded) != want {
+		t.Errorf("initial restart override = %q, want %q", decoded, want)
+	}
+	if !strings.Contains(strings.Join(p.OneTimeInstructions[0].Args, " "), "rke2-agent") {
+		t.Errorf("expected the worker to restart the agent unit, got %v", p.OneTimeInstructions[0].Args)
+	}
+
+	p, err = restartPlan(s, worker, initSecret, "10.0.0.1", "v", false)
+	if err != nil {
+		t.Fatalf("restartPlan: %v", err)
+	}
+	last := p.OneTimeInstructions[len(p.OneTimeInstructions)-1]
+	if last.Name != "remove-server-arg" {
+		t.Errorf("final restart must remove the server override, last instruction is %q", last.Name)
+	}
+}
+
+func TestEveryStepHasADefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	for _, step := range (&handler{}).definition().Steps {
+		if step.Timeout <= 0 {
+			t.Errorf("step %s has no default timeout", step.Name)
+		}
+	}
+}
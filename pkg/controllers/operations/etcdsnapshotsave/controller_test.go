@@ -2,7 +2,6 @@
 
 import (
 	"encoding/json"
-	"errors"
 	"reflect"
 	"testing"
 
@@ -11,8 +10,8 @@
 	"github.com/rancher/rancher/pkg/capr"
 	ops "github.com/rancher/rancher/pkg/operations"
 	planapi "github.com/rancher/rancher/pkg/plan"
-	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
-	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
 	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -20,10 +19,8 @@
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 )
 
 // stubAdapter is a minimal ops.Adapter implementation for tests. Each field is what the
@@ -54,6 +51,8 @@ func (a *stubAdapter) RuntimeCommand() string                            { retur
 func (a *stubAdapter) DistroDataDirectory(_ *corev1.Secret) string       { return a.dataDir }
 func (a *stubAdapter) ProvisioningDataDirectory(_ *corev1.Secret) string { return a.provisioningDir }
 func (a *stubAdapter) ServerUnit() string                                { return a.serverUnit }
+func (a *stubAdapter) AgentUnit() string                                 { return a.runtimeCommand + "-agent" }
+func (a *stubAdapter) InstallerImage(v string) string                    { return "installer:" + v }
 func (a *stubAdapter) RenderProbes(_ *corev1.Secret, _ bool) (map[string]rkeplan.Probe, error) {
 	return map[string]rkeplan.Probe{}, nil
 }
@@ -63,6 +62,10 @@ func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret,
 	return nil, nil
 }
 
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return nil, nil
+}
+
 // The six methods below complete the ops.Adapter contract for the stub. They are not exercised
 // by the snapshot-save controller (which only consumes runtime/dataDir/serverUnit/probes/plans),
 // so each returns a static, runtime-appropriate value.
@@ -79,31 +82,6 @@ func (a *stubAdapter) ToS3ArgsEnvAndFiles(_ *corev1.Secret) ([]string, []string,
 	return nil, nil, nil
 }
 
-// fakeDynamic satisfies the controller's dynamicResolver interface for the success-path tests.
-// Enqueue records the (gvk, namespace, name) tuple so tests can assert handleSucceeded nudged
-// the parent cluster.
-type fakeDynamic struct {
-	gets       map[string]runtime.Object
-	enqueued   []string
-	getErr     error
-	enqueueErr error
-}
-
-func (f *fakeDynamic) Get(gvk schema.GroupVersionKind, ns, name string) (runtime.Object, error) {
-	if f.getErr != nil {
-		return nil, f.getErr
-	}
-	if obj, ok := f.gets[gvk.String()+"/"+ns+"/"+name]; ok {
-		return obj, nil
-	}
-	return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
-}
-
-func (f *fakeDynamic) Enqueue(gvk schema.GroupVersionKind, ns, name string) error {
-	f.enqueued = append(f.enqueued, gvk.String()+"/"+ns+"/"+name)
-	return f.enqueueErr
-}
-
 // defaultAdapter returns a fully-populated stubAdapter suitable for most reconcile tests. K3s is
 // chosen because the runtime is irrelevant to the controller logic; only the rendered command
 // strings matter.
@@ -118,30 +96,23 @@ func defaultAdapter() *stubAdapter {
 	}
 }
 
-// newScope wires together the common per-reconcile context for the tests. The ownerKey mirrors
-// what the real controller computes in onChange (plan.ControllerOwnerKey(op, ControllerOwnerKey))
-// so beacon fixtures created with `testOwnerKey` will match ownership + delegate checks.
-func newScope(op *opv1alpha1.ETCDSnapshotSave, beacon *planv1alpha1.Beacon, adapter *stubAdapter) *scope {
+// newScope wires together the common per-reconcile context for the tests. The OwnerKey mirrors
+// what the engine computes (plan.ControllerOwnerKey(op, ControllerOwnerKey)).
+func newScope(op *opv1alpha1.ETCDSnapshotSave, adapter *stubAdapter) *scope {
 	cluster := &unstructured.Unstructured{}
 	cluster.SetName("test")
 	cluster.SetNamespace("fleet-default")
 	cluster.SetAPIVersion("provisioning.cattle.io/v1")
 	cluster.SetKind("Cluster")
 	return &scope{
-		ownerKey:   planapi.ControllerOwnerKey(op, ControllerOwnerKey),
-		op:         op,
-		beacon:     beacon,
-		namespace:  "fleet-default",
-		clusterObj: cluster,
-		adapter:    adapter,
+		OwnerKey:  planapi.ControllerOwnerKey(op, ControllerOwnerKey),
+		Op:        op,
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
 	}
 }
 
-// testOwnerKey is the fully-qualified beacon owner key for the canonical `newOp()` operation.
-// Tests that want to build a beacon owned by "us" pass this to newBeacon, instead of the plain
-// ControllerOwnerKey prefix which no longer matches what the handler computes at reconcile time.
-var testOwnerKey = planapi.ControllerOwnerKey(newOp(), ControllerOwnerKey)
-
 func newOp() *opv1alpha1.ETCDSnapshotSave {
 	return &opv1alpha1.ETCDSnapshotSave{
 		ObjectMeta: metav1.ObjectMeta{
@@ -155,22 +126,6 @@ func newOp() *opv1alpha1.ETCDSnapshotSave {
 	}
 }
 
-func newBeacon(owner string, active bool) *planv1alpha1.Beacon {
-	// Beacon ownership lives on Status.Owner (the plan.AcquireBeacon helper writes there).
-	// We populate the legacy BeaconOwnerLabel too so any caller that still reads it (e.g.
-	// EncryptionKeyRotation's reclaimStaleBeaconOwnerIfNeeded) keeps working.
-	return &planv1alpha1.Beacon{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "test",
-			Namespace: "fleet-default",
-		},
-		Status: planv1alpha1.BeaconStatus{
-			Active: active,
-			Owner:  owner,
-		},
-	}
-}
-
 // newPlanSecret builds a machine-plan secret carrying the cluster-name + etcd-role labels and
 // non-nil Annotations (the plan.Store assumes Annotations is non-nil).
 func newPlanSecret(name string) *corev1.Secret {
@@ -224,296 +179,40 @@ func withFailedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.S
 	return out
 }
 
-// newSecretClient mocks the SecretClient used by planapi.Store.AssignPlan. Update echoes the
-// passed-in secret back to the caller so the store treats it as the "post-update" state.
-func newSecretClient(t *testing.T, ctrl *gomock.Controller, items ...*corev1.Secret) *ctrlfake.MockClientInterface[*corev1.Secret, *corev1.SecretList] {
+// newHandler returns a handler collecting the given machine-plan secrets from an indexed secret
+// cache. Update echoes the passed-in secret back to the caller so the store treats it as the
+// "post-update" state.
+func newHandler(t *testing.T, ctrl *gomock.Controller, items ...*corev1.Secret) *handler {
 	t.Helper()
 	m := ctrlfake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl)
 	m.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
 		return s, nil
 	}).AnyTimes()
-	m.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(ns string, opts metav1.ListOptions) (*corev1.SecretList, error) {
-		sel, err := labels.Parse(opts.LabelSelector)
-		if err != nil {
-			return nil, err
-		}
-		var out corev1.SecretList
-		for _, s := range items {
-			if s.Namespace != ns {
-				continue
-			}
-			if !sel.Matches(labels.Set(s.Labels)) {
-				continue
-			}
-			out.Items = append(out.Items, *s)
-		}
-		return &out, nil
+	// Replacing a plan records it in a plan history secret, which does not exist yet.
+	m.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
 	}).AnyTimes()
-	return m
-}
-
-// fakeBeaconClient is a tiny in-memory implementation of plancontrollers.BeaconClient covering
-// only Update and UpdateStatus — the only methods AcquireBeacon/ReleaseBeacon/ToggleBeacon call.
-// Building a gomock interface for the full ClientInterface would dwarf the controller logic
-// under test; this hand-written stub keeps the assertions readable.
-type fakeBeaconClient struct {
-	plancontrollers.BeaconClient // embed for any unused method; nil panics signal an unexpected call
-
-	updates       []*planv1alpha1.Beacon
-	statusUpdates []*planv1alpha1.Beacon
-	updateErr     error
-}
-
-func (f *fakeBeaconClient) Update(b *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
-	f.updates = append(f.updates, b.DeepCopy())
-	return b, f.updateErr
-}
-
-func (f *fakeBeaconClient) UpdateStatus(b *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
-	f.statusUpdates = append(f.statusUpdates, b.DeepCopy())
-	return b, nil
-}
-
-// --- updateStatus ---------------------------------------------------------------------------
-
-func TestUpdateStatusPaused(t *testing.T) {
-	t.Parallel()
-
-	op := newOp()
-	op.Spec.Paused = true
-	op.Generation = 7
-
-	status := updateStatus(op, opv1alpha1.ETCDSnapshotSaveStatus{})
-
-	assert.Equal(t, int64(7), status.ObservedGeneration, "ObservedGeneration must be copied from the op")
-	assert.Equal(t, "True", opv1alpha1.PausedCondition.GetStatus(&status))
-	assert.Equal(t, opv1alpha1.PausedReason, opv1alpha1.PausedCondition.GetReason(&status))
-}
-
-func TestUpdateStatusByPhase(t *testing.T) {
-	t.Parallel()
-
-	cases := []struct {
-		name  string
-		phase opv1alpha1.OperationPhase
-		check func(t *testing.T, status opv1alpha1.ETCDSnapshotSaveStatus)
-	}{
-		{
-			name:  "pending sets Pending=True",
-			phase: opv1alpha1.OperationPhasePending,
-			check: func(t *testing.T, s opv1alpha1.ETCDSnapshotSaveStatus) {
-				assert.Equal(t, "True", opv1alpha1.PendingCondition.GetStatus(&s))
-			},
-		},
-		{
-			name:  "in-progress clears Pending",
-			phase: opv1alpha1.OperationPhaseInProgress,
-			check: func(t *testing.T, s opv1alpha1.ETCDSnapshotSaveStatus) {
-				assert.Equal(t, "False", opv1alpha1.PendingCondition.GetStatus(&s))
-				assert.Equal(t, opv1alpha1.InProgressReason, opv1alpha1.PendingCondition.GetReason(&s))
-			},
-		},
-		{
-			name:  "succeeded clears Pending+InProgress+Failed",
-			phase: opv1alpha1.OperationPhaseSucceeded,
-			check: func(t *testing.T, s opv1alpha1.ETCDSnapshotSaveStatus) {
-				assert.Equal(t, "False", opv1alpha1.PendingCondition.GetStatus(&s))
-				assert.Equal(t, "False", opv1alpha1.InProgressCondition.GetStatus(&s))
-				assert.Equal(t, "False", opv1alpha1.FailedCondition.GetStatus(&s))
-				assert.Equal(t, opv1alpha1.NotFailedReason, opv1alpha1.FailedCondition.GetReason(&s))
-			},
-		},
-		{
-			name:  "failed clears Pending+InProgress+Succeeded",
-			phase: opv1alpha1.OperationPhaseFailed,
-			check: func(t *testing.T, s opv1alpha1.ETCDSnapshotSaveStatus) {
-				assert.Equal(t, "False", opv1alpha1.PendingCondition.GetStatus(&s))
-				assert.Equal(t, "False", opv1alpha1.InProgressCondition.GetStatus(&s))
-				assert.Equal(t, "False", opv1alpha1.SucceededCondition.GetStatus(&s))
-				assert.Equal(t, opv1alpha1.NotSuccessfulReason, opv1alpha1.SucceededCondition.GetReason(&s))
-			},
-		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			op := newOp()
-			status := updateStatus(op, opv1alpha1.ETCDSnapshotSaveStatus{
-				OperationStatus: opv1alpha1.OperationStatus{Phase: tc.phase},
-			})
-			tc.check(t, status)
-		})
-	}
-}
-
-// --- handlePending --------------------------------------------------------------------------
-
-func TestHandlePending_NilBeacon(t *testing.T) {
-	t.Parallel()
-
-	h := &handler{beacons: &fakeBeaconClient{}}
-	s := newScope(newOp(), nil, defaultAdapter())
-
-	got, err := h.handlePending(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	// AcquireBeacon returns nil when beacon is nil → handler keeps Pending and reports waiting.
-	assert.Empty(t, string(got.Phase), "no phase set yet")
-	assert.Equal(t, opv1alpha1.WaitingForBeaconReason, opv1alpha1.PendingCondition.GetReason(&got))
-}
-
-func TestHandlePending_BeaconOwnedByOther(t *testing.T) {
-	t.Parallel()
-
-	h := &handler{beacons: &fakeBeaconClient{}}
-	s := newScope(newOp(), newBeacon("some-other-controller", false), defaultAdapter())
-
-	got, err := h.handlePending(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	// AcquireBeacon returns nil when another controller owns it → still waiting.
-	assert.Empty(t, string(got.Phase))
-	assert.Equal(t, opv1alpha1.WaitingForBeaconReason, opv1alpha1.PendingCondition.GetReason(&got))
-}
-
-func TestHandlePending_WaitingForRegistration(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	adapter := defaultAdapter()
-	adapter.waitForRegisterOK = false
-
-	h := &handler{beacons: beacons}
-	s := newScope(newOp(), newBeacon(testOwnerKey, false), adapter)
-
-	got, err := h.handlePending(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	assert.Empty(t, string(got.Phase), "phase must not advance until all agents have registered")
-	assert.Equal(t, opv1alpha1.WaitingForRegistrationReason, opv1alpha1.PendingCondition.GetReason(&got))
-}
-
-func TestHandlePending_TransitionsToInProgress(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	h := &handler{beacons: beacons}
-	a := defaultAdapter()
-	a.waitForRegisterOK = true
-	s := newScope(newOp(), newBeacon(testOwnerKey, false), a)
-
-	got, err := h.handlePending(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, got.Phase)
-	assert.Equal(t, opv1alpha1.ETCDSnapshotSaveStepPreflight, got.Step)
-}
-
-func TestHandlePending_WaitForRegisterErrorBubbles(t *testing.T) {
-	t.Parallel()
-
-	sentinel := errors.New("kaboom")
-	adapter := defaultAdapter()
-	adapter.waitForRegisterErr = sentinel
-	adapter.waitForRegisterOK = false
-
-	h := &handler{beacons: &fakeBeaconClient{}}
-	_, err := h.handlePending(newScope(newOp(), newBeacon(testOwnerKey, false), adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.ErrorIs(t, err, sentinel)
-}
-
-// --- handleInProgress -----------------------------------------------------------------------
-
-func TestHandleInProgress_BeaconLost(t *testing.T) {
-	t.Parallel()
-
-	h := &handler{beacons: &fakeBeaconClient{}}
-	// Beacon owned by some other controller → handler must fail rather than continue.
-	s := newScope(newOp(), newBeacon("other", true), defaultAdapter())
-
-	got, err := h.handleInProgress(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
-	assert.Equal(t, opv1alpha1.BeaconLostReason, opv1alpha1.FailedCondition.GetReason(&got))
-}
-
-func TestHandleInProgress_UnknownStep(t *testing.T) {
-	t.Parallel()
-
-	h := &handler{beacons: &fakeBeaconClient{}}
-	op := newOp()
-	op.Status.Step = "Whatever"
-	s := newScope(op, newBeacon(testOwnerKey, false), defaultAdapter())
-
-	got, err := h.handleInProgress(s, opv1alpha1.ETCDSnapshotSaveStatus{Step: "Whatever"})
-	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
-	assert.Equal(t, opv1alpha1.UnknownStepReason, opv1alpha1.FailedCondition.GetReason(&got))
-}
-
-// --- handleFailed / handleSucceeded --------------------------------------------------------
-
-func TestHandleFailed_HoldingBeaconReleases(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	h := &handler{beacons: beacons}
-	s := newScope(newOp(), newBeacon(testOwnerKey, true), defaultAdapter())
-
-	_, err := h.handleFailed(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	// ReleaseBeacon on the owner path clears Active + Owner + Delegates in a single UpdateStatus
-	// call, so we expect exactly one status update and no main-resource update.
-	if assert.Len(t, beacons.statusUpdates, 1, "ReleaseBeacon should update the beacon status") {
-		assert.False(t, beacons.statusUpdates[0].Status.Active, "beacon must be toggled inactive on release")
-		assert.Equal(t, "", beacons.statusUpdates[0].Status.Owner, "Status.Owner must be cleared on release")
+	m.EXPECT().Create(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		return s, nil
+	}).AnyTimes()
+	return &handler{
+		secretCache: newSecretCache(t, items...),
+		store:       planapi.NewStore(m),
 	}
-	assert.Empty(t, beacons.updates, "ReleaseBeacon no longer touches the main resource")
-}
-
-func TestHandleFailed_NotHoldingNoOp(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	h := &handler{beacons: beacons}
-	s := newScope(newOp(), newBeacon("other", true), defaultAdapter())
-
-	_, err := h.handleFailed(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	assert.Empty(t, beacons.updates, "non-owners must not touch the beacon")
-	assert.Empty(t, beacons.statusUpdates)
-}
-
-func TestHandleSucceeded_NotHoldingNoOp(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	dyn := &fakeDynamic{}
-	h := &handler{beacons: beacons, dynamic: dyn}
-	s := newScope(newOp(), newBeacon("other", true), defaultAdapter())
-
-	_, err := h.handleSucceeded(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	assert.Empty(t, beacons.updates)
-	assert.Empty(t, dyn.enqueued, "non-owners must not enqueue the cluster")
 }
 
-func TestHandleSucceeded_HoldingBeaconEnqueuesCluster(t *testing.T) {
-	t.Parallel()
-
-	beacons := &fakeBeaconClient{}
-	dyn := &fakeDynamic{}
-	h := &handler{beacons: beacons, dynamic: dyn}
-	s := newScope(newOp(), newBeacon(testOwnerKey, true), defaultAdapter())
-
-	_, err := h.handleSucceeded(s, opv1alpha1.ETCDSnapshotSaveStatus{})
-	assert.NoError(t, err)
-	// ReleaseBeacon on the owner path clears Active + Owner + Delegates in a single UpdateStatus
-	// call.
-	if assert.Len(t, beacons.statusUpdates, 1, "ReleaseBeacon should update the beacon status") {
-		assert.False(t, beacons.statusUpdates[0].Status.Active, "beacon must be toggled inactive on release")
-		assert.Equal(t, "", beacons.statusUpdates[0].Status.Owner, "Status.Owner must be cleared on release")
-	}
-	assert.Empty(t, beacons.updates, "ReleaseBeacon no longer touches the main resource")
-	if assert.Len(t, dyn.enqueued, 1, "parent cluster must be re-enqueued") {
-		assert.Equal(t, "provisioning.cattle.io/v1, Kind=Cluster/fleet-default/test", dyn.enqueued[0])
+// newSecretCache returns a secret cache with the plan indexers registered, holding items.
+func newSecretCache(t *testing.T, items ...*corev1.Secret) corecontrollers.SecretCache {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := generic.NewCache[*corev1.Secret](indexer, corev1.Resource("secrets"))
+	planapi.RegisterIndexers(c)
+	for _, item := range items {
+		if err := indexer.Add(item); err != nil {
+			t.Fatalf("adding %s: %v", item.Name, err)
+		}
 	}
+	return c
 }
 
 // --- reconcileSave --------------------------------------------------------------------------
@@ -558,15 +257,14 @@ func TestReconcileSave_NoSecrets(t *testing.T) {
 	t.Parallel()
 
 	ctrl := gomock.NewController(t)
-	h := &handler{
-		secrets: newSecretClient(t, ctrl),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl)
 
-	status, err := h.reconcileSave(newScope(newOp(), nil, defaultAdapter()), opv1alpha1.ETCDSnapshotSaveStatus{})
-	// The Collector validator surfaces the empty-set condition as an error; the outer status
-	// handler will requeue (and the op stays in its current phase until the situation resolves).
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileSave(newScope(newOp(), defaultAdapter()), &status)
+	// The Collector validator surfaces the empty-set condition as a terminal error; the step fails
+	// the operation instead of requeueing.
 	assert.NoError(t, err, "terminal errors should not trigger reenqueue")
+	assert.False(t, done)
 	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase, "terminal errors should cause operation to fail")
 }
 
@@ -578,20 +276,19 @@ func TestReconcileSave_WaitsForPlanApply(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := newPlanSecret("etcd-1") // no plan applied yet → first dispatch
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileSave(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileSave(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	// Plan was just delivered to the agent — controller must report InProgress and let the next
+	// Plan was just delivered to the agent — the step must report waiting and let the next
 	// reconcile poll feedback.
-	assert.Empty(t, string(got.Phase), "phase must not advance while a plan is pending")
-	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&got))
+	assert.False(t, done, "step must not complete while a plan is pending")
+	assert.Empty(t, string(status.Phase), "phase must not advance while a plan is pending")
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
 }
 
-func TestReconcileSave_TransitionsToRestartWhenApplied(t *testing.T) {
+func TestReconcileSave_CompletesWhenApplied(t *testing.T) {
 	t.Parallel()
 
 	ctrl := gomock.NewController(t)
@@ -599,15 +296,13 @@ func TestReconcileSave_TransitionsToRestartWhenApplied(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := withAppliedPlan(newPlanSecret("etcd-1"), expectedSavePlan(op, adapter))
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileSave(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileSave(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	assert.Empty(t, string(got.Phase), "phase must not change on a clean transition")
-	assert.Equal(t, opv1alpha1.ETCDSnapshotSaveStepRestart, got.Step)
+	assert.True(t, done, "step must complete once every etcd node applied the plan")
+	assert.Empty(t, string(status.Phase), "phase must not change on a clean transition")
 }
 
 func TestReconcileSave_PlanFailureMarksFailed(t *testing.T) {
@@ -618,46 +313,34 @@ func TestReconcileSave_PlanFailureMarksFailed(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := withFailedPlan(newPlanSecret("etcd-1"), expectedSavePlan(op, adapter))
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileSave(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileSave(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
-	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&got))
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
 }
 
 func TestReconcileSave_AppliesSnapshotArgs(t *testing.T) {
 	t.Parallel()
 
-	ctrl := gomock.NewController(t)
 	op := newOp()
 	op.Spec.Args.Name = "my-snap"
-	adapter := defaultAdapter()
 
-	// Pre-populate so the test traverses the "applied" branch without needing additional poll
-	// cycles — we're asserting on the *plan content* not the wait behaviour here.
-	expectedPlan := expectedSavePlan(op, adapter)
-	secret := withAppliedPlan(newPlanSecret("etcd-1"), expectedPlan)
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
-
-	_, err := h.reconcileSave(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	got, err := savePlan(newScope(op, defaultAdapter()), newPlanSecret("etcd-1"))
 	assert.NoError(t, err)
 
 	wantArgs := []string{"etcd-snapshot", "save", "--name", "my-snap"}
-	if !reflect.DeepEqual(expectedPlan.OneTimeInstructions[0].Args, wantArgs) {
-		t.Errorf("plan args = %v, want %v — snapshot Args were not threaded through", expectedPlan.OneTimeInstructions[0].Args, wantArgs)
+	if !reflect.DeepEqual(got.OneTimeInstructions[0].Args, wantArgs) {
+		t.Errorf("plan args = %v, want %v — snapshot Args were not threaded through", got.OneTimeInstructions[0].Args, wantArgs)
 	}
 }
 
 // --- reconcileRestart -----------------------------------------------------------------------
 
-func TestReconcileRestart_MarksSucceededWhenApplied(t *testing.T) {
+func TestReconcileRestart_CompletesWhenApplied(t *testing.T) {
 	t.Parallel()
 
 	ctrl := gomock.NewController(t)
@@ -665,15 +348,13 @@ func TestReconcileRestart_MarksSucceededWhenApplied(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := withAppliedPlan(newPlanSecret("etcd-1"), expectedRestartPlan(adapter))
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileRestart(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileRestart(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, got.Phase)
-	assert.Equal(t, opv1alpha1.FinishedReason, opv1alpha1.SucceededCondition.GetReason(&got))
+	// Restart is the last step, so the engine marks the operation Succeeded once it completes.
+	assert.True(t, done)
 }
 
 func TestReconcileRestart_WaitsForPlanApply(t *testing.T) {
@@ -684,15 +365,14 @@ func TestReconcileRestart_WaitsForPlanApply(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := newPlanSecret("etcd-1") // no plan applied yet
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileRestart(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileRestart(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	assert.Empty(t, string(got.Phase), "phase must not advance to Succeeded while restart is pending")
-	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&got))
+	assert.False(t, done, "step must not complete while restart is pending")
+	assert.Empty(t, string(status.Phase))
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
 }
 
 func TestReconcileRestart_PlanFailureMarksFailed(t *testing.T) {
@@ -703,15 +383,14 @@ func TestReconcileRestart_PlanFailureMarksFailed(t *testing.T) {
 	adapter := defaultAdapter()
 
 	secret := withFailedPlan(newPlanSecret("etcd-1"), expectedRestartPlan(adapter))
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, secret),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, secret)
 
-	got, err := h.reconcileRestart(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileRestart(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
-	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&got))
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
 }
 
 func TestReconcileRestart_FiltersToEtcdSecrets(t *testing.T) {
@@ -735,14 +414,32 @@ func TestReconcileRestart_FiltersToEtcdSecrets(t *testing.T) {
 		},
 		Type: planapi.SecretTypeMachinePlan,
 	}
-	h := &handler{
-		secrets: newSecretClient(t, ctrl, etcd, worker),
-	}
-	h.store = planapi.NewStore(h.secrets)
+	h := newHandler(t, ctrl, etcd, worker)
 
-	got, err := h.reconcileRestart(newScope(op, nil, adapter), opv1alpha1.ETCDSnapshotSaveStatus{})
+	status := opv1alpha1.ETCDSnapshotSaveStatus{}
+	done, err := h.reconcileRestart(newScope(op, adapter), &status)
 	assert.NoError(t, err)
-	// Worker secret must be ignored — only etcd nodes receive the restart plan; success would
+	// Worker secret must be ignored — only etcd nodes receive the restart plan; completion would
 	// not be reached if the worker were included (its plan is not in "applied" state).
-	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, got.Phase, "non-etcd secrets must not be in the iteration")
+	assert.True(t, done, "non-etcd secrets must not be in the iteration")
+}
+
+// --- definition -----------------------------------------------------------------------------
+
+func TestDefinition_StepsInOrderWithoutPause(t *testing.T) {
+	t.Parallel()
+
+	def := (&handler{}).definition()
+
+	names := make([]opv1alpha1.ETCDSnapshotSaveStep, 0, len(def.Steps))
+	for _, step := range def.Steps {
+		names = append(names, step.Name)
+		assert.False(t, step.PauseCluster, "snapshot saves must never pause the cluster")
+	}
+	assert.Equal(t, []opv1alpha1.ETCDSnapshotSaveStep{
+		opv1alpha1.ETCDSnapshotSaveStepPreflight,
+		opv1alpha1.ETCDSnapshotSaveStepSave,
+		opv1alpha1.ETCDSnapshotSaveStepRestart,
+	}, names)
+	assert.Nil(t, def.Steps[0].Preview, "preflight must run for real in a dry run")
 }
@@ -0,0 +1,752 @@
+package kubernetesupgrade
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/capr"
+	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	"github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/rancher/pkg/wrangler"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// ControllerOwnerKey is the value used to identify the kubernetes-upgrade handler currently owns the beacon.
+	ControllerOwnerKey = "kubernetes-upgrade"
+
+	// Step hook label prefixes for the kubernetesupgrade operation. They follow the shared label
+	// semantics documented on planv1alpha1's phase-hook label constants, but each prefix only fires
+	// when the operation enters the matching step.
+
+	// PreflightStepHookLabelPrefix gates the Preflight step, before the controller validates the
+	// requested version and upgrade strategy against the cluster.
+	PreflightStepHookLabelPrefix = "preflight.step.hook.operation.cattle.io/"
+
+	// EtcdStepHookLabelPrefix gates the Etcd step, before the cluster is paused and reconcileEtcd
+	// assigns the upgrade plan to the etcd nodes. Fires before PauseCluster so a delegate observes
+	// the cluster in its pre-pause state.
+	EtcdStepHookLabelPrefix = "etcd.step.hook.operation.cattle.io/"
+
+	// ControlPlaneStepHookLabelPrefix gates the ControlPlane step, before reconcileControlPlane
+	// assigns the upgrade plan to the control-plane nodes that do not hold the etcd role.
+	ControlPlaneStepHookLabelPrefix = "control-plane.step.hook.operation.cattle.io/"
+
+	// WorkerStepHookLabelPrefix gates the Worker step, before reconcileWorker assigns the upgrade
+	// plan to the worker-only nodes.
+	WorkerStepHookLabelPrefix = "worker.step.hook.operation.cattle.io/"
+
+	// idempotencyKey is the top-level key used to scope idempotency tracking for this controller.
+	idempotencyKey = "kubernetes-upgrade"
+
+	// maxFailures is the number of times the system-agent attempts an upgrade plan before the
+	// operation is marked as failed. Uncordoning a freshly restarted server can transiently fail
+	// while the apiserver comes back up, so a node is given a few attempts.
+	maxFailures = 5
+)
+
+type (
+	scope = ops.Scope[*opv1alpha1.KubernetesUpgrade]
+	step  = ops.Step[*opv1alpha1.KubernetesUpgrade, opv1alpha1.KubernetesUpgradeStatus, opv1alpha1.KubernetesUpgradeStep]
+)
+
+// handler holds the step reconcilers of the KubernetesUpgrade controller. The shared state machine
+// (beacon ownership, hooks, pause, cancellation and TTL expiry) is driven by an ops.Engine; all
+// fields are populated at Register time and the handler itself is stateless across reconciles.
+type handler struct {
+	secretCache corecontrollers.SecretCache
+
+	store *plan.Store
+}
+
+// Register wires the KubernetesUpgrade controller into the given wrangler context. It must be
+// called exactly once per process; subsequent calls would clobber the registered status handler.
+func Register(ctx context.Context, clients *wrangler.CAPIContext) {
+	h := &handler{
+		secretCache: clients.Core.Secret().Cache(),
+		store:       plan.NewStore(clients.Core.Secret()),
+	}
+	engine := ops.NewEngine(clients, clients.Operation.KubernetesUpgrade(), h.definition())
+
+	operationcontrollers.RegisterKubernetesUpgradeStatusHandler(ctx, clients.Operation.KubernetesUpgrade(), "", "kubernetes-upgrade-handler", engine.OnChange)
+	ops.WatchBeaconQueue(ctx, clients.Plan.Beacon(), opv1alpha1.SchemeGroupVersion.WithKind("KubernetesUpgrade"),
+		clients.Operation.KubernetesUpgrade().Cache(), clients.Operation.KubernetesUpgrade(),
+		func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+	ops.RecordOperations(ctx, clients.Operation.KubernetesUpgrade(), clients.Operation.OperationRecord(), clients.Operation.OperationRecord().Cache(),
+		opv1alpha1.SchemeGroupVersion.WithKind("KubernetesUpgrade"),
+		func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.OperationSpec {
+			return &op.Spec.OperationSpec
+		},
+		func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.OperationStatus {
+			return &op.Status.OperationStatus
+		})
+}
+
+// definition returns the engine definition of the KubernetesUpgrade operation: Preflight validates
+// the requested version and strategies, then Etcd, ControlPlane and Worker pause the cluster and
+// upgrade the nodes of their role at most maxUnavailable at a time. The node steps upgrade their
+// nodes one batch at a time, so their default timeouts leave room for large clusters.
+func (h *handler) definition() ops.Definition[*opv1alpha1.KubernetesUpgrade, opv1alpha1.KubernetesUpgradeStatus, opv1alpha1.KubernetesUpgradeStep] {
+	return ops.Definition[*opv1alpha1.KubernetesUpgrade, opv1alpha1.KubernetesUpgradeStatus, opv1alpha1.KubernetesUpgradeStep]{
+		Name:             ControllerOwnerKey,
+		GroupVersionKind: opv1alpha1.SchemeGroupVersion.WithKind("KubernetesUpgrade"),
+		Steps: []step{
+			{
+				Name:            opv1alpha1.KubernetesUpgradeStepPreflight,
+				HookLabelPrefix: PreflightStepHookLabelPrefix,
+				Reconcile:       h.reconcilePreflight,
+				Timeout:         15 * time.Minute,
+				Preview:         h.previewPreflight,
+			},
+			{
+				Name:            opv1alpha1.KubernetesUpgradeStepEtcd,
+				HookLabelPrefix: EtcdStepHookLabelPrefix,
+				PauseCluster:    true,
+				Reconcile:       h.reconcileEtcd,
+				Timeout:         2 * time.Hour,
+				Preview:         h.previewEtcd,
+			},
+			{
+				Name:            opv1alpha1.KubernetesUpgradeStepControlPlane,
+				HookLabelPrefix: ControlPlaneStepHookLabelPrefix,
+				PauseCluster:    true,
+				Reconcile:       h.reconcileControlPlane,
+				Timeout:         2 * time.Hour,
+				Preview:         h.previewControlPlane,
+			},
+			{
+				Name:            opv1alpha1.KubernetesUpgradeStepWorker,
+				HookLabelPrefix: WorkerStepHookLabelPrefix,
+				PauseCluster:    true,
+				Reconcile:       h.reconcileWorker,
+				Timeout:         4 * time.Hour,
+				Preview:         h.previewWorker,
+			},
+		},
+		Spec:   func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		Status: func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.KubernetesUpgradeStatus { return &op.Status },
+		OperationStatus: func(status *opv1alpha1.KubernetesUpgradeStatus) *opv1alpha1.OperationStatus {
+			return &status.OperationStatus
+		},
+		Step: func(status *opv1alpha1.KubernetesUpgradeStatus) *opv1alpha1.KubernetesUpgradeStep {
+			return &status.Step
+		},
+	}
+}
+
+// reconcilePreflight verifies that the requested version belongs to the distro running on the
+// cluster, that every maxUnavailable value can be parsed, and that every node of a role that
+// requests a drain has a node name to drain. The operation is marked as Failed when any check
+// fails; nothing has been changed on the cluster yet.
+func (h *handler) reconcilePreflight(s *scope, status *opv1alpha1.KubernetesUpgradeStatus) (bool, error) {
+	logrus.Debugf("[kubernetesupgrade] %s/%s: handling preflight", s.Op.Namespace, s.Op.Name)
+
+	if msg, err := h.preflight(s); err != nil {
+		return false, err
+	} else if msg != "" {
+		logrus.Errorf("[kubernetesupgrade] %s/%s: marking operation as failed: %s", s.Op.Namespace, s.Op.Name, msg)
+		ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, msg)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// preflight runs the checks of the Preflight step. A non-empty message means a check failed.
+func (h *handler) preflight(s *scope) (string, error) {
+	args := s.Op.Spec.Args
+
+	if runtime := capr.GetRuntime(args.KubernetesVersion); runtime != s.Adapter.RuntimeCommand() {
+		return fmt.Sprintf("version %s is a %s version, but the cluster is running %s", args.KubernetesVersion, runtime, s.Adapter.RuntimeCommand()), nil
+	}
+
+	for _, role := range roles(s) {
+		if _, err := concurrencyFor(role.strategy.MaxUnavailable, 1); err != nil {
+			return fmt.Sprintf("invalid %s strategy: %v", role.step, err), nil
+		}
+
+		if role.strategy.Drain == nil {
+			continue
+		}
+
+		secrets, err := h.collect(s, role.step).Collect()
+		if plan.IsTransient(err) {
+			return "", err
+		} else if err != nil {
+			return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+		}
+
+		for _, secret := range secrets {
+			if secret.Labels[capr.NodeNameLabel] == "" {
+				return fmt.Sprintf("cannot drain %s/%s: no node name found", secret.Namespace, secret.Name), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// reconcileEtcd upgrades the etcd nodes, including etcd nodes that also hold the control-plane
+// role, in plan.DefaultSorter order so the init node is always upgraded first. At most
+// maxUnavailable nodes are handed a plan at a time, and the next node is only handed a plan once a
+// previous one has applied and its probes pass.
+//
+// Clusters without etcd nodes (e.g. K3s with an external datastore) complete the step at once.
+func (h *handler) reconcileEtcd(s *scope, status *opv1alpha1.KubernetesUpgradeStatus) (bool, error) {
+	logrus.Debugf("[kubernetesupgrade] %s/%s: handling etcd upgrade", s.Op.Namespace, s.Op.Name)
+
+	return h.rollout(s, status, opv1alpha1.KubernetesUpgradeStepEtcd, s.Op.Spec.Args.Etcd)
+}
+
+// reconcileControlPlane upgrades the control-plane nodes that do not hold the etcd role, following
+// the same rollout rules as reconcileEtcd.
+func (h *handler) reconcileControlPlane(s *scope, status *opv1alpha1.KubernetesUpgradeStatus) (bool, error) {
+	logrus.Debugf("[kubernetesupgrade] %s/%s: handling control-plane upgrade", s.Op.Namespace, s.Op.Name)
+
+	return h.rollout(s, status, opv1alpha1.KubernetesUpgradeStepControlPlane, s.Op.Spec.Args.ControlPlane)
+}
+
+// reconcileWorker upgrades the linux worker-only nodes. Windows workers are skipped, as the
+// installer image and drain instructions are only rendered for linux nodes; they must be upgraded
+// out of band.
+//
+// Worker nodes do not have an admin kubeconfig, so when a drain is requested an elected
+// control-plane leader drains every node before it is handed its upgrade plan and uncordons it
+// once the upgrade has applied.
+//
+// Once every worker has applied the upgrade, the new version is recorded on the cluster. The
+// planner converges the nodes on the cluster's version as soon as the cluster is unpaused, and the
+// cluster stays paused until the operation ends, so the version is only written once every node
+// runs it. A failed upgrade leaves the version unchanged.
+func (h *handler) reconcileWorker(s *scope, status *opv1alpha1.KubernetesUpgradeStatus) (bool, error) {
+	logrus.Debugf("[kubernetesupgrade] %s/%s: handling worker upgrade", s.Op.Namespace, s.Op.Name)
+
+	var (
+		done     bool
+		err      error
+		strategy = s.Op.Spec.Args.Worker
+	)
+	if strategy.Drain == nil {
+		done, err = h.rollout(s, status, opv1alpha1.KubernetesUpgradeStepWorker, strategy)
+	} else {
+		done, err = h.rolloutWithLeaderDrain(s, status, opv1alpha1.KubernetesUpgradeStepWorker, strategy)
+	}
+	if err != nil || !done {
+		return false, err
+	}
+
+	if versioned, ok := s.Adapter.(ops.VersionedAdapter); ok {
+		if err := versioned.SetKubernetesVersion(s.Op.Spec.Args.KubernetesVersion); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// rollout assigns the upgrade plan to the nodes collected for step, handing out at most
+// maxUnavailable plans that have not yet applied at a time. Server nodes drain and uncordon
+// themselves when the strategy requests a drain. Returns true once every node has applied the
+// upgrade; the status is updated while the operation is still waiting or has failed.
+func (h *handler) rollout(s *scope, status *opv1alpha1.KubernetesUpgradeStatus, step opv1alpha1.KubernetesUpgradeStep, strategy opv1alpha1.KubernetesUpgradeRoleStrategy) (bool, error) {
+	secrets, err := h.collect(s, step).Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		failCollect(s, status, err)
+		return false, nil
+	}
+
+	concurrency, err := concurrencyFor(strategy.MaxUnavailable, len(secrets))
+	if err != nil {
+		failStrategy(s, status, step, err)
+		return false, nil
+	}
+
+	waiting := false
+	results := make([]plan.PlanStatus, 0, len(secrets))
+
+	for _, secret := range secrets {
+		nodePlan, err := upgradePlan(s, secret, strategy.Drain)
+		if err != nil {
+			return false, err
+		}
+
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, maxFailures, maxFailures)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+
+		if planStatus.Failure() {
+			failPlan(s, status, secret)
+			return false, nil
+		}
+
+		if planStatus.Waiting() {
+			logrus.Debugf("[kubernetesupgrade] %s/%s: waiting for upgrade of %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+
+			waiting = true
+			concurrency--
+			if concurrency <= 0 {
+				break
+			}
+		}
+	}
+
+	if waiting {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", step, plan.Message(results)))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// rolloutWithLeaderDrain is the worker rollout used when a drain is requested. Every reconcile,
+// workers whose upgrade plan has already been assigned are checked first; the remaining workers
+// are pending. Up to maxUnavailable minus the in-flight workers are picked as candidates, and the
+// leader is handed a plan that drains every candidate and uncordons every completed worker. The
+// candidates are only handed their upgrade plans once the leader plan has applied.
+//
+// Drain and uncordon instructions are keyed per node, so a leader plan that is re-rendered as the
+// rollout progresses never drains a node that has already been upgraded a second time.
+func (h *handler) rolloutWithLeaderDrain(s *scope, status *opv1alpha1.KubernetesUpgradeStatus, step opv1alpha1.KubernetesUpgradeStep, strategy opv1alpha1.KubernetesUpgradeRoleStrategy) (bool, error) {
+	secrets, err := h.collect(s, step).Collect()
+	if plan.IsTransient(err) {
+		return false, err
+	} else if err != nil {
+		failCollect(s, status, err)
+		return false, nil
+	}
+
+	leader, err := s.Adapter.FindOrElectLeader(ControllerOwnerKey, ops.And(ops.IsControlPlane, ops.Not(ops.IsWindows)))
+	if err != nil {
+		return false, err
+	}
+
+	if leader == nil {
+		logrus.Debugf("[kubernetesupgrade] %s/%s: no suitable control-plane leader found yet, will retry", s.Op.Namespace, s.Op.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForSuitableLeaderReason, "waiting for a suitable control-plane leader to drain worker nodes")
+		return false, nil
+	}
+	ops.RecordLeader(&status.OperationStatus, leader)
+
+	concurrency, err := concurrencyFor(strategy.MaxUnavailable, len(secrets))
+	if err != nil {
+		failStrategy(s, status, step, err)
+		return false, nil
+	}
+
+	var (
+		completed, pending []*corev1.Secret
+		inflight           int
+		plans              = make(map[*corev1.Secret]*plan.Plan, len(secrets))
+		results            = make([]plan.PlanStatus, 0, len(secrets)+1)
+	)
+
+	for _, secret := range secrets {
+		nodePlan, err := upgradePlan(s, secret, nil)
+		if err != nil {
+			return false, err
+		}
+		plans[secret] = nodePlan
+
+		assigned, err := isAssigned(secret, nodePlan)
+		if err != nil {
+			return false, err
+		}
+		if !assigned {
+			pending = append(pending, secret)
+			continue
+		}
+
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, nodePlan, maxFailures, maxFailures)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+
+		switch {
+		case planStatus.Failure():
+			failPlan(s, status, secret)
+			return false, nil
+		case planStatus.Waiting():
+			inflight++
+		default:
+			completed = append(completed, secret)
+		}
+	}
+
+	candidates := pending[:min(len(pending), max(concurrency-inflight, 0))]
+
+	leaderPlan, err := drainPlan(s, leader, strategy.Drain, candidates, completed)
+	if err != nil {
+		return false, err
+	}
+
+	leaderStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(leader, leaderPlan, maxFailures, maxFailures)
+	if err != nil {
+		return false, err
+	}
+	ops.RecordPlanChange(&status.OperationStatus, leader, leaderStatus.Diff)
+
+	if leaderStatus.Failure() {
+		failPlan(s, status, leader)
+		return false, nil
+	}
+
+	if leaderStatus.Waiting() {
+		logrus.Debugf("[kubernetesupgrade] %s/%s: waiting for leader %s/%s to drain and uncordon workers", s.Op.Namespace, s.Op.Name, leader.Namespace, leader.Name)
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", step, plan.Message(append(results, *leaderStatus))))
+		return false, nil
+	}
+
+	for _, secret := range candidates {
+		planStatus, err := h.store.ForAssigner(s.OwnerKey).AssignPlan(secret, plans[secret], maxFailures, maxFailures)
+		if err != nil {
+			return false, err
+		}
+		ops.RecordPlanChange(&status.OperationStatus, secret, planStatus.Diff)
+
+		results = append(results, *planStatus)
+	}
+
+	if inflight > 0 || len(candidates) > 0 {
+		ops.Wait(&status.OperationStatus, opv1alpha1.WaitingForPlanAppliedReason, fmt.Sprintf("Waiting in step %s: %s", step, plan.Message(results)))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// previewPreflight runs the checks of the Preflight step for a dry run. It renders no plans.
+func (h *handler) previewPreflight(s *scope, _ *ops.PlanPreview) (string, error) {
+	return h.preflight(s)
+}
+
+// previewEtcd renders the upgrade plans of the etcd nodes for a dry run.
+func (h *handler) previewEtcd(s *scope, preview *ops.PlanPreview) (string, error) {
+	return h.previewRollout(s, preview, opv1alpha1.KubernetesUpgradeStepEtcd, s.Op.Spec.Args.Etcd)
+}
+
+// previewControlPlane renders the upgrade plans of the control-plane nodes that do not hold the
+// etcd role for a dry run.
+func (h *handler) previewControlPlane(s *scope, preview *ops.PlanPreview) (string, error) {
+	return h.previewRollout(s, preview, opv1alpha1.KubernetesUpgradeStepControlPlane, s.Op.Spec.Args.ControlPlane)
+}
+
+// previewWorker renders the worker rollout for a dry run, including the plans of the drain leader
+// when a drain is requested. The drain leader is not marked.
+func (h *handler) previewWorker(s *scope, preview *ops.PlanPreview) (string, error) {
+	strategy := s.Op.Spec.Args.Worker
+	if strategy.Drain == nil {
+		return h.previewRollout(s, preview, opv1alpha1.KubernetesUpgradeStepWorker, strategy)
+	}
+
+	secrets, err := h.collect(s, opv1alpha1.KubernetesUpgradeStepWorker).Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+	return previewLeaderDrain(s, preview, strategy, secrets)
+}
+
+// previewRollout renders the upgrade plan of every node collected for step into preview.
+func (h *handler) previewRollout(s *scope, preview *ops.PlanPreview, step opv1alpha1.KubernetesUpgradeStep, strategy opv1alpha1.KubernetesUpgradeRoleStrategy) (string, error) {
+	secrets, err := h.collect(s, step).Collect()
+	if plan.IsTransient(err) {
+		return "", err
+	} else if err != nil {
+		return fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err), nil
+	}
+
+	for _, secret := range secrets {
+		nodePlan, err := upgradePlan(s, secret, strategy.Drain)
+		if err != nil {
+			return "", err
+		}
+		preview.Add(string(step), secret, nodePlan, maxFailures, maxFailures)
+	}
+	return "", nil
+}
+
+// previewLeaderDrain renders the worker rollout of rolloutWithLeaderDrain into preview: for every
+// batch of maxUnavailable workers, the leader plan draining the batch and uncordoning the workers
+// upgraded so far, followed by the upgrade plans of the batch. The final leader plan uncordons
+// every worker. A non-empty message means no drain leader could be elected or the strategy is
+// invalid.
+func previewLeaderDrain(s *scope, preview *ops.PlanPreview, strategy opv1alpha1.KubernetesUpgradeRoleStrategy, secrets []*corev1.Secret) (string, error) {
+	leader, err := s.Adapter.PreviewLeader(ControllerOwnerKey, ops.And(ops.IsControlPlane, ops.Not(ops.IsWindows)))
+	if err != nil {
+		return "", err
+	} else if leader == nil {
+		return "no suitable control-plane leader found to drain worker nodes", nil
+	}
+
+	concurrency, err := concurrencyFor(strategy.MaxUnavailable, len(secrets))
+	if err != nil {
+		return fmt.Sprintf("invalid %s strategy: %v", opv1alpha1.KubernetesUpgradeStepWorker, err), nil
+	}
+
+	step := string(opv1alpha1.KubernetesUpgradeStepWorker)
+	for i := 0; i < len(secrets); i += concurrency {
+		candidates := secrets[i:min(i+concurrency, len(secrets))]
+
+		leaderPlan, err := drainPlan(s, leader, strategy.Drain, candidates, secrets[:i])
+		if err != nil {
+			return "", err
+		}
+		preview.Add(step, leader, leaderPlan, maxFailures, maxFailures)
+
+		for _, secret := range candidates {
+			nodePlan, err := upgradePlan(s, secret, nil)
+			if err != nil {
+				return "", err
+			}
+			preview.Add(step, secret, nodePlan, maxFailures, maxFailures)
+		}
+	}
+
+	leaderPlan, err := drainPlan(s, leader, strategy.Drain, nil, secrets)
+	if err != nil {
+		return "", err
+	}
+	preview.Add(step, leader, leaderPlan, maxFailures, maxFailures)
+	return "", nil
+}
+
+// failCollect marks the operation as failed after a terminal error collecting the machine-plan
+// secrets for the current step.
+func failCollect(s *scope, status *opv1alpha1.KubernetesUpgradeStatus, err error) {
+	logrus.Errorf("[kubernetesupgrade] %s/%s: marking operation as failed: encountered terminal error collecting machine-plan secrets: %v", s.Op.Namespace, s.Op.Name, err)
+	ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("encountered terminal error collecting machine-plan secrets: %v", err))
+}
+
+// failStrategy marks the operation as failed when the maxUnavailable of the strategy of step can
+// not be resolved, e.g. because the spec was changed after the Preflight step validated it.
+func failStrategy(s *scope, status *opv1alpha1.KubernetesUpgradeStatus, step opv1alpha1.KubernetesUpgradeStep, err error) {
+	logrus.Errorf("[kubernetesupgrade] %s/%s: marking operation as failed: invalid %s strategy: %v", s.Op.Namespace, s.Op.Name, step, err)
+	ops.Fail(&status.OperationStatus, opv1alpha1.PreflightCheckFailedReason, fmt.Sprintf("invalid %s strategy: %v", step, err))
+}
+
+// failPlan marks the operation as failed after the plan assigned to secret has failed.
+func failPlan(s *scope, status *opv1alpha1.KubernetesUpgradeStatus, secret *corev1.Secret) {
+	logrus.Errorf("[kubernetesupgrade] %s/%s: marking operation as failed: failed to apply plan for %s/%s", s.Op.Namespace, s.Op.Name, secret.Namespace, secret.Name)
+	ops.Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("kubernetes upgrade failed for %s/%s", secret.Namespace, secret.Name))
+}
+
+// role pairs a rollout step with the strategy requested for it.
+type role struct {
+	step     opv1alpha1.KubernetesUpgradeStep
+	strategy opv1alpha1.KubernetesUpgradeRoleStrategy
+}
+
+// roles returns the rollout steps of the operation in the order they are reconciled.
+func roles(s *scope) []role {
+	return []role{
+		{opv1alpha1.KubernetesUpgradeStepEtcd, s.Op.Spec.Args.Etcd},
+		{opv1alpha1.KubernetesUpgradeStepControlPlane, s.Op.Spec.Args.ControlPlane},
+		{opv1alpha1.KubernetesUpgradeStepWorker, s.Op.Spec.Args.Worker},
+	}
+}
+
+// collect returns a Collector for the machine-plan secrets upgraded by the given step, sorted with
+// plan.DefaultSorter. Each node is only collected by the first step that matches one of its roles.
+func (h *handler) collect(s *scope, step opv1alpha1.KubernetesUpgradeStep) *plan.Collector {
+	collector := plan.NewCachedCollector(h.secretCache, s.Cluster, s.Namespace).
+		WithSorter(plan.DefaultSorter())
+
+	switch step {
+	case opv1alpha1.KubernetesUpgradeStepEtcd:
+		collector = collector.WithLabels(plan.Label(capr.EtcdRoleLabel, "true"))
+	case opv1alpha1.KubernetesUpgradeStepControlPlane:
+		collector = collector.WithLabels(plan.Label(capr.ControlPlaneRoleLabel, "true")).
+			WithFilter(plan.FilterFunc(ops.Not(ops.IsEtcd)))
+	default:
+		collector = collector.WithLabels(plan.Label(capr.WorkerRoleLabel, "true")).
+			WithFilter(plan.FilterFunc(ops.And(ops.Not(ops.Or(ops.IsEtcd, ops.IsControlPlane)), ops.Not(ops.IsWindows))))
+	}
+
+	return collector
+}
+
+// upgradePlan builds the plan that installs the requested version on a single node. When drain is
+// set, the node drains itself with its own admin kubeconfig before the install and uncordons
+// itself afterwards; this is only valid for server nodes.
+func upgradePlan(s *scope, secret *corev1.Secret, drain *opv1alpha1.KubernetesUpgradeDrainOptions) (*plan.Plan, error) {
+	server := ops.IsEtcd(secret) || ops.IsControlPlane(secret)
+
+	probes, err := s.Adapter.RenderProbes(secret, server)
+	if err != nil {
+		return nil, err
+	}
+
+	nodePlan := &plan.Plan{
+		Probes: probes,
+	}
+
+	if drain == nil {
+		nodePlan.OneTimeInstructions = []plan.OneTimeInstruction{installInstruction(s, secret)}
+		return nodePlan, nil
+	}
+
+	var (
+		provisioningDir = s.Adapter.ProvisioningDataDirectory(secret)
+		kubectl         = s.Adapter.KubectlPath(secret)
+		kubeconfig      = s.Adapter.KubeconfigPath(secret)
+		nodeName        = secret.Labels[capr.NodeNameLabel]
+	)
+
+	nodePlan.Files = []plan.File{ops.IdempotentScriptFile(provisioningDir)}
+	nodePlan.OneTimeInstructions = []plan.OneTimeInstruction{
+		ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/drain", s.IdempotencyValue(), kubectl,
+			drainArgs(kubeconfig, drain, nodeName), nil),
+		installInstruction(s, secret),
+		uncordonInstruction(s, provisioningDir, "uncordon", kubectl, kubeconfig, nodeName),
+	}
+
+	return nodePlan, nil
+}
+
+// drainPlan builds the plan handed to the leader while workers are upgraded: drain every
+// candidate, then uncordon every worker that has completed its upgrade.
+func drainPlan(s *scope, leader *corev1.Secret, drain *opv1alpha1.KubernetesUpgradeDrainOptions, candidates, completed []*corev1.Secret) (*plan.Plan, error) {
+	probes, err := s.Adapter.RenderProbes(leader, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		provisioningDir = s.Adapter.ProvisioningDataDirectory(leader)
+		kubectl         = s.Adapter.KubectlPath(leader)
+		kubeconfig      = s.Adapter.KubeconfigPath(leader)
+	)
+
+	instructions := make([]plan.OneTimeInstruction, 0, len(candidates)+len(completed))
+	for _, secret := range candidates {
+		nodeName := secret.Labels[capr.NodeNameLabel]
+		instructions = append(instructions, ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/drain-"+nodeName,
+			s.IdempotencyValue(), kubectl, drainArgs(kubeconfig, drain, nodeName), nil))
+	}
+	for _, secret := range completed {
+		nodeName := secret.Labels[capr.NodeNameLabel]
+		instructions = append(instructions, uncordonInstruction(s, provisioningDir, "uncordon-"+nodeName, kubectl, kubeconfig, nodeName))
+	}
+
+	return &plan.Plan{
+		Files:               []plan.File{ops.IdempotentScriptFile(provisioningDir)},
+		OneTimeInstructions: instructions,
+		Probes:              probes,
+	}, nil
+}
+
+// installInstruction returns the instruction running the installer image for the requested
+// version. The restart stamp is derived from the operation UID and version, so the distro service
+// is restarted exactly once per operation even if the same version is already installed.
+func installInstruction(s *scope, secret *corev1.Secret) plan.OneTimeInstruction {
+	version := s.Op.Spec.Args.KubernetesVersion
+
+	image := s.Op.Spec.Args.InstallerImage
+	if image == "" {
+		image = s.Adapter.InstallerImage(version)
+	}
+
+	env := []string{
+		fmt.Sprintf("%s_DATA_DIR=%s", capr.GetRuntimeEnv(version), s.Adapter.DistroDataDirectory(secret)),
+		"RESTART_STAMP=" + plan.PlanHash([]byte(s.IdempotencyValue()+version)),
+	}
+	if !ops.IsEtcd(secret) && !ops.IsControlPlane(secret) {
+		env = append(env, fmt.Sprintf("INSTALL_%s_EXEC=agent", capr.GetRuntimeEnv(version)))
+	}
+
+	return plan.OneTimeInstruction{
+		CommonInstruction: plan.CommonInstruction{
+			Name:    "install",
+			Image:   image,
+			Command: "sh",
+			Args:    []string{"-c", "run.sh"},
+			Env:     env,
+		},
+	}
+}
+
+// uncordonInstruction returns an instruction that uncordons nodeName. The apiserver may still be
+// restarting right after an upgrade, so the uncordon is retried for up to a minute before the
+// instruction fails. The instruction is keyed on the operation UID, so a plan that is re-delivered
+// or re-rendered never uncordons a node that was cordoned again after the upgrade.
+func uncordonInstruction(s *scope, provisioningDir, key, kubectl, kubeconfig, nodeName string) plan.OneTimeInstruction {
+	return ops.IdempotentInstruction(provisioningDir, idempotencyKey+"/"+key, s.IdempotencyValue(), "/bin/sh",
+		[]string{
+			"-c",
+			fmt.Sprintf("for i in $(seq 1 12); do %s --kubeconfig %s uncordon %s && exit 0; sleep 5; done; exit 1", kubectl, kubeconfig, nodeName),
+		}, nil)
+}
+
+func drainArgs(kubeconfig string, opts *opv1alpha1.KubernetesUpgradeDrainOptions, nodes ...string) []string {
+	args := append([]string{"--kubeconfig", kubeconfig, "drain"}, nodes...)
+	args = append(args, "--ignore-daemonsets="+strconv.FormatBool(ptr.Deref(opts.IgnoreDaemonSets, true)))
+	if opts.Force {
+		args = append(args, "--force")
+	}
+	if opts.DeleteEmptyDirData {
+		args = append(args, "--delete-emptydir-data")
+	}
+	if opts.DisableEviction {
+		args = append(args, "--disable-eviction")
+	}
+	if opts.GracePeriod > 0 {
+		args = append(args, fmt.Sprintf("--grace-period=%d", opts.GracePeriod))
+	}
+	if opts.Timeout > 0 {
+		args = append(args, fmt.Sprintf("--timeout=%ds", opts.Timeout))
+	}
+	return args
+}
+
+// isAssigned reports whether nodePlan is the plan currently assigned to secret.
+func isAssigned(secret *corev1.Secret, nodePlan *plan.Plan) (bool, error) {
+	data, err := json.Marshal(&nodePlan)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(secret.Data["plan"], data), nil
+}
+
+// concurrencyFor resolves maxUnavailable against the number of nodes of a role. Percentages are
+// rounded up, and the result is never less than 1 so a rollout always makes progress.
+func concurrencyFor(maxUnavailable string, count int) (int, error) {
+	if maxUnavailable == "" {
+		return 1, nil
+	}
+
+	if percent, ok := strings.CutSuffix(maxUnavailable, "%"); ok {
+		p, err := strconv.Atoi(percent)
+		if err != nil || p < 1 || p > 100 {
+			return 0, fmt.Errorf("invalid maxUnavailable %q: percentage must be between 1%% and 100%%", maxUnavailable)
+		}
+		return max(int(math.Ceil(float64(count*p)/100)), 1), nil
+	}
+
+	n, err := strconv.Atoi(maxUnavailable)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid maxUnavailable %q: must be a positive integer or a percentage", maxUnavailable)
+	}
+	return n, nil
+}
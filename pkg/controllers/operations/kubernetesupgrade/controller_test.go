@@ -0,0 +1,557 @@
+package kubernetesupgrade
+
+import (
+	"encoding/json"
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/capr"
+	ops "github.com/rancher/rancher/pkg/operations"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+// stubAdapter is a minimal ops.VersionedAdapter implementation for tests. PauseCluster and
+// SetKubernetesVersion record every call so tests can assert the cluster is only moved to the new
+// version once every node has been upgraded.
+type stubAdapter struct {
+	runtimeCommand    string
+	dataDir           string
+	provisioningDir   string
+	serverUnit        string
+	waitForRegisterOK bool
+	probes            map[string]planapi.Probe
+	pauses            []bool
+	versions          []string
+	leader            *corev1.Secret
+}
+
+func (a *stubAdapter) BeaconRef() (string, string)   { return "test-namespace", "test-cluster" }
+func (a *stubAdapter) EtcdSnapshotNamespace() string { return "test-namespace" }
+func (a *stubAdapter) ClusterObject() (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+func (a *stubAdapter) WaitForRegister() (bool, error) { return a.waitForRegisterOK, nil }
+func (a *stubAdapter) PauseCluster(pause bool) error {
+	a.pauses = append(a.pauses, pause)
+	return nil
+}
+func (a *stubAdapter) SetKubernetesVersion(version string) error {
+	a.versions = append(a.versions, version)
+	return nil
+}
+func (a *stubAdapter) RuntimeCommand() string                            { return a.runtimeCommand }
+func (a *stubAdapter) DistroDataDirectory(_ *corev1.Secret) string       { return a.dataDir }
+func (a *stubAdapter) ProvisioningDataDirectory(_ *corev1.Secret) string { return a.provisioningDir }
+func (a *stubAdapter) ServerUnit() string                                { return a.serverUnit }
+func (a *stubAdapter) AgentUnit() string                                 { return a.runtimeCommand + "-agent" }
+func (a *stubAdapter) InstallerImage(v string) string                    { return "installer:" + v }
+func (a *stubAdapter) RenderProbes(_ *corev1.Secret, _ bool) (map[string]planapi.Probe, error) {
+	return a.probes, nil
+}
+func (a *stubAdapter) KubectlPath(_ *corev1.Secret) string { return a.dataDir + "/bin/kubectl" }
+func (a *stubAdapter) KubeconfigPath(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/" + a.runtimeCommand + ".yaml"
+}
+func (a *stubAdapter) FindOrElectLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return a.leader, nil
+}
+func (a *stubAdapter) PreviewLeader(_ string, _ ops.Filter) (*corev1.Secret, error) {
+	return a.leader, nil
+}
+func (a *stubAdapter) ConfigFile(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/config.yaml"
+}
+func (a *stubAdapter) ConfigDirectory(_ *corev1.Secret) string {
+	return "/etc/rancher/" + a.runtimeCommand + "/config.yaml.d"
+}
+func (a *stubAdapter) GetServerURL(_ *corev1.Secret) string      { return "" }
+func (a *stubAdapter) GetSupervisorPort(_ *corev1.Secret) string { return "9345" }
+func (a *stubAdapter) LoopbackAddress(_ *corev1.Secret) string   { return "127.0.0.1" }
+func (a *stubAdapter) ToS3ArgsEnvAndFiles(_ *corev1.Secret) ([]string, []string, []planapi.File) {
+	return nil, nil, nil
+}
+
+func defaultAdapter() *stubAdapter {
+	return &stubAdapter{
+		runtimeCommand:  "rke2",
+		dataDir:         "/var/lib/rancher/rke2",
+		provisioningDir: "/var/lib/rancher/capr",
+		serverUnit:      "rke2-server",
+	}
+}
+
+func newScope(op *opv1alpha1.KubernetesUpgrade, adapter *stubAdapter) *scope {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetName("test")
+	cluster.SetNamespace("fleet-default")
+	cluster.SetAPIVersion("provisioning.cattle.io/v1")
+	cluster.SetKind("Cluster")
+	return &scope{
+		Op:        op,
+		OwnerKey:  planapi.ControllerOwnerKey(op, ControllerOwnerKey),
+		Namespace: "fleet-default",
+		Cluster:   cluster,
+		Adapter:   adapter,
+	}
+}
+
+func newOp() *opv1alpha1.KubernetesUpgrade {
+	return &opv1alpha1.KubernetesUpgrade{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "upgrade-1",
+			Namespace: "fleet-default",
+			UID:       "op-uid",
+		},
+		Spec: opv1alpha1.KubernetesUpgradeSpec{
+			Args: opv1alpha1.KubernetesUpgradeArgs{KubernetesVersion: "v1.33.1+rke2r1"},
+		},
+	}
+}
+
+// newPlanSecret builds a machine-plan secret for the test cluster carrying the given role labels
+// and a node name matching the secret name.
+func newPlanSecret(name string, roles ...string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "fleet-default",
+			UID:         types.UID(name + "-uid"),
+			Annotations: map[string]string{},
+			Labels: map[string]string{
+				capr.ClusterNameLabel: "test",
+				capr.NodeNameLabel:    name,
+			},
+		},
+		Type: planapi.SecretTypeMachinePlan,
+	}
+	for _, role := range roles {
+		secret.Labels[role] = "true"
+	}
+	return secret
+}
+
+func withAppliedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	if out.Data == nil {
+		out.Data = map[string][]byte{}
+	}
+	out.Data["plan"] = data
+	out.Data["appliedPlan"] = data
+	out.Data["probe-statuses"] = []byte(`{"x":{"healthy":true}}`)
+	out.Annotations[planapi.PlanProbesPassedAnnotation] = "applied"
+	return out
+}
+
+func withFailedPlan(secret *corev1.Secret, expectedPlan *planapi.Plan) *corev1.Secret {
+	out := secret.DeepCopy()
+	data, _ := json.Marshal(expectedPlan)
+	if out.Data == nil {
+		out.Data = map[string][]byte{}
+	}
+	out.Data["plan"] = data
+	out.Data["failed-checksum"] = []byte(planapi.PlanHash(data))
+	out.Data["failure-count"] = []byte("5")
+	out.Data["max-failures"] = []byte("5")
+	out.Data["failure-threshold"] = []byte("5")
+	return out
+}
+
+// newHandler returns a handler collecting the given machine-plan secrets from an indexed secret
+// cache. Every secret updated through its store is recorded in updated so tests can assert which
+// nodes received a plan.
+func newHandler(t *testing.T, ctrl *gomock.Controller, updated *[]string, items ...*corev1.Secret) *handler {
+	t.Helper()
+	m := ctrlfake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+	m.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		if updated != nil {
+			*updated = append(*updated, s.Name)
+		}
+		return s, nil
+	}).AnyTimes()
+	// Replacing a plan records it in a plan history secret, which does not exist yet.
+	m.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}).AnyTimes()
+	m.EXPECT().Create(gomock.Any()).DoAndReturn(func(s *corev1.Secret) (*corev1.Secret, error) {
+		return s, nil
+	}).AnyTimes()
+	return &handler{
+		secretCache: newSecretCache(t, items...),
+		store:       planapi.NewStore(m),
+	}
+}
+
+// newSecretCache returns a secret cache with the plan indexers registered, holding items.
+func newSecretCache(t *testing.T, items ...*corev1.Secret) corecontrollers.SecretCache {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := generic.NewCache[*corev1.Secret](indexer, corev1.Resource("secrets"))
+	planapi.RegisterIndexers(c)
+	for _, item := range items {
+		if err := indexer.Add(item); err != nil {
+			t.Fatalf("adding %s: %v", item.Name, err)
+		}
+	}
+	return c
+}
+
+func expectedUpgradePlan(t *testing.T, s *scope, secret *corev1.Secret, drain *opv1alpha1.KubernetesUpgradeDrainOptions) *planapi.Plan {
+	t.Helper()
+	p, err := upgradePlan(s, secret, drain)
+	assert.NoError(t, err)
+	return p
+}
+
+// --- definition -----------------------------------------------------------------------------
+
+func TestDefinition_StepTimeouts(t *testing.T) {
+	t.Parallel()
+
+	for _, step := range (&handler{}).definition().Steps {
+		assert.Positive(t, step.Timeout, "step %s must have a default timeout", step.Name)
+	}
+}
+
+// --- helpers --------------------------------------------------------------------------------
+
+func TestConcurrencyFor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		maxUnavailable string
+		count          int
+		want           int
+		wantErr        bool
+	}{
+		{"", 5, 1, false},
+		{"3", 5, 3, false},
+		{"10", 2, 10, false},
+		{"20%", 7, 2, false},
+		{"50%", 4, 2, false},
+		{"1%", 3, 1, false},
+		{"100%", 0, 1, false},
+		{"0", 5, 0, true},
+		{"0%", 5, 0, true},
+		{"101%", 5, 0, true},
+		{"-1", 5, 0, true},
+		{"abc", 5, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := concurrencyFor(tt.maxUnavailable, tt.count)
+		if tt.wantErr {
+			assert.Error(t, err, tt.maxUnavailable)
+			continue
+		}
+		assert.NoError(t, err, tt.maxUnavailable)
+		assert.Equal(t, tt.want, got, tt.maxUnavailable)
+	}
+}
+
+func TestDrainArgs(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		[]string{"--kubeconfig", "kc", "drain", "node-1", "--ignore-daemonsets=true"},
+		drainArgs("kc", &opv1alpha1.KubernetesUpgradeDrainOptions{}, "node-1"))
+
+	assert.Equal(t,
+		[]string{"--kubeconfig", "kc", "drain", "node-1", "--ignore-daemonsets=false", "--force", "--delete-emptydir-data",
+			"--disable-eviction", "--grace-period=30", "--timeout=120s"},
+		drainArgs("kc", &opv1alpha1.KubernetesUpgradeDrainOptions{
+			Force:              true,
+			IgnoreDaemonSets:   ptr.To(false),
+			DeleteEmptyDirData: true,
+			DisableEviction:    true,
+			GracePeriod:        30,
+			Timeout:            120,
+		}, "node-1"))
+}
+
+func TestUpgradePlan(t *testing.T) {
+	t.Parallel()
+
+	s := newScope(newOp(), defaultAdapter())
+
+	worker := expectedUpgradePlan(t, s, newPlanSecret("worker-1", capr.WorkerRoleLabel), nil)
+	if assert.Len(t, worker.OneTimeInstructions, 1) {
+		install := worker.OneTimeInstructions[0]
+		assert.Equal(t, "installer:v1.33.1+rke2r1", install.Image)
+		assert.Contains(t, install.Env, "RKE2_DATA_DIR=/var/lib/rancher/rke2")
+		assert.Contains(t, install.Env, "INSTALL_RKE2_EXEC=agent")
+	}
+	assert.Empty(t, worker.Files)
+
+	s.Op.Spec.Args.InstallerImage = "custom:tag"
+	server := expectedUpgradePlan(t, s, newPlanSecret("cp-1", capr.ControlPlaneRoleLabel), &opv1alpha1.KubernetesUpgradeDrainOptions{})
+	if assert.Len(t, server.OneTimeInstructions, 3) {
+		drain := server.OneTimeInstructions[0]
+		// Args: -x <script> <key> <hashedValue> <hashedCommand> <command> <provisioningDir> <args...>
+		assert.Equal(t, "kubernetes-upgrade/drain", drain.Args[2])
+		assert.Equal(t, append([]string{"/var/lib/rancher/rke2/bin/kubectl"}, drainArgs("/etc/rancher/rke2/rke2.yaml", &opv1alpha1.KubernetesUpgradeDrainOptions{}, "cp-1")...),
+			append([]string{drain.Args[5]}, drain.Args[7:]...))
+
+		install := server.OneTimeInstructions[1]
+		assert.Equal(t, "custom:tag", install.Image)
+		assert.NotContains(t, install.Env, "INSTALL_RKE2_EXEC=agent")
+
+		uncordon := server.OneTimeInstructions[2]
+		assert.Equal(t, "kubernetes-upgrade/uncordon", uncordon.Args[2], "uncordon must only run once per operation")
+		assert.Equal(t, drain.Args[3], uncordon.Args[3], "uncordon must be keyed on the operation UID")
+	}
+	assert.Len(t, server.Files, 1)
+}
+
+func TestDrainPlan_UncordonIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	s := newScope(newOp(), defaultAdapter())
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	done := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+	done.Labels[capr.NodeNameLabel] = "worker-1"
+	next := newPlanSecret("worker-2", capr.WorkerRoleLabel)
+	next.Labels[capr.NodeNameLabel] = "worker-2"
+
+	first, err := drainPlan(s, cp, &opv1alpha1.KubernetesUpgradeDrainOptions{}, nil, []*corev1.Secret{done})
+	assert.NoError(t, err)
+	second, err := drainPlan(s, cp, &opv1alpha1.KubernetesUpgradeDrainOptions{}, []*corev1.Secret{next}, []*corev1.Secret{done})
+	assert.NoError(t, err)
+
+	// The leader plan is re-rendered for every batch; the uncordon of a completed worker must be the
+	// same idempotent instruction every time so it never runs twice.
+	uncordon := first.OneTimeInstructions[0]
+	assert.Equal(t, "kubernetes-upgrade/uncordon-worker-1", uncordon.Args[2])
+	assert.Equal(t, uncordon, second.OneTimeInstructions[1])
+}
+
+// --- step reconcilers -----------------------------------------------------------------------
+
+func TestReconcilePreflight_RuntimeMismatchFails(t *testing.T) {
+	t.Parallel()
+
+	op := newOp()
+	op.Spec.Args.KubernetesVersion = "v1.33.1+k3s1"
+
+	h := &handler{}
+	status := opv1alpha1.KubernetesUpgradeStatus{}
+	done, err := h.reconcilePreflight(newScope(op, defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcilePreflight_InvalidMaxUnavailableFails(t *testing.T) {
+	t.Parallel()
+
+	op := newOp()
+	op.Spec.Args.Worker.MaxUnavailable = "0"
+
+	h := &handler{}
+	status := opv1alpha1.KubernetesUpgradeStatus{}
+	done, err := h.reconcilePreflight(newScope(op, defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcilePreflight_DrainWithoutNodeNameFails(t *testing.T) {
+	t.Parallel()
+
+	worker := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+	delete(worker.Labels, capr.NodeNameLabel)
+
+	ctrl := gomock.NewController(t)
+	h := newHandler(t, ctrl, nil, worker)
+
+	op := newOp()
+	op.Spec.Args.Worker.Drain = &opv1alpha1.KubernetesUpgradeDrainOptions{}
+
+	status := opv1alpha1.KubernetesUpgradeStatus{}
+	done, err := h.reconcilePreflight(newScope(op, defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcilePreflight_Completes(t *testing.T) {
+	t.Parallel()
+
+	h := &handler{}
+	status := opv1alpha1.KubernetesUpgradeStatus{}
+	done, err := h.reconcilePreflight(newScope(newOp(), defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, string(status.Phase))
+}
+
+func TestReconcileEtcd_RespectsMaxUnavailable(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	a := defaultAdapter()
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		newPlanSecret("etcd-3", capr.EtcdRoleLabel),
+		newPlanSecret("etcd-2", capr.EtcdRoleLabel),
+		newPlanSecret("etcd-1", capr.EtcdRoleLabel, capr.InitNodeLabel),
+		newPlanSecret("cp-1", capr.ControlPlaneRoleLabel),
+	)
+
+	op := newOp()
+	op.Spec.Args.Etcd.MaxUnavailable = "2"
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepEtcd}
+	done, err := h.reconcileEtcd(newScope(op, a), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Empty(t, a.versions, "the cluster must keep its version until every node is upgraded")
+	assert.Len(t, updated, 2, "at most maxUnavailable nodes may receive a plan")
+	assert.Equal(t, "etcd-1", updated[0], "the init node must be upgraded first")
+	assert.NotContains(t, updated, "cp-1")
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+}
+
+func TestReconcileControlPlane_CompletesWhenApplied(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	s := newScope(newOp(), defaultAdapter())
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	h := newHandler(t, ctrl, nil,
+		withAppliedPlan(cp, expectedUpgradePlan(t, s, cp, nil)),
+		newPlanSecret("etcd-1", capr.EtcdRoleLabel, capr.ControlPlaneRoleLabel),
+	)
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepControlPlane}
+	done, err := h.reconcileControlPlane(s, &status)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, string(status.Phase))
+}
+
+func TestReconcileControlPlane_PlanFailureMarksFailed(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	s := newScope(newOp(), defaultAdapter())
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	h := newHandler(t, ctrl, nil, withFailedPlan(cp, expectedUpgradePlan(t, s, cp, nil)))
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepControlPlane}
+	done, err := h.reconcileControlPlane(s, &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PlanFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcileControlPlane_InvalidMaxUnavailableFails(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	h := newHandler(t, ctrl, nil, newPlanSecret("cp-1", capr.ControlPlaneRoleLabel))
+
+	op := newOp()
+	op.Spec.Args.ControlPlane.MaxUnavailable = "abc"
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepControlPlane}
+	done, err := h.reconcileControlPlane(newScope(op, defaultAdapter()), &status)
+	assert.NoError(t, err, "an invalid strategy must fail the operation rather than be retried")
+	assert.False(t, done)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.PreflightCheckFailedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestReconcileWorker_CompletesWithoutWorkers(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	a := defaultAdapter()
+	var updated []string
+	h := newHandler(t, ctrl, &updated,
+		newPlanSecret("cp-1", capr.ControlPlaneRoleLabel, capr.WorkerRoleLabel),
+	)
+
+	op := newOp()
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepWorker}
+	done, err := h.reconcileWorker(newScope(op, a), &status)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, updated, "server nodes were already upgraded in the previous steps")
+	assert.Equal(t, []string{op.Spec.Args.KubernetesVersion}, a.versions, "the cluster must be moved to the new version so the planner does not roll nodes back")
+}
+
+func TestReconcileWorker_LeaderDrainsBeforeUpgrade(t *testing.T) {
+	t.Parallel()
+
+	drain := &opv1alpha1.KubernetesUpgradeDrainOptions{}
+	op := newOp()
+	op.Spec.Args.Worker.Drain = drain
+
+	cp := newPlanSecret("cp-1", capr.ControlPlaneRoleLabel)
+	worker := newPlanSecret("worker-1", capr.WorkerRoleLabel)
+
+	// The leader has not drained the worker yet: only the leader may receive a plan.
+	ctrl := gomock.NewController(t)
+	a := defaultAdapter()
+	a.leader = cp
+	var updated []string
+	h := newHandler(t, ctrl, &updated, cp, worker)
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepWorker}
+	done, err := h.reconcileWorker(newScope(op, a), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []string{"cp-1"}, updated)
+	assert.Equal(t, opv1alpha1.WaitingForPlanAppliedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+
+	// Once the leader has drained the worker, the worker is handed its upgrade plan.
+	s := newScope(op, a)
+	leaderPlan, err := drainPlan(s, cp, drain, []*corev1.Secret{worker}, nil)
+	assert.NoError(t, err)
+	a.leader = withAppliedPlan(cp, leaderPlan)
+
+	updated = nil
+	h = newHandler(t, ctrl, &updated, a.leader, worker)
+
+	status = opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepWorker}
+	done, err = h.reconcileWorker(s, &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []string{"worker-1"}, updated)
+	assert.Empty(t, string(status.Phase))
+	assert.Empty(t, a.versions, "the cluster must keep its version until every node is upgraded")
+}
+
+func TestReconcileWorker_WaitsForLeader(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	var updated []string
+	h := newHandler(t, ctrl, &updated, newPlanSecret("worker-1", capr.WorkerRoleLabel))
+
+	op := newOp()
+	op.Spec.Args.Worker.Drain = &opv1alpha1.KubernetesUpgradeDrainOptions{}
+
+	status := opv1alpha1.KubernetesUpgradeStatus{Step: opv1alpha1.KubernetesUpgradeStepWorker}
+	done, err := h.reconcileWorker(newScope(op, defaultAdapter()), &status)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Empty(t, updated)
+	assert.Equal(t, opv1alpha1.WaitingForSuitableLeaderReason, opv1alpha1.InProgressCondition.GetReason(&status))
+}
@@ -0,0 +1,134 @@
+package operationset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// errNotOwned is returned by children.Create when the operation already exists and is not owned by
+// the set creating it.
+var errNotOwned = errors.New("operation already exists and is not owned by the set")
+
+// children creates and observes the operations of a single kind on behalf of OperationSets.
+type children interface {
+	// Create creates the named operation in the namespace of set, owned by set, from the raw spec of
+	// the template, targeting the cluster referenced by clusterRef. An operation that already exists
+	// is only accepted if set owns it; otherwise an error wrapping errNotOwned is returned.
+	Create(set *opv1alpha1.OperationSet, name string, labels map[string]string, clusterRef *corev1.ObjectReference, spec []byte) error
+
+	// Phases returns the phases of the operations created by the named set, keyed by operation name.
+	Phases(namespace, set string) (map[string]opv1alpha1.OperationPhase, error)
+}
+
+// operationController is the subset of the generated operation controller children needs.
+type operationController[T ops.Object] interface {
+	ops.OperationWatcher[T]
+	Create(op T) (T, error)
+	Get(namespace, name string, options metav1.GetOptions) (T, error)
+}
+
+// operationChildren implements children for the operation kind T.
+type operationChildren[T ops.Object] struct {
+	operations operationController[T]
+	cache      generic.CacheInterface[T]
+	newOp      func() T
+}
+
+// watchChildren returns the children of the operation kind T, and registers a handler enqueuing the
+// set that created an operation of the kind whenever it changes.
+func watchChildren[T ops.Object](ctx context.Context, operations operationController[T], cache generic.CacheInterface[T], sets operationcontrollers.OperationSetController, kind string, newOp func() T) children {
+	operations.OnChange(ctx, "operation-set-"+kind, func(_ string, op T) (T, error) {
+		if isNilObject(op) {
+			return op, nil
+		}
+		if set := op.GetLabels()[opv1alpha1.OperationSetLabel]; set != "" {
+			sets.Enqueue(op.GetNamespace(), set)
+		}
+		return op, nil
+	})
+	return &operationChildren[T]{
+		operations: operations,
+		cache:      cache,
+		newOp:      newOp,
+	}
+}
+
+func (c *operationChildren[T]) Create(set *opv1alpha1.OperationSet, name string, labels map[string]string, clusterRef *corev1.ObjectReference, spec []byte) error {
+	obj := map[string]any{}
+	if len(spec) > 0 {
+		if err := json.Unmarshal(spec, &obj); err != nil {
+			return err
+		}
+	}
+	ref, err := runtime.DefaultUnstructuredConverter.ToUnstructured(clusterRef)
+	if err != nil {
+		return err
+	}
+	obj["clusterRef"] = ref
+
+	op := c.newOp()
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(map[string]any{"spec": obj}, op); err != nil {
+		return err
+	}
+	op.SetNamespace(set.Namespace)
+	op.SetName(name)
+	op.SetLabels(labels)
+	op.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(set, opv1alpha1.SchemeGroupVersion.WithKind("OperationSet"))})
+
+	_, err = c.operations.Create(op)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// The set may have created the operation in an earlier sync whose status update was lost.
+	// Operations created by anyone else must not be adopted.
+	existing, err := c.operations.Get(set.Namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, set) {
+		return fmt.Errorf("%s/%s: %w", set.Namespace, name, errNotOwned)
+	}
+	return nil
+}
+
+func (c *operationChildren[T]) Phases(namespace, set string) (map[string]opv1alpha1.OperationPhase, error) {
+	list, err := c.cache.List(namespace, labels.SelectorFromSet(labels.Set{opv1alpha1.OperationSetLabel: set}))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]opv1alpha1.OperationPhase, len(list))
+	for _, op := range list {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(op)
+		if err != nil {
+			return nil, err
+		}
+		phase, _, _ := unstructured.NestedString(obj, "status", "phase")
+		result[op.GetName()] = opv1alpha1.OperationPhase(phase)
+	}
+	return result, nil
+}
+
+// isNilObject reports whether obj is nil, as passed to handlers for deleted objects.
+func isNilObject(obj ops.Object) bool {
+	if obj == nil {
+		return true
+	}
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
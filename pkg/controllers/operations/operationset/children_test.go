@@ -0,0 +1,69 @@
+package operationset
+
+import (
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newChildren(t *testing.T) (*operationChildren[*opv1alpha1.ETCDSnapshotSave], *ctrlfake.MockControllerInterface[*opv1alpha1.ETCDSnapshotSave, *opv1alpha1.ETCDSnapshotSaveList]) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	operations := ctrlfake.NewMockControllerInterface[*opv1alpha1.ETCDSnapshotSave, *opv1alpha1.ETCDSnapshotSaveList](ctrl)
+	return &operationChildren[*opv1alpha1.ETCDSnapshotSave]{
+		operations: operations,
+		newOp:      func() *opv1alpha1.ETCDSnapshotSave { return &opv1alpha1.ETCDSnapshotSave{} },
+	}, operations
+}
+
+func TestCreate_OwnedBySet(t *testing.T) {
+	c, operations := newChildren(t)
+	set := newSet(1, 0)
+	set.UID = "set-uid"
+
+	var created *opv1alpha1.ETCDSnapshotSave
+	operations.EXPECT().Create(gomock.Any()).DoAndReturn(func(op *opv1alpha1.ETCDSnapshotSave) (*opv1alpha1.ETCDSnapshotSave, error) {
+		created = op
+		return op, nil
+	})
+
+	err := c.Create(set, "snapshots-c1", map[string]string{opv1alpha1.OperationSetLabel: set.Name}, &corev1.ObjectReference{Name: "c1"}, []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, set.Namespace, created.Namespace)
+	assert.Equal(t, "c1", created.Spec.ClusterRef.Name)
+	assert.True(t, metav1.IsControlledBy(created, set), "the operation must be owned by the set")
+}
+
+func TestCreate_AlreadyExists(t *testing.T) {
+	set := newSet(1, 0)
+	set.UID = "set-uid"
+	alreadyExists := apierrors.NewAlreadyExists(opv1alpha1.Resource("etcdsnapshotsaves"), "snapshots-c1")
+
+	t.Run("owned by the set", func(t *testing.T) {
+		c, operations := newChildren(t)
+		existing := &opv1alpha1.ETCDSnapshotSave{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       set.Namespace,
+			Name:            "snapshots-c1",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(set, opv1alpha1.SchemeGroupVersion.WithKind("OperationSet"))},
+		}}
+		operations.EXPECT().Create(gomock.Any()).Return(nil, alreadyExists)
+		operations.EXPECT().Get(set.Namespace, "snapshots-c1", gomock.Any()).Return(existing, nil)
+
+		assert.NoError(t, c.Create(set, "snapshots-c1", nil, &corev1.ObjectReference{Name: "c1"}, nil))
+	})
+
+	t.Run("not owned by the set", func(t *testing.T) {
+		c, operations := newChildren(t)
+		existing := &opv1alpha1.ETCDSnapshotSave{ObjectMeta: metav1.ObjectMeta{Namespace: set.Namespace, Name: "snapshots-c1"}}
+		operations.EXPECT().Create(gomock.Any()).Return(nil, alreadyExists)
+		operations.EXPECT().Get(set.Namespace, "snapshots-c1", gomock.Any()).Return(existing, nil)
+
+		assert.ErrorIs(t, c.Create(set, "snapshots-c1", nil, &corev1.ObjectReference{Name: "c1"}, nil), errNotOwned)
+	})
+}
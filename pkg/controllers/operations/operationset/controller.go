@@ -0,0 +1,307 @@
+package operationset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"time"
+
+	mgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	operationcontrollers "github.com/rancher/rancher/pkg/generated/controllers/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// resyncInterval is how often a running set is re-examined, so that operations deleted while
+// running are noticed even though deletions do not enqueue the set.
+const resyncInterval = 30 * time.Second
+
+type handler struct {
+	operationSets operationcontrollers.OperationSetController
+	clusters      mgmtcontrollers.ClusterCache
+	records       generic.CacheInterface[*opv1alpha1.OperationRecord]
+
+	// kinds are the operation kinds a set can create, keyed by kind.
+	kinds map[string]children
+
+	newAdapter func(ustr *unstructured.Unstructured) (ops.Adapter, error)
+}
+
+// Register wires the OperationSet controller into the given wrangler context. It must be called
+// exactly once per process; subsequent calls would clobber the registered status handler.
+func Register(ctx context.Context, clients *wrangler.CAPIContext) {
+	sets := clients.Operation.OperationSet()
+	h := &handler{
+		operationSets: sets,
+		clusters:      clients.Mgmt.Cluster().Cache(),
+		records:       clients.Operation.OperationRecord().Cache(),
+		kinds: map[string]children{
+			"CertificateRotation": watchChildren(ctx, clients.Operation.CertificateRotation(), clients.Operation.CertificateRotation().Cache(), sets, "CertificateRotation",
+				func() *opv1alpha1.CertificateRotation { return &opv1alpha1.CertificateRotation{} }),
+			"EncryptionKeyRotation": watchChildren(ctx, clients.Operation.EncryptionKeyRotation(), clients.Operation.EncryptionKeyRotation().Cache(), sets, "EncryptionKeyRotation",
+				func() *opv1alpha1.EncryptionKeyRotation { return &opv1alpha1.EncryptionKeyRotation{} }),
+			"ETCDSnapshotSave": watchChildren(ctx, clients.Operation.ETCDSnapshotSave(), clients.Operation.ETCDSnapshotSave().Cache(), sets, "ETCDSnapshotSave",
+				func() *opv1alpha1.ETCDSnapshotSave { return &opv1alpha1.ETCDSnapshotSave{} }),
+			"ETCDSnapshotRestore": watchChildren(ctx, clients.Operation.ETCDSnapshotRestore(), clients.Operation.ETCDSnapshotRestore().Cache(), sets, "ETCDSnapshotRestore",
+				func() *opv1alpha1.ETCDSnapshotRestore { return &opv1alpha1.ETCDSnapshotRestore{} }),
+			"KubernetesUpgrade": watchChildren(ctx, clients.Operation.KubernetesUpgrade(), clients.Operation.KubernetesUpgrade().Cache(), sets, "KubernetesUpgrade",
+				func() *opv1alpha1.KubernetesUpgrade { return &opv1alpha1.KubernetesUpgrade{} }),
+			"CustomOperation": watchChildren(ctx, clients.Operation.CustomOperation(), clients.Operation.CustomOperation().Cache(), sets, "CustomOperation",
+				func() *opv1alpha1.CustomOperation { return &opv1alpha1.CustomOperation{} }),
+		},
+		newAdapter: func(ustr *unstructured.Unstructured) (ops.Adapter, error) {
+			return ops.NewAdapter(clients, ustr)
+		},
+	}
+
+	operationcontrollers.RegisterOperationSetStatusHandler(ctx, sets, "", "operation-set-handler", h.OnChange)
+}
+
+func (h *handler) OnChange(set *opv1alpha1.OperationSet, status opv1alpha1.OperationSetStatus) (opv1alpha1.OperationSetStatus, error) {
+	if set == nil || set.DeletionTimestamp != nil {
+		return status, nil
+	}
+	status.ObservedGeneration = set.Generation
+
+	kind, ok := h.kinds[set.Spec.Template.Kind]
+	if !ok {
+		logrus.Errorf("[operationset] %s/%s: unsupported operation kind %q", set.Namespace, set.Name, set.Spec.Template.Kind)
+		markFailed(&status, opv1alpha1.InvalidOperationSetReason, fmt.Sprintf("unsupported operation kind %q", set.Spec.Template.Kind))
+		return status, nil
+	}
+
+	var err error
+	switch status.Phase {
+	case "", opv1alpha1.OperationPhasePending:
+		status.SetPhase(opv1alpha1.OperationPhasePending)
+		status, err = h.selectClusters(set, status)
+		if err != nil || status.Phase != opv1alpha1.OperationPhaseInProgress {
+			return status, err
+		}
+		fallthrough
+	case opv1alpha1.OperationPhaseInProgress:
+		status, err = h.reconcile(set, kind, status)
+		if err != nil {
+			return status, err
+		}
+		if status.Phase == opv1alpha1.OperationPhaseInProgress {
+			h.operationSets.EnqueueAfter(set.Namespace, set.Name, resyncInterval)
+		}
+	}
+	return status, nil
+}
+
+// selectClusters records the management clusters matching the cluster selector of the set as its
+// targets and moves the set to InProgress. Clusters no adapter is registered for are skipped, as
+// no operation can run against them.
+func (h *handler) selectClusters(set *opv1alpha1.OperationSet, status opv1alpha1.OperationSetStatus) (opv1alpha1.OperationSetStatus, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&set.Spec.ClusterSelector)
+	if err != nil {
+		logrus.Errorf("[operationset] %s/%s: invalid cluster selector: %v", set.Namespace, set.Name, err)
+		markFailed(&status, opv1alpha1.InvalidOperationSetReason, fmt.Sprintf("invalid cluster selector: %v", err))
+		return status, nil
+	}
+
+	clusters, err := h.clusters.List(selector)
+	if err != nil {
+		return status, err
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	var targets []opv1alpha1.OperationSetCluster
+	for _, cluster := range clusters {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cluster)
+		if err != nil {
+			return status, err
+		}
+		ustr := &unstructured.Unstructured{Object: obj}
+		ustr.SetAPIVersion(mgmtv3.SchemeGroupVersion.String())
+		ustr.SetKind("Cluster")
+
+		if _, err := h.newAdapter(ustr); err != nil {
+			logrus.Infof("[operationset] %s/%s: skipping cluster %s: %v", set.Namespace, set.Name, cluster.Name, err)
+			continue
+		}
+		targets = append(targets, opv1alpha1.OperationSetCluster{Name: cluster.Name})
+	}
+
+	if len(targets) == 0 {
+		logrus.Errorf("[operationset] %s/%s: cluster selector matches no supported cluster", set.Namespace, set.Name)
+		markFailed(&status, opv1alpha1.NoClustersSelectedReason, "cluster selector matches no supported cluster")
+		return status, nil
+	}
+
+	logrus.Infof("[operationset] %s/%s: selected %d clusters", set.Namespace, set.Name, len(targets))
+	status.Clusters = targets
+	status.Total = int32(len(targets))
+	status.SetPhase(opv1alpha1.OperationPhaseInProgress)
+	return status, nil
+}
+
+// reconcile observes the operations of the set, creates operations for the remaining clusters
+// within the concurrency limit and failure budget, and rolls the result up into the status.
+func (h *handler) reconcile(set *opv1alpha1.OperationSet, kind children, status opv1alpha1.OperationSetStatus) (opv1alpha1.OperationSetStatus, error) {
+	phases, err := kind.Phases(set.Namespace, set.Name)
+	if err != nil {
+		return status, err
+	}
+
+	for i := range status.Clusters {
+		cluster := &status.Clusters[i]
+		if cluster.Operation == "" || ops.IsTerminal(cluster.Phase) {
+			continue
+		}
+		if phase, ok := phases[cluster.Operation]; ok {
+			cluster.Phase = phase
+			continue
+		}
+		// An operation that has never been observed may just not be in the cache yet. Once
+		// observed, a missing operation was deleted, e.g. when its TTL expired.
+		if cluster.Phase != "" {
+			cluster.Phase, err = h.recordedPhase(set, cluster)
+			if err != nil {
+				return status, err
+			}
+		}
+	}
+
+	tally(&status)
+	budgetExceeded := status.Failed+status.Canceled > set.Spec.FailureBudget
+
+	if !budgetExceeded && !set.Spec.Paused {
+		maxConcurrent := max(set.Spec.MaxConcurrent, 1)
+		for i := range status.Clusters {
+			if status.Running >= maxConcurrent {
+				break
+			}
+			cluster := &status.Clusters[i]
+			if cluster.Operation != "" {
+				continue
+			}
+
+			operation := name.SafeConcatName(set.Name, cluster.Name)
+			labels := map[string]string{}
+			maps.Copy(labels, set.Spec.Template.Labels)
+			labels[opv1alpha1.OperationSetLabel] = set.Name
+
+			err := kind.Create(set, operation, labels, clusterRef(cluster.Name), set.Spec.Template.Spec.Raw)
+			if errors.Is(err, errNotOwned) {
+				logrus.Errorf("[operationset] %s/%s: %v", set.Namespace, set.Name, err)
+				markFailed(&status, opv1alpha1.OperationExistsReason, fmt.Sprintf("cannot create %s %s: %v", set.Spec.Template.Kind, operation, err))
+				return status, nil
+			} else if err != nil {
+				return status, err
+			}
+			logrus.Infof("[operationset] %s/%s: created %s %s/%s for cluster %s", set.Namespace, set.Name, set.Spec.Template.Kind, set.Namespace, operation, cluster.Name)
+
+			cluster.Operation = operation
+			status.Running++
+		}
+	}
+
+	switch {
+	case status.Running > 0:
+		opv1alpha1.InProgressCondition.True(&status)
+		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.InProgressReason)
+		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("%d of %d operations finished, %d running", finished(&status), status.Total, status.Running))
+	case budgetExceeded:
+		logrus.Errorf("[operationset] %s/%s: failure budget exceeded", set.Namespace, set.Name)
+		markFailed(&status, opv1alpha1.FailureBudgetExceededReason, fmt.Sprintf("%d of %d operations failed or were canceled, exceeding the failure budget of %d",
+			status.Failed+status.Canceled, status.Total, set.Spec.FailureBudget))
+	case finished(&status) == status.Total && status.DryRuns > 0 && status.Succeeded == 0:
+		logrus.Infof("[operationset] %s/%s: marking dry run as completed", set.Namespace, set.Name)
+		status.SetPhase(opv1alpha1.OperationPhaseDryRunCompleted)
+		opv1alpha1.InProgressCondition.False(&status)
+		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.DryRunCompletedReason)
+		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("%d of %d operations completed a dry run", status.DryRuns, status.Total))
+	case finished(&status) == status.Total:
+		logrus.Infof("[operationset] %s/%s: marking as success", set.Namespace, set.Name)
+		status.SetPhase(opv1alpha1.OperationPhaseSucceeded)
+		opv1alpha1.InProgressCondition.False(&status)
+		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.FinishedReason)
+		opv1alpha1.SucceededCondition.True(&status)
+		opv1alpha1.SucceededCondition.Reason(&status, opv1alpha1.FinishedReason)
+		opv1alpha1.SucceededCondition.Message(&status, fmt.Sprintf("%d of %d operations succeeded", status.Succeeded, status.Total))
+	case set.Spec.Paused:
+		opv1alpha1.InProgressCondition.True(&status)
+		opv1alpha1.InProgressCondition.Reason(&status, opv1alpha1.PausedReason)
+		opv1alpha1.InProgressCondition.Message(&status, fmt.Sprintf("%d of %d operations finished, set is paused", finished(&status), status.Total))
+	}
+	return status, nil
+}
+
+// recordedPhase returns the phase an operation of the set finished in after it was deleted, as
+// recorded by its OperationRecord. Operations deleted without a record are reported as Canceled.
+func (h *handler) recordedPhase(set *opv1alpha1.OperationSet, cluster *opv1alpha1.OperationSetCluster) (opv1alpha1.OperationPhase, error) {
+	records, err := ops.ListOperationRecords(h.records, set.Namespace, cluster.Name)
+	if err != nil {
+		return "", err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		ref := records[i].Spec.OperationRef
+		if ref.Kind == set.Spec.Template.Kind && ref.Name == cluster.Operation {
+			return records[i].Spec.Phase, nil
+		}
+	}
+	logrus.Warnf("[operationset] %s/%s: operation %s was deleted before it finished", set.Namespace, set.Name, cluster.Operation)
+	return opv1alpha1.OperationPhaseCanceled, nil
+}
+
+// tally counts the operations of the set by phase. Operations that are created but not observed
+// yet count as running.
+func tally(status *opv1alpha1.OperationSetStatus) {
+	status.Running, status.Succeeded, status.Failed, status.Canceled, status.DryRuns = 0, 0, 0, 0, 0
+	for _, cluster := range status.Clusters {
+		switch {
+		case cluster.Operation == "":
+		case cluster.Phase == opv1alpha1.OperationPhaseSucceeded:
+			status.Succeeded++
+		case cluster.Phase == opv1alpha1.OperationPhaseDryRunCompleted:
+			status.DryRuns++
+		case cluster.Phase == opv1alpha1.OperationPhaseFailed:
+			status.Failed++
+		case cluster.Phase == opv1alpha1.OperationPhaseCanceled:
+			status.Canceled++
+		default:
+			status.Running++
+		}
+	}
+}
+
+// finished returns the number of operations of the set that reached a terminal phase.
+func finished(status *opv1alpha1.OperationSetStatus) int32 {
+	return status.Succeeded + status.Failed + status.Canceled + status.DryRuns
+}
+
+// clusterRef returns the reference to the named management cluster set on created operations.
+func clusterRef(name string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: mgmtv3.SchemeGroupVersion.String(),
+		Kind:       "Cluster",
+		Name:       name,
+	}
+}
+
+// markFailed transitions status to the Failed phase with the given reason and condition message.
+// Callers are responsible for logging before calling.
+func markFailed(status *opv1alpha1.OperationSetStatus, reason, condMsg string) {
+	status.SetPhase(opv1alpha1.OperationPhaseFailed)
+	opv1alpha1.InProgressCondition.False(status)
+	opv1alpha1.InProgressCondition.Reason(status, opv1alpha1.FinishedReason)
+	opv1alpha1.FailedCondition.True(status)
+	opv1alpha1.FailedCondition.Reason(status, reason)
+	opv1alpha1.FailedCondition.Message(status, condMsg)
+}
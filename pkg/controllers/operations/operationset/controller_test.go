@@ -0,0 +1,290 @@
+package operationset
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	ops "github.com/rancher/rancher/pkg/operations"
+	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeChildren is an in-memory children implementation for tests.
+type fakeChildren struct {
+	created map[string]*opv1alpha1.ETCDSnapshotSave
+	phases  map[string]opv1alpha1.OperationPhase
+	// foreign are the names of operations that exist without being owned by the set.
+	foreign map[string]bool
+}
+
+func newFakeChildren() *fakeChildren {
+	return &fakeChildren{
+		created: map[string]*opv1alpha1.ETCDSnapshotSave{},
+		phases:  map[string]opv1alpha1.OperationPhase{},
+		foreign: map[string]bool{},
+	}
+}
+
+func (c *fakeChildren) Create(set *opv1alpha1.OperationSet, name string, labels map[string]string, clusterRef *corev1.ObjectReference, spec []byte) error {
+	if c.foreign[name] {
+		return fmt.Errorf("%s/%s: %w", set.Namespace, name, errNotOwned)
+	}
+	op := &opv1alpha1.ETCDSnapshotSave{
+		ObjectMeta: metav1.ObjectMeta{Namespace: set.Namespace, Name: name, Labels: labels},
+	}
+	op.Spec.ClusterRef = clusterRef
+	c.created[name] = op
+	c.phases[name] = ""
+	return nil
+}
+
+func (c *fakeChildren) Phases(_, _ string) (map[string]opv1alpha1.OperationPhase, error) {
+	result := map[string]opv1alpha1.OperationPhase{}
+	for name, phase := range c.phases {
+		result[name] = phase
+	}
+	return result, nil
+}
+
+func newSet(maxConcurrent, failureBudget int32) *opv1alpha1.OperationSet {
+	return &opv1alpha1.OperationSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "snapshots", Generation: 1},
+		Spec: opv1alpha1.OperationSetSpec{
+			ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			Template: opv1alpha1.OperationSetTemplate{
+				Kind:   "ETCDSnapshotSave",
+				Labels: map[string]string{"team": "platform"},
+				Spec:   runtime.RawExtension{Raw: []byte(`{}`)},
+			},
+			MaxConcurrent: maxConcurrent,
+			FailureBudget: failureBudget,
+		},
+	}
+}
+
+func newHandler(t *testing.T, kind *fakeChildren, records []*opv1alpha1.OperationRecord, clusters ...string) *handler {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	clusterCache := ctrlfake.NewMockNonNamespacedCacheInterface[*mgmtv3.Cluster](ctrl)
+	clusterCache.EXPECT().List(gomock.Any()).DoAndReturn(func(selector labels.Selector) ([]*mgmtv3.Cluster, error) {
+		var result []*mgmtv3.Cluster
+		for _, name := range clusters {
+			cluster := &mgmtv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"env": "prod"}}}
+			if selector.Matches(labels.Set(cluster.Labels)) {
+				result = append(result, cluster)
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+
+	recordCache := ctrlfake.NewMockCacheInterface[*opv1alpha1.OperationRecord](ctrl)
+	recordCache.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(_ string, selector labels.Selector) ([]*opv1alpha1.OperationRecord, error) {
+		var result []*opv1alpha1.OperationRecord
+		for _, record := range records {
+			if selector.Matches(labels.Set(record.Labels)) {
+				result = append(result, record)
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+
+	sets := ctrlfake.NewMockControllerInterface[*opv1alpha1.OperationSet, *opv1alpha1.OperationSetList](ctrl)
+	sets.EXPECT().EnqueueAfter(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	return &handler{
+		operationSets: sets,
+		clusters:      clusterCache,
+		records:       recordCache,
+		kinds:         map[string]children{"ETCDSnapshotSave": kind},
+		newAdapter: func(ustr *unstructured.Unstructured) (ops.Adapter, error) {
+			if ustr.GetName() == "unsupported" {
+				return nil, errors.New("unsupported cluster")
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestOnChange_SelectsClustersAndRespectsConcurrency(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c3", "c1", "unsupported", "c2")
+	set := newSet(2, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, status.Phase)
+	assert.Equal(t, int32(3), status.Total)
+	assert.Equal(t, int32(2), status.Running)
+	assert.Len(t, kind.created, 2)
+	assert.Equal(t, []string{"c1", "c2", "c3"}, []string{status.Clusters[0].Name, status.Clusters[1].Name, status.Clusters[2].Name})
+	assert.Empty(t, status.Clusters[2].Operation)
+
+	op := kind.created[status.Clusters[0].Operation]
+	assert.Equal(t, "c1", op.Spec.ClusterRef.Name)
+	assert.Equal(t, "snapshots", op.Labels[opv1alpha1.OperationSetLabel])
+	assert.Equal(t, "platform", op.Labels["team"])
+
+	// Finishing one operation makes room for the next.
+	kind.phases[status.Clusters[0].Operation] = opv1alpha1.OperationPhaseSucceeded
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Len(t, kind.created, 3)
+	assert.Equal(t, int32(2), status.Running)
+	assert.Equal(t, int32(1), status.Succeeded)
+}
+
+func TestOnChange_SucceedsWhenAllOperationsFinish(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c1", "c2")
+	set := newSet(2, 1)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	kind.phases[status.Clusters[0].Operation] = opv1alpha1.OperationPhaseSucceeded
+	kind.phases[status.Clusters[1].Operation] = opv1alpha1.OperationPhaseFailed
+
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, status.Phase)
+	assert.Equal(t, int32(1), status.Succeeded)
+	assert.Equal(t, int32(1), status.Failed)
+	assert.Equal(t, int32(0), status.Running)
+}
+
+func TestOnChange_DryRunsAreNotCountedAsSuccess(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c1", "c2")
+	set := newSet(2, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	kind.phases[status.Clusters[0].Operation] = opv1alpha1.OperationPhaseDryRunCompleted
+	kind.phases[status.Clusters[1].Operation] = opv1alpha1.OperationPhaseDryRunCompleted
+
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseDryRunCompleted, status.Phase)
+	assert.Equal(t, int32(2), status.DryRuns)
+	assert.Equal(t, int32(0), status.Succeeded)
+	assert.Equal(t, int32(0), status.Running)
+	assert.False(t, opv1alpha1.SucceededCondition.IsTrue(&status))
+}
+
+func TestOnChange_FailureBudgetStopsCreation(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c1", "c2", "c3")
+	set := newSet(1, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	kind.phases[status.Clusters[0].Operation] = opv1alpha1.OperationPhaseFailed
+
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.FailureBudgetExceededReason, opv1alpha1.FailedCondition.GetReason(&status))
+	assert.Len(t, kind.created, 1)
+}
+
+func TestOnChange_ExistingOperationNotOwnedFailsSet(t *testing.T) {
+	kind := newFakeChildren()
+	kind.foreign["snapshots-c1"] = true
+	h := newHandler(t, kind, nil, "c1")
+	set := newSet(1, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.OperationExistsReason, opv1alpha1.FailedCondition.GetReason(&status))
+	assert.Empty(t, status.Clusters[0].Operation, "an operation the set does not own must not be tracked")
+	assert.Empty(t, kind.created)
+}
+
+func TestOnChange_PausedCreatesNothing(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c1")
+	set := newSet(1, 0)
+	set.Spec.Paused = true
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, status.Phase)
+	assert.Equal(t, opv1alpha1.PausedReason, opv1alpha1.InProgressCondition.GetReason(&status))
+	assert.Empty(t, kind.created)
+}
+
+func TestOnChange_NoClustersSelectedFails(t *testing.T) {
+	h := newHandler(t, newFakeChildren(), nil, "unsupported")
+	set := newSet(1, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.NoClustersSelectedReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestOnChange_UnsupportedKindFails(t *testing.T) {
+	h := newHandler(t, newFakeChildren(), nil, "c1")
+	set := newSet(1, 0)
+	set.Spec.Template.Kind = "Unknown"
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	assert.Equal(t, opv1alpha1.InvalidOperationSetReason, opv1alpha1.FailedCondition.GetReason(&status))
+}
+
+func TestOnChange_DeletedOperationUsesRecord(t *testing.T) {
+	kind := newFakeChildren()
+	records := []*opv1alpha1.OperationRecord{{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{opv1alpha1.OperationRecordClusterLabel: "c1"}},
+		Spec: opv1alpha1.OperationRecordSpec{
+			OperationRef: corev1.ObjectReference{Kind: "ETCDSnapshotSave", Name: "snapshots-c1"},
+			Phase:        opv1alpha1.OperationPhaseSucceeded,
+		},
+	}}
+	h := newHandler(t, kind, records, "c1", "c2")
+	set := newSet(2, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+	kind.phases["snapshots-c1"] = opv1alpha1.OperationPhaseInProgress
+	kind.phases["snapshots-c2"] = opv1alpha1.OperationPhaseInProgress
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+
+	delete(kind.phases, "snapshots-c1")
+	delete(kind.phases, "snapshots-c2")
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, status.Clusters[0].Phase)
+	assert.Equal(t, opv1alpha1.OperationPhaseCanceled, status.Clusters[1].Phase)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+}
+
+func TestOnChange_UnobservedOperationIsRunning(t *testing.T) {
+	kind := newFakeChildren()
+	h := newHandler(t, kind, nil, "c1")
+	set := newSet(1, 0)
+
+	status, err := h.OnChange(set, set.Status)
+	assert.NoError(t, err)
+
+	// The operation is not in the cache yet.
+	delete(kind.phases, "snapshots-c1")
+	status, err = h.OnChange(set, status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, status.Phase)
+	assert.Equal(t, int32(1), status.Running)
+	assert.Len(t, kind.created, 1)
+}
@@ -208,9 +208,15 @@ func PlanCRDs() []string {
 
 func OperationCRDs() []string {
 	return []string{
+		"certificaterotations.operation.cattle.io",
 		"encryptionkeyrotations.operation.cattle.io",
 		"etcdsnapshotsaves.operation.cattle.io",
 		"etcdsnapshotrestores.operation.cattle.io",
+		"kubernetesupgrades.operation.cattle.io",
+		"operationtemplates.operation.cattle.io",
+		"customoperations.operation.cattle.io",
+		"operationrecords.operation.cattle.io",
+		"operationsets.operation.cattle.io",
 	}
 }
 
@@ -226,6 +232,7 @@ func OperationCRDs() []string {
 	"azureadproviders.management.cattle.io":                           false,
 	"basicauths.project.cattle.io":                                    false,
 	"beacons.plan.cattle.io":                                          true,
+	"certificaterotations.operation.cattle.io":                        true,
 	"certificates.project.cattle.io":                                  false,
 	"cloudcredentials.management.cattle.io":                           false,
 	"clusterauthtokens.cluster.cattle.io":                             false,
@@ -241,6 +248,7 @@ func OperationCRDs() []string {
 	"clusteruserattributes.cluster.cattle.io":                         false,
 	"composeconfigs.management.cattle.io":                             false,
 	"custommachines.rke.cattle.io":                                    true,
+	"customoperations.operation.cattle.io":                            true,
 	"dockercredentials.project.cattle.io":                             false,
 	"dynamicschemas.management.cattle.io":                             true,
 	"encryptionkeyrotations.operation.cattle.io":                      true,
@@ -258,6 +266,7 @@ func OperationCRDs() []string {
 	"groups.management.cattle.io":                                     false,
 	"ipaddressclaims.ipam.cluster.x-k8s.io":                           false,
 	"kontainerdrivers.management.cattle.io":                           false,
+	"kubernetesupgrades.operation.cattle.io":                          true,
 	"localproviders.management.cattle.io":                             false,
 	"machinedeployments.cluster.x-k8s.io":                             false,
 	"machinepools.cluster.x-k8s.io":                                   false,
@@ -272,7 +281,10 @@ func OperationCRDs() []string {
 	"oidcclients.management.cattle.io":                                true,
 	"oidcproviders.management.cattle.io":                              false,
 	"openldapproviders.management.cattle.io":                          false,
+	"operationrecords.operation.cattle.io":                            true,
+	"operationsets.operation.cattle.io":                               true,
 	"operations.catalog.cattle.io":                                    false,
+	"operationtemplates.operation.cattle.io":                          true,
 	"podsecurityadmissionconfigurationtemplates.management.cattle.io": false,
 	"preferences.management.cattle.io":                                false,
 	"principals.management.cattle.io":                                 false,
@@ -28,6 +28,14 @@ type FakeOperationV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeOperationV1alpha1) CertificateRotations(namespace string) v1alpha1.CertificateRotationInterface {
+	return newFakeCertificateRotations(c, namespace)
+}
+
+func (c *FakeOperationV1alpha1) CustomOperations(namespace string) v1alpha1.CustomOperationInterface {
+	return newFakeCustomOperations(c, namespace)
+}
+
 func (c *FakeOperationV1alpha1) ETCDSnapshotRestores(namespace string) v1alpha1.ETCDSnapshotRestoreInterface {
 	return newFakeETCDSnapshotRestores(c, namespace)
 }
@@ -40,6 +48,22 @@ func (c *FakeOperationV1alpha1) EncryptionKeyRotations(namespace string) v1alpha
 	return newFakeEncryptionKeyRotations(c, namespace)
 }
 
+func (c *FakeOperationV1alpha1) KubernetesUpgrades(namespace string) v1alpha1.KubernetesUpgradeInterface {
+	return newFakeKubernetesUpgrades(c, namespace)
+}
+
+func (c *FakeOperationV1alpha1) OperationRecords(namespace string) v1alpha1.OperationRecordInterface {
+	return newFakeOperationRecords(c, namespace)
+}
+
+func (c *FakeOperationV1alpha1) OperationSets(namespace string) v1alpha1.OperationSetInterface {
+	return newFakeOperationSets(c, namespace)
+}
+
+func (c *FakeOperationV1alpha1) OperationTemplates() v1alpha1.OperationTemplateInterface {
+	return newFakeOperationTemplates(c)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeOperationV1alpha1) RESTClient() rest.Interface {
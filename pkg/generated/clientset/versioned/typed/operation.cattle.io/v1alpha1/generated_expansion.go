@@ -18,8 +18,20 @@
 
 package v1alpha1
 
+type CertificateRotationExpansion interface{}
+
+type CustomOperationExpansion interface{}
+
 type ETCDSnapshotRestoreExpansion interface{}
 
 type ETCDSnapshotSaveExpansion interface{}
 
 type EncryptionKeyRotationExpansion interface{}
+
+type KubernetesUpgradeExpansion interface{}
+
+type OperationRecordExpansion interface{}
+
+type OperationSetExpansion interface{}
+
+type OperationTemplateExpansion interface{}
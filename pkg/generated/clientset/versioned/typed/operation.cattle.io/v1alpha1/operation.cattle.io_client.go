@@ -28,9 +28,15 @@
 
 type OperationV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	CertificateRotationsGetter
+	CustomOperationsGetter
 	ETCDSnapshotRestoresGetter
 	ETCDSnapshotSavesGetter
 	EncryptionKeyRotationsGetter
+	KubernetesUpgradesGetter
+	OperationRecordsGetter
+	OperationSetsGetter
+	OperationTemplatesGetter
 }
 
 // OperationV1alpha1Client is used to interact with features provided by the operation.cattle.io group.
@@ -38,6 +44,14 @@ type OperationV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *OperationV1alpha1Client) CertificateRotations(namespace string) CertificateRotationInterface {
+	return newCertificateRotations(c, namespace)
+}
+
+func (c *OperationV1alpha1Client) CustomOperations(namespace string) CustomOperationInterface {
+	return newCustomOperations(c, namespace)
+}
+
 func (c *OperationV1alpha1Client) ETCDSnapshotRestores(namespace string) ETCDSnapshotRestoreInterface {
 	return newETCDSnapshotRestores(c, namespace)
 }
@@ -50,6 +64,22 @@ func (c *OperationV1alpha1Client) EncryptionKeyRotations(namespace string) Encry
 	return newEncryptionKeyRotations(c, namespace)
 }
 
+func (c *OperationV1alpha1Client) KubernetesUpgrades(namespace string) KubernetesUpgradeInterface {
+	return newKubernetesUpgrades(c, namespace)
+}
+
+func (c *OperationV1alpha1Client) OperationRecords(namespace string) OperationRecordInterface {
+	return newOperationRecords(c, namespace)
+}
+
+func (c *OperationV1alpha1Client) OperationSets(namespace string) OperationSetInterface {
+	return newOperationSets(c, namespace)
+}
+
+func (c *OperationV1alpha1Client) OperationTemplates() OperationTemplateInterface {
+	return newOperationTemplates(c)
+}
+
 // NewForConfig creates a new OperationV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
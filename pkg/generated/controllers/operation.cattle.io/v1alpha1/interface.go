@@ -31,9 +31,15 @@ func init() {
 }
 
 type Interface interface {
+	CertificateRotation() CertificateRotationController
+	CustomOperation() CustomOperationController
 	ETCDSnapshotRestore() ETCDSnapshotRestoreController
 	ETCDSnapshotSave() ETCDSnapshotSaveController
 	EncryptionKeyRotation() EncryptionKeyRotationController
+	KubernetesUpgrade() KubernetesUpgradeController
+	OperationRecord() OperationRecordController
+	OperationSet() OperationSetController
+	OperationTemplate() OperationTemplateController
 }
 
 func New(controllerFactory controller.SharedControllerFactory) Interface {
@@ -46,6 +52,14 @@ type version struct {
 	controllerFactory controller.SharedControllerFactory
 }
 
+func (v *version) CertificateRotation() CertificateRotationController {
+	return generic.NewController[*v1alpha1.CertificateRotation, *v1alpha1.CertificateRotationList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "CertificateRotation"}, "certificaterotations", true, v.controllerFactory)
+}
+
+func (v *version) CustomOperation() CustomOperationController {
+	return generic.NewController[*v1alpha1.CustomOperation, *v1alpha1.CustomOperationList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "CustomOperation"}, "customoperations", true, v.controllerFactory)
+}
+
 func (v *version) ETCDSnapshotRestore() ETCDSnapshotRestoreController {
 	return generic.NewController[*v1alpha1.ETCDSnapshotRestore, *v1alpha1.ETCDSnapshotRestoreList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "ETCDSnapshotRestore"}, "etcdsnapshotrestores", true, v.controllerFactory)
 }
@@ -57,3 +71,19 @@ func (v *version) ETCDSnapshotSave() ETCDSnapshotSaveController {
 func (v *version) EncryptionKeyRotation() EncryptionKeyRotationController {
 	return generic.NewController[*v1alpha1.EncryptionKeyRotation, *v1alpha1.EncryptionKeyRotationList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "EncryptionKeyRotation"}, "encryptionkeyrotations", true, v.controllerFactory)
 }
+
+func (v *version) KubernetesUpgrade() KubernetesUpgradeController {
+	return generic.NewController[*v1alpha1.KubernetesUpgrade, *v1alpha1.KubernetesUpgradeList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "KubernetesUpgrade"}, "kubernetesupgrades", true, v.controllerFactory)
+}
+
+func (v *version) OperationRecord() OperationRecordController {
+	return generic.NewController[*v1alpha1.OperationRecord, *v1alpha1.OperationRecordList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "OperationRecord"}, "operationrecords", true, v.controllerFactory)
+}
+
+func (v *version) OperationSet() OperationSetController {
+	return generic.NewController[*v1alpha1.OperationSet, *v1alpha1.OperationSetList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "OperationSet"}, "operationsets", true, v.controllerFactory)
+}
+
+func (v *version) OperationTemplate() OperationTemplateController {
+	return generic.NewNonNamespacedController[*v1alpha1.OperationTemplate, *v1alpha1.OperationTemplateList](schema.GroupVersionKind{Group: "operation.cattle.io", Version: "v1alpha1", Kind: "OperationTemplate"}, "operationtemplates", v.controllerFactory)
+}
@@ -49,6 +49,17 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	prometheus.MustRegister(numNodes)
 	prometheus.MustRegister(numCores)
 
+	// plan delivery metrics
+	prometheus.MustRegister(planAssignments)
+	prometheus.MustRegister(planAppliedDuration)
+	prometheus.MustRegister(planProbesPassedDuration)
+	prometheus.MustRegister(planProbeFailures)
+
+	// operation lifecycle metrics
+	prometheus.MustRegister(operations)
+	prometheus.MustRegister(operationStepDuration)
+	prometheus.MustRegister(beaconOwner)
+
 	gc := metricGarbageCollector{
 		clusterLister:  scaledContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:     scaledContext.Management.Nodes("").Controller().Lister(),
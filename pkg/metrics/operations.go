@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	operationTypeLabel  = "type"
+	operationPhaseLabel = "phase"
+	operationStepLabel  = "step"
+	beaconClusterLabel  = "cluster"
+	beaconKindLabel     = "kind"
+)
+
+var (
+	operations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "cluster_manager",
+			Name:      "operations",
+			Help:      "Number of cluster operations by type and phase",
+		},
+		[]string{operationTypeLabel, operationPhaseLabel},
+	)
+	operationStepDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "cluster_manager",
+			Name:      "operation_step_duration_seconds",
+			Help:      "Time cluster operations spent in each of their steps",
+			Buckets:   planDurationBuckets,
+		},
+		[]string{operationTypeLabel, operationStepLabel},
+	)
+	beaconOwner = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "cluster_manager",
+			Name:      "beacon_owner_since_seconds",
+			Help:      "Unix time the current owner of the beacon of a cluster, labeled by its kind of operation, acquired it; a cluster without an owner has no series",
+		},
+		[]string{beaconClusterLabel, beaconKindLabel},
+	)
+)
+
+// MoveOperation moves an operation of the given type from one phase to another in the operations
+// gauge. An empty from adds a new operation, an empty to removes a deleted one.
+func MoveOperation(operationType, from, to string) {
+	if !prometheusMetrics || from == to {
+		return
+	}
+	if from != "" {
+		operations.With(prometheus.Labels{operationTypeLabel: operationType, operationPhaseLabel: from}).Dec()
+	}
+	if to != "" {
+		operations.With(prometheus.Labels{operationTypeLabel: operationType, operationPhaseLabel: to}).Inc()
+	}
+}
+
+// ObserveOperationStep records the time an operation of the given type spent in a step.
+func ObserveOperationStep(operationType, step string, duration time.Duration) {
+	if prometheusMetrics {
+		operationStepDuration.With(prometheus.Labels{operationTypeLabel: operationType, operationStepLabel: step}).Observe(duration.Seconds())
+	}
+}
+
+// SetBeaconOwner records that an operation of the given kind owns the beacon of the cluster since
+// the given time.
+func SetBeaconOwner(cluster, kind string, since time.Time) {
+	if prometheusMetrics {
+		beaconOwner.With(prometheus.Labels{beaconClusterLabel: cluster, beaconKindLabel: kind}).Set(float64(since.Unix()))
+	}
+}
+
+// UnsetBeaconOwner removes the owner of the given kind of the beacon of the cluster.
+func UnsetBeaconOwner(cluster, kind string) {
+	if prometheusMetrics {
+		beaconOwner.Delete(prometheus.Labels{beaconClusterLabel: cluster, beaconKindLabel: kind})
+	}
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	planClusterLabel = "cluster"
+	planProbeLabel   = "probe"
+)
+
+var (
+	// planDurationBuckets range from 5 seconds to a little under 3 hours, which covers plans from
+	// a single file drop up to a full Kubernetes upgrade of a node.
+	planDurationBuckets = prometheus.ExponentialBuckets(5, 2, 12)
+
+	planAssignments = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "cluster_manager",
+			Name:      "plan_assignments_total",
+			Help:      "Number of plans assigned to the machine-plan secrets of rancher managed clusters",
+		},
+		[]string{planClusterLabel},
+	)
+	planAppliedDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "cluster_manager",
+			Name:      "plan_applied_duration_seconds",
+			Help:      "Time from a plan being assigned to a machine-plan secret until the system-agent applied it",
+			Buckets:   planDurationBuckets,
+		},
+		[]string{planClusterLabel},
+	)
+	planProbesPassedDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "cluster_manager",
+			Name:      "plan_probes_passed_duration_seconds",
+			Help:      "Time from a plan being assigned to a machine-plan secret until all of its probes passed",
+			Buckets:   planDurationBuckets,
+		},
+		[]string{planClusterLabel},
+	)
+	planProbeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "cluster_manager",
+			Name:      "plan_probe_failures_total",
+			Help:      "Number of failed probe checks reported by the system-agents of rancher managed clusters",
+		},
+		[]string{planClusterLabel, planProbeLabel},
+	)
+)
+
+// IncPlanAssignments counts a plan assigned to a machine-plan secret of the cluster.
+func IncPlanAssignments(cluster string) {
+	if prometheusMetrics {
+		planAssignments.With(prometheus.Labels{planClusterLabel: cluster}).Inc()
+	}
+}
+
+// ObservePlanApplied records the time a plan of the cluster took from being assigned to being
+// applied.
+func ObservePlanApplied(cluster string, duration time.Duration) {
+	if prometheusMetrics {
+		planAppliedDuration.With(prometheus.Labels{planClusterLabel: cluster}).Observe(duration.Seconds())
+	}
+}
+
+// ObservePlanProbesPassed records the time a plan of the cluster took from being assigned until
+// its probes passed.
+func ObservePlanProbesPassed(cluster string, duration time.Duration) {
+	if prometheusMetrics {
+		planProbesPassedDuration.With(prometheus.Labels{planClusterLabel: cluster}).Observe(duration.Seconds())
+	}
+}
+
+// AddPlanProbeFailures counts failed checks of the named probe on a node of the cluster.
+func AddPlanProbeFailures(cluster, probe string, failures int) {
+	if prometheusMetrics && failures > 0 {
+		planProbeFailures.With(prometheus.Labels{planClusterLabel: cluster, planProbeLabel: probe}).Add(float64(failures))
+	}
+}
@@ -146,7 +146,12 @@ func (h *authorizeHandler) authEndpoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.validateScopes(params.scopes, oidcClient); err != nil {
+	if !isGrantTypeAllowed(oidcClient, grantTypeAuthorizationCode) {
+		oidcerror.RedirectWithError(params.redirectURI, oidcerror.UnauthorizedClient, "authorization_code grant not allowed for this client", params.state, w, r)
+		return
+	}
+
+	if err := validateScopes(params.scopes, oidcClient); err != nil {
 		oidcerror.RedirectWithError(params.redirectURI, oidcerror.InvalidScope, err.Error(), params.state, w, r)
 		return
 	}
@@ -282,7 +287,7 @@ func getAuthParamsFromRequest(r *http.Request) (*authParams, error) {
 // If oidcClient.Spec.Scopes is empty, the allowed scopes default to supportedScopes.
 // It returns nil when all requested scopes are allowed; otherwise it returns a
 // non-nil error describing the invalid scopes.
-func (h *authorizeHandler) validateScopes(requested []string, oidcClient *v3.OIDCClient) error {
+func validateScopes(requested []string, oidcClient *v3.OIDCClient) error {
 	scopes := slices.Clone(oidcClient.Spec.Scopes)
 
 	if len(scopes) == 0 {
@@ -23,14 +23,23 @@ type OpenIDConfiguration struct {
 	ResponseTypesSupported []string `json:"response_types_supported"`
 	// SubjectTypesSupported subject types supported, only 'public' is supported
 	SubjectTypesSupported []string `json:"subject_types_supported"`
-	// IDTokenSigningAlgsValuesSupported only RS256 is supported
+	// IDTokenSigningAlgsValuesSupported can be RS256, ES256 and EdDSA
 	IDTokenSigningAlgsValuesSupported []string `json:"id_token_signing_alg_values_supported"`
 	// CodeChallengeMethodsSupported only S256 is supported
 	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
 	// ScopesSupported can be openid, profile, offline_token
 	ScopesSupported []string `json:"scopes_supported"`
-	// GrantTypesSupported only code is supported
+	// GrantTypesSupported can be authorization_code, refresh_token, client_credentials and
+	// urn:ietf:params:oauth:grant-type:device_code
 	GrantTypesSupported []string `json:"grant_types_supported"`
+	// DeviceAuthorizationEndpoint is the device authorization endpoint
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	// IntrospectionEndpoint is the token introspection endpoint
+	IntrospectionEndpoint string `json:"introspection_endpoint"`
+	// RevocationEndpoint is the token revocation endpoint
+	RevocationEndpoint string `json:"revocation_endpoint"`
+	// EndSessionEndpoint is the RP-initiated logout endpoint
+	EndSessionEndpoint string `json:"end_session_endpoint"`
 }
 
 func openIDConfigurationEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -42,10 +51,14 @@ func openIDConfigurationEndpoint(w http.ResponseWriter, r *http.Request) {
 		UserInfoEndpoint:                  oidcProviderHost() + "/userinfo",
 		ResponseTypesSupported:            []string{"code"},
 		SubjectTypesSupported:             []string{"public"},
-		IDTokenSigningAlgsValuesSupported: []string{"RS256"},
+		IDTokenSigningAlgsValuesSupported: supportedSigningAlgs,
 		CodeChallengeMethodsSupported:     []string{"S256"},
 		ScopesSupported:                   []string{"openid", "profile", "offline_access"},
-		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		GrantTypesSupported:               supportedGrantTypes,
+		DeviceAuthorizationEndpoint:       oidcProviderHost() + "/device_authorization",
+		IntrospectionEndpoint:             oidcProviderHost() + "/introspect",
+		RevocationEndpoint:                oidcProviderHost() + "/revoke",
+		EndSessionEndpoint:                oidcProviderHost() + "/end_session",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -19,5 +19,5 @@ func TestOpenIDConfigurationEndpoint(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
-	assert.JSONEq(t, `{"issuer":"https://rancher.com/oidc","authorization_endpoint":"https://rancher.com/oidc/authorize","token_endpoint":"https://rancher.com/oidc/token","userinfo_endpoint":"https://rancher.com/oidc/userinfo","jwks_uri":"https://rancher.com/oidc/.well-known/jwks.json","response_types_supported":["code"],"subject_types_supported":["public"],"id_token_signing_alg_values_supported":["RS256"],"code_challenge_methods_supported":["S256"],"scopes_supported":["openid","profile","offline_access"],"grant_types_supported":["authorization_code","refresh_token"]}`, strings.TrimSpace(rec.Body.String()))
+	assert.JSONEq(t, `{"issuer":"https://rancher.com/oidc","authorization_endpoint":"https://rancher.com/oidc/authorize","token_endpoint":"https://rancher.com/oidc/token","userinfo_endpoint":"https://rancher.com/oidc/userinfo","jwks_uri":"https://rancher.com/oidc/.well-known/jwks.json","response_types_supported":["code"],"subject_types_supported":["public"],"id_token_signing_alg_values_supported":["RS256","ES256","EdDSA"],"code_challenge_methods_supported":["S256"],"scopes_supported":["openid","profile","offline_access"],"grant_types_supported":["authorization_code","refresh_token","client_credentials","urn:ietf:params:oauth:grant-type:device_code"],"device_authorization_endpoint":"https://rancher.com/oidc/device_authorization","introspection_endpoint":"https://rancher.com/oidc/introspect","revocation_endpoint":"https://rancher.com/oidc/revoke","end_session_endpoint":"https://rancher.com/oidc/end_session"}`, strings.TrimSpace(rec.Body.String()))
 }
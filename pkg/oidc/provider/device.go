@@ -0,0 +1,438 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	oidcerror "github.com/rancher/rancher/pkg/oidc/provider/error"
+	"github.com/rancher/rancher/pkg/oidc/provider/session"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// deviceCodeExpiration is how long a device has to get its request approved.
+	deviceCodeExpiration = maxTime
+	// devicePollingInterval is the minimum time between two polls of the token endpoint by a device.
+	devicePollingInterval = 5 * time.Second
+	// userCodeAttempts is how many user codes are generated before giving up on finding a unique one.
+	userCodeAttempts = 3
+	// csrfHeader is the header holding the value of the CSRF cookie in requests authenticated with the session cookie.
+	csrfHeader = "X-Api-Csrf"
+	// csrfFormField is the form field holding the value of the CSRF cookie in reviews posted by the verification page.
+	csrfFormField = "csrf"
+)
+
+type deviceCodeCreator interface {
+	GenerateDeviceCode() (string, error)
+	GenerateUserCode() (string, error)
+}
+
+type deviceSessionStore interface {
+	AddDevice(deviceCode string, session session.DeviceSession) error
+	GetDevice(userCode string, now time.Time) (*session.DeviceSession, error)
+	ReviewDevice(userCode string, approved bool, tokenName string, now time.Time) (*session.DeviceSession, error)
+	PollDevice(deviceCode string, clientID string, now time.Time) (*session.DeviceSession, error)
+}
+
+// DeviceAuthorizationResponse represents a successful response returned by the device authorization endpoint.
+type DeviceAuthorizationResponse struct {
+	// DeviceCode is the device verification code.
+	DeviceCode string `json:"device_code"`
+	// UserCode is the code the user enters at the verification URI.
+	UserCode string `json:"user_code"`
+	// VerificationURI is where the user approves the request.
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete is the verification URI including the user code.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	// ExpiresIn indicates when device_code and user_code expire, in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+	// Interval is the minimum amount of time in seconds the client should wait between polling requests.
+	Interval int64 `json:"interval"`
+}
+
+// DeviceVerificationResponse describes a pending device authorization request to the user reviewing it.
+type DeviceVerificationResponse struct {
+	// ClientID is the OIDC client requesting access.
+	ClientID string `json:"client_id"`
+	// Description is the description of the OIDC client.
+	Description string `json:"description,omitempty"`
+	// Scope is the requested scope.
+	Scope string `json:"scope"`
+	// Status is the status of the request.
+	Status string `json:"status"`
+}
+
+// deviceHandler implements the endpoints of the device authorization grant (RFC 8628). Devices request a device and a
+// user code from the device authorization endpoint, and poll the token endpoint with the device code while the user
+// approves the request, logged in Rancher, at the verification endpoint.
+type deviceHandler struct {
+	tokenHandler *tokenHandler
+	authHandler  *authorizeHandler
+	sessions     deviceSessionStore
+	codeCreator  deviceCodeCreator
+	now          func() time.Time
+}
+
+func newDeviceHandler(tokenHandler *tokenHandler, authHandler *authorizeHandler, sessions deviceSessionStore, codeCreator deviceCodeCreator) *deviceHandler {
+	return &deviceHandler{
+		tokenHandler: tokenHandler,
+		authHandler:  authHandler,
+		sessions:     sessions,
+		codeCreator:  codeCreator,
+		now:          time.Now,
+	}
+}
+
+// deviceAuthorizationEndpoint handles the device authorization endpoint of the OIDC provider.
+func (h *deviceHandler) deviceAuthorizationEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "unsupported method", http.StatusMethodNotAllowed, w)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, fmt.Sprintf("error parsing parameters from request %v", err), http.StatusBadRequest, w)
+		return
+	}
+
+	oidcClient, oidcErr := h.tokenHandler.authenticateClient(r, grantTypeDeviceCode)
+	if oidcErr != nil {
+		logrus.Debug("[OIDC provider] error authenticating device authorization request: " + oidcErr.ToString())
+		oidcErr.Write(http.StatusBadRequest, w)
+		return
+	}
+	scopes := parseScope(r.Form.Get("scope"))
+	if err := validateScopes(scopes, oidcClient); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidScope, err.Error(), http.StatusBadRequest, w)
+		return
+	}
+
+	deviceCode, err := h.codeCreator.GenerateDeviceCode()
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error generating device code %v", err)
+		oidcerror.WriteError(oidcerror.ServerError, "failed to generate device code", http.StatusInternalServerError, w)
+		return
+	}
+
+	now := h.now()
+	deviceSession := session.DeviceSession{
+		ClientID:  oidcClient.Status.ClientID,
+		Scope:     scopes,
+		Status:    session.DevicePending,
+		Interval:  devicePollingInterval,
+		CreatedAt: now,
+		ExpiresAt: now.Add(deviceCodeExpiration),
+	}
+	// user codes are short, a collision with a pending request is retried with a new one.
+	for range userCodeAttempts {
+		deviceSession.UserCode, err = h.codeCreator.GenerateUserCode()
+		if err != nil {
+			break
+		}
+		if err = h.sessions.AddDevice(deviceCode, deviceSession); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error adding device session %v", err)
+		oidcerror.WriteError(oidcerror.ServerError, "failed to store device authorization session", http.StatusInternalServerError, w)
+		return
+	}
+
+	userCode := formatUserCode(deviceSession.UserCode)
+	writeJSON(w, DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         oidcProviderHost() + "/device",
+		VerificationURIComplete: oidcProviderHost() + "/device?user_code=" + userCode,
+		ExpiresIn:               int64(deviceCodeExpiration.Seconds()),
+		Interval:                int64(devicePollingInterval.Seconds()),
+	})
+}
+
+// deviceVerificationEndpoint handles the verification endpoint of the device authorization grant. Users logged in
+// Rancher get the pending request of a user code with a GET request, and approve or deny it with a POST request, with
+// the user_code and approve parameters. Browsers, i.e. requests accepting text/html, are served a page to enter the
+// user code and approve or deny the request, other clients get a JSON DeviceVerificationResponse.
+func (h *deviceHandler) deviceVerificationEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "unsupported method", http.StatusMethodNotAllowed, w)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, fmt.Sprintf("error parsing parameters from request %v", err), http.StatusBadRequest, w)
+		return
+	}
+	browser := acceptsHTML(r)
+	token, err := h.authHandler.getAndVerifyRancherTokenFromRequest(r)
+	if err != nil {
+		logrus.Debugf("[OIDC provider] error verifying Rancher token for device verification: %v", err)
+		if browser {
+			writeDevicePage(w, http.StatusUnauthorized, devicePage{LoginURL: settings.ServerURL.Get() + "/dashboard/auth/login"})
+			return
+		}
+		oidcerror.WriteError(oidcerror.AccessDenied, "log in to Rancher to review the request", http.StatusUnauthorized, w)
+		return
+	}
+	var csrf string
+	if browser {
+		if csrf, err = csrfCookieValue(w, r); err != nil {
+			logrus.Errorf("[OIDC provider] error generating CSRF token %v", err)
+			writeDevicePage(w, http.StatusInternalServerError, devicePage{Error: "failed to generate CSRF token"})
+			return
+		}
+	}
+	userCode := normalizeUserCode(r.Form.Get("user_code"))
+	if userCode == "" {
+		if browser && r.Method == http.MethodGet {
+			writeDevicePage(w, http.StatusOK, devicePage{})
+			return
+		}
+		writeVerificationError(w, browser, oidcerror.InvalidRequest, "missing user_code", http.StatusBadRequest)
+		return
+	}
+
+	now := h.now()
+	var deviceSession *session.DeviceSession
+	if r.Method == http.MethodGet {
+		deviceSession, err = h.sessions.GetDevice(userCode, now)
+	} else {
+		if !validCSRF(r) {
+			writeVerificationError(w, browser, oidcerror.AccessDenied, "invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		// store ext tokens in canonical form, i.e. with an `ext/` prefix.
+		deviceSession, err = h.sessions.ReviewDevice(userCode, r.Form.Get("approve") == "true", token.GetFullName(), now)
+	}
+	switch {
+	case errors.Is(err, session.ErrDeviceCodeNotFound):
+		writeVerificationError(w, browser, oidcerror.InvalidRequest, "invalid user_code", http.StatusNotFound)
+		return
+	case errors.Is(err, session.ErrDeviceCodeExpired):
+		writeVerificationError(w, browser, oidcerror.ExpiredToken, "the user_code has expired", http.StatusBadRequest)
+		return
+	case err != nil:
+		logrus.Errorf("[OIDC provider] error reviewing device session %v", err)
+		writeVerificationError(w, browser, oidcerror.ServerError, "failed to review device authorization request", http.StatusInternalServerError)
+		return
+	}
+
+	resp := DeviceVerificationResponse{
+		ClientID: deviceSession.ClientID,
+		Scope:    strings.Join(deviceSession.Scope, " "),
+		Status:   string(deviceSession.Status),
+	}
+	if oidcClient, err := h.tokenHandler.getOIDCClientByClientID(deviceSession.ClientID); err == nil {
+		resp.Description = oidcClient.Spec.Description
+	}
+	if browser {
+		writeDevicePage(w, http.StatusOK, devicePage{UserCode: formatUserCode(userCode), CSRF: csrf, Request: &resp})
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// createTokenFromDeviceCode creates a response with an id_token (if openid scope is provided), access_token and
+// refresh_token for a device authorization request approved by the user.
+func (h *tokenHandler) createTokenFromDeviceCode(r *http.Request) (TokenResponse, *oidcerror.Error) {
+	oidcClient, oidcErr := h.authenticateClient(r, grantTypeDeviceCode)
+	if oidcErr != nil {
+		return TokenResponse{}, oidcErr
+	}
+
+	deviceSession, err := h.deviceSessions.PollDevice(r.Form.Get("device_code"), oidcClient.Status.ClientID, h.now())
+	switch {
+	case errors.Is(err, session.ErrDeviceCodeNotFound):
+		return TokenResponse{}, oidcerror.New(oidcerror.InvalidGrant, "invalid device_code")
+	case errors.Is(err, session.ErrDeviceCodeExpired):
+		return TokenResponse{}, oidcerror.New(oidcerror.ExpiredToken, "the device_code has expired")
+	case errors.Is(err, session.ErrSlowDown):
+		return TokenResponse{}, oidcerror.New(oidcerror.SlowDown, "polling too often")
+	case err != nil:
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "error retrieving device session: %s", err)
+	}
+
+	switch deviceSession.Status {
+	case session.DeviceApproved:
+	case session.DeviceDenied:
+		return TokenResponse{}, oidcerror.New(oidcerror.AccessDenied, "the request was denied")
+	default:
+		return TokenResponse{}, oidcerror.New(oidcerror.AuthorizationPending, "the request is pending")
+	}
+
+	rancherToken, err := h.extTokenStore.Fetch(deviceSession.TokenName)
+	if err != nil {
+		return TokenResponse{}, oidcerror.New(oidcerror.InvalidGrant, "Rancher token is not valid anymore")
+	}
+
+	return h.createTokenResponse(rancherToken, oidcClient, "", deviceSession.Scope)
+}
+
+// validCSRF returns whether a request authenticated with the session cookie carries the value of the CSRF cookie in
+// the CSRF header, as the Rancher API requires, or in the csrf field of the form posted by the verification page.
+// Requests authenticated with the Authorization header are not subject to CSRF.
+func validCSRF(r *http.Request) bool {
+	if r.Header.Get(tokens.AuthHeaderName) != "" {
+		return true
+	}
+	cookie, err := r.Cookie(tokens.CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	value := r.Header.Get(csrfHeader)
+	if value == "" {
+		value = r.PostForm.Get(csrfFormField)
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(value)) == 1
+}
+
+// csrfCookieValue returns the value of the CSRF cookie of the request. A new CSRF cookie is set, the same way the
+// Rancher API does, if the request doesn't have one yet.
+func csrfCookieValue(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(tokens.CSRFCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	value := make([]byte, 16)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	cookie := &http.Cookie{
+		Name:     tokens.CSRFCookie,
+		Value:    hex.EncodeToString(value),
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+
+	return cookie.Value, nil
+}
+
+// acceptsHTML returns whether the request comes from a browser, which is served the verification page.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// devicePage is the data of the verification page.
+type devicePage struct {
+	// LoginURL is set when the user must log in to Rancher first.
+	LoginURL string
+	// Error describes why the request can't be reviewed.
+	Error string
+	// UserCode is the formatted user code of Request.
+	UserCode string
+	// CSRF is the value of the CSRF cookie, posted back with the review.
+	CSRF string
+	// Request is the reviewed request, nil until the user entered a user code.
+	Request *DeviceVerificationResponse
+}
+
+var devicePageTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Rancher - Device authorization</title>
+</head>
+<body>
+<h1>Device authorization</h1>
+{{- if .LoginURL}}
+<p>Log in to Rancher to review the request, then open this page again.</p>
+<p><a href="{{.LoginURL}}">Log in</a></p>
+{{- else if .Error}}
+<p>{{.Error}}</p>
+<p><a href="device">Enter another code</a></p>
+{{- else if not .Request}}
+<form method="get">
+<label for="user_code">Enter the code displayed on your device</label>
+<input id="user_code" name="user_code" autocomplete="off" autofocus required>
+<button type="submit">Continue</button>
+</form>
+{{- else if eq .Request.Status "pending"}}
+<p><strong>{{.Request.ClientID}}</strong>{{with .Request.Description}} ({{.}}){{end}} requests access to your Rancher account with the scope <code>{{.Request.Scope}}</code>.</p>
+<p>Only approve the request if your device displays the code <strong>{{.UserCode}}</strong>.</p>
+<form method="post">
+<input type="hidden" name="user_code" value="{{.UserCode}}">
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+<button type="submit" name="approve" value="true">Approve</button>
+<button type="submit" name="approve" value="false">Deny</button>
+</form>
+{{- else if eq .Request.Status "approved"}}
+<p>The request was approved, you can return to your device.</p>
+{{- else}}
+<p>The request was denied.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// writeDevicePage renders the verification page.
+func writeDevicePage(w http.ResponseWriter, status int, page devicePage) {
+	writePage(w, status, devicePageTemplate, page)
+}
+
+// writePage renders a page of the OIDC provider. Pages can't be framed, so the user can't be tricked into
+// approving a request or logging out.
+func writePage(w http.ResponseWriter, status int, tmpl *template.Template, data any) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		logrus.Errorf("[OIDC provider] error rendering %s page %v", tmpl.Name(), err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeVerificationError writes an error of the verification endpoint as a page for browsers, and as an OIDC error
+// otherwise.
+func writeVerificationError(w http.ResponseWriter, browser bool, errorType string, description string, status int) {
+	if browser {
+		writeDevicePage(w, status, devicePage{Error: description})
+		return
+	}
+	oidcerror.WriteError(errorType, description, status, w)
+}
+
+// formatUserCode formats a user code for display, e.g. BCDF-2456.
+func formatUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	if len(userCode) <= 4 {
+		return userCode
+	}
+
+	return userCode[:4] + "-" + userCode[4:]
+}
+
+// normalizeUserCode reverts formatUserCode on a user code typed in by the user.
+func normalizeUserCode(userCode string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+}
+
+// parseScope parses a space separated scope parameter.
+func parseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		oidcerror.WriteError(oidcerror.ServerError, "failed to encode response", http.StatusInternalServerError, w)
+	}
+}
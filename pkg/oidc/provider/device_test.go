@@ -0,0 +1,924 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	providermocks "github.com/rancher/rancher/pkg/auth/providers/mocks"
+	exttokenstore "github.com/rancher/rancher/pkg/ext/stores/tokens"
+	"github.com/rancher/rancher/pkg/oidc/mocks"
+	"github.com/rancher/rancher/pkg/oidc/provider/session"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestDeviceAuthorizationEndpoint(t *testing.T) {
+	const (
+		fakeClientID     = "client-id"
+		fakeClientName   = "client-name"
+		fakeClientSecret = "client-secret"
+		fakeDeviceCode   = "device-code"
+		fakeServerUrl    = "https://www.fake.com"
+	)
+	type mockParams struct {
+		oidcClientCache *fake.MockNonNamespacedCacheInterface[*v3.OIDCClient]
+		oidcClient      *fake.MockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList]
+		secretCache     *fake.MockCacheInterface[*corev1.Secret]
+		sessions        *mocks.MockdeviceSessionStore
+		codeCreator     *mocks.MockdeviceCodeCreator
+	}
+	_ = settings.ServerURL.Set(fakeServerUrl)
+	fakeTime := time.Unix(0, 0)
+	ctrl := gomock.NewController(t)
+	deviceOIDCClient := &v3.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeClientName},
+		Spec: v3.OIDCClientSpec{
+			GrantTypes: []string{grantTypeDeviceCode},
+		},
+		Status: v3.OIDCClientStatus{ClientID: fakeClientID},
+	}
+	clientSecret := &corev1.Secret{
+		Data: map[string][]byte{"client-secret-1": []byte(fakeClientSecret)},
+	}
+	fakeSession := session.DeviceSession{
+		ClientID:  fakeClientID,
+		UserCode:  "bcdf2456",
+		Scope:     []string{"openid", "profile"},
+		Status:    session.DevicePending,
+		Interval:  devicePollingInterval,
+		CreatedAt: fakeTime,
+		ExpiresAt: fakeTime.Add(deviceCodeExpiration),
+	}
+	newRequest := func(data url.Values) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "https://rancher.com", bytes.NewBufferString(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+		return req
+	}
+	authenticateClient := func(m mockParams, oidcClient *v3.OIDCClient) {
+		m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return([]*v3.OIDCClient{oidcClient}, nil)
+		m.secretCache.EXPECT().Get(secretsNamespace, fakeClientID).Return(clientSecret, nil)
+		m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, gomock.Any()).Return(oidcClient, nil)
+	}
+
+	tests := map[string]struct {
+		req          func() *http.Request
+		mockSetup    func(mockParams)
+		wantHttpCode int
+		wantResponse *DeviceAuthorizationResponse
+		wantError    string
+	}{
+		"device and user codes are returned": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"scope": {"openid profile"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateClient(m, deviceOIDCClient)
+				m.codeCreator.EXPECT().GenerateDeviceCode().Return(fakeDeviceCode, nil)
+				m.codeCreator.EXPECT().GenerateUserCode().Return("bcdf2456", nil)
+				m.sessions.EXPECT().AddDevice(fakeDeviceCode, fakeSession).Return(nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantResponse: &DeviceAuthorizationResponse{
+				DeviceCode:              fakeDeviceCode,
+				UserCode:                "BCDF-2456",
+				VerificationURI:         fakeServerUrl + "/oidc/device",
+				VerificationURIComplete: fakeServerUrl + "/oidc/device?user_code=BCDF-2456",
+				ExpiresIn:               int64(deviceCodeExpiration.Seconds()),
+				Interval:                int64(devicePollingInterval.Seconds()),
+			},
+		},
+		"user code collision is retried": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"scope": {"openid profile"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateClient(m, deviceOIDCClient)
+				m.codeCreator.EXPECT().GenerateDeviceCode().Return(fakeDeviceCode, nil)
+				duplicate := fakeSession
+				duplicate.UserCode = "duplicat"
+				gomock.InOrder(
+					m.codeCreator.EXPECT().GenerateUserCode().Return("duplicat", nil),
+					m.codeCreator.EXPECT().GenerateUserCode().Return("bcdf2456", nil),
+				)
+				m.sessions.EXPECT().AddDevice(fakeDeviceCode, duplicate).Return(fmt.Errorf("user code already exists"))
+				m.sessions.EXPECT().AddDevice(fakeDeviceCode, fakeSession).Return(nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantResponse: &DeviceAuthorizationResponse{
+				DeviceCode:              fakeDeviceCode,
+				UserCode:                "BCDF-2456",
+				VerificationURI:         fakeServerUrl + "/oidc/device",
+				VerificationURIComplete: fakeServerUrl + "/oidc/device?user_code=BCDF-2456",
+				ExpiresIn:               int64(deviceCodeExpiration.Seconds()),
+				Interval:                int64(devicePollingInterval.Seconds()),
+			},
+		},
+		"device grant is not allowed by default": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"scope": {"openid"}})
+			},
+			mockSetup: func(m mockParams) {
+				oidcClient := deviceOIDCClient.DeepCopy()
+				oidcClient.Spec.GrantTypes = nil
+				authenticateClient(m, oidcClient)
+			},
+			wantHttpCode: http.StatusBadRequest,
+			wantError:    `{"error":"unauthorized_client","error_description":"grant_type not allowed for this client"}`,
+		},
+		"invalid scope": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"scope": {"openid invalid"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateClient(m, deviceOIDCClient)
+			},
+			wantHttpCode: http.StatusBadRequest,
+			wantError:    `{"error":"invalid_scope","error_description":"invalid scope: invalid"}`,
+		},
+		"GET is not supported": {
+			req: func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com", nil)
+				return req
+			},
+			wantHttpCode: http.StatusMethodNotAllowed,
+			wantError:    `{"error":"invalid_request","error_description":"unsupported method"}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := mockParams{
+				oidcClientCache: fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl),
+				oidcClient:      fake.NewMockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList](ctrl),
+				secretCache:     fake.NewMockCacheInterface[*corev1.Secret](ctrl),
+				sessions:        mocks.NewMockdeviceSessionStore(ctrl),
+				codeCreator:     mocks.NewMockdeviceCodeCreator(ctrl),
+			}
+			if test.mockSetup != nil {
+				test.mockSetup(m)
+			}
+			th := newTokenHandler(nil, nil, nil, nil, nil, nil, m.oidcClientCache, m.oidcClient, m.secretCache, nil, m.sessions, nil)
+			th.now = func() time.Time { return fakeTime }
+			h := newDeviceHandler(th, nil, m.sessions, m.codeCreator)
+			h.now = func() time.Time { return fakeTime }
+			rec := httptest.NewRecorder()
+
+			h.deviceAuthorizationEndpoint(rec, test.req())
+
+			assert.Equal(t, test.wantHttpCode, rec.Code)
+			if test.wantError != "" {
+				assert.JSONEq(t, test.wantError, strings.TrimSpace(rec.Body.String()))
+			}
+			if test.wantResponse != nil {
+				var resp DeviceAuthorizationResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, *test.wantResponse, resp)
+				assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestDeviceVerificationEndpoint(t *testing.T) {
+	const (
+		fakeTokenName  = "fake-token-name"
+		fakeTokenValue = "fake-token-value"
+		fakeUserID     = "fake-user-id"
+		fakeClientID   = "client-id"
+		fakeCSRF       = "csrf-value"
+	)
+	type mockParams struct {
+		tokenCache      *fake.MockNonNamespacedCacheInterface[*v3.Token]
+		userLister      *fake.MockNonNamespacedCacheInterface[*v3.User]
+		oidcClientCache *fake.MockNonNamespacedCacheInterface[*v3.OIDCClient]
+		sessions        *mocks.MockdeviceSessionStore
+	}
+	fakeTime := time.Unix(0, 0)
+	ctrl := gomock.NewController(t)
+	pendingSession := &session.DeviceSession{
+		ClientID: fakeClientID,
+		UserCode: "bcdf2456",
+		Scope:    []string{"openid", "profile"},
+		Status:   session.DevicePending,
+	}
+	authenticateUser := func(m mockParams) {
+		m.tokenCache.EXPECT().Get(fakeTokenName).Return(&v3.Token{
+			ObjectMeta: metav1.ObjectMeta{Name: fakeTokenName},
+			Token:      fakeTokenValue,
+			UserID:     fakeUserID,
+		}, nil)
+		m.userLister.EXPECT().Get(fakeUserID).Return(&v3.User{
+			ObjectMeta: metav1.ObjectMeta{Name: fakeUserID},
+		}, nil)
+	}
+	newRequest := func(method string, data url.Values, cookies ...*http.Cookie) *http.Request {
+		var req *http.Request
+		if method == http.MethodGet {
+			req, _ = http.NewRequest(method, "https://rancher.com/oidc/device?"+data.Encode(), nil)
+		} else {
+			req, _ = http.NewRequest(method, "https://rancher.com/oidc/device", bytes.NewBufferString(data.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.AddCookie(&http.Cookie{Name: "R_SESS", Value: fakeTokenName + ":" + fakeTokenValue})
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		return req
+	}
+
+	tests := map[string]struct {
+		req          func() *http.Request
+		mockSetup    func(mockParams)
+		wantHttpCode int
+		wantResponse *DeviceVerificationResponse
+		wantError    string
+	}{
+		"get a pending request": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{"user_code": {"BCDF-2456"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				m.sessions.EXPECT().GetDevice("bcdf2456", fakeTime).Return(pendingSession, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return([]*v3.OIDCClient{{
+					Spec:   v3.OIDCClientSpec{Description: "my app"},
+					Status: v3.OIDCClientStatus{ClientID: fakeClientID},
+				}}, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantResponse: &DeviceVerificationResponse{
+				ClientID:    fakeClientID,
+				Description: "my app",
+				Scope:       "openid profile",
+				Status:      "pending",
+			},
+		},
+		"approve a request": {
+			req: func() *http.Request {
+				req := newRequest(http.MethodPost, url.Values{"user_code": {"bcdf 2456"}, "approve": {"true"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+				req.Header.Set(csrfHeader, fakeCSRF)
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				approved := *pendingSession
+				approved.Status = session.DeviceApproved
+				approved.TokenName = fakeTokenName
+				m.sessions.EXPECT().ReviewDevice("bcdf2456", true, fakeTokenName, fakeTime).Return(&approved, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return(nil, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantResponse: &DeviceVerificationResponse{
+				ClientID: fakeClientID,
+				Scope:    "openid profile",
+				Status:   "approved",
+			},
+		},
+		"deny a request": {
+			req: func() *http.Request {
+				req := newRequest(http.MethodPost, url.Values{"user_code": {"BCDF-2456"}, "approve": {"false"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+				req.Header.Set(csrfHeader, fakeCSRF)
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				denied := *pendingSession
+				denied.Status = session.DeviceDenied
+				m.sessions.EXPECT().ReviewDevice("bcdf2456", false, fakeTokenName, fakeTime).Return(&denied, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return(nil, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantResponse: &DeviceVerificationResponse{
+				ClientID: fakeClientID,
+				Scope:    "openid profile",
+				Status:   "denied",
+			},
+		},
+		"approving without the CSRF header is forbidden": {
+			req: func() *http.Request {
+				return newRequest(http.MethodPost, url.Values{"user_code": {"BCDF-2456"}, "approve": {"true"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+			},
+			wantHttpCode: http.StatusForbidden,
+			wantError:    `{"error":"access_denied","error_description":"invalid CSRF token"}`,
+		},
+		"unknown user code": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{"user_code": {"BCDF-2456"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				m.sessions.EXPECT().GetDevice("bcdf2456", fakeTime).Return(nil, session.ErrDeviceCodeNotFound)
+			},
+			wantHttpCode: http.StatusNotFound,
+			wantError:    `{"error":"invalid_request","error_description":"invalid user_code"}`,
+		},
+		"expired user code": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{"user_code": {"BCDF-2456"}})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				m.sessions.EXPECT().GetDevice("bcdf2456", fakeTime).Return(nil, session.ErrDeviceCodeExpired)
+			},
+			wantHttpCode: http.StatusBadRequest,
+			wantError:    `{"error":"expired_token","error_description":"the user_code has expired"}`,
+		},
+		"missing Rancher token": {
+			req: func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com/oidc/device?user_code=BCDF-2456", nil)
+				return req
+			},
+			wantHttpCode: http.StatusUnauthorized,
+			wantError:    `{"error":"access_denied","error_description":"log in to Rancher to review the request"}`,
+		},
+	}
+
+	mockProvider := providermocks.NewMockAuthProvider(ctrl)
+	mockProvider.EXPECT().IsDisabledProvider().Return(false, nil).AnyTimes()
+	providers.SetProviders(map[string]common.AuthProvider{"local": mockProvider})
+	t.Cleanup(func() { providers.SetProviders(nil) })
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// no t.Parallel() because of shared controller, provider map
+			tc := fake.NewMockNonNamespacedCacheInterface[*v3.Token](ctrl)
+			sc := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+			uc := fake.NewMockNonNamespacedControllerInterface[*v3.User, *v3.UserList](ctrl)
+			sc.EXPECT().Cache().Return(fake.NewMockCacheInterface[*corev1.Secret](ctrl))
+			uc.EXPECT().Cache().Return(nil)
+			ets := exttokenstore.NewSystem(nil, nil, sc, uc, tc, nil, nil, nil, nil, nil)
+			m := mockParams{
+				tokenCache:      tc,
+				userLister:      fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl),
+				oidcClientCache: fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl),
+				sessions:        mocks.NewMockdeviceSessionStore(ctrl),
+			}
+			if test.mockSetup != nil {
+				test.mockSetup(m)
+			}
+			th := newTokenHandler(ets, nil, nil, nil, nil, nil, m.oidcClientCache, nil, nil, nil, m.sessions, nil)
+			ah := newAuthorizeHandler(ets, m.userLister, nil, nil, m.oidcClientCache)
+			h := newDeviceHandler(th, ah, m.sessions, nil)
+			h.now = func() time.Time { return fakeTime }
+			rec := httptest.NewRecorder()
+
+			h.deviceVerificationEndpoint(rec, test.req())
+
+			assert.Equal(t, test.wantHttpCode, rec.Code)
+			if test.wantError != "" {
+				assert.JSONEq(t, test.wantError, strings.TrimSpace(rec.Body.String()))
+			}
+			if test.wantResponse != nil {
+				var resp DeviceVerificationResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, *test.wantResponse, resp)
+			}
+		})
+	}
+}
+
+func TestCreateTokenFromDeviceCode(t *testing.T) {
+	const (
+		fakeClientID     = "client-id"
+		fakeClientName   = "client-name"
+		fakeClientSecret = "client-secret"
+		fakeDeviceCode   = "device-code"
+	)
+	fakeTime := time.Unix(0, 0)
+	ctrl := gomock.NewController(t)
+	deviceOIDCClient := &v3.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeClientName},
+		Spec: v3.OIDCClientSpec{
+			GrantTypes: []string{grantTypeDeviceCode},
+		},
+		Status: v3.OIDCClientStatus{ClientID: fakeClientID},
+	}
+	clientSecret := &corev1.Secret{
+		Data: map[string][]byte{"client-secret-1": []byte(fakeClientSecret)},
+	}
+
+	tests := map[string]struct {
+		session   *session.DeviceSession
+		pollErr   error
+		wantError string
+	}{
+		"pending": {
+			session:   &session.DeviceSession{Status: session.DevicePending},
+			wantError: `{"error":"authorization_pending","error_description":"the request is pending"}`,
+		},
+		"denied": {
+			session:   &session.DeviceSession{Status: session.DeviceDenied},
+			wantError: `{"error":"access_denied","error_description":"the request was denied"}`,
+		},
+		"polling too often": {
+			pollErr:   session.ErrSlowDown,
+			wantError: `{"error":"slow_down","error_description":"polling too often"}`,
+		},
+		"expired": {
+			pollErr:   session.ErrDeviceCodeExpired,
+			wantError: `{"error":"expired_token","error_description":"the device_code has expired"}`,
+		},
+		"unknown device code": {
+			pollErr:   session.ErrDeviceCodeNotFound,
+			wantError: `{"error":"invalid_grant","error_description":"invalid device_code"}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			oidcClientCache := fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl)
+			oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return([]*v3.OIDCClient{deviceOIDCClient}, nil)
+			secretCache := fake.NewMockCacheInterface[*corev1.Secret](ctrl)
+			secretCache.EXPECT().Get(secretsNamespace, fakeClientID).Return(clientSecret, nil)
+			oidcClient := fake.NewMockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList](ctrl)
+			oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, gomock.Any()).Return(deviceOIDCClient, nil)
+			sessions := mocks.NewMockdeviceSessionStore(ctrl)
+			sessions.EXPECT().PollDevice(fakeDeviceCode, fakeClientID, fakeTime).Return(test.session, test.pollErr)
+			h := newTokenHandler(nil, nil, nil, nil, nil, nil, oidcClientCache, oidcClient, secretCache, nil, sessions, nil)
+			h.now = func() time.Time { return fakeTime }
+
+			data := url.Values{}
+			data.Set("grant_type", grantTypeDeviceCode)
+			data.Set("device_code", fakeDeviceCode)
+			req, _ := http.NewRequest(http.MethodPost, "https://rancher.com", bytes.NewBufferString(data.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+			rec := httptest.NewRecorder()
+
+			h.tokenEndpoint(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.JSONEq(t, test.wantError, strings.TrimSpace(rec.Body.String()))
+		})
+	}
+}
+
+func TestUserCodeFormat(t *testing.T) {
+	assert.Equal(t, "BCDF-2456", formatUserCode("bcdf2456"))
+	assert.Equal(t, "bcdf2456", normalizeUserCode("BCDF-2456"))
+	assert.Equal(t, "bcdf2456", normalizeUserCode("bcdf 2456"))
+	assert.Equal(t, "x7kq9m2p", normalizeUserCode(formatUserCode("x7kq9m2p")))
+}
+
+func TestValidCSRF(t *testing.T) {
+	tests := map[string]struct {
+		header  string
+		form    string
+		cookie  string
+		authz   string
+		invalid bool
+	}{
+		"matching header and cookie":         {header: "value", cookie: "value"},
+		"matching form field and cookie":     {form: "value", cookie: "value"},
+		"mismatching form field and cookie":  {form: "other", cookie: "value", invalid: true},
+		"mismatching header and cookie":      {header: "other", cookie: "value", invalid: true},
+		"missing header":                     {cookie: "value", invalid: true},
+		"missing cookie":                     {header: "value", invalid: true},
+		"authenticated with the auth header": {authz: "Bearer token:key"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "https://rancher.com", nil)
+			if test.header != "" {
+				req.Header.Set(csrfHeader, test.header)
+			}
+			if test.form != "" {
+				req.PostForm = url.Values{csrfFormField: {test.form}}
+			}
+			if test.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "CSRF", Value: test.cookie})
+			}
+			if test.authz != "" {
+				req.Header.Set("Authorization", test.authz)
+			}
+
+			assert.Equal(t, !test.invalid, validCSRF(req))
+		})
+	}
+}
+
+func TestDeviceVerificationPage(t *testing.T) {
+	const (
+		fakeTokenName  = "fake-token-name"
+		fakeTokenValue = "fake-token-value"
+		fakeUserID     = "fake-user-id"
+		fakeClientID   = "client-id"
+		fakeCSRF       = "csrf-value"
+		fakeServerUrl  = "https://www.fake.com"
+	)
+	type mockParams struct {
+		tokenCache      *fake.MockNonNamespacedCacheInterface[*v3.Token]
+		userLister      *fake.MockNonNamespacedCacheInterface[*v3.User]
+		oidcClientCache *fake.MockNonNamespacedCacheInterface[*v3.OIDCClient]
+		sessions        *mocks.MockdeviceSessionStore
+	}
+	_ = settings.ServerURL.Set(fakeServerUrl)
+	fakeTime := time.Unix(0, 0)
+	ctrl := gomock.NewController(t)
+	pendingSession := &session.DeviceSession{
+		ClientID: fakeClientID,
+		UserCode: "bcdf2456",
+		Scope:    []string{"openid", "profile"},
+		Status:   session.DevicePending,
+	}
+	authenticateUser := func(m mockParams) {
+		m.tokenCache.EXPECT().Get(fakeTokenName).Return(&v3.Token{
+			ObjectMeta: metav1.ObjectMeta{Name: fakeTokenName},
+			Token:      fakeTokenValue,
+			UserID:     fakeUserID,
+		}, nil)
+		m.userLister.EXPECT().Get(fakeUserID).Return(&v3.User{
+			ObjectMeta: metav1.ObjectMeta{Name: fakeUserID},
+		}, nil)
+	}
+	newRequest := func(method string, data url.Values, cookies ...*http.Cookie) *http.Request {
+		var req *http.Request
+		if method == http.MethodGet {
+			req, _ = http.NewRequest(method, "https://rancher.com/oidc/device?"+data.Encode(), nil)
+		} else {
+			req, _ = http.NewRequest(method, "https://rancher.com/oidc/device", bytes.NewBufferString(data.Encode()))
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		}
+		req.Header.Set("Accept", "text/html,application/xhtml+xml")
+		req.AddCookie(&http.Cookie{Name: "R_SESS", Value: fakeTokenName + ":" + fakeTokenValue})
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		return req
+	}
+
+	tests := map[string]struct {
+		req          func() *http.Request
+		mockSetup    func(mockParams)
+		wantHttpCode int
+		wantContains []string
+		wantCSRF     bool
+	}{
+		"log in first": {
+			req: func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com/oidc/device?user_code=BCDF-2456", nil)
+				req.Header.Set("Accept", "text/html")
+				return req
+			},
+			wantHttpCode: http.StatusUnauthorized,
+			wantContains: []string{`href="` + fakeServerUrl + `/dashboard/auth/login"`},
+		},
+		"enter a user code": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{})
+			},
+			mockSetup:    authenticateUser,
+			wantHttpCode: http.StatusOK,
+			wantContains: []string{`<input id="user_code" name="user_code"`},
+			wantCSRF:     true,
+		},
+		"review a pending request": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{"user_code": {"bcdf2456"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				m.sessions.EXPECT().GetDevice("bcdf2456", fakeTime).Return(pendingSession, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return([]*v3.OIDCClient{{
+					Spec:   v3.OIDCClientSpec{Description: "<my app>"},
+					Status: v3.OIDCClientStatus{ClientID: fakeClientID},
+				}}, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantContains: []string{
+				"<strong>client-id</strong> (&lt;my app&gt;)",
+				"<code>openid profile</code>",
+				`<input type="hidden" name="user_code" value="BCDF-2456">`,
+				`<input type="hidden" name="csrf" value="` + fakeCSRF + `">`,
+				`name="approve" value="true"`,
+			},
+		},
+		"approve a request with the CSRF form field": {
+			req: func() *http.Request {
+				return newRequest(http.MethodPost, url.Values{"user_code": {"BCDF-2456"}, "approve": {"true"}, "csrf": {fakeCSRF}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				approved := *pendingSession
+				approved.Status = session.DeviceApproved
+				approved.TokenName = fakeTokenName
+				m.sessions.EXPECT().ReviewDevice("bcdf2456", true, fakeTokenName, fakeTime).Return(&approved, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return(nil, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantContains: []string{"The request was approved"},
+		},
+		"deny a request with the CSRF form field": {
+			req: func() *http.Request {
+				return newRequest(http.MethodPost, url.Values{"user_code": {"BCDF-2456"}, "approve": {"false"}, "csrf": {fakeCSRF}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				denied := *pendingSession
+				denied.Status = session.DeviceDenied
+				m.sessions.EXPECT().ReviewDevice("bcdf2456", false, fakeTokenName, fakeTime).Return(&denied, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return(nil, nil)
+			},
+			wantHttpCode: http.StatusOK,
+			wantContains: []string{"The request was denied"},
+		},
+		"approving with a wrong CSRF form field is forbidden": {
+			req: func() *http.Request {
+				return newRequest(http.MethodPost, url.Values{"user_code": {"BCDF-2456"}, "approve": {"true"}, "csrf": {"other"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup:    authenticateUser,
+			wantHttpCode: http.StatusForbidden,
+			wantContains: []string{"invalid CSRF token"},
+		},
+		"unknown user code": {
+			req: func() *http.Request {
+				return newRequest(http.MethodGet, url.Values{"user_code": {"BCDF-2456"}}, &http.Cookie{Name: "CSRF", Value: fakeCSRF})
+			},
+			mockSetup: func(m mockParams) {
+				authenticateUser(m)
+				m.sessions.EXPECT().GetDevice("bcdf2456", fakeTime).Return(nil, session.ErrDeviceCodeNotFound)
+			},
+			wantHttpCode: http.StatusNotFound,
+			wantContains: []string{"invalid user_code", `<a href="device">Enter another code</a>`},
+		},
+	}
+
+	mockProvider := providermocks.NewMockAuthProvider(ctrl)
+	mockProvider.EXPECT().IsDisabledProvider().Return(false, nil).AnyTimes()
+	providers.SetProviders(map[string]common.AuthProvider{"local": mockProvider})
+	t.Cleanup(func() { providers.SetProviders(nil) })
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// no t.Parallel() because of shared controller, provider map
+			tc := fake.NewMockNonNamespacedCacheInterface[*v3.Token](ctrl)
+			sc := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+			uc := fake.NewMockNonNamespacedControllerInterface[*v3.User, *v3.UserList](ctrl)
+			sc.EXPECT().Cache().Return(fake.NewMockCacheInterface[*corev1.Secret](ctrl))
+			uc.EXPECT().Cache().Return(nil)
+			ets := exttokenstore.NewSystem(nil, nil, sc, uc, tc, nil, nil, nil, nil, nil)
+			m := mockParams{
+				tokenCache:      tc,
+				userLister:      fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl),
+				oidcClientCache: fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl),
+				sessions:        mocks.NewMockdeviceSessionStore(ctrl),
+			}
+			if test.mockSetup != nil {
+				test.mockSetup(m)
+			}
+			th := newTokenHandler(ets, nil, nil, nil, nil, nil, m.oidcClientCache, nil, nil, nil, m.sessions, nil)
+			ah := newAuthorizeHandler(ets, m.userLister, nil, nil, m.oidcClientCache)
+			h := newDeviceHandler(th, ah, m.sessions, nil)
+			h.now = func() time.Time { return fakeTime }
+			rec := httptest.NewRecorder()
+
+			h.deviceVerificationEndpoint(rec, test.req())
+
+			assert.Equal(t, test.wantHttpCode, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+			for _, want := range test.wantContains {
+				assert.Contains(t, rec.Body.String(), want)
+			}
+			var csrfCookie *http.Cookie
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == "CSRF" {
+					csrfCookie = cookie
+				}
+			}
+			if test.wantCSRF {
+				if assert.NotNil(t, csrfCookie) {
+					assert.Len(t, csrfCookie.Value, 32)
+					assert.Equal(t, "/", csrfCookie.Path)
+				}
+			} else {
+				assert.Nil(t, csrfCookie)
+			}
+		})
+	}
+}
+
+// memoryDeviceSessions is an in-memory deviceSessionStore.
+type memoryDeviceSessions struct {
+	sessions map[string]*session.DeviceSession
+}
+
+func (m *memoryDeviceSessions) AddDevice(deviceCode string, s session.DeviceSession) error {
+	m.sessions[deviceCode] = &s
+	return nil
+}
+
+func (m *memoryDeviceSessions) pending(userCode string) (*session.DeviceSession, error) {
+	for _, s := range m.sessions {
+		if s.UserCode == userCode && s.Status == session.DevicePending {
+			return s, nil
+		}
+	}
+	return nil, session.ErrDeviceCodeNotFound
+}
+
+func (m *memoryDeviceSessions) GetDevice(userCode string, _ time.Time) (*session.DeviceSession, error) {
+	return m.pending(userCode)
+}
+
+func (m *memoryDeviceSessions) ReviewDevice(userCode string, approved bool, tokenName string, _ time.Time) (*session.DeviceSession, error) {
+	s, err := m.pending(userCode)
+	if err != nil {
+		return nil, err
+	}
+	if approved {
+		s.Status = session.DeviceApproved
+		s.TokenName = tokenName
+	} else {
+		s.Status = session.DeviceDenied
+	}
+	return s, nil
+}
+
+func (m *memoryDeviceSessions) PollDevice(deviceCode string, clientID string, _ time.Time) (*session.DeviceSession, error) {
+	s, ok := m.sessions[deviceCode]
+	if !ok || s.ClientID != clientID {
+		return nil, session.ErrDeviceCodeNotFound
+	}
+	if s.Status != session.DevicePending {
+		delete(m.sessions, deviceCode)
+	}
+	return s, nil
+}
+
+func TestDeviceFlow(t *testing.T) {
+	const (
+		fakeClientID     = "client-id"
+		fakeClientName   = "client-name"
+		fakeClientSecret = "client-secret"
+		fakeTokenName    = "fake-token-name"
+		fakeTokenValue   = "fake-token-value"
+		fakeUserID       = "fake-user-id"
+		fakeServerUrl    = "https://www.fake.com"
+	)
+	_ = settings.ServerURL.Set(fakeServerUrl)
+	fakeTime := time.Unix(0, 0)
+	ctrl := gomock.NewController(t)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	deviceOIDCClient := &v3.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeClientName},
+		Spec: v3.OIDCClientSpec{
+			GrantTypes:             []string{grantTypeDeviceCode},
+			TokenExpirationSeconds: 3600,
+		},
+		Status: v3.OIDCClientStatus{ClientID: fakeClientID},
+	}
+
+	mockProvider := providermocks.NewMockAuthProvider(ctrl)
+	mockProvider.EXPECT().IsDisabledProvider().Return(false, nil).AnyTimes()
+	providers.SetProviders(map[string]common.AuthProvider{"local": mockProvider})
+	t.Cleanup(func() { providers.SetProviders(nil) })
+
+	tokenCache := fake.NewMockNonNamespacedCacheInterface[*v3.Token](ctrl)
+	tokenCache.EXPECT().Get(fakeTokenName).Return(&v3.Token{
+		ObjectMeta:   metav1.ObjectMeta{Name: fakeTokenName},
+		Token:        fakeTokenValue,
+		UserID:       fakeUserID,
+		Enabled:      ptr.To(true),
+		AuthProvider: "local",
+	}, nil).AnyTimes()
+	userLister := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+	userLister.EXPECT().Get(fakeUserID).Return(&v3.User{
+		ObjectMeta:  metav1.ObjectMeta{Name: fakeUserID},
+		DisplayName: "Fake User",
+	}, nil).AnyTimes()
+	userAttributeLister := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+	userAttributeLister.EXPECT().Get(fakeUserID).Return(nil, apierrors.NewNotFound(schema.GroupResource{}, fakeUserID))
+	oidcClientCache := fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl)
+	oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeClientID).Return([]*v3.OIDCClient{deviceOIDCClient}, nil).AnyTimes()
+	oidcClient := fake.NewMockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList](ctrl)
+	oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, gomock.Any()).Return(deviceOIDCClient, nil).AnyTimes()
+	secretCache := fake.NewMockCacheInterface[*corev1.Secret](ctrl)
+	secretCache.EXPECT().Get(secretsNamespace, fakeClientID).Return(&corev1.Secret{
+		Data: map[string][]byte{"client-secret-1": []byte(fakeClientSecret)},
+	}, nil).AnyTimes()
+	signingKeyGetter := mocks.NewMocksigningKeyGetter(ctrl)
+	signingKeyGetter.EXPECT().GetSigningKey().Return(privateKey, "kid", nil)
+	codeCreator := mocks.NewMockdeviceCodeCreator(ctrl)
+	codeCreator.EXPECT().GenerateDeviceCode().Return("device-code", nil)
+	codeCreator.EXPECT().GenerateUserCode().Return("bcdf2456", nil)
+	sc := fake.NewMockControllerInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+	uc := fake.NewMockNonNamespacedControllerInterface[*v3.User, *v3.UserList](ctrl)
+	sc.EXPECT().Cache().Return(fake.NewMockCacheInterface[*corev1.Secret](ctrl))
+	uc.EXPECT().Cache().Return(nil)
+	ets := exttokenstore.NewSystem(nil, nil, sc, uc, tokenCache, nil, nil, nil, nil, nil)
+	sessions := &memoryDeviceSessions{sessions: map[string]*session.DeviceSession{}}
+
+	th := newTokenHandler(ets, tokenCache, userLister, userAttributeLister, nil, signingKeyGetter, oidcClientCache, oidcClient, secretCache, nil, sessions, nil)
+	th.now = func() time.Time { return fakeTime }
+	ah := newAuthorizeHandler(ets, userLister, nil, nil, oidcClientCache)
+	h := newDeviceHandler(th, ah, sessions, codeCreator)
+	h.now = func() time.Time { return fakeTime }
+
+	clientRequest := func(data url.Values) *http.Request {
+		req, _ := http.NewRequest(http.MethodPost, "https://rancher.com", bytes.NewBufferString(data.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+		return req
+	}
+	pollToken := func(deviceCode string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		th.tokenEndpoint(rec, clientRequest(url.Values{"grant_type": {grantTypeDeviceCode}, "device_code": {deviceCode}}))
+		return rec
+	}
+	browserRequest := func(req *http.Request, cookies ...*http.Cookie) *http.Request {
+		req.Header.Set("Accept", "text/html")
+		req.AddCookie(&http.Cookie{Name: "R_SESS", Value: fakeTokenName + ":" + fakeTokenValue})
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+
+	// the device gets a device and a user code.
+	rec := httptest.NewRecorder()
+	h.deviceAuthorizationEndpoint(rec, clientRequest(url.Values{"scope": {"openid profile"}}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var authorization DeviceAuthorizationResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &authorization))
+
+	// the device polls while the request is pending.
+	rec = pollToken(authorization.DeviceCode)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"authorization_pending","error_description":"the request is pending"}`, strings.TrimSpace(rec.Body.String()))
+
+	// the user opens the verification page of the user code, which sets the CSRF cookie.
+	req, _ := http.NewRequest(http.MethodGet, authorization.VerificationURIComplete, nil)
+	rec = httptest.NewRecorder()
+	h.deviceVerificationEndpoint(rec, browserRequest(req))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<strong>client-id</strong>")
+	var csrfCookie *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "CSRF" {
+			csrfCookie = cookie
+		}
+	}
+	if !assert.NotNil(t, csrfCookie) {
+		return
+	}
+	assert.Contains(t, rec.Body.String(), `<input type="hidden" name="csrf" value="`+csrfCookie.Value+`">`)
+
+	// the user approves the request with the page form.
+	form := url.Values{"user_code": {authorization.UserCode}, "approve": {"true"}, "csrf": {csrfCookie.Value}}
+	req, _ = http.NewRequest(http.MethodPost, authorization.VerificationURIComplete, bytes.NewBufferString(form.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.deviceVerificationEndpoint(rec, browserRequest(req, &http.Cookie{Name: "CSRF", Value: csrfCookie.Value}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "The request was approved")
+
+	// the device exchanges the device code for tokens of the user.
+	rec = pollToken(authorization.DeviceCode)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var tokenResponse TokenResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokenResponse))
+	assert.NotEmpty(t, tokenResponse.IDToken)
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenResponse.AccessToken, &claims, func(token *jwt.Token) (any, error) {
+		return &privateKey.PublicKey, nil
+	}, jwt.WithTimeFunc(func() time.Time { return fakeTime }))
+	assert.NoError(t, err)
+	assert.Equal(t, fakeUserID, claims["sub"])
+	assert.Equal(t, fakeTokenName, claims["token"])
+
+	// the device code can only be exchanged once.
+	rec = pollToken(authorization.DeviceCode)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"invalid_grant","error_description":"invalid device_code"}`, strings.TrimSpace(rec.Body.String()))
+}
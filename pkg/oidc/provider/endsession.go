@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rancher/rancher/pkg/auth/accessor"
+	"github.com/rancher/rancher/pkg/auth/providers/azure"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	oidcerror "github.com/rancher/rancher/pkg/oidc/provider/error"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+)
+
+var cookieUnsetTimestamp = time.Date(1982, time.February, 10, 23, 0, 0, 0, time.UTC)
+
+type rancherSessionManager interface {
+	GetToken(tokenAuthValue string) (accessor.TokenAccessor, int, error)
+	DeleteTokenByName(name string) (int, error)
+}
+
+// endSessionHandler implements RP-initiated logout. It logs the user out of Rancher, which invalidates the refresh
+// tokens issued for the Rancher session of the user as well.
+type endSessionHandler struct {
+	tokenHandler *tokenHandler
+	tokenMgr     rancherSessionManager
+}
+
+func newEndSessionHandler(tokenHandler *tokenHandler, tokenMgr rancherSessionManager) *endSessionHandler {
+	return &endSessionHandler{
+		tokenHandler: tokenHandler,
+		tokenMgr:     tokenMgr,
+	}
+}
+
+// endSessionEndpoint handles the end_session endpoint of the OIDC provider. The id_token_hint parameter is required,
+// and the user is only logged out of Rancher if it's the subject of the id_token. Anyone holding an id_token of the
+// user could otherwise log the user out with a link, so the user must confirm the logout on a page posting back to
+// this endpoint. The user is then redirected to the post_logout_redirect_uri, which must be registered in the OIDC
+// client, or to the Rancher login page.
+func (h *endSessionHandler) endSessionEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "unsupported method", http.StatusMethodNotAllowed, w)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "error parsing parameters from request", http.StatusBadRequest, w)
+		return
+	}
+	idTokenHint := r.Form.Get("id_token_hint")
+	if idTokenHint == "" {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "missing id_token_hint", http.StatusBadRequest, w)
+		return
+	}
+	// the id_token is likely expired by the time the user logs out.
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(idTokenHint, &claims, h.tokenHandler.verificationKey, jwt.WithoutClaimsValidation()); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "invalid id_token_hint", http.StatusBadRequest, w)
+		return
+	}
+	aud, _ := claims.GetAudience()
+	clientID := r.Form.Get("client_id")
+	if clientID == "" && len(aud) > 0 {
+		clientID = aud[0]
+	}
+	if !slices.Contains(aud, clientID) {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "client_id doesn't match the id_token_hint", http.StatusBadRequest, w)
+		return
+	}
+
+	redirectURI := r.Form.Get("post_logout_redirect_uri")
+	if redirectURI != "" {
+		oidcClient, err := h.tokenHandler.getOIDCClientByClientID(clientID)
+		if err != nil {
+			oidcerror.WriteError(oidcerror.InvalidRequest, "invalid client_id", http.StatusBadRequest, w)
+			return
+		}
+		if !slices.Contains(oidcClient.Spec.PostLogoutRedirectURIs, redirectURI) {
+			oidcerror.WriteError(oidcerror.InvalidRequest, "post_logout_redirect_uri not registered", http.StatusBadRequest, w)
+			return
+		}
+	}
+
+	sub, _ := claims.GetSubject()
+	if storedToken := h.sessionToken(r, sub); storedToken != nil {
+		if !logoutConfirmed(r) {
+			csrf, err := csrfCookieValue(w, r)
+			if err != nil {
+				logrus.Errorf("[OIDC provider] error generating CSRF token: %v", err)
+				oidcerror.WriteError(oidcerror.ServerError, "failed to generate CSRF token", http.StatusInternalServerError, w)
+				return
+			}
+			writePage(w, http.StatusOK, endSessionPageTemplate, endSessionPage{
+				IDTokenHint:           idTokenHint,
+				ClientID:              clientID,
+				PostLogoutRedirectURI: redirectURI,
+				State:                 r.Form.Get("state"),
+				CSRF:                  csrf,
+				CancelURL:             settings.ServerURL.Get() + "/dashboard/",
+			})
+			return
+		}
+		if err := h.logout(w, r, storedToken); err != nil {
+			logrus.Errorf("[OIDC provider] error logging out user %s: %v", sub, err)
+			oidcerror.WriteError(oidcerror.ServerError, "failed to log out", http.StatusInternalServerError, w)
+			return
+		}
+	}
+
+	if redirectURI == "" {
+		http.Redirect(w, r, settings.ServerURL.Get()+"/dashboard/auth/login?logged-out=true", http.StatusFound)
+		return
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "invalid post_logout_redirect_uri", http.StatusBadRequest, w)
+		return
+	}
+	if state := r.Form.Get("state"); state != "" {
+		q := u.Query()
+		q.Set("state", state)
+		u.RawQuery = q.Encode()
+	}
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// sessionToken returns the Rancher token of the request if it belongs to the user. Requests without a valid Rancher
+// token are already logged out.
+func (h *endSessionHandler) sessionToken(r *http.Request, userID string) accessor.TokenAccessor {
+	tokenAuthValue := tokens.GetTokenAuthFromRequest(r)
+	if tokenAuthValue == "" {
+		return nil
+	}
+	storedToken, _, err := h.tokenMgr.GetToken(tokenAuthValue)
+	if err != nil {
+		logrus.Debugf("[OIDC provider] not logging out invalid Rancher token: %v", err)
+		return nil
+	}
+	if storedToken.GetUserID() != userID {
+		logrus.Debugf("[OIDC provider] not logging out Rancher token %s of another user", storedToken.GetName())
+		return nil
+	}
+
+	return storedToken
+}
+
+// logoutConfirmed returns whether the request was posted by the user with the confirmation page.
+func logoutConfirmed(r *http.Request) bool {
+	return r.Method == http.MethodPost && r.PostForm.Get("confirm") == "true" && validCSRF(r)
+}
+
+// logout deletes the Rancher token of the request and unsets the session cookies, like the Rancher logout.
+func (h *endSessionHandler) logout(w http.ResponseWriter, r *http.Request, storedToken accessor.TokenAccessor) error {
+	isSecure := r.URL.Scheme == "https"
+	for _, cookieName := range []string{tokens.CookieName, tokens.CSRFCookie, tokens.IDTokenCookieName, azure.IDTokenCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    "",
+			Secure:   isSecure,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   -1,
+			Expires:  cookieUnsetTimestamp,
+		})
+	}
+	_, err := h.tokenMgr.DeleteTokenByName(storedToken.GetName())
+
+	return err
+}
+
+// endSessionPage is the data of the logout confirmation page. The parameters of the request are posted back with the
+// confirmation.
+type endSessionPage struct {
+	IDTokenHint           string
+	ClientID              string
+	PostLogoutRedirectURI string
+	State                 string
+	// CSRF is the value of the CSRF cookie, posted back with the confirmation.
+	CSRF string
+	// CancelURL is where the user goes when not logging out.
+	CancelURL string
+}
+
+var endSessionPageTemplate = template.Must(template.New("end session").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Rancher - Log out</title>
+</head>
+<body>
+<h1>Log out</h1>
+<p><strong>{{.ClientID}}</strong> requests to log you out of Rancher.</p>
+<form method="post" action="end_session">
+<input type="hidden" name="id_token_hint" value="{{.IDTokenHint}}">
+<input type="hidden" name="client_id" value="{{.ClientID}}">
+{{- with .PostLogoutRedirectURI}}
+<input type="hidden" name="post_logout_redirect_uri" value="{{.}}">
+{{- end}}
+{{- with .State}}
+<input type="hidden" name="state" value="{{.}}">
+{{- end}}
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+<button type="submit" name="confirm" value="true">Log out</button>
+</form>
+<p><a href="{{.CancelURL}}">Stay logged in</a></p>
+</body>
+</html>
+`))
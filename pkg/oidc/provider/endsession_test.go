@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/oidc/mocks"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEndSessionEndpoint(t *testing.T) {
+	const (
+		fakeServerURL   = "https://rancher.com"
+		fakeRedirectURI = "https://client.com/logged-out"
+		fakeTokenValue  = "token-name:secret"
+	)
+	ctrl := gomock.NewController(t)
+	_ = settings.ServerURL.Set(fakeServerURL)
+	t.Cleanup(func() { _ = settings.ServerURL.Set("") })
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	// the id_token_hint is accepted even if it's expired.
+	idToken := signToken(t, privateKey, jwt.MapClaims{
+		"aud": []string{fakeIntrospectionClientID},
+		"exp": time.Now().Add(-time.Hour).Unix(),
+		"sub": fakeIntrospectionUserID,
+	})
+	rancherToken := &v3.Token{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeIntrospectionTokenName},
+		UserID:     fakeIntrospectionUserID,
+	}
+	oidcClient := &v3.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeIntrospectionClientName},
+		Spec: v3.OIDCClientSpec{
+			PostLogoutRedirectURIs: []string{fakeRedirectURI},
+		},
+		Status: v3.OIDCClientStatus{ClientID: fakeIntrospectionClientID},
+	}
+	newRequest := func(params url.Values, withToken bool) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, fakeServerURL+"/oidc/end_session?"+params.Encode(), nil)
+		if withToken {
+			req.AddCookie(&http.Cookie{Name: tokens.CookieName, Value: fakeTokenValue})
+		}
+		return req
+	}
+	// newConfirmation posts the params the way the confirmation page does.
+	newConfirmation := func(params url.Values, csrf string) *http.Request {
+		params.Set("confirm", "true")
+		params.Set("csrf", csrf)
+		req := httptest.NewRequest(http.MethodPost, fakeServerURL+"/oidc/end_session", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: tokens.CookieName, Value: fakeTokenValue})
+		req.AddCookie(&http.Cookie{Name: tokens.CSRFCookie, Value: "csrf123"})
+		return req
+	}
+
+	tests := map[string]struct {
+		req           func() *http.Request
+		mockSetup     func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager)
+		wantCode      int
+		wantLocation  string
+		wantBody      string
+		wantPage      []string
+		wantLoggedOut bool
+	}{
+		"asks the user to confirm the logout": {
+			req: func() *http.Request {
+				return newRequest(url.Values{
+					"id_token_hint":            {idToken},
+					"post_logout_redirect_uri": {fakeRedirectURI},
+					"state":                    {"state123"},
+				}, true)
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeIntrospectionClientID).Return([]*v3.OIDCClient{oidcClient}, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(rancherToken, http.StatusOK, nil)
+			},
+			wantCode: http.StatusOK,
+			wantPage: []string{
+				`<input type="hidden" name="id_token_hint" value="` + idToken + `">`,
+				`<input type="hidden" name="client_id" value="` + fakeIntrospectionClientID + `">`,
+				`<input type="hidden" name="post_logout_redirect_uri" value="` + fakeRedirectURI + `">`,
+				`<input type="hidden" name="state" value="state123">`,
+				`<button type="submit" name="confirm" value="true">Log out</button>`,
+			},
+		},
+		"asks the user to confirm a logout posted without the CSRF cookie value": {
+			req: func() *http.Request {
+				return newConfirmation(url.Values{"id_token_hint": {idToken}}, "other")
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(rancherToken, http.StatusOK, nil)
+			},
+			wantCode: http.StatusOK,
+			wantPage: []string{`<input type="hidden" name="csrf" value="csrf123">`},
+		},
+		"logs out and redirects to the post logout redirect uri": {
+			req: func() *http.Request {
+				return newConfirmation(url.Values{
+					"id_token_hint":            {idToken},
+					"post_logout_redirect_uri": {fakeRedirectURI},
+					"state":                    {"state123"},
+				}, "csrf123")
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeIntrospectionClientID).Return([]*v3.OIDCClient{oidcClient}, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(rancherToken, http.StatusOK, nil)
+				tokenMgr.EXPECT().DeleteTokenByName(fakeIntrospectionTokenName).Return(http.StatusOK, nil)
+			},
+			wantCode:      http.StatusFound,
+			wantLocation:  fakeRedirectURI + "?state=state123",
+			wantLoggedOut: true,
+		},
+		"logs out and redirects to the login page": {
+			req: func() *http.Request {
+				return newConfirmation(url.Values{"id_token_hint": {idToken}}, "csrf123")
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(rancherToken, http.StatusOK, nil)
+				tokenMgr.EXPECT().DeleteTokenByName(fakeIntrospectionTokenName).Return(http.StatusOK, nil)
+			},
+			wantCode:      http.StatusFound,
+			wantLocation:  fakeServerURL + "/dashboard/auth/login?logged-out=true",
+			wantLoggedOut: true,
+		},
+		"doesn't log out the Rancher token of another user": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"id_token_hint": {idToken}}, true)
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(&v3.Token{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-token"},
+					UserID:     "other-user-id",
+				}, http.StatusOK, nil)
+			},
+			wantCode:     http.StatusFound,
+			wantLocation: fakeServerURL + "/dashboard/auth/login?logged-out=true",
+		},
+		"redirects without a Rancher token": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"id_token_hint": {idToken}}, false)
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+			},
+			wantCode:     http.StatusFound,
+			wantLocation: fakeServerURL + "/dashboard/auth/login?logged-out=true",
+		},
+		"error deleting the Rancher token": {
+			req: func() *http.Request {
+				return newConfirmation(url.Values{"id_token_hint": {idToken}}, "csrf123")
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				tokenMgr.EXPECT().GetToken(fakeTokenValue).Return(rancherToken, http.StatusOK, nil)
+				tokenMgr.EXPECT().DeleteTokenByName(fakeIntrospectionTokenName).Return(http.StatusInternalServerError, fmt.Errorf("unexpected error"))
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"server_error","error_description":"failed to log out"}`,
+		},
+		"unregistered post logout redirect uri": {
+			req: func() *http.Request {
+				return newRequest(url.Values{
+					"id_token_hint":            {idToken},
+					"post_logout_redirect_uri": {"https://evil.com"},
+				}, true)
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeIntrospectionClientID).Return([]*v3.OIDCClient{oidcClient}, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid_request","error_description":"post_logout_redirect_uri not registered"}`,
+		},
+		"client_id doesn't match the id_token_hint": {
+			req: func() *http.Request {
+				return newRequest(url.Values{
+					"id_token_hint": {idToken},
+					"client_id":     {"other-client-id"},
+				}, true)
+			},
+			mockSetup: func(m introspectionMockParams, tokenMgr *mocks.MockrancherSessionManager) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid_request","error_description":"client_id doesn't match the id_token_hint"}`,
+		},
+		"invalid id_token_hint": {
+			req: func() *http.Request {
+				return newRequest(url.Values{"id_token_hint": {"invalid"}}, true)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid_request","error_description":"invalid id_token_hint"}`,
+		},
+		"missing id_token_hint": {
+			req: func() *http.Request {
+				return newRequest(url.Values{}, true)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid_request","error_description":"missing id_token_hint"}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := newIntrospectionMocks(ctrl)
+			tokenMgr := mocks.NewMockrancherSessionManager(ctrl)
+			if test.mockSetup != nil {
+				test.mockSetup(m, tokenMgr)
+			}
+			h := newEndSessionHandler(m.tokenHandler(), tokenMgr)
+			rec := httptest.NewRecorder()
+
+			h.endSessionEndpoint(rec, test.req())
+
+			assert.Equal(t, test.wantCode, rec.Code)
+			assert.Equal(t, test.wantLocation, rec.Header().Get("Location"))
+			if test.wantBody != "" {
+				assert.JSONEq(t, test.wantBody, strings.TrimSpace(rec.Body.String()))
+			}
+			for _, want := range test.wantPage {
+				assert.Contains(t, rec.Body.String(), want)
+			}
+			var unsetCookies []string
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.MaxAge < 0 {
+					unsetCookies = append(unsetCookies, cookie.Name)
+				}
+			}
+			if test.wantLoggedOut {
+				assert.Contains(t, unsetCookies, tokens.CookieName)
+				assert.Contains(t, unsetCookies, tokens.CSRFCookie)
+			} else {
+				assert.Empty(t, unsetCookies)
+			}
+		})
+	}
+}
@@ -18,6 +18,20 @@
 	InvalidScope = "invalid_scope"
 	// ServerError the authorization server encountered an unexpected condition that prevented it from fulfilling the request.
 	ServerError = "server_error"
+	// InvalidClient client authentication failed.
+	InvalidClient = "invalid_client"
+	// InvalidGrant the provided authorization grant is invalid, expired, revoked, or was issued to another client.
+	InvalidGrant = "invalid_grant"
+	// UnauthorizedClient the authenticated client is not authorized to use this authorization grant type.
+	UnauthorizedClient = "unauthorized_client"
+	// AuthorizationPending the device authorization request is still pending as the user hasn't yet completed the user-interaction steps.
+	AuthorizationPending = "authorization_pending"
+	// SlowDown the device authorization request is still pending, and the client must poll less frequently.
+	SlowDown = "slow_down"
+	// ExpiredToken the device_code has expired, and the device authorization session has concluded.
+	ExpiredToken = "expired_token"
+	// InvalidToken the access token provided is expired, revoked, malformed, or invalid.
+	InvalidToken = "invalid_token"
 )
 
 // Error represents an error returned.
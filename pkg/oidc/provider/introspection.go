@@ -0,0 +1,225 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rancher/rancher/pkg/auth/accessor"
+	"github.com/rancher/rancher/pkg/auth/providers"
+	oidcerror "github.com/rancher/rancher/pkg/oidc/provider/error"
+	"github.com/sirupsen/logrus"
+)
+
+type tokenRevoker interface {
+	RevokeToken(token string, expiresAt time.Time) error
+	IsTokenRevoked(token string) (bool, error)
+}
+
+// IntrospectionResponse represents the response returned by the introspection endpoint, as defined in RFC 7662.
+// Only Active is set for inactive tokens.
+type IntrospectionResponse struct {
+	// Active is whether the token is active.
+	Active bool `json:"active"`
+	// Scope is the space separated scope of the token.
+	Scope string `json:"scope,omitempty"`
+	// ClientID is the OIDC client the token was issued to.
+	ClientID string `json:"client_id,omitempty"`
+	// TokenType is the type of an access token.
+	TokenType string `json:"token_type,omitempty"`
+	// Exp is when the token expires, in seconds since the epoch.
+	Exp int64 `json:"exp,omitempty"`
+	// Iat is when the token was issued, in seconds since the epoch.
+	Iat int64 `json:"iat,omitempty"`
+	// Sub is the subject of the token, either a Rancher user or an OIDC client.
+	Sub string `json:"sub,omitempty"`
+	// Aud is the audience of the token.
+	Aud []string `json:"aud,omitempty"`
+	// Iss is the issuer of the token.
+	Iss string `json:"iss,omitempty"`
+}
+
+// introspectionEndpoint handles the introspection endpoint of the OIDC provider. OIDC clients can only introspect the
+// tokens issued to them.
+func (h *tokenHandler) introspectionEndpoint(w http.ResponseWriter, r *http.Request) {
+	token, oidcClientID, ok := h.parseTokenRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.introspect(token, oidcClientID)
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error introspecting token: %v", err)
+		oidcerror.WriteError(oidcerror.ServerError, "failed to introspect token", http.StatusInternalServerError, w)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// revocationEndpoint handles the revocation endpoint of the OIDC provider, as defined in RFC 7009. Revoked access
+// tokens are reported inactive by the introspection endpoint, and revoked refresh tokens can't be used anymore.
+func (h *tokenHandler) revocationEndpoint(w http.ResponseWriter, r *http.Request) {
+	token, oidcClientID, ok := h.parseTokenRequest(w, r)
+	if !ok {
+		return
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, &claims, h.verificationKey); err != nil {
+		// invalid and expired tokens don't need to be revoked.
+		logrus.Debugf("[OIDC provider] not revoking invalid token: %v", err)
+		return
+	}
+	aud, _ := claims.GetAudience()
+	if !slices.Contains(aud, oidcClientID) {
+		oidcerror.WriteError(oidcerror.UnauthorizedClient, "the token was not issued to this client", http.StatusBadRequest, w)
+		return
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "the token doesn't expire", http.StatusBadRequest, w)
+		return
+	}
+	if err := h.revocations.RevokeToken(token, exp.Time); err != nil {
+		logrus.Errorf("[OIDC provider] error revoking token: %v", err)
+		oidcerror.WriteError(oidcerror.ServerError, "failed to revoke token", http.StatusInternalServerError, w)
+		return
+	}
+}
+
+// parseTokenRequest authenticates the OIDC client of an introspection or revocation request, and returns the token
+// of the request. It writes the error in the response otherwise.
+func (h *tokenHandler) parseTokenRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	if r.Method != http.MethodPost {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "unsupported method", http.StatusMethodNotAllowed, w)
+		return "", "", false
+	}
+	if err := r.ParseForm(); err != nil {
+		oidcerror.WriteError(oidcerror.InvalidRequest, fmt.Sprintf("error parsing parameters from request %v", err), http.StatusBadRequest, w)
+		return "", "", false
+	}
+	oidcClient, oidcErr := h.verifyClientCredentials(r)
+	if oidcErr != nil {
+		logrus.Debug("[OIDC provider] error authenticating client: " + oidcErr.ToString())
+		oidcErr.Write(http.StatusUnauthorized, w)
+		return "", "", false
+	}
+	token := r.Form.Get("token")
+	if token == "" {
+		oidcerror.WriteError(oidcerror.InvalidRequest, "missing token", http.StatusBadRequest, w)
+		return "", "", false
+	}
+
+	return token, oidcClient.Status.ClientID, true
+}
+
+// introspect returns the introspection response for a token. Access and refresh tokens are active if they are valid,
+// were issued to the OIDC client, weren't revoked, and the Rancher token they were issued for is still valid.
+func (h *tokenHandler) introspect(token string, oidcClientID string) (IntrospectionResponse, error) {
+	inactive := IntrospectionResponse{Active: false}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, &claims, h.verificationKey); err != nil {
+		return inactive, nil
+	}
+	aud, _ := claims.GetAudience()
+	if !slices.Contains(aud, oidcClientID) {
+		return inactive, nil
+	}
+	revoked, err := h.revocations.IsTokenRevoked(token)
+	if err != nil {
+		return inactive, err
+	}
+	if revoked {
+		return inactive, nil
+	}
+
+	sub, _ := claims.GetSubject()
+	resp := IntrospectionResponse{
+		Active:   true,
+		ClientID: oidcClientID,
+		Sub:      sub,
+		Aud:      aud,
+	}
+	resp.Iss, _ = claims.GetIssuer()
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		resp.Exp = exp.Unix()
+	}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		resp.Iat = iat.Unix()
+	}
+	scope, hasScope := claims["scope"]
+	scopes, _ := scope.([]any)
+	for _, s := range scopes {
+		if s, ok := s.(string); ok {
+			resp.Scope = strings.TrimSpace(resp.Scope + " " + s)
+		}
+	}
+
+	var rancherToken accessor.TokenAccessor
+	rancherTokenHash, isRefreshToken := claims["rancher_token_hash"].(string)
+	switch {
+	case isRefreshToken:
+		var oidcErr *oidcerror.Error
+		rancherToken, oidcErr = h.getRancherTokenByHash(sub, rancherTokenHash)
+		if oidcErr != nil {
+			return inactive, errors.New(oidcErr.ToString())
+		}
+		if rancherToken == nil {
+			return inactive, nil
+		}
+	case hasScope:
+		// access tokens of the client_credentials grant don't have a Rancher token.
+		resp.TokenType = bearerTokenType
+		if tokenName, _ := claims["token"].(string); tokenName != "" {
+			if rancherToken, err = h.extTokenStore.Fetch(tokenName); err != nil {
+				return inactive, nil
+			}
+		}
+	default:
+		// id tokens are not meant to be introspected.
+		return inactive, nil
+	}
+	if rancherToken != nil && !h.isRancherTokenActive(rancherToken) {
+		return inactive, nil
+	}
+
+	return resp, nil
+}
+
+// isRancherTokenActive returns whether a Rancher token and its user are still valid.
+func (h *tokenHandler) isRancherTokenActive(rancherToken accessor.TokenAccessor) bool {
+	if rancherToken.GetIsExpired() || !rancherToken.GetIsEnabled() {
+		return false
+	}
+	if authProvider := rancherToken.GetAuthProvider(); authProvider != "" {
+		disabled, err := providers.IsDisabledProvider(authProvider)
+		if err != nil || disabled {
+			return false
+		}
+	}
+	user, err := h.userLister.Get(rancherToken.GetUserID())
+	if err != nil {
+		return false
+	}
+
+	return user.Enabled == nil || *user.Enabled
+}
+
+// verificationKey returns the public key verifying the signature of a token issued by the OIDC provider.
+func (h *tokenHandler) verificationKey(token *jwt.Token) (any, error) {
+	// Ensure correct signing method
+	if !slices.Contains(supportedSigningAlgs, token.Method.Alg()) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("can't find kid")
+	}
+
+	return h.jwks.GetPublicKey(kid)
+}
@@ -0,0 +1,370 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/providers"
+	"github.com/rancher/rancher/pkg/auth/providers/common"
+	providermocks "github.com/rancher/rancher/pkg/auth/providers/mocks"
+	"github.com/rancher/rancher/pkg/auth/tokens"
+	"github.com/rancher/rancher/pkg/oidc/mocks"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+type introspectionMockParams struct {
+	tokenCache       *fake.MockNonNamespacedCacheInterface[*v3.Token]
+	userLister       *fake.MockNonNamespacedCacheInterface[*v3.User]
+	oidcClientCache  *fake.MockNonNamespacedCacheInterface[*v3.OIDCClient]
+	oidcClient       *fake.MockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList]
+	secretCache      *fake.MockCacheInterface[*corev1.Secret]
+	signingKeyGetter *mocks.MocksigningKeyGetter
+	revocations      *mocks.MocktokenRevoker
+}
+
+const (
+	fakeIntrospectionClientID     = "client-id"
+	fakeIntrospectionClientName   = "client-name"
+	fakeIntrospectionClientSecret = "client-secret"
+	fakeIntrospectionUserID       = "user-id"
+	fakeIntrospectionTokenName    = "token-name"
+	fakeIntrospectionAuthProvider = "auth-provider"
+	fakeIntrospectionSigningKey   = "key"
+)
+
+func newIntrospectionMocks(ctrl *gomock.Controller) introspectionMockParams {
+	return introspectionMockParams{
+		tokenCache:       fake.NewMockNonNamespacedCacheInterface[*v3.Token](ctrl),
+		userLister:       fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl),
+		oidcClientCache:  fake.NewMockNonNamespacedCacheInterface[*v3.OIDCClient](ctrl),
+		oidcClient:       fake.NewMockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList](ctrl),
+		secretCache:      fake.NewMockCacheInterface[*corev1.Secret](ctrl),
+		signingKeyGetter: mocks.NewMocksigningKeyGetter(ctrl),
+		revocations:      mocks.NewMocktokenRevoker(ctrl),
+	}
+}
+
+func (m introspectionMockParams) tokenHandler() *tokenHandler {
+	return newTokenHandler(nil, m.tokenCache, m.userLister, nil, nil, m.signingKeyGetter, m.oidcClientCache, m.oidcClient, m.secretCache, nil, nil, m.revocations)
+}
+
+// authenticateClient sets up the mocks for authenticating the OIDC client of the request.
+func (m introspectionMockParams) authenticateClient() {
+	oidcClient := &v3.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeIntrospectionClientName},
+		Status:     v3.OIDCClientStatus{ClientID: fakeIntrospectionClientID},
+	}
+	m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeIntrospectionClientID).Return([]*v3.OIDCClient{oidcClient}, nil)
+	m.secretCache.EXPECT().Get(secretsNamespace, fakeIntrospectionClientID).Return(&corev1.Secret{
+		Data: map[string][]byte{"client-secret-1": []byte(fakeIntrospectionClientSecret)},
+	}, nil)
+	m.oidcClient.EXPECT().Patch(fakeIntrospectionClientName, types.JSONPatchType, gomock.Any()).Return(oidcClient, nil)
+}
+
+func newTokenRequest(token string) *http.Request {
+	data := url.Values{}
+	data.Set("token", token)
+	req, _ := http.NewRequest(http.MethodPost, "https://rancher.com", bytes.NewBufferString(data.Encode()))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeIntrospectionClientID+":"+fakeIntrospectionClientSecret))))
+
+	return req
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = fakeIntrospectionSigningKey
+	signed, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	return signed
+}
+
+func TestIntrospectionEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	now := time.Unix(time.Now().Unix(), 0)
+	hash := sha256.Sum256([]byte(fakeIntrospectionTokenName))
+	rancherTokenHash := hex.EncodeToString(hash[:])
+	rancherToken := &v3.Token{
+		ObjectMeta:   metav1.ObjectMeta{Name: fakeIntrospectionTokenName},
+		UserID:       fakeIntrospectionUserID,
+		Enabled:      ptr.To(true),
+		AuthProvider: fakeIntrospectionAuthProvider,
+	}
+	userTokensSelector := labels.SelectorFromSet(map[string]string{tokens.UserIDLabel: fakeIntrospectionUserID})
+	accessToken := signToken(t, privateKey, jwt.MapClaims{
+		"aud":       []string{fakeIntrospectionClientID},
+		"exp":       now.Add(time.Hour).Unix(),
+		"iat":       now.Unix(),
+		"iss":       "https://rancher.com/oidc",
+		"sub":       fakeIntrospectionClientID,
+		"client_id": fakeIntrospectionClientID,
+		"scope":     []string{"read", "write"},
+	})
+	refreshToken := signToken(t, privateKey, jwt.MapClaims{
+		"aud":                []string{fakeIntrospectionClientID},
+		"exp":                now.Add(time.Hour).Unix(),
+		"iat":                now.Unix(),
+		"sub":                fakeIntrospectionUserID,
+		"rancher_token_hash": rancherTokenHash,
+		"scope":              []string{"openid", "offline_access"},
+	})
+
+	tests := map[string]struct {
+		req       func() *http.Request
+		mockSetup func(m introspectionMockParams)
+		wantCode  int
+		wantBody  string
+	}{
+		"active access token": {
+			req: func() *http.Request {
+				return newTokenRequest(accessToken)
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(accessToken).Return(false, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: fmt.Sprintf(`{"active":true,"scope":"read write","client_id":"client-id","token_type":"Bearer","exp":%d,"iat":%d,"sub":"client-id","aud":["client-id"],"iss":"https://rancher.com/oidc"}`, now.Add(time.Hour).Unix(), now.Unix()),
+		},
+		"active refresh token": {
+			req: func() *http.Request {
+				return newTokenRequest(refreshToken)
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(refreshToken).Return(false, nil)
+				m.tokenCache.EXPECT().List(userTokensSelector).Return([]*v3.Token{rancherToken}, nil)
+				m.userLister.EXPECT().Get(fakeIntrospectionUserID).Return(&v3.User{Enabled: ptr.To(true)}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: fmt.Sprintf(`{"active":true,"scope":"openid offline_access","client_id":"client-id","exp":%d,"iat":%d,"sub":"user-id","aud":["client-id"]}`, now.Add(time.Hour).Unix(), now.Unix()),
+		},
+		"refresh token of a disabled user": {
+			req: func() *http.Request {
+				return newTokenRequest(refreshToken)
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(refreshToken).Return(false, nil)
+				m.tokenCache.EXPECT().List(userTokensSelector).Return([]*v3.Token{rancherToken}, nil)
+				m.userLister.EXPECT().Get(fakeIntrospectionUserID).Return(&v3.User{Enabled: ptr.To(false)}, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"active":false}`,
+		},
+		"revoked token": {
+			req: func() *http.Request {
+				return newTokenRequest(accessToken)
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(accessToken).Return(true, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"active":false}`,
+		},
+		"token issued to another client": {
+			req: func() *http.Request {
+				return newTokenRequest(signToken(t, privateKey, jwt.MapClaims{
+					"aud":   []string{"other-client-id"},
+					"exp":   now.Add(time.Hour).Unix(),
+					"scope": []string{"read"},
+				}))
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"active":false}`,
+		},
+		"expired token": {
+			req: func() *http.Request {
+				return newTokenRequest(signToken(t, privateKey, jwt.MapClaims{
+					"aud":   []string{fakeIntrospectionClientID},
+					"exp":   now.Add(-time.Hour).Unix(),
+					"scope": []string{"read"},
+				}))
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"active":false}`,
+		},
+		"id token": {
+			req: func() *http.Request {
+				return newTokenRequest(signToken(t, privateKey, jwt.MapClaims{
+					"aud": []string{fakeIntrospectionClientID},
+					"exp": now.Add(time.Hour).Unix(),
+					"sub": fakeIntrospectionUserID,
+				}))
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(gomock.Any()).Return(false, nil)
+			},
+			wantCode: http.StatusOK,
+			wantBody: `{"active":false}`,
+		},
+		"missing token": {
+			req: func() *http.Request {
+				return newTokenRequest("")
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"invalid_request","error_description":"missing token"}`,
+		},
+		"invalid client credentials": {
+			req: func() *http.Request {
+				req := newTokenRequest(accessToken)
+				req.SetBasicAuth(fakeIntrospectionClientID, "wrong-secret")
+				return req
+			},
+			mockSetup: func(m introspectionMockParams) {
+				m.oidcClientCache.EXPECT().GetByIndex(OIDCClientByIDIndex, fakeIntrospectionClientID).Return([]*v3.OIDCClient{{
+					ObjectMeta: metav1.ObjectMeta{Name: fakeIntrospectionClientName},
+					Status:     v3.OIDCClientStatus{ClientID: fakeIntrospectionClientID},
+				}}, nil)
+				m.secretCache.EXPECT().Get(secretsNamespace, fakeIntrospectionClientID).Return(&corev1.Secret{
+					Data: map[string][]byte{"client-secret-1": []byte(fakeIntrospectionClientSecret)},
+				}, nil)
+			},
+			wantCode: http.StatusUnauthorized,
+		},
+		"unsupported method": {
+			req: func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com", nil)
+				return req
+			},
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	// register auth provider
+	mockProvider := providermocks.NewMockAuthProvider(ctrl)
+	mockProvider.EXPECT().IsDisabledProvider().Return(false, nil).AnyTimes()
+	providers.SetProviders(map[string]common.AuthProvider{fakeIntrospectionAuthProvider: mockProvider})
+	t.Cleanup(func() { providers.SetProviders(nil) })
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := newIntrospectionMocks(ctrl)
+			if test.mockSetup != nil {
+				test.mockSetup(m)
+			}
+			rec := httptest.NewRecorder()
+
+			m.tokenHandler().introspectionEndpoint(rec, test.req())
+
+			assert.Equal(t, test.wantCode, rec.Code)
+			if test.wantBody != "" {
+				assert.JSONEq(t, test.wantBody, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
+
+func TestRevocationEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	expiresAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	refreshToken := signToken(t, privateKey, jwt.MapClaims{
+		"aud":                []string{fakeIntrospectionClientID},
+		"exp":                expiresAt.Unix(),
+		"sub":                fakeIntrospectionUserID,
+		"rancher_token_hash": "hash",
+	})
+
+	tests := map[string]struct {
+		token     string
+		mockSetup func(m introspectionMockParams)
+		wantCode  int
+		wantBody  string
+	}{
+		"token is revoked": {
+			token: refreshToken,
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().RevokeToken(refreshToken, expiresAt).Return(nil)
+			},
+			wantCode: http.StatusOK,
+		},
+		"invalid token is ignored": {
+			token: "invalid",
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+			},
+			wantCode: http.StatusOK,
+		},
+		"token issued to another client": {
+			token: signToken(t, privateKey, jwt.MapClaims{
+				"aud": []string{"other-client-id"},
+				"exp": expiresAt.Unix(),
+			}),
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+			},
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"error":"unauthorized_client","error_description":"the token was not issued to this client"}`,
+		},
+		"error revoking token": {
+			token: refreshToken,
+			mockSetup: func(m introspectionMockParams) {
+				m.authenticateClient()
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeIntrospectionSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().RevokeToken(refreshToken, expiresAt).Return(fmt.Errorf("unexpected error"))
+			},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"error":"server_error","error_description":"failed to revoke token"}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := newIntrospectionMocks(ctrl)
+			test.mockSetup(m)
+			rec := httptest.NewRecorder()
+
+			m.tokenHandler().revocationEndpoint(rec, newTokenRequest(test.token))
+
+			assert.Equal(t, test.wantCode, rec.Code)
+			if test.wantBody != "" {
+				assert.JSONEq(t, test.wantBody, strings.TrimSpace(rec.Body.String()))
+			}
+		})
+	}
+}
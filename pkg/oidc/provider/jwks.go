@@ -1,6 +1,10 @@
 package provider
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,9 +14,12 @@
 	"fmt"
 	"math/big"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/golang-jwt/jwt/v5"
 	oidcerror "github.com/rancher/rancher/pkg/oidc/provider/error"
+	"github.com/rancher/rancher/pkg/settings"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
@@ -26,13 +33,23 @@
 	keySecretName      = "oidc-signing-key"
 )
 
+// supportedSigningAlgs are the algorithms of the keys the OIDC provider can sign tokens with.
+var supportedSigningAlgs = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
 // JWK represents a JSON Web Key
 type JWK struct {
-	Kty string `json:"kty"` // Key Type (e.g., RSA)
-	Use string `json:"use"` // Key Usage (e.g., sig)
-	Kid string `json:"kid"` // Key ID
-	N   string `json:"n"`   // Modulus
-	E   string `json:"e"`   // Exponent
+	Kty string `json:"kty"`           // Key Type (e.g., RSA, EC, OKP)
+	Use string `json:"use"`           // Key Usage (e.g., sig)
+	Kid string `json:"kid"`           // Key ID
+	N   string `json:"n,omitempty"`   // Modulus of RSA keys
+	E   string `json:"e,omitempty"`   // Exponent of RSA keys
+	Crv string `json:"crv,omitempty"` // Curve of EC and OKP keys
+	X   string `json:"x,omitempty"`   // X coordinate of EC keys, or the public key of OKP keys
+	Y   string `json:"y,omitempty"`   // Y coordinate of EC keys
 }
 
 // JWKS represents a JSON Web Key Set
@@ -51,7 +68,7 @@ type oidcKeyClient struct {
 }
 
 // GetPublicKey returns the public key specified by the kid
-func (h *oidcKeyClient) GetPublicKey(kid string) (*rsa.PublicKey, error) {
+func (h *oidcKeyClient) GetPublicKey(kid string) (crypto.PublicKey, error) {
 	s, err := h.secretCache.Get(keySecretNamespace, keySecretName)
 	if err != nil {
 		return nil, fmt.Errorf("getting public key: %w", err)
@@ -79,28 +96,11 @@ func newJWKSHandler(secretCache corecontrollers.SecretCache, secretClient coreco
 
 	if errors.IsNotFound(err) {
 		logrus.Infof("[OIDC provider] creating a new signing key")
-		// generate a default RSA private key
-		privateKey, err := rsa.GenerateKey(rand.Reader, keyBits)
+		privateKeyPEM, publicKeyPEM, err := GenerateSigningKey(SigningAlgorithm())
 		if err != nil {
 			return nil, err
 		}
 
-		// Encode private key to PEM
-		privateKeyPEM := pem.EncodeToMemory(&pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-		})
-
-		// Encode public key to PEM
-		publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal public key: %w", err)
-		}
-		publicKeyPEM := pem.EncodeToMemory(&pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: publicKeyDER,
-		})
-
 		secret := &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      keySecretName,
@@ -144,6 +144,9 @@ func newJWKSHandler(secretCache corecontrollers.SecretCache, secretClient coreco
 //
 // It will sign jwt tokens with key2.pem, but jwks will return key1.pub and key2.pub in order to avoid disruptions when doing a key rotation from key1 to key2.
 // Only one private key (.pem) can be in this secret. Note that the private and public keys must have the same name (kid) with different suffix (.pem and .pub).
+// RSA, EC P-256 and Ed25519 keys are supported, for signing tokens with RS256, ES256 and EdDSA respectively.
+// The signing key is rotated periodically, or on demand with the cattle.io/oidc-signing-key-rotate annotation. The previous public key is kept in the
+// secret during the oidc-provider-signing-key-rotation-overlap setting, so tokens signed before the rotation can still be verified.
 func (h *jwksHandler) jwksEndpoint(w http.ResponseWriter, r *http.Request) {
 	s, err := h.secretCache.Get(keySecretNamespace, keySecretName)
 	if err != nil {
@@ -163,18 +166,34 @@ func (h *jwksHandler) jwksEndpoint(w http.ResponseWriter, r *http.Request) {
 			oidcerror.WriteError(oidcerror.ServerError, "failed to extract public key from secret data", http.StatusInternalServerError, w)
 			return
 		}
-		if pubKey.N.BitLen() < 2048 {
-			logrus.Warnf("[OIDC provider] ignoring key because the size is less than 2048 bits")
-			continue
-		}
 
-		keys = append(keys, JWK{
-			Kty: "RSA",
+		jwk := JWK{
 			Use: "sig",
 			Kid: strings.TrimSuffix(name, ".pub"),
-			N:   base64.RawURLEncoding.EncodeToString(pubKey.N.Bytes()),
-			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pubKey.E)).Bytes()),
-		})
+		}
+		switch key := pubKey.(type) {
+		case *rsa.PublicKey:
+			if key.N.BitLen() < 2048 {
+				logrus.Warnf("[OIDC provider] ignoring key because the size is less than 2048 bits")
+				continue
+			}
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = key.Curve.Params().Name
+			// coordinates are padded to the size of the curve, as required by RFC 7518.
+			size := (key.Curve.Params().BitSize + 7) / 8
+			jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		}
+
+		keys = append(keys, jwk)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -184,7 +203,7 @@ func (h *jwksHandler) jwksEndpoint(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetSigningKey returns the key used for signing jwt tokens, and it's key id (kid)
-func (h *jwksHandler) GetSigningKey() (*rsa.PrivateKey, string, error) {
+func (h *jwksHandler) GetSigningKey() (crypto.Signer, string, error) {
 	s, err := h.secretCache.Get(keySecretNamespace, keySecretName)
 	if err != nil {
 		return nil, "", err
@@ -197,30 +216,149 @@ func (h *jwksHandler) GetSigningKey() (*rsa.PrivateKey, string, error) {
 	return nil, "", fmt.Errorf("signing key not found")
 }
 
-func getPrivateKeyFromSecretData(name string, privateKeyPEM []byte) (*rsa.PrivateKey, string, error) {
+// SigningAlgorithm returns the algorithm of the oidc-provider-signing-algorithm setting. An unsupported algorithm is
+// ignored in favor of the default one, so an invalid setting can't prevent the signing key from being created or
+// rotated.
+func SigningAlgorithm() string {
+	alg := settings.OIDCProviderSigningAlgorithm.Get()
+	if slices.Contains(supportedSigningAlgs, alg) {
+		return alg
+	}
+	logrus.Warnf("[OIDC provider] unsupported signing algorithm %q in setting %s, using %s instead. Supported algorithms are %s",
+		alg, settings.OIDCProviderSigningAlgorithm.Name, settings.OIDCProviderSigningAlgorithm.Default, strings.Join(supportedSigningAlgs, ", "))
+
+	return settings.OIDCProviderSigningAlgorithm.Default
+}
+
+// GenerateSigningKey generates a new key pair for the signing algorithm, and returns the private and public keys
+// PEM encoded.
+func GenerateSigningKey(alg string) ([]byte, []byte, error) {
+	var privateKey crypto.Signer
+	var privateKeyBlock *pem.Block
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		rsaKey, err := rsa.GenerateKey(rand.Reader, keyBits)
+		if err != nil {
+			return nil, nil, err
+		}
+		privateKey = rsaKey
+		privateKeyBlock = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+		}
+	case jwt.SigningMethodES256.Alg():
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+		privateKey = ecKey
+		privateKeyBlock = &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(edKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+		privateKey = edKey
+		privateKeyBlock = &pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: der,
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	})
+
+	return pem.EncodeToMemory(privateKeyBlock), publicKeyPEM, nil
+}
+
+// signingMethodForKey returns the signing method of the jwt tokens signed with the key.
+func signingMethodForKey(key crypto.Signer) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+		}
+		return jwt.SigningMethodES256, nil
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// getPrivateKeyFromSecretData decodes a PEM encoded RSA (PKCS #1), EC (SEC 1) or PKCS #8 private key.
+func getPrivateKeyFromSecretData(name string, privateKeyPEM []byte) (crypto.Signer, string, error) {
 	block, _ := pem.Decode(privateKeyPEM)
-	if block == nil || block.Type != "RSA PRIVATE KEY" {
+	if block == nil {
 		return nil, "", fmt.Errorf("failed to decode PEM block")
 	}
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse RSA private key: %w", err)
+	var privateKey crypto.Signer
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse RSA private key: %w", err)
+		}
+		privateKey = rsaKey
+	case "EC PRIVATE KEY":
+		ecKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse EC private key: %w", err)
+		}
+		privateKey = ecKey
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse private key: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, "", fmt.Errorf("unsupported private key type %T", key)
+		}
+		privateKey = signer
+	default:
+		return nil, "", fmt.Errorf("failed to decode PEM block")
+	}
+	if _, err := signingMethodForKey(privateKey); err != nil {
+		return nil, "", err
 	}
 	return privateKey, strings.TrimSuffix(name, ".pem"), nil
 }
 
-func getPublicKeyFromSecretData(publicKeyPEM []byte) (*rsa.PublicKey, error) {
+func getPublicKeyFromSecretData(publicKeyPEM []byte) (crypto.PublicKey, error) {
 	block, _ := pem.Decode(publicKeyPEM)
 	if block == nil || block.Type != "PUBLIC KEY" {
 		return nil, fmt.Errorf("failed to decode PEM block containing public key")
 	}
-	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse RSA public key: %w", err)
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
 	}
-	publicKey, ok := pubInterface.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("not an RSA public key")
+	switch publicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
 	}
-	return publicKey, nil
 }
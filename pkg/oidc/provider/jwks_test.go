@@ -1,8 +1,12 @@
 package provider
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"net/http"
@@ -10,6 +14,8 @@
 	"strings"
 	"testing"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rancher/rancher/pkg/settings"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/v3/pkg/generic/fake"
 	"github.com/stretchr/testify/assert"
@@ -164,7 +170,7 @@ func TestGetSigningKey(t *testing.T) {
 	tests := map[string]struct {
 		secretCache func() corecontrollers.SecretCache
 		expectedKid string
-		expectedKey *rsa.PrivateKey
+		expectedKey crypto.Signer
 		expectedErr string
 	}{
 		"get signing key": {
@@ -232,7 +238,7 @@ func TestGetPublicKey(t *testing.T) {
 	tests := map[string]struct {
 		secretCache func() corecontrollers.SecretCache
 		kid         string
-		expectedKey *rsa.PublicKey
+		expectedKey crypto.PublicKey
 		expectedErr string
 	}{
 		"get signing key": {
@@ -349,3 +355,125 @@ func TestNewJWKSHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestJWKSEndpointKeyTypes(t *testing.T) {
+	ctlr := gomock.NewController(t)
+
+	for _, alg := range supportedSigningAlgs {
+		t.Run(alg, func(t *testing.T) {
+			privateKeyPEM, publicKeyPEM, err := GenerateSigningKey(alg)
+			assert.NoError(t, err)
+			cache := fake.NewMockCacheInterface[*v1.Secret](ctlr)
+			cache.EXPECT().Get(keySecretNamespace, keySecretName).Return(&v1.Secret{
+				Data: map[string][]byte{
+					"key.pem": privateKeyPEM,
+					"key.pub": publicKeyPEM,
+				},
+			}, nil)
+			rec := httptest.NewRecorder()
+			h := jwksHandler{secretCache: cache}
+
+			h.jwksEndpoint(rec, &http.Request{})
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var jwks JWKS
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &jwks))
+			assert.Len(t, jwks.Keys, 1)
+			jwk := jwks.Keys[0]
+			assert.Equal(t, "key", jwk.Kid)
+			assert.Equal(t, "sig", jwk.Use)
+			switch alg {
+			case "RS256":
+				assert.Equal(t, "RSA", jwk.Kty)
+				assert.NotEmpty(t, jwk.N)
+				assert.Equal(t, "AQAB", jwk.E)
+			case "ES256":
+				assert.Equal(t, "EC", jwk.Kty)
+				assert.Equal(t, "P-256", jwk.Crv)
+				// coordinates of P-256 keys are 32 bytes long.
+				assert.Len(t, jwk.X, 43)
+				assert.Len(t, jwk.Y, 43)
+			case "EdDSA":
+				assert.Equal(t, "OKP", jwk.Kty)
+				assert.Equal(t, "Ed25519", jwk.Crv)
+				assert.Len(t, jwk.X, 43)
+				assert.Empty(t, jwk.Y)
+			}
+		})
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	tests := map[string]struct {
+		expectedKey    any
+		expectedMethod jwt.SigningMethod
+		expectedErr    string
+	}{
+		"RS256": {
+			expectedKey:    &rsa.PrivateKey{},
+			expectedMethod: jwt.SigningMethodRS256,
+		},
+		"ES256": {
+			expectedKey:    &ecdsa.PrivateKey{},
+			expectedMethod: jwt.SigningMethodES256,
+		},
+		"EdDSA": {
+			expectedKey:    ed25519.PrivateKey{},
+			expectedMethod: jwt.SigningMethodEdDSA,
+		},
+		"HS256": {
+			expectedErr: `unsupported signing algorithm "HS256"`,
+		},
+	}
+
+	for alg, test := range tests {
+		t.Run(alg, func(t *testing.T) {
+			t.Parallel()
+			privateKeyPEM, publicKeyPEM, err := GenerateSigningKey(alg)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			key, kid, err := getPrivateKeyFromSecretData("kid.pem", privateKeyPEM)
+			assert.NoError(t, err)
+			assert.Equal(t, "kid", kid)
+			assert.IsType(t, test.expectedKey, key)
+			method, err := signingMethodForKey(key)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedMethod, method)
+			publicKey, err := getPublicKeyFromSecretData(publicKeyPEM)
+			assert.NoError(t, err)
+			assert.Equal(t, key.Public(), publicKey)
+
+			// tokens signed with the key are verified with the public key.
+			signed, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "user"}).SignedString(key)
+			assert.NoError(t, err)
+			_, err = jwt.Parse(signed, func(*jwt.Token) (any, error) { return publicKey, nil }, jwt.WithValidMethods(supportedSigningAlgs))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSigningAlgorithm(t *testing.T) {
+	previous := settings.OIDCProviderSigningAlgorithm.Get()
+	t.Cleanup(func() { _ = settings.OIDCProviderSigningAlgorithm.Set(previous) })
+
+	tests := map[string]string{
+		"RS256": "RS256",
+		"ES256": "ES256",
+		"EdDSA": "EdDSA",
+		"HS256": "RS256",
+		"es256": "RS256",
+		"":      "RS256",
+	}
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			// no t.Parallel() because of the shared setting
+			assert.NoError(t, settings.OIDCProviderSigningAlgorithm.Set(value))
+
+			assert.Equal(t, expected, SigningAlgorithm())
+		})
+	}
+}
@@ -32,10 +32,12 @@
 )
 
 type Provider struct {
-	jwksHandler     *jwksHandler
-	authHandler     *authorizeHandler
-	tokenHandler    *tokenHandler
-	userInfoHandler *userInfoHandler
+	jwksHandler       *jwksHandler
+	authHandler       *authorizeHandler
+	tokenHandler      *tokenHandler
+	userInfoHandler   *userInfoHandler
+	deviceHandler     *deviceHandler
+	endSessionHandler *endSessionHandler
 }
 
 // OIDCClientIDIndexFunc indexes the .status.clientID field from OIDCClient
@@ -49,7 +51,7 @@ func OIDCClientIDIndexFunc(obj interface{}) ([]string, error) {
 	return []string{o.Status.ClientID}, nil
 }
 
-func NewProvider(ctx context.Context, extTokenStore *exttokenstore.SystemStore, tokenCache wrangmgmtv3.TokenCache, tokenClient wrangmgmtv3.TokenClient, userLister wrangmgmtv3.UserCache, userAttributeLister wrangmgmtv3.UserAttributeCache, secretCache corecontrollers.SecretCache, secretClient corecontrollers.SecretClient, oidcClientCache wrangmgmtv3.OIDCClientCache, oidcClientController wrangmgmtv3.OIDCClientController, namespaceClient corecontrollers.NamespaceClient) (Provider, error) {
+func NewProvider(ctx context.Context, extTokenStore *exttokenstore.SystemStore, tokenCache wrangmgmtv3.TokenCache, tokenClient wrangmgmtv3.TokenClient, userLister wrangmgmtv3.UserCache, userAttributeLister wrangmgmtv3.UserAttributeCache, secretCache corecontrollers.SecretCache, secretClient corecontrollers.SecretClient, oidcClientCache wrangmgmtv3.OIDCClientCache, oidcClientController wrangmgmtv3.OIDCClientController, namespaceClient corecontrollers.NamespaceClient, tokenManager rancherSessionManager) (Provider, error) {
 	sessionStorage := session.NewSecretSessionStore(ctx, secretCache, secretClient, maxTime)
 	jwks, err := newJWKSHandler(secretCache, secretClient)
 	if err != nil {
@@ -73,11 +75,16 @@ func NewProvider(ctx context.Context, extTokenStore *exttokenstore.SystemStore,
 		return Provider{}, err
 	}
 
+	authHandler := newAuthorizeHandler(extTokenStore, userLister, sessionStorage, &randomstring.Generator{}, oidcClientCache)
+	tokenHandler := newTokenHandler(extTokenStore, tokenCache, userLister, userAttributeLister, sessionStorage, jwks, oidcClientCache, oidcClientController, secretCache, tokenClient, sessionStorage, sessionStorage)
+
 	return Provider{
-		jwksHandler:     jwks,
-		authHandler:     newAuthorizeHandler(extTokenStore, userLister, sessionStorage, &randomstring.Generator{}, oidcClientCache),
-		tokenHandler:    newTokenHandler(extTokenStore, tokenCache, userLister, userAttributeLister, sessionStorage, jwks, oidcClientCache, oidcClientController, secretCache, tokenClient),
-		userInfoHandler: newUserInfoHandler(userLister, userAttributeLister, jwks),
+		jwksHandler:       jwks,
+		authHandler:       authHandler,
+		tokenHandler:      tokenHandler,
+		userInfoHandler:   newUserInfoHandler(userLister, userAttributeLister, jwks, sessionStorage),
+		deviceHandler:     newDeviceHandler(tokenHandler, authHandler, sessionStorage, &randomstring.Generator{}),
+		endSessionHandler: newEndSessionHandler(tokenHandler, tokenManager),
 	}, nil
 }
 
@@ -137,4 +144,9 @@ func (p *Provider) RegisterOIDCProviderHandles(mux *http.ServeMux) {
 	mux.HandleFunc("/oidc/authorize", p.middleware(p.authHandler.authEndpoint))
 	mux.HandleFunc("/oidc/token", p.middleware(p.tokenHandler.tokenEndpoint))
 	mux.HandleFunc("/oidc/userinfo", p.middleware(p.userInfoHandler.userInfoEndpoint))
+	mux.HandleFunc("/oidc/device_authorization", p.middleware(p.deviceHandler.deviceAuthorizationEndpoint))
+	mux.HandleFunc("/oidc/device", p.middleware(p.deviceHandler.deviceVerificationEndpoint))
+	mux.HandleFunc("/oidc/introspect", p.middleware(p.tokenHandler.introspectionEndpoint))
+	mux.HandleFunc("/oidc/revoke", p.middleware(p.tokenHandler.revocationEndpoint))
+	mux.HandleFunc("/oidc/end_session", p.middleware(p.endSessionHandler.endSessionEndpoint))
 }
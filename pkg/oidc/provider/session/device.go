@@ -0,0 +1,275 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	deviceSecretKey   = "device-session"
+	deviceSecretLabel = "cattle.io/oidc-device-code"
+	userCodeLabel     = "cattle.io/oidc-user-code"
+
+	// slowDownIncrement is added to the polling interval of a device polling too often, as per RFC 8628.
+	slowDownIncrement = 5 * time.Second
+)
+
+var (
+	// ErrDeviceCodeNotFound is returned for unknown or already used device and user codes.
+	ErrDeviceCodeNotFound = errors.New("device code not found")
+	// ErrDeviceCodeExpired is returned for device and user codes past their expiration time.
+	ErrDeviceCodeExpired = errors.New("device code has expired")
+	// ErrSlowDown is returned by PollDevice when the device polls more often than its interval.
+	ErrSlowDown = errors.New("device is polling too often")
+)
+
+// DeviceStatus is the status of a device authorization request.
+type DeviceStatus string
+
+const (
+	// DevicePending means the user hasn't approved or denied the request yet.
+	DevicePending DeviceStatus = "pending"
+	// DeviceApproved means the user approved the request.
+	DeviceApproved DeviceStatus = "approved"
+	// DeviceDenied means the user denied the request.
+	DeviceDenied DeviceStatus = "denied"
+)
+
+// DeviceSession holds a device authorization request (RFC 8628) from the device authorization endpoint until the
+// device exchanges its device code in the token endpoint.
+type DeviceSession struct {
+	// ClientID represents the OIDC client id
+	ClientID string
+	// UserCode is the code the user enters to approve the request
+	UserCode string
+	// Scope is the OIDC scope
+	Scope []string
+	// Status is the status of the request
+	Status DeviceStatus
+	// TokenName is the Rancher token name of the user who approved the request
+	TokenName string
+	// Interval is the minimum time between two polls of the device
+	Interval time.Duration
+	// CreatedAt represents when the session was created
+	CreatedAt time.Time
+	// ExpiresAt represents when the device and user codes expire
+	ExpiresAt time.Time
+	// LastPolledAt represents when the device last polled the token endpoint
+	LastPolledAt time.Time
+}
+
+// AddDevice stores a device session referenced by a device code in a k8s secret. The user code of the session must be
+// unique among the pending sessions.
+func (m *SecretSessionStore) AddDevice(deviceCode string, session DeviceSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, err := m.secretCache.List(namespace, labels.Set{userCodeLabel: session.UserCode}.AsSelector())
+	if err != nil {
+		return fmt.Errorf("error listing device sessions: %w", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("user code already exists")
+	}
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("error marshalling device session: %w", err)
+	}
+	_, err = m.secretClient.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deviceCode,
+			Namespace: namespace,
+			Labels: map[string]string{
+				deviceSecretLabel: "true",
+				userCodeLabel:     session.UserCode,
+			},
+		},
+		Data: map[string][]byte{
+			deviceSecretKey: sessionBytes,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating device session: %w", err)
+	}
+
+	return nil
+}
+
+// GetDevice returns the pending device session with the given user code.
+func (m *SecretSessionStore) GetDevice(userCode string, now time.Time) (*DeviceSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, session, err := m.getPendingDevice(userCode, now)
+	return session, err
+}
+
+// ReviewDevice approves, or denies, the pending device session with the given user code on behalf of the user owning
+// the Rancher token.
+func (m *SecretSessionStore) ReviewDevice(userCode string, approved bool, tokenName string, now time.Time) (*DeviceSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	secret, session, err := m.getPendingDevice(userCode, now)
+	if err != nil {
+		return nil, err
+	}
+	if approved {
+		session.Status = DeviceApproved
+		session.TokenName = tokenName
+	} else {
+		session.Status = DeviceDenied
+	}
+	if err := m.updateDevice(secret, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// PollDevice returns the device session of the device code for the client polling the token endpoint. Sessions that
+// were approved or denied are removed, so a device code can only be exchanged once. ErrSlowDown is returned, and the
+// polling interval of the session increased, if the client polls more often than the interval.
+func (m *SecretSessionStore) PollDevice(deviceCode string, clientID string, now time.Time) (*DeviceSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	secret, err := m.secretClient.Get(namespace, deviceCode, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrDeviceCodeNotFound
+		}
+		return nil, fmt.Errorf("error getting device session: %w", err)
+	}
+	session, err := decodeDeviceSession(secret)
+	if err != nil {
+		return nil, err
+	}
+	if session.ClientID != clientID {
+		return nil, ErrDeviceCodeNotFound
+	}
+	if now.After(session.ExpiresAt) {
+		return nil, ErrDeviceCodeExpired
+	}
+
+	if session.Status != DevicePending {
+		if err := m.secretClient.Delete(namespace, deviceCode, &metav1.DeleteOptions{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, ErrDeviceCodeNotFound
+			}
+			return nil, fmt.Errorf("error consuming device code: %w", err)
+		}
+		return session, nil
+	}
+
+	slowDown := !session.LastPolledAt.IsZero() && now.Sub(session.LastPolledAt) < session.Interval
+	if slowDown {
+		session.Interval += slowDownIncrement
+	}
+	session.LastPolledAt = now
+	if err := m.updateDevice(secret, session); err != nil {
+		return nil, err
+	}
+	if slowDown {
+		return nil, ErrSlowDown
+	}
+
+	return session, nil
+}
+
+func (m *SecretSessionStore) getPendingDevice(userCode string, now time.Time) (*corev1.Secret, *DeviceSession, error) {
+	secrets, err := m.secretCache.List(namespace, labels.Set{userCodeLabel: userCode}.AsSelector())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing device sessions: %w", err)
+	}
+	if len(secrets) == 0 {
+		return nil, nil, ErrDeviceCodeNotFound
+	}
+	// The cache may be stale, the session is reviewed from its latest version.
+	secret, err := m.secretClient.Get(namespace, secrets[0].Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, ErrDeviceCodeNotFound
+		}
+		return nil, nil, fmt.Errorf("error getting device session: %w", err)
+	}
+	session, err := decodeDeviceSession(secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.Status != DevicePending {
+		return nil, nil, ErrDeviceCodeNotFound
+	}
+	if now.After(session.ExpiresAt) {
+		return nil, nil, ErrDeviceCodeExpired
+	}
+
+	return secret, session, nil
+}
+
+func (m *SecretSessionStore) updateDevice(secret *corev1.Secret, session *DeviceSession) error {
+	sessionBytes, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("error marshalling device session: %w", err)
+	}
+	secret = secret.DeepCopy()
+	secret.Data[deviceSecretKey] = sessionBytes
+	if _, err := m.secretClient.Update(secret); err != nil {
+		return fmt.Errorf("error updating device session: %w", err)
+	}
+
+	return nil
+}
+
+func decodeDeviceSession(secret *corev1.Secret) (*DeviceSession, error) {
+	var session DeviceSession
+	if err := json.Unmarshal(secret.Data[deviceSecretKey], &session); err != nil {
+		return nil, fmt.Errorf("error unmarshalling device session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// cleanUpExpiredRecords deletes the device sessions and the revoked tokens past their expiration time on every tick.
+// This includes the approved device sessions never exchanged by their device.
+func (m *SecretSessionStore) cleanUpExpiredRecords(ctx context.Context, c <-chan time.Time) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-c:
+			m.mu.Lock()
+			m.deleteExpiredDeviceSessions(now)
+			m.deleteExpiredRevokedTokens(now)
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *SecretSessionStore) deleteExpiredDeviceSessions(now time.Time) {
+	secrets, err := m.secretCache.List(namespace, labels.Set{deviceSecretLabel: "true"}.AsSelector())
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error listing device sessions: %v", err)
+		return
+	}
+	for _, secret := range secrets {
+		session, err := decodeDeviceSession(secret)
+		if err != nil {
+			logrus.Errorf("[OIDC provider] %v", err)
+		} else if !now.After(session.ExpiresAt) {
+			continue
+		}
+		if err := m.secretClient.Delete(namespace, secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			logrus.Errorf("[OIDC provider] error deleting device session: %v", err)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	fakeDeviceCode = "device-code"
+	fakeUserCode   = "bcdf2456"
+)
+
+func deviceSecret(t *testing.T, s DeviceSession) *v1.Secret {
+	t.Helper()
+	sessionBytes, err := json.Marshal(s)
+	assert.NoError(t, err)
+
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fakeDeviceCode,
+			Namespace: namespace,
+			Labels: map[string]string{
+				deviceSecretLabel: "true",
+				userCodeLabel:     s.UserCode,
+			},
+		},
+		Data: map[string][]byte{
+			deviceSecretKey: sessionBytes,
+		},
+	}
+}
+
+func TestAddDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	fakeSession := DeviceSession{
+		ClientID:  "client-id",
+		UserCode:  fakeUserCode,
+		Status:    DevicePending,
+		Interval:  5 * time.Second,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Minute),
+	}
+	userCodeSelector := labels.Set{userCodeLabel: fakeUserCode}.AsSelector()
+
+	tests := map[string]struct {
+		mockSetup      func(cache *fake.MockCacheInterface[*v1.Secret], client *fake.MockClientInterface[*v1.Secret, *v1.SecretList])
+		expectedErrMsg string
+	}{
+		"user code is not present": {
+			mockSetup: func(cache *fake.MockCacheInterface[*v1.Secret], client *fake.MockClientInterface[*v1.Secret, *v1.SecretList]) {
+				cache.EXPECT().List(namespace, userCodeSelector).Return(nil, nil)
+				client.EXPECT().Create(deviceSecret(t, fakeSession)).Return(&v1.Secret{}, nil)
+			},
+		},
+		"user code is already present": {
+			mockSetup: func(cache *fake.MockCacheInterface[*v1.Secret], client *fake.MockClientInterface[*v1.Secret, *v1.SecretList]) {
+				cache.EXPECT().List(namespace, userCodeSelector).Return([]*v1.Secret{deviceSecret(t, fakeSession)}, nil)
+			},
+			expectedErrMsg: "user code already exists",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cache := fake.NewMockCacheInterface[*v1.Secret](ctrl)
+			client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+			test.mockSetup(cache, client)
+			store := &SecretSessionStore{secretCache: cache, secretClient: client}
+
+			err := store.AddDevice(fakeDeviceCode, fakeSession)
+
+			if test.expectedErrMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, test.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestReviewDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	pending := DeviceSession{
+		ClientID:  "client-id",
+		UserCode:  fakeUserCode,
+		Status:    DevicePending,
+		ExpiresAt: now.Add(time.Minute),
+	}
+	userCodeSelector := labels.Set{userCodeLabel: fakeUserCode}.AsSelector()
+
+	tests := map[string]struct {
+		stored         DeviceSession
+		approved       bool
+		expectedStatus DeviceStatus
+		expectedToken  string
+		expectedErr    error
+	}{
+		"approve": {
+			stored:         pending,
+			approved:       true,
+			expectedStatus: DeviceApproved,
+			expectedToken:  "token-name",
+		},
+		"deny": {
+			stored:         pending,
+			expectedStatus: DeviceDenied,
+		},
+		"already reviewed": {
+			stored: func() DeviceSession {
+				s := pending
+				s.Status = DeviceApproved
+				return s
+			}(),
+			approved:    true,
+			expectedErr: ErrDeviceCodeNotFound,
+		},
+		"expired": {
+			stored: func() DeviceSession {
+				s := pending
+				s.ExpiresAt = now.Add(-time.Second)
+				return s
+			}(),
+			approved:    true,
+			expectedErr: ErrDeviceCodeExpired,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			secret := deviceSecret(t, test.stored)
+			cache := fake.NewMockCacheInterface[*v1.Secret](ctrl)
+			cache.EXPECT().List(namespace, userCodeSelector).Return([]*v1.Secret{secret}, nil)
+			client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+			client.EXPECT().Get(namespace, fakeDeviceCode, metav1.GetOptions{}).Return(secret, nil)
+			var updated *v1.Secret
+			if test.expectedErr == nil {
+				client.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *v1.Secret) (*v1.Secret, error) {
+					updated = s
+					return s, nil
+				})
+			}
+			store := &SecretSessionStore{secretCache: cache, secretClient: client}
+
+			session, err := store.ReviewDevice(fakeUserCode, test.approved, "token-name", now)
+
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, session.Status)
+			assert.Equal(t, test.expectedToken, session.TokenName)
+			stored, err := decodeDeviceSession(updated)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, stored.Status)
+			assert.Equal(t, test.expectedToken, stored.TokenName)
+		})
+	}
+}
+
+func TestPollDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	pending := DeviceSession{
+		ClientID:  "client-id",
+		UserCode:  fakeUserCode,
+		Status:    DevicePending,
+		Interval:  5 * time.Second,
+		ExpiresAt: now.Add(time.Minute),
+	}
+	withChanges := func(change func(s *DeviceSession)) DeviceSession {
+		s := pending
+		change(&s)
+		return s
+	}
+
+	tests := map[string]struct {
+		stored           *DeviceSession
+		clientID         string
+		expectUpdate     bool
+		expectDelete     bool
+		expectedInterval time.Duration
+		expectedStatus   DeviceStatus
+		expectedErr      error
+	}{
+		"first poll of a pending session": {
+			stored:           &pending,
+			clientID:         "client-id",
+			expectUpdate:     true,
+			expectedInterval: 5 * time.Second,
+			expectedStatus:   DevicePending,
+		},
+		"poll after the interval": {
+			stored: ptr.To(withChanges(func(s *DeviceSession) {
+				s.LastPolledAt = now.Add(-5 * time.Second)
+			})),
+			clientID:         "client-id",
+			expectUpdate:     true,
+			expectedInterval: 5 * time.Second,
+			expectedStatus:   DevicePending,
+		},
+		"poll before the interval": {
+			stored: ptr.To(withChanges(func(s *DeviceSession) {
+				s.LastPolledAt = now.Add(-time.Second)
+			})),
+			clientID:         "client-id",
+			expectUpdate:     true,
+			expectedInterval: 10 * time.Second,
+			expectedErr:      ErrSlowDown,
+		},
+		"approved session is consumed": {
+			stored: ptr.To(withChanges(func(s *DeviceSession) {
+				s.Status = DeviceApproved
+				s.TokenName = "token-name"
+			})),
+			clientID:       "client-id",
+			expectDelete:   true,
+			expectedStatus: DeviceApproved,
+		},
+		"denied session is consumed": {
+			stored: ptr.To(withChanges(func(s *DeviceSession) {
+				s.Status = DeviceDenied
+			})),
+			clientID:       "client-id",
+			expectDelete:   true,
+			expectedStatus: DeviceDenied,
+		},
+		"another client": {
+			stored:      &pending,
+			clientID:    "other-client-id",
+			expectedErr: ErrDeviceCodeNotFound,
+		},
+		"expired": {
+			stored: ptr.To(withChanges(func(s *DeviceSession) {
+				s.ExpiresAt = now.Add(-time.Second)
+			})),
+			clientID:    "client-id",
+			expectedErr: ErrDeviceCodeExpired,
+		},
+		"unknown device code": {
+			clientID:    "client-id",
+			expectedErr: ErrDeviceCodeNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+			if test.stored == nil {
+				client.EXPECT().Get(namespace, fakeDeviceCode, metav1.GetOptions{}).Return(nil, errors.NewNotFound(schema.GroupResource{}, ""))
+			} else {
+				client.EXPECT().Get(namespace, fakeDeviceCode, metav1.GetOptions{}).Return(deviceSecret(t, *test.stored), nil)
+			}
+			var updated *v1.Secret
+			if test.expectUpdate {
+				client.EXPECT().Update(gomock.Any()).DoAndReturn(func(s *v1.Secret) (*v1.Secret, error) {
+					updated = s
+					return s, nil
+				})
+			}
+			if test.expectDelete {
+				client.EXPECT().Delete(namespace, fakeDeviceCode, &metav1.DeleteOptions{}).Return(nil)
+			}
+			store := &SecretSessionStore{secretClient: client}
+
+			session, err := store.PollDevice(fakeDeviceCode, test.clientID, now)
+
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedStatus, session.Status)
+			}
+			if test.expectUpdate {
+				stored, err := decodeDeviceSession(updated)
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedInterval, stored.Interval)
+				assert.True(t, now.Equal(stored.LastPolledAt))
+			}
+		})
+	}
+}
+
+func TestDeleteExpiredDeviceSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	expired := deviceSecret(t, DeviceSession{UserCode: "expired", Status: DeviceApproved, ExpiresAt: now.Add(-time.Second)})
+	expired.Name = "expired"
+	notExpired := deviceSecret(t, DeviceSession{UserCode: "pending", Status: DevicePending, ExpiresAt: now.Add(time.Minute)})
+	notExpired.Name = "pending"
+	corrupted := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "corrupted", Namespace: namespace}}
+
+	cache := fake.NewMockCacheInterface[*v1.Secret](ctrl)
+	cache.EXPECT().List(namespace, labels.Set{deviceSecretLabel: "true"}.AsSelector()).Return([]*v1.Secret{expired, notExpired, corrupted}, nil)
+	client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+	client.EXPECT().Delete(namespace, "expired", &metav1.DeleteOptions{}).Return(nil)
+	client.EXPECT().Delete(namespace, "corrupted", &metav1.DeleteOptions{}).Return(errors.NewNotFound(schema.GroupResource{}, ""))
+	store := &SecretSessionStore{secretCache: cache, secretClient: client}
+
+	store.deleteExpiredDeviceSessions(now)
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	revokedTokenKey    = "expires-at"
+	revokedTokenLabel  = "cattle.io/oidc-revoked-token"
+	revokedTokenPrefix = "revoked-"
+)
+
+// RevokeToken records a token revoked with the revocation endpoint, until it expires. Only the sha256 hash of the
+// token is stored.
+func (m *SecretSessionStore) RevokeToken(token string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := revokedTokenSecretName(token)
+	_, err := m.secretClient.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				revokedTokenLabel: "true",
+			},
+		},
+		Data: map[string][]byte{
+			revokedTokenKey: []byte(expiresAt.UTC().Format(time.RFC3339)),
+		},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+
+	// the secret cache may not have seen the secret yet, so the revocation is also recorded in memory.
+	if m.revoked == nil {
+		m.revoked = map[string]time.Time{}
+	}
+	m.revoked[name] = expiresAt
+
+	return nil
+}
+
+// IsTokenRevoked returns whether the token was revoked with the revocation endpoint.
+func (m *SecretSessionStore) IsTokenRevoked(token string) (bool, error) {
+	m.mu.Lock()
+	_, ok := m.revoked[revokedTokenSecretName(token)]
+	m.mu.Unlock()
+	if ok {
+		return true, nil
+	}
+
+	return isTokenRevoked(m.secretCache, token)
+}
+
+// RevokedTokens checks the tokens revoked with the revocation endpoint outside the OIDC provider, e.g. when
+// authenticating requests to the Rancher API. Unlike a SecretSessionStore, it doesn't clean up expired records.
+// Revocations are read from the secret cache, so they are seen once the cache is updated.
+type RevokedTokens struct {
+	secretCache corecontrollers.SecretCache
+}
+
+// NewRevokedTokens creates a new RevokedTokens.
+func NewRevokedTokens(secretCache corecontrollers.SecretCache) *RevokedTokens {
+	return &RevokedTokens{secretCache: secretCache}
+}
+
+// IsTokenRevoked returns whether the token was revoked with the revocation endpoint.
+func (r *RevokedTokens) IsTokenRevoked(token string) (bool, error) {
+	return isTokenRevoked(r.secretCache, token)
+}
+
+// isTokenRevoked looks up the revoked token in the secret cache, as it is checked on every request authenticated
+// with a token issued by the OIDC provider.
+func isTokenRevoked(secretCache corecontrollers.SecretCache, token string) (bool, error) {
+	_, err := secretCache.Get(namespace, revokedTokenSecretName(token))
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error getting revoked token: %w", err)
+	}
+
+	return true, nil
+}
+
+func revokedTokenSecretName(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return revokedTokenPrefix + hex.EncodeToString(hash[:])
+}
+
+// deleteExpiredRevokedTokens deletes the revoked tokens past their expiration time, which are rejected anyway.
+func (m *SecretSessionStore) deleteExpiredRevokedTokens(now time.Time) {
+	for name, expiresAt := range m.revoked {
+		if now.After(expiresAt) {
+			delete(m.revoked, name)
+		}
+	}
+
+	secrets, err := m.secretCache.List(namespace, labels.Set{revokedTokenLabel: "true"}.AsSelector())
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error listing revoked tokens: %v", err)
+		return
+	}
+	for _, secret := range secrets {
+		expiresAt, err := time.Parse(time.RFC3339, string(secret.Data[revokedTokenKey]))
+		if err != nil {
+			// keep the revocation rather than risk accepting the token again.
+			logrus.Errorf("[OIDC provider] error parsing expiration time of revoked token %s: %v", secret.Name, err)
+			continue
+		}
+		if !now.After(expiresAt) {
+			continue
+		}
+		if err := m.secretClient.Delete(namespace, secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			logrus.Errorf("[OIDC provider] error deleting revoked token: %v", err)
+		}
+	}
+}
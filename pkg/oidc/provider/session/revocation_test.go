@@ -0,0 +1,140 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const fakeToken = "token"
+
+func TestRevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	expiresAt := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	revokedSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      revokedTokenSecretName(fakeToken),
+			Namespace: namespace,
+			Labels: map[string]string{
+				revokedTokenLabel: "true",
+			},
+		},
+		Data: map[string][]byte{
+			revokedTokenKey: []byte("2025-01-01T00:00:00Z"),
+		},
+	}
+
+	tests := map[string]struct {
+		createErr      error
+		expectedErrMsg string
+	}{
+		"token is revoked": {},
+		"token is already revoked": {
+			createErr: errors.NewAlreadyExists(schema.GroupResource{}, ""),
+		},
+		"error creating secret": {
+			createErr:      fmt.Errorf("unexpected error"),
+			expectedErrMsg: "error revoking token",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+			client.EXPECT().Create(revokedSecret).Return(revokedSecret, test.createErr)
+			store := &SecretSessionStore{secretClient: client}
+
+			err := store.RevokeToken(fakeToken, expiresAt)
+
+			if test.expectedErrMsg == "" {
+				assert.NoError(t, err)
+				// the revocation is effective before the secret cache sees the secret.
+				revoked, err := store.IsTokenRevoked(fakeToken)
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			} else {
+				assert.ErrorContains(t, err, test.expectedErrMsg)
+			}
+		})
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	tests := map[string]struct {
+		getErr         error
+		expected       bool
+		expectedErrMsg string
+	}{
+		"token is revoked": {
+			expected: true,
+		},
+		"token is not revoked": {
+			getErr: errors.NewNotFound(schema.GroupResource{}, ""),
+		},
+		"error getting secret": {
+			getErr:         fmt.Errorf("unexpected error"),
+			expectedErrMsg: "error getting revoked token",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cache := fake.NewMockCacheInterface[*v1.Secret](ctrl)
+			cache.EXPECT().Get(namespace, revokedTokenSecretName(fakeToken)).Return(&v1.Secret{}, test.getErr).Times(2)
+			store := &SecretSessionStore{secretCache: cache}
+
+			for _, revocations := range []interface{ IsTokenRevoked(string) (bool, error) }{store, NewRevokedTokens(cache)} {
+				revoked, err := revocations.IsTokenRevoked(fakeToken)
+
+				if test.expectedErrMsg == "" {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorContains(t, err, test.expectedErrMsg)
+				}
+				assert.Equal(t, test.expected, revoked)
+			}
+		})
+	}
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	revokedSecret := func(name string, expiresAt string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string][]byte{revokedTokenKey: []byte(expiresAt)},
+		}
+	}
+	expired := revokedSecret("expired", now.Add(-time.Second).UTC().Format(time.RFC3339))
+	notExpired := revokedSecret("not-expired", now.Add(time.Hour).UTC().Format(time.RFC3339))
+	corrupted := revokedSecret("corrupted", "not-a-time")
+
+	cache := fake.NewMockCacheInterface[*v1.Secret](ctrl)
+	cache.EXPECT().List(namespace, labels.Set{revokedTokenLabel: "true"}.AsSelector()).Return([]*v1.Secret{expired, notExpired, corrupted}, nil)
+	client := fake.NewMockClientInterface[*v1.Secret, *v1.SecretList](ctrl)
+	client.EXPECT().Delete(namespace, "expired", &metav1.DeleteOptions{}).Return(nil)
+	store := &SecretSessionStore{
+		secretCache:  cache,
+		secretClient: client,
+		revoked: map[string]time.Time{
+			"expired":     now.Add(-time.Second),
+			"not-expired": now.Add(time.Hour),
+		},
+	}
+
+	store.deleteExpiredRevokedTokens(now)
+
+	assert.Equal(t, map[string]time.Time{"not-expired": now.Add(time.Hour)}, store.revoked)
+}
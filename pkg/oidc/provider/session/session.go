@@ -46,6 +46,8 @@ type SecretSessionStore struct {
 	secretClient corecontrollers.SecretClient
 	expiryTime   time.Duration
 	mu           sync.Mutex
+	// revoked holds the expiration time of the tokens revoked by this store, by the name of their secret.
+	revoked map[string]time.Time
 }
 
 // NewSecretSessionStore creates a new SecretSessionStore
@@ -58,6 +60,9 @@ func NewSecretSessionStore(ctx context.Context, secretCache corecontrollers.Secr
 	t := time.NewTicker(expiryTime)
 	// codes are valid for a maximum of 10 minutes. Therefore, we need to clean the expired sessions associated with these codes.
 	go storage.cleanUpExpiredSessions(ctx, t.C)
+	// device codes and revoked tokens carry their own expiration time, and are cleaned up separately.
+	recordsTicker := time.NewTicker(expiryTime)
+	go storage.cleanUpExpiredRecords(ctx, recordsTicker.C)
 
 	return storage
 }
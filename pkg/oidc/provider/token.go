@@ -1,7 +1,7 @@
 package provider
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
@@ -33,13 +33,26 @@
 
 const bearerTokenType = "Bearer"
 
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// supportedGrantTypes are the grant types supported by the token endpoint.
+var supportedGrantTypes = []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypeClientCredentials, grantTypeDeviceCode}
+
+// defaultGrantTypes are the grant types allowed for OIDC clients not configuring any.
+var defaultGrantTypes = []string{grantTypeAuthorizationCode, grantTypeRefreshToken}
+
 type sessionGetterRemover interface {
 	GetAndRemove(code string) (*session.Session, error)
 }
 
 type signingKeyGetter interface {
-	GetSigningKey() (*rsa.PrivateKey, string, error)
-	GetPublicKey(kid string) (*rsa.PublicKey, error)
+	GetSigningKey() (crypto.Signer, string, error)
+	GetPublicKey(kid string) (crypto.PublicKey, error)
 }
 
 type tokenHandler struct {
@@ -53,6 +66,8 @@ type tokenHandler struct {
 	oidcClient          wrangmgmtv3.OIDCClientClient
 	secretCache         corev1.SecretCache
 	jwks                signingKeyGetter
+	deviceSessions      deviceSessionStore
+	revocations         tokenRevoker
 	now                 func() time.Time
 }
 
@@ -88,7 +103,9 @@ func newTokenHandler(extTokenStore *exttokenstore.SystemStore,
 	oidcClientCache wrangmgmtv3.OIDCClientCache,
 	oidcClient wrangmgmtv3.OIDCClientClient,
 	secretCache corev1.SecretCache,
-	tokenClient wrangmgmtv3.TokenClient) *tokenHandler {
+	tokenClient wrangmgmtv3.TokenClient,
+	deviceSessions deviceSessionStore,
+	revocations tokenRevoker) *tokenHandler {
 
 	return &tokenHandler{
 		extTokenStore:       extTokenStore,
@@ -101,6 +118,8 @@ func newTokenHandler(extTokenStore *exttokenstore.SystemStore,
 		oidcClientCache:     oidcClientCache,
 		oidcClient:          oidcClient,
 		secretCache:         secretCache,
+		deviceSessions:      deviceSessions,
+		revocations:         revocations,
 		now:                 time.Now,
 	}
 }
@@ -114,41 +133,34 @@ func (h *tokenHandler) tokenEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var tokenResponse TokenResponse
+	var oidcErr *oidcerror.Error
 	switch r.Form.Get("grant_type") {
-	case "authorization_code":
-		tokenResponse, oidcErr := h.createTokenFromCode(r)
-		if oidcErr != nil {
-			logrus.Debug("[OIDC provider] error creating token response: " + oidcErr.ToString())
-			oidcErr.Write(http.StatusBadRequest, w)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Cache-Control", "no-store")
-		w.Header().Set("Pragma", "no-cache")
-		err = json.NewEncoder(w).Encode(tokenResponse)
-		if err != nil {
-			oidcerror.WriteError(oidcerror.ServerError, "failed to encode token response", http.StatusInternalServerError, w)
-			return
-		}
-	case "refresh_token":
-		tokenResponse, oidcErr := h.createRefreshToken(r)
-		if oidcErr != nil {
-			logrus.Debug("[OIDC provider] error creating refresh token response: " + oidcErr.ToString())
-			oidcErr.Write(http.StatusBadRequest, w)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Cache-Control", "no-store")
-		w.Header().Set("Pragma", "no-cache")
-		err = json.NewEncoder(w).Encode(tokenResponse)
-		if err != nil {
-			oidcerror.WriteError(oidcerror.ServerError, "failed to encode refresh token response", http.StatusInternalServerError, w)
-			return
-		}
+	case grantTypeAuthorizationCode:
+		tokenResponse, oidcErr = h.createTokenFromCode(r)
+	case grantTypeRefreshToken:
+		tokenResponse, oidcErr = h.createRefreshToken(r)
+	case grantTypeClientCredentials:
+		tokenResponse, oidcErr = h.createTokenFromClientCredentials(r)
+	case grantTypeDeviceCode:
+		tokenResponse, oidcErr = h.createTokenFromDeviceCode(r)
 	default:
 		http.Error(w, "grant_type not supported", http.StatusInternalServerError)
 		return
 	}
+	if oidcErr != nil {
+		logrus.Debugf("[OIDC provider] error creating %s token response: %s", r.Form.Get("grant_type"), oidcErr.ToString())
+		oidcErr.Write(http.StatusBadRequest, w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	err = json.NewEncoder(w).Encode(tokenResponse)
+	if err != nil {
+		oidcerror.WriteError(oidcerror.ServerError, "failed to encode token response", http.StatusInternalServerError, w)
+		return
+	}
 }
 
 // createTokenFromCode creates a response with an id_token (if openid scope is
@@ -180,6 +192,9 @@ func (h *tokenHandler) createTokenFromCode(r *http.Request) (TokenResponse, *oid
 	if oidcErr := h.isValidClientSecret(clientSecret, oidcClient); oidcErr != nil {
 		return TokenResponse{}, oidcErr
 	}
+	if !isGrantTypeAllowed(oidcClient, grantTypeAuthorizationCode) {
+		return TokenResponse{}, oidcerror.New(oidcerror.UnauthorizedClient, "grant_type not allowed for this client")
+	}
 
 	// PKCE verification
 	code_verifier := r.Form.Get("code_verifier")
@@ -227,22 +242,7 @@ func (h *tokenHandler) isValidClientSecret(clientSecret string, oidcClient *v3.O
 func (h *tokenHandler) createRefreshToken(r *http.Request) (TokenResponse, *oidcerror.Error) {
 	refreshToken := r.Form.Get("refresh_token")
 	// verify refresh_token signature
-	token, err := jwt.ParseWithClaims(refreshToken, &RefreshTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Ensure correct signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		kid, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, fmt.Errorf("can't find kid")
-		}
-		pubKey, err := h.jwks.GetPublicKey(kid)
-		if err != nil {
-			return nil, err
-		}
-
-		return pubKey, nil
-	})
+	token, err := jwt.ParseWithClaims(refreshToken, &RefreshTokenClaims{}, h.verificationKey)
 	if err != nil {
 		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to parse refresh token: %v", err)
 	}
@@ -250,40 +250,18 @@ func (h *tokenHandler) createRefreshToken(r *http.Request) (TokenResponse, *oidc
 	if !ok || !token.Valid {
 		return TokenResponse{}, oidcerror.New(oidcerror.ServerError, "refresh token not valid")
 	}
-
-	// get rancher Token associated with this refresh_token
-	// search for legacy token first
-	tokenList, err := h.tokenCache.List(labels.SelectorFromSet(map[string]string{
-		tokens.UserIDLabel: claims.Subject,
-	}))
+	revoked, err := h.revocations.IsTokenRevoked(refreshToken)
 	if err != nil {
-		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError,
-			"[OIDC provider] failed to retrieve legacy tokens for user %q: %v", claims.Subject, err)
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to check if refresh token is revoked: %v", err)
 	}
-	var rancherToken accessor.TokenAccessor
-	for _, token := range tokenList {
-		hash := sha256.Sum256([]byte(token.Name))
-		rancherTokenHash := hex.EncodeToString(hash[:])
-		if rancherTokenHash == claims.RancherTokenHash {
-			rancherToken = token
-			break
-		}
+	if revoked {
+		return TokenResponse{}, oidcerror.New(oidcerror.InvalidGrant, "refresh token has been revoked")
 	}
-	if rancherToken == nil {
-		// no matching legacy token found, now search ext tokens for a match
-		tokenList, err := h.extTokenStore.ListForUser(claims.Subject)
-		if err != nil {
-			return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError,
-				"[OIDC provider] failed to retrieve ext tokens for user %q: %v", claims.Subject, err)
-		}
-		for _, token := range tokenList.Items {
-			hash := sha256.Sum256([]byte(token.Name))
-			rancherTokenHash := hex.EncodeToString(hash[:])
-			if rancherTokenHash == claims.RancherTokenHash {
-				rancherToken = &token
-				break
-			}
-		}
+
+	// get rancher Token associated with this refresh_token
+	rancherToken, oidcErr := h.getRancherTokenByHash(claims.Subject, claims.RancherTokenHash)
+	if oidcErr != nil {
+		return TokenResponse{}, oidcErr
 	}
 	if rancherToken == nil {
 		// neither legacy nor ext token found
@@ -306,10 +284,126 @@ func (h *tokenHandler) createRefreshToken(r *http.Request) (TokenResponse, *oidc
 	if oidcErr := h.isValidClientSecret(clientSecret, oidcClient); oidcErr != nil {
 		return TokenResponse{}, oidcErr
 	}
+	if !isGrantTypeAllowed(oidcClient, grantTypeRefreshToken) {
+		return TokenResponse{}, oidcerror.New(oidcerror.UnauthorizedClient, "grant_type not allowed for this client")
+	}
 
 	return h.createTokenResponse(rancherToken, oidcClient, "", claims.Scope)
 }
 
+// getRancherTokenByHash returns the Rancher token of the user whose name has the sha256 hash, as found in refresh
+// tokens. It returns nil if there is no such token.
+func (h *tokenHandler) getRancherTokenByHash(userID string, rancherTokenHash string) (accessor.TokenAccessor, *oidcerror.Error) {
+	// search for legacy token first
+	tokenList, err := h.tokenCache.List(labels.SelectorFromSet(map[string]string{
+		tokens.UserIDLabel: userID,
+	}))
+	if err != nil {
+		return nil, oidcerror.Newf(oidcerror.ServerError,
+			"[OIDC provider] failed to retrieve legacy tokens for user %q: %v", userID, err)
+	}
+	for _, token := range tokenList {
+		hash := sha256.Sum256([]byte(token.Name))
+		if hex.EncodeToString(hash[:]) == rancherTokenHash {
+			return token, nil
+		}
+	}
+
+	// no matching legacy token found, now search ext tokens for a match
+	extTokenList, err := h.extTokenStore.ListForUser(userID)
+	if err != nil {
+		return nil, oidcerror.Newf(oidcerror.ServerError,
+			"[OIDC provider] failed to retrieve ext tokens for user %q: %v", userID, err)
+	}
+	for _, token := range extTokenList.Items {
+		hash := sha256.Sum256([]byte(token.Name))
+		if hex.EncodeToString(hash[:]) == rancherTokenHash {
+			return &token, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// createTokenFromClientCredentials creates a response with an access_token identifying the OIDC client itself, for
+// services without an end-user. Neither an id_token nor a refresh_token are issued, and the access_token is not
+// associated with a Rancher token, so it can't be used to authenticate to Rancher.
+func (h *tokenHandler) createTokenFromClientCredentials(r *http.Request) (TokenResponse, *oidcerror.Error) {
+	oidcClient, oidcErr := h.authenticateClient(r, grantTypeClientCredentials)
+	if oidcErr != nil {
+		return TokenResponse{}, oidcErr
+	}
+	scopes := parseScope(r.Form.Get("scope"))
+	if err := validateScopes(scopes, oidcClient); err != nil {
+		return TokenResponse{}, oidcerror.New(oidcerror.InvalidScope, err.Error())
+	}
+
+	key, kid, err := h.jwks.GetSigningKey()
+	if err != nil {
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to get signing key: %v", err)
+	}
+	method, err := signingMethodForKey(key)
+	if err != nil {
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to get signing method: %v", err)
+	}
+	accessTokenString, err := createClientAccessToken(oidcClient, scopes, kid, method, h.now()).SignedString(key)
+	if err != nil {
+		logrus.Errorf("[OIDC provider] failed to sign access token %v", err)
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to sign access token: %v", err)
+	}
+
+	return TokenResponse{
+		AccessToken: accessTokenString,
+		TokenType:   bearerTokenType,
+		ExpiresIn:   oidcClient.Spec.TokenExpirationSeconds,
+	}, nil
+}
+
+// authenticateClient returns the OIDC client authenticated by the request, if it is allowed to use the grant type.
+func (h *tokenHandler) authenticateClient(r *http.Request, grantType string) (*v3.OIDCClient, *oidcerror.Error) {
+	oidcClient, oidcErr := h.verifyClientCredentials(r)
+	if oidcErr != nil {
+		return nil, oidcErr
+	}
+	if !isGrantTypeAllowed(oidcClient, grantType) {
+		return nil, oidcerror.New(oidcerror.UnauthorizedClient, "grant_type not allowed for this client")
+	}
+
+	return oidcClient, nil
+}
+
+// verifyClientCredentials returns the OIDC client authenticated by the client_id and client_secret of the request,
+// set in the Authorization header or as form params.
+func (h *tokenHandler) verifyClientCredentials(r *http.Request) (*v3.OIDCClient, *oidcerror.Error) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.FormValue("client_id")
+		clientSecret = r.FormValue("client_secret")
+	}
+	if clientID == "" {
+		return nil, oidcerror.New(oidcerror.InvalidClient, "missing client_id")
+	}
+	oidcClient, err := h.getOIDCClientByClientID(clientID)
+	if err != nil {
+		return nil, oidcerror.New(oidcerror.InvalidClient, "invalid client_id")
+	}
+	if oidcErr := h.isValidClientSecret(clientSecret, oidcClient); oidcErr != nil {
+		return nil, oidcErr
+	}
+
+	return oidcClient, nil
+}
+
+// isGrantTypeAllowed returns whether the OIDC client is allowed to use the grant type. Clients not configuring grant
+// types are allowed to use defaultGrantTypes.
+func isGrantTypeAllowed(oidcClient *v3.OIDCClient, grantType string) bool {
+	if len(oidcClient.Spec.GrantTypes) == 0 {
+		return slices.Contains(defaultGrantTypes, grantType)
+	}
+
+	return slices.Contains(oidcClient.Spec.GrantTypes, grantType)
+}
+
 // createTokenResponse creates an id_token, access_token and refresh_token for a valid Rancher token
 func (h *tokenHandler) createTokenResponse(rancherToken accessor.TokenAccessor, oidcClient *v3.OIDCClient, nonce string, scopes []string) (TokenResponse, *oidcerror.Error) {
 	// verify Rancher token and user are valid
@@ -356,8 +450,12 @@ func (h *tokenHandler) createTokenResponse(rancherToken accessor.TokenAccessor,
 	if err != nil {
 		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to get signing key: %v", err)
 	}
+	method, err := signingMethodForKey(key)
+	if err != nil {
+		return TokenResponse{}, oidcerror.Newf(oidcerror.ServerError, "failed to get signing method: %v", err)
+	}
 
-	accessToken := CreateAccessToken(oidcClient, rancherToken, scopes, kid, h.now())
+	accessToken := CreateAccessToken(oidcClient, rancherToken, scopes, kid, method, h.now())
 	accessTokenString, err := accessToken.SignedString(key)
 	if err != nil {
 		logrus.Errorf("[OIDC provider] failed to sign access token %v", err)
@@ -370,7 +468,7 @@ func (h *tokenHandler) createTokenResponse(rancherToken accessor.TokenAccessor,
 	}
 
 	if slices.Contains(scopes, "openid") {
-		idToken := createIDToken(oidcClient, rancherToken, scopes, user, nonce, groups, kid, h.now())
+		idToken := createIDToken(oidcClient, rancherToken, scopes, user, nonce, groups, kid, method, h.now())
 		idTokenString, err := idToken.SignedString(key)
 		if err != nil {
 			logrus.Errorf("[OIDC provider] failed to sign id token %v", err)
@@ -394,7 +492,7 @@ func (h *tokenHandler) createTokenResponse(rancherToken accessor.TokenAccessor,
 		if authProvider != "" {
 			refreshClaims["auth_provider"] = authProvider
 		}
-		refreshToken := jwt.NewWithClaims(jwt.SigningMethodRS256, refreshClaims)
+		refreshToken := jwt.NewWithClaims(method, refreshClaims)
 		refreshToken.Header["kid"] = kid
 		refreshTokenString, err := refreshToken.SignedString(key)
 		if err != nil {
@@ -413,7 +511,7 @@ func (h *tokenHandler) createTokenResponse(rancherToken accessor.TokenAccessor,
 	return resp, nil
 }
 
-func createIDToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAccessor, scopes []string, user *v3.User, nonce string, groups []string, kid string, now time.Time) *jwt.Token {
+func createIDToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAccessor, scopes []string, user *v3.User, nonce string, groups []string, kid string, method jwt.SigningMethod, now time.Time) *jwt.Token {
 	idClaims := jwt.MapClaims{
 		"aud": []string{oidcClient.Status.ClientID},
 		"exp": now.Add(time.Duration(oidcClient.Spec.TokenExpirationSeconds) * time.Second).Unix(),
@@ -435,14 +533,14 @@ func createIDToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAccesso
 	if authProvider != "" {
 		idClaims["auth_provider"] = authProvider
 	}
-	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, idClaims)
+	idToken := jwt.NewWithClaims(method, idClaims)
 	idToken.Header["kid"] = kid
 
 	return idToken
 }
 
 // CreateAccessToken creates and returns a JWT access token.
-func CreateAccessToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAccessor, scopes []string, kid string, now time.Time) *jwt.Token {
+func CreateAccessToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAccessor, scopes []string, kid string, method jwt.SigningMethod, now time.Time) *jwt.Token {
 	// store token name and kind separately
 	accessClaims := jwt.MapClaims{
 		"aud":        []string{oidcClient.Status.ClientID},
@@ -458,7 +556,25 @@ func CreateAccessToken(oidcClient *v3.OIDCClient, rancherToken accessor.TokenAcc
 	if authProvider != "" {
 		accessClaims["auth_provider"] = authProvider
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodRS256, accessClaims)
+	accessToken := jwt.NewWithClaims(method, accessClaims)
+	accessToken.Header["kid"] = kid
+
+	return accessToken
+}
+
+// createClientAccessToken creates and returns a JWT access token for the client_credentials grant. Its subject is the
+// client id.
+func createClientAccessToken(oidcClient *v3.OIDCClient, scopes []string, kid string, method jwt.SigningMethod, now time.Time) *jwt.Token {
+	accessClaims := jwt.MapClaims{
+		"aud":       []string{oidcClient.Status.ClientID},
+		"exp":       now.Add(time.Duration(oidcClient.Spec.TokenExpirationSeconds) * time.Second).Unix(),
+		"iss":       settings.ServerURL.Get() + "/oidc",
+		"iat":       now.Unix(),
+		"sub":       oidcClient.Status.ClientID,
+		"client_id": oidcClient.Status.ClientID,
+		"scope":     scopes,
+	}
+	accessToken := jwt.NewWithClaims(method, accessClaims)
 	accessToken.Header["kid"] = kid
 
 	return accessToken
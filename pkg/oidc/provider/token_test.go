@@ -57,6 +57,7 @@ type mockParams struct {
 		useAttributeLister *fake.MockNonNamespacedCacheInterface[*v3.UserAttribute]
 		sessionClient      *mocks.MocksessionGetterRemover
 		signingKeyGetter   *mocks.MocksigningKeyGetter
+		revocations        *mocks.MocktokenRevoker
 	}
 	const (
 		fakeCode                 = "code123"
@@ -904,6 +905,23 @@ type mockParams struct {
 			},
 			wantError: `{"error":"server_error","error_description":"failed to parse refresh token: token signature is invalid: crypto/rsa: verification error"}`,
 		},
+		"refresh_token fails when it has been revoked": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "refresh_token")
+				data.Set("refresh_token", fakeRefreshTokenString)
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				m.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				m.revocations.EXPECT().IsTokenRevoked(fakeRefreshTokenString).Return(true, nil)
+			},
+			wantError: `{"error":"invalid_grant","error_description":"refresh token has been revoked"}`,
+		},
 		"refresh_token fails when the associated Rancher token is no longer present": {
 			req: func() *http.Request {
 				data := url.Values{}
@@ -1339,6 +1357,136 @@ type mockParams struct {
 			},
 			wantExpiresIn: ptr.To(int64(fakeTokenLifespan)),
 		},
+		"client_credentials returns an access_token for the client": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "client_credentials")
+				data.Set("scope", "openid profile")
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				clientCredentialsOIDCClient := fakeOIDCClient.DeepCopy()
+				clientCredentialsOIDCClient.Spec.GrantTypes = []string{"client_credentials"}
+				m.oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", fakeClientID).Return([]*v3.OIDCClient{clientCredentialsOIDCClient}, nil)
+				m.secretCache.EXPECT().Get("cattle-oidc-client-secrets", fakeClientID).Return(fakeClientk8sSecret, nil)
+				m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, clientSecretIDPatch).Return(clientCredentialsOIDCClient, nil)
+				m.signingKeyGetter.EXPECT().GetSigningKey().Return(privateKey, fakeSigningKey, nil)
+			},
+			wantAccessTokenClaims: &jwt.MapClaims{
+				"aud":       []any{fakeClientID},
+				"exp":       float64(fakeTime().Add(fakeTokenLifespan * time.Second).Unix()),
+				"iss":       settings.ServerURL.Get() + "/oidc",
+				"iat":       float64(fakeTime().Unix()),
+				"sub":       fakeClientID,
+				"client_id": fakeClientID,
+				"scope":     fakeScopes,
+			},
+			wantExpiresIn: ptr.To(int64(fakeTokenLifespan)),
+		},
+		"client_credentials with client credentials as form params": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "client_credentials")
+				data.Set("client_id", fakeClientID)
+				data.Set("client_secret", fakeClientSecret)
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				clientCredentialsOIDCClient := fakeOIDCClient.DeepCopy()
+				clientCredentialsOIDCClient.Spec.GrantTypes = []string{"client_credentials"}
+				m.oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", fakeClientID).Return([]*v3.OIDCClient{clientCredentialsOIDCClient}, nil)
+				m.secretCache.EXPECT().Get("cattle-oidc-client-secrets", fakeClientID).Return(fakeClientk8sSecret, nil)
+				m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, clientSecretIDPatch).Return(clientCredentialsOIDCClient, nil)
+				m.signingKeyGetter.EXPECT().GetSigningKey().Return(privateKey, fakeSigningKey, nil)
+			},
+			wantAccessTokenClaims: &jwt.MapClaims{
+				"aud":       []any{fakeClientID},
+				"exp":       float64(fakeTime().Add(fakeTokenLifespan * time.Second).Unix()),
+				"iss":       settings.ServerURL.Get() + "/oidc",
+				"iat":       float64(fakeTime().Unix()),
+				"sub":       fakeClientID,
+				"client_id": fakeClientID,
+				"scope":     nil,
+			},
+			wantExpiresIn: ptr.To(int64(fakeTokenLifespan)),
+		},
+		"client_credentials is not allowed by default": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "client_credentials")
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				m.oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", fakeClientID).Return([]*v3.OIDCClient{fakeOIDCClient}, nil)
+				m.secretCache.EXPECT().Get("cattle-oidc-client-secrets", fakeClientID).Return(fakeClientk8sSecret, nil)
+				m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, clientSecretIDPatch).Return(fakeOIDCClient, nil)
+			},
+			wantError: `{"error":"unauthorized_client","error_description":"grant_type not allowed for this client"}`,
+		},
+		"client_credentials with an invalid scope": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "client_credentials")
+				data.Set("scope", "openid invalid")
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				clientCredentialsOIDCClient := fakeOIDCClient.DeepCopy()
+				clientCredentialsOIDCClient.Spec.GrantTypes = []string{"client_credentials"}
+				m.oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", fakeClientID).Return([]*v3.OIDCClient{clientCredentialsOIDCClient}, nil)
+				m.secretCache.EXPECT().Get("cattle-oidc-client-secrets", fakeClientID).Return(fakeClientk8sSecret, nil)
+				m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, clientSecretIDPatch).Return(clientCredentialsOIDCClient, nil)
+			},
+			wantError: `{"error":"invalid_scope","error_description":"invalid scope: invalid"}`,
+		},
+		"client_credentials without client_id": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "client_credentials")
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+				return req
+			},
+			wantError: `{"error":"invalid_client","error_description":"missing client_id"}`,
+		},
+		"authorization_code is not allowed when the client only allows client_credentials": {
+			req: func() *http.Request {
+				data := url.Values{}
+				data.Set("grant_type", "authorization_code")
+				data.Set("code", fakeCode)
+				data.Set("code_verifier", fakeCodeVerifier)
+				req, _ := http.NewRequest("POST", "https://rancher.com", bytes.NewBufferString(data.Encode()))
+				req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+				req.Header.Add("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(fakeClientID+":"+fakeClientSecret))))
+
+				return req
+			},
+			mockSetup: func(m mockParams) {
+				clientCredentialsOIDCClient := fakeOIDCClient.DeepCopy()
+				clientCredentialsOIDCClient.Spec.GrantTypes = []string{"client_credentials"}
+				m.sessionClient.EXPECT().GetAndRemove(fakeCode).Return(fakeSession, nil)
+				m.oidcClientCache.EXPECT().GetByIndex("oidc.management.cattle.io/oidcclient-by-id", fakeClientID).Return([]*v3.OIDCClient{clientCredentialsOIDCClient}, nil)
+				m.secretCache.EXPECT().Get("cattle-oidc-client-secrets", fakeClientID).Return(fakeClientk8sSecret, nil)
+				m.oidcClient.EXPECT().Patch(fakeClientName, types.JSONPatchType, clientSecretIDPatch).Return(clientCredentialsOIDCClient, nil)
+			},
+			wantError: `{"error":"unauthorized_client","error_description":"grant_type not allowed for this client"}`,
+		},
 	}
 
 	// register auth provider
@@ -1370,11 +1518,13 @@ type mockParams struct {
 				oidcClient:         fake.NewMockNonNamespacedClientInterface[*v3.OIDCClient, *v3.OIDCClientList](ctrl),
 				sessionClient:      mocks.NewMocksessionGetterRemover(ctrl),
 				signingKeyGetter:   mocks.NewMocksigningKeyGetter(ctrl),
+				revocations:        mocks.NewMocktokenRevoker(ctrl),
 			}
 			if test.mockSetup != nil {
 				test.mockSetup(m)
 			}
-			h := newTokenHandler(m.extTokenStore, m.tokenCache, m.userLister, m.useAttributeLister, m.sessionClient, m.signingKeyGetter, m.oidcClientCache, m.oidcClient, m.secretCache, m.tokenClient)
+			m.revocations.EXPECT().IsTokenRevoked(gomock.Any()).Return(false, nil).AnyTimes()
+			h := newTokenHandler(m.extTokenStore, m.tokenCache, m.userLister, m.useAttributeLister, m.sessionClient, m.signingKeyGetter, m.oidcClientCache, m.oidcClient, m.secretCache, m.tokenClient, nil, m.revocations)
 			h.now = fakeTime
 			rec := httptest.NewRecorder()
 
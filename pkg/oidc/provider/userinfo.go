@@ -10,6 +10,7 @@
 	"github.com/golang-jwt/jwt/v5"
 	wrangmgmtv3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	oidcerror "github.com/rancher/rancher/pkg/oidc/provider/error"
+	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
@@ -24,14 +25,16 @@ type userInfoHandler struct {
 	userCache           wrangmgmtv3.UserCache
 	userAttributeLister wrangmgmtv3.UserAttributeCache
 	jwks                signingKeyGetter
+	revocations         tokenRevoker
 }
 
-func newUserInfoHandler(userLister wrangmgmtv3.UserCache, userAttributeLister wrangmgmtv3.UserAttributeCache, jwks signingKeyGetter) *userInfoHandler {
+func newUserInfoHandler(userLister wrangmgmtv3.UserCache, userAttributeLister wrangmgmtv3.UserAttributeCache, jwks signingKeyGetter, revocations tokenRevoker) *userInfoHandler {
 
 	return &userInfoHandler{
 		userCache:           userLister,
 		userAttributeLister: userAttributeLister,
 		jwks:                jwks,
+		revocations:         revocations,
 	}
 }
 
@@ -47,7 +50,7 @@ func (h *userInfoHandler) userInfoEndpoint(w http.ResponseWriter, r *http.Reques
 	// verify access_token signature
 	_, err = jwt.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (interface{}, error) {
 		// Ensure correct signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		if !slices.Contains(supportedSigningAlgs, token.Method.Alg()) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		kid, ok := token.Header["kid"].(string)
@@ -65,6 +68,17 @@ func (h *userInfoHandler) userInfoEndpoint(w http.ResponseWriter, r *http.Reques
 		oidcerror.WriteError(oidcerror.InvalidRequest, fmt.Sprintf("invalid access_token: %v", err), http.StatusBadRequest, w)
 		return
 	}
+	revoked, err := h.revocations.IsTokenRevoked(accessToken)
+	if err != nil {
+		logrus.Errorf("[OIDC provider] error checking if access token is revoked: %v", err)
+		oidcerror.WriteError(oidcerror.ServerError, "failed to check if access_token is revoked", http.StatusInternalServerError, w)
+		return
+	}
+	if revoked {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		oidcerror.WriteError(oidcerror.InvalidToken, "access_token has been revoked", http.StatusUnauthorized, w)
+		return
+	}
 
 	userId, ok := claims["sub"].(string)
 	if !ok {
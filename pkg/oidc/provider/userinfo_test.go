@@ -27,6 +27,7 @@ type mockParams struct {
 		userCache          *fake.MockNonNamespacedCacheInterface[*v3.User]
 		useAttributeLister *fake.MockNonNamespacedCacheInterface[*v3.UserAttribute]
 		signingKeyGetter   *mocks.MocksigningKeyGetter
+		revocations        *mocks.MocktokenRevoker
 	}
 	const (
 		fakeSigningKey = "signing-key"
@@ -92,6 +93,7 @@ type mockParams struct {
 		mockSetup    func(mockParams)
 		wantResponse *UserInfoResponse
 		wantError    string
+		wantHttpCode int
 		wantHeaders  map[string]string
 	}{
 		"success response": {
@@ -103,6 +105,7 @@ type mockParams struct {
 			},
 			mockSetup: func(mockParams mockParams) {
 				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(fakeAccessTokenString).Return(false, nil)
 				mockParams.userCache.EXPECT().Get(fakeUserID).Return(&fakeUser, nil)
 				mockParams.useAttributeLister.EXPECT().Get(fakeUserID).Return(&fakeUserAttributes, nil)
 			},
@@ -121,6 +124,7 @@ type mockParams struct {
 			},
 			mockSetup: func(mockParams mockParams) {
 				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(fakeAccessTokenNoProfileString).Return(false, nil)
 				mockParams.useAttributeLister.EXPECT().Get(fakeUserID).Return(&fakeUserAttributes, nil)
 			},
 			wantResponse: &UserInfoResponse{
@@ -137,6 +141,7 @@ type mockParams struct {
 			},
 			mockSetup: func(mockParams mockParams) {
 				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(fakeAccessTokenNoGroupsString).Return(false, nil)
 				mockParams.userCache.EXPECT().Get(fakeUserID).Return(&fakeUser, nil)
 			},
 			wantResponse: &UserInfoResponse{
@@ -174,9 +179,26 @@ type mockParams struct {
 			},
 			mockSetup: func(mockParams mockParams) {
 				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(gomock.Any()).Return(false, nil)
 			},
 			wantError: `{"error":"invalid_request","error_description":"invalid access_token: it doesn't have scope"}`,
 		},
+		"revoked access token": {
+			req: func() *http.Request {
+				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com", nil)
+				req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", fakeAccessTokenString))
+				return req
+			},
+			mockSetup: func(mockParams mockParams) {
+				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(fakeAccessTokenString).Return(true, nil)
+			},
+			wantHttpCode: http.StatusUnauthorized,
+			wantHeaders: map[string]string{
+				"WWW-Authenticate": `Bearer error="invalid_token"`,
+			},
+			wantError: `{"error":"invalid_token","error_description":"access_token has been revoked"}`,
+		},
 		"no access token": {
 			req: func() *http.Request {
 				req, _ := http.NewRequest(http.MethodGet, "https://rancher.com", nil)
@@ -192,6 +214,7 @@ type mockParams struct {
 			},
 			mockSetup: func(mockParams mockParams) {
 				mockParams.signingKeyGetter.EXPECT().GetPublicKey(fakeSigningKey).Return(&privateKey.PublicKey, nil)
+				mockParams.revocations.EXPECT().IsTokenRevoked(fakeAccessTokenString).Return(false, nil)
 				mockParams.userCache.EXPECT().Get(fakeUserID).Return(&fakeUser, nil)
 				mockParams.useAttributeLister.EXPECT().Get(fakeUserID).Return(&fakeUserAttributes, nil)
 			},
@@ -209,6 +232,7 @@ type mockParams struct {
 				userCache:          fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl),
 				useAttributeLister: fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl),
 				signingKeyGetter:   mocks.NewMocksigningKeyGetter(ctrl),
+				revocations:        mocks.NewMocktokenRevoker(ctrl),
 			}
 			if test.mockSetup != nil {
 				test.mockSetup(m)
@@ -217,11 +241,15 @@ type mockParams struct {
 				userCache:           m.userCache,
 				userAttributeLister: m.useAttributeLister,
 				jwks:                m.signingKeyGetter,
+				revocations:         m.revocations,
 			}
 			rec := httptest.NewRecorder()
 
 			h.userInfoEndpoint(rec, test.req())
 
+			if test.wantHttpCode != 0 {
+				assert.Equal(t, test.wantHttpCode, rec.Code)
+			}
 			for k, v := range test.wantHeaders {
 				assert.Equal(t, v, rec.Header().Get(k), "response header %s", k)
 			}
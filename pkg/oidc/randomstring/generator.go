@@ -13,6 +13,8 @@
 	clientIDPrefix     = "client-"
 	codePrefix         = "code-"
 	clientSecretPrefix = "secret-"
+	deviceCodePrefix   = "device-"
+	userCodeLength     = 8
 )
 
 type Generator struct{}
@@ -34,6 +36,17 @@ func (r *Generator) GenerateCode() (string, error) {
 	return r.generateRandomString(codePrefix, codeLength)
 }
 
+// GenerateDeviceCode generates an OIDC Device Code. It has 'device-' as a prefix and 56 random characters.
+func (r *Generator) GenerateDeviceCode() (string, error) {
+	return r.generateRandomString(deviceCodePrefix, codeLength)
+}
+
+// GenerateUserCode generates the user code of a device authorization request. It has 8 random characters and no
+// prefix, as it is typed in by the user.
+func (r *Generator) GenerateUserCode() (string, error) {
+	return r.generateRandomString("", userCodeLength)
+}
+
 func (r *Generator) generateRandomString(prefix string, length int) (string, error) {
 	token := make([]byte, length)
 	for i := range token {
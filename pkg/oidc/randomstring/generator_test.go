@@ -36,3 +36,23 @@ func TestGenerateCode(t *testing.T) {
 	assert.True(t, len(code) == 61)
 	assert.True(t, strings.HasPrefix(code, codePrefix))
 }
+
+func TestGenerateDeviceCode(t *testing.T) {
+	g := Generator{}
+
+	code, err := g.GenerateDeviceCode()
+
+	assert.NoError(t, err)
+	assert.True(t, len(code) == 63)
+	assert.True(t, strings.HasPrefix(code, deviceCodePrefix))
+}
+
+func TestGenerateUserCode(t *testing.T) {
+	g := Generator{}
+
+	code, err := g.GenerateUserCode()
+
+	assert.NoError(t, err)
+	assert.Len(t, code, userCodeLength)
+	assert.Empty(t, strings.Trim(code, characters))
+}
@@ -6,8 +6,10 @@
 	"path"
 	"strings"
 
+	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/plan"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -49,7 +51,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:9099/liveness",
 			},
 		},
@@ -58,7 +60,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:2381/health",
 			},
 		},
@@ -67,7 +69,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL:        "https://%s:6443/readyz",
 				CACert:     "%s/server/tls/server-ca.crt",
 				ClientCert: "%s/server/tls/client-kube-apiserver.crt",
@@ -79,7 +81,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "https://%s:%s/healthz",
 			},
 		},
@@ -88,7 +90,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "https://%s:%s/healthz",
 			},
 		},
@@ -97,7 +99,7 @@
 			TimeoutSeconds:      5,
 			SuccessThreshold:    1,
 			FailureThreshold:    5,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL: "http://%s:10248/healthz",
 			},
 		},
@@ -106,7 +108,7 @@
 			TimeoutSeconds:      30,
 			SuccessThreshold:    1,
 			FailureThreshold:    30,
-			HTTPGetAction: plan.HTTPGetAction{
+			HTTPGetAction: &plan.HTTPGetAction{
 				URL:        "https://%s:%d/v1-%s/readyz",
 				CACert:     "%s/agent/server-ca.crt",
 				ClientCert: "%s/agent/client-kubelet.crt",
@@ -117,6 +119,7 @@
 	ErrEmptyCACert  = errors.New("cacert cannot be empty")
 	ErrEmptyPort    = errors.New("port cannot be empty")
 	ErrEmptyAddress = errors.New("address cannot be empty")
+	ErrNotHTTPProbe = errors.New("probe has no httpGet action")
 )
 
 // Adapter is an interface for different types of cluster objects.
@@ -160,6 +163,13 @@ type Adapter interface {
 	// ServerUnit returns the systemd unit name for a distro server node.
 	ServerUnit() string
 
+	// AgentUnit returns the systemd unit name for a distro agent (worker-only) node.
+	AgentUnit() string
+
+	// InstallerImage returns the system-agent installer image for the given RKE2/K3s version, resolved against the
+	// private registry configured for the cluster, if any.
+	InstallerImage(kubernetesVersion string) string
+
 	// DistroDataDirectory returns the path to the RKE2/K3s data-dir on the host machine.
 	DistroDataDirectory(secret *corev1.Secret) string
 
@@ -189,6 +199,10 @@ type Adapter interface {
 	// no suitable candidate exists yet.
 	FindOrElectLeader(operation string, filter Filter) (*corev1.Secret, error)
 
+	// PreviewLeader returns the machine-plan secret FindOrElectLeader would currently return for the
+	// given operation, without marking it. Used to render the plans of a dry run.
+	PreviewLeader(operation string, filter Filter) (*corev1.Secret, error)
+
 	// GetServerURL returns the server url required to join nodes to this host.
 	// The URL is of the form `https://<InternalIP>:<supervisor port>`
 	GetServerURL(secret *corev1.Secret) string
@@ -200,6 +214,16 @@ type Adapter interface {
 	ToS3ArgsEnvAndFiles(secret *corev1.Secret) ([]string, []string, []plan.File)
 }
 
+// VersionedAdapter is implemented by adapters whose cluster object declares the desired RKE2/K3s
+// version, which the cluster's planner converges the nodes to whenever the cluster is not paused.
+// Operations that install a different version must record it there before unpausing.
+type VersionedAdapter interface {
+	Adapter
+
+	// SetKubernetesVersion sets the desired RKE2/K3s version of the cluster.
+	SetKubernetesVersion(version string) error
+}
+
 // NewAdapter returns an Adapter for the given cluster object.
 // For Provisioning clusters the controlPlane object is extracted and then a CAPR Adapter is used to prevent duplication.
 // The wrangler.CAPIContext is used in order to allow the adapter to access specific typed caches for ease of use.
@@ -217,6 +241,11 @@ func NewAdapter(clients *wrangler.CAPIContext, ustr *unstructured.Unstructured)
 	return adapter(clients, ustr)
 }
 
+// installerImage returns the unresolved system-agent installer image for the given RKE2/K3s version.
+func installerImage(kubernetesVersion string) string {
+	return settings.SystemAgentInstallerImage.Get() + capr.GetRuntime(kubernetesVersion) + ":" + strings.ReplaceAll(kubernetesVersion, "+", "-")
+}
+
 type AdapterFactory func(*wrangler.CAPIContext, *unstructured.Unstructured) (Adapter, error)
 
 var adapterFactory = map[string]AdapterFactory{}
@@ -236,6 +265,10 @@ func ReplaceCACertAndPortForProbes(probe plan.Probe, cacert, host, port string)
 	if host == "" {
 		return plan.Probe{}, ErrEmptyAddress
 	}
+	if probe.HTTPGetAction == nil {
+		return plan.Probe{}, ErrNotHTTPProbe
+	}
+	probe = *probe.DeepCopy()
 	probe.HTTPGetAction.CACert = cacert
 	probe.HTTPGetAction.URL = fmt.Sprintf(probe.HTTPGetAction.URL, host, port)
 	return probe, nil
@@ -245,7 +278,16 @@ func ReplaceCACertAndPortForProbes(probe plan.Probe, cacert, host, port string)
 func ReplaceURLForProbes(probes map[string]plan.Probe, loopbackAddress string) map[string]plan.Probe {
 	result := make(map[string]plan.Probe, len(probes))
 	for k, v := range probes {
-		v.HTTPGetAction.URL = replaceIfFormatSpecifier(v.HTTPGetAction.URL, loopbackAddress)
+		v = *v.DeepCopy()
+		if v.HTTPGetAction != nil {
+			v.HTTPGetAction.URL = replaceIfFormatSpecifier(v.HTTPGetAction.URL, loopbackAddress)
+		}
+		if v.TCPSocketAction != nil {
+			v.TCPSocketAction.Address = replaceIfFormatSpecifier(v.TCPSocketAction.Address, loopbackAddress)
+		}
+		if v.GRPCAction != nil {
+			v.GRPCAction.Address = replaceIfFormatSpecifier(v.GRPCAction.Address, loopbackAddress)
+		}
 		result[k] = v
 	}
 	return result
@@ -255,9 +297,22 @@ func ReplaceURLForProbes(probes map[string]plan.Probe, loopbackAddress string) m
 func InsertDataDirForProbes(dataDir string, probes map[string]plan.Probe) map[string]plan.Probe {
 	result := make(map[string]plan.Probe, len(probes))
 	for k, v := range probes {
-		v.HTTPGetAction.CACert = replaceIfFormatSpecifier(v.HTTPGetAction.CACert, dataDir)
-		v.HTTPGetAction.ClientCert = replaceIfFormatSpecifier(v.HTTPGetAction.ClientCert, dataDir)
-		v.HTTPGetAction.ClientKey = replaceIfFormatSpecifier(v.HTTPGetAction.ClientKey, dataDir)
+		v = *v.DeepCopy()
+		if v.HTTPGetAction != nil {
+			v.HTTPGetAction.CACert = replaceIfFormatSpecifier(v.HTTPGetAction.CACert, dataDir)
+			v.HTTPGetAction.ClientCert = replaceIfFormatSpecifier(v.HTTPGetAction.ClientCert, dataDir)
+			v.HTTPGetAction.ClientKey = replaceIfFormatSpecifier(v.HTTPGetAction.ClientKey, dataDir)
+		}
+		if v.ExecAction != nil {
+			for i, arg := range v.ExecAction.Command {
+				v.ExecAction.Command[i] = replaceIfFormatSpecifier(arg, dataDir)
+			}
+		}
+		if v.GRPCAction != nil {
+			v.GRPCAction.CACert = replaceIfFormatSpecifier(v.GRPCAction.CACert, dataDir)
+			v.GRPCAction.ClientCert = replaceIfFormatSpecifier(v.GRPCAction.ClientCert, dataDir)
+			v.GRPCAction.ClientKey = replaceIfFormatSpecifier(v.GRPCAction.ClientKey, dataDir)
+		}
 		result[k] = v
 	}
 	return result
@@ -0,0 +1,39 @@
+package operations
+
+import (
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/sirupsen/logrus"
+)
+
+// CancelPlans cancels the plans the system-agents are still applying on the machine-plan secrets of
+// the cluster, through plan.Store.CancelPlan. It is called from the Canceled phase of an operation,
+// so the plans it handed out do not keep running after the beacon is released. Only the plans
+// assigned by the operation identified by ownerKey, see plan.Store.ForAssigner, are cancelled, and
+// only while it holds the beacon or is in its delegate chain. Plans that were already applied or
+// reached a terminal state are left as they are. The machine-plan secrets are collected from
+// secrets, a cache with the indexers of plan.RegisterIndexers.
+func CancelPlans(store *plan.Store, secrets plan.SecretCache, beacon *planv1alpha1.Beacon, ownerKey string, cluster plan.ClusterRef, namespace string) error {
+	if !plan.IsOwningBeaconHolder(beacon, ownerKey) && !plan.IsInDelegateChain(beacon, ownerKey) {
+		return nil
+	}
+
+	candidates, err := plan.NewCachedCollector(secrets, cluster, namespace).Collect()
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range candidates {
+		if !plan.AssignedBy(secret, ownerKey) {
+			continue
+		}
+		updated, err := store.CancelPlan(secret)
+		if err != nil {
+			return err
+		}
+		if updated != secret {
+			logrus.Infof("[operations] %s: cancelled plan of %s/%s", ownerKey, updated.Namespace, updated.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// cancelSecrets is an in-memory secret client holding machine-plan secrets. Update replaces the
+// stored secret and records its name.
+type cancelSecrets struct {
+	corecontrollers.SecretClient
+
+	items   []*corev1.Secret
+	updated []string
+}
+
+func (f *cancelSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	for i, item := range f.items {
+		if item.Name == secret.Name {
+			f.items[i] = secret.DeepCopy()
+		}
+	}
+	f.updated = append(f.updated, secret.Name)
+	return secret, nil
+}
+
+// cancelSecretCache serves the secrets of a cancelSecrets client as a plan.SecretCache. Index
+// lookups return every secret of the namespace; the Collector filters them by cluster and role.
+type cancelSecretCache struct {
+	corecontrollers.SecretCache
+
+	secrets *cancelSecrets
+}
+
+func (c *cancelSecretCache) List(namespace string, selector labels.Selector) ([]*corev1.Secret, error) {
+	var result []*corev1.Secret
+	for _, secret := range c.secrets.items {
+		if secret.Namespace == namespace && selector.Matches(labels.Set(secret.Labels)) {
+			result = append(result, secret)
+		}
+	}
+	return result, nil
+}
+
+func (c *cancelSecretCache) GetByIndex(_, key string) ([]*corev1.Secret, error) {
+	namespace, _, _ := strings.Cut(key, "/")
+	return c.List(namespace, labels.Everything())
+}
+
+// newCancelSecret returns a machine-plan secret of the "test" cluster assigned a plan in the given
+// state by the given assigner.
+func newCancelSecret(name string, state plan.PlanState, assigner string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "fleet-default",
+			Labels:      map[string]string{"rke.cattle.io/cluster-name": "test"},
+			Annotations: map[string]string{plan.PlanAssignedByAnnotation: assigner},
+		},
+		Type: plan.SecretTypeMachinePlan,
+		Data: map[string][]byte{"plan": []byte("{}"), plan.PlanStateKey: []byte(state)},
+	}
+}
+
+func TestCancelPlans(t *testing.T) {
+	t.Parallel()
+
+	const ownerKey = "fleet-default/upgrade"
+	other := newCancelSecret("other-cluster", plan.PlanStateInProgress, ownerKey)
+	other.Labels["rke.cattle.io/cluster-name"] = "other"
+	secrets := &cancelSecrets{items: []*corev1.Secret{
+		newCancelSecret("in-progress", plan.PlanStateInProgress, ownerKey),
+		newCancelSecret("pending", plan.PlanStatePending, ownerKey),
+		newCancelSecret("succeeded", plan.PlanStateSucceeded, ownerKey),
+		newCancelSecret("other-operation", plan.PlanStateInProgress, "fleet-default/restore"),
+		newCancelSecret("unassigned", plan.PlanStateInProgress, ""),
+		other,
+	}}
+	beacon := &planv1alpha1.Beacon{Status: planv1alpha1.BeaconStatus{Owner: ownerKey}}
+	cluster := &metav1.ObjectMeta{Name: "test"}
+
+	// Operations not holding the beacon cancel nothing.
+	err := CancelPlans(plan.NewStore(secrets), &cancelSecretCache{secrets: secrets}, &planv1alpha1.Beacon{}, ownerKey, cluster, "fleet-default")
+	assert.NoError(t, err)
+	assert.Empty(t, secrets.updated)
+
+	err = CancelPlans(plan.NewStore(secrets), &cancelSecretCache{secrets: secrets}, beacon, ownerKey, cluster, "fleet-default")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"in-progress", "pending"}, secrets.updated)
+	for _, secret := range secrets.items {
+		cancelled := secret.Name == "in-progress" || secret.Name == "pending"
+		assert.Equal(t, cancelled, secret.Annotations[plan.PlanCancelledAnnotation] == "true", secret.Name)
+	}
+
+	// Plans that were already cancelled are not cancelled again.
+	err = CancelPlans(plan.NewStore(secrets), &cancelSecretCache{secrets: secrets}, beacon, ownerKey, cluster, "fleet-default")
+	assert.NoError(t, err)
+	assert.Len(t, secrets.updated, 2)
+}
@@ -11,6 +11,7 @@
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/plan"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	provimage "github.com/rancher/rancher/pkg/provisioningv2/image"
 	"github.com/rancher/rancher/pkg/utils"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/v3/pkg/data/convert"
@@ -192,6 +193,17 @@ func (a *CAPRAdapter) ServerUnit() string {
 	return "k3s"
 }
 
+// AgentUnit returns the systemd unit name for a distro agent (worker-only) node.
+func (a *CAPRAdapter) AgentUnit() string {
+	return a.RuntimeCommand() + "-agent"
+}
+
+// InstallerImage returns the system-agent installer image for the given version, resolved against the
+// registry configured on the RKEControlPlane.
+func (a *CAPRAdapter) InstallerImage(kubernetesVersion string) string {
+	return provimage.ResolveWithControlPlane(installerImage(kubernetesVersion), a.controlPlane)
+}
+
 // RenderProbes renders the probes for a given machine-plan secret based on its role.
 // If the cluster is using a custom data directory or secure probes, this information is extracted from the cluster object and rendered in.
 func (a *CAPRAdapter) RenderProbes(secret *corev1.Secret, supervisor bool) (map[string]plan.Probe, error) {
@@ -237,6 +249,7 @@ func (a *CAPRAdapter) RenderProbes(secret *corev1.Secret, supervisor bool) (map[
 		if runtime == capr.RuntimeK3S {
 			port = 6443
 		}
+		supervisorProbe = *supervisorProbe.DeepCopy()
 		supervisorProbe.HTTPGetAction.URL = fmt.Sprintf(supervisorProbe.HTTPGetAction.URL, loopbackAddress, port, runtime)
 		probes[SupervisorProbeName] = supervisorProbe
 	}
@@ -284,8 +297,19 @@ func (a *CAPRAdapter) isSuitableLeader(s *corev1.Secret) (bool, error) {
 // otherwise a new leader is elected and the annotation written with retry-on-conflict.
 // Returns nil, nil when no suitable candidate exists yet.
 func (a *CAPRAdapter) FindOrElectLeader(operation string, filter Filter) (*corev1.Secret, error) {
-	secrets := a.clients.Core.Secret()
-	candidates, err := plan.NewCollector(secrets, a.controlPlane, a.controlPlane.Namespace).
+	return a.findOrElectLeader(operation, filter, true)
+}
+
+// PreviewLeader returns the machine-plan secret FindOrElectLeader would return for the given
+// operation without writing or clearing the leader annotation.
+func (a *CAPRAdapter) PreviewLeader(operation string, filter Filter) (*corev1.Secret, error) {
+	return a.findOrElectLeader(operation, filter, false)
+}
+
+// findOrElectLeader implements FindOrElectLeader and PreviewLeader. The leader annotation is
+// only written or cleared when elect is true.
+func (a *CAPRAdapter) findOrElectLeader(operation string, filter Filter, elect bool) (*corev1.Secret, error) {
+	candidates, err := plan.NewCachedCollector(a.clients.Core.Secret().Cache(), a.controlPlane, a.controlPlane.Namespace).
 		WithFilter(plan.FilterFunc(filter)).
 		WithSorter(plan.DefaultSorter()).
 		Collect()
@@ -332,18 +356,21 @@ func (a *CAPRAdapter) FindOrElectLeader(operation string, filter Filter) (*corev
 		if markedReady {
 			return marked, nil
 		}
-		logrus.Warnf("[operations] %s/%s: elected leader %s is no longer suitable, re-electing", a.controlPlane.Namespace, a.controlPlane.Name, marked.Name)
-		if err := a.clearLeaderAnnotation(marked, operation); err != nil {
-			return nil, err
+		if elect {
+			logrus.Warnf("[operations] %s/%s: elected leader %s is no longer suitable, re-electing", a.controlPlane.Namespace, a.controlPlane.Name, marked.Name)
+			if err := a.clearLeaderAnnotation(marked, operation); err != nil {
+				return nil, err
+			}
 		}
 	}
-	if initCandidate != nil {
-		return a.markLeader(initCandidate, operation)
+	leader := initCandidate
+	if leader == nil {
+		leader = fallback
 	}
-	if fallback != nil {
-		return a.markLeader(fallback, operation)
+	if leader == nil || !elect {
+		return leader, nil
 	}
-	return nil, nil
+	return a.markLeader(leader, operation)
 }
 
 func (a *CAPRAdapter) markLeader(secret *corev1.Secret, operation string) (*corev1.Secret, error) {
@@ -568,3 +595,35 @@ func (a *CAPRAdapter) PauseCluster(pause bool) error {
 	_, err = a.clients.CAPI.Cluster().Update(cluster)
 	return err
 }
+
+// SetKubernetesVersion sets the desired RKE2/K3s version of the cluster, so the planner converges
+// on the version installed by an operation instead of rolling the nodes back once the cluster is
+// unpaused. The version is written to the provisioning cluster when one exists, as it owns the
+// RKEControlPlane spec; otherwise the RKEControlPlane is updated directly.
+func (a *CAPRAdapter) SetKubernetesVersion(version string) error {
+	cluster, err := a.clients.Provisioning.Cluster().Cache().Get(a.controlPlane.Namespace, a.controlPlane.Name)
+	if err == nil {
+		if cluster.Spec.KubernetesVersion == version {
+			return nil
+		}
+		cluster = cluster.DeepCopy()
+		cluster.Spec.KubernetesVersion = version
+		_, err = a.clients.Provisioning.Cluster().Update(cluster)
+		return err
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		controlPlane, err := a.clients.RKE.RKEControlPlane().Get(a.controlPlane.Namespace, a.controlPlane.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if controlPlane.Spec.KubernetesVersion == version {
+			return nil
+		}
+		controlPlane.Spec.KubernetesVersion = version
+		_, err = a.clients.RKE.RKEControlPlane().Update(controlPlane)
+		return err
+	})
+}
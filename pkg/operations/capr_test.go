@@ -7,6 +7,7 @@
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/capr"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
 	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
@@ -76,6 +77,50 @@ func TestCAPRAdapter_ServerUnit(t *testing.T) {
 	}
 }
 
+func TestCAPRAdapter_AgentUnit(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		k8sVersion string
+		want       string
+	}{
+		{"rke2 version", "v1.28.5+rke2r1", "rke2-agent"},
+		{"k3s version", "v1.28.5+k3s1", "k3s-agent"},
+		{"k3s default", "v1.28.5", "k3s-agent"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &CAPRAdapter{
+				controlPlane: &rkev1.RKEControlPlane{
+					Spec: rkev1.RKEControlPlaneSpec{
+						KubernetesVersion: tc.k8sVersion,
+					},
+				},
+			}
+			got := a.AgentUnit()
+			assert.Equal(t, tc.want, got, "AgentUnit mismatch for %s", tc.k8sVersion)
+		})
+	}
+}
+
+func TestCAPRAdapter_InstallerImage(t *testing.T) {
+	t.Parallel()
+
+	a := &CAPRAdapter{
+		controlPlane: &rkev1.RKEControlPlane{
+			Spec: rkev1.RKEControlPlaneSpec{
+				KubernetesVersion: "v1.28.5+rke2r1",
+			},
+		},
+	}
+
+	// The installer image is derived from the requested version, not the version on the control plane.
+	assert.Equal(t, settings.SystemAgentInstallerImage.Get()+"rke2:v1.29.1-rke2r1", a.InstallerImage("v1.29.1+rke2r1"))
+	assert.Equal(t, settings.SystemAgentInstallerImage.Get()+"k3s:v1.29.1-k3s1", a.InstallerImage("v1.29.1+k3s1"))
+}
+
 // --- WaitForRegister ------------------------------------------------------------------------
 
 func newMachinePlanSecret(name, machineName string) *corev1.Secret {
@@ -7,6 +7,7 @@
 	bootstrapv1beta2 "github.com/rancher/cluster-api-provider-rke2/bootstrap/api/v1beta2"
 	controlplanev1beta2 "github.com/rancher/cluster-api-provider-rke2/controlplane/api/v1beta2"
 	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/image"
 	"github.com/rancher/rancher/pkg/plan"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -203,6 +204,17 @@ func (a *CAPRKE2Adapter) ServerUnit() string {
 	return "rke2-server"
 }
 
+// AgentUnit returns the systemd unit name for the RKE2 agent.
+func (a *CAPRKE2Adapter) AgentUnit() string {
+	return "rke2-agent"
+}
+
+// InstallerImage returns the system-agent installer image for the given version, resolved against the
+// system-default-registry.
+func (a *CAPRKE2Adapter) InstallerImage(kubernetesVersion string) string {
+	return image.Resolve(installerImage(kubernetesVersion))
+}
+
 // extraArgsFor returns the ExtraArgs slice for the named control-plane component, or nil when
 // the component is unset on the RKE2ControlPlane spec. The result is passed into
 // renderSecureProbe (which accepts `any`) to drive --secure-port / --tls-cert-file / --cert-dir
@@ -266,6 +278,7 @@ func (a *CAPRKE2Adapter) RenderProbes(secret *corev1.Secret, supervisor bool) (m
 	// substitution doesn't break it. RKE2 supervisor port is always 9345.
 	if supervisor && (IsEtcd(secret) || IsControlPlane(secret)) {
 		supervisorProbe := AllProbes[SupervisorProbeName]
+		supervisorProbe = *supervisorProbe.DeepCopy()
 		supervisorProbe.HTTPGetAction.URL = fmt.Sprintf(supervisorProbe.HTTPGetAction.URL, loopbackAddress, 9345, capr.RuntimeRKE2)
 		probes[SupervisorProbeName] = supervisorProbe
 	}
@@ -311,8 +324,19 @@ func (a *CAPRKE2Adapter) isSuitableLeader(s *corev1.Secret) (bool, error) {
 // existing annotated leader if still suitable, otherwise prefer the init node, otherwise fall
 // back to the first sorted candidate.
 func (a *CAPRKE2Adapter) FindOrElectLeader(operation string, filter Filter) (*corev1.Secret, error) {
-	secrets := a.clients.Core.Secret()
-	candidates, err := plan.NewCollector(secrets, a.controlPlane, a.controlPlane.Namespace).
+	return a.findOrElectLeader(operation, filter, true)
+}
+
+// PreviewLeader returns the machine-plan secret FindOrElectLeader would return for the given
+// operation without writing or clearing the leader annotation.
+func (a *CAPRKE2Adapter) PreviewLeader(operation string, filter Filter) (*corev1.Secret, error) {
+	return a.findOrElectLeader(operation, filter, false)
+}
+
+// findOrElectLeader implements FindOrElectLeader and PreviewLeader. The leader annotation is
+// only written or cleared when elect is true.
+func (a *CAPRKE2Adapter) findOrElectLeader(operation string, filter Filter, elect bool) (*corev1.Secret, error) {
+	candidates, err := plan.NewCachedCollector(a.clients.Core.Secret().Cache(), a.controlPlane, a.controlPlane.Namespace).
 		WithFilter(plan.FilterFunc(filter)).
 		WithSorter(plan.DefaultSorter()).
 		Collect()
@@ -359,18 +383,21 @@ func (a *CAPRKE2Adapter) FindOrElectLeader(operation string, filter Filter) (*co
 		if markedReady {
 			return marked, nil
 		}
-		logrus.Warnf("[operations] %s/%s: elected leader %s is no longer suitable, re-electing", a.controlPlane.Namespace, a.controlPlane.Name, marked.Name)
-		if err := a.clearLeaderAnnotation(marked, operation); err != nil {
-			return nil, err
+		if elect {
+			logrus.Warnf("[operations] %s/%s: elected leader %s is no longer suitable, re-electing", a.controlPlane.Namespace, a.controlPlane.Name, marked.Name)
+			if err := a.clearLeaderAnnotation(marked, operation); err != nil {
+				return nil, err
+			}
 		}
 	}
-	if initCandidate != nil {
-		return a.markLeader(initCandidate, operation)
+	leader := initCandidate
+	if leader == nil {
+		leader = fallback
 	}
-	if fallback != nil {
-		return a.markLeader(fallback, operation)
+	if leader == nil || !elect {
+		return leader, nil
 	}
-	return nil, nil
+	return a.markLeader(leader, operation)
 }
 
 // markLeader writes the OperationLeaderAnnotation on the given secret with retry-on-conflict.
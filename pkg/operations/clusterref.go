@@ -0,0 +1,30 @@
+package operations
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ValidateClusterRef returns an error when ref does not point at the cluster the operation's
+// namespace belongs to. Operation permissions are granted per namespace (cluster owners receive
+// them in the management cluster namespace), so an operation must never reach a cluster in another
+// namespace:
+//   - a namespaced cluster object (provisioning or CAPI cluster) must live in the operation's
+//     namespace;
+//   - a cluster-scoped management cluster must be the one named after the operation's namespace.
+func ValidateClusterRef(namespace string, ref *corev1.ObjectReference) error {
+	if ref == nil {
+		return fmt.Errorf("clusterRef is required")
+	}
+	if ref.Namespace != "" {
+		if ref.Namespace != namespace {
+			return fmt.Errorf("clusterRef namespace %s does not match the operation namespace %s", ref.Namespace, namespace)
+		}
+		return nil
+	}
+	if ref.Name != namespace {
+		return fmt.Errorf("clusterRef %s does not belong to the operation namespace %s", ref.Name, namespace)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateClusterRef(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		ref     *corev1.ObjectReference
+		wantErr bool
+	}{
+		{name: "missing ref", ref: nil, wantErr: true},
+		{name: "namespaced cluster in the operation namespace", ref: &corev1.ObjectReference{Kind: "Cluster", Namespace: "fleet-default", Name: "test"}},
+		{name: "namespaced cluster in another namespace", ref: &corev1.ObjectReference{Kind: "Cluster", Namespace: "other", Name: "test"}, wantErr: true},
+		{name: "management cluster named after the operation namespace", ref: &corev1.ObjectReference{Kind: "Cluster", Name: "fleet-default"}},
+		{name: "another management cluster", ref: &corev1.ObjectReference{Kind: "Cluster", Name: "c-other"}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateClusterRef("fleet-default", tc.ref)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package operations
+
+import (
+	"fmt"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// CreatedByPolicyName is the name of the MutatingAdmissionPolicy, and of its binding, stamping
+// operations with the user that created them.
+const CreatedByPolicyName = "operation-created-by.cattle.io"
+
+// createdByResources are the operation resources stamped with the CreatedByAnnotation.
+var createdByResources = []string{
+	opv1alpha1.CertificateRotationResourceName,
+	opv1alpha1.CustomOperationResourceName,
+	opv1alpha1.ETCDSnapshotRestoreResourceName,
+	opv1alpha1.ETCDSnapshotSaveResourceName,
+	opv1alpha1.EncryptionKeyRotationResourceName,
+	opv1alpha1.KubernetesUpgradeResourceName,
+	opv1alpha1.OperationSetResourceName,
+}
+
+// CreatedByPolicy returns the MutatingAdmissionPolicy and binding setting the CreatedByAnnotation of
+// every operation to the user the API server authenticated the create request as. Requests made
+// through the Rancher API impersonate the Rancher user, so the annotation holds the ID of the
+// Rancher user. Updates carrying the annotation have it reset to the value it held before, so it
+// cannot be forged after the fact either.
+func CreatedByPolicy() (*admissionregistrationv1.MutatingAdmissionPolicy, *admissionregistrationv1.MutatingAdmissionPolicyBinding) {
+	failurePolicy := admissionregistrationv1.Fail
+	annotation := opv1alpha1.CreatedByAnnotation
+
+	policy := &admissionregistrationv1.MutatingAdmissionPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingAdmissionPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: CreatedByPolicyName},
+		Spec: admissionregistrationv1.MutatingAdmissionPolicySpec{
+			MatchConstraints: &admissionregistrationv1.MatchResources{
+				ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{{
+					RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+						Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{opv1alpha1.SchemeGroupVersion.Group},
+							APIVersions: []string{"*"},
+							Resources:   createdByResources,
+						},
+					},
+				}},
+			},
+			MatchConditions: []admissionregistrationv1.MatchCondition{{
+				Name: "create-or-annotated-update",
+				Expression: fmt.Sprintf(`request.operation == "CREATE" || (has(object.metadata.annotations) && %q in object.metadata.annotations)`,
+					annotation),
+			}},
+			Mutations: []admissionregistrationv1.Mutation{{
+				PatchType: admissionregistrationv1.PatchTypeApplyConfiguration,
+				ApplyConfiguration: &admissionregistrationv1.ApplyConfiguration{
+					Expression: fmt.Sprintf(`Object{metadata: Object.metadata{annotations: {%[1]q: request.operation == "CREATE" ? request.userInfo.username : `+
+						`(has(oldObject.metadata.annotations) && %[1]q in oldObject.metadata.annotations ? oldObject.metadata.annotations[%[1]q] : "")}}}`,
+						annotation),
+				},
+			}},
+			FailurePolicy:      &failurePolicy,
+			ReinvocationPolicy: admissionregistrationv1.NeverReinvocationPolicy,
+		},
+	}
+
+	binding := &admissionregistrationv1.MutatingAdmissionPolicyBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingAdmissionPolicyBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: CreatedByPolicyName},
+		Spec: admissionregistrationv1.MutatingAdmissionPolicyBindingSpec{
+			PolicyName: CreatedByPolicyName,
+		},
+	}
+
+	return policy, binding
+}
+
+// ApplyCreatedByPolicy applies the policy returned by CreatedByPolicy and its binding.
+func ApplyCreatedByPolicy(apply apply.Apply) error {
+	policy, binding := CreatedByPolicy()
+	return apply.
+		WithSetID("operation-created-by").
+		WithDynamicLookup().
+		ApplyObjects([]runtime.Object{policy, binding}...)
+}
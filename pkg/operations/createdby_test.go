@@ -0,0 +1,30 @@
+package operations
+
+import (
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+)
+
+func TestCreatedByPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, binding := CreatedByPolicy()
+
+	assert.Equal(t, CreatedByPolicyName, binding.Spec.PolicyName)
+	if assert.Len(t, policy.Spec.MatchConstraints.ResourceRules, 1) {
+		rule := policy.Spec.MatchConstraints.ResourceRules[0]
+		assert.ElementsMatch(t, []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}, rule.Operations)
+		assert.Equal(t, []string{opv1alpha1.SchemeGroupVersion.Group}, rule.APIGroups)
+		assert.Contains(t, rule.Resources, opv1alpha1.KubernetesUpgradeResourceName)
+		assert.NotContains(t, rule.Resources, opv1alpha1.OperationRecordResourceName, "records are written by Rancher, not by users")
+	}
+	if assert.Len(t, policy.Spec.Mutations, 1) {
+		expression := policy.Spec.Mutations[0].ApplyConfiguration.Expression
+		assert.Contains(t, expression, `"operation.cattle.io/created-by": request.operation == "CREATE" ? request.userInfo.username`)
+		assert.Contains(t, expression, `oldObject.metadata.annotations["operation.cattle.io/created-by"]`)
+	}
+	assert.Equal(t, admissionregistrationv1.Fail, *policy.Spec.FailurePolicy)
+}
@@ -0,0 +1,796 @@
+package operations
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Object is implemented by every operation type driven by an Engine.
+type Object interface {
+	runtime.Object
+	metav1.Object
+}
+
+// OperationClient is the subset of the generated operation controller an Engine needs. The
+// generated controllers, e.g. operationcontrollers.KubernetesUpgradeController, satisfy it
+// directly.
+type OperationClient[T Object] interface {
+	Get(namespace, name string, options metav1.GetOptions) (T, error)
+	Delete(namespace, name string, options *metav1.DeleteOptions) error
+	EnqueueAfter(namespace, name string, duration time.Duration)
+}
+
+// DynamicResolver is the subset of *dynamic.Controller an Engine needs: Get for cluster lookup
+// during dispatch, and Enqueue for nudging the parent cluster controller after a successful
+// operation.
+type DynamicResolver interface {
+	Get(gvk schema.GroupVersionKind, namespace, name string) (runtime.Object, error)
+	Enqueue(gvk schema.GroupVersionKind, namespace, name string) error
+}
+
+// StepFunc reconciles a single step of an operation. It returns true once the step is complete,
+// at which point the Engine advances to the next step, or marks the operation Succeeded after the
+// last one. A StepFunc that needs to wait updates the InProgress condition and returns false; a
+// StepFunc that gives up sets a terminal phase on the status, e.g. through Fail or Cancel.
+type StepFunc[T Object, S any] func(s *Scope[T], status *S) (bool, error)
+
+// PreviewFunc renders the plans a step would assign into preview without assigning them. A
+// non-empty message fails the dry run, e.g. when the step would fail on the current cluster state.
+type PreviewFunc[T Object] func(s *Scope[T], preview *PlanPreview) (string, error)
+
+// Step is a single, ordered step of an operation driven by an Engine.
+type Step[T Object, S any, K ~string] struct {
+	// Name is recorded in the operation status while the step is running.
+	Name K
+
+	// HookLabelPrefix gates the step. When the operation carries a label with this prefix, the
+	// beacon is delegated to the label value before Reconcile is called. Steps without a prefix
+	// cannot be delegated.
+	HookLabelPrefix string
+
+	// PauseCluster pauses the cluster after the step hook has cleared and before Reconcile is
+	// called, so the provisioning controllers do not race with the plans handed out by the step.
+	// The cluster is unpaused once the operation reaches a terminal phase.
+	PauseCluster bool
+
+	// Reconcile drives the step.
+	Reconcile StepFunc[T, S]
+
+	// Timeout is the default time the operation may spend in the step before it is marked as
+	// Failed. It is overridden by spec.stepTimeouts; zero means the step never times out.
+	Timeout time.Duration
+
+	// Preview renders the plans of the step for a dry run. In a dry run, the first step declaring
+	// a Preview and every later step are previewed rather than reconciled, while earlier steps,
+	// such as preflight checks, are reconciled as usual. Later steps without a Preview are skipped.
+	Preview PreviewFunc[T]
+
+	// Recovery marks a step that only runs once another step switched the operation to it, e.g. to
+	// roll back after a failure. The Engine never advances into a recovery step, so the operation
+	// succeeds once the last other step completes, and dry runs do not preview it.
+	Recovery bool
+}
+
+// Definition declares an operation type for an Engine. The accessors bridge between the typed
+// operation objects and the fields shared by every operation, since the Engine cannot reach them
+// through its type parameters.
+type Definition[T Object, S any, K ~string] struct {
+	// Name identifies the operation type. It prefixes the beacon owner key of every operation of
+	// this type, and is used as the log tag.
+	Name string
+
+	// GroupVersionKind is the kind of the operation type, used to reference operations from the
+	// objects they own.
+	GroupVersionKind schema.GroupVersionKind
+
+	// Steps are run in order once the operation has acquired the beacon and every system-agent
+	// has registered. At least one step is required.
+	Steps []Step[T, S, K]
+
+	// Spec returns the shared spec of op.
+	Spec func(op T) *opv1alpha1.OperationSpec
+
+	// Status returns the current status of op.
+	Status func(op T) *S
+
+	// OperationStatus returns the shared status embedded in status.
+	OperationStatus func(status *S) *opv1alpha1.OperationStatus
+
+	// Step returns the step field of status.
+	Step func(status *S) *K
+
+	// StepFailed, when set, is called instead of Fail when the current step times out, so the
+	// operation can handle the timeout like any other failure of the step, e.g. by switching to a
+	// recovery step.
+	StepFailed func(s *Scope[T], status *S, reason, message string)
+}
+
+// Scope bundles the per-reconcile values derived from the operation, parent cluster, and beacon.
+// It is built fresh on every invocation and handed to the StepFunc of the current step.
+type Scope[T Object] struct {
+	Op        T
+	OwnerKey  string
+	Namespace string
+
+	Beacon  *planv1alpha1.Beacon
+	Cluster *unstructured.Unstructured
+	Adapter Adapter
+}
+
+// IdempotencyValue returns the value used to scope idempotent instructions to this operation.
+func (s *Scope[T]) IdempotencyValue() string {
+	return string(s.Op.GetUID())
+}
+
+// Engine drives operations through the shared state machine: acquire the cluster's beacon while
+// Pending, wait for the system-agents to register, run the declared steps in order while
+// InProgress, and finally unpause the cluster and release the beacon in the terminal phase.
+// Phase and step hooks, beacon delegation and TTL expiry are handled on behalf of every step.
+type Engine[T Object, S any, K ~string] struct {
+	def Definition[T, S, K]
+
+	operations  OperationClient[T]
+	beacons     plancontrollers.BeaconClient
+	configMaps  corecontrollers.ConfigMapClient
+	secretCache corecontrollers.SecretCache
+	store       *plan.Store
+	dynamic     DynamicResolver
+	newAdapter  func(*unstructured.Unstructured) (Adapter, error)
+}
+
+// NewEngine returns an Engine for the given operation definition. Register its OnChange method
+// as the status handler of the operation type.
+func NewEngine[T Object, S any, K ~string](clients *wrangler.CAPIContext, operations OperationClient[T], def Definition[T, S, K]) *Engine[T, S, K] {
+	return &Engine[T, S, K]{
+		def:         def,
+		operations:  operations,
+		beacons:     clients.Plan.Beacon(),
+		configMaps:  clients.Core.ConfigMap(),
+		secretCache: clients.Core.Secret().Cache(),
+		store:       plan.NewStore(clients.Core.Secret()),
+		dynamic:     clients.Dynamic,
+		newAdapter: func(ustr *unstructured.Unstructured) (Adapter, error) {
+			return NewAdapter(clients, ustr)
+		},
+	}
+}
+
+// OnChange is the status handler entrypoint. It dispatches to the phase-specific handler, then
+// refreshes the common conditions.
+//
+// When the resulting status is identical to the prior status, the handler either deletes the
+// operation (terminal phase past its TTL) or re-enqueues itself after 5 seconds so the next poll
+// can pick up any out-of-band changes (plan secret state, beacon transitions, etc.).
+func (e *Engine[T, S, K]) OnChange(op T, status S) (S, error) {
+	if isNilObject(op) {
+		return status, nil
+	}
+
+	if err := e.onChange(op, &status); err != nil {
+		return status, err
+	}
+	e.updateStatus(op, &status)
+
+	if equality.Semantic.DeepEqual(e.def.Status(op), &status) {
+		opStatus := e.def.OperationStatus(&status)
+		// The HasActiveLifecycleHook guard defers TTL garbage collection while any lifecycle-hook
+		// label is still on the op, so a terminal op waiting on a delegate is not deleted out from
+		// under it.
+		if IsTerminal(opStatus.Phase) &&
+			IsExpired(e.def.Spec(op), opStatus) &&
+			!planv1alpha1.HasActiveLifecycleHook(op) {
+			if err := e.operations.Delete(op.GetNamespace(), op.GetName(), &metav1.DeleteOptions{}); err != nil {
+				return status, err
+			}
+			return status, generic.ErrSkip
+		}
+
+		e.operations.EnqueueAfter(op.GetNamespace(), op.GetName(), 5*time.Second)
+	}
+	return status, nil
+}
+
+// onChange resolves the parent cluster reference, locates the cluster's beacon, builds an Adapter
+// for the cluster kind, and dispatches to the phase-specific handler. Leaves the status unmodified
+// when op is being deleted or paused, and while the beacon has not yet been created during the
+// Pending phase.
+func (e *Engine[T, S, K]) onChange(op T, status *S) error {
+	if op.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	spec := e.def.Spec(op)
+	if IsPaused(spec) {
+		logrus.Debugf("[%s] %s/%s: skipping paused operation", e.def.Name, op.GetNamespace(), op.GetName())
+		return nil
+	}
+
+	opStatus := e.def.OperationStatus(status)
+	if opStatus.Phase == "" {
+		setPhase(opStatus, opv1alpha1.OperationPhasePending)
+	}
+
+	if spec.ClusterRef == nil {
+		Fail(opStatus, opv1alpha1.ClusterNotFoundReason, "clusterRef is required")
+		return nil
+	}
+	if err := ValidateClusterRef(op.GetNamespace(), spec.ClusterRef); err != nil {
+		logrus.Errorf("[%s] %s/%s: rejecting clusterRef: %v", e.def.Name, op.GetNamespace(), op.GetName(), err)
+		Fail(opStatus, opv1alpha1.ClusterRefForbiddenReason, err.Error())
+		return nil
+	}
+
+	gvk := schema.FromAPIVersionAndKind(spec.ClusterRef.APIVersion, spec.ClusterRef.Kind)
+	ref, err := e.dynamic.Get(gvk, spec.ClusterRef.Namespace, spec.ClusterRef.Name)
+	if apierrors.IsNotFound(err) {
+		key := fmt.Sprintf("apiVersion=%s, kind=%s", spec.ClusterRef.APIVersion, spec.ClusterRef.Kind)
+		if spec.ClusterRef.Namespace != "" {
+			key += fmt.Sprintf(", namespace=%s", spec.ClusterRef.Namespace)
+		}
+		key += fmt.Sprintf(", name=%s", spec.ClusterRef.Name)
+		logrus.Errorf("[%s] %s/%s: failed to find cluster for %s", e.def.Name, op.GetNamespace(), op.GetName(), key)
+
+		Fail(opStatus, opv1alpha1.ClusterNotFoundReason, fmt.Sprintf("cluster %s not found", key))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ustrMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ref)
+	if err != nil {
+		return err
+	}
+
+	ustr := unstructured.Unstructured{Object: ustrMap}
+
+	a, err := e.newAdapter(&ustr)
+	if err != nil {
+		return err
+	}
+
+	clusterObj, err := a.ClusterObject()
+	if err != nil {
+		return err
+	}
+
+	// Resolve the beacon via the adapter, not the ClusterRef, as the beacon lives next to the
+	// cluster object the adapter drives, which is not necessarily the referenced object.
+	namespace, beaconName := a.BeaconRef()
+
+	beacon, err := e.beacons.Get(namespace, beaconName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && opStatus.Phase == opv1alpha1.OperationPhasePending {
+		logrus.Warnf("[%s] %s/%s: failed to find beacon %s/%s", e.def.Name, op.GetNamespace(), op.GetName(), namespace, beaconName)
+
+		opv1alpha1.PendingCondition.True(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.WaitingForBeaconReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "waiting for beacon creation")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s := &Scope[T]{
+		Op:        op,
+		OwnerKey:  plan.ControllerOwnerKey(op, e.def.Name),
+		Namespace: namespace,
+		Beacon:    beacon,
+		Cluster:   clusterObj,
+		Adapter:   a,
+	}
+
+	switch opStatus.Phase {
+	case opv1alpha1.OperationPhasePending:
+		return e.handlePending(s, status)
+	case opv1alpha1.OperationPhaseInProgress:
+		return e.handleInProgress(s, status)
+	case opv1alpha1.OperationPhaseCanceled:
+		return e.handleTerminal(s, opStatus, planv1alpha1.CanceledPhaseHookLabelPrefix, opv1alpha1.CanceledCondition)
+	case opv1alpha1.OperationPhaseFailed:
+		return e.handleTerminal(s, opStatus, planv1alpha1.FailedPhaseHookLabelPrefix, opv1alpha1.FailedCondition)
+	case opv1alpha1.OperationPhaseSucceeded:
+		return e.handleTerminal(s, opStatus, planv1alpha1.SucceededPhaseHookLabelPrefix, opv1alpha1.SucceededCondition)
+	case opv1alpha1.OperationPhaseDryRunCompleted:
+		return e.handleDryRunCompleted(s)
+	}
+
+	Fail(opStatus, opv1alpha1.UnknownPhaseReason, fmt.Sprintf("unknown phase [%s]", opStatus.Phase))
+	return nil
+}
+
+// handlePending queues the operation for the cluster's beacon and acquires it once the operation
+// is next in line and its schedule allows it to start. It then runs the Pending phase hook and
+// waits for every expected system-agent to register a machine-plan secret. On success the
+// operation transitions to InProgress at the first step.
+func (e *Engine[T, S, K]) handlePending(s *Scope[T], status *S) error {
+	opStatus := e.def.OperationStatus(status)
+
+	if IsDryRun(e.def.Spec(s.Op)) {
+		return e.handleDryRun(s, opStatus)
+	}
+
+	if err := e.reclaimStaleBeaconOwner(s); err != nil {
+		return err
+	}
+
+	if !plan.IsInDelegateChain(s.Beacon, s.OwnerKey) {
+		acquired, err := AcquireQueuedBeacon(s.Beacon, e.beacons, s.OwnerKey, s.Op, e.def.GroupVersionKind, e.def.Spec(s.Op), opStatus)
+		if err != nil || acquired == nil {
+			return err
+		}
+		s.Beacon = acquired
+	}
+
+	if delegated, err := e.handleHook(s, planv1alpha1.PendingPhaseHookLabelPrefix); err != nil {
+		return err
+	} else if delegated {
+		waitForDelegates(s, opStatus, opv1alpha1.PendingCondition)
+		return nil
+	}
+
+	if ok, err := s.Adapter.WaitForRegister(); err != nil {
+		return err
+	} else if !ok {
+		logrus.Infof("[%s] %s/%s: waiting for system-agents to connect", e.def.Name, s.Op.GetNamespace(), s.Op.GetName())
+		opv1alpha1.PendingCondition.True(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.WaitingForRegistrationReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "waiting for system-agents to connect")
+		return nil
+	}
+
+	if len(e.def.Steps) == 0 {
+		Fail(opStatus, opv1alpha1.UnknownStepReason, "operation declares no steps")
+		return nil
+	}
+
+	logrus.Infof("[%s] %s/%s: transitioning to %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), e.def.Steps[0].Name)
+
+	setPhase(opStatus, opv1alpha1.OperationPhaseInProgress)
+	e.setStep(status, e.def.Steps[0].Name)
+
+	opv1alpha1.InProgressCondition.True(opStatus)
+	opv1alpha1.InProgressCondition.Reason(opStatus, opv1alpha1.InProgressReason)
+	return nil
+}
+
+// handleInProgress re-verifies beacon ownership, marks the beacon active so the system-agents keep
+// polling, runs the InProgress phase hook and the current step's hook, and then reconciles the
+// current step. An unknown step marks the operation Failed.
+func (e *Engine[T, S, K]) handleInProgress(s *Scope[T], status *S) error {
+	opStatus := e.def.OperationStatus(status)
+
+	current := *e.def.Step(status)
+	index := -1
+	for i, step := range e.def.Steps {
+		if step.Name == current {
+			index = i
+			break
+		}
+	}
+
+	var stepPrefix string
+	if index >= 0 {
+		stepPrefix = e.def.Steps[index].HookLabelPrefix
+	}
+
+	// Stage 1 (loose): the op must appear somewhere in the ownership chain. If a step hook is
+	// active, the absence is treated as a step-scoped delegation rather than a loss.
+	if !plan.IsOwningBeaconHolder(s.Beacon, s.OwnerKey) && !plan.IsInDelegateChain(s.Beacon, s.OwnerKey) {
+		if stepPrefix != "" && planv1alpha1.HasStepHookLabel(s.Op, stepPrefix) {
+			waitForDelegates(s, opStatus, opv1alpha1.InProgressCondition)
+			return nil
+		}
+		logrus.Errorf("[%s] %s/%s: beacon reassigned, aborting", e.def.Name, s.Op.GetNamespace(), s.Op.GetName())
+		Fail(opStatus, opv1alpha1.BeaconLostReason, "beacon reassigned, aborting")
+		return nil
+	}
+
+	var err error
+	s.Beacon, err = plan.ToggleBeacon(s.Beacon, true, e.beacons)
+	if err != nil {
+		return err
+	}
+
+	if delegated, err := e.handleHook(s, planv1alpha1.InProgressPhaseHookLabelPrefix); err != nil {
+		return err
+	} else if delegated {
+		waitForDelegates(s, opStatus, opv1alpha1.InProgressCondition)
+		return nil
+	}
+
+	// Stage 2 (strict): the op must be the primary owner or the most-recent delegate to drive
+	// step work.
+	if !plan.AuthorizedForBeacon(s.Beacon, s.OwnerKey) {
+		if stepPrefix != "" && planv1alpha1.HasStepHookLabel(s.Op, stepPrefix) {
+			waitForDelegates(s, opStatus, opv1alpha1.InProgressCondition)
+			return nil
+		}
+		logrus.Errorf("[%s] %s/%s: beacon lost, aborting", e.def.Name, s.Op.GetNamespace(), s.Op.GetName())
+		Fail(opStatus, opv1alpha1.BeaconLostReason, "Beacon acquired by another controller, aborting")
+		return nil
+	}
+
+	if index < 0 {
+		names := make([]string, 0, len(e.def.Steps))
+		for _, step := range e.def.Steps {
+			names = append(names, fmt.Sprintf("%q", step.Name))
+		}
+		Fail(opStatus, opv1alpha1.UnknownStepReason, fmt.Sprintf("current step [%q] is unknown, expected one of: [%s]", current, strings.Join(names, ", ")))
+		return nil
+	}
+
+	step := e.def.Steps[index]
+
+	// The timeout is checked on a copy of the status so StepFailed sees the status of the step as it
+	// was. The copy only contributes the step start it recorded, if any.
+	timedOut := opStatus.DeepCopy()
+	if CheckStepTimeout(e.def.Spec(s.Op), timedOut, current, e.timeouts(), time.Now()) {
+		logrus.Errorf("[%s] %s/%s: step %s timed out", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), current)
+		if e.def.StepFailed != nil {
+			e.def.StepFailed(s, status, opv1alpha1.StepTimedOutReason, opv1alpha1.FailedCondition.GetMessage(timedOut))
+		} else {
+			*opStatus = *timedOut
+		}
+		return nil
+	}
+	opStatus.StepHistory = timedOut.StepHistory
+
+	// Hook check before PauseCluster so a delegate can inspect or modify the cluster's pre-pause
+	// state. PauseCluster is idempotent so re-entering after the hook clears just no-ops.
+	if step.HookLabelPrefix != "" {
+		if delegated, err := e.handleHook(s, step.HookLabelPrefix); err != nil {
+			return err
+		} else if delegated {
+			waitForDelegates(s, opStatus, opv1alpha1.InProgressCondition)
+			return nil
+		}
+	}
+
+	// Dry runs complete from Pending; this only catches a dry run that was already running its
+	// steps when dry runs stopped acquiring the beacon. It must not run a step either.
+	if IsDryRun(e.def.Spec(s.Op)) {
+		return e.preview(s, opStatus, e.def.Steps[index:])
+	}
+
+	if step.PauseCluster {
+		if err := s.Adapter.PauseCluster(true); err != nil {
+			return err
+		}
+	}
+
+	done, err := step.Reconcile(s, status)
+	if err != nil || !done || IsTerminal(opStatus.Phase) {
+		return err
+	}
+
+	for _, next := range e.def.Steps[index+1:] {
+		if next.Recovery {
+			continue
+		}
+		logrus.Infof("[%s] %s/%s: transitioning to %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), next.Name)
+		e.setStep(status, next.Name)
+		return nil
+	}
+
+	logrus.Infof("[%s] %s/%s: marking as success", e.def.Name, s.Op.GetNamespace(), s.Op.GetName())
+
+	setPhase(opStatus, opv1alpha1.OperationPhaseSucceeded)
+
+	opv1alpha1.SucceededCondition.True(opStatus)
+	opv1alpha1.SucceededCondition.Reason(opStatus, opv1alpha1.FinishedReason)
+	opv1alpha1.SucceededCondition.Message(opStatus, "Operation completed successfully")
+	return nil
+}
+
+// handleDryRun renders the plans of every step of a Pending dry run once every expected
+// system-agent has registered a machine-plan secret. A dry run never assigns a plan, so it neither
+// queues for nor acquires the beacon, runs no phase or step hooks, and leaves the operations
+// waiting for the beacon alone.
+func (e *Engine[T, S, K]) handleDryRun(s *Scope[T], opStatus *opv1alpha1.OperationStatus) error {
+	if ok, err := s.Adapter.WaitForRegister(); err != nil {
+		return err
+	} else if !ok {
+		logrus.Infof("[%s] %s/%s: waiting for system-agents to connect", e.def.Name, s.Op.GetNamespace(), s.Op.GetName())
+		opv1alpha1.PendingCondition.True(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.WaitingForRegistrationReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "waiting for system-agents to connect")
+		return nil
+	}
+
+	return e.preview(s, opStatus, e.def.Steps)
+}
+
+// preview renders the plans of the given steps, records them in the plan preview ConfigMap of the
+// operation and marks the dry run as DryRunCompleted. The cluster is never paused by a dry run.
+func (e *Engine[T, S, K]) preview(s *Scope[T], opStatus *opv1alpha1.OperationStatus, steps []Step[T, S, K]) error {
+	preview := &PlanPreview{}
+	for _, step := range steps {
+		if step.Preview == nil || step.Recovery {
+			continue
+		}
+		msg, err := step.Preview(s, preview)
+		if err != nil {
+			return err
+		}
+		if msg != "" {
+			logrus.Errorf("[%s] %s/%s: marking dry run as failed in step %s: %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), step.Name, msg)
+			Fail(opStatus, opv1alpha1.PlanFailedReason, fmt.Sprintf("dry run failed in step %s: %s", step.Name, msg))
+			return nil
+		}
+	}
+
+	name, err := WritePlanPreview(e.configMaps, s.Op, e.def.GroupVersionKind, preview)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("[%s] %s/%s: dry run rendered %d plans into configmap %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), len(preview.Plans), name)
+	CompleteDryRun(opStatus, name)
+	return nil
+}
+
+// handleTerminal runs the phase hook of a terminal phase, then unpauses the cluster and releases
+// the beacon if it is held by the operation, so the next operation in line can acquire it. A
+// Canceled operation holding the beacon first cancels the plans it assigned that are still in
+// flight on the cluster's nodes. On the owner path of a Succeeded operation, the parent cluster
+// object is enqueued so any post-operation reconciliation runs promptly.
+func (e *Engine[T, S, K]) handleTerminal(s *Scope[T], opStatus *opv1alpha1.OperationStatus, prefix string, cond condition.Cond) error {
+	if delegated, err := e.handleHook(s, prefix); err != nil {
+		return err
+	} else if delegated {
+		waitForDelegates(s, opStatus, cond)
+		return nil
+	}
+
+	owning := plan.IsOwningBeaconHolder(s.Beacon, s.OwnerKey)
+	holding := owning || plan.IsInDelegateChain(s.Beacon, s.OwnerKey)
+	if opStatus.Phase == opv1alpha1.OperationPhaseCanceled {
+		if err := CancelPlans(e.store, e.secretCache, s.Beacon, s.OwnerKey, s.Cluster, s.Namespace); err != nil {
+			return err
+		}
+	}
+
+	if e.pauses() {
+		if err := s.Adapter.PauseCluster(false); err != nil {
+			return err
+		}
+	}
+
+	if holding {
+		if err := plan.ReleaseBeacon(s.Beacon, e.beacons, s.OwnerKey); err != nil {
+			return err
+		}
+	}
+	if owning && opStatus.Phase == opv1alpha1.OperationPhaseSucceeded {
+		gvk := schema.FromAPIVersionAndKind(s.Cluster.GetAPIVersion(), s.Cluster.GetKind())
+		_ = e.dynamic.Enqueue(gvk, s.Cluster.GetNamespace(), s.Cluster.GetName())
+	}
+
+	return nil
+}
+
+// handleDryRunCompleted releases the beacon if the dry run still holds it, which only happens for a
+// dry run that started running its steps before dry runs stopped acquiring the beacon. No phase
+// hook runs and the cluster is neither unpaused nor enqueued, as a dry run changes nothing.
+func (e *Engine[T, S, K]) handleDryRunCompleted(s *Scope[T]) error {
+	if !plan.IsOwningBeaconHolder(s.Beacon, s.OwnerKey) && !plan.IsInDelegateChain(s.Beacon, s.OwnerKey) {
+		return nil
+	}
+	return plan.ReleaseBeacon(s.Beacon, e.beacons, s.OwnerKey)
+}
+
+// handleHook delegates the beacon to the value of the first label on the operation carrying the
+// given prefix. Returns true when a delegate was found, in which case the caller must wait.
+func (e *Engine[T, S, K]) handleHook(s *Scope[T], prefix string) (bool, error) {
+	for k, delegate := range s.Op.GetLabels() {
+		if !strings.HasPrefix(k, prefix) || delegate == "" {
+			continue
+		}
+
+		logrus.Tracef("[%s] %s/%s: delegating ownership of beacon to %s on behalf of %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(),
+			delegate, strings.TrimPrefix(k, prefix))
+
+		if plan.IsInDelegateChain(s.Beacon, delegate) {
+			return true, nil
+		}
+
+		beacon, err := plan.PushDelegate(s.Beacon, delegate, e.beacons)
+		if err != nil {
+			return true, err
+		}
+		s.Beacon = beacon
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// reclaimStaleBeaconOwner clears the beacon owner when it is another operation of the same type
+// that no longer exists or has already reached a terminal phase, e.g. because it was deleted
+// before it could release the beacon. Owners of other operation types are left untouched.
+func (e *Engine[T, S, K]) reclaimStaleBeaconOwner(s *Scope[T]) error {
+	if s.Beacon == nil {
+		return nil
+	}
+
+	owner := s.Beacon.Status.Owner
+	ref, ok := strings.CutPrefix(owner, e.def.Name+"/")
+	if !ok || owner == s.OwnerKey {
+		return nil
+	}
+
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		namespace, name = "", ref
+	}
+
+	current, err := e.operations.Get(namespace, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && !IsTerminal(e.def.OperationStatus(e.def.Status(current)).Phase) {
+		return nil
+	}
+
+	logrus.Infof("[%s] %s/%s: reclaiming beacon from stale owner %s", e.def.Name, s.Op.GetNamespace(), s.Op.GetName(), owner)
+
+	beacon := s.Beacon.DeepCopy()
+	beacon.Status.Active = false
+	beacon.Status.Owner = ""
+	beacon.Status.Delegates = nil
+
+	beacon, err = e.beacons.UpdateStatus(beacon)
+	if err != nil {
+		return err
+	}
+	s.Beacon = beacon
+	return nil
+}
+
+// updateStatus updates the ObservedGeneration and the conditions that are not owned by the
+// current phase handler.
+func (e *Engine[T, S, K]) updateStatus(op T, status *S) {
+	opStatus := e.def.OperationStatus(status)
+
+	opStatus.ObservedGeneration = op.GetGeneration()
+	if e.def.Spec(op).Paused {
+		opv1alpha1.PausedCondition.True(opStatus)
+		opv1alpha1.PausedCondition.Reason(opStatus, opv1alpha1.PausedReason)
+		opv1alpha1.PausedCondition.Message(opStatus, "Operation is paused")
+	} else {
+		opv1alpha1.PausedCondition.False(opStatus)
+		opv1alpha1.PausedCondition.Reason(opStatus, opv1alpha1.NotPausedReason)
+		opv1alpha1.PausedCondition.Message(opStatus, "")
+	}
+
+	switch opStatus.Phase {
+	case opv1alpha1.OperationPhasePending:
+		opv1alpha1.PendingCondition.True(opStatus)
+	case opv1alpha1.OperationPhaseInProgress:
+		opv1alpha1.PendingCondition.False(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.InProgressReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "Operation now in progress")
+	case opv1alpha1.OperationPhaseSucceeded:
+		opv1alpha1.PendingCondition.False(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.FinishedReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "Operation completed successfully")
+		opv1alpha1.InProgressCondition.False(opStatus)
+		opv1alpha1.InProgressCondition.Reason(opStatus, opv1alpha1.FinishedReason)
+		opv1alpha1.InProgressCondition.Message(opStatus, "Operation completed successfully")
+		opv1alpha1.FailedCondition.False(opStatus)
+		opv1alpha1.FailedCondition.Reason(opStatus, opv1alpha1.NotFailedReason)
+		opv1alpha1.FailedCondition.Message(opStatus, "Operation completed successfully")
+	case opv1alpha1.OperationPhaseFailed:
+		opv1alpha1.PendingCondition.False(opStatus)
+		opv1alpha1.PendingCondition.Reason(opStatus, opv1alpha1.FinishedReason)
+		opv1alpha1.PendingCondition.Message(opStatus, "Operation failed")
+		opv1alpha1.InProgressCondition.False(opStatus)
+		opv1alpha1.InProgressCondition.Reason(opStatus, opv1alpha1.FinishedReason)
+		opv1alpha1.InProgressCondition.Message(opStatus, "Operation failed")
+		opv1alpha1.SucceededCondition.False(opStatus)
+		opv1alpha1.SucceededCondition.Reason(opStatus, opv1alpha1.NotSuccessfulReason)
+		opv1alpha1.SucceededCondition.Message(opStatus, "Operation failed")
+	}
+}
+
+func (e *Engine[T, S, K]) setStep(status *S, step K) {
+	if current := e.def.Step(status); *current != step {
+		*current = step
+		opStatus := e.def.OperationStatus(status)
+		opStatus.LastUpdated = metav1.Now()
+		opStatus.RecordStep(string(step), opStatus.LastUpdated)
+	}
+}
+
+// pauses reports whether any step of the definition pauses the cluster, in which case terminal
+// phases unpause it. Operations that never pause leave the cluster's pause state alone.
+func (e *Engine[T, S, K]) pauses() bool {
+	for _, step := range e.def.Steps {
+		if step.PauseCluster {
+			return true
+		}
+	}
+	return false
+}
+
+// timeouts returns the default timeout of every step declaring one.
+func (e *Engine[T, S, K]) timeouts() map[K]time.Duration {
+	timeouts := map[K]time.Duration{}
+	for _, step := range e.def.Steps {
+		if step.Timeout > 0 {
+			timeouts[step.Name] = step.Timeout
+		}
+	}
+	return timeouts
+}
+
+// Fail marks the operation as Failed with the given reason and message.
+func Fail(status *opv1alpha1.OperationStatus, reason, message string) {
+	setPhase(status, opv1alpha1.OperationPhaseFailed)
+
+	opv1alpha1.FailedCondition.True(status)
+	opv1alpha1.FailedCondition.Reason(status, reason)
+	opv1alpha1.FailedCondition.Message(status, message)
+}
+
+// Cancel marks the operation as Canceled with the given reason and message. Operations are only
+// canceled when they are given up on before they start, e.g. for an invalid maintenance window;
+// steps whose checks fail Fail instead.
+func Cancel(status *opv1alpha1.OperationStatus, reason, message string) {
+	setPhase(status, opv1alpha1.OperationPhaseCanceled)
+
+	opv1alpha1.CanceledCondition.True(status)
+	opv1alpha1.CanceledCondition.Reason(status, reason)
+	opv1alpha1.CanceledCondition.Message(status, message)
+}
+
+// Wait reports on the InProgress condition why the current step is waiting.
+func Wait(status *opv1alpha1.OperationStatus, reason, message string) {
+	opv1alpha1.InProgressCondition.True(status)
+	opv1alpha1.InProgressCondition.Reason(status, reason)
+	opv1alpha1.InProgressCondition.Message(status, message)
+}
+
+func waitForDelegates[T Object](s *Scope[T], status *opv1alpha1.OperationStatus, cond condition.Cond) {
+	cond.True(status)
+	cond.Reason(status, opv1alpha1.WaitingForDelegateReason)
+	cond.Message(status, fmt.Sprintf("Waiting for delegates to finish: %v", opv1alpha1.WaitingForDelegateMessage(s.Beacon)))
+}
+
+func setPhase(status *opv1alpha1.OperationStatus, phase opv1alpha1.OperationPhase) {
+	if status.Phase == phase {
+		return
+	}
+	status.Phase = phase
+	status.LastUpdated = metav1.Now()
+	if IsTerminal(phase) {
+		status.FinishStep(status.LastUpdated)
+	}
+}
+
+// isNilObject reports whether op is nil, including a typed nil pointer.
+func isNilObject(op Object) bool {
+	if op == nil {
+		return true
+	}
+	v := reflect.ValueOf(op)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,606 @@
+package operations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The engine tests drive CertificateRotation objects through a two-step definition that only
+// records which steps were reconciled.
+
+type engineOp = opv1alpha1.CertificateRotation
+type engineStatus = opv1alpha1.CertificateRotationStatus
+type engineStep = opv1alpha1.CertificateRotationStep
+
+const (
+	engineStepOne engineStep = "One"
+	engineStepTwo engineStep = "Two"
+
+	engineStepOneHookLabelPrefix = "one.step.hook.operation.cattle.io/"
+)
+
+// engineAdapter stubs the Adapter methods used by the Engine itself. Steps in these tests never
+// call into the adapter.
+type engineAdapter struct {
+	Adapter
+
+	registered  bool
+	registerErr error
+	pauses      []bool
+}
+
+func (a *engineAdapter) BeaconRef() (string, string) { return "fleet-default", "test" }
+func (a *engineAdapter) ClusterObject() (*unstructured.Unstructured, error) {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("provisioning.cattle.io/v1")
+	cluster.SetKind("Cluster")
+	cluster.SetNamespace("fleet-default")
+	cluster.SetName("test")
+	return cluster, nil
+}
+func (a *engineAdapter) WaitForRegister() (bool, error) { return a.registered, a.registerErr }
+func (a *engineAdapter) PauseCluster(pause bool) error {
+	a.pauses = append(a.pauses, pause)
+	return nil
+}
+
+// engineBeacons is an in-memory beacon client holding a single beacon.
+type engineBeacons struct {
+	plancontrollers.BeaconClient
+
+	beacon *planv1alpha1.Beacon
+}
+
+func (f *engineBeacons) Get(_, _ string, _ metav1.GetOptions) (*planv1alpha1.Beacon, error) {
+	return f.beacon.DeepCopy(), nil
+}
+
+func (f *engineBeacons) Update(b *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	f.beacon = b.DeepCopy()
+	return b, nil
+}
+
+func (f *engineBeacons) UpdateStatus(b *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	f.beacon = b.DeepCopy()
+	return b, nil
+}
+
+// engineOperations records deletes and enqueues, and resolves Get from a fixed set of objects.
+type engineOperations struct {
+	objects  map[string]*engineOp
+	deleted  []string
+	enqueued int
+}
+
+func (f *engineOperations) Get(namespace, name string, _ metav1.GetOptions) (*engineOp, error) {
+	if op, ok := f.objects[namespace+"/"+name]; ok {
+		return op, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+}
+
+func (f *engineOperations) Delete(namespace, name string, _ *metav1.DeleteOptions) error {
+	f.deleted = append(f.deleted, namespace+"/"+name)
+	return nil
+}
+
+func (f *engineOperations) EnqueueAfter(_, _ string, _ time.Duration) {
+	f.enqueued++
+}
+
+type engineDynamic struct {
+	enqueued []string
+}
+
+func (f *engineDynamic) Get(_ schema.GroupVersionKind, namespace, name string) (runtime.Object, error) {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetAPIVersion("provisioning.cattle.io/v1")
+	cluster.SetKind("Cluster")
+	cluster.SetNamespace(namespace)
+	cluster.SetName(name)
+	return cluster, nil
+}
+
+func (f *engineDynamic) Enqueue(gvk schema.GroupVersionKind, namespace, name string) error {
+	f.enqueued = append(f.enqueued, gvk.String()+"/"+namespace+"/"+name)
+	return nil
+}
+
+type engineFixture struct {
+	engine     *Engine[*engineOp, engineStatus, engineStep]
+	adapter    *engineAdapter
+	beacons    *engineBeacons
+	operations *engineOperations
+	dynamic    *engineDynamic
+	configMaps *previewConfigMaps
+	secrets    *cancelSecrets
+
+	// reconciled records the steps reconciled, in order.
+	reconciled []engineStep
+	// results maps each step to the result its StepFunc returns.
+	results map[engineStep]bool
+}
+
+func newEngineFixture(owner string) *engineFixture {
+	f := &engineFixture{
+		adapter: &engineAdapter{registered: true},
+		beacons: &engineBeacons{beacon: &planv1alpha1.Beacon{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+			Status:     planv1alpha1.BeaconStatus{Owner: owner},
+		}},
+		operations: &engineOperations{objects: map[string]*engineOp{}},
+		dynamic:    &engineDynamic{},
+		configMaps: newPreviewConfigMaps(),
+		secrets:    &cancelSecrets{},
+		results:    map[engineStep]bool{engineStepOne: true, engineStepTwo: true},
+	}
+
+	reconcile := func(step engineStep) StepFunc[*engineOp, engineStatus] {
+		return func(_ *Scope[*engineOp], _ *engineStatus) (bool, error) {
+			f.reconciled = append(f.reconciled, step)
+			return f.results[step], nil
+		}
+	}
+
+	f.engine = &Engine[*engineOp, engineStatus, engineStep]{
+		def: Definition[*engineOp, engineStatus, engineStep]{
+			Name: "test-operation",
+			Steps: []Step[*engineOp, engineStatus, engineStep]{
+				{Name: engineStepOne, HookLabelPrefix: engineStepOneHookLabelPrefix, Reconcile: reconcile(engineStepOne)},
+				{Name: engineStepTwo, PauseCluster: true, Reconcile: reconcile(engineStepTwo)},
+			},
+			Spec:            func(op *engineOp) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+			Status:          func(op *engineOp) *engineStatus { return &op.Status },
+			OperationStatus: func(status *engineStatus) *opv1alpha1.OperationStatus { return &status.OperationStatus },
+			Step:            func(status *engineStatus) *engineStep { return &status.Step },
+		},
+		operations:  f.operations,
+		beacons:     f.beacons,
+		dynamic:     f.dynamic,
+		configMaps:  f.configMaps,
+		secretCache: &cancelSecretCache{secrets: f.secrets},
+		store:       plan.NewStore(f.secrets),
+		newAdapter:  func(*unstructured.Unstructured) (Adapter, error) { return f.adapter, nil },
+	}
+	return f
+}
+
+func newEngineOp(name string) *engineOp {
+	return &engineOp{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "fleet-default", UID: "op-uid"},
+		Spec: opv1alpha1.CertificateRotationSpec{
+			OperationSpec: opv1alpha1.OperationSpec{
+				ClusterRef: &corev1.ObjectReference{APIVersion: "provisioning.cattle.io/v1", Kind: "Cluster", Namespace: "fleet-default", Name: "test"},
+				TTL:        -1,
+			},
+		},
+	}
+}
+
+func engineOwnerKey(op *engineOp) string {
+	return plan.ControllerOwnerKey(op, "test-operation")
+}
+
+func TestEngine_PendingAcquiresBeaconAndStartsFirstStep(t *testing.T) {
+	t.Parallel()
+
+	f := newEngineFixture("")
+	op := newEngineOp("op")
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, got.Phase)
+	assert.Equal(t, engineStepOne, got.Step)
+	assert.Equal(t, engineOwnerKey(op), f.beacons.beacon.Status.Owner)
+	assert.Empty(t, f.reconciled, "steps must not run before the next reconcile")
+}
+
+func TestEngine_PendingWaitsForRegistration(t *testing.T) {
+	t.Parallel()
+
+	f := newEngineFixture("")
+	f.adapter.registered = false
+	op := newEngineOp("op")
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhasePending, got.Phase)
+	assert.Equal(t, opv1alpha1.WaitingForRegistrationReason, opv1alpha1.PendingCondition.GetReason(&got))
+}
+
+func TestEngine_PendingWaitForRegisterErrorBubbles(t *testing.T) {
+	t.Parallel()
+
+	sentinel := errors.New("kaboom")
+	f := newEngineFixture("")
+	f.adapter.registerErr = sentinel
+	op := newEngineOp("op")
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.ErrorIs(t, err, sentinel)
+}
+
+func TestEngine_PendingReclaimsStaleOwner(t *testing.T) {
+	t.Parallel()
+
+	// The previous operation of the same type was deleted without releasing the beacon.
+	f := newEngineFixture(engineOwnerKey(newEngineOp("gone")))
+	op := newEngineOp("op")
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, got.Phase)
+	assert.Equal(t, engineOwnerKey(op), f.beacons.beacon.Status.Owner)
+}
+
+func TestEngine_PendingLeavesLiveOwner(t *testing.T) {
+	t.Parallel()
+
+	other := newEngineOp("other")
+	other.Status.Phase = opv1alpha1.OperationPhaseInProgress
+
+	f := newEngineFixture(engineOwnerKey(other))
+	f.operations.objects["fleet-default/other"] = other
+	op := newEngineOp("op")
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhasePending, got.Phase)
+	assert.Equal(t, opv1alpha1.WaitingForBeaconReason, opv1alpha1.PendingCondition.GetReason(&got))
+	assert.Equal(t, engineOwnerKey(other), f.beacons.beacon.Status.Owner)
+}
+
+func TestEngine_RunsStepsInOrderThenSucceeds(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, engineStepTwo, got.Step)
+	assert.Empty(t, f.adapter.pauses, "step one does not pause the cluster")
+
+	op.Status = got
+	got, err = f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, got.Phase)
+	assert.Equal(t, opv1alpha1.FinishedReason, opv1alpha1.SucceededCondition.GetReason(&got))
+	assert.Equal(t, []engineStep{engineStepOne, engineStepTwo}, f.reconciled)
+	assert.Equal(t, []bool{true}, f.adapter.pauses)
+	assert.True(t, f.beacons.beacon.Status.Active, "the beacon must be active while steps run")
+}
+
+func TestEngine_StepWaitingStaysOnStep(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.results[engineStepOne] = false
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, got.Phase)
+	assert.Equal(t, engineStepOne, got.Step)
+}
+
+func TestEngine_StepFailureStopsOperation(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[0].Reconcile = func(_ *Scope[*engineOp], status *engineStatus) (bool, error) {
+		Fail(&status.OperationStatus, opv1alpha1.PlanFailedReason, "boom")
+		return true, nil
+	}
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, engineStepOne, got.Step, "a failed step must not advance")
+	assert.Equal(t, "boom", opv1alpha1.FailedCondition.GetMessage(&got))
+}
+
+func TestEngine_RecoveryStepIsNotAdvancedInto(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[1].Recovery = true
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseSucceeded, got.Phase)
+	assert.Equal(t, engineStepOne, got.Step)
+	assert.Equal(t, []engineStep{engineStepOne}, f.reconciled)
+}
+
+func TestEngine_TimedOutStepFails(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[0].Timeout = time.Hour
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+	op.Status.RecordStep(string(engineStepOne), metav1.NewTime(time.Now().Add(-2*time.Hour)))
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, opv1alpha1.StepTimedOutReason, opv1alpha1.FailedCondition.GetReason(&got))
+	assert.Empty(t, f.reconciled, "a timed out step must not run")
+}
+
+func TestEngine_TimedOutStepCallsStepFailed(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[0].Timeout = time.Hour
+	f.engine.def.Steps[1].Recovery = true
+	f.engine.def.StepFailed = func(_ *Scope[*engineOp], status *engineStatus, reason, message string) {
+		assert.Equal(t, opv1alpha1.StepTimedOutReason, reason)
+		assert.Contains(t, message, "did not complete within 1h")
+		f.engine.setStep(status, engineStepTwo)
+	}
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+	op.Status.RecordStep(string(engineStepOne), metav1.NewTime(time.Now().Add(-2*time.Hour)))
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseInProgress, got.Phase)
+	assert.Equal(t, engineStepTwo, got.Step)
+	assert.False(t, opv1alpha1.FailedCondition.IsTrue(&got), "the operation must only fail through StepFailed")
+}
+
+func TestEngine_StepHookDelegatesBeacon(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Labels = map[string]string{engineStepOneHookLabelPrefix + "inspect": "delegate"}
+	f := newEngineFixture(engineOwnerKey(op))
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, f.reconciled, "the step must not run while delegated")
+	assert.Equal(t, []string{"delegate"}, f.beacons.beacon.Status.Delegates)
+	assert.Equal(t, opv1alpha1.WaitingForDelegateReason, opv1alpha1.InProgressCondition.GetReason(&got))
+}
+
+func TestEngine_UnknownStepFails(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = "Whatever"
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, opv1alpha1.UnknownStepReason, opv1alpha1.FailedCondition.GetReason(&got))
+}
+
+func TestEngine_BeaconLostFails(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture("someone-else")
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, opv1alpha1.BeaconLostReason, opv1alpha1.FailedCondition.GetReason(&got))
+}
+
+func TestEngine_TerminalUnpausesAndReleasesBeacon(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	op.Status.Phase = opv1alpha1.OperationPhaseSucceeded
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, f.adapter.pauses)
+	assert.Empty(t, f.beacons.beacon.Status.Owner)
+	assert.Equal(t, []string{"provisioning.cattle.io/v1, Kind=Cluster/fleet-default/test"}, f.dynamic.enqueued)
+}
+
+func TestEngine_TerminalWithoutPauseStepLeavesClusterAlone(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[1].PauseCluster = false
+	op.Status.Phase = opv1alpha1.OperationPhaseFailed
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, f.adapter.pauses, "operations that never pause must not unpause the cluster")
+	assert.Empty(t, f.beacons.beacon.Status.Owner)
+	assert.Empty(t, f.dynamic.enqueued, "only Succeeded operations enqueue the cluster")
+}
+
+func TestEngine_TerminalNotHoldingLeavesBeacon(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture("someone-else")
+	op.Status.Phase = opv1alpha1.OperationPhaseSucceeded
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, "someone-else", f.beacons.beacon.Status.Owner)
+	assert.Empty(t, f.dynamic.enqueued, "non-owners must not enqueue the cluster")
+}
+
+func TestEngine_CanceledCancelsAssignedPlans(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.secrets.items = []*corev1.Secret{
+		newCancelSecret("in-progress", plan.PlanStateInProgress, engineOwnerKey(op)),
+		newCancelSecret("succeeded", plan.PlanStateSucceeded, engineOwnerKey(op)),
+		newCancelSecret("other-operation", plan.PlanStateInProgress, "someone-else"),
+	}
+	op.Status.Phase = opv1alpha1.OperationPhaseCanceled
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"in-progress"}, f.secrets.updated)
+	assert.Equal(t, []bool{false}, f.adapter.pauses)
+	assert.Empty(t, f.beacons.beacon.Status.Owner)
+}
+
+func TestEngine_CanceledWithoutBeaconLeavesPlans(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture("someone-else")
+	f.secrets.items = []*corev1.Secret{newCancelSecret("in-progress", plan.PlanStateInProgress, engineOwnerKey(op))}
+	op.Status.Phase = opv1alpha1.OperationPhaseCanceled
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, f.secrets.updated)
+	assert.Equal(t, "someone-else", f.beacons.beacon.Status.Owner)
+}
+
+func TestEngine_ExpiredTerminalOperationIsDeleted(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.TTL = 0
+	f := newEngineFixture("")
+	op.Status.Phase = opv1alpha1.OperationPhaseFailed
+	op.Status.LastUpdated = metav1.NewTime(time.Now().Add(-time.Minute))
+	f.engine.updateStatus(op, &op.Status)
+
+	_, err := f.engine.OnChange(op, *op.Status.DeepCopy())
+	assert.ErrorIs(t, err, generic.ErrSkip)
+	assert.Equal(t, []string{"fleet-default/op"}, f.operations.deleted)
+}
+
+func TestEngine_PausedOperationIsSkipped(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.Paused = true
+	f := newEngineFixture("")
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, string(got.Phase))
+	assert.Equal(t, "True", opv1alpha1.PausedCondition.GetStatus(&got))
+	assert.Empty(t, f.beacons.beacon.Status.Owner)
+}
+
+func TestEngine_DryRunRendersEveryStepFromPending(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.DryRun = true
+	f := newEngineFixture("")
+	f.engine.def.GroupVersionKind = opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation")
+	for i, name := range []string{"node-0", "node-1"} {
+		f.engine.def.Steps[i].Preview = func(_ *Scope[*engineOp], preview *PlanPreview) (string, error) {
+			preview.Add(string(f.engine.def.Steps[i].Name), newPreviewSecret(name), &plan.Plan{}, 1, -1)
+			return "", nil
+		}
+	}
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseDryRunCompleted, got.Phase)
+	assert.Equal(t, "False", opv1alpha1.SucceededCondition.GetStatus(&got))
+	assert.Equal(t, opv1alpha1.DryRunCompletedReason, opv1alpha1.SucceededCondition.GetReason(&got))
+	assert.Equal(t, "op-plan-preview", got.PlanPreview)
+	assert.Empty(t, f.reconciled, "a dry run must not run any step")
+	assert.Empty(t, f.adapter.pauses, "a dry run must not pause the cluster")
+	assert.Empty(t, f.beacons.beacon.Status.Owner, "a dry run must not acquire the beacon")
+	assert.Empty(t, f.beacons.beacon.Status.Queue, "a dry run must not queue for the beacon")
+	data := f.configMaps.objects["fleet-default/op-plan-preview"].Data[PlanPreviewDataKey]
+	assert.Contains(t, data, "fleet-default/node-0")
+	assert.Contains(t, data, "fleet-default/node-1")
+}
+
+func TestEngine_DryRunWaitsForRegistration(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.DryRun = true
+	f := newEngineFixture("")
+	f.adapter.registered = false
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhasePending, got.Phase)
+	assert.Equal(t, opv1alpha1.WaitingForRegistrationReason, opv1alpha1.PendingCondition.GetReason(&got))
+	assert.Empty(t, f.configMaps.objects)
+}
+
+func TestEngine_DryRunCompletedSkipsSucceededHandling(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.DryRun = true
+	op.Labels = map[string]string{planv1alpha1.SucceededPhaseHookLabelPrefix + "x": "delegate"}
+	f := newEngineFixture(engineOwnerKey(op))
+	op.Status.Phase = opv1alpha1.OperationPhaseDryRunCompleted
+
+	_, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, f.beacons.beacon.Status.Owner, "a dry run still holding the beacon must release it")
+	assert.Empty(t, f.beacons.beacon.Status.Delegates, "the Succeeded hook must not run")
+	assert.Empty(t, f.adapter.pauses)
+	assert.Empty(t, f.dynamic.enqueued, "a dry run must not enqueue the cluster")
+}
+
+func TestEngine_DryRunPreviewMessageFailsOperation(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	op.Spec.DryRun = true
+	f := newEngineFixture(engineOwnerKey(op))
+	f.engine.def.Steps[1].Preview = func(_ *Scope[*engineOp], _ *PlanPreview) (string, error) {
+		return "no eligible leader", nil
+	}
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepTwo
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, "dry run failed in step Two: no eligible leader", opv1alpha1.FailedCondition.GetMessage(&got))
+	assert.Empty(t, f.configMaps.objects)
+}
@@ -183,6 +183,29 @@ func TestIsControlPlane(t *testing.T) {
 	}
 }
 
+func TestIsWorker(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		secret *corev1.Secret
+		expect bool
+	}{
+		{"worker=true", newSecret(map[string]string{capr.WorkerRoleLabel: "true"}), true},
+		{"worker=false", newSecret(map[string]string{capr.WorkerRoleLabel: "false"}), false},
+		{"worker missing", newSecret(map[string]string{}), false},
+		{"nil labels", &corev1.Secret{}, false},
+		{"nil secret", nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := IsWorker(tc.secret)
+			assert.Equal(t, tc.expect, got, "IsWorker mismatch")
+		})
+	}
+}
+
 func TestIsWindows(t *testing.T) {
 	t.Parallel()
 
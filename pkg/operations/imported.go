@@ -7,6 +7,7 @@
 	mgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/capr"
 	provcluster "github.com/rancher/rancher/pkg/controllers/provisioningv2/cluster"
+	"github.com/rancher/rancher/pkg/image"
 	"github.com/rancher/rancher/pkg/plan"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -269,6 +270,20 @@ func (a *ImportedAdapter) ServerUnit() string {
 	return "k3s"
 }
 
+// AgentUnit returns the systemd unit name for a distro agent (worker-only) node.
+func (a *ImportedAdapter) AgentUnit() string {
+	if a.cluster.Status.Provider == "rke2" {
+		return "rke2-agent"
+	}
+	return "k3s-agent"
+}
+
+// InstallerImage returns the system-agent installer image for the given version, resolved against the
+// registry configured on the management cluster.
+func (a *ImportedAdapter) InstallerImage(kubernetesVersion string) string {
+	return image.ResolveWithCluster(installerImage(kubernetesVersion), a.cluster)
+}
+
 func (a *ImportedAdapter) DistroDataDirectory(_ *corev1.Secret) string {
 	if a.cluster.Status.Provider == "rke2" {
 		return "/var/lib/rancher/rke2"
@@ -322,6 +337,7 @@ func (a *ImportedAdapter) RenderProbes(secret *corev1.Secret, supervisor bool) (
 		if runtime == capr.RuntimeK3S {
 			port = 6443
 		}
+		supervisorProbe = *supervisorProbe.DeepCopy()
 		supervisorProbe.HTTPGetAction.URL = fmt.Sprintf(supervisorProbe.HTTPGetAction.URL, loopbackAddress, port, runtime)
 		probes[SupervisorProbeName] = supervisorProbe
 	}
@@ -371,7 +387,19 @@ func (a *ImportedAdapter) isSuitableLeader(s *corev1.Secret) (bool, error) {
 // otherwise a new leader is elected and the annotation written with retry-on-conflict.
 // Returns nil, nil when no suitable candidate exists yet.
 func (a *ImportedAdapter) FindOrElectLeader(operation string, filter Filter) (*corev1.Secret, error) {
-	candidates, err := plan.NewCollector(a.clients.Core.Secret(), a.cluster, a.cluster.Name).
+	return a.findOrElectLeader(operation, filter, true)
+}
+
+// PreviewLeader returns the machine-plan secret FindOrElectLeader would return for the given
+// operation without writing or clearing the leader annotation.
+func (a *ImportedAdapter) PreviewLeader(operation string, filter Filter) (*corev1.Secret, error) {
+	return a.findOrElectLeader(operation, filter, false)
+}
+
+// findOrElectLeader implements FindOrElectLeader and PreviewLeader. The leader annotation is
+// only written or cleared when elect is true.
+func (a *ImportedAdapter) findOrElectLeader(operation string, filter Filter, elect bool) (*corev1.Secret, error) {
+	candidates, err := plan.NewCachedCollector(a.clients.Core.Secret().Cache(), a.cluster, a.cluster.Name).
 		WithFilter(plan.FilterFunc(filter)).
 		WithSorter(plan.DefaultSorter()).
 		Collect()
@@ -417,18 +445,21 @@ func (a *ImportedAdapter) FindOrElectLeader(operation string, filter Filter) (*c
 		if markedReady {
 			return marked, nil
 		}
-		logrus.Warnf("[operations] %s: elected leader %s is no longer suitable, re-electing", a.cluster.Name, marked.Name)
-		if err := a.clearLeaderAnnotation(marked, operation); err != nil {
-			return nil, err
+		if elect {
+			logrus.Warnf("[operations] %s: elected leader %s is no longer suitable, re-electing", a.cluster.Name, marked.Name)
+			if err := a.clearLeaderAnnotation(marked, operation); err != nil {
+				return nil, err
+			}
 		}
 	}
-	if initCandidate != nil {
-		return a.markLeader(initCandidate, operation)
+	leader := initCandidate
+	if leader == nil {
+		leader = fallback
 	}
-	if fallback != nil {
-		return a.markLeader(fallback, operation)
+	if leader == nil || !elect {
+		return leader, nil
 	}
-	return nil, nil
+	return a.markLeader(leader, operation)
 }
 
 func (a *ImportedAdapter) KubectlPath(secret *corev1.Secret) string {
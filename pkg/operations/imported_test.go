@@ -6,6 +6,7 @@
 	mgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/capr"
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/wrangler"
 	ctrlfake "github.com/rancher/wrangler/v3/pkg/generic/fake"
 	"github.com/stretchr/testify/assert"
@@ -73,6 +74,48 @@ func TestImportedAdapter_ServerUnit(t *testing.T) {
 	}
 }
 
+func TestImportedAdapter_AgentUnit(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		provider string
+		want     string
+	}{
+		{"rke2 provider", "rke2", "rke2-agent"},
+		{"k3s provider", "k3s", "k3s-agent"},
+		{"empty provider defaults to k3s", "", "k3s-agent"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &ImportedAdapter{
+				cluster: &mgmtv3.Cluster{
+					Status: mgmtv3.ClusterStatus{
+						Provider: tc.provider,
+					},
+				},
+			}
+			got := a.AgentUnit()
+			assert.Equal(t, tc.want, got, "AgentUnit mismatch for provider=%q", tc.provider)
+		})
+	}
+}
+
+func TestImportedAdapter_InstallerImage(t *testing.T) {
+	t.Parallel()
+
+	a := &ImportedAdapter{
+		cluster: &mgmtv3.Cluster{
+			Status: mgmtv3.ClusterStatus{
+				Provider: "rke2",
+			},
+		},
+	}
+
+	assert.Equal(t, settings.SystemAgentInstallerImage.Get()+"rke2:v1.29.1-rke2r1", a.InstallerImage("v1.29.1+rke2r1"))
+}
+
 // --- WaitForRegister ------------------------------------------------------------------------
 
 func newImportedMachinePlanSecret(name, machineName string) *corev1.Secret {
@@ -0,0 +1,192 @@
+package operations
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/metrics"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/rancher/wrangler/v3/pkg/kv"
+)
+
+// ObserveOperations registers the handlers keeping the operation lifecycle metrics of every kind of
+// operation: the number of operations in each phase, and the time spent in each step.
+func ObserveOperations(ctx context.Context, clients *wrangler.CAPIContext) {
+	observeOperations(ctx, clients.Operation.CertificateRotation(), "CertificateRotation", func(op *opv1alpha1.CertificateRotation) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	observeOperations(ctx, clients.Operation.CustomOperation(), "CustomOperation", func(op *opv1alpha1.CustomOperation) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	observeOperations(ctx, clients.Operation.EncryptionKeyRotation(), "EncryptionKeyRotation", func(op *opv1alpha1.EncryptionKeyRotation) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	observeOperations(ctx, clients.Operation.ETCDSnapshotRestore(), "ETCDSnapshotRestore", func(op *opv1alpha1.ETCDSnapshotRestore) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	observeOperations(ctx, clients.Operation.ETCDSnapshotSave(), "ETCDSnapshotSave", func(op *opv1alpha1.ETCDSnapshotSave) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	observeOperations(ctx, clients.Operation.KubernetesUpgrade(), "KubernetesUpgrade", func(op *opv1alpha1.KubernetesUpgrade) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+}
+
+func observeOperations[T Object](ctx context.Context, operations OperationWatcher[T], kind string, status func(op T) *opv1alpha1.OperationStatus) {
+	h := newOperationMetrics[T](kind, status)
+	operations.OnChange(ctx, "operation-metrics-"+kind, h.OnChange)
+}
+
+// operationMetrics tracks the last phase and the last finished step seen per operation, so every
+// phase transition and step is only counted once.
+type operationMetrics[T Object] struct {
+	kind   string
+	status func(op T) *opv1alpha1.OperationStatus
+
+	// started is when the handler was created. Steps finished before are not observed, so a restart
+	// does not observe the step history of every operation again.
+	started time.Time
+
+	// move and observeStep record the metrics; they are replaced in tests.
+	move        func(kind, from, to string)
+	observeStep func(kind, step string, duration time.Duration)
+
+	lock  sync.Mutex
+	state map[string]operationMetricsState
+}
+
+type operationMetricsState struct {
+	phase        string
+	lastFinished time.Time
+}
+
+func newOperationMetrics[T Object](kind string, status func(op T) *opv1alpha1.OperationStatus) *operationMetrics[T] {
+	return &operationMetrics[T]{
+		kind:        kind,
+		status:      status,
+		started:     time.Now(),
+		move:        metrics.MoveOperation,
+		observeStep: metrics.ObserveOperationStep,
+		state:       map[string]operationMetricsState{},
+	}
+}
+
+func (h *operationMetrics[T]) OnChange(key string, op T) (T, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	previous, seen := h.state[key]
+	if isNilObject(op) {
+		if seen {
+			h.move(h.kind, previous.phase, "")
+			delete(h.state, key)
+		}
+		return op, nil
+	}
+
+	status := h.status(op)
+	current := operationMetricsState{
+		phase:        string(status.Phase),
+		lastFinished: previous.lastFinished,
+	}
+	if current.phase == "" {
+		current.phase = string(opv1alpha1.OperationPhasePending)
+	}
+	if !seen {
+		current.lastFinished = h.started
+	}
+	if current.phase != previous.phase {
+		h.move(h.kind, previous.phase, current.phase)
+	}
+
+	for _, step := range status.StepHistory {
+		if step.Finished == nil || !step.Finished.After(current.lastFinished) {
+			continue
+		}
+		h.observeStep(h.kind, step.Name, step.Finished.Sub(step.Started.Time))
+		current.lastFinished = step.Finished.Time
+	}
+
+	h.state[key] = current
+	return op, nil
+}
+
+// BeaconWatcher is the subset of the generated Beacon controller ObserveBeacons needs.
+type BeaconWatcher interface {
+	OnChange(ctx context.Context, name string, sync generic.ObjectHandler[*planv1alpha1.Beacon])
+}
+
+// otherBeaconOwnerKind is the kind recorded for beacon owners not matching any of the owner key
+// prefixes given to ObserveBeacons.
+const otherBeaconOwnerKind = "Other"
+
+// ObserveBeacons registers a handler recording since when the beacon of every cluster is owned, and
+// by what kind of operation, so a cluster stuck in an operation can be alerted on. kinds maps the
+// owner key prefix of each operation controller, e.g. "kubernetes-upgrade", to the kind of its
+// operations. Owners are recorded by kind rather than by key, as every operation has its own key.
+func ObserveBeacons(ctx context.Context, beacons BeaconWatcher, kinds map[string]string) {
+	h := &beaconMetrics{
+		kinds:  kinds,
+		set:    metrics.SetBeaconOwner,
+		unset:  metrics.UnsetBeaconOwner,
+		owners: map[string]string{},
+	}
+	beacons.OnChange(ctx, "beacon-metrics", h.OnChange)
+}
+
+// beaconMetrics tracks the last owner seen per beacon, so the series of a previous owner is removed
+// once the beacon is released or handed over.
+type beaconMetrics struct {
+	kinds map[string]string
+
+	// set and unset record the metrics; they are replaced in tests.
+	set   func(cluster, kind string, since time.Time)
+	unset func(cluster, kind string)
+
+	lock   sync.Mutex
+	owners map[string]string
+}
+
+// kind returns the kind of operation owning a beacon under the given owner key. Owner keys are the
+// owner key prefix of a controller, followed by the namespace and name of the operation, e.g.
+// "kubernetes-upgrade/fleet-default/upgrade".
+func (h *beaconMetrics) kind(owner string) string {
+	for prefix, kind := range h.kinds {
+		if owner == prefix || strings.HasPrefix(owner, prefix+"/") {
+			return kind
+		}
+	}
+	return otherBeaconOwnerKind
+}
+
+func (h *beaconMetrics) OnChange(key string, beacon *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	previous := h.owners[key]
+	owner := ""
+	if beacon != nil {
+		owner = beacon.Status.Owner
+	}
+	if owner == previous {
+		return beacon, nil
+	}
+
+	// The beacon of a cluster is named after it.
+	_, cluster := kv.RSplit(key, "/")
+	if previous != "" {
+		h.unset(cluster, h.kind(previous))
+	}
+	if owner != "" {
+		h.set(cluster, h.kind(owner), time.Now())
+		h.owners[key] = owner
+	} else {
+		delete(h.owners, key)
+	}
+	return beacon, nil
+}
@@ -0,0 +1,93 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOperationMetrics(t *testing.T) {
+	t.Parallel()
+
+	var moves, steps []string
+	h := newOperationMetrics[*engineOp]("CertificateRotation", func(op *engineOp) *opv1alpha1.OperationStatus {
+		return &op.Status.OperationStatus
+	})
+	h.move = func(kind, from, to string) {
+		moves = append(moves, kind+":"+from+"->"+to)
+	}
+	h.observeStep = func(kind, step string, duration time.Duration) {
+		steps = append(steps, kind+":"+step+":"+duration.String())
+	}
+
+	op := newEngineOp("op")
+	_, _ = h.OnChange("fleet-default/op", op)
+
+	started := metav1.NewTime(h.started.Add(time.Second))
+	finished := metav1.NewTime(started.Add(time.Minute))
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.StepHistory = []opv1alpha1.StepTiming{
+		{Name: "Old", Started: metav1.NewTime(h.started.Add(-time.Hour)), Finished: &metav1.Time{Time: h.started.Add(-time.Minute)}},
+		{Name: string(engineStepOne), Started: started, Finished: &finished},
+		{Name: string(engineStepTwo), Started: finished},
+	}
+	_, _ = h.OnChange("fleet-default/op", op)
+	// The same status is not counted twice.
+	_, _ = h.OnChange("fleet-default/op", op)
+
+	_, _ = h.OnChange("fleet-default/op", nil)
+
+	assert.Equal(t, []string{
+		"CertificateRotation:->Pending",
+		"CertificateRotation:Pending->InProgress",
+		"CertificateRotation:InProgress->",
+	}, moves)
+	assert.Equal(t, []string{"CertificateRotation:One:1m0s"}, steps)
+	assert.Empty(t, h.state)
+}
+
+func TestBeaconMetrics(t *testing.T) {
+	t.Parallel()
+
+	var events []string
+	h := &beaconMetrics{
+		kinds: map[string]string{
+			"kubernetes-upgrade":      "KubernetesUpgrade",
+			"encryption-key-rotation": "EncryptionKeyRotation",
+		},
+		set: func(cluster, kind string, _ time.Time) {
+			events = append(events, "set "+cluster+" "+kind)
+		},
+		unset: func(cluster, kind string) {
+			events = append(events, "unset "+cluster+" "+kind)
+		},
+		owners: map[string]string{},
+	}
+
+	beacon := &planv1alpha1.Beacon{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"}}
+	_, _ = h.OnChange("fleet-default/test", beacon)
+
+	beacon.Status.Owner = "kubernetes-upgrade/fleet-default/upgrade"
+	_, _ = h.OnChange("fleet-default/test", beacon)
+	_, _ = h.OnChange("fleet-default/test", beacon)
+
+	beacon.Status.Owner = "encryption-key-rotation/fleet-default/rotate"
+	_, _ = h.OnChange("fleet-default/test", beacon)
+
+	beacon.Status.Owner = "imported-day2ops-disable"
+	_, _ = h.OnChange("fleet-default/test", beacon)
+
+	_, _ = h.OnChange("fleet-default/test", nil)
+
+	assert.Equal(t, []string{
+		"set test KubernetesUpgrade",
+		"unset test KubernetesUpgrade", "set test EncryptionKeyRotation",
+		"unset test EncryptionKeyRotation", "set test Other",
+		"unset test Other",
+	}, events)
+	assert.Empty(t, h.owners)
+}
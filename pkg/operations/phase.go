@@ -6,14 +6,15 @@
 	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
 )
 
-// IsTerminal returns true when the operation has reached a terminal phase: Succeeded, Failed, or
-// Canceled. Terminal operations no longer dispatch plans or modify cluster state. The
+// IsTerminal returns true when the operation has reached a terminal phase: Succeeded, Failed,
+// Canceled, or DryRunCompleted. Terminal operations no longer dispatch plans or modify cluster state. The
 // etcdsnapshotsave/etcdsnapshotrestore controllers use this to decide when to release the beacon
 // and when to respect the TTL for automatic deletion.
 func IsTerminal(phase opv1alpha1.OperationPhase) bool {
 	return phase == opv1alpha1.OperationPhaseSucceeded ||
 		phase == opv1alpha1.OperationPhaseFailed ||
-		phase == opv1alpha1.OperationPhaseCanceled
+		phase == opv1alpha1.OperationPhaseCanceled ||
+		phase == opv1alpha1.OperationPhaseDryRunCompleted
 }
 
 // IsExpired returns true when the operation has lived longer than its TTL measured from its
@@ -0,0 +1,146 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PlanPreviewDataKey is the key of the plan preview ConfigMap holding the rendered plans as a JSON
+// list, in the order the operation would assign them.
+const PlanPreviewDataKey = "plans.json"
+
+// IsDryRun returns true when the operation only previews the plans it would assign. A dry run
+// never queues for or acquires the beacon of the cluster and never assigns a plan, not even the
+// plans of its preflight checks: operation controllers render the plans of every step into a
+// PlanPreview straight from the Pending phase, then complete the dry run.
+func IsDryRun(spec *opv1alpha1.OperationSpec) bool {
+	return spec.DryRun
+}
+
+// RenderedPlan is the plan a step of an operation would assign to a single machine-plan secret.
+type RenderedPlan struct {
+	// Step is the step of the operation assigning the plan.
+	Step string `json:"step"`
+
+	// Secret is the namespace/name of the machine-plan secret the plan would be written to.
+	Secret string `json:"secret"`
+
+	// Machine is the name of the machine backing the machine-plan secret, if known.
+	Machine string `json:"machine,omitempty"`
+
+	// MaxFailures and FailureThreshold are the values the step would pass to Store.AssignPlan.
+	MaxFailures      int `json:"maxFailures"`
+	FailureThreshold int `json:"failureThreshold"`
+
+	// Plan is the plan exactly as it would be written to the machine-plan secret. As in the secret,
+	// the content of files is base64 encoded.
+	Plan planapi.Plan `json:"plan"`
+}
+
+// PlanPreview accumulates the plans rendered by a dry run. Steps add the plans in the order they
+// would assign them, so the preview also captures the node ordering of each step.
+type PlanPreview struct {
+	Plans []RenderedPlan
+}
+
+// Add records the plan that step would assign to secret, along with the arguments the step would
+// pass to Store.AssignPlan.
+func (p *PlanPreview) Add(step string, secret *corev1.Secret, nodePlan *planapi.Plan, maxFailures, failureThreshold int) {
+	p.Plans = append(p.Plans, RenderedPlan{
+		Step:             step,
+		Secret:           secret.Namespace + "/" + secret.Name,
+		Machine:          MachineName(secret),
+		MaxFailures:      maxFailures,
+		FailureThreshold: failureThreshold,
+		Plan:             *nodePlan,
+	})
+}
+
+// PlanPreviewName returns the name of the ConfigMap holding the plan preview of the named operation.
+func PlanPreviewName(operation string) string {
+	return operation + "-plan-preview"
+}
+
+// WritePlanPreview creates or updates the ConfigMap holding the plan preview of op, and returns its
+// name. The ConfigMap is owned by op so that it is garbage collected along with it. gvk is the kind
+// of op, as objects served from the cache do not carry their TypeMeta.
+func WritePlanPreview(configMaps corecontrollers.ConfigMapClient, op metav1.Object, gvk schema.GroupVersionKind, preview *PlanPreview) (string, error) {
+	plans := preview.Plans
+	if plans == nil {
+		plans = []RenderedPlan{}
+	}
+	data, err := json.Marshal(plans)
+	if err != nil {
+		return "", err
+	}
+
+	name := PlanPreviewName(op.GetName())
+
+	configMap, err := configMaps.Get(op.GetNamespace(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: op.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: gvk.GroupVersion().String(),
+					Kind:       gvk.Kind,
+					Name:       op.GetName(),
+					UID:        op.GetUID(),
+				}},
+			},
+			Data: map[string]string{PlanPreviewDataKey: string(data)},
+		})
+		return name, err
+	} else if err != nil {
+		return "", err
+	}
+
+	if !isOwnedBy(configMap, op) {
+		return "", fmt.Errorf("configmap %s/%s already exists and is not owned by operation %s", configMap.Namespace, configMap.Name, op.GetName())
+	}
+	if configMap.Data[PlanPreviewDataKey] == string(data) {
+		return name, nil
+	}
+
+	configMap = configMap.DeepCopy()
+	configMap.Data = map[string]string{PlanPreviewDataKey: string(data)}
+	_, err = configMaps.Update(configMap)
+	return name, err
+}
+
+// CompleteDryRun marks the operation as DryRunCompleted and records the name of the ConfigMap
+// holding its plan preview. A completed dry run is terminal but is not a success: the Succeeded
+// condition is left False, so that neither the Succeeded phase hooks nor anything counting
+// successful operations picks it up.
+func CompleteDryRun(status *opv1alpha1.OperationStatus, configMap string) {
+	setPhase(status, opv1alpha1.OperationPhaseDryRunCompleted)
+	status.PlanPreview = configMap
+	status.QueuePosition = 0
+
+	msg := fmt.Sprintf("Dry run completed, rendered plans are recorded in configmap %s", configMap)
+	for _, cond := range []condition.Cond{opv1alpha1.PendingCondition, opv1alpha1.InProgressCondition, opv1alpha1.SucceededCondition} {
+		cond.False(status)
+		cond.Reason(status, opv1alpha1.DryRunCompletedReason)
+		cond.Message(status, msg)
+	}
+}
+
+// isOwnedBy returns true when obj has an owner reference to owner.
+func isOwnedBy(obj, owner metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package operations
+
+import (
+	"encoding/json"
+	"testing"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	planapi "github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// previewConfigMaps is an in-memory ConfigMap client recording creates and updates.
+type previewConfigMaps struct {
+	corecontrollers.ConfigMapClient
+
+	objects map[string]*corev1.ConfigMap
+	creates int
+	updates int
+}
+
+func newPreviewConfigMaps(items ...*corev1.ConfigMap) *previewConfigMaps {
+	f := &previewConfigMaps{objects: map[string]*corev1.ConfigMap{}}
+	for _, item := range items {
+		f.objects[item.Namespace+"/"+item.Name] = item
+	}
+	return f
+}
+
+func (f *previewConfigMaps) Get(namespace, name string, _ metav1.GetOptions) (*corev1.ConfigMap, error) {
+	if configMap, ok := f.objects[namespace+"/"+name]; ok {
+		return configMap.DeepCopy(), nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+func (f *previewConfigMaps) Create(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	f.creates++
+	f.objects[configMap.Namespace+"/"+configMap.Name] = configMap.DeepCopy()
+	return configMap, nil
+}
+
+func (f *previewConfigMaps) Update(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	f.updates++
+	f.objects[configMap.Namespace+"/"+configMap.Name] = configMap.DeepCopy()
+	return configMap, nil
+}
+
+func newPreviewOp() *opv1alpha1.ETCDSnapshotRestore {
+	return &opv1alpha1.ETCDSnapshotRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "fleet-default", UID: "op-uid"},
+	}
+}
+
+func newPreviewSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "fleet-default",
+			Labels:    map[string]string{planv1alpha1.MachineLifecycleNameLabel: name + "-machine"},
+		},
+	}
+}
+
+var previewGVK = opv1alpha1.SchemeGroupVersion.WithKind("ETCDSnapshotRestore")
+
+func TestPlanPreview_Add(t *testing.T) {
+	t.Parallel()
+
+	preview := &PlanPreview{}
+	nodePlan := &planapi.Plan{OneTimeInstructions: []planapi.OneTimeInstruction{{CommonInstruction: planapi.CommonInstruction{Name: "restore", Command: "rke2"}}}}
+	preview.Add("Restore", newPreviewSecret("etcd-0"), nodePlan, 1, -1)
+
+	if !assert.Len(t, preview.Plans, 1) {
+		return
+	}
+	assert.Equal(t, RenderedPlan{
+		Step:             "Restore",
+		Secret:           "fleet-default/etcd-0",
+		Machine:          "etcd-0-machine",
+		MaxFailures:      1,
+		FailureThreshold: -1,
+		Plan:             *nodePlan,
+	}, preview.Plans[0])
+}
+
+func TestWritePlanPreview_CreatesOwnedConfigMap(t *testing.T) {
+	t.Parallel()
+
+	configMaps := newPreviewConfigMaps()
+	preview := &PlanPreview{}
+	preview.Add("Shutdown", newPreviewSecret("etcd-0"), &planapi.Plan{}, 1, -1)
+
+	name, err := WritePlanPreview(configMaps, newPreviewOp(), previewGVK, preview)
+	assert.NoError(t, err)
+	assert.Equal(t, "restore-plan-preview", name)
+
+	configMap := configMaps.objects["fleet-default/restore-plan-preview"]
+	if !assert.NotNil(t, configMap) || !assert.Len(t, configMap.OwnerReferences, 1) {
+		return
+	}
+	assert.Equal(t, metav1.OwnerReference{
+		APIVersion: "operation.cattle.io/v1alpha1",
+		Kind:       "ETCDSnapshotRestore",
+		Name:       "restore",
+		UID:        "op-uid",
+	}, configMap.OwnerReferences[0])
+
+	var plans []RenderedPlan
+	assert.NoError(t, json.Unmarshal([]byte(configMap.Data[PlanPreviewDataKey]), &plans))
+	if !assert.Len(t, plans, 1) {
+		return
+	}
+	assert.Equal(t, "Shutdown", plans[0].Step)
+	assert.Equal(t, "fleet-default/etcd-0", plans[0].Secret)
+}
+
+func TestWritePlanPreview_EmptyPreviewIsAnEmptyList(t *testing.T) {
+	t.Parallel()
+
+	configMaps := newPreviewConfigMaps()
+
+	_, err := WritePlanPreview(configMaps, newPreviewOp(), previewGVK, &PlanPreview{})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", configMaps.objects["fleet-default/restore-plan-preview"].Data[PlanPreviewDataKey])
+}
+
+func TestWritePlanPreview_UpdatesOnlyWhenChanged(t *testing.T) {
+	t.Parallel()
+
+	configMaps := newPreviewConfigMaps()
+	op := newPreviewOp()
+	preview := &PlanPreview{}
+	preview.Add("Shutdown", newPreviewSecret("etcd-0"), &planapi.Plan{}, 1, -1)
+
+	_, err := WritePlanPreview(configMaps, op, previewGVK, preview)
+	assert.NoError(t, err)
+	_, err = WritePlanPreview(configMaps, op, previewGVK, preview)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, configMaps.creates)
+	assert.Equal(t, 0, configMaps.updates, "an unchanged preview must not be rewritten")
+
+	preview.Add("Restore", newPreviewSecret("etcd-0"), &planapi.Plan{}, 1, -1)
+	_, err = WritePlanPreview(configMaps, op, previewGVK, preview)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, configMaps.updates)
+}
+
+func TestWritePlanPreview_RejectsForeignConfigMap(t *testing.T) {
+	t.Parallel()
+
+	configMaps := newPreviewConfigMaps(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore-plan-preview", Namespace: "fleet-default"},
+		Data:       map[string]string{"unrelated": "data"},
+	})
+
+	_, err := WritePlanPreview(configMaps, newPreviewOp(), previewGVK, &PlanPreview{})
+	assert.ErrorContains(t, err, "is not owned by operation restore")
+	assert.Equal(t, 0, configMaps.updates)
+}
+
+func TestCompleteDryRun(t *testing.T) {
+	t.Parallel()
+
+	status := &opv1alpha1.OperationStatus{Phase: opv1alpha1.OperationPhasePending, QueuePosition: 2}
+	CompleteDryRun(status, "restore-plan-preview")
+
+	assert.Equal(t, opv1alpha1.OperationPhaseDryRunCompleted, status.Phase)
+	assert.True(t, IsTerminal(status.Phase))
+	assert.Equal(t, "restore-plan-preview", status.PlanPreview)
+	assert.Zero(t, status.QueuePosition)
+	assert.True(t, opv1alpha1.SucceededCondition.IsFalse(status))
+	assert.Equal(t, opv1alpha1.DryRunCompletedReason, opv1alpha1.SucceededCondition.GetReason(status))
+	assert.True(t, opv1alpha1.PendingCondition.IsFalse(status))
+}
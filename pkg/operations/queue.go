@@ -0,0 +1,245 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// queueResyncInterval is how often a beacon with a non-empty queue is re-examined, so entries of
+// operations deleted or finished while queued are pruned even if the beacon itself does not change.
+const queueResyncInterval = 30 * time.Second
+
+// NextStart returns the earliest time at or after now the operation may start, honouring its
+// notBefore time and maintenance window. It returns now when the operation may start right away.
+// An error is returned when the maintenance window is invalid.
+func NextStart(spec *opv1alpha1.OperationSpec, now time.Time) (time.Time, error) {
+	start := now
+	if spec.NotBefore != nil && spec.NotBefore.Time.After(start) {
+		start = spec.NotBefore.Time
+	}
+
+	window := spec.MaintenanceWindow
+	if window == nil {
+		return start, nil
+	}
+
+	if window.Duration.Duration <= 0 {
+		return time.Time{}, fmt.Errorf("maintenance window duration must be positive, got %s", window.Duration.Duration)
+	}
+	opens, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid maintenance window start %q: %w", window.Start, err)
+	}
+	timeZone := window.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid maintenance window time zone %q: %w", timeZone, err)
+	}
+	days := map[string]bool{}
+	for _, day := range window.Days {
+		if !isWeekday(day) {
+			return time.Time{}, fmt.Errorf("invalid maintenance window day %q", day)
+		}
+		days[string(day)] = true
+	}
+
+	// Windows are examined in the order they open, starting a week back so that a window which
+	// opened earlier and is still open, possibly past midnight, is found first.
+	local := start.In(loc)
+	for offset := -7; offset <= 7; offset++ {
+		open := time.Date(local.Year(), local.Month(), local.Day()+offset, opens.Hour(), opens.Minute(), 0, 0, loc)
+		if len(days) > 0 && !days[open.Weekday().String()] {
+			continue
+		}
+		if !open.Add(window.Duration.Duration).After(start) {
+			continue
+		}
+		if open.After(start) {
+			return open, nil
+		}
+		return start, nil
+	}
+
+	return time.Time{}, fmt.Errorf("maintenance window never opens")
+}
+
+// AcquireQueuedBeacon queues op for the beacon of its cluster, then acquires the beacon on its
+// behalf once op is next in line and its schedule allows it to start. While op is waiting, its
+// queue position and the reason it is waiting are recorded on status. An invalid maintenance
+// window cancels op. Returns nil without error while op has to keep waiting.
+func AcquireQueuedBeacon(beacon *planv1alpha1.Beacon, beacons plancontrollers.BeaconClient, ownerKey string, op metav1.Object, gvk schema.GroupVersionKind, spec *opv1alpha1.OperationSpec, status *opv1alpha1.OperationStatus) (*planv1alpha1.Beacon, error) {
+	if beacon == nil {
+		opv1alpha1.PendingCondition.True(status)
+		opv1alpha1.PendingCondition.Reason(status, opv1alpha1.WaitingForBeaconReason)
+		opv1alpha1.PendingCondition.Message(status, "waiting for beacon creation")
+		return nil, nil
+	}
+	if plan.AuthorizedForBeacon(beacon, ownerKey) {
+		status.QueuePosition = 0
+		return beacon, nil
+	}
+
+	now := time.Now()
+	start, err := NextStart(spec, now)
+	if err != nil {
+		if _, err := plan.DequeueBeacon(beacon, beacons, ownerKey); err != nil {
+			return nil, err
+		}
+		status.QueuePosition = 0
+		Cancel(status, opv1alpha1.InvalidScheduleReason, err.Error())
+		return nil, nil
+	}
+
+	entry := planv1alpha1.BeaconQueueEntry{
+		Owner: ownerKey,
+		ObjectRef: corev1.ObjectReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  op.GetNamespace(),
+			Name:       op.GetName(),
+			UID:        op.GetUID(),
+		},
+		Priority: spec.Priority,
+		Created:  op.GetCreationTimestamp(),
+	}
+	if start.After(now) {
+		notBefore := metav1.NewTime(start)
+		entry.NotBefore = &notBefore
+	}
+
+	beacon, err = plan.EnqueueBeacon(beacon, beacons, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	acquired, err := plan.AcquireQueuedBeacon(beacon, beacons, ownerKey, now)
+	if err != nil {
+		return nil, err
+	}
+	if acquired != nil {
+		status.QueuePosition = 0
+		return acquired, nil
+	}
+
+	status.QueuePosition = int32(plan.QueuePosition(beacon, ownerKey))
+
+	opv1alpha1.PendingCondition.True(status)
+	switch {
+	case entry.NotBefore != nil:
+		opv1alpha1.PendingCondition.Reason(status, opv1alpha1.WaitingForScheduleReason)
+		opv1alpha1.PendingCondition.Message(status, fmt.Sprintf("waiting for schedule, not before %s", entry.NotBefore.UTC().Format(time.RFC3339)))
+	case beacon.Status.Owner != "":
+		opv1alpha1.PendingCondition.Reason(status, opv1alpha1.WaitingForBeaconReason)
+		opv1alpha1.PendingCondition.Message(status, fmt.Sprintf("waiting for beacon acquisition, queue position %d, beacon held by %s", status.QueuePosition, beacon.Status.Owner))
+	default:
+		opv1alpha1.PendingCondition.Reason(status, opv1alpha1.WaitingInQueueReason)
+		opv1alpha1.PendingCondition.Message(status, fmt.Sprintf("waiting in queue, position %d", status.QueuePosition))
+	}
+	return nil, nil
+}
+
+// OperationGetter is the subset of the generated operation cache WatchBeaconQueue needs.
+type OperationGetter[T Object] interface {
+	Get(namespace, name string) (T, error)
+}
+
+// OperationEnqueuer is the subset of the generated operation controller WatchBeaconQueue needs.
+type OperationEnqueuer interface {
+	Enqueue(namespace, name string)
+}
+
+// WatchBeaconQueue registers a beacon handler maintaining the queue entries of operations of the
+// given kind. Entries of operations that were deleted, recreated or reached a terminal phase are
+// removed, and once the beacon is free, the operation next in line is enqueued so that it starts
+// without waiting for its next poll. Every operation controller registers its own handler, so each
+// only needs to understand the entries of its kind.
+func WatchBeaconQueue[T Object](ctx context.Context, beacons plancontrollers.BeaconController, gvk schema.GroupVersionKind, operations OperationGetter[T], enqueuer OperationEnqueuer, status func(op T) *opv1alpha1.OperationStatus) {
+	h := &queueHandler[T]{
+		beacons:    beacons,
+		gvk:        gvk,
+		operations: operations,
+		enqueuer:   enqueuer,
+		status:     status,
+	}
+	beacons.OnChange(ctx, "beacon-queue-"+gvk.Kind, h.OnChange)
+}
+
+type queueHandler[T Object] struct {
+	beacons    plancontrollers.BeaconController
+	gvk        schema.GroupVersionKind
+	operations OperationGetter[T]
+	enqueuer   OperationEnqueuer
+	status     func(op T) *opv1alpha1.OperationStatus
+}
+
+func (h *queueHandler[T]) OnChange(_ string, beacon *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	if beacon == nil || beacon.DeletionTimestamp != nil || len(beacon.Status.Queue) == 0 {
+		return beacon, nil
+	}
+
+	var stale []string
+	for _, entry := range beacon.Status.Queue {
+		if !h.owns(entry) {
+			continue
+		}
+		op, err := h.operations.Get(entry.ObjectRef.Namespace, entry.ObjectRef.Name)
+		if apierrors.IsNotFound(err) {
+			stale = append(stale, entry.Owner)
+			continue
+		} else if err != nil {
+			return beacon, err
+		}
+		if op.GetUID() != entry.ObjectRef.UID || IsTerminal(h.status(op).Phase) {
+			stale = append(stale, entry.Owner)
+		}
+	}
+
+	if len(stale) > 0 {
+		logrus.Debugf("[%s] beacon %s/%s: pruning stale queue entries %v", h.gvk.Kind, beacon.Namespace, beacon.Name, stale)
+		updated, err := plan.DequeueBeacon(beacon, h.beacons, stale...)
+		if err != nil {
+			return beacon, err
+		}
+		beacon = updated
+	}
+
+	if beacon.Status.Owner == "" {
+		if next := plan.NextInQueue(beacon, time.Now()); next != nil && h.owns(*next) {
+			h.enqueuer.Enqueue(next.ObjectRef.Namespace, next.ObjectRef.Name)
+		}
+	}
+
+	if len(beacon.Status.Queue) > 0 {
+		h.beacons.EnqueueAfter(beacon.Namespace, beacon.Name, queueResyncInterval)
+	}
+	return beacon, nil
+}
+
+// owns returns true when entry was queued by an operation of the handler's kind.
+func (h *queueHandler[T]) owns(entry planv1alpha1.BeaconQueueEntry) bool {
+	return entry.ObjectRef.APIVersion == h.gvk.GroupVersion().String() && entry.ObjectRef.Kind == h.gvk.Kind
+}
+
+// isWeekday returns true when day names a day of the week.
+func isWeekday(day opv1alpha1.Weekday) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if string(day) == d.String() {
+			return true
+		}
+	}
+	return false
+}
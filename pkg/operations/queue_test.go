@@ -0,0 +1,361 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+)
+
+var queueGVK = opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation")
+
+// queueBeacons is an in-memory BeaconController recording status updates and delayed enqueues.
+type queueBeacons struct {
+	plancontrollers.BeaconController
+
+	beacon   *planv1alpha1.Beacon
+	resynced int
+}
+
+func (f *queueBeacons) UpdateStatus(b *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	f.beacon = b.DeepCopy()
+	return b, nil
+}
+
+func (f *queueBeacons) EnqueueAfter(_, _ string, _ time.Duration) {
+	f.resynced++
+}
+
+// queueOperations resolves operations from a fixed set of objects and records enqueues.
+type queueOperations struct {
+	objects  map[string]*engineOp
+	enqueued []string
+}
+
+func (f *queueOperations) Get(namespace, name string) (*engineOp, error) {
+	if op, ok := f.objects[namespace+"/"+name]; ok {
+		return op, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "certificaterotations"}, name)
+}
+
+func (f *queueOperations) Enqueue(namespace, name string) {
+	f.enqueued = append(f.enqueued, namespace+"/"+name)
+}
+
+func newQueueEntry(op *engineOp, priority int32) planv1alpha1.BeaconQueueEntry {
+	return planv1alpha1.BeaconQueueEntry{
+		Owner: engineOwnerKey(op),
+		ObjectRef: corev1.ObjectReference{
+			APIVersion: queueGVK.GroupVersion().String(),
+			Kind:       queueGVK.Kind,
+			Namespace:  op.Namespace,
+			Name:       op.Name,
+			UID:        op.UID,
+		},
+		Priority: priority,
+		Created:  op.CreationTimestamp,
+	}
+}
+
+func TestNextStart(t *testing.T) {
+	t.Parallel()
+
+	// 2026-01-07 is a Wednesday.
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	window := func(start string, duration time.Duration, days ...opv1alpha1.Weekday) *opv1alpha1.MaintenanceWindow {
+		return &opv1alpha1.MaintenanceWindow{Start: start, Duration: metav1.Duration{Duration: duration}, Days: days}
+	}
+
+	tests := []struct {
+		name      string
+		notBefore *time.Time
+		window    *opv1alpha1.MaintenanceWindow
+		want      time.Time
+		wantErr   string
+	}{
+		{
+			name: "unscheduled starts now",
+			want: now,
+		},
+		{
+			name:      "past notBefore starts now",
+			notBefore: ptr.To(at(6, 0, 0)),
+			want:      now,
+		},
+		{
+			name:      "future notBefore",
+			notBefore: ptr.To(at(8, 9, 30)),
+			want:      at(8, 9, 30),
+		},
+		{
+			name:   "inside the window starts now",
+			window: window("11:00", 2*time.Hour),
+			want:   now,
+		},
+		{
+			name:   "later the same day",
+			window: window("22:00", 4*time.Hour),
+			want:   at(7, 22, 0),
+		},
+		{
+			name:   "window closed for the day opens tomorrow",
+			window: window("02:00", time.Hour),
+			want:   at(8, 2, 0),
+		},
+		{
+			name:   "window spanning midnight is still open",
+			window: window("22:00", 16*time.Hour),
+			want:   now,
+		},
+		{
+			name:   "restricted to days",
+			window: window("02:00", time.Hour, "Saturday", "Sunday"),
+			want:   at(10, 2, 0),
+		},
+		{
+			name:      "window after notBefore",
+			notBefore: ptr.To(at(10, 3, 0)),
+			window:    window("02:00", 2*time.Hour, "Saturday", "Monday"),
+			want:      at(10, 3, 0),
+		},
+		{
+			name:      "notBefore past the window moves to the next window",
+			notBefore: ptr.To(at(10, 5, 0)),
+			window:    window("02:00", 2*time.Hour, "Saturday", "Monday"),
+			want:      at(12, 2, 0),
+		},
+		{
+			name:    "non-positive duration",
+			window:  window("02:00", 0),
+			wantErr: "duration must be positive",
+		},
+		{
+			name:    "invalid start",
+			window:  window("25:00", time.Hour),
+			wantErr: "invalid maintenance window start",
+		},
+		{
+			name:    "invalid day",
+			window:  window("02:00", time.Hour, "Someday"),
+			wantErr: "invalid maintenance window day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := &opv1alpha1.OperationSpec{MaintenanceWindow: tt.window}
+			if tt.notBefore != nil {
+				notBefore := metav1.NewTime(*tt.notBefore)
+				spec.NotBefore = &notBefore
+			}
+
+			got, err := NextStart(spec, now)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestNextStart_TimeZone(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 7, 12, 0, 0, 0, time.UTC)
+	spec := &opv1alpha1.OperationSpec{MaintenanceWindow: &opv1alpha1.MaintenanceWindow{
+		Start:    "22:00",
+		Duration: metav1.Duration{Duration: time.Hour},
+		TimeZone: "Asia/Tokyo",
+	}}
+
+	got, err := NextStart(spec, now)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2026, 1, 7, 13, 0, 0, 0, time.UTC).Equal(got), "got %s", got)
+
+	spec.MaintenanceWindow.TimeZone = "Nowhere/Special"
+	_, err = NextStart(spec, now)
+	assert.ErrorContains(t, err, "invalid maintenance window time zone")
+}
+
+func TestAcquireQueuedBeacon_AcquiresFreeBeacon(t *testing.T) {
+	t.Parallel()
+
+	beacons := &queueBeacons{beacon: &planv1alpha1.Beacon{}}
+	op := newEngineOp("op")
+	status := &opv1alpha1.OperationStatus{QueuePosition: 2}
+
+	acquired, err := AcquireQueuedBeacon(beacons.beacon, beacons, engineOwnerKey(op), op, queueGVK, &op.Spec.OperationSpec, status)
+	assert.NoError(t, err)
+	if assert.NotNil(t, acquired) {
+		assert.Equal(t, engineOwnerKey(op), acquired.Status.Owner)
+		assert.Empty(t, acquired.Status.Queue)
+	}
+	assert.Zero(t, status.QueuePosition)
+}
+
+func TestAcquireQueuedBeacon_QueuesBehindOwner(t *testing.T) {
+	t.Parallel()
+
+	beacons := &queueBeacons{beacon: &planv1alpha1.Beacon{Status: planv1alpha1.BeaconStatus{Owner: "other"}}}
+	op := newEngineOp("op")
+	status := &opv1alpha1.OperationStatus{}
+
+	acquired, err := AcquireQueuedBeacon(beacons.beacon, beacons, engineOwnerKey(op), op, queueGVK, &op.Spec.OperationSpec, status)
+	assert.NoError(t, err)
+	assert.Nil(t, acquired)
+	assert.Equal(t, int32(1), status.QueuePosition)
+	assert.Equal(t, opv1alpha1.WaitingForBeaconReason, opv1alpha1.PendingCondition.GetReason(status))
+	assert.Contains(t, opv1alpha1.PendingCondition.GetMessage(status), "beacon held by other")
+	if assert.Len(t, beacons.beacon.Status.Queue, 1) {
+		assert.Equal(t, newQueueEntry(op, 0), beacons.beacon.Status.Queue[0])
+	}
+}
+
+func TestAcquireQueuedBeacon_WaitsForHigherPriority(t *testing.T) {
+	t.Parallel()
+
+	first := newEngineOp("first")
+	first.UID = "first-uid"
+	beacons := &queueBeacons{beacon: &planv1alpha1.Beacon{Status: planv1alpha1.BeaconStatus{
+		Queue: []planv1alpha1.BeaconQueueEntry{newQueueEntry(first, 10)},
+	}}}
+	op := newEngineOp("op")
+	status := &opv1alpha1.OperationStatus{}
+
+	acquired, err := AcquireQueuedBeacon(beacons.beacon, beacons, engineOwnerKey(op), op, queueGVK, &op.Spec.OperationSpec, status)
+	assert.NoError(t, err)
+	assert.Nil(t, acquired)
+	assert.Equal(t, int32(2), status.QueuePosition)
+	assert.Equal(t, opv1alpha1.WaitingInQueueReason, opv1alpha1.PendingCondition.GetReason(status))
+}
+
+func TestAcquireQueuedBeacon_WaitsForSchedule(t *testing.T) {
+	t.Parallel()
+
+	beacons := &queueBeacons{beacon: &planv1alpha1.Beacon{}}
+	op := newEngineOp("op")
+	notBefore := metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))
+	op.Spec.NotBefore = &notBefore
+	status := &opv1alpha1.OperationStatus{}
+
+	acquired, err := AcquireQueuedBeacon(beacons.beacon, beacons, engineOwnerKey(op), op, queueGVK, &op.Spec.OperationSpec, status)
+	assert.NoError(t, err)
+	assert.Nil(t, acquired)
+	assert.Equal(t, int32(1), status.QueuePosition)
+	assert.Equal(t, opv1alpha1.WaitingForScheduleReason, opv1alpha1.PendingCondition.GetReason(status))
+	if assert.Len(t, beacons.beacon.Status.Queue, 1) && assert.NotNil(t, beacons.beacon.Status.Queue[0].NotBefore) {
+		assert.True(t, notBefore.Equal(beacons.beacon.Status.Queue[0].NotBefore))
+	}
+}
+
+func TestAcquireQueuedBeacon_InvalidScheduleCancels(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	beacons := &queueBeacons{beacon: &planv1alpha1.Beacon{Status: planv1alpha1.BeaconStatus{
+		Owner: "other",
+		Queue: []planv1alpha1.BeaconQueueEntry{newQueueEntry(op, 0)},
+	}}}
+	op.Spec.MaintenanceWindow = &opv1alpha1.MaintenanceWindow{Start: "02:00"}
+	status := &opv1alpha1.OperationStatus{QueuePosition: 1}
+
+	acquired, err := AcquireQueuedBeacon(beacons.beacon, beacons, engineOwnerKey(op), op, queueGVK, &op.Spec.OperationSpec, status)
+	assert.NoError(t, err)
+	assert.Nil(t, acquired)
+	assert.Equal(t, opv1alpha1.OperationPhaseCanceled, status.Phase)
+	assert.Equal(t, opv1alpha1.InvalidScheduleReason, opv1alpha1.CanceledCondition.GetReason(status))
+	assert.Zero(t, status.QueuePosition)
+	assert.Empty(t, beacons.beacon.Status.Queue, "a canceled operation must leave the queue")
+}
+
+func TestWatchBeaconQueue_PrunesStaleEntriesAndStartsNext(t *testing.T) {
+	t.Parallel()
+
+	finished := newEngineOp("finished")
+	finished.UID = "finished-uid"
+	finished.Status.Phase = opv1alpha1.OperationPhaseFailed
+	recreated := newEngineOp("recreated")
+	recreated.UID = "recreated-uid"
+	deleted := newEngineOp("deleted")
+	deleted.UID = "deleted-uid"
+	next := newEngineOp("next")
+	next.UID = "next-uid"
+
+	foreign := planv1alpha1.BeaconQueueEntry{
+		Owner:     "other-kind/fleet-default/foreign",
+		ObjectRef: corev1.ObjectReference{APIVersion: queueGVK.GroupVersion().String(), Kind: "ETCDSnapshotSave", Namespace: "fleet-default", Name: "foreign"},
+	}
+
+	beacons := &queueBeacons{}
+	operations := &queueOperations{objects: map[string]*engineOp{
+		"fleet-default/finished":  finished,
+		"fleet-default/recreated": newEngineOp("recreated"),
+		"fleet-default/next":      next,
+	}}
+	h := &queueHandler[*engineOp]{
+		beacons:    beacons,
+		gvk:        queueGVK,
+		operations: operations,
+		enqueuer:   operations,
+		status:     func(op *engineOp) *opv1alpha1.OperationStatus { return &op.Status.OperationStatus },
+	}
+
+	beacon := &planv1alpha1.Beacon{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "fleet-default"},
+		Status: planv1alpha1.BeaconStatus{Queue: []planv1alpha1.BeaconQueueEntry{
+			newQueueEntry(finished, 10),
+			newQueueEntry(recreated, 10),
+			newQueueEntry(deleted, 10),
+			newQueueEntry(next, 5),
+			foreign,
+		}},
+	}
+
+	got, err := h.OnChange("", beacon)
+	assert.NoError(t, err)
+	assert.Equal(t, []planv1alpha1.BeaconQueueEntry{newQueueEntry(next, 5), foreign}, got.Status.Queue)
+	assert.Equal(t, []string{"fleet-default/next"}, operations.enqueued)
+	assert.Equal(t, 1, beacons.resynced, "a non-empty queue must be re-examined")
+}
+
+func TestWatchBeaconQueue_LeavesOwnedBeacon(t *testing.T) {
+	t.Parallel()
+
+	next := newEngineOp("next")
+	beacons := &queueBeacons{}
+	operations := &queueOperations{objects: map[string]*engineOp{"fleet-default/next": next}}
+	h := &queueHandler[*engineOp]{
+		beacons:    beacons,
+		gvk:        queueGVK,
+		operations: operations,
+		enqueuer:   operations,
+		status:     func(op *engineOp) *opv1alpha1.OperationStatus { return &op.Status.OperationStatus },
+	}
+
+	beacon := &planv1alpha1.Beacon{Status: planv1alpha1.BeaconStatus{
+		Owner: plan.ControllerOwnerKey(newEngineOp("running"), "test-operation"),
+		Queue: []planv1alpha1.BeaconQueueEntry{newQueueEntry(next, 0)},
+	}}
+
+	_, err := h.OnChange("", beacon)
+	assert.NoError(t, err)
+	assert.Nil(t, beacons.beacon, "live entries must not be pruned")
+	assert.Empty(t, operations.enqueued, "nothing starts while the beacon is held")
+}
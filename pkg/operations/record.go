@@ -0,0 +1,203 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxPlanChangeMessage bounds the message of the PlanChanged condition, so a plan replacing many
+// files does not bloat the status of the operation.
+const maxPlanChangeMessage = 1024
+
+// RecordPlanChange records the diff of a plan the operation just replaced on the node of the given
+// machine-plan secret in the PlanChanged condition, so users can tell why a node got a new plan.
+// Plans assigned to a secret without a plan have no diff and are not recorded.
+func RecordPlanChange(status *opv1alpha1.OperationStatus, secret *corev1.Secret, diff *plan.PlanDiff) {
+	if diff == nil {
+		return
+	}
+	node := MachineName(secret)
+	if node == "" {
+		node = secret.Name
+	}
+	message := fmt.Sprintf("replaced plan of %s: %s", node, diff)
+	if len(message) > maxPlanChangeMessage {
+		message = message[:maxPlanChangeMessage-3] + "..."
+	}
+	logrus.Infof("[operations] %s/%s: %s", secret.Namespace, secret.Name, message)
+	opv1alpha1.PlanChangedCondition.True(status)
+	opv1alpha1.PlanChangedCondition.Reason(status, opv1alpha1.PlanReplacedReason)
+	opv1alpha1.PlanChangedCondition.Message(status, message)
+}
+
+// RecordLeader records the machine owning the given machine-plan secret as the leader of the
+// operation. Leaders without a machine are recorded by the name of their secret.
+func RecordLeader(status *opv1alpha1.OperationStatus, leader *corev1.Secret) {
+	if leader == nil {
+		return
+	}
+	if name := MachineName(leader); name != "" {
+		status.Leader = name
+		return
+	}
+	status.Leader = leader.Name
+}
+
+// RecordName returns the name of the OperationRecord of op. Records are named after the UID of the
+// operation, so an operation recreated under the same name is recorded separately.
+func RecordName(gvk schema.GroupVersionKind, op metav1.Object) string {
+	return fmt.Sprintf("%s-%s", strings.ToLower(gvk.Kind), op.GetUID())
+}
+
+// NewOperationRecord returns the OperationRecord of op, capturing its spec and final status. The
+// record lives in the namespace of op and is labeled with the name of the cluster op ran against.
+func NewOperationRecord(gvk schema.GroupVersionKind, op Object, spec *opv1alpha1.OperationSpec, status *opv1alpha1.OperationStatus) (*opv1alpha1.OperationRecord, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(op)
+	if err != nil {
+		return nil, err
+	}
+	rawSpec, err := json.Marshal(obj["spec"])
+	if err != nil {
+		return nil, err
+	}
+	rawStatus, err := json.Marshal(obj["status"])
+	if err != nil {
+		return nil, err
+	}
+
+	record := opv1alpha1.NewOperationRecord(op.GetNamespace(), RecordName(gvk, op), opv1alpha1.OperationRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				opv1alpha1.OperationRecordKindLabel: gvk.Kind,
+			},
+		},
+		Spec: opv1alpha1.OperationRecordSpec{
+			OperationRef: corev1.ObjectReference{
+				APIVersion: gvk.GroupVersion().String(),
+				Kind:       gvk.Kind,
+				Namespace:  op.GetNamespace(),
+				Name:       op.GetName(),
+				UID:        op.GetUID(),
+			},
+			CreatedBy:       op.GetAnnotations()[opv1alpha1.CreatedByAnnotation],
+			Phase:           status.Phase,
+			Started:         op.GetCreationTimestamp(),
+			Completed:       status.LastUpdated,
+			Leader:          status.Leader,
+			OperationSpec:   runtime.RawExtension{Raw: rawSpec},
+			OperationStatus: runtime.RawExtension{Raw: rawStatus},
+		},
+	})
+
+	if spec.ClusterRef != nil {
+		record.Spec.ClusterRef = spec.ClusterRef.DeepCopy()
+		if len(validation.IsValidLabelValue(spec.ClusterRef.Name)) == 0 {
+			record.Labels[opv1alpha1.OperationRecordClusterLabel] = spec.ClusterRef.Name
+		}
+	}
+
+	for _, step := range status.StepHistory {
+		record.Spec.Steps = append(record.Spec.Steps, *step.DeepCopy())
+	}
+
+	switch status.Phase {
+	case opv1alpha1.OperationPhaseFailed:
+		record.Spec.Message = opv1alpha1.FailedCondition.GetMessage(status)
+	case opv1alpha1.OperationPhaseCanceled:
+		record.Spec.Message = opv1alpha1.CanceledCondition.GetMessage(status)
+	}
+
+	return record, nil
+}
+
+// ListOperationRecords returns the records of the operations that ran against the named cluster,
+// in the order they completed. All records in the namespace are returned when clusterName is
+// empty, and the records of all namespaces when namespace is empty.
+func ListOperationRecords(records generic.CacheInterface[*opv1alpha1.OperationRecord], namespace, clusterName string) ([]*opv1alpha1.OperationRecord, error) {
+	selector := labels.Everything()
+	if clusterName != "" {
+		selector = labels.SelectorFromSet(labels.Set{opv1alpha1.OperationRecordClusterLabel: clusterName})
+	}
+
+	result, err := records.List(namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Spec.Completed.Before(&result[j].Spec.Completed)
+	})
+	return result, nil
+}
+
+// OperationWatcher is the subset of the generated operation controller RecordOperations needs.
+type OperationWatcher[T Object] interface {
+	OnChange(ctx context.Context, name string, sync generic.ObjectHandler[T])
+}
+
+// RecordClient is the subset of the generated OperationRecord controller RecordOperations needs.
+type RecordClient interface {
+	Create(record *opv1alpha1.OperationRecord) (*opv1alpha1.OperationRecord, error)
+}
+
+// RecordOperations registers a handler writing an OperationRecord for every operation of the given
+// kind once it reaches a terminal phase and the delegates of its phase hooks have finished, i.e.
+// once the operation no longer carries a lifecycle-hook label. Records are only ever created, never updated, so the
+// history of a cluster is append-only; it is left to the operator to prune old records.
+func RecordOperations[T Object](ctx context.Context, operations OperationWatcher[T], records RecordClient, recordCache generic.CacheInterface[*opv1alpha1.OperationRecord], gvk schema.GroupVersionKind, spec func(op T) *opv1alpha1.OperationSpec, status func(op T) *opv1alpha1.OperationStatus) {
+	h := &recordHandler[T]{
+		records:     records,
+		recordCache: recordCache,
+		gvk:         gvk,
+		spec:        spec,
+		status:      status,
+	}
+	operations.OnChange(ctx, "operation-record-"+gvk.Kind, h.OnChange)
+}
+
+type recordHandler[T Object] struct {
+	records     RecordClient
+	recordCache generic.CacheInterface[*opv1alpha1.OperationRecord]
+	gvk         schema.GroupVersionKind
+	spec        func(op T) *opv1alpha1.OperationSpec
+	status      func(op T) *opv1alpha1.OperationStatus
+}
+
+func (h *recordHandler[T]) OnChange(_ string, op T) (T, error) {
+	if isNilObject(op) || !IsTerminal(h.status(op).Phase) || planv1alpha1.HasActiveLifecycleHook(op) {
+		return op, nil
+	}
+
+	name := RecordName(h.gvk, op)
+	if _, err := h.recordCache.Get(op.GetNamespace(), name); err == nil {
+		return op, nil
+	} else if !apierrors.IsNotFound(err) {
+		return op, err
+	}
+
+	record, err := NewOperationRecord(h.gvk, op, h.spec(op), h.status(op))
+	if err != nil {
+		return op, err
+	}
+
+	logrus.Debugf("[%s] %s/%s: recording %s operation as %s/%s", h.gvk.Kind, op.GetNamespace(), op.GetName(), record.Spec.Phase, record.Namespace, name)
+	if _, err := h.records.Create(record); err != nil && !apierrors.IsAlreadyExists(err) {
+		return op, err
+	}
+	return op, nil
+}
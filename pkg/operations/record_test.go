@@ -0,0 +1,255 @@
+package operations
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/rancher/rancher/pkg/plan"
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var recordGVK = opv1alpha1.SchemeGroupVersion.WithKind("CertificateRotation")
+
+// recordStore is an in-memory OperationRecord client and cache.
+type recordStore struct {
+	generic.CacheInterface[*opv1alpha1.OperationRecord]
+
+	records map[string]*opv1alpha1.OperationRecord
+	created int
+}
+
+func newRecordStore(records ...*opv1alpha1.OperationRecord) *recordStore {
+	s := &recordStore{records: map[string]*opv1alpha1.OperationRecord{}}
+	for _, record := range records {
+		s.records[record.Namespace+"/"+record.Name] = record
+	}
+	return s
+}
+
+func (s *recordStore) Create(record *opv1alpha1.OperationRecord) (*opv1alpha1.OperationRecord, error) {
+	s.created++
+	s.records[record.Namespace+"/"+record.Name] = record
+	return record, nil
+}
+
+func (s *recordStore) Get(namespace, name string) (*opv1alpha1.OperationRecord, error) {
+	if record, ok := s.records[namespace+"/"+name]; ok {
+		return record, nil
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "operationrecords"}, name)
+}
+
+func (s *recordStore) List(namespace string, selector labels.Selector) ([]*opv1alpha1.OperationRecord, error) {
+	var result []*opv1alpha1.OperationRecord
+	for _, record := range s.records {
+		if (namespace == "" || record.Namespace == namespace) && selector.Matches(labels.Set(record.Labels)) {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+func newRecordedOp(phase opv1alpha1.OperationPhase) *engineOp {
+	op := &engineOp{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "fleet-default",
+			Name:              "rotate",
+			UID:               "1234",
+			CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Annotations:       map[string]string{opv1alpha1.CreatedByAnnotation: "u-abcde"},
+		},
+	}
+	op.Spec.ClusterRef = &corev1.ObjectReference{
+		APIVersion: "provisioning.cattle.io/v1",
+		Kind:       "Cluster",
+		Namespace:  "fleet-default",
+		Name:       "downstream",
+	}
+	op.Spec.TTL = 60
+	op.Status.Phase = phase
+	op.Status.LastUpdated = metav1.NewTime(time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC))
+	return op
+}
+
+func TestStepHistory(t *testing.T) {
+	var status opv1alpha1.CertificateRotationStatus
+
+	status.SetStep(opv1alpha1.CertificateRotationStepRotate)
+	status.SetStep(opv1alpha1.CertificateRotationStepRotate)
+	status.SetStep(opv1alpha1.CertificateRotationStepRestart)
+	if !assert.Len(t, status.StepHistory, 2) {
+		return
+	}
+	assert.Equal(t, string(opv1alpha1.CertificateRotationStepRotate), status.StepHistory[0].Name)
+	assert.NotNil(t, status.StepHistory[0].Finished, "leaving a step must close its timing")
+	assert.Nil(t, status.StepHistory[1].Finished, "the running step must stay open")
+
+	status.SetPhase(opv1alpha1.OperationPhaseSucceeded)
+	assert.NotNil(t, status.StepHistory[1].Finished, "a terminal phase must close the running step")
+
+	for i := 0; i < 40; i++ {
+		status.RecordStep("step", metav1.Now())
+	}
+	assert.Len(t, status.StepHistory, 32, "only the most recent transitions are kept")
+}
+
+func TestRecordLeader(t *testing.T) {
+	var status opv1alpha1.OperationStatus
+
+	RecordLeader(&status, nil)
+	assert.Empty(t, status.Leader)
+
+	RecordLeader(&status, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "plan-secret"}})
+	assert.Equal(t, "plan-secret", status.Leader)
+
+	RecordLeader(&status, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:   "plan-secret",
+		Labels: map[string]string{planv1alpha1.MachineLifecycleNameLabel: "machine-1"},
+	}})
+	assert.Equal(t, "machine-1", status.Leader)
+}
+
+func TestRecordPlanChange(t *testing.T) {
+	var status opv1alpha1.OperationStatus
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:   "plan-secret",
+		Labels: map[string]string{planv1alpha1.MachineLifecycleNameLabel: "machine-1"},
+	}}
+
+	RecordPlanChange(&status, secret, nil)
+	assert.Empty(t, status.Conditions, "a first plan has no diff to record")
+
+	RecordPlanChange(&status, secret, &plan.PlanDiff{Instructions: []plan.EntryChange{{Name: "install", Type: plan.Changed}}})
+	assert.True(t, opv1alpha1.PlanChangedCondition.IsTrue(&status))
+	assert.Equal(t, opv1alpha1.PlanReplacedReason, opv1alpha1.PlanChangedCondition.GetReason(&status))
+	assert.Equal(t, "replaced plan of machine-1: instructions: ~install", opv1alpha1.PlanChangedCondition.GetMessage(&status))
+
+	var files []plan.FileChange
+	for range 100 {
+		files = append(files, plan.FileChange{Path: "/var/lib/rancher/rke2/server/manifests/rancher/addon.yaml", Type: plan.Added})
+	}
+	RecordPlanChange(&status, secret, &plan.PlanDiff{Files: files})
+	assert.Len(t, opv1alpha1.PlanChangedCondition.GetMessage(&status), maxPlanChangeMessage, "the message must be truncated")
+}
+
+func TestNewOperationRecord(t *testing.T) {
+	op := newRecordedOp(opv1alpha1.OperationPhaseFailed)
+	op.Status.Leader = "machine-1"
+	op.Status.RecordStep(string(opv1alpha1.CertificateRotationStepRotate), op.Status.LastUpdated)
+	Fail(&op.Status.OperationStatus, opv1alpha1.PlanFailedReason, "rotation failed")
+
+	record, err := NewOperationRecord(recordGVK, op, &op.Spec.OperationSpec, &op.Status.OperationStatus)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "fleet-default", record.Namespace)
+	assert.Equal(t, "certificaterotation-1234", record.Name)
+	assert.Equal(t, "OperationRecord", record.Kind)
+	assert.Empty(t, record.OwnerReferences, "records must outlive the recorded operation")
+	assert.Equal(t, "downstream", record.Labels[opv1alpha1.OperationRecordClusterLabel])
+	assert.Equal(t, "CertificateRotation", record.Labels[opv1alpha1.OperationRecordKindLabel])
+
+	assert.Equal(t, "rotate", record.Spec.OperationRef.Name)
+	assert.Equal(t, op.UID, record.Spec.OperationRef.UID)
+	assert.Equal(t, "downstream", record.Spec.ClusterRef.Name)
+	assert.Equal(t, "u-abcde", record.Spec.CreatedBy)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, record.Spec.Phase)
+	assert.Equal(t, "rotation failed", record.Spec.Message)
+	assert.Equal(t, op.CreationTimestamp, record.Spec.Started)
+	assert.Equal(t, op.Status.LastUpdated, record.Spec.Completed)
+	assert.Equal(t, "machine-1", record.Spec.Leader)
+	if assert.Len(t, record.Spec.Steps, 1) {
+		assert.NotNil(t, record.Spec.Steps[0].Finished)
+	}
+
+	var spec opv1alpha1.CertificateRotationSpec
+	if assert.NoError(t, json.Unmarshal(record.Spec.OperationSpec.Raw, &spec)) {
+		assert.Equal(t, int64(60), spec.TTL)
+	}
+	var status opv1alpha1.CertificateRotationStatus
+	if assert.NoError(t, json.Unmarshal(record.Spec.OperationStatus.Raw, &status)) {
+		assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+	}
+}
+
+func TestRecordOperations(t *testing.T) {
+	records := newRecordStore()
+	h := &recordHandler[*engineOp]{
+		records:     records,
+		recordCache: records,
+		gvk:         recordGVK,
+		spec:        func(op *engineOp) *opv1alpha1.OperationSpec { return &op.Spec.OperationSpec },
+		status:      func(op *engineOp) *opv1alpha1.OperationStatus { return &op.Status.OperationStatus },
+	}
+
+	_, err := h.OnChange("", newRecordedOp(opv1alpha1.OperationPhaseInProgress))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, records.created, "running operations must not be recorded")
+
+	_, err = h.OnChange("", nil)
+	assert.NoError(t, err)
+
+	op := newRecordedOp(opv1alpha1.OperationPhaseSucceeded)
+	op.Labels = map[string]string{planv1alpha1.SucceededPhaseHookLabelPrefix + "verify": "verifier"}
+	_, err = h.OnChange("", op)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, records.created, "operations must not be recorded before their hook delegates finish")
+
+	op.Labels = nil
+	for i := 0; i < 2; i++ {
+		_, err = h.OnChange("", op)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, records.created, "an operation must be recorded exactly once")
+}
+
+func TestListOperationRecords(t *testing.T) {
+	newRecord := func(namespace, name, cluster string, completed int) *opv1alpha1.OperationRecord {
+		return &opv1alpha1.OperationRecord{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels:    map[string]string{opv1alpha1.OperationRecordClusterLabel: cluster},
+			},
+			Spec: opv1alpha1.OperationRecordSpec{
+				Completed: metav1.NewTime(time.Date(2026, 1, 1, completed, 0, 0, 0, time.UTC)),
+			},
+		}
+	}
+	records := newRecordStore(
+		newRecord("fleet-default", "late", "a", 3),
+		newRecord("fleet-default", "early", "a", 1),
+		newRecord("fleet-default", "other", "b", 2),
+		newRecord("fleet-local", "local", "a", 4),
+	)
+
+	names := func(records []*opv1alpha1.OperationRecord) []string {
+		var result []string
+		for _, record := range records {
+			result = append(result, record.Name)
+		}
+		return result
+	}
+
+	got, err := ListOperationRecords(records, "fleet-default", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"early", "late"}, names(got))
+
+	got, err = ListOperationRecords(records, "", "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"early", "late", "local"}, names(got))
+
+	got, err = ListOperationRecords(records, "fleet-default", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"early", "other", "late"}, names(got))
+}
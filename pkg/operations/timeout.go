@@ -0,0 +1,59 @@
+package operations
+
+import (
+	"fmt"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// StepTimeout returns how long an operation may spend in step: the timeout set for the step in
+// spec.stepTimeouts, or the default of the operation type otherwise. Zero means the step never
+// times out.
+func StepTimeout[K ~string](spec *opv1alpha1.OperationSpec, step K, defaults map[K]time.Duration) time.Duration {
+	if timeout, ok := spec.StepTimeouts[string(step)]; ok {
+		return max(timeout.Duration, 0)
+	}
+	return defaults[step]
+}
+
+// StepStarted returns when the operation entered step, as recorded in its step history, or zero if
+// the history has no running entry for the step.
+func StepStarted(status *opv1alpha1.OperationStatus, step string) time.Time {
+	if n := len(status.StepHistory); n > 0 {
+		if last := status.StepHistory[n-1]; last.Name == step && last.Finished == nil {
+			return last.Started.Time
+		}
+	}
+	return time.Time{}
+}
+
+// CheckStepTimeout marks the operation as Failed when it has spent longer than the timeout of step
+// in it, and returns true if so. Steps whose start is unknown, such as those of operations created
+// before step history was recorded, have their start recorded at now so they time out a full
+// timeout later rather than at once. The failure message carries the message last reported on the
+// InProgress condition, which names the nodes the step is still waiting on, as bucketed by
+// plan.Message. The beacon is released by the Failed phase handler like for any other failure.
+func CheckStepTimeout[K ~string](spec *opv1alpha1.OperationSpec, status *opv1alpha1.OperationStatus, step K, defaults map[K]time.Duration, now time.Time) bool {
+	timeout := StepTimeout(spec, step, defaults)
+	if timeout <= 0 {
+		return false
+	}
+	started := StepStarted(status, string(step))
+	if started.IsZero() {
+		status.RecordStep(string(step), metav1.NewTime(now))
+		return false
+	}
+	if now.Sub(started) <= timeout {
+		return false
+	}
+
+	msg := fmt.Sprintf("step %s did not complete within %s", step, duration.HumanDuration(timeout))
+	if waiting := opv1alpha1.InProgressCondition.GetMessage(status); waiting != "" {
+		msg = fmt.Sprintf("%s, last reported: %s", msg, waiting)
+	}
+	Fail(status, opv1alpha1.StepTimedOutReason, msg)
+	return true
+}
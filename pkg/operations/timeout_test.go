@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	opv1alpha1 "github.com/rancher/rancher/pkg/apis/operation.cattle.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStepTimeout(t *testing.T) {
+	defaults := map[engineStep]time.Duration{engineStepOne: time.Hour}
+	spec := &opv1alpha1.OperationSpec{
+		StepTimeouts: map[string]metav1.Duration{
+			string(engineStepTwo): {Duration: time.Minute},
+		},
+	}
+
+	assert.Equal(t, time.Hour, StepTimeout(spec, engineStepOne, defaults), "unlisted steps use the default")
+	assert.Equal(t, time.Minute, StepTimeout(spec, engineStepTwo, defaults), "listed steps use the spec")
+
+	spec.StepTimeouts[string(engineStepOne)] = metav1.Duration{}
+	assert.Zero(t, StepTimeout(spec, engineStepOne, defaults), "a zero duration disables the default")
+}
+
+func TestCheckStepTimeout(t *testing.T) {
+	defaults := map[engineStep]time.Duration{engineStepOne: time.Hour}
+	started := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newStatus := func() *opv1alpha1.OperationStatus {
+		status := &opv1alpha1.OperationStatus{Phase: opv1alpha1.OperationPhaseInProgress}
+		status.RecordStep(string(engineStepOne), metav1.NewTime(started))
+		Wait(status, opv1alpha1.WaitingForPlanAppliedReason, "Waiting in step One: waiting for probes: machine-1")
+		return status
+	}
+
+	t.Run("within timeout", func(t *testing.T) {
+		status := newStatus()
+		assert.False(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepOne, defaults, started.Add(time.Hour)))
+		assert.Equal(t, opv1alpha1.OperationPhaseInProgress, status.Phase)
+	})
+
+	t.Run("past timeout", func(t *testing.T) {
+		status := newStatus()
+		assert.True(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepOne, defaults, started.Add(time.Hour+time.Second)))
+		assert.Equal(t, opv1alpha1.OperationPhaseFailed, status.Phase)
+		assert.Equal(t, opv1alpha1.StepTimedOutReason, opv1alpha1.FailedCondition.GetReason(status))
+		assert.Contains(t, opv1alpha1.FailedCondition.GetMessage(status), "step One did not complete within 60m")
+		assert.Contains(t, opv1alpha1.FailedCondition.GetMessage(status), "machine-1", "the message must name the nodes that did not converge")
+	})
+
+	t.Run("step without timeout", func(t *testing.T) {
+		status := newStatus()
+		assert.False(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepTwo, defaults, started.Add(24*time.Hour)))
+	})
+
+	t.Run("unknown start", func(t *testing.T) {
+		status := &opv1alpha1.OperationStatus{
+			Phase:       opv1alpha1.OperationPhaseInProgress,
+			LastUpdated: metav1.NewTime(started.Add(-24 * time.Hour)),
+		}
+		assert.False(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepOne, defaults, started),
+			"the time the status was last updated is not the start of the step")
+		assert.Equal(t, started, StepStarted(status, string(engineStepOne)), "the start is recorded when first seen")
+		assert.False(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepOne, defaults, started.Add(time.Hour)))
+		assert.True(t, CheckStepTimeout(&opv1alpha1.OperationSpec{}, status, engineStepOne, defaults, started.Add(time.Hour+time.Second)))
+	})
+}
+
+func TestEngine_StepTimeoutFailsOperation(t *testing.T) {
+	t.Parallel()
+
+	op := newEngineOp("op")
+	f := newEngineFixture(engineOwnerKey(op))
+	f.results[engineStepOne] = false
+	f.engine.def.Steps[0].Timeout = time.Minute
+	op.Status.Phase = opv1alpha1.OperationPhaseInProgress
+	op.Status.Step = engineStepOne
+	op.Status.RecordStep(string(engineStepOne), metav1.NewTime(time.Now().Add(-time.Hour)))
+
+	got, err := f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Equal(t, opv1alpha1.OperationPhaseFailed, got.Phase)
+	assert.Equal(t, opv1alpha1.StepTimedOutReason, opv1alpha1.FailedCondition.GetReason(&got))
+	assert.Empty(t, f.reconciled, "a timed out step must not be reconciled")
+
+	op.Status = got
+	_, err = f.engine.OnChange(op, op.Status)
+	assert.NoError(t, err)
+	assert.Empty(t, f.beacons.beacon.Status.Owner, "the beacon must be released once the operation failed")
+}
@@ -1,6 +1,9 @@
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 type BeaconSpec struct {
 }
@@ -28,6 +31,37 @@ type BeaconStatus struct {
 	// The last element in the array is the currently active handler.
 	// +optional
 	Delegates []string `json:"delegates,omitempty"`
+
+	// Queue holds the objects waiting to acquire the beacon once it is released, in the order they
+	// were enqueued. The entry acquiring the beacon next is the eligible entry with the highest
+	// priority, oldest first; see plan.NextInQueue.
+	// +optional
+	// +listType=map
+	// +listMapKey=owner
+	Queue []BeaconQueueEntry `json:"queue,omitempty"`
+}
+
+// BeaconQueueEntry records an object waiting to acquire the beacon.
+type BeaconQueueEntry struct {
+	// Owner is the key the object acquires the beacon as.
+	Owner string `json:"owner"`
+
+	// ObjectRef references the object waiting for the beacon.
+	ObjectRef corev1.ObjectReference `json:"objectRef"`
+
+	// Priority orders the entries of the queue. Entries with a higher priority acquire the beacon
+	// first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// Created orders entries of equal priority, oldest first. It is usually the creation timestamp
+	// of the queued object.
+	Created metav1.Time `json:"created"`
+
+	// NotBefore is the earliest time the entry may acquire the beacon. Entries that are not yet
+	// eligible are passed over in favour of the next eligible entry.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
 }
 
 // +genclient
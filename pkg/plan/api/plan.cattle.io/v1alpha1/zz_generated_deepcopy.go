@@ -87,6 +87,28 @@ func (in *BeaconList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BeaconQueueEntry) DeepCopyInto(out *BeaconQueueEntry) {
+	*out = *in
+	out.ObjectRef = in.ObjectRef
+	in.Created.DeepCopyInto(&out.Created)
+	if in.NotBefore != nil {
+		in, out := &in.NotBefore, &out.NotBefore
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BeaconQueueEntry.
+func (in *BeaconQueueEntry) DeepCopy() *BeaconQueueEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(BeaconQueueEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BeaconSpec) DeepCopyInto(out *BeaconSpec) {
 	*out = *in
@@ -118,6 +140,13 @@ func (in *BeaconStatus) DeepCopyInto(out *BeaconStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Queue != nil {
+		in, out := &in.Queue, &out.Queue
+		*out = make([]BeaconQueueEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1,6 +1,10 @@
 package plan
 
 import (
+	"cmp"
+	"slices"
+	"time"
+
 	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
 	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -213,3 +217,129 @@ func ControllerOwnerKey(obj metav1.Object, prefix string) string {
 
 	return prefix + "/" + key
 }
+
+// EnqueueBeacon adds entry to the queue of the beacon, or updates the priority and notBefore of the
+// entry already queued for the same owner. The original Created timestamp of a queued entry is
+// kept, so re-enqueueing never moves an entry back in line. If the beacon is nil or the entry is
+// already up to date, the beacon is returned unchanged.
+func EnqueueBeacon(beacon *planv1alpha1.Beacon, beacons plancontrollers.BeaconClient, entry planv1alpha1.BeaconQueueEntry) (*planv1alpha1.Beacon, error) {
+	if beacon == nil {
+		return nil, nil
+	}
+
+	index := slices.IndexFunc(beacon.Status.Queue, func(e planv1alpha1.BeaconQueueEntry) bool {
+		return e.Owner == entry.Owner
+	})
+	if index >= 0 {
+		queued := beacon.Status.Queue[index]
+		if queued.Priority == entry.Priority && queued.ObjectRef == entry.ObjectRef && notBeforeEqual(queued.NotBefore, entry.NotBefore) {
+			return beacon, nil
+		}
+		entry.Created = queued.Created
+	}
+
+	beacon = beacon.DeepCopy()
+	if index >= 0 {
+		beacon.Status.Queue[index] = entry
+	} else {
+		beacon.Status.Queue = append(beacon.Status.Queue, entry)
+	}
+
+	return beacons.UpdateStatus(beacon)
+}
+
+// DequeueBeacon removes the entries of the given owners from the queue of the beacon. If none of
+// the owners are queued, the beacon is returned unchanged.
+func DequeueBeacon(beacon *planv1alpha1.Beacon, beacons plancontrollers.BeaconClient, owners ...string) (*planv1alpha1.Beacon, error) {
+	if beacon == nil {
+		return nil, nil
+	}
+
+	queue := slices.DeleteFunc(slices.Clone(beacon.Status.Queue), func(e planv1alpha1.BeaconQueueEntry) bool {
+		return slices.Contains(owners, e.Owner)
+	})
+	if len(queue) == len(beacon.Status.Queue) {
+		return beacon, nil
+	}
+
+	beacon = beacon.DeepCopy()
+	beacon.Status.Queue = queue
+	if len(queue) == 0 {
+		beacon.Status.Queue = nil
+	}
+
+	return beacons.UpdateStatus(beacon)
+}
+
+// SortedQueue returns the queue of the beacon in the order entries acquire the beacon, ignoring
+// their notBefore: highest priority first, then oldest first. Entries that tie on both keep the
+// order they were enqueued in.
+func SortedQueue(beacon *planv1alpha1.Beacon) []planv1alpha1.BeaconQueueEntry {
+	if beacon == nil {
+		return nil
+	}
+
+	queue := slices.Clone(beacon.Status.Queue)
+	slices.SortStableFunc(queue, func(a, b planv1alpha1.BeaconQueueEntry) int {
+		if a.Priority != b.Priority {
+			return cmp.Compare(b.Priority, a.Priority)
+		}
+		return a.Created.Time.Compare(b.Created.Time)
+	})
+	return queue
+}
+
+// QueuePosition returns the 1-based position of owner in the sorted queue of the beacon, or 0 if
+// owner is not queued.
+func QueuePosition(beacon *planv1alpha1.Beacon, owner string) int {
+	return slices.IndexFunc(SortedQueue(beacon), func(e planv1alpha1.BeaconQueueEntry) bool {
+		return e.Owner == owner
+	}) + 1
+}
+
+// NextInQueue returns the entry that acquires the beacon next at the given time: the first entry
+// of the sorted queue whose notBefore has passed. Returns nil if no entry is eligible.
+func NextInQueue(beacon *planv1alpha1.Beacon, now time.Time) *planv1alpha1.BeaconQueueEntry {
+	for _, entry := range SortedQueue(beacon) {
+		if entry.NotBefore == nil || !entry.NotBefore.Time.After(now) {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// AcquireQueuedBeacon acquires a beacon on behalf of a queued owner. It behaves like AcquireBeacon,
+// except that a free beacon is only handed to desired when it is the entry returned by
+// NextInQueue; desired is removed from the queue in the same update. Returns nil without error
+// when the beacon is owned by another controller or another entry is next in line.
+func AcquireQueuedBeacon(beacon *planv1alpha1.Beacon, beacons plancontrollers.BeaconClient, desired string, now time.Time) (*planv1alpha1.Beacon, error) {
+	if beacon == nil {
+		return nil, nil
+	} else if AuthorizedForBeacon(beacon, desired) {
+		return beacon, nil
+	} else if beacon.Status.Owner != "" {
+		return nil, nil
+	}
+
+	if next := NextInQueue(beacon, now); next == nil || next.Owner != desired {
+		return nil, nil
+	}
+
+	beacon = beacon.DeepCopy()
+	beacon.Status.Owner = desired
+	beacon.Status.Queue = slices.DeleteFunc(beacon.Status.Queue, func(e planv1alpha1.BeaconQueueEntry) bool {
+		return e.Owner == desired
+	})
+	if len(beacon.Status.Queue) == 0 {
+		beacon.Status.Queue = nil
+	}
+
+	return beacons.UpdateStatus(beacon)
+}
+
+func notBeforeEqual(a, b *metav1.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,225 @@
+package plan
+
+import (
+	"testing"
+	"time"
+
+	planv1alpha1 "github.com/rancher/rancher/pkg/plan/api/plan.cattle.io/v1alpha1"
+	plancontrollers "github.com/rancher/rancher/pkg/plan/generated/controllers/plan.cattle.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeBeacons is an in-memory BeaconClient that counts status updates.
+type fakeBeacons struct {
+	plancontrollers.BeaconClient
+
+	updates int
+}
+
+func (f *fakeBeacons) UpdateStatus(beacon *planv1alpha1.Beacon) (*planv1alpha1.Beacon, error) {
+	f.updates++
+	return beacon, nil
+}
+
+var queueEpoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func queueEntry(owner string, priority int32, created int, notBefore *time.Time) planv1alpha1.BeaconQueueEntry {
+	entry := planv1alpha1.BeaconQueueEntry{
+		Owner:    owner,
+		Priority: priority,
+		Created:  metav1.NewTime(queueEpoch.Add(time.Duration(created) * time.Second)),
+	}
+	if notBefore != nil {
+		t := metav1.NewTime(*notBefore)
+		entry.NotBefore = &t
+	}
+	return entry
+}
+
+func queuedBeacon(owner string, entries ...planv1alpha1.BeaconQueueEntry) *planv1alpha1.Beacon {
+	return &planv1alpha1.Beacon{
+		Status: planv1alpha1.BeaconStatus{Owner: owner, Queue: entries},
+	}
+}
+
+func owners(entries []planv1alpha1.BeaconQueueEntry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Owner)
+	}
+	return result
+}
+
+func TestSortedQueue(t *testing.T) {
+	beacon := queuedBeacon("",
+		queueEntry("low-old", 0, 0, nil),
+		queueEntry("high-new", 10, 5, nil),
+		queueEntry("low-new", 0, 3, nil),
+		queueEntry("high-old", 10, 1, nil),
+		queueEntry("low-new-tie", 0, 3, nil),
+	)
+
+	got := owners(SortedQueue(beacon))
+	want := []string{"high-old", "high-new", "low-old", "low-new", "low-new-tie"}
+	if len(got) != len(want) {
+		t.Fatalf("SortedQueue = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("SortedQueue = %v, want %v", got, want)
+		}
+	}
+
+	if pos := QueuePosition(beacon, "low-old"); pos != 3 {
+		t.Errorf("QueuePosition(low-old) = %d, want 3", pos)
+	}
+	if pos := QueuePosition(beacon, "missing"); pos != 0 {
+		t.Errorf("QueuePosition(missing) = %d, want 0", pos)
+	}
+}
+
+func TestNextInQueueSkipsEntriesNotYetEligible(t *testing.T) {
+	later := queueEpoch.Add(time.Hour)
+	beacon := queuedBeacon("",
+		queueEntry("scheduled", 10, 0, &later),
+		queueEntry("ready", 0, 1, nil),
+	)
+
+	if next := NextInQueue(beacon, queueEpoch); next == nil || next.Owner != "ready" {
+		t.Errorf("NextInQueue before notBefore = %v, want ready", next)
+	}
+	if next := NextInQueue(beacon, later); next == nil || next.Owner != "scheduled" {
+		t.Errorf("NextInQueue at notBefore = %v, want scheduled", next)
+	}
+	if next := NextInQueue(queuedBeacon("", queueEntry("scheduled", 0, 0, &later)), queueEpoch); next != nil {
+		t.Errorf("NextInQueue = %v, want nil when no entry is eligible", next)
+	}
+}
+
+func TestEnqueueBeacon(t *testing.T) {
+	beacons := &fakeBeacons{}
+	beacon := queuedBeacon("other")
+
+	beacon, err := EnqueueBeacon(beacon, beacons, queueEntry("a", 0, 5, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(beacon.Status.Queue) != 1 || beacons.updates != 1 {
+		t.Fatalf("expected a single queued entry after one update, got %v after %d updates", beacon.Status.Queue, beacons.updates)
+	}
+
+	if _, err := EnqueueBeacon(beacon, beacons, queueEntry("a", 0, 9, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if beacons.updates != 1 {
+		t.Errorf("re-enqueueing an unchanged entry must not update the beacon")
+	}
+
+	beacon, err = EnqueueBeacon(beacon, beacons, queueEntry("a", 7, 9, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(beacon.Status.Queue) != 1 || beacon.Status.Queue[0].Priority != 7 {
+		t.Fatalf("expected the queued entry to be updated in place, got %v", beacon.Status.Queue)
+	}
+	if !beacon.Status.Queue[0].Created.Time.Equal(queueEpoch.Add(5 * time.Second)) {
+		t.Errorf("re-enqueueing must keep the original created timestamp, got %v", beacon.Status.Queue[0].Created)
+	}
+}
+
+func TestDequeueBeacon(t *testing.T) {
+	beacons := &fakeBeacons{}
+	beacon := queuedBeacon("", queueEntry("a", 0, 0, nil), queueEntry("b", 0, 1, nil))
+
+	if _, err := DequeueBeacon(beacon, beacons, "missing"); err != nil || beacons.updates != 0 {
+		t.Fatalf("dequeueing an owner that is not queued must not update the beacon (err=%v, updates=%d)", err, beacons.updates)
+	}
+
+	updated, err := DequeueBeacon(beacon, beacons, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.Queue != nil {
+		t.Errorf("expected an empty queue, got %v", updated.Status.Queue)
+	}
+	if len(beacon.Status.Queue) != 2 {
+		t.Errorf("DequeueBeacon must not modify its input")
+	}
+}
+
+func TestAcquireQueuedBeacon(t *testing.T) {
+	later := queueEpoch.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		beacon   *planv1alpha1.Beacon
+		desired  string
+		acquired bool
+		queue    []string
+	}{
+		{
+			name:     "head of the queue acquires a free beacon",
+			beacon:   queuedBeacon("", queueEntry("a", 0, 0, nil), queueEntry("b", 0, 1, nil)),
+			desired:  "a",
+			acquired: true,
+			queue:    []string{"b"},
+		},
+		{
+			name:    "later entry waits its turn",
+			beacon:  queuedBeacon("", queueEntry("a", 0, 0, nil), queueEntry("b", 0, 1, nil)),
+			desired: "b",
+		},
+		{
+			name:     "higher priority jumps the queue",
+			beacon:   queuedBeacon("", queueEntry("a", 0, 0, nil), queueEntry("b", 5, 1, nil)),
+			desired:  "b",
+			acquired: true,
+			queue:    []string{"a"},
+		},
+		{
+			name:     "entry passes over a scheduled entry",
+			beacon:   queuedBeacon("", queueEntry("a", 0, 0, &later), queueEntry("b", 0, 1, nil)),
+			desired:  "b",
+			acquired: true,
+			queue:    []string{"a"},
+		},
+		{
+			name:    "owned beacon is not acquired",
+			beacon:  queuedBeacon("other", queueEntry("a", 0, 0, nil)),
+			desired: "a",
+		},
+		{
+			name:     "current owner keeps the beacon",
+			beacon:   queuedBeacon("a", queueEntry("b", 0, 0, nil)),
+			desired:  "a",
+			acquired: true,
+			queue:    []string{"b"},
+		},
+		{
+			name:    "unqueued owner does not acquire a free beacon",
+			beacon:  queuedBeacon(""),
+			desired: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AcquireQueuedBeacon(tt.beacon, &fakeBeacons{}, tt.desired, queueEpoch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.acquired {
+				if got != nil {
+					t.Fatalf("expected the beacon not to be acquired, got owner %q", got.Status.Owner)
+				}
+				return
+			}
+			if got == nil || got.Status.Owner != tt.desired {
+				t.Fatalf("expected the beacon to be owned by %q, got %v", tt.desired, got)
+			}
+			if queue := owners(got.Status.Queue); len(queue) != len(tt.queue) || (len(queue) > 0 && queue[0] != tt.queue[0]) {
+				t.Errorf("queue = %v, want %v", queue, tt.queue)
+			}
+		})
+	}
+}
@@ -3,11 +3,13 @@
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 )
 
 const (
@@ -116,13 +118,30 @@ func getNodeScore(labels map[string]string) int {
 
 // Selector defines the contract for building and compiling label queries.
 type Selector interface {
-	// ToK8sSelectors evaluates the AST and returns a slice of valid K8s selector strings.
+	// ToK8sSelectors evaluates the AST and returns a slice of valid K8s selector strings. A node
+	// that cannot be expressed as a labels.Requirement, e.g. because of an invalid label value,
+	// compiles to labels.Everything(), so the queries return a superset of the matching secrets.
 	ToK8sSelectors() []labels.Selector
+
+	// Matches evaluates the AST against the labels of a single secret.
+	Matches(labels.Labels) bool
+
+	// Native returns true if every node of the AST compiles into a labels.Requirement. The results
+	// of a selector that is not native are filtered with Matches after the fetch.
+	Native() bool
 }
 
 // label is a single key=value match. It produces exactly one underlying labels.Selector.
 type label struct{ key, value string }
 
+// requirement is a single set-based or negated match. It produces exactly one underlying
+// labels.Selector, or labels.Everything() if the requirement is invalid.
+type requirement struct {
+	key      string
+	operator selection.Operator
+	values   []string
+}
+
 // andSelector composes its children as a conjunction. Each underlying selector must match.
 type andSelector struct{ selectors []Selector }
 
@@ -133,6 +152,45 @@ func (l label) ToK8sSelectors() []labels.Selector {
 	return []labels.Selector{labels.SelectorFromSet(labels.Set{l.key: l.value})}
 }
 
+func (l label) Matches(ls labels.Labels) bool {
+	return ls.Has(l.key) && ls.Get(l.key) == l.value
+}
+
+func (l label) Native() bool { return true }
+
+func (r requirement) ToK8sSelectors() []labels.Selector {
+	req, err := labels.NewRequirement(r.key, r.operator, r.values)
+	if err != nil {
+		return []labels.Selector{labels.Everything()}
+	}
+	return []labels.Selector{labels.NewSelector().Add(*req)}
+}
+
+// Matches follows the semantics of labels.Requirement: NotEquals and NotIn also match secrets
+// without the label.
+func (r requirement) Matches(ls labels.Labels) bool {
+	has := ls.Has(r.key)
+	value := ls.Get(r.key)
+	switch r.operator {
+	case selection.NotEquals:
+		return !has || value != r.values[0]
+	case selection.In:
+		return has && slices.Contains(r.values, value)
+	case selection.NotIn:
+		return !has || !slices.Contains(r.values, value)
+	case selection.Exists:
+		return has
+	case selection.DoesNotExist:
+		return !has
+	}
+	return false
+}
+
+func (r requirement) Native() bool {
+	_, err := labels.NewRequirement(r.key, r.operator, r.values)
+	return err == nil
+}
+
 // ToK8sSelectors for AND performs a Cartesian product of all child selectors.
 // Example: AND([A], OR[B, C]) -> ["A,B", "A,C"]
 func (a andSelector) ToK8sSelectors() []labels.Selector {
@@ -155,6 +213,24 @@ func (a andSelector) ToK8sSelectors() []labels.Selector {
 	return result
 }
 
+func (a andSelector) Matches(ls labels.Labels) bool {
+	for _, child := range a.selectors {
+		if !child.Matches(ls) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a andSelector) Native() bool {
+	for _, child := range a.selectors {
+		if !child.Native() {
+			return false
+		}
+	}
+	return true
+}
+
 // ToK8sSelectors for OR simply flattens and concatenates child queries.
 func (o orSelector) ToK8sSelectors() []labels.Selector {
 	var result []labels.Selector
@@ -164,9 +240,54 @@ func (o orSelector) ToK8sSelectors() []labels.Selector {
 	return result
 }
 
+func (o orSelector) Matches(ls labels.Labels) bool {
+	for _, child := range o.selectors {
+		if child.Matches(ls) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o orSelector) Native() bool {
+	for _, child := range o.selectors {
+		if !child.Native() {
+			return false
+		}
+	}
+	return true
+}
+
 // Label returns a Selector that matches a single key=value label.
 func Label(key, value string) Selector { return label{key: key, value: value} }
 
+// NotLabel returns a Selector that matches secrets whose key label is not set to value, including
+// secrets without the label.
+func NotLabel(key, value string) Selector {
+	return requirement{key: key, operator: selection.NotEquals, values: []string{value}}
+}
+
+// In returns a Selector that matches secrets whose key label is set to any of values.
+func In(key string, values ...string) Selector {
+	return requirement{key: key, operator: selection.In, values: values}
+}
+
+// NotIn returns a Selector that matches secrets whose key label is set to none of values, including
+// secrets without the label.
+func NotIn(key string, values ...string) Selector {
+	return requirement{key: key, operator: selection.NotIn, values: values}
+}
+
+// Exists returns a Selector that matches secrets carrying the key label, whatever its value.
+func Exists(key string) Selector {
+	return requirement{key: key, operator: selection.Exists}
+}
+
+// DoesNotExist returns a Selector that matches secrets without the key label.
+func DoesNotExist(key string) Selector {
+	return requirement{key: key, operator: selection.DoesNotExist}
+}
+
 // And returns a Selector that matches the conjunction of all provided selectors.
 // At the cache level this expands to one query per branch of any nested OR.
 func And(selectors ...Selector) Selector { return andSelector{selectors: selectors} }
@@ -190,6 +311,7 @@ func Or(selectors ...Selector) Selector { return orSelector{selectors: selectors
 // Collector is not safe for concurrent use — build one per query.
 type Collector struct {
 	client      SecretClient
+	cache       SecretCache
 	namespace   string
 	clusterName string
 	selectors   []Selector
@@ -225,6 +347,11 @@ func NewCollector(client SecretClient, cluster ClusterRef, namespace string) *Co
 // To express disjunctions, wrap with Or(...):
 //
 //	c.WithLabels(planapi.Or(planapi.Label(...), planapi.Label(...)))
+//
+// Prefer the set-based and negated selectors over an equivalent WithFilter, as they are pushed down
+// to the cache:
+//
+//	c.WithLabels(planapi.Exists(capr.EtcdRoleLabel), planapi.NotLabel(capr.CattleOSLabel, "windows"))
 func (c *Collector) WithLabels(selectors ...Selector) *Collector {
 	c.selectors = append(c.selectors, selectors...)
 	return c
@@ -304,10 +431,11 @@ func IsTransient(err error) bool {
 //
 // The cluster name auto-filter and the WithLabels selectors are combined into a single AND.
 // Any nested OR is expanded into multiple cache queries and the results are deduplicated by UID
-// before filtering.
+// before filtering. Selectors that are not Native are evaluated again before the WithFilter filters
+// run.
 func (c *Collector) Collect() ([]*corev1.Secret, error) {
-	if c.client == nil {
-		return nil, errors.New("plan: Collector has no SecretCache")
+	if c.client == nil && c.cache == nil {
+		return nil, errors.New("plan: Collector has no SecretClient or SecretCache")
 	}
 
 	root := c.composedSelector()
@@ -316,21 +444,21 @@ func (c *Collector) Collect() ([]*corev1.Secret, error) {
 	if root != nil {
 		queries = root.ToK8sSelectors()
 	}
+	// Selectors that cannot be pushed down to the cache in full are evaluated after the fetch.
+	filters := c.filters
+	if root != nil && !root.Native() {
+		filters = append([]FilterFunc{func(s *corev1.Secret) bool {
+			return root.Matches(labels.Set(s.Labels))
+		}}, filters...)
+	}
 
 	var secrets []*corev1.Secret
 	for _, sel := range queries {
-		list, err := c.client.List(c.namespace, metav1.ListOptions{
-			LabelSelector: sel.String(),
-			FieldSelector: fmt.Sprintf("type=%s", SecretTypeMachinePlan),
-		})
+		fetched, err := c.fetch(sel)
 		if err != nil {
 			return nil, err
-		} else if list == nil {
-			continue
-		}
-		for secret := range list.Items {
-			secrets = append(secrets, &list.Items[secret])
 		}
+		secrets = append(secrets, fetched...)
 	}
 
 	// Deduplicate by UID. Overlapping OR branches can surface the same secret more than once.
@@ -352,7 +480,7 @@ func (c *Collector) Collect() ([]*corev1.Secret, error) {
 	filtered := make([]*corev1.Secret, 0, len(uniqueSecrets))
 	for _, s := range uniqueSecrets {
 		keep := true
-		for _, f := range c.filters {
+		for _, f := range filters {
 			if !f(s) {
 				keep = false
 				break
@@ -379,6 +507,27 @@ func (c *Collector) Collect() ([]*corev1.Secret, error) {
 	return result, nil
 }
 
+// fetch returns the machine-plan secrets matching a single compiled query, from the cache of a
+// Collector built by NewCachedCollector, or through a List call otherwise.
+func (c *Collector) fetch(sel labels.Selector) ([]*corev1.Secret, error) {
+	if c.cache != nil {
+		return c.fetchCached(sel)
+	}
+
+	list, err := c.client.List(c.namespace, metav1.ListOptions{
+		LabelSelector: sel.String(),
+		FieldSelector: fmt.Sprintf("type=%s", SecretTypeMachinePlan),
+	})
+	if err != nil || list == nil {
+		return nil, err
+	}
+	secrets := make([]*corev1.Secret, 0, len(list.Items))
+	for secret := range list.Items {
+		secrets = append(secrets, &list.Items[secret])
+	}
+	return secrets, nil
+}
+
 // composedSelector builds the root Selector for the query: the auto cluster-name filter AND any
 // WithLabels selectors. Returns nil when no filters are configured (caller treats nil as
 // "match-everything" via labels.Everything()).
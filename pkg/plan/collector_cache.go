@@ -0,0 +1,123 @@
+package plan
+
+import (
+	"slices"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// MachinePlanByClusterIndex indexes machine-plan secrets by "<namespace>/<cluster name>".
+	MachinePlanByClusterIndex = "plan.cattle.io/machine-plan-by-cluster"
+
+	// MachinePlanByRoleIndex indexes machine-plan secrets by "<namespace>/<cluster name>/<label>"
+	// for every role label set to "true" on the secret.
+	MachinePlanByRoleIndex = "plan.cattle.io/machine-plan-by-role"
+)
+
+// indexedRoleLabels are the labels indexed by MachinePlanByRoleIndex.
+var indexedRoleLabels = []string{labelEtcd, labelControl, labelWorker, labelInitNode}
+
+// SecretCache defines the contract for looking up secrets in an informer cache. The generated
+// wrangler SecretCache satisfies it once RegisterIndexers has been called on it.
+type SecretCache interface {
+	List(namespace string, selector labels.Selector) ([]*corev1.Secret, error)
+	GetByIndex(indexName, key string) ([]*corev1.Secret, error)
+}
+
+// SecretIndexer is the subset of the generated wrangler SecretCache RegisterIndexers needs.
+type SecretIndexer interface {
+	AddIndexer(indexName string, indexer generic.Indexer[*corev1.Secret])
+}
+
+// RegisterIndexers adds the MachinePlanByClusterIndex and MachinePlanByRoleIndex indexers to the
+// secret cache. It must be called once, before the cache is started, for the controllers building
+// collectors with NewCachedCollector.
+func RegisterIndexers(cache SecretIndexer) {
+	cache.AddIndexer(MachinePlanByClusterIndex, machinePlanByCluster)
+	cache.AddIndexer(MachinePlanByRoleIndex, machinePlanByRole)
+}
+
+func machinePlanByCluster(secret *corev1.Secret) ([]string, error) {
+	if secret.Type != SecretTypeMachinePlan || secret.Labels[labelClusterName] == "" {
+		return nil, nil
+	}
+	return []string{secret.Namespace + "/" + secret.Labels[labelClusterName]}, nil
+}
+
+func machinePlanByRole(secret *corev1.Secret) ([]string, error) {
+	if secret.Type != SecretTypeMachinePlan || secret.Labels[labelClusterName] == "" {
+		return nil, nil
+	}
+	var keys []string
+	for _, role := range indexedRoleLabels {
+		if secret.Labels[role] == "true" {
+			keys = append(keys, secret.Namespace+"/"+secret.Labels[labelClusterName]+"/"+role)
+		}
+	}
+	return keys, nil
+}
+
+// NewCachedCollector creates a Collector like NewCollector, but backed by a secret cache with the
+// indexers of RegisterIndexers instead of a List call per query. A query of a cluster is answered
+// from the MachinePlanByRoleIndex when it requires a role label to be "true", and from the
+// MachinePlanByClusterIndex otherwise, so a lookup only visits the secrets of the cluster and does
+// not copy them. A Collector without a cluster lists the namespace from the cache.
+//
+// The returned secrets are the cached objects: callers must not modify them, and should DeepCopy
+// a secret before updating it. Store.AssignPlan and Store.CancelPlan copy the secrets they update.
+func NewCachedCollector(cache SecretCache, cluster ClusterRef, namespace string) *Collector {
+	c := NewCollector(nil, cluster, namespace)
+	c.cache = cache
+	return c
+}
+
+// fetchCached returns the machine-plan secrets matching a single compiled query from the cache.
+func (c *Collector) fetchCached(sel labels.Selector) ([]*corev1.Secret, error) {
+	var (
+		candidates []*corev1.Secret
+		err        error
+	)
+	if c.clusterName == "" {
+		candidates, err = c.cache.List(c.namespace, sel)
+	} else if role := indexedRole(sel); role != "" {
+		candidates, err = c.cache.GetByIndex(MachinePlanByRoleIndex, c.namespace+"/"+c.clusterName+"/"+role)
+	} else {
+		candidates, err = c.cache.GetByIndex(MachinePlanByClusterIndex, c.namespace+"/"+c.clusterName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]*corev1.Secret, 0, len(candidates))
+	for _, secret := range candidates {
+		if secret.Type == SecretTypeMachinePlan && sel.Matches(labels.Set(secret.Labels)) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+// indexedRole returns the first role label of indexedRoleLabels the query requires to be "true", or
+// an empty string if there is none.
+func indexedRole(sel labels.Selector) string {
+	requirements, _ := sel.Requirements()
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+		case selection.In:
+			if requirement.Values().Len() != 1 {
+				continue
+			}
+		default:
+			continue
+		}
+		if requirement.Values().Has("true") && slices.Contains(indexedRoleLabels, requirement.Key()) {
+			return requirement.Key()
+		}
+	}
+	return ""
+}
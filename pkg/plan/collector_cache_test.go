@@ -0,0 +1,208 @@
+package plan
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newIndexedCache returns a wrangler secret cache with the plan indexers registered, holding secrets.
+func newIndexedCache(tb testing.TB, secrets ...*corev1.Secret) *generic.Cache[*corev1.Secret] {
+	tb.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c := generic.NewCache[*corev1.Secret](indexer, corev1.Resource("secrets"))
+	RegisterIndexers(c)
+	for _, secret := range secrets {
+		if err := indexer.Add(secret); err != nil {
+			tb.Fatalf("adding %s: %v", secret.Name, err)
+		}
+	}
+	return c
+}
+
+func sortedNames(s []*corev1.Secret) []string {
+	names := secretNames(s)
+	sort.Strings(names)
+	return names
+}
+
+func TestCachedCollectorMatchesListCollector(t *testing.T) {
+	t.Parallel()
+
+	bootstrap := newSecret("bootstrap", map[string]string{labelClusterName: "mine", labelEtcd: "true"})
+	bootstrap.Type = corev1.SecretTypeOpaque
+	secrets := []*corev1.Secret{
+		newSecret("etcd-init", map[string]string{labelClusterName: "mine", labelEtcd: "true", labelInitNode: "true"}),
+		newSecret("etcd", map[string]string{labelClusterName: "mine", labelEtcd: "true"}),
+		newSecret("control", map[string]string{labelClusterName: "mine", labelControl: "true"}),
+		newSecret("worker", map[string]string{labelClusterName: "mine", labelWorker: "true"}),
+		newSecret("windows-worker", map[string]string{labelClusterName: "mine", labelWorker: "true", labelOS: osWindows}),
+		newSecret("foreign-etcd", map[string]string{labelClusterName: "other", labelEtcd: "true"}),
+		newSecret("orphan", nil),
+		bootstrap,
+	}
+
+	tests := []struct {
+		name      string
+		cluster   ClusterRef
+		selectors []Selector
+		want      []string
+	}{
+		{
+			name:    "cluster",
+			cluster: fakeCluster{name: "mine"},
+			want:    []string{"control", "etcd", "etcd-init", "windows-worker", "worker"},
+		},
+		{
+			name:      "role",
+			cluster:   fakeCluster{name: "mine"},
+			selectors: []Selector{Label(labelEtcd, "true")},
+			want:      []string{"etcd", "etcd-init"},
+		},
+		{
+			name:      "role and negation",
+			cluster:   fakeCluster{name: "mine"},
+			selectors: []Selector{Label(labelEtcd, "true"), NotLabel(labelInitNode, "true")},
+			want:      []string{"etcd"},
+		},
+		{
+			name:      "or of roles",
+			cluster:   fakeCluster{name: "mine"},
+			selectors: []Selector{Or(Label(labelEtcd, "true"), Label(labelControl, "true"))},
+			want:      []string{"control", "etcd", "etcd-init"},
+		},
+		{
+			name:      "set based",
+			cluster:   fakeCluster{name: "mine"},
+			selectors: []Selector{Exists(labelWorker), NotIn(labelOS, osWindows)},
+			want:      []string{"worker"},
+		},
+		{
+			name:      "not native",
+			cluster:   fakeCluster{name: "mine"},
+			selectors: []Selector{In("not a valid key!", "x")},
+			want:      []string{},
+		},
+		{
+			name:      "no cluster",
+			selectors: []Selector{Label(labelEtcd, "true")},
+			want:      []string{"etcd", "etcd-init", "foreign-etcd"},
+		},
+	}
+
+	cached := newIndexedCache(t, secrets...)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, err := NewCollector(&fakeClient{secrets: secrets}, tt.cluster, "fleet-default").WithLabels(tt.selectors...).Collect()
+			if err != nil {
+				t.Fatalf("list Collect: %v", err)
+			}
+			fromCache, err := NewCachedCollector(cached, tt.cluster, "fleet-default").WithLabels(tt.selectors...).Collect()
+			if err != nil {
+				t.Fatalf("cached Collect: %v", err)
+			}
+
+			// The List-based fake ignores the type field selector, so only compare machine plans.
+			var listedPlans []*corev1.Secret
+			for _, secret := range listed {
+				if secret.Type == SecretTypeMachinePlan {
+					listedPlans = append(listedPlans, secret)
+				}
+			}
+			if got := sortedNames(fromCache); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cached Collect = %v, want %v", got, tt.want)
+			}
+			if got, want := sortedNames(fromCache), sortedNames(listedPlans); !reflect.DeepEqual(got, want) {
+				t.Errorf("cached Collect = %v, List Collect = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCachedCollectorUsesIndexes(t *testing.T) {
+	t.Parallel()
+
+	etcd := newSecret("etcd", map[string]string{labelClusterName: "mine", labelEtcd: "true", labelInitNode: "true"})
+	other := newSecret("other", map[string]string{labelClusterName: "mine", labelWorker: "false"})
+	bootstrap := newSecret("bootstrap", map[string]string{labelClusterName: "mine"})
+	bootstrap.Type = corev1.SecretTypeOpaque
+
+	keys, _ := machinePlanByCluster(etcd)
+	if want := []string{"fleet-default/mine"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("by cluster = %v, want %v", keys, want)
+	}
+	keys, _ = machinePlanByRole(etcd)
+	if want := []string{"fleet-default/mine/" + labelEtcd, "fleet-default/mine/" + labelInitNode}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("by role = %v, want %v", keys, want)
+	}
+	if keys, _ = machinePlanByRole(other); len(keys) != 0 {
+		t.Errorf("by role of a secret without roles = %v, want none", keys)
+	}
+	if keys, _ = machinePlanByCluster(bootstrap); len(keys) != 0 {
+		t.Errorf("by cluster of a non machine-plan secret = %v, want none", keys)
+	}
+
+	for _, tt := range []struct {
+		sel  Selector
+		want string
+	}{
+		{sel: Label(labelEtcd, "true"), want: labelEtcd},
+		{sel: In(labelWorker, "true"), want: labelWorker},
+		{sel: In(labelWorker, "true", "false")},
+		{sel: Label(labelEtcd, "false")},
+		{sel: NotLabel(labelEtcd, "true")},
+		{sel: And(Label(labelOS, osWindows), Exists(labelEtcd))},
+	} {
+		for _, k8s := range tt.sel.ToK8sSelectors() {
+			if got := indexedRole(k8s); got != tt.want {
+				t.Errorf("indexedRole(%s) = %q, want %q", k8s, got, tt.want)
+			}
+		}
+	}
+}
+
+// benchmarkSecrets returns machine-plan secrets of clusters nodes each, a third of them etcd nodes.
+func benchmarkSecrets(clusters, nodes int) []*corev1.Secret {
+	secrets := make([]*corev1.Secret, 0, clusters*nodes)
+	for c := 0; c < clusters; c++ {
+		for n := 0; n < nodes; n++ {
+			lbls := map[string]string{labelClusterName: fmt.Sprintf("cluster-%d", c), labelWorker: "true"}
+			if n%3 == 0 {
+				lbls = map[string]string{labelClusterName: fmt.Sprintf("cluster-%d", c), labelEtcd: "true"}
+			}
+			secrets = append(secrets, newSecret(fmt.Sprintf("cluster-%d-node-%d", c, n), lbls))
+		}
+	}
+	return secrets
+}
+
+func BenchmarkCollect(b *testing.B) {
+	secrets := benchmarkSecrets(100, 10)
+	cluster := fakeCluster{name: "cluster-42"}
+	etcd := Label(labelEtcd, "true")
+
+	b.Run("List", func(b *testing.B) {
+		client := &fakeClient{secrets: secrets}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			client.calls = client.calls[:0]
+			if _, err := NewCollector(client, cluster, "fleet-default").WithLabels(etcd).Collect(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Cache", func(b *testing.B) {
+		cached := newIndexedCache(b, secrets...)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewCachedCollector(cached, cluster, "fleet-default").WithLabels(etcd).Collect(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -330,6 +330,118 @@ func TestOrSelectorDeduplicatedByUID(t *testing.T) {
 	}
 }
 
+func TestSetBasedSelectorsArePushedDown(t *testing.T) {
+	t.Parallel()
+
+	cache := &fakeClient{
+		secrets: []*corev1.Secret{
+			newSecret("etcd", map[string]string{labelClusterName: "mine", labelEtcd: "true", labelOS: "linux"}),
+			newSecret("cp", map[string]string{labelClusterName: "mine", labelControl: "true"}),
+			newSecret("windows-worker", map[string]string{labelClusterName: "mine", labelWorker: "true", labelOS: "windows"}),
+			newSecret("init", map[string]string{labelClusterName: "mine", labelEtcd: "true", labelInitNode: "true"}),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		query    string
+		want     []string
+	}{
+		{
+			name:     "not label",
+			selector: NotLabel(labelOS, "windows"),
+			query:    "cattle.io/os!=windows,rke.cattle.io/cluster-name=mine",
+			want:     []string{"cp", "etcd", "init"},
+		},
+		{
+			name:     "in",
+			selector: In(labelOS, "linux", "windows"),
+			query:    "cattle.io/os in (linux,windows),rke.cattle.io/cluster-name=mine",
+			want:     []string{"etcd", "windows-worker"},
+		},
+		{
+			name:     "not in",
+			selector: NotIn(labelOS, "linux", "windows"),
+			query:    "cattle.io/os notin (linux,windows),rke.cattle.io/cluster-name=mine",
+			want:     []string{"cp", "init"},
+		},
+		{
+			name:     "exists",
+			selector: Exists(labelEtcd),
+			query:    "rke.cattle.io/cluster-name=mine,rke.cattle.io/etcd-role",
+			want:     []string{"etcd", "init"},
+		},
+		{
+			name:     "does not exist",
+			selector: And(Exists(labelEtcd), DoesNotExist(labelInitNode)),
+			query:    "rke.cattle.io/cluster-name=mine,rke.cattle.io/etcd-role,!rke.cattle.io/init-node",
+			want:     []string{"etcd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &fakeClient{secrets: cache.secrets}
+			got, err := NewCollector(cache, fakeCluster{name: "mine"}, "fleet-default").
+				WithLabels(tt.selector).
+				Collect()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			names := secretNames(got)
+			sort.Strings(names)
+			if !equalStrings(names, tt.want) {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+			if want := []string{"fleet-default|" + tt.query}; !equalStrings(cache.calls, want) {
+				t.Errorf("got queries %v, want %v", cache.calls, want)
+			}
+		})
+	}
+}
+
+func TestInvalidSelectorFallsBackToFilter(t *testing.T) {
+	t.Parallel()
+
+	cache := &fakeClient{
+		secrets: []*corev1.Secret{
+			newSecret("etcd", map[string]string{labelClusterName: "mine", labelEtcd: "true"}),
+			newSecret("cp", map[string]string{labelClusterName: "mine", labelControl: "true"}),
+			newSecret("worker", map[string]string{labelClusterName: "mine", labelWorker: "true"}),
+		},
+	}
+
+	// An empty In set and a value that is not a valid label value cannot be compiled into a
+	// requirement, so their branches fetch the whole cluster and are filtered afterwards.
+	selector := Or(In(labelEtcd), Label(labelControl, "true"), NotLabel(labelWorker, "not a label value"))
+	if selector.Native() {
+		t.Fatal("expected the selector not to be native")
+	}
+
+	got, err := NewCollector(cache, fakeCluster{name: "mine"}, "fleet-default").
+		WithLabels(selector).
+		Collect()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := secretNames(got)
+	sort.Strings(names)
+	if want := []string{"cp", "etcd", "worker"}; !equalStrings(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	got, err = NewCollector(cache, fakeCluster{name: "mine"}, "fleet-default").
+		WithLabels(Or(In(labelEtcd), Label(labelControl, "true"))).
+		Collect()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"cp"}; !equalStrings(secretNames(got), want) {
+		t.Errorf("got %v, want %v", secretNames(got), want)
+	}
+}
+
 func TestValidatorRunsAfterFilteringAndSorting(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,271 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeType is the kind of change made to a file, instruction or probe between two plans.
+type ChangeType string
+
+const (
+	Added   ChangeType = "Added"
+	Removed ChangeType = "Removed"
+	Changed ChangeType = "Changed"
+)
+
+// FileChange is a change to a file between two plans, identified by its path.
+type FileChange struct {
+	Path string
+	Type ChangeType
+
+	// ContentChanged, PermissionsChanged and OwnerChanged describe what changed about a Changed
+	// file. FlagsChanged reports a change of its Directory, Action, Dynamic or Minor fields.
+	ContentChanged     bool
+	PermissionsChanged bool
+	OwnerChanged       bool
+	FlagsChanged       bool
+
+	// Minor and Dynamic are the flags of the file in the new plan, or in the old plan for a
+	// Removed file.
+	Minor   bool
+	Dynamic bool
+}
+
+// EntryChange is a change to an instruction or probe between two plans, identified by its name.
+// Unnamed instructions are identified by their index, e.g. "[2]".
+type EntryChange struct {
+	Name string
+	Type ChangeType
+}
+
+// PlanDiff is the difference between two plans. Every list is sorted by path or name. The order of
+// the instructions is not compared.
+type PlanDiff struct {
+	Files                []FileChange
+	Instructions         []EntryChange
+	PeriodicInstructions []EntryChange
+	Probes               []EntryChange
+}
+
+// Diff returns the difference between the old and new plan.
+func Diff(old, new Plan) PlanDiff {
+	return PlanDiff{
+		Files:                diffFiles(old.Files, new.Files),
+		Instructions:         diffEntries(oneTimeEntries(old.OneTimeInstructions), oneTimeEntries(new.OneTimeInstructions)),
+		PeriodicInstructions: diffEntries(periodicEntries(old.PeriodicInstructions), periodicEntries(new.PeriodicInstructions)),
+		Probes:               diffEntries(probeEntries(old.Probes), probeEntries(new.Probes)),
+	}
+}
+
+// Empty returns true if the plans do not differ.
+func (d PlanDiff) Empty() bool {
+	return len(d.Files) == 0 && len(d.Instructions) == 0 && len(d.PeriodicInstructions) == 0 && len(d.Probes) == 0
+}
+
+// Minor returns true if the plans differ only in files flagged Minor, or in the permissions, owner
+// or flags of files whose content is unchanged. This matches the CAPR planner, which does not drain
+// a node for a minor plan change.
+func (d PlanDiff) Minor() bool {
+	if d.Empty() || len(d.Instructions) > 0 || len(d.PeriodicInstructions) > 0 || len(d.Probes) > 0 {
+		return false
+	}
+	for _, file := range d.Files {
+		if !file.Minor && (file.Type != Changed || file.ContentChanged) {
+			return false
+		}
+	}
+	return true
+}
+
+// DynamicOnly returns true if the plans differ only in files flagged Dynamic. The CAPR planner
+// leaves Dynamic files out of the restart and drain stamps of a node.
+func (d PlanDiff) DynamicOnly() bool {
+	if d.Empty() || len(d.Instructions) > 0 || len(d.PeriodicInstructions) > 0 || len(d.Probes) > 0 {
+		return false
+	}
+	for _, file := range d.Files {
+		if !file.Dynamic {
+			return false
+		}
+	}
+	return true
+}
+
+// String summarizes the diff on a single line, for logs and condition messages, e.g.
+// "files: +/etc/a (minor), ~/etc/b (content, permissions); instructions: -install".
+func (d PlanDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	var sections []string
+	if len(d.Files) > 0 {
+		files := make([]string, 0, len(d.Files))
+		for _, file := range d.Files {
+			files = append(files, file.String())
+		}
+		sections = append(sections, "files: "+strings.Join(files, ", "))
+	}
+	for _, section := range []struct {
+		name    string
+		changes []EntryChange
+	}{
+		{"instructions", d.Instructions},
+		{"periodic instructions", d.PeriodicInstructions},
+		{"probes", d.Probes},
+	} {
+		if len(section.changes) == 0 {
+			continue
+		}
+		entries := make([]string, 0, len(section.changes))
+		for _, change := range section.changes {
+			entries = append(entries, changeSymbol(change.Type)+change.Name)
+		}
+		sections = append(sections, section.name+": "+strings.Join(entries, ", "))
+	}
+	return strings.Join(sections, "; ")
+}
+
+func (c FileChange) String() string {
+	var details []string
+	if c.ContentChanged {
+		details = append(details, "content")
+	}
+	if c.PermissionsChanged {
+		details = append(details, "permissions")
+	}
+	if c.OwnerChanged {
+		details = append(details, "owner")
+	}
+	if c.FlagsChanged {
+		details = append(details, "flags")
+	}
+	if c.Minor {
+		details = append(details, "minor")
+	}
+	if c.Dynamic {
+		details = append(details, "dynamic")
+	}
+
+	s := changeSymbol(c.Type) + c.Path
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
+
+func changeSymbol(t ChangeType) string {
+	switch t {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func diffFiles(old, new []File) []FileChange {
+	oldFiles := make(map[string]File, len(old))
+	for _, file := range old {
+		oldFiles[file.Path] = file
+	}
+	newFiles := make(map[string]File, len(new))
+	for _, file := range new {
+		newFiles[file.Path] = file
+	}
+
+	var result []FileChange
+	for path, newFile := range newFiles {
+		oldFile, ok := oldFiles[path]
+		if !ok {
+			result = append(result, FileChange{Path: path, Type: Added, Minor: newFile.Minor, Dynamic: newFile.Dynamic})
+			continue
+		}
+		change := FileChange{
+			Path:               path,
+			Type:               Changed,
+			ContentChanged:     oldFile.Content != newFile.Content,
+			PermissionsChanged: oldFile.Permissions != newFile.Permissions,
+			OwnerChanged:       oldFile.UID != newFile.UID || oldFile.GID != newFile.GID,
+			FlagsChanged: oldFile.Directory != newFile.Directory || oldFile.Action != newFile.Action ||
+				oldFile.Dynamic != newFile.Dynamic || oldFile.Minor != newFile.Minor,
+			Minor:   newFile.Minor,
+			Dynamic: newFile.Dynamic,
+		}
+		if change.ContentChanged || change.PermissionsChanged || change.OwnerChanged || change.FlagsChanged {
+			result = append(result, change)
+		}
+	}
+	for path, oldFile := range oldFiles {
+		if _, ok := newFiles[path]; !ok {
+			result = append(result, FileChange{Path: path, Type: Removed, Minor: oldFile.Minor, Dynamic: oldFile.Dynamic})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// diffEntries compares the JSON encoded entries of two plans by name, so that entries differing only
+// in ways lost when the plan is stored, e.g. nil and empty slices, are equal.
+func diffEntries(old, new map[string][]byte) []EntryChange {
+	var result []EntryChange
+	for name, newEntry := range new {
+		oldEntry, ok := old[name]
+		switch {
+		case !ok:
+			result = append(result, EntryChange{Name: name, Type: Added})
+		case !bytes.Equal(oldEntry, newEntry):
+			result = append(result, EntryChange{Name: name, Type: Changed})
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			result = append(result, EntryChange{Name: name, Type: Removed})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// entryName returns the name an instruction is identified by in a PlanDiff.
+func entryName(name string, index int) string {
+	if name == "" {
+		return fmt.Sprintf("[%d]", index)
+	}
+	return name
+}
+
+func oneTimeEntries(instructions []OneTimeInstruction) map[string][]byte {
+	result := make(map[string][]byte, len(instructions))
+	for i, instruction := range instructions {
+		result[entryName(instruction.Name, i)], _ = json.Marshal(instruction)
+	}
+	return result
+}
+
+func periodicEntries(instructions []PeriodicInstruction) map[string][]byte {
+	result := make(map[string][]byte, len(instructions))
+	for i, instruction := range instructions {
+		result[entryName(instruction.Name, i)], _ = json.Marshal(instruction)
+	}
+	return result
+}
+
+func probeEntries(probes map[string]Probe) map[string][]byte {
+	result := make(map[string][]byte, len(probes))
+	for name, probe := range probes {
+		result[name], _ = json.Marshal(probe)
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package plan
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func diffBasePlan() Plan {
+	return Plan{
+		Files: []File{
+			{Path: "/etc/rancher/rke2/config.yaml", Content: "YQ==", Permissions: "0600"},
+			{Path: "/var/lib/rancher/rke2/server/manifests/rancher/addon.yaml", Content: "Yg==", Minor: true},
+			{Path: "/var/lib/rancher/capr/idempotence/idempotent.sh", Content: "Yw==", Dynamic: true},
+		},
+		OneTimeInstructions: []OneTimeInstruction{
+			{CommonInstruction: CommonInstruction{Name: "install", Command: "sh", Args: []string{}}},
+			{CommonInstruction: CommonInstruction{Command: "true"}},
+		},
+		PeriodicInstructions: []PeriodicInstruction{
+			{CommonInstruction: CommonInstruction{Name: "status", Command: "rke2"}},
+		},
+		Probes: map[string]Probe{
+			"kubelet": {HTTPGetAction: &HTTPGetAction{URL: "https://127.0.0.1:10248/healthz"}},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutate      func(p *Plan)
+		want        PlanDiff
+		minor       bool
+		dynamicOnly bool
+		summary     string
+	}{
+		{
+			name:    "no changes",
+			mutate:  func(p *Plan) {},
+			summary: "no changes",
+		},
+		{
+			name: "major file changes",
+			mutate: func(p *Plan) {
+				p.Files[0].Content = "ZA=="
+				p.Files[0].Permissions = "0644"
+				p.Files = append(p.Files, File{Path: "/etc/rancher/rke2/registries.yaml"})
+			},
+			want: PlanDiff{Files: []FileChange{
+				{Path: "/etc/rancher/rke2/config.yaml", Type: Changed, ContentChanged: true, PermissionsChanged: true},
+				{Path: "/etc/rancher/rke2/registries.yaml", Type: Added},
+			}},
+			summary: "files: ~/etc/rancher/rke2/config.yaml (content, permissions), +/etc/rancher/rke2/registries.yaml",
+		},
+		{
+			name: "minor file changes",
+			mutate: func(p *Plan) {
+				p.Files[1].Content = "ZQ=="
+				p.Files[0].UID = 1000
+			},
+			want: PlanDiff{Files: []FileChange{
+				{Path: "/etc/rancher/rke2/config.yaml", Type: Changed, OwnerChanged: true},
+				{Path: "/var/lib/rancher/rke2/server/manifests/rancher/addon.yaml", Type: Changed, ContentChanged: true, Minor: true},
+			}},
+			minor:   true,
+			summary: "files: ~/etc/rancher/rke2/config.yaml (owner), ~/var/lib/rancher/rke2/server/manifests/rancher/addon.yaml (content, minor)",
+		},
+		{
+			name: "dynamic file removed",
+			mutate: func(p *Plan) {
+				p.Files = p.Files[:2]
+			},
+			want: PlanDiff{Files: []FileChange{
+				{Path: "/var/lib/rancher/capr/idempotence/idempotent.sh", Type: Removed, Dynamic: true},
+			}},
+			dynamicOnly: true,
+			summary:     "files: -/var/lib/rancher/capr/idempotence/idempotent.sh (dynamic)",
+		},
+		{
+			name: "instruction and probe changes",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[0].Args = []string{"-c", "install.sh"}
+				p.OneTimeInstructions[1].Command = "false"
+				p.PeriodicInstructions = nil
+				p.Probes["etcd"] = Probe{TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2379"}}
+			},
+			want: PlanDiff{
+				Instructions:         []EntryChange{{Name: "[1]", Type: Changed}, {Name: "install", Type: Changed}},
+				PeriodicInstructions: []EntryChange{{Name: "status", Type: Removed}},
+				Probes:               []EntryChange{{Name: "etcd", Type: Added}},
+			},
+			summary: "instructions: ~[1], ~install; periodic instructions: -status; probes: +etcd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := diffBasePlan()
+			tt.mutate(&p)
+
+			diff := Diff(diffBasePlan(), p)
+			if !reflect.DeepEqual(diff, tt.want) {
+				t.Errorf("unexpected diff:\n got %+v\nwant %+v", diff, tt.want)
+			}
+			if diff.Minor() != tt.minor {
+				t.Errorf("expected Minor() to be %v", tt.minor)
+			}
+			if diff.DynamicOnly() != tt.dynamicOnly {
+				t.Errorf("expected DynamicOnly() to be %v", tt.dynamicOnly)
+			}
+			if diff.String() != tt.summary {
+				t.Errorf("unexpected summary:\n got %s\nwant %s", diff.String(), tt.summary)
+			}
+		})
+	}
+}
+
+func TestStoreDiff(t *testing.T) {
+	store := NewStore(nil)
+
+	t.Run("stored plan", func(t *testing.T) {
+		raw, err := json.Marshal(diffBasePlan())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The stored plan drops the empty args of the install instruction, which must not count as a
+		// change.
+		diff, err := store.Diff(&corev1.Secret{Data: map[string][]byte{"plan": raw}}, diffBasePlan())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !diff.Empty() {
+			t.Errorf("expected no changes, got %s", diff)
+		}
+	})
+
+	t.Run("no stored plan", func(t *testing.T) {
+		diff, err := store.Diff(&corev1.Secret{}, diffBasePlan())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(diff.Files) != 3 || len(diff.Instructions) != 2 || len(diff.PeriodicInstructions) != 1 || len(diff.Probes) != 1 {
+			t.Errorf("expected everything to be added, got %s", diff)
+		}
+	})
+
+	t.Run("malformed stored plan", func(t *testing.T) {
+		if _, err := store.Diff(&corev1.Secret{Data: map[string][]byte{"plan": []byte("{")}}, diffBasePlan()); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestAssignPlan_Diff(t *testing.T) {
+	raw, err := json.Marshal(diffBasePlan())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next := diffBasePlan()
+	next.OneTimeInstructions[0].Args = []string{"--upgrade"}
+
+	t.Run("replaced plan", func(t *testing.T) {
+		secret := &corev1.Secret{Data: map[string][]byte{"plan": raw}}
+		status, err := NewStore(&updatingSecrets{}).AssignPlan(secret, &next, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.Diff == nil || status.Diff.String() != "instructions: ~install" {
+			t.Fatalf("unexpected diff: %v", status.Diff)
+		}
+		if got := status.Secret.Annotations[PlanDiffAnnotation]; got != status.Diff.String() {
+			t.Errorf("expected the diff to be recorded on the secret, got %q", got)
+		}
+	})
+
+	t.Run("first plan", func(t *testing.T) {
+		status, err := NewStore(&updatingSecrets{}).AssignPlan(&corev1.Secret{}, &next, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if status.Diff != nil {
+			t.Errorf("expected no diff, got %s", status.Diff)
+		}
+		if _, ok := status.Secret.Annotations[PlanDiffAnnotation]; ok {
+			t.Error("expected no diff to be recorded on the secret")
+		}
+	})
+}
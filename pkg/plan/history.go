@@ -0,0 +1,244 @@
+package plan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretTypeMachinePlanHistory is the type of the companion secret holding the plan history of a
+	// machine-plan secret.
+	SecretTypeMachinePlanHistory = "rke.cattle.io/machine-plan-history"
+
+	// PlanHistoryKey is the Secret data key storing the gzip-compressed JSON list of
+	// PlanHistoryEntry of a plan history secret, newest first.
+	PlanHistoryKey = "history"
+
+	// DefaultHistoryLimit is the number of replaced plans kept per machine by a Store, unless changed
+	// with WithHistory.
+	DefaultHistoryLimit = 10
+
+	// maxHistorySize caps the compressed size of the history, well below the 1MiB limit of a secret.
+	// The oldest entries are dropped first once it is reached.
+	maxHistorySize = 512 * 1024
+)
+
+// PlanHistoryEntry is a plan replaced by AssignPlan, along with what the agent reported about it
+// before it was replaced.
+type PlanHistoryEntry struct {
+	// Checksum is the PlanHash of Plan.
+	Checksum string `json:"checksum"`
+	// Plan is the JSON encoded plan.
+	Plan json.RawMessage `json:"plan"`
+	// Revision is the plan revision the agent loaded the plan as, if it did.
+	Revision int64 `json:"revision,omitempty"`
+	// State is the last plan state written for the plan.
+	State PlanState `json:"state,omitempty"`
+	// Applied is whether the agent applied the plan successfully.
+	Applied bool `json:"applied"`
+	// FailureCount is the number of failed attempts to apply the plan.
+	FailureCount int `json:"failureCount,omitempty"`
+	// AssignedAt is when the plan was assigned, from the PlanLastUpdatedAnnotation.
+	AssignedAt string `json:"assignedAt,omitempty"`
+	// ProbesPassedAt is when the probes of the plan last passed, from the PlanProbesPassedAnnotation.
+	ProbesPassedAt string `json:"probesPassedAt,omitempty"`
+	// ReplacedAt is when the plan was replaced by the next one.
+	ReplacedAt string `json:"replacedAt"`
+	// AppliedOutput and AppliedPeriodicOutput are the gzip-compressed outputs of the plan, as found in
+	// the machine-plan secret. They are read with Output and PeriodicOutput.
+	AppliedOutput         []byte `json:"appliedOutput,omitempty"`
+	AppliedPeriodicOutput []byte `json:"appliedPeriodicOutput,omitempty"`
+}
+
+// Output decodes the output of the one-time instructions of the plan, like ReadAppliedOutput.
+func (e *PlanHistoryEntry) Output() (map[string][]byte, error) {
+	var out map[string][]byte
+	if err := decodeOutput(e.AppliedOutput, "applied-output", "plan "+e.Checksum, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PeriodicOutput decodes the output of the periodic instructions of the plan, including their exit
+// codes, like ReadAppliedPeriodicOutput.
+func (e *PlanHistoryEntry) PeriodicOutput() (map[string]PeriodicInstructionOutput, error) {
+	var out map[string]PeriodicInstructionOutput
+	if err := decodeOutput(e.AppliedPeriodicOutput, "applied-periodic-output", "plan "+e.Checksum, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HistorySecretName returns the name of the plan history secret of a machine-plan secret.
+func HistorySecretName(secret *corev1.Secret) string {
+	return name.SafeConcatName(secret.Name, "history")
+}
+
+// WithHistory makes AssignPlan keep the last limit plans it replaces on each machine-plan secret,
+// in a companion secret named by HistorySecretName and owned by the machine-plan secret, instead of
+// DefaultHistoryLimit. A limit of 0 disables the history.
+func (s *Store) WithHistory(limit int) *Store {
+	s.historyLimit = limit
+	return s
+}
+
+// History returns the plans replaced on the machine-plan secret, newest first. It returns nil if
+// no history has been recorded for the secret.
+func (s *Store) History(secret *corev1.Secret) ([]PlanHistoryEntry, error) {
+	history, err := s.secrets.Get(secret.Namespace, HistorySecretName(secret), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeHistory(history)
+}
+
+// newHistoryEntry returns the history entry of the plan currently assigned to the secret.
+func newHistoryEntry(secret *corev1.Secret, now time.Time) PlanHistoryEntry {
+	planData := secret.Data["plan"]
+	entry := PlanHistoryEntry{
+		Checksum:              PlanHash(planData),
+		State:                 PlanState(secret.Data[PlanStateKey]),
+		Applied:               bytes.Equal(planData, secret.Data["appliedPlan"]),
+		AssignedAt:            secret.Annotations[PlanLastUpdatedAnnotation],
+		ProbesPassedAt:        secret.Annotations[PlanProbesPassedAnnotation],
+		ReplacedAt:            now.UTC().Format(time.RFC3339),
+		AppliedOutput:         secret.Data["applied-output"],
+		AppliedPeriodicOutput: secret.Data["applied-periodic-output"],
+	}
+	// A plan that is not valid JSON is recorded by its checksum only.
+	if json.Valid(planData) {
+		entry.Plan = json.RawMessage(planData)
+	}
+	if revision, err := strconv.ParseInt(string(secret.Data[PlanRevisionKey]), 10, 64); err == nil {
+		entry.Revision = revision
+	}
+	if string(secret.Data["failed-checksum"]) == entry.Checksum {
+		entry.FailureCount, _ = strconv.Atoi(string(secret.Data["failure-count"]))
+	}
+	return entry
+}
+
+// RecordHistory records the plan assigned to the machine-plan secret in its plan history, for
+// callers about to replace it without AssignPlan, such as the CAPR planner. It does nothing if the
+// secret has no plan or the history is disabled.
+func (s *Store) RecordHistory(secret *corev1.Secret) error {
+	if s.historyLimit <= 0 || len(secret.Data["plan"]) == 0 {
+		return nil
+	}
+	if err := s.recordHistory(secret, newHistoryEntry(secret, time.Now())); err != nil {
+		return fmt.Errorf("recording plan history of %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+	return nil
+}
+
+// recordHistory adds the entry to the plan history secret of the machine-plan secret, creating it
+// if needed. Recording the same plan assignment again, e.g. when the update replacing it conflicted
+// and is retried, replaces the previous entry.
+func (s *Store) recordHistory(secret *corev1.Secret, entry PlanHistoryEntry) error {
+	history, err := s.secrets.Get(secret.Namespace, HistorySecretName(secret), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		history = newHistorySecret(secret)
+	} else if err != nil {
+		return err
+	} else {
+		history = history.DeepCopy()
+	}
+
+	entries, err := decodeHistory(history)
+	if err != nil {
+		// A corrupted history is not worth blocking plan assignments for; start over.
+		entries = nil
+	}
+	if len(entries) > 0 && entries[0].Checksum == entry.Checksum && entries[0].AssignedAt == entry.AssignedAt {
+		entries = entries[1:]
+	}
+	entries = append([]PlanHistoryEntry{entry}, entries...)
+	if len(entries) > s.historyLimit {
+		entries = entries[:s.historyLimit]
+	}
+
+	data, err := encodeHistory(entries)
+	if err != nil {
+		return err
+	}
+	if history.Data == nil {
+		history.Data = map[string][]byte{}
+	}
+	history.Data[PlanHistoryKey] = data
+
+	if history.ResourceVersion == "" {
+		_, err = s.secrets.Create(history)
+	} else {
+		_, err = s.secrets.Update(history)
+	}
+	return err
+}
+
+// newHistorySecret returns the plan history secret of the machine-plan secret, owned by it so it is
+// deleted along with it.
+func newHistorySecret(secret *corev1.Secret) *corev1.Secret {
+	history := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HistorySecretName(secret),
+			Namespace: secret.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Name:       secret.Name,
+				UID:        secret.UID,
+			}},
+		},
+		Type: SecretTypeMachinePlanHistory,
+	}
+	if cluster := secret.Labels[labelClusterName]; cluster != "" {
+		history.Labels = map[string]string{labelClusterName: cluster}
+	}
+	return history
+}
+
+func decodeHistory(history *corev1.Secret) ([]PlanHistoryEntry, error) {
+	var entries []PlanHistoryEntry
+	if err := decodeOutput(history.Data[PlanHistoryKey], PlanHistoryKey, history.Name, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// encodeHistory compresses the entries, dropping the oldest ones until they fit in maxHistorySize.
+func encodeHistory(entries []PlanHistoryEntry) ([]byte, error) {
+	for {
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if buf.Len() <= maxHistorySize || len(entries) == 0 {
+			return buf.Bytes(), nil
+		}
+		if len(entries) == 1 && (entries[0].AppliedOutput != nil || entries[0].AppliedPeriodicOutput != nil) {
+			// Even the newest plan alone is too large: keep it without its outputs.
+			entries = []PlanHistoryEntry{entries[0]}
+			entries[0].AppliedOutput, entries[0].AppliedPeriodicOutput = nil, nil
+			continue
+		}
+		entries = entries[:len(entries)-1]
+	}
+}
@@ -0,0 +1,247 @@
+package plan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// storedSecrets is an in-memory SecretClient supporting Get, Create and Update by name.
+type storedSecrets struct {
+	corecontrollers.SecretClient
+
+	secrets map[string]*corev1.Secret
+	version int
+}
+
+func (f *storedSecrets) Get(namespace, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	if secret, ok := f.secrets[namespace+"/"+name]; ok {
+		return secret.DeepCopy(), nil
+	}
+	return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+}
+
+func (f *storedSecrets) Create(secret *corev1.Secret) (*corev1.Secret, error) {
+	if _, ok := f.secrets[secret.Namespace+"/"+secret.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(corev1.Resource("secrets"), secret.Name)
+	}
+	return f.Update(secret)
+}
+
+func (f *storedSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	f.version++
+	secret = secret.DeepCopy()
+	secret.ResourceVersion = strconv.Itoa(f.version)
+	f.secrets[secret.Namespace+"/"+secret.Name] = secret
+	return secret.DeepCopy(), nil
+}
+
+func gzipJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func namedPlan(name string) *Plan {
+	return &Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: name, Command: "sh"}}}}
+}
+
+func TestAssignPlan_History(t *testing.T) {
+	secrets := &storedSecrets{secrets: map[string]*corev1.Secret{}}
+	store := NewStore(secrets).WithHistory(2)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "machine-plan",
+			Namespace: "fleet-default",
+			UID:       "uid",
+			Labels:    map[string]string{labelClusterName: "mine"},
+		},
+		Type: SecretTypeMachinePlan,
+	}
+
+	// The first plan replaces nothing.
+	status, err := store.AssignPlan(secret, namedPlan("first"), 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if history, err := store.History(status.Secret); err != nil || history != nil {
+		t.Fatalf("expected no history, got %v, %v", history, err)
+	}
+
+	// The agent applies the first plan, and reports its output.
+	secret = status.Secret
+	secret.Data["appliedPlan"] = secret.Data["plan"]
+	secret.Data[PlanStateKey] = []byte(PlanStateSucceeded)
+	secret.Data[PlanRevisionKey] = []byte("1")
+	secret.Data["applied-output"] = gzipJSON(t, map[string][]byte{"first": []byte("ok")})
+	secret.Data["applied-periodic-output"] = gzipJSON(t, map[string]PeriodicInstructionOutput{"check": {Name: "check", ExitCode: 3}})
+	firstChecksum := PlanHash(secret.Data["plan"])
+
+	for _, name := range []string{"second", "third"} {
+		status, err = store.AssignPlan(secret, namedPlan(name), 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		secret = status.Secret
+	}
+	history, err := store.History(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got %d", len(history))
+	}
+
+	second, first := history[0], history[1]
+	if second.Checksum == firstChecksum || first.Checksum != firstChecksum {
+		t.Errorf("expected the history to be newest first, got %s, %s", second.Checksum, first.Checksum)
+	}
+	if !first.Applied || first.State != PlanStateSucceeded || first.Revision != 1 || first.ReplacedAt == "" || first.AssignedAt == "" {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if plan, err := Parse(first.Plan); err != nil || plan.OneTimeInstructions[0].Name != "first" {
+		t.Errorf("unexpected plan %s: %v", first.Plan, err)
+	}
+	if output, err := first.Output(); err != nil || !reflect.DeepEqual(output, map[string][]byte{"first": []byte("ok")}) {
+		t.Errorf("unexpected output %v: %v", output, err)
+	}
+	if output, err := first.PeriodicOutput(); err != nil || output["check"].ExitCode != 3 {
+		t.Errorf("unexpected periodic output %v: %v", output, err)
+	}
+	if second.Applied || second.State != PlanStatePending {
+		t.Errorf("unexpected entry: %+v", second)
+	}
+
+	// Assigning a fourth plan drops the oldest entry.
+	status, err = store.AssignPlan(secret, namedPlan("fourth"), 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	history, err = store.History(status.Secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 2 || history[1].Checksum != second.Checksum {
+		t.Errorf("expected the first plan to be dropped, got %d entries", len(history))
+	}
+
+	stored := secrets.secrets["fleet-default/"+HistorySecretName(secret)]
+	if stored.Type != SecretTypeMachinePlanHistory || stored.Labels[labelClusterName] != "mine" ||
+		len(stored.OwnerReferences) != 1 || stored.OwnerReferences[0].UID != "uid" {
+		t.Errorf("unexpected history secret: %+v", stored.ObjectMeta)
+	}
+}
+
+func TestAssignPlan_HistoryRetriedAssignment(t *testing.T) {
+	secrets := &storedSecrets{secrets: map[string]*corev1.Secret{}}
+	store := NewStore(secrets)
+	status, err := store.AssignPlan(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-plan"}}, namedPlan("first"), 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Replacing the same plan twice, as when the first update conflicted, records it once.
+	for range 2 {
+		if _, err := store.AssignPlan(status.Secret, namedPlan("second"), 1, 1); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	history, err := store.History(status.Secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("expected 1 history entry, got %d", len(history))
+	}
+}
+
+func TestAssignPlan_HistoryDisabled(t *testing.T) {
+	secrets := &storedSecrets{secrets: map[string]*corev1.Secret{}}
+	store := NewStore(secrets).WithHistory(0)
+	status, err := store.AssignPlan(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-plan"}}, namedPlan("first"), 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.AssignPlan(status.Secret, namedPlan("second"), 1, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets.secrets) != 1 {
+		t.Errorf("expected no history secret, got %d secrets", len(secrets.secrets))
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	secrets := &storedSecrets{secrets: map[string]*corev1.Secret{}}
+	store := NewStore(secrets)
+
+	// A secret without a plan has nothing to record.
+	empty := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-plan", Namespace: "fleet-default"}}
+	if err := store.RecordHistory(empty); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets.secrets) != 0 {
+		t.Fatalf("expected no history secret, got %d secrets", len(secrets.secrets))
+	}
+
+	raw, err := json.Marshal(namedPlan("first"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret := empty.DeepCopy()
+	secret.Data = map[string][]byte{"plan": raw, "appliedPlan": raw}
+	if err := store.RecordHistory(secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	history, err := store.History(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(history) != 1 || history[0].Checksum != PlanHash(raw) || !history[0].Applied {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
+
+func TestEncodeHistorySizeCap(t *testing.T) {
+	// Random-looking output does not compress, so a few entries exceed maxHistorySize.
+	large := make([]byte, maxHistorySize/2)
+	rand.New(rand.NewSource(1)).Read(large)
+	entries := []PlanHistoryEntry{
+		{Checksum: "c", AppliedOutput: large},
+		{Checksum: "b", AppliedOutput: large},
+		{Checksum: "a", AppliedOutput: large},
+	}
+	data, err := encodeHistory(entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) > maxHistorySize {
+		t.Errorf("expected the history to fit in %d bytes, got %d", maxHistorySize, len(data))
+	}
+	decoded, err := decodeHistory(&corev1.Secret{Data: map[string][]byte{PlanHistoryKey: data}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded) == 0 || len(decoded) == len(entries) || decoded[0].Checksum != "c" {
+		t.Errorf("expected the oldest entries to be dropped, got %d entries", len(decoded))
+	}
+}
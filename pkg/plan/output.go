@@ -14,17 +14,9 @@
 // Returns nil if no output has been written yet. Keys in the returned map correspond to
 // instruction names that were assigned with SaveOutput: true.
 func ReadAppliedOutput(secret *corev1.Secret) (map[string][]byte, error) {
-	raw := secret.Data["applied-output"]
-	if len(raw) == 0 {
-		return nil, nil
-	}
-	decoded, err := decompressGzip(raw)
-	if err != nil {
-		return nil, fmt.Errorf("reading applied-output from %s: %w", secret.Name, err)
-	}
 	var out map[string][]byte
-	if err := json.Unmarshal(decoded, &out); err != nil {
-		return nil, fmt.Errorf("parsing applied-output from %s: %w", secret.Name, err)
+	if err := decodeOutput(secret.Data["applied-output"], "applied-output", secret.Name, &out); err != nil {
+		return nil, err
 	}
 	return out, nil
 }
@@ -33,19 +25,27 @@ func ReadAppliedOutput(secret *corev1.Secret) (map[string][]byte, error) {
 // plan secret. Returns nil if no periodic output has been written yet. Keys correspond to
 // periodic instruction names.
 func ReadAppliedPeriodicOutput(secret *corev1.Secret) (map[string]PeriodicInstructionOutput, error) {
-	raw := secret.Data["applied-periodic-output"]
+	var out map[string]PeriodicInstructionOutput
+	if err := decodeOutput(secret.Data["applied-periodic-output"], "applied-periodic-output", secret.Name, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeOutput decodes the gzip-compressed JSON output stored under key into out, leaving out
+// untouched if raw is empty. name identifies the owner of the output in errors.
+func decodeOutput(raw []byte, key, name string, out any) error {
 	if len(raw) == 0 {
-		return nil, nil
+		return nil
 	}
 	decoded, err := decompressGzip(raw)
 	if err != nil {
-		return nil, fmt.Errorf("reading applied-periodic-output from %s: %w", secret.Name, err)
+		return fmt.Errorf("reading %s from %s: %w", key, name, err)
 	}
-	var out map[string]PeriodicInstructionOutput
-	if err := json.Unmarshal(decoded, &out); err != nil {
-		return nil, fmt.Errorf("parsing applied-periodic-output from %s: %w", secret.Name, err)
+	if err := json.Unmarshal(decoded, out); err != nil {
+		return fmt.Errorf("parsing %s from %s: %w", key, name, err)
 	}
-	return out, nil
+	return nil
 }
 
 func decompressGzip(data []byte) ([]byte, error) {
@@ -1,13 +1,25 @@
 package plan
 
-// Probe describes a health check to be performed against the node.
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Probe describes a health check to be performed against the node. Exactly one of HTTPGetAction,
+// TCPSocketAction, ExecAction and GRPCAction must be set.
 type Probe struct {
-	Name                string        `json:"name,omitempty"`
-	InitialDelaySeconds int           `json:"initialDelaySeconds,omitempty"` // default 0
-	TimeoutSeconds      int           `json:"timeoutSeconds,omitempty"`      // default 1
-	SuccessThreshold    int           `json:"successThreshold,omitempty"`    // default 1
-	FailureThreshold    int           `json:"failureThreshold,omitempty"`    // default 3
-	HTTPGetAction       HTTPGetAction `json:"httpGet,omitempty"`
+	Name                string           `json:"name,omitempty"`
+	InitialDelaySeconds int              `json:"initialDelaySeconds,omitempty"` // default 0
+	TimeoutSeconds      int              `json:"timeoutSeconds,omitempty"`      // default 1
+	SuccessThreshold    int              `json:"successThreshold,omitempty"`    // default 1
+	FailureThreshold    int              `json:"failureThreshold,omitempty"`    // default 3
+	HTTPGetAction       *HTTPGetAction   `json:"httpGet,omitempty"`
+	TCPSocketAction     *TCPSocketAction `json:"tcpSocket,omitempty"`
+	ExecAction          *ExecAction      `json:"exec,omitempty"`
+	GRPCAction          *GRPCAction      `json:"grpc,omitempty"`
 }
 
 // HTTPGetAction describes an HTTP GET request used by a Probe.
@@ -19,9 +31,168 @@ type HTTPGetAction struct {
 	CACert     string `json:"caCert,omitempty"`
 }
 
+// TCPSocketAction describes a TCP connection used by a Probe. The probe succeeds if the connection
+// can be established.
+type TCPSocketAction struct {
+	// Address is the host:port to connect to (e.g. 127.0.0.1:2380).
+	Address string `json:"address,omitempty"`
+}
+
+// ExecAction describes a command run on the node by a Probe. The probe succeeds if the command
+// exits with status 0.
+type ExecAction struct {
+	// Command is the command line to execute. It is not run in a shell; to use a shell, call it
+	// explicitly (e.g. ["/bin/sh", "-c", "..."]).
+	Command []string `json:"command,omitempty"`
+	Env     []string `json:"env,omitempty"`
+}
+
+// GRPCAction describes a call to the standard gRPC health checking service used by a Probe. The
+// probe succeeds if the service reports SERVING.
+type GRPCAction struct {
+	// Address is the host:port, or unix:// socket path, of the gRPC server
+	// (e.g. unix:///run/k3s/containerd/containerd.sock).
+	Address string `json:"address,omitempty"`
+	// Service is the name of the service to check. The overall health of the server is checked when
+	// empty.
+	Service    string `json:"service,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+	CACert     string `json:"caCert,omitempty"`
+}
+
 // ProbeStatus represents the current health status of a probe as reported by the agent.
 type ProbeStatus struct {
 	Healthy      bool `json:"healthy,omitempty"`
 	SuccessCount int  `json:"successCount,omitempty"`
 	FailureCount int  `json:"failureCount,omitempty"`
 }
+
+// DeepCopyInto copies the receiver into out. The actions are copied rather than shared.
+func (p *Probe) DeepCopyInto(out *Probe) {
+	*out = *p
+	if p.HTTPGetAction != nil {
+		action := *p.HTTPGetAction
+		out.HTTPGetAction = &action
+	}
+	if p.TCPSocketAction != nil {
+		action := *p.TCPSocketAction
+		out.TCPSocketAction = &action
+	}
+	if p.ExecAction != nil {
+		action := ExecAction{
+			Command: append([]string(nil), p.ExecAction.Command...),
+			Env:     append([]string(nil), p.ExecAction.Env...),
+		}
+		out.ExecAction = &action
+	}
+	if p.GRPCAction != nil {
+		action := *p.GRPCAction
+		out.GRPCAction = &action
+	}
+}
+
+// DeepCopy returns a deep copy of the probe.
+func (p *Probe) DeepCopy() *Probe {
+	if p == nil {
+		return nil
+	}
+	out := new(Probe)
+	p.DeepCopyInto(out)
+	return out
+}
+
+// Validate returns an error if the probe does not define exactly one valid action, or defines a
+// negative delay, timeout or threshold. The hosts of addresses and URLs may still contain the %s
+// format specifiers substituted when probes are rendered for a node.
+func (p Probe) Validate() error {
+	if p.InitialDelaySeconds < 0 || p.TimeoutSeconds < 0 || p.SuccessThreshold < 0 || p.FailureThreshold < 0 {
+		return errors.New("plan: probe delay, timeout and thresholds must not be negative")
+	}
+
+	var actions []string
+	if p.HTTPGetAction != nil {
+		actions = append(actions, "httpGet")
+	}
+	if p.TCPSocketAction != nil {
+		actions = append(actions, "tcpSocket")
+	}
+	if p.ExecAction != nil {
+		actions = append(actions, "exec")
+	}
+	if p.GRPCAction != nil {
+		actions = append(actions, "grpc")
+	}
+	switch len(actions) {
+	case 0:
+		return errors.New("plan: probe defines no action")
+	case 1:
+	default:
+		return fmt.Errorf("plan: probe defines more than one action: %s", strings.Join(actions, ", "))
+	}
+
+	switch {
+	case p.TCPSocketAction != nil:
+		return p.TCPSocketAction.Validate()
+	case p.ExecAction != nil:
+		return p.ExecAction.Validate()
+	case p.GRPCAction != nil:
+		return p.GRPCAction.Validate()
+	default:
+		return p.HTTPGetAction.Validate()
+	}
+}
+
+// Validate returns an error if the action has no http or https URL.
+func (a HTTPGetAction) Validate() error {
+	if !strings.HasPrefix(a.URL, "http://") && !strings.HasPrefix(a.URL, "https://") {
+		return fmt.Errorf("plan: httpGet probe URL %q must start with http:// or https://", a.URL)
+	}
+	return nil
+}
+
+// Validate returns an error if the action has no valid host:port address.
+func (a TCPSocketAction) Validate() error {
+	if err := validateHostPort(a.Address); err != nil {
+		return fmt.Errorf("plan: tcpSocket probe address: %w", err)
+	}
+	return nil
+}
+
+// Validate returns an error if the action has no command.
+func (a ExecAction) Validate() error {
+	if len(a.Command) == 0 || a.Command[0] == "" {
+		return errors.New("plan: exec probe command must not be empty")
+	}
+	return nil
+}
+
+// Validate returns an error if the action has no valid host:port or unix socket address.
+func (a GRPCAction) Validate() error {
+	if path, ok := strings.CutPrefix(a.Address, "unix://"); ok {
+		if path == "" {
+			return errors.New("plan: grpc probe address: unix socket path must not be empty")
+		}
+		return nil
+	}
+	if err := validateHostPort(a.Address); err != nil {
+		return fmt.Errorf("plan: grpc probe address: %w", err)
+	}
+	return nil
+}
+
+// validateHostPort returns an error if address is not a host:port pair with a numeric port.
+func validateHostPort(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("%q has no host", address)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q has invalid port %q", address, port)
+	}
+	return nil
+}
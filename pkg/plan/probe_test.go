@@ -0,0 +1,169 @@
+package plan
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProbeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe Probe
+		want  string
+	}{
+		{
+			name:  "http",
+			probe: Probe{Name: "kube-apiserver", HTTPGetAction: &HTTPGetAction{URL: "https://127.0.0.1:6443/readyz", CACert: "/ca.crt"}},
+			want:  `{"name":"kube-apiserver","httpGet":{"url":"https://127.0.0.1:6443/readyz","caCert":"/ca.crt"}}`,
+		},
+		{
+			name:  "tcp",
+			probe: Probe{Name: "etcd-peer", TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2380"}},
+			want:  `{"name":"etcd-peer","tcpSocket":{"address":"127.0.0.1:2380"}}`,
+		},
+		{
+			name:  "exec",
+			probe: Probe{Name: "health", ExecAction: &ExecAction{Command: []string{"/bin/sh", "-c", "exit 0"}, Env: []string{"A=b"}}},
+			want:  `{"name":"health","exec":{"command":["/bin/sh","-c","exit 0"],"env":["A=b"]}}`,
+		},
+		{
+			name:  "grpc",
+			probe: Probe{Name: "containerd", GRPCAction: &GRPCAction{Address: "unix:///run/k3s/containerd/containerd.sock", Service: "containerd"}},
+			want:  `{"name":"containerd","grpc":{"address":"unix:///run/k3s/containerd/containerd.sock","service":"containerd"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.probe)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(raw) != tt.want {
+				t.Errorf("got %s, want %s", raw, tt.want)
+			}
+
+			var decoded Probe
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.probe, decoded) {
+				t.Errorf("round trip mismatch:\n got %+v\nwant %+v", decoded, tt.probe)
+			}
+			if err := decoded.Validate(); err != nil {
+				t.Errorf("decoded probe does not validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestProbeDeepCopy(t *testing.T) {
+	original := Probe{
+		HTTPGetAction:   &HTTPGetAction{URL: "https://127.0.0.1:6443/readyz"},
+		TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2380"},
+		ExecAction:      &ExecAction{Command: []string{"true"}},
+		GRPCAction:      &GRPCAction{Address: "127.0.0.1:9000"},
+	}
+
+	copied := original.DeepCopy()
+	copied.HTTPGetAction.URL = "changed"
+	copied.TCPSocketAction.Address = "changed"
+	copied.ExecAction.Command[0] = "changed"
+	copied.GRPCAction.Address = "changed"
+
+	if original.HTTPGetAction.URL != "https://127.0.0.1:6443/readyz" || original.TCPSocketAction.Address != "127.0.0.1:2380" || original.ExecAction.Command[0] != "true" || original.GRPCAction.Address != "127.0.0.1:9000" {
+		t.Errorf("copy shares actions with the original: %+v", original)
+	}
+}
+
+func TestProbeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   Probe
+		wantErr string
+	}{
+		{
+			name:  "http",
+			probe: Probe{HTTPGetAction: &HTTPGetAction{URL: "https://%s:6443/readyz"}},
+		},
+		{
+			name:    "http without scheme",
+			probe:   Probe{HTTPGetAction: &HTTPGetAction{URL: "127.0.0.1:6443"}},
+			wantErr: "must start with http:// or https://",
+		},
+		{
+			name:  "tcp",
+			probe: Probe{TCPSocketAction: &TCPSocketAction{Address: "%s:2380"}},
+		},
+		{
+			name:  "tcp ipv6",
+			probe: Probe{TCPSocketAction: &TCPSocketAction{Address: "[::1]:2380"}},
+		},
+		{
+			name:    "tcp without port",
+			probe:   Probe{TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1"}},
+			wantErr: "tcpSocket probe address",
+		},
+		{
+			name:    "tcp port out of range",
+			probe:   Probe{TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:70000"}},
+			wantErr: "invalid port",
+		},
+		{
+			name:  "exec",
+			probe: Probe{ExecAction: &ExecAction{Command: []string{"/usr/local/bin/health.sh"}}},
+		},
+		{
+			name:    "exec without command",
+			probe:   Probe{ExecAction: &ExecAction{}},
+			wantErr: "command must not be empty",
+		},
+		{
+			name:  "grpc unix socket",
+			probe: Probe{GRPCAction: &GRPCAction{Address: "unix:///run/containerd/containerd.sock"}},
+		},
+		{
+			name:  "grpc tcp",
+			probe: Probe{GRPCAction: &GRPCAction{Address: "127.0.0.1:9000"}},
+		},
+		{
+			name:    "grpc empty unix socket",
+			probe:   Probe{GRPCAction: &GRPCAction{Address: "unix://"}},
+			wantErr: "unix socket path must not be empty",
+		},
+		{
+			name:    "no action",
+			probe:   Probe{Name: "empty"},
+			wantErr: "defines no action",
+		},
+		{
+			name: "multiple actions",
+			probe: Probe{
+				HTTPGetAction:   &HTTPGetAction{URL: "https://127.0.0.1:6443/readyz"},
+				TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:6443"},
+			},
+			wantErr: "more than one action: httpGet, tcpSocket",
+		},
+		{
+			name:    "negative threshold",
+			probe:   Probe{FailureThreshold: -1, TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2380"}},
+			wantErr: "must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.probe.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
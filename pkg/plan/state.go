@@ -32,10 +32,18 @@
 	// has been marked as failed.
 	PlanStateFailed PlanState = "failed"
 
-	// PlanStateCancelled means the plan was cancelled via the
-	// plan.cattle.io/cancelled: "true" annotation. This transition is handled by
-	// the cancellation ticket and is listed here for completeness.
+	// PlanStateCancelled means the agent stopped applying the plan after the orchestrator set
+	// the PlanCancelledAnnotation, e.g. through Store.CancelPlan. Instructions not yet started
+	// are skipped.
 	PlanStateCancelled PlanState = "cancelled"
+
+	// PlanCancelledAnnotation is set to "true" on a plan Secret by the orchestrator to ask the
+	// agent to stop applying the current plan. It is cleared when new plan content is written.
+	PlanCancelledAnnotation = "plan.cattle.io/cancelled"
+
+	// PlanAssignedByAnnotation is set on a plan Secret by a Store created through ForAssigner to
+	// the assigner of the plan it last wrote, so an operation only cancels the plans it assigned.
+	PlanAssignedByAnnotation = "plan.cattle.io/assigned-by"
 )
 
 // IsTerminal returns true when the state is a terminal state (succeeded, failed, or cancelled).
@@ -5,6 +5,7 @@
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -23,6 +24,8 @@
 // passed and probes passed
 // failing (but not yet failed)
 // failed and hit max failures
+// waiting to be cancelled
+// cancelled
 //
 
 // PlanStatus represents the current status of a plan.
@@ -33,10 +36,12 @@
 // - InProgress
 // - Applied && !(ProbesPassed)
 // - Failing
+// - CancelRequested
 //
 // The following states are terminal:
 // - Applied && ProbesPassed
 // - Failed
+// - Cancelled
 type PlanStatus struct {
 	// Secret is the machine-plan secret containing the plan.
 	Secret *corev1.Secret
@@ -58,6 +63,17 @@ type PlanStatus struct {
 
 	// Failed is true if the plan has failed to be applied.
 	Failed bool
+
+	// CancelRequested is true if the plan has been cancelled through CancelPlan, but the agent has
+	// not yet stopped applying it.
+	CancelRequested bool
+
+	// Cancelled is true if the agent stopped applying the plan after it was cancelled.
+	Cancelled bool
+
+	// Diff is the difference between the previously assigned plan and the plan just assigned. It is
+	// only set when AssignPlan replaced an existing plan.
+	Diff *PlanDiff
 }
 
 // Success returns true if the plan has been successfully applied and all probes have passed.
@@ -73,6 +89,10 @@ func (p *PlanStatus) Failure() bool {
 // Waiting returns true if the plan is in a transient state.
 func (p *PlanStatus) Waiting() bool {
 	switch {
+	case p.Cancelled:
+		return false
+	case p.CancelRequested:
+		return true
 	case p.Pending:
 		return true
 	case p.InProgress:
@@ -91,6 +111,10 @@ func (p *PlanStatus) Waiting() bool {
 
 func (p *PlanStatus) String() string {
 	switch {
+	case p.Cancelled:
+		return "plan cancelled"
+	case p.CancelRequested:
+		return "waiting for plan to be cancelled"
 	case p.Pending:
 		return "waiting for plan to be picked up"
 	case p.InProgress:
@@ -112,10 +136,12 @@ func (p *PlanStatus) String() string {
 // Nodes are bucketed by their active operational phase according to a strict priority hierarchy:
 //  1. failing plan (Failing == true, Failed == false)
 //  2. waiting for plan to be picked up (Pending == true)
-//  3. waiting for plan applied (InProgress == true)
-//  4. waiting for probes (Applied == true, ProbesPassed == false)
+//  3. waiting for plan to be cancelled (CancelRequested == true)
+//  4. waiting for plan applied (InProgress == true)
+//  5. waiting for probes (Applied == true, ProbesPassed == false)
 //
-// Nodes that do not fit into these buckets (e.g., fully successfully applied or strictly failed) are ignored.
+// Nodes that do not fit into these buckets (e.g., fully successfully applied, strictly failed or cancelled) are
+// ignored.
 // Within each bucket, node names are sorted lexicographically to guarantee deterministic outputs.
 //
 // Output string patterns adapt dynamically based on the node count per bucket:
@@ -156,10 +182,14 @@ func Message(results []PlanStatus) string {
 		}
 
 		// Order of evaluation sets the bucket for each node
-		if res.Failing && !res.Failed {
+		if res.Cancelled {
+			continue
+		} else if res.Failing && !res.Failed {
 			buckets["failing plan"] = append(buckets["failing plan"], name)
 		} else if res.Pending {
 			buckets["waiting for plan to be picked up"] = append(buckets["waiting for plan to be picked up"], name)
+		} else if res.CancelRequested {
+			buckets["waiting for plan to be cancelled"] = append(buckets["waiting for plan to be cancelled"], name)
 		} else if res.InProgress {
 			buckets["waiting for plan applied"] = append(buckets["waiting for plan applied"], name)
 		} else if res.Applied && !res.ProbesPassed {
@@ -178,12 +208,14 @@ func Message(results []PlanStatus) string {
 			return 1
 		case "waiting for plan to be picked up":
 			return 2
-		case "waiting for plan applied":
+		case "waiting for plan to be cancelled":
 			return 3
-		case "waiting for probes":
+		case "waiting for plan applied":
 			return 4
-		default:
+		case "waiting for probes":
 			return 5
+		default:
+			return 6
 		}
 	}
 
@@ -237,15 +269,51 @@ type msgGroup struct {
 }
 
 type Store struct {
-	secrets corecontrollers.SecretClient
+	secrets      corecontrollers.SecretClient
+	historyLimit int
+
+	conditionalInstructions bool
+	extendedProbes          bool
+	assigner                string
 }
 
 func NewStore(secrets corecontrollers.SecretClient) *Store {
 	return &Store{
-		secrets: secrets,
+		secrets:      secrets,
+		historyLimit: DefaultHistoryLimit,
 	}
 }
 
+// WithConditionalInstructions makes AssignPlan assign plans whose one-time instructions set
+// DependsOn, RunIf or ContinueOnError. Only use it once every system-agent the store assigns plans to
+// is known to honour them, since older agents silently run such instructions unconditionally.
+func (s *Store) WithConditionalInstructions() *Store {
+	s.conditionalInstructions = true
+	return s
+}
+
+// WithExtendedProbes makes AssignPlan assign plans with tcpSocket, exec or grpc probes. Only use it
+// once every system-agent the store assigns plans to is known to run them, since older agents only
+// run httpGet probes and never report the others as healthy.
+func (s *Store) WithExtendedProbes() *Store {
+	s.extendedProbes = true
+	return s
+}
+
+// ForAssigner returns a copy of the store recording assigner, e.g. the beacon owner key of an
+// operation, in the PlanAssignedByAnnotation of the secrets it assigns a new plan to. The store
+// itself is left unchanged, so it can be shared by the operations of a controller.
+func (s *Store) ForAssigner(assigner string) *Store {
+	scoped := *s
+	scoped.assigner = assigner
+	return &scoped
+}
+
+// AssignedBy returns true if the plan currently assigned to the secret was assigned by assigner.
+func AssignedBy(secret *corev1.Secret, assigner string) bool {
+	return assigner != "" && secret.Annotations[PlanAssignedByAnnotation] == assigner
+}
+
 // ParseProbeStatuses parses the probe statuses from the secret.
 // Returns a map of the probe name to ProbeStatus and a boolean indicating if all probes are healthy.
 // If the probeStatuses is empty returns an error.
@@ -278,10 +346,54 @@ func PlanHash(plan []byte) string {
 	return hex.EncodeToString(result[:])
 }
 
+// Diff returns the difference between the plan currently assigned to the secret and the given plan.
+// A secret without a plan is treated as having an empty plan.
+func (s *Store) Diff(secret *corev1.Secret, plan Plan) (PlanDiff, error) {
+	var current Plan
+	if raw := secret.Data["plan"]; len(raw) > 0 {
+		var err error
+		if current, err = Parse(raw); err != nil {
+			return PlanDiff{}, err
+		}
+	}
+	return Diff(current, plan), nil
+}
+
+// ErrInvalidPlan is returned, wrapped, by AssignPlan for plans failing Validate.
+var ErrInvalidPlan = errors.New("plan: invalid plan")
+
+// ErrConditionalInstructions is returned, wrapped, by AssignPlan for plans setting DependsOn, RunIf or
+// ContinueOnError on a store not created WithConditionalInstructions.
+var ErrConditionalInstructions = errors.New("plan: dependsOn, runIf and continueOnError are ignored by system-agents not known to support them")
+
+// ErrExtendedProbes is returned, wrapped, by AssignPlan for plans with tcpSocket, exec or grpc probes
+// on a store not created WithExtendedProbes.
+var ErrExtendedProbes = errors.New("plan: tcpSocket, exec and grpc probes are not run by system-agents not known to support them")
+
 // AssignPlan assigns the plan to the secret.
 // Returns a PlanStatus indicating the current state of the plan.
+// Plans failing Validate are not assigned; an error wrapping ErrInvalidPlan is returned instead. So
+// are plans using conditional instructions without WithConditionalInstructions, with an error also
+// wrapping ErrConditionalInstructions, and plans using tcpSocket, exec or grpc probes without
+// WithExtendedProbes, with an error also wrapping ErrExtendedProbes.
+// The plan being replaced is recorded in the plan history of the secret first, see WithHistory.
+// With ForAssigner, a new plan is annotated with its assigner.
+// The given secret is not modified: it is copied before the plan is written, so cached secrets, e.g.
+// those of a Collector built by NewCachedCollector, can be passed as they are.
 // This function is based off the CAPR assignAndCheckPlan function and will supersede it in the future once its CAPI dependency is unraveled.
 func (s *Store) AssignPlan(secret *corev1.Secret, plan *Plan, maxFailures, failureThreshold int) (*PlanStatus, error) {
+	if plan != nil {
+		if errs := Validate(*plan); len(errs) > 0 {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPlan, errs.ToAggregate())
+		}
+		if !s.conditionalInstructions && usesConditionalInstructions(*plan) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPlan, ErrConditionalInstructions)
+		}
+		if !s.extendedProbes && usesExtendedProbes(*plan) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPlan, ErrExtendedProbes)
+		}
+	}
+
 	data, err := json.Marshal(&plan)
 	if err != nil {
 		return nil, err
@@ -300,11 +412,29 @@ func (s *Store) AssignPlan(secret *corev1.Secret, plan *Plan, maxFailures, failu
 	}
 
 	if !bytes.Equal(secret.Data["plan"], data) {
+		if err := s.RecordHistory(secret); err != nil {
+			return nil, err
+		}
+		// A plan that cannot be parsed is overwritten without a diff.
+		delete(secret.Annotations, PlanDiffAnnotation)
+		if len(secret.Data["plan"]) > 0 && plan != nil {
+			if diff, err := s.Diff(secret, *plan); err == nil {
+				result.Diff = &diff
+				secret.Annotations[PlanDiffAnnotation] = diff.String()
+			}
+		}
 		result.Pending = true
 		delete(secret.Data, "probe-statuses")
+		delete(secret.Annotations, PlanCancelledAnnotation)
+		if s.assigner != "" {
+			secret.Annotations[PlanAssignedByAnnotation] = s.assigner
+		} else {
+			delete(secret.Annotations, PlanAssignedByAnnotation)
+		}
 		secret.Annotations[PlanLastUpdatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
 		secret.Annotations[PlanProbesPassedAnnotation] = ""
 
+		secret.Data[PlanStateKey] = []byte(PlanStatePending)
 		secret.Data["plan"] = data
 		if maxFailures > 0 || maxFailures == -1 {
 			secret.Data["max-failures"] = []byte(strconv.Itoa(maxFailures))
@@ -373,9 +503,40 @@ func (s *Store) AssignPlan(secret *corev1.Secret, plan *Plan, maxFailures, failu
 		result.Applied = true
 	}
 
-	if result.Applied || result.Failed {
+	if secret.Annotations[PlanCancelledAnnotation] == "true" {
+		if PlanState(secret.Data[PlanStateKey]) == PlanStateCancelled {
+			result.Cancelled = true
+		} else if !result.Applied && !result.Failed {
+			result.CancelRequested = true
+		}
+	}
+
+	if result.Applied || result.Failed || result.Cancelled {
 		result.InProgress = false
 	}
 
 	return result, nil
 }
+
+// CancelPlan asks the agent to stop applying the plan assigned to the secret by setting the
+// PlanCancelledAnnotation. The agent skips the instructions it has not yet started and sets the plan
+// state to PlanStateCancelled. Secrets without a plan, and secrets whose plan has already been
+// applied, reached a terminal state or been cancelled, are returned unchanged.
+// Assigning a new plan with AssignPlan clears the cancellation. Like AssignPlan, it does not modify
+// the given secret.
+func (s *Store) CancelPlan(secret *corev1.Secret) (*corev1.Secret, error) {
+	planData := secret.Data["plan"]
+	if len(planData) == 0 ||
+		bytes.Equal(planData, secret.Data["appliedPlan"]) ||
+		PlanState(secret.Data[PlanStateKey]).IsTerminal() ||
+		secret.Annotations[PlanCancelledAnnotation] == "true" {
+		return secret, nil
+	}
+
+	secret = secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[PlanCancelledAnnotation] = "true"
+	return s.secrets.Update(secret)
+}
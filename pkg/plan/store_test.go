@@ -1,9 +1,13 @@
 package plan
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -78,6 +82,16 @@ func TestMessage(t *testing.T) {
 			},
 			expected: "failing plan for node-failing, waiting for plan to be picked up for node-pending, waiting for plan applied for node-progress, waiting for probes for node-probes",
 		},
+		{
+			name: "Cancelling nodes rank between pending and in progress, cancelled nodes are excluded",
+			results: []PlanStatus{
+				{Secret: mockSecret("node-progress"), InProgress: true},
+				{Secret: mockSecret("node-cancelling"), InProgress: true, CancelRequested: true},
+				{Secret: mockSecret("node-cancelled"), Cancelled: true},
+				{Secret: mockSecret("node-pending"), Pending: true},
+			},
+			expected: "waiting for plan to be picked up for node-pending, waiting for plan to be cancelled for node-cancelling, waiting for plan applied for node-progress",
+		},
 		{
 			name: "Mixed messages with duplicate nodes per tier",
 			results: []PlanStatus{
@@ -99,4 +113,191 @@ func TestMessage(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// updatingSecrets is a SecretClient recording the secrets passed to Update. No secret can be read
+// from it, so plan history secrets are always created.
+type updatingSecrets struct {
+	corecontrollers.SecretClient
+
+	updated []*corev1.Secret
+	created []*corev1.Secret
+}
+
+func (f *updatingSecrets) Get(_, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+}
+
+func (f *updatingSecrets) Create(secret *corev1.Secret) (*corev1.Secret, error) {
+	f.created = append(f.created, secret)
+	return secret, nil
+}
+
+func (f *updatingSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	f.updated = append(f.updated, secret)
+	return secret, nil
+}
+
+func TestCancelPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		cancelled bool
+		updated   bool
+	}{
+		{
+			name:    "plan in progress",
+			data:    map[string][]byte{"plan": []byte("{}"), PlanStateKey: []byte(PlanStateInProgress)},
+			updated: true,
+		},
+		{
+			name: "no plan",
+		},
+		{
+			name: "plan already applied",
+			data: map[string][]byte{"plan": []byte("{}"), "appliedPlan": []byte("{}")},
+		},
+		{
+			name: "plan failed",
+			data: map[string][]byte{"plan": []byte("{}"), PlanStateKey: []byte(PlanStateFailed)},
+		},
+		{
+			name:      "plan already cancelled",
+			data:      map[string][]byte{"plan": []byte("{}")},
+			cancelled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := &updatingSecrets{}
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "machine-plan"}, Data: tt.data}
+			if tt.cancelled {
+				secret.Annotations = map[string]string{PlanCancelledAnnotation: "true"}
+			}
+
+			got, err := NewStore(secrets).CancelPlan(secret)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.updated != (len(secrets.updated) == 1) {
+				t.Fatalf("expected update to be %v, got %d updates", tt.updated, len(secrets.updated))
+			}
+			if tt.updated && got.Annotations[PlanCancelledAnnotation] != "true" {
+				t.Errorf("expected the %s annotation to be set", PlanCancelledAnnotation)
+			}
+			if tt.updated && secret.Annotations != nil {
+				t.Error("expected the given secret to be left unmodified")
+			}
+		})
+	}
+}
+
+func TestAssignPlan_Cancellation(t *testing.T) {
+	p := Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: "install", Command: "sh"}}}}
+	raw, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newSecret := func(state PlanState) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "machine-plan", Annotations: map[string]string{PlanCancelledAnnotation: "true"}},
+			Data:       map[string][]byte{"plan": raw, PlanStateKey: []byte(state)},
+		}
+	}
+
+	t.Run("cancel requested", func(t *testing.T) {
+		status, err := NewStore(&updatingSecrets{}).AssignPlan(newSecret(PlanStateInProgress), &p, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !status.CancelRequested || status.Cancelled || !status.Waiting() {
+			t.Errorf("unexpected status: %+v", status)
+		}
+		if status.String() != "waiting for plan to be cancelled" {
+			t.Errorf("unexpected status string %q", status.String())
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		status, err := NewStore(&updatingSecrets{}).AssignPlan(newSecret(PlanStateCancelled), &p, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !status.Cancelled || status.CancelRequested || status.InProgress || status.Waiting() {
+			t.Errorf("unexpected status: %+v", status)
+		}
+	})
+
+	t.Run("new plan clears the cancellation", func(t *testing.T) {
+		secrets := &updatingSecrets{}
+		next := Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: "upgrade", Command: "sh"}}}}
+		status, err := NewStore(secrets).AssignPlan(newSecret(PlanStateCancelled), &next, 1, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !status.Pending || status.Cancelled || status.CancelRequested {
+			t.Errorf("unexpected status: %+v", status)
+		}
+		if len(secrets.updated) != 1 {
+			t.Fatalf("expected the secret to be updated, got %d updates", len(secrets.updated))
+		}
+		if _, ok := secrets.updated[0].Annotations[PlanCancelledAnnotation]; ok {
+			t.Errorf("expected the %s annotation to be cleared", PlanCancelledAnnotation)
+		}
+		if state := string(secrets.updated[0].Data[PlanStateKey]); state != string(PlanStatePending) {
+			t.Errorf("expected the plan state to be reset to pending, got %q", state)
+		}
+	})
+}
+
+func TestAssignPlan_ForAssigner(t *testing.T) {
+	store := NewStore(&updatingSecrets{})
+	p := Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: "restart", Command: "true"}}}}
+
+	status, err := store.ForAssigner("fleet-default/upgrade").AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !AssignedBy(status.Secret, "fleet-default/upgrade") {
+		t.Errorf("expected the plan to be assigned by the operation, got %q", status.Secret.Annotations[PlanAssignedByAnnotation])
+	}
+	if AssignedBy(status.Secret, "fleet-default/restore") || AssignedBy(status.Secret, "") {
+		t.Error("expected the plan not to be assigned by anyone else")
+	}
+
+	// A plan assigned without an assigner clears the annotation, and the shared store is unchanged.
+	next := Plan{}
+	status, err = store.AssignPlan(status.Secret, &next, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := status.Secret.Annotations[PlanAssignedByAnnotation]; ok {
+		t.Errorf("expected no assigner, got %q", status.Secret.Annotations[PlanAssignedByAnnotation])
+	}
+}
+
+func TestAssignPlan_DoesNotModifySecret(t *testing.T) {
+	previous := Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: "install", Command: "sh"}}}}
+	raw, err := json.Marshal(&previous)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine-plan", Annotations: map[string]string{PlanCancelledAnnotation: "true"}},
+		Data:       map[string][]byte{"plan": raw, PlanStateKey: []byte(PlanStateCancelled), "probe-statuses": []byte("{}")},
+	}
+	cached := secret.DeepCopy()
+
+	secrets := &updatingSecrets{}
+	next := Plan{OneTimeInstructions: []OneTimeInstruction{{CommonInstruction: CommonInstruction{Name: "upgrade", Command: "sh"}}}}
+	if _, err := NewStore(secrets).ForAssigner("fleet-default/upgrade").AssignPlan(secret, &next, 1, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets.updated) != 1 {
+		t.Fatalf("expected the secret to be updated, got %d updates", len(secrets.updated))
+	}
+	if !reflect.DeepEqual(cached, secret) {
+		t.Errorf("expected the given secret to be left unmodified, got %+v", secret)
+	}
+}
@@ -0,0 +1,224 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	// LiveOutputKey is the plan secret key the system-agent streams the output of the instructions
+	// of the plan it is applying to, while they run.
+	LiveOutputKey = "live-output"
+
+	// MaxLiveOutputBytes bounds the output kept under LiveOutputKey. Once exceeded, the oldest
+	// chunks are dropped.
+	MaxLiveOutputBytes = 256 * 1024
+
+	// StdoutStream and StderrStream identify the stream an OutputChunk was written to.
+	StdoutStream = "stdout"
+	StderrStream = "stderr"
+)
+
+// OutputChunk is a piece of output written by a running instruction.
+type OutputChunk struct {
+	// Sequence orders the chunks of a plan. It starts at 1 and increases by one per chunk, so a
+	// gap between the chunks read indicates dropped output.
+	Sequence    int64  `json:"seq"`
+	Instruction string `json:"instruction"`
+	Stream      string `json:"stream"`
+	// Time is a time.RFC3339Nano formatted string of when the chunk was written.
+	Time string `json:"time"`
+	Data []byte `json:"data"`
+}
+
+// LiveOutput is the rolling output of the instructions of a plan, stored uncompressed as JSON under
+// LiveOutputKey so the system-agent can append to it without rewriting a gzip stream.
+type LiveOutput struct {
+	// Checksum is the PlanHash of the plan the output belongs to.
+	Checksum string        `json:"checksum"`
+	Chunks   []OutputChunk `json:"chunks,omitempty"`
+	// Last is the sequence of the last chunk appended, including dropped chunks.
+	Last int64 `json:"last,omitempty"`
+}
+
+// Append adds data written by the named instruction to stream as a new chunk, dropping the oldest
+// chunks once the output exceeds MaxLiveOutputBytes. The newest chunk is always kept. Append is
+// used by the system-agent.
+func (o *LiveOutput) Append(instruction, stream string, data []byte, now time.Time) OutputChunk {
+	o.Last++
+	chunk := OutputChunk{
+		Sequence:    o.Last,
+		Instruction: instruction,
+		Stream:      stream,
+		Time:        now.UTC().Format(time.RFC3339Nano),
+		Data:        data,
+	}
+	o.Chunks = append(o.Chunks, chunk)
+
+	size := 0
+	for _, c := range o.Chunks {
+		size += len(c.Data)
+	}
+	for len(o.Chunks) > 1 && size > MaxLiveOutputBytes {
+		size -= len(o.Chunks[0].Data)
+		o.Chunks = o.Chunks[1:]
+	}
+	return chunk
+}
+
+// Since returns the chunks written after the chunk with sequence after, of the named instruction or
+// of all instructions when instruction is empty.
+func (o *LiveOutput) Since(instruction string, after int64) []OutputChunk {
+	var result []OutputChunk
+	for _, chunk := range o.Chunks {
+		if chunk.Sequence > after && (instruction == "" || chunk.Instruction == instruction) {
+			result = append(result, chunk)
+		}
+	}
+	return result
+}
+
+// ReadLiveOutput decodes the live output from a plan secret. Returns nil if no output has been
+// written yet, or if the output belongs to a plan other than the one currently assigned to the
+// secret.
+func ReadLiveOutput(secret *corev1.Secret) (*LiveOutput, error) {
+	raw := secret.Data[LiveOutputKey]
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var out LiveOutput
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("parsing %s from %s: %w", LiveOutputKey, secret.Name, err)
+	}
+	if out.Checksum != PlanHash(secret.Data["plan"]) {
+		return nil, nil
+	}
+	return &out, nil
+}
+
+// SecretWatcher is the subset of the generated Secret client WatchLiveOutput needs.
+type SecretWatcher interface {
+	Get(namespace, name string, options metav1.GetOptions) (*corev1.Secret, error)
+	Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// LiveOutputStream is the live output of a plan secret being streamed by WatchLiveOutput.
+type LiveOutputStream struct {
+	// C receives the chunks of the stream. It is closed when the stream ends.
+	C <-chan OutputChunk
+
+	err error
+}
+
+// Err returns the error that ended the stream, or nil if it ended because its context was done or
+// the secret was deleted. It must only be called once C is closed.
+func (s *LiveOutputStream) Err() error {
+	return s.err
+}
+
+// WatchLiveOutput streams the live output of the named plan secret, starting after the chunk with
+// sequence after, of the named instruction or of all instructions when instruction is empty. When a
+// new plan is assigned to the secret, its output is streamed from the beginning. The stream ends
+// when ctx is done, the secret is deleted, or watching the secret fails, in which case the error is
+// available from Err.
+func WatchLiveOutput(ctx context.Context, secrets SecretWatcher, namespace, name, instruction string, after int64) (*LiveOutputStream, error) {
+	secret, err := secrets.Get(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(chan OutputChunk)
+	stream := &LiveOutputStream{C: result}
+	go func() {
+		defer close(result)
+
+		checksum := ""
+		// emit sends the chunks of secret not sent yet, and returns false once ctx is done.
+		emit := func(secret *corev1.Secret) bool {
+			out, err := ReadLiveOutput(secret)
+			if err != nil || out == nil {
+				return true
+			}
+			if out.Checksum != checksum {
+				if checksum != "" {
+					after = 0
+				}
+				checksum = out.Checksum
+			}
+			for _, chunk := range out.Since(instruction, after) {
+				select {
+				case result <- chunk:
+					after = chunk.Sequence
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		for {
+			if !emit(secret) {
+				return
+			}
+
+			w, err := secrets.Watch(namespace, metav1.ListOptions{
+				FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+				ResourceVersion: secret.ResourceVersion,
+			})
+			if err != nil {
+				stream.err = fmt.Errorf("watching %s/%s: %w", namespace, name, err)
+				return
+			}
+			for done := false; !done; {
+				select {
+				case <-ctx.Done():
+					w.Stop()
+					return
+				case event, ok := <-w.ResultChan():
+					if !ok {
+						done = true
+						continue
+					}
+					switch event.Type {
+					case watch.Deleted:
+						w.Stop()
+						return
+					case watch.Added, watch.Modified:
+						if s, ok := event.Object.(*corev1.Secret); ok {
+							secret = s
+							if !emit(secret) {
+								w.Stop()
+								return
+							}
+						}
+					case watch.Error:
+						w.Stop()
+						err := apierrors.FromObject(event.Object)
+						if !apierrors.IsResourceExpired(err) && !apierrors.IsGone(err) {
+							stream.err = fmt.Errorf("watching %s/%s: %w", namespace, name, err)
+							return
+						}
+						done = true
+					}
+				}
+			}
+
+			// The watch expired; resume from the latest state of the secret.
+			if secret, err = secrets.Get(namespace, name, metav1.GetOptions{}); err != nil {
+				if !apierrors.IsNotFound(err) {
+					stream.err = fmt.Errorf("getting %s/%s: %w", namespace, name, err)
+				}
+				return
+			}
+		}
+	}()
+	return stream, nil
+}
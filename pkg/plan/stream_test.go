@@ -0,0 +1,246 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestLiveOutputAppend(t *testing.T) {
+	var out LiveOutput
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first := out.Append("restore", StdoutStream, []byte("restoring"), now)
+	if first.Sequence != 1 || first.Time != "2026-01-02T03:04:05Z" {
+		t.Errorf("unexpected chunk: %+v", first)
+	}
+
+	big := make([]byte, MaxLiveOutputBytes)
+	out.Append("restore", StderrStream, big, now)
+	if len(out.Chunks) != 1 || out.Chunks[0].Sequence != 2 {
+		t.Fatalf("expected the oldest chunk to be dropped, got %d chunks", len(out.Chunks))
+	}
+
+	out.Append("restore", StdoutStream, big, now)
+	if len(out.Chunks) != 1 || out.Chunks[0].Sequence != 3 || out.Last != 3 {
+		t.Fatalf("expected only the newest chunk to be kept, got %+v", out.Chunks[0].Sequence)
+	}
+}
+
+func TestLiveOutputSince(t *testing.T) {
+	var out LiveOutput
+	now := time.Now()
+	out.Append("shutdown", StdoutStream, []byte("a"), now)
+	out.Append("restore", StdoutStream, []byte("b"), now)
+	out.Append("restore", StderrStream, []byte("c"), now)
+
+	if got := out.Since("", 1); len(got) != 2 || got[0].Sequence != 2 {
+		t.Errorf("unexpected chunks: %+v", got)
+	}
+	if got := out.Since("restore", 2); len(got) != 1 || string(got[0].Data) != "c" {
+		t.Errorf("unexpected chunks: %+v", got)
+	}
+	if got := out.Since("shutdown", 1); len(got) != 0 {
+		t.Errorf("unexpected chunks: %+v", got)
+	}
+}
+
+// newLiveOutputSecret returns a plan secret assigned rawPlan, with live output of the given
+// instruction chunks written for the plan checksum.
+func newLiveOutputSecret(t *testing.T, rawPlan, checksum string, data ...string) *corev1.Secret {
+	t.Helper()
+	out := LiveOutput{Checksum: checksum}
+	for _, d := range data {
+		out.Append("restore", StdoutStream, []byte(d), time.Now())
+	}
+	raw, err := json.Marshal(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "machine-plan"},
+		Data:       map[string][]byte{"plan": []byte(rawPlan), LiveOutputKey: raw},
+	}
+}
+
+func TestReadLiveOutput(t *testing.T) {
+	t.Run("no output", func(t *testing.T) {
+		out, err := ReadLiveOutput(&corev1.Secret{})
+		if err != nil || out != nil {
+			t.Fatalf("expected no output, got %+v, %v", out, err)
+		}
+	})
+
+	t.Run("output of the assigned plan", func(t *testing.T) {
+		out, err := ReadLiveOutput(newLiveOutputSecret(t, "{}", PlanHash([]byte("{}")), "a", "b"))
+		if err != nil || out == nil || len(out.Chunks) != 2 {
+			t.Fatalf("unexpected output: %+v, %v", out, err)
+		}
+	})
+
+	t.Run("output of a previous plan", func(t *testing.T) {
+		out, err := ReadLiveOutput(newLiveOutputSecret(t, `{"instructions":[]}`, PlanHash([]byte("{}")), "a"))
+		if err != nil || out != nil {
+			t.Fatalf("expected no output, got %+v, %v", out, err)
+		}
+	})
+
+	t.Run("malformed output", func(t *testing.T) {
+		_, err := ReadLiveOutput(&corev1.Secret{Data: map[string][]byte{LiveOutputKey: []byte("{")}})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+type fakeSecretWatcher struct {
+	secret *corev1.Secret
+	getErr error
+	// watchers are returned by successive calls to Watch.
+	watchers []*watch.FakeWatcher
+}
+
+func (f *fakeSecretWatcher) Get(_, _ string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	return f.secret, f.getErr
+}
+
+func (f *fakeSecretWatcher) Watch(_ string, _ metav1.ListOptions) (watch.Interface, error) {
+	w := f.watchers[0]
+	f.watchers = f.watchers[1:]
+	return w, nil
+}
+
+func receive(t *testing.T, chunks <-chan OutputChunk) OutputChunk {
+	t.Helper()
+	select {
+	case chunk := <-chunks:
+		return chunk
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a chunk")
+		return OutputChunk{}
+	}
+}
+
+// ended waits for stream to end and returns its error.
+func ended(t *testing.T, stream *LiveOutputStream) error {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-stream.C:
+			if !ok {
+				return stream.Err()
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the stream to end")
+			return nil
+		}
+	}
+}
+
+func TestWatchLiveOutput(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checksum := PlanHash([]byte("{}"))
+	watcher := watch.NewFake()
+	secrets := &fakeSecretWatcher{
+		secret:   newLiveOutputSecret(t, "{}", checksum, "a", "b"),
+		watchers: []*watch.FakeWatcher{watcher},
+	}
+
+	stream, err := WatchLiveOutput(ctx, secrets, "fleet-default", "machine-plan", "restore", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if chunk := receive(t, stream.C); string(chunk.Data) != "b" {
+		t.Errorf("expected the chunk after the requested sequence, got %+v", chunk)
+	}
+
+	watcher.Modify(newLiveOutputSecret(t, "{}", checksum, "a", "b", "c"))
+	if chunk := receive(t, stream.C); string(chunk.Data) != "c" || chunk.Sequence != 3 {
+		t.Errorf("expected the appended chunk, got %+v", chunk)
+	}
+
+	// A new plan streams from the beginning.
+	newChecksum := PlanHash([]byte(`{"instructions":[]}`))
+	watcher.Modify(newLiveOutputSecret(t, `{"instructions":[]}`, newChecksum, "x"))
+	if chunk := receive(t, stream.C); string(chunk.Data) != "x" || chunk.Sequence != 1 {
+		t.Errorf("expected the first chunk of the new plan, got %+v", chunk)
+	}
+
+	watcher.Delete(secrets.secret)
+	if err := ended(t, stream); err != nil {
+		t.Errorf("expected the stream to end without an error when the secret is deleted, got %v", err)
+	}
+}
+
+func TestWatchLiveOutput_Errors(t *testing.T) {
+	checksum := PlanHash([]byte("{}"))
+
+	t.Run("watch error", func(t *testing.T) {
+		watcher := watch.NewFake()
+		secrets := &fakeSecretWatcher{
+			secret:   newLiveOutputSecret(t, "{}", checksum, "a"),
+			watchers: []*watch.FakeWatcher{watcher},
+		}
+		stream, err := WatchLiveOutput(context.Background(), secrets, "fleet-default", "machine-plan", "", 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		forbidden := apierrors.NewForbidden(corev1.Resource("secrets"), "machine-plan", nil)
+		watcher.Error(&forbidden.ErrStatus)
+		if err := ended(t, stream); !apierrors.IsForbidden(err) {
+			t.Errorf("expected the watch error to be forwarded, got %v", err)
+		}
+	})
+
+	t.Run("expired watch resumes", func(t *testing.T) {
+		expired, resumed := watch.NewFake(), watch.NewFake()
+		secrets := &fakeSecretWatcher{
+			secret:   newLiveOutputSecret(t, "{}", checksum, "a"),
+			watchers: []*watch.FakeWatcher{expired, resumed},
+		}
+		stream, err := WatchLiveOutput(context.Background(), secrets, "fleet-default", "machine-plan", "", 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		secrets.secret = newLiveOutputSecret(t, "{}", checksum, "a", "b")
+		gone := apierrors.NewResourceExpired("too old resource version")
+		expired.Error(&gone.ErrStatus)
+		if chunk := receive(t, stream.C); string(chunk.Data) != "b" {
+			t.Errorf("expected the chunk written while the watch expired, got %+v", chunk)
+		}
+
+		resumed.Delete(secrets.secret)
+		if err := ended(t, stream); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("get error after the watch expired", func(t *testing.T) {
+		watcher := watch.NewFake()
+		secrets := &fakeSecretWatcher{
+			secret:   newLiveOutputSecret(t, "{}", checksum, "a"),
+			watchers: []*watch.FakeWatcher{watcher},
+		}
+		stream, err := WatchLiveOutput(context.Background(), secrets, "fleet-default", "machine-plan", "", 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		secrets.getErr = apierrors.NewServiceUnavailable("etcd unavailable")
+		watcher.Stop()
+		if err := ended(t, stream); !apierrors.IsServiceUnavailable(err) {
+			t.Errorf("expected the get error to be forwarded, got %v", err)
+		}
+	})
+}
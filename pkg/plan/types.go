@@ -8,6 +8,10 @@
 
 	// PlanProbesPassedAnnotation is a constant representing the annotation storing the last known time the probes passed for a plan.
 	PlanProbesPassedAnnotation = "rke.cattle.io/plan-probes-passed"
+
+	// PlanDiffAnnotation is a constant representing the annotation storing a summary of what changed between the
+	// previous plan and the plan last assigned to a plan secret, as returned by PlanDiff.String.
+	PlanDiffAnnotation = "rke.cattle.io/plan-diff"
 )
 
 // Plan represents the basic unit of work performed by the system-agent.
@@ -35,6 +39,10 @@ type File struct {
 }
 
 // CommonInstruction holds fields shared by all instruction types.
+// System-agents predating DependsOn, RunIf and ContinueOnError ignore them: they run every
+// instruction, and stop at the first failure. Store.AssignPlan therefore only assigns plans setting
+// them when the store was created WithConditionalInstructions, and they are never valid on periodic
+// instructions.
 type CommonInstruction struct {
 	Name    string   `json:"name,omitempty"`
 	Image   string   `json:"image,omitempty"`
@@ -49,6 +57,32 @@ type CommonInstruction struct {
 	// The rendered script is stored in the system-agent's data directory, with the following format:
 	// /var/lib/rancher/agent/plans/<plan-name>/<instruction-name>-<sha256sum>.sh
 	Script string `json:"script,omitempty"`
+
+	// DependsOn names earlier instructions of the same plan that must have succeeded for this
+	// instruction to run. The instruction is skipped if any of them failed or was skipped.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// RunIf is the condition the node must meet for this instruction to run. The instruction is
+	// skipped, without failing the plan, if the condition is not met.
+	RunIf *Condition `json:"runIf,omitempty"`
+
+	// ContinueOnError indicates whether the remaining instructions of the plan run if this one
+	// fails. The failure is still reported in the output, but does not fail the plan.
+	ContinueOnError bool `json:"continueOnError,omitempty"`
+}
+
+// Condition is a check performed on the node before an instruction runs. Exactly one of Shell,
+// FileExists and FileAbsent must be set.
+type Condition struct {
+	// Shell is a command evaluated with /bin/sh -c; the condition is met if it exits with status 0
+	// (e.g. "test -s /etc/rancher/rke2/config.yaml").
+	Shell string `json:"shell,omitempty"`
+
+	// FileExists is the absolute path of a file the condition requires to exist.
+	FileExists string `json:"fileExists,omitempty"`
+
+	// FileAbsent is the absolute path of a file the condition requires not to exist.
+	FileAbsent string `json:"fileAbsent,omitempty"`
 }
 
 // OneTimeInstruction is an instruction that is executed exactly once.
@@ -0,0 +1,208 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// MinPeriodSeconds is the shortest period a periodic instruction may run at. A PeriodSeconds of 0
+// selects the system-agent default of 600.
+const MinPeriodSeconds = 5
+
+// windowsAbsPath matches absolute paths on Windows nodes, e.g. c:\var\lib\rancher or C:/etc.
+var windowsAbsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// Validate checks the plan for mistakes that would otherwise only surface on the node once the
+// system-agent tries to apply it, and returns every problem found. The field paths of the errors
+// are relative to the plan.
+func Validate(plan Plan) field.ErrorList {
+	return validatePlan(plan, nil)
+}
+
+// ValidateSecret validates the plan stored in a machine plan secret, for use by admission webhooks.
+// Secrets without a plan are valid. The field paths of the errors are relative to the secret.
+func ValidateSecret(secret *corev1.Secret) field.ErrorList {
+	raw := secret.Data["plan"]
+	if len(raw) == 0 {
+		return nil
+	}
+
+	fldPath := field.NewPath("data").Key("plan")
+	plan, err := Parse(raw)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, field.OmitValueType{}, err.Error())}
+	}
+	return validatePlan(plan, fldPath)
+}
+
+// validatePlan validates the plan found at fldPath, or the plan itself when fldPath is nil.
+func validatePlan(plan Plan, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	filesPath := fldPath.Child("files")
+	for i, file := range plan.Files {
+		allErrs = append(allErrs, validateFile(file, filesPath.Index(i))...)
+	}
+
+	instructionsPath := fldPath.Child("instructions")
+	names := map[string]bool{}
+	for i, instruction := range plan.OneTimeInstructions {
+		allErrs = append(allErrs, validateInstruction(instruction.CommonInstruction, names, instructionsPath.Index(i))...)
+	}
+
+	periodicPath := fldPath.Child("periodicInstructions")
+	names = map[string]bool{}
+	for i, instruction := range plan.PeriodicInstructions {
+		instructionPath := periodicPath.Index(i)
+		// Periodic instruction output is reported keyed by name, so unlike one-time instructions,
+		// periodic instructions must be named.
+		if instruction.Name == "" {
+			allErrs = append(allErrs, field.Required(instructionPath.Child("name"), "periodic instructions must be named"))
+		}
+		allErrs = append(allErrs, validateInstruction(instruction.CommonInstruction, names, instructionPath)...)
+		// Periodic instructions run on their own schedule, independently of each other.
+		if len(instruction.DependsOn) > 0 {
+			allErrs = append(allErrs, field.Forbidden(instructionPath.Child("dependsOn"), "not supported on periodic instructions"))
+		}
+		if instruction.RunIf != nil {
+			allErrs = append(allErrs, field.Forbidden(instructionPath.Child("runIf"), "not supported on periodic instructions"))
+		}
+		if instruction.ContinueOnError {
+			allErrs = append(allErrs, field.Forbidden(instructionPath.Child("continueOnError"), "not supported on periodic instructions"))
+		}
+		if instruction.PeriodSeconds != 0 && instruction.PeriodSeconds < MinPeriodSeconds {
+			allErrs = append(allErrs, field.Invalid(instructionPath.Child("periodSeconds"), instruction.PeriodSeconds,
+				fmt.Sprintf("must be 0 (the default of 600) or at least %d", MinPeriodSeconds)))
+		}
+	}
+
+	probesPath := fldPath.Child("probes")
+	probeNames := make([]string, 0, len(plan.Probes))
+	for name := range plan.Probes {
+		probeNames = append(probeNames, name)
+	}
+	sort.Strings(probeNames)
+	for _, name := range probeNames {
+		if err := plan.Probes[name].Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(probesPath.Key(name), field.OmitValueType{}, strings.TrimPrefix(err.Error(), "plan: ")))
+		}
+	}
+
+	return allErrs
+}
+
+// usesConditionalInstructions reports whether any one-time instruction of the plan sets DependsOn,
+// RunIf or ContinueOnError.
+func usesConditionalInstructions(plan Plan) bool {
+	for _, instruction := range plan.OneTimeInstructions {
+		if len(instruction.DependsOn) > 0 || instruction.RunIf != nil || instruction.ContinueOnError {
+			return true
+		}
+	}
+	return false
+}
+
+// usesExtendedProbes returns true if any probe of the plan uses an action other than httpGet.
+func usesExtendedProbes(plan Plan) bool {
+	for _, probe := range plan.Probes {
+		if probe.TCPSocketAction != nil || probe.ExecAction != nil || probe.GRPCAction != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFile checks that the file has an absolute path without parent directory references, and
+// octal permissions.
+func validateFile(file File, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if file.Path == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("path"), ""))
+	} else {
+		allErrs = append(allErrs, validateAbsPath(file.Path, fldPath.Child("path"))...)
+	}
+
+	if file.Permissions != "" {
+		if mode, err := strconv.ParseUint(file.Permissions, 8, 32); err != nil || mode > 0o7777 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("permissions"), file.Permissions, "must be an octal file mode, e.g. 0644"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateInstruction checks that the instruction runs something, that its name, if any, is not in
+// seen, that it depends only on instructions in seen, and that its condition is valid. The name is
+// added to seen.
+func validateInstruction(instruction CommonInstruction, seen map[string]bool, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Dependencies must name earlier instructions, which also rules out cycles.
+	for i, dependency := range instruction.DependsOn {
+		if !seen[dependency] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dependsOn").Index(i), dependency, "must name an earlier instruction"))
+		}
+	}
+	if instruction.Name != "" {
+		if seen[instruction.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), instruction.Name))
+		}
+		seen[instruction.Name] = true
+	}
+	if instruction.Command == "" && instruction.Script == "" && instruction.Image == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("command"), "one of command, script or image must be set"))
+	}
+	if instruction.RunIf != nil {
+		allErrs = append(allErrs, validateCondition(*instruction.RunIf, fldPath.Child("runIf"))...)
+	}
+
+	return allErrs
+}
+
+// validateCondition checks that exactly one check of the condition is set, and that file checks use
+// absolute paths.
+func validateCondition(condition Condition, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	var checks []string
+	if condition.Shell != "" {
+		checks = append(checks, "shell")
+	}
+	if condition.FileExists != "" {
+		checks = append(checks, "fileExists")
+		allErrs = append(allErrs, validateAbsPath(condition.FileExists, fldPath.Child("fileExists"))...)
+	}
+	if condition.FileAbsent != "" {
+		checks = append(checks, "fileAbsent")
+		allErrs = append(allErrs, validateAbsPath(condition.FileAbsent, fldPath.Child("fileAbsent"))...)
+	}
+	switch len(checks) {
+	case 0:
+		allErrs = append(allErrs, field.Required(fldPath, "one of shell, fileExists or fileAbsent must be set"))
+	case 1:
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath, field.OmitValueType{}, "only one of shell, fileExists or fileAbsent may be set, got "+strings.Join(checks, ", ")))
+	}
+
+	return allErrs
+}
+
+// validateAbsPath checks that path is an absolute Linux or Windows path without parent directory
+// references.
+func validateAbsPath(path string, fldPath *field.Path) field.ErrorList {
+	if !strings.HasPrefix(path, "/") && !windowsAbsPath.MatchString(path) {
+		return field.ErrorList{field.Invalid(fldPath, path, "must be an absolute path")}
+	}
+	if slices.Contains(strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }), "..") {
+		return field.ErrorList{field.Invalid(fldPath, path, "must not contain '..'")}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func validPlan() Plan {
+	return Plan{
+		Files: []File{
+			{Path: "/etc/rancher/rke2/config.yaml.d/50-rancher.yaml", Permissions: "0600"},
+			{Path: `c:\var\lib\rancher\capr\idempotence\idempotent.ps1`},
+		},
+		OneTimeInstructions: []OneTimeInstruction{
+			{CommonInstruction: CommonInstruction{Name: "restart", Command: "systemctl", Args: []string{"restart", "rke2-server"}}},
+			{CommonInstruction: CommonInstruction{Script: "#!/bin/sh\necho hello"}},
+			{CommonInstruction: CommonInstruction{Script: "#!/bin/sh\necho hello again"}},
+			{CommonInstruction: CommonInstruction{Name: "install", Image: "rancher/system-agent-installer-rke2:v1.33.1-rke2r1"}},
+		},
+		PeriodicInstructions: []PeriodicInstruction{
+			{CommonInstruction: CommonInstruction{Name: "status", Command: "rke2"}, PeriodSeconds: 5},
+			{CommonInstruction: CommonInstruction{Name: "etcd-status", Command: "rke2"}},
+		},
+		Probes: map[string]Probe{
+			"kubelet": {HTTPGetAction: &HTTPGetAction{URL: "https://127.0.0.1:10248/healthz"}},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(p *Plan)
+		want   []string
+	}{
+		{
+			name:   "valid plan",
+			mutate: func(p *Plan) {},
+		},
+		{
+			name: "relative file path",
+			mutate: func(p *Plan) {
+				p.Files[0].Path = "etc/rancher/rke2/config.yaml"
+			},
+			want: []string{`files[0].path: Invalid value: "etc/rancher/rke2/config.yaml": must be an absolute path`},
+		},
+		{
+			name: "path traversal",
+			mutate: func(p *Plan) {
+				p.Files[0].Path = "/var/lib/rancher/../../etc/shadow"
+				p.Files[1].Path = `c:\var\..\windows\system32`
+			},
+			want: []string{"files[0].path", "files[1].path"},
+		},
+		{
+			name: "missing file path",
+			mutate: func(p *Plan) {
+				p.Files[0].Path = ""
+			},
+			want: []string{"files[0].path: Required value"},
+		},
+		{
+			name: "invalid permissions",
+			mutate: func(p *Plan) {
+				p.Files[0].Permissions = "0999"
+				p.Files[1].Permissions = "17777"
+			},
+			want: []string{"files[0].permissions", "files[1].permissions"},
+		},
+		{
+			name: "duplicate instruction names",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[3].Name = "restart"
+				p.PeriodicInstructions[1].Name = "status"
+			},
+			want: []string{`instructions[3].name: Duplicate value: "restart"`, `periodicInstructions[1].name: Duplicate value: "status"`},
+		},
+		{
+			name: "instruction without command or script",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[0].Command = ""
+			},
+			want: []string{"instructions[0].command: Required value"},
+		},
+		{
+			name: "conditional and dependent instructions",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[0].RunIf = &Condition{FileExists: "/etc/rancher/rke2/config.yaml"}
+				p.OneTimeInstructions[1].ContinueOnError = true
+				p.OneTimeInstructions[3].DependsOn = []string{"restart"}
+				p.OneTimeInstructions[3].RunIf = &Condition{Shell: "test -s /etc/rancher/rke2/config.yaml"}
+			},
+		},
+		{
+			name: "dependency on a later or unknown instruction",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[0].DependsOn = []string{"install"}
+				p.OneTimeInstructions[3].DependsOn = []string{"restart", "install", "missing"}
+			},
+			want: []string{
+				`instructions[0].dependsOn[0]: Invalid value: "install"`,
+				`instructions[3].dependsOn[1]: Invalid value: "install"`,
+				`instructions[3].dependsOn[2]: Invalid value: "missing"`,
+			},
+		},
+		{
+			name: "invalid conditions",
+			mutate: func(p *Plan) {
+				p.OneTimeInstructions[0].RunIf = &Condition{}
+				p.OneTimeInstructions[1].RunIf = &Condition{Shell: "true", FileAbsent: "/tmp/done"}
+				p.OneTimeInstructions[2].RunIf = &Condition{FileAbsent: "done"}
+			},
+			want: []string{
+				"instructions[0].runIf: Required value",
+				"instructions[1].runIf: Invalid value: only one of shell, fileExists or fileAbsent may be set, got shell, fileAbsent",
+				`instructions[2].runIf.fileAbsent: Invalid value: "done": must be an absolute path`,
+			},
+		},
+		{
+			name: "conditional periodic instruction",
+			mutate: func(p *Plan) {
+				p.PeriodicInstructions[0].DependsOn = []string{"etcd-status"}
+				p.PeriodicInstructions[1].RunIf = &Condition{FileExists: "/var/lib/rancher/rke2/server/db"}
+				p.PeriodicInstructions[1].ContinueOnError = true
+			},
+			want: []string{
+				`periodicInstructions[0].dependsOn[0]: Invalid value: "etcd-status"`,
+				"periodicInstructions[0].dependsOn: Forbidden: not supported on periodic instructions",
+				"periodicInstructions[1].runIf: Forbidden: not supported on periodic instructions",
+				"periodicInstructions[1].continueOnError: Forbidden: not supported on periodic instructions",
+			},
+		},
+		{
+			name: "unnamed periodic instruction",
+			mutate: func(p *Plan) {
+				p.PeriodicInstructions[0].Name = ""
+			},
+			want: []string{"periodicInstructions[0].name: Required value"},
+		},
+		{
+			name: "period too short",
+			mutate: func(p *Plan) {
+				p.PeriodicInstructions[0].PeriodSeconds = 1
+			},
+			want: []string{"periodicInstructions[0].periodSeconds: Invalid value: 1"},
+		},
+		{
+			name: "invalid probe",
+			mutate: func(p *Plan) {
+				p.Probes["etcd"] = Probe{SuccessThreshold: -1, TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2379"}}
+			},
+			want: []string{"probes[etcd]: Invalid value: probe delay, timeout and thresholds must not be negative"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validPlan()
+			tt.mutate(&p)
+
+			errs := Validate(p)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("expected %d errors, got %v", len(tt.want), errs)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("expected error %d to contain %q, got %q", i, want, errs[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSecret(t *testing.T) {
+	t.Run("no plan", func(t *testing.T) {
+		if errs := ValidateSecret(&corev1.Secret{}); len(errs) != 0 {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	})
+
+	t.Run("malformed plan", func(t *testing.T) {
+		errs := ValidateSecret(&corev1.Secret{Data: map[string][]byte{"plan": []byte("{not valid json")}})
+		if len(errs) != 1 || errs[0].Field != "data[plan]" || errs[0].Type != field.ErrorTypeInvalid {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	})
+
+	t.Run("invalid plan", func(t *testing.T) {
+		p := validPlan()
+		p.Files[0].Path = "relative"
+		raw, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		errs := ValidateSecret(&corev1.Secret{Data: map[string][]byte{"plan": raw}})
+		if len(errs) != 1 || errs[0].Field != "data[plan].files[0].path" {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	})
+}
+
+func TestAssignPlan_RejectsInvalidPlan(t *testing.T) {
+	store := NewStore(nil)
+	p := validPlan()
+	p.OneTimeInstructions[0].Command = ""
+
+	_, err := store.AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if !errors.Is(err, ErrInvalidPlan) {
+		t.Fatalf("expected ErrInvalidPlan, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "instructions[0].command") {
+		t.Errorf("expected the field errors in %q", err.Error())
+	}
+}
+
+func TestAssignPlan_ConditionalInstructions(t *testing.T) {
+	p := validPlan()
+	p.OneTimeInstructions[3].DependsOn = []string{"restart"}
+	p.OneTimeInstructions[3].RunIf = &Condition{FileAbsent: "/var/lib/rancher/rke2/bin/rke2"}
+
+	_, err := NewStore(&updatingSecrets{}).AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if !errors.Is(err, ErrInvalidPlan) || !errors.Is(err, ErrConditionalInstructions) {
+		t.Fatalf("expected ErrConditionalInstructions without a known agent, got %v", err)
+	}
+
+	status, err := NewStore(&updatingSecrets{}).WithConditionalInstructions().AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Pending {
+		t.Errorf("expected the plan to be assigned, got %+v", status)
+	}
+}
+
+func TestAssignPlan_ExtendedProbes(t *testing.T) {
+	p := validPlan()
+	p.Probes["etcd"] = Probe{TCPSocketAction: &TCPSocketAction{Address: "127.0.0.1:2379"}}
+
+	_, err := NewStore(&updatingSecrets{}).AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if !errors.Is(err, ErrInvalidPlan) || !errors.Is(err, ErrExtendedProbes) {
+		t.Fatalf("expected ErrExtendedProbes without a known agent, got %v", err)
+	}
+
+	status, err := NewStore(&updatingSecrets{}).WithExtendedProbes().AssignPlan(&corev1.Secret{}, &p, 1, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Pending {
+		t.Errorf("expected the plan to be assigned, got %+v", status)
+	}
+}
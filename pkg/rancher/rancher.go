@@ -47,6 +47,7 @@
 	"github.com/rancher/rancher/pkg/multiclustermanager"
 	"github.com/rancher/rancher/pkg/multiclustermanager/whitelist"
 	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/plan"
 	"github.com/rancher/rancher/pkg/scc"
 	"github.com/rancher/rancher/pkg/serviceaccounttoken"
 	"github.com/rancher/rancher/pkg/settings"
@@ -205,6 +206,9 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 	if features.ProvisioningV2.Enabled() {
 		// ensure indexers are registered for all replicas
 		provisioningv2.RegisterIndexers(wranglerContext)
+		// indexes machine-plan secrets for the operation controllers, before the secret cache is
+		// started
+		plan.RegisterIndexers(wranglerContext.Core.Secret().Cache())
 	}
 
 	clientSet, err := clientset.NewForConfig(restConfig)
@@ -457,6 +457,23 @@
 	// An empty string or a zero value means SCIM tokens do not expire.
 	ExpireSCIMTokensAfter = NewSetting("expire-scim-tokens-after", "720h") // 30 days
 
+	// OIDCProviderSigningAlgorithm is the algorithm of the keys generated for signing the tokens issued by the OIDC provider.
+	// Valid values are "RS256", "ES256" and "EdDSA", other values are ignored in favor of "RS256".
+	// A change only applies to the keys generated by the next rotation.
+	OIDCProviderSigningAlgorithm = NewSetting("oidc-provider-signing-algorithm", "RS256")
+
+	// OIDCProviderSigningKeyRotationInterval is the duration after which the signing key of the OIDC provider is rotated.
+	// The value should be expressed in valid time.Duration units e.g. "2160h".
+	// An empty string or a zero value means the signing key is only rotated on demand.
+	// Keys created before rotation was introduced are first rotated one interval after the upgrade.
+	OIDCProviderSigningKeyRotationInterval = NewSetting("oidc-provider-signing-key-rotation-interval", "2160h") // 90 days
+
+	// OIDCProviderSigningKeyRotationOverlap is the duration the previous signing key of the OIDC provider is still
+	// published in the JWKS after a rotation, so the tokens it signed can still be verified.
+	// It's extended to the longest token or refresh token lifetime of the OIDC clients.
+	// The value should be expressed in valid time.Duration units e.g. "720h".
+	OIDCProviderSigningKeyRotationOverlap = NewSetting("oidc-provider-signing-key-rotation-overlap", "720h") // 30 days
+
 	ImportedClusterDay2OpsEnabledDefault = NewSetting("imported-cluster-day2-ops-enabled", "true")
 
 	// AssetsImage is the image used for Rancher's `ClusterRepo` assets on downstream clusters.